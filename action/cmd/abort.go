@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// abortOnCritical returns a context cancelled with the first critical error
+// the results record, such as rejected credentials, so that no new file or
+// apply stage is started once every remaining one would fail the same way
+func abortOnCritical(ctx context.Context, results *runResults) (context.Context, context.CancelCauseFunc) {
+	ctx, abort := context.WithCancelCause(ctx)
+	results.aggregator.OnCritical(func(err *errors.Nobl9Error) {
+		logrus.WithError(err).Error("Critical error, aborting the remaining work")
+		abort(err)
+	})
+	return ctx, abort
+}
+
+// abortedBy returns the critical error that aborted the run, or nil
+func abortedBy(ctx context.Context) error {
+	var critical *errors.Nobl9Error
+	if stderrors.As(context.Cause(ctx), &critical) {
+		return critical
+	}
+	return nil
+}
+
+// abortParsedFiles records the parsed files as aborted and returns none of
+// them once the run was aborted; otherwise it returns the files unchanged
+func abortParsedFiles(ctx context.Context, files []*parsedFile, summary *runSummary, results *runResults) []*parsedFile {
+	if abortedBy(ctx) == nil {
+		return files
+	}
+	for _, file := range files {
+		summary.FilesAborted++
+		results.addAbortedFile(file.Path)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/access"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+)
+
+// Report access command
+var reportAccessCmd = &cobra.Command{
+	Use:   "access",
+	Short: "Render a per-project matrix of users and roles for access reviews",
+	Long: `Collect the role bindings of the repository, of Nobl9, or both, and render a matrix of the users and
+groups holding a role in each project, and in the organization, for a compliance access review.
+
+With --source both the repository and Nobl9 roles are shown side by side, and each row is marked in
+sync, only in the repository, only in Nobl9 or role differs. Live role bindings are shown by the
+users' emails; okta-group: and github-team: role bindings of the repository are shown as written,
+while Nobl9 holds a role binding per member.
+
+The report is written as markdown, CSV or JSON to stdout and, with --report-file, to a file.`,
+	Example: `  # Review the access the repository grants
+  nobl9-action report access
+
+  # Compare the repository with Nobl9 as CSV for the payments projects
+  nobl9-action report access --source both --project 'payments-*' --output csv --report-file access.csv \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	Args: cobra.NoArgs,
+	RunE: runReportAccess,
+}
+
+// runReportAccess renders the access matrix of the repository and Nobl9
+func runReportAccess(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	source, err := access.ParseSource(config.AccessSource)
+	if err != nil {
+		return configError(err)
+	}
+	format, err := access.ParseFormat(config.AccessFormat)
+	if err != nil {
+		return configError(err)
+	}
+	if source.Live() && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("client-id and client-secret are required for source %s", source))
+	}
+	patterns := commalist.Split(config.AccessProjects)
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	var repoGrants, liveGrants []access.Grant
+	if source.Repo() {
+		if repoGrants, err = repoAccess(); err != nil {
+			return err
+		}
+	}
+	if source.Live() {
+		if liveGrants, err = liveAccess(ctx); err != nil {
+			return err
+		}
+	}
+	if repoGrants, err = access.FilterProjects(repoGrants, patterns); err != nil {
+		return configError(err)
+	}
+	if liveGrants, err = access.FilterProjects(liveGrants, patterns); err != nil {
+		return configError(err)
+	}
+
+	report := access.New(source, repoGrants, liveGrants)
+	rendered, err := report.Render(format)
+	if err != nil {
+		return err
+	}
+	if _, err := cmd.OutOrStdout().Write(rendered); err != nil {
+		return err
+	}
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, string(rendered)); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote access report")
+	}
+
+	setGitHubOutput("access-users", fmt.Sprintf("%d", report.Principals()))
+	logrus.WithFields(logrus.Fields{
+		"source":   source,
+		"projects": len(report.Projects),
+		"users":    report.Principals(),
+	}).Info("Access report completed")
+	return nil
+}
+
+// repoAccess returns the grants of the role bindings of the repository
+func repoAccess() ([]access.Grant, error) {
+	paths, err := inputFiles()
+	if err != nil {
+		return nil, typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+	var objects []manifest.Object
+	for _, path := range paths {
+		parsed, err := parseRemoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, parsed.Objects...)
+	}
+	return access.Grants(objects), nil
+}
+
+// liveAccess returns the grants of the role bindings in Nobl9, with user
+// IDs replaced by emails
+func liveAccess(ctx context.Context) ([]access.Grant, error) {
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return nil, typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+	objects, err := listObjects(ctx, nobl9API(client), []manifest.Kind{manifest.KindRoleBinding})
+	if err != nil {
+		return nil, err
+	}
+	objects, _ = nobl9client.SubstituteUserIDs(objects, userEmails(ctx, client, objects))
+	return access.Grants(objects), nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/sarif"
+	"github.com/your-org/nobl9-action/version"
+)
+
+// annotationOut receives the ::error workflow commands GitHub Actions shows
+// as annotations on the failing lines; nil outside GitHub Actions
+var annotationOut io.Writer = githubAnnotationOut()
+
+// githubAnnotationOut returns stdout when running in GitHub Actions
+func githubAnnotationOut() io.Writer {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		return os.Stdout
+	}
+	return nil
+}
+
+// invalidObjectRule is the SARIF rule of objects failing validation
+const invalidObjectRule = "invalid-object"
+
+// sarifLog collects the failures of the run for --sarif-file; nil when it
+// is not set
+var sarifLog *sarif.Log
+
+// startSARIF starts collecting failures when --sarif-file is set, and
+// returns the function writing them at the end of the run
+func startSARIF() func() {
+	if config.SarifFile == "" {
+		return func() {}
+	}
+	sarifLog = sarif.New("nobl9-action", version.Version, sarif.Rule{ID: invalidObjectRule, Description: "Nobl9 object fails validation"})
+	return func() {
+		log := logrus.WithField("path", config.SarifFile)
+		if err := sarifLog.Write(config.SarifFile); err != nil {
+			log.WithError(err).Warn("Failed to write SARIF file")
+		} else {
+			log.WithField("results", sarifLog.Len()).Info("Wrote SARIF file")
+		}
+		sarifLog = nil
+	}
+}
+
+// annotateError annotates a file with a failure, at the line and column of
+// position when it is known, and adds it to the SARIF log
+func annotateError(path string, position parser.Position, message string) {
+	if sarifLog != nil {
+		sarifLog.Add(sarif.Result{
+			RuleID:  invalidObjectRule,
+			Message: message,
+			Path:    repositoryPath(path),
+			Line:    position.Line,
+			Column:  position.Column,
+		})
+	}
+	if annotationOut == nil {
+		return
+	}
+	properties := "file=" + escapeAnnotationProperty(repositoryPath(path))
+	if position.Line > 0 {
+		properties += fmt.Sprintf(",line=%d,col=%d", position.Line, position.Column)
+	}
+	fmt.Fprintf(annotationOut, "::error %s::%s\n", properties, escapeAnnotationData(message))
+}
+
+// validateObjects validates the objects decoded from the file at path and
+// returns a message per invalid object, naming the line and column of the
+// failing field in the file as written when found. Each failure is also
+// annotated.
+func validateObjects(path string, objects []manifest.Object) []string {
+	var messages []string
+	var content []byte
+	for _, obj := range objects {
+		err := obj.Validate()
+		if err == nil {
+			continue
+		}
+		if content == nil && !isCSVFile(path) && !isOwnersFile(path) {
+			content, _ = os.ReadFile(path)
+		}
+		position, _ := parser.LocateError(content, obj, err)
+		at := ""
+		if position.Line > 0 {
+			at = fmt.Sprintf(" at line %d, column %d", position.Line, position.Column)
+		}
+		messages = append(messages, fmt.Sprintf("%s '%s'%s: %v", obj.GetKind(), obj.GetName(), at, err))
+		annotateError(path, position, fmt.Sprintf("%s '%s': %v", obj.GetKind(), obj.GetName(), err))
+	}
+	return messages
+}
+
+// escapeAnnotationData escapes the message of a workflow command
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a property value of a workflow command
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Destructive actions need approval: the person running the action in a
+// terminal confirms them, and runs without a terminal, such as CI jobs,
+// need --auto-approve
+var (
+	approvalIn  io.Reader = os.Stdin
+	approvalOut io.Writer = os.Stderr
+	interactive           = stdinIsTerminal
+)
+
+// approveDeletion asks for approval to delete objects of a kind, listing
+// them. It returns an error when the deletion is not approved.
+func approveDeletion(kind string, names []string) error {
+	if config.AutoApprove {
+		return nil
+	}
+	if !interactive() {
+		return fmt.Errorf("deleting %d %ss requires --auto-approve when not running in a terminal", len(names), kind)
+	}
+
+	fmt.Fprintf(approvalOut, "\nThe run will delete %d %ss:\n", len(names), kind)
+	for _, name := range names {
+		fmt.Fprintf(approvalOut, "  - %s\n", name)
+	}
+	confirmed, err := confirmName(approvalIn, approvalOut, "the number of "+kind+"s", strconv.Itoa(len(names)), "delete them")
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return fmt.Errorf("deleting %d %ss was not confirmed", len(names), kind)
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether someone can answer a prompt: stdin is a
+// terminal and the run is not a CI job
+func stdinIsTerminal() bool {
+	if os.Getenv("CI") != "" {
+		return false
+	}
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
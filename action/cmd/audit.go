@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// openAuditLog opens the --audit-log file, or returns nil when there is none
+// or the run changes nothing
+func openAuditLog(dryRun bool) (*audit.Log, error) {
+	if config.AuditLog == "" || dryRun {
+		return nil, nil
+	}
+	return audit.Open(config.AuditLog, provenance.SourceFromEnv())
+}
+
+// liveObjects returns the live versions of the objects by key, read with one
+// request per kind. Objects that do not exist yet are missing. Role bindings
+// are taken from liveBindings instead when it is set, as they were already
+// read.
+func liveObjects(ctx context.Context, client nobl9.Nobl9API, objects []manifest.Object, liveBindings map[compare.Key]manifest.Object) (map[compare.Key]manifest.Object, error) {
+	live := make(map[compare.Key]manifest.Object, len(objects))
+	names := make(map[manifest.Kind][]string)
+	var kinds []manifest.Kind
+	for _, obj := range objects {
+		if liveBindings != nil && obj.GetKind() == manifest.KindRoleBinding {
+			if liveObject, found := liveBindings[compare.KeyOf(obj)]; found {
+				live[compare.KeyOf(obj)] = liveObject
+			}
+			continue
+		}
+		if _, found := names[obj.GetKind()]; !found {
+			kinds = append(kinds, obj.GetKind())
+		}
+		names[obj.GetKind()] = append(names[obj.GetKind()], obj.GetName())
+	}
+
+	for _, kind := range kinds {
+		found, err := client.GetObjects(ctx, kind, sdk.ProjectsWildcard, names[kind])
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range found {
+			live[compare.KeyOf(obj)] = obj
+		}
+	}
+	return live, nil
+}
+
+// auditApplied appends the objects applied from a file to the audit log.
+// live holds their versions before the apply, or is nil if they could not
+// be read. The objects are already applied, so failures are only logged.
+func auditApplied(log *audit.Log, source string, objects []manifest.Object, live map[compare.Key]manifest.Object) {
+	entries, err := audit.Applied(objects, live, source)
+	if err == nil {
+		err = log.Record(entries...)
+	}
+	if err != nil {
+		logrus.WithField("file", source).WithError(err).Error("Failed to record applied objects in the audit log")
+	}
+}
+
+// auditDeleted appends a deleted object to the audit log
+func auditDeleted(log *audit.Log, obj manifest.Object) {
+	entry, err := audit.Deleted(obj)
+	if err == nil {
+		err = log.Record(entry)
+	}
+	if err != nil {
+		logrus.WithField("object", compare.KeyOf(obj).String()).WithError(err).Error("Failed to record deleted object in the audit log")
+	}
+}
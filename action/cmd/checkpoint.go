@@ -0,0 +1,144 @@
+package main
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/checkpoint"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// resumedReason is reported for files skipped because the interrupted run
+// resumed by --resume already applied them
+const resumedReason = "applied by the resumed run"
+
+// runCheckpoint records the files the running process or apply command
+// completed; it is nil while no run is in progress, when --checkpoint-file
+// is empty and in dry runs
+var runCheckpoint *checkpointRun
+
+// checkpointRun records the completed files of a run in --checkpoint-file
+type checkpointRun struct {
+	path       string
+	checkpoint *checkpoint.Checkpoint
+	// resumed is the checkpoint of the run resumed by --resume, or nil
+	resumed *checkpoint.Checkpoint
+	// digests identify the planned objects of each file, by path
+	digests map[string]string
+}
+
+// startCheckpoint starts recording the files the run completes to
+// --checkpoint-file. With --resume it continues the checkpoint left by the
+// interrupted run; otherwise the run starts over and an earlier checkpoint
+// is discarded.
+func startCheckpoint(runStart time.Time) {
+	runCheckpoint = nil
+	if config.CheckpointFile == "" || config.DryRun {
+		return
+	}
+
+	log := logrus.WithField("path", config.CheckpointFile)
+	commit := provenance.SourceFromEnv().SHA
+	run := &checkpointRun{
+		path:       config.CheckpointFile,
+		checkpoint: checkpoint.New(commit, runStart),
+		digests:    make(map[string]string),
+	}
+	runCheckpoint = run
+
+	if !config.Resume {
+		if err := checkpoint.Remove(config.CheckpointFile); err != nil {
+			log.WithError(err).Warn("Failed to discard the checkpoint of an earlier run")
+		}
+		return
+	}
+
+	resumed, err := checkpoint.Load(config.CheckpointFile)
+	switch {
+	case err != nil:
+		log.WithError(err).Warn("Failed to load checkpoint, applying every file")
+		return
+	case resumed == nil:
+		log.Info("No checkpoint to resume from, applying every file")
+		return
+	}
+	if resumed.Commit != "" && commit != "" && resumed.Commit != commit {
+		log.WithFields(logrus.Fields{
+			"checkpoint_commit": resumed.Commit,
+			"commit":            commit,
+		}).Warn("Resuming a run of another commit; files whose objects changed since are applied again")
+	}
+	log.WithFields(logrus.Fields{
+		"files":      resumed.Len(),
+		"started_at": resumed.StartedAt.Format(time.RFC3339),
+	}).Info("Resuming from checkpoint")
+	resumed.Commit = commit
+	run.checkpoint, run.resumed = resumed, resumed
+}
+
+// skipCompletedFiles returns the prepared files to apply. Files the resumed
+// run completed with the same objects are reported as skipped instead.
+func skipCompletedFiles(prepared []*preparedFile, summary *runSummary, results *runResults) []*preparedFile {
+	run := runCheckpoint
+	if run == nil {
+		return prepared
+	}
+
+	remaining := prepared[:0]
+	for _, file := range prepared {
+		digest, err := planner.Hash(file.Objects)
+		if err != nil {
+			logrus.WithField("file", file.Path).WithError(err).Warn("Failed to hash file, it is not checkpointed")
+			remaining = append(remaining, file)
+			continue
+		}
+		run.digests[file.Path] = digest
+		if !run.resumed.Completed(file.Path, digest) {
+			remaining = append(remaining, file)
+			continue
+		}
+		logrus.WithField("file", file.Path).Info("File applied by the resumed run, skipping")
+		summary.FilesResumed++
+		results.addSkippedFile(file.Path, resumedReason)
+	}
+	return remaining
+}
+
+// complete records a file whose objects were all applied and saves the
+// checkpoint, so a run interrupted from then on skips the file when resumed
+func (r *checkpointRun) complete(file *preparedFile) {
+	if r == nil {
+		return
+	}
+	digest, found := r.digests[file.Path]
+	if !found {
+		return
+	}
+	r.checkpoint.Complete(file.Path, digest, time.Now())
+	if err := r.checkpoint.Save(r.path); err != nil {
+		logrus.WithField("path", r.path).WithError(err).Warn("Failed to save checkpoint")
+	}
+}
+
+// finishCheckpoint ends recording the run. The checkpoint of a run that
+// completed every file is removed, as is the one of a run that was rolled
+// back; otherwise it is kept for a run with --resume to skip the completed
+// files.
+func finishCheckpoint(summary *runSummary) {
+	run := runCheckpoint
+	runCheckpoint = nil
+	if run == nil {
+		return
+	}
+
+	log := logrus.WithField("path", run.path)
+	incomplete := summary.AbortedBy != nil || summary.FilesWithErrors > 0 || summary.FilesAborted > 0
+	if incomplete && summary.Rollback == nil && run.checkpoint.Len() > 0 {
+		log.WithField("files", run.checkpoint.Len()).Info("Run incomplete, rerun with --resume to skip the completed files")
+		return
+	}
+	if err := checkpoint.Remove(run.path); err != nil {
+		log.WithError(err).Warn("Failed to remove checkpoint")
+	}
+}
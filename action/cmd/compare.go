@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+)
+
+// Compare orgs command - diff two organizations
+var compareOrgsCmd = &cobra.Command{
+	Use:   "compare-orgs",
+	Short: "Compare the objects of two Nobl9 organizations",
+	Long: `Compare Projects, RoleBindings and SLOs (or the kinds selected with --kinds) between two Nobl9
+organizations, such as staging and production, and report the objects present in one but not the
+other. Nothing is changed in either organization.`,
+	Example: `  # Find what staging has that production does not
+  nobl9-action compare-orgs \
+    --source-client-id "$STAGING_CLIENT_ID" --source-client-secret "$STAGING_CLIENT_SECRET" \
+    --target-client-id "$PROD_CLIENT_ID" --target-client-secret "$PROD_CLIENT_SECRET"
+
+  # Compare services and SLOs as JSON and fail when the organizations differ
+  nobl9-action compare-orgs --kinds service,slo --output json --fail-on-diff \
+    --source-client-id "$STAGING_CLIENT_ID" --source-client-secret "$STAGING_CLIENT_SECRET" \
+    --target-client-id "$PROD_CLIENT_ID" --target-client-secret "$PROD_CLIENT_SECRET"`,
+	GroupID: groupUtility,
+	RunE:    runCompareOrgs,
+}
+
+// runCompareOrgs lists the selected kinds in both organizations and reports
+// the objects present in only one of them
+func runCompareOrgs(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	kinds, err := compare.ParseKinds(config.CompareKinds)
+	if err != nil {
+		return configError(fmt.Errorf("invalid kinds: %w", err))
+	}
+	if config.Output != "text" && config.Output != "json" {
+		return configError(fmt.Errorf("invalid output format: %s", config.Output))
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	// Clients read their credentials when created, so each gets its own set
+	source, err := createNobl9Client(config.SourceClientID, config.SourceClientSecret)
+	if err != nil {
+		return fmt.Errorf("source organization: %w", err)
+	}
+	target, err := createNobl9Client(config.TargetClientID, config.TargetClientSecret)
+	if err != nil {
+		return fmt.Errorf("target organization: %w", err)
+	}
+
+	sourceAPI, targetAPI := nobl9API(source), nobl9API(target)
+
+	result := &compare.Result{
+		Source: organizationName(ctx, sourceAPI, "source"),
+		Target: organizationName(ctx, targetAPI, "target"),
+	}
+
+	sourceObjects, err := listObjects(ctx, sourceAPI, kinds)
+	if err != nil {
+		return fmt.Errorf("source organization %s: %w", result.Source, err)
+	}
+	targetObjects, err := listObjects(ctx, targetAPI, kinds)
+	if err != nil {
+		return fmt.Errorf("target organization %s: %w", result.Target, err)
+	}
+	result.Kinds = compare.Objects(kinds, sourceObjects, targetObjects)
+
+	if err := writeComparison(cmd.OutOrStdout(), result, config.Output); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"source":      result.Source,
+		"target":      result.Target,
+		"differences": result.Differences(),
+	}).Info("Comparison completed")
+
+	if config.FailOnDiff && result.Differences() > 0 {
+		return fmt.Errorf("organizations differ by %d objects", result.Differences())
+	}
+	return nil
+}
+
+// organizationName returns the organization of the client, or fallback if
+// it cannot be read
+func organizationName(ctx context.Context, client nobl9.Nobl9API, fallback string) string {
+	organization, err := getOrganization(ctx, client)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to read the %s organization", fallback)
+		return fallback
+	}
+	return organization
+}
+
+// listObjects returns the live objects of the given kinds across all projects
+func listObjects(ctx context.Context, client nobl9.Nobl9API, kinds []manifest.Kind) ([]manifest.Object, error) {
+	var objects []manifest.Object
+	for _, kind := range kinds {
+		found, err := client.GetObjects(ctx, kind, sdk.ProjectsWildcard, nil)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, found...)
+	}
+	return objects, nil
+}
+
+// writeComparison writes the comparison as text or JSON
+func writeComparison(w io.Writer, result *compare.Result, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	fmt.Fprintf(w, "Comparing %s (source) with %s (target)\n", result.Source, result.Target)
+	for _, kind := range result.Kinds {
+		fmt.Fprintf(w, "\n%s: %d in both, %d only in %s, %d only in %s\n",
+			kind.Kind, kind.Common, len(kind.OnlyInSource), result.Source, len(kind.OnlyInTarget), result.Target)
+		for _, key := range kind.OnlyInSource {
+			fmt.Fprintf(w, "  - %s (only in %s)\n", key, result.Source)
+		}
+		for _, key := range kind.OnlyInTarget {
+			fmt.Fprintf(w, "  + %s (only in %s)\n", key, result.Target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/compat"
+)
+
+// checkCompatibility fails a manifest holding objects of another n9 API
+// version or fields the pinned SDK would silently drop, and returns the
+// manifest with its renamed fields migrated when --migrate-fields is set.
+// YAML the check cannot read is returned as is for the SDK to report.
+func checkCompatibility(content []byte, source string) ([]byte, error) {
+	result, err := compat.Check(content, config.MigrateFields)
+	if err != nil {
+		return content, nil
+	}
+
+	for _, issue := range result.Migrated() {
+		logrus.WithFields(logrus.Fields{
+			"file":  source,
+			"kind":  issue.Kind,
+			"name":  issue.Name,
+			"field": issue.Field,
+			"line":  issue.Line,
+		}).Info("Migrated renamed field: " + issue.Message)
+	}
+
+	if err := result.Err(); err != nil {
+		if !config.MigrateFields {
+			for _, issue := range result.Issues {
+				if issue.Type == compat.IssueRenamedField {
+					return nil, fmt.Errorf("%w (set --migrate-fields to rename the renamed fields)", err)
+				}
+			}
+		}
+		return nil, err
+	}
+	return result.Content, nil
+}
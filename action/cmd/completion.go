@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/access"
+	"github.com/your-org/nobl9-action/pkg/inventory"
+	"github.com/your-org/nobl9-action/pkg/notify"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Completion command - shell completion scripts
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a shell completion script for nobl9-action.
+
+To load completions:
+
+Bash:
+  $ source <(nobl9-action completion bash)
+  # To load completions for each session, execute once:
+  $ nobl9-action completion bash > /etc/bash_completion.d/nobl9-action
+
+Zsh:
+  $ nobl9-action completion zsh > "${fpath[1]}/_nobl9-action"
+
+Fish:
+  $ nobl9-action completion fish > ~/.config/fish/completions/nobl9-action.fish
+
+PowerShell:
+  PS> nobl9-action completion powershell | Out-String | Invoke-Expression`,
+	Example: `  nobl9-action completion bash > /etc/bash_completion.d/nobl9-action
+  nobl9-action completion zsh > "${fpath[1]}/_nobl9-action"`,
+	GroupID:               groupUtility,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE:                  runCompletion,
+}
+
+// runCompletion writes the completion script for the requested shell to stdout
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(os.Stdout)
+	case "fish":
+		return cmd.Root().GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		return cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell: %s", args[0])
+	}
+}
+
+// registerFlagCompletions registers value completions for enumerated flags
+func registerFlagCompletions(cmd *cobra.Command) {
+	completions := map[string][]string{
+		"log-level":         {"debug", "info", "warn", "error"},
+		"log-format":        {"json", "text"},
+		"output":            {"text", "json"},
+		"apply-granularity": {planner.GranularityFile, planner.GranularityProject, planner.GranularityObject},
+		"notify-on":         {notify.OnAlways, notify.OnFailure},
+		"source":            {string(access.SourceRepo), string(access.SourceLive), string(access.SourceBoth)},
+	}
+	// The reports are written in their own formats
+	switch cmd {
+	case reportAccessCmd:
+		completions["output"] = access.Formats
+	case reportSLOsCmd:
+		completions["output"] = inventory.Formats
+	}
+
+	for name, values := range completions {
+		if cmd.Flags().Lookup(name) == nil {
+			continue
+		}
+		values := values
+		if err := cmd.RegisterFlagCompletionFunc(name, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return values, cobra.ShellCompDirectiveNoFileComp
+		}); err != nil {
+			panic(fmt.Sprintf("failed to register completion for %s: %v", name, err))
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/gitref"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/refdiff"
+)
+
+// Diff command - object-level differences between two refs
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Report the objects added, removed and changed between two git refs",
+	Long: `Parse the manifests of the repository at two git refs and report the objects the head adds, removes
+or changes compared with the base, with the fields that changed. Objects are matched by kind, project
+and name and compared like drift compares live objects, so moving an object to another file,
+reordering keys or reformatting YAML is not a change, while a renamed object is reported as removed
+and added.
+
+Each ref is read with git archive, so the command needs git and the refs must be fetched, e.g. with
+fetch-depth: 0 in actions/checkout. Instead of a ref, --base-path and --head-path read the manifests
+of a directory, such as a second checkout. Nothing is read from Nobl9.`,
+	Example: `  # Review the manifests a pull request changes
+  nobl9-action diff --base origin/main --head HEAD
+
+  # Write a markdown report for a pull request comment
+  nobl9-action diff --base "$GITHUB_BASE_REF" --report-file diff.md
+
+  # Compare two checkouts
+  nobl9-action diff --base-path ../main --head-path .`,
+	GroupID: groupUtility,
+	Args:    cobra.NoArgs,
+	RunE:    runDiff,
+}
+
+// runDiff reports the object-level differences between the base and head
+func runDiff(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if config.Output != "text" && config.Output != "json" {
+		return configError(fmt.Errorf("invalid output format: %s", config.Output))
+	}
+	if (config.DiffBase == "") == (config.DiffBasePath == "") {
+		return configError(fmt.Errorf("exactly one of base and base-path is required"))
+	}
+	if config.DiffHead != "" && config.DiffHeadPath != "" {
+		return configError(fmt.Errorf("head and head-path cannot be used together"))
+	}
+	ignoreFields, err := driftIgnoreFields(config.IgnoreFields)
+	if err != nil {
+		return configError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	baseName, headName := diffSideName(config.DiffBase, config.DiffBasePath), diffSideName(config.DiffHead, config.DiffHeadPath)
+	base, err := diffItems(ctx, config.DiffBase, config.DiffBasePath)
+	if err != nil {
+		return err
+	}
+	head, err := diffItems(ctx, config.DiffHead, config.DiffHeadPath)
+	if err != nil {
+		return err
+	}
+
+	report, err := refdiff.Compare(drift.NewWithIgnoreFields(ignoreFields), baseName, headName, base, head)
+	if err != nil {
+		return err
+	}
+
+	if err := writeRefDiff(cmd.OutOrStdout(), report, config.Output); err != nil {
+		return err
+	}
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, report.Markdown()); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote diff report")
+	}
+
+	setGitHubOutput("objects-added", fmt.Sprintf("%d", report.Count(refdiff.StatusAdded)))
+	setGitHubOutput("objects-removed", fmt.Sprintf("%d", report.Count(refdiff.StatusRemoved)))
+	setGitHubOutput("objects-changed", fmt.Sprintf("%d", report.Count(refdiff.StatusChanged)))
+
+	logrus.WithFields(logrus.Fields{
+		"base":     baseName,
+		"head":     headName,
+		"compared": report.Compared,
+		"added":    report.Count(refdiff.StatusAdded),
+		"removed":  report.Count(refdiff.StatusRemoved),
+		"changed":  report.Count(refdiff.StatusChanged),
+	}).Info("Diff completed")
+	return nil
+}
+
+// diffSideName names a side of the diff by its ref or directory; a side
+// without either is the HEAD ref
+func diffSideName(ref, path string) string {
+	switch {
+	case path != "":
+		return path
+	case ref != "":
+		return ref
+	}
+	return "HEAD"
+}
+
+// diffItems returns the objects of the repository at the ref, or of the
+// directory when path is set, with the file declaring each relative to the
+// repository
+func diffItems(ctx context.Context, ref, path string) ([]planner.Item, error) {
+	if path == "" {
+		if ref == "" {
+			ref = "HEAD"
+		}
+		dir, err := os.MkdirTemp("", "nobl9-diff-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", ref, err)
+		}
+		defer os.RemoveAll(dir)
+		if err := gitref.Extract(ctx, config.RepoPath, ref, dir); err != nil {
+			return nil, typedError(errors.ErrorTypeFileProcessing, "failed to read manifests", err)
+		}
+		path = dir
+	}
+
+	// The files of the ref are scanned and named like the repository's
+	previous := config.RepoPath
+	config.RepoPath = path
+	defer func() { config.RepoPath = previous }()
+
+	paths, err := inputFiles()
+	if err != nil {
+		return nil, typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+	var items []planner.Item
+	for _, file := range paths {
+		parsed, err := parseRemoteFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range parsed.Objects {
+			items = append(items, planner.Item{Object: obj, Source: relativePath(file)})
+		}
+	}
+	return items, nil
+}
+
+// writeRefDiff writes the diff as text or JSON
+func writeRefDiff(w io.Writer, report *refdiff.Report, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	if !report.HasChanges() {
+		fmt.Fprintf(w, "No changes between %s and %s: %d objects compared\n", report.Base, report.Head, report.Compared)
+		return nil
+	}
+
+	fmt.Fprintf(w, "%s...%s: %s of %d objects\n", report.Base, report.Head, report.Summary(), report.Compared)
+	for _, object := range report.Objects {
+		fmt.Fprintf(w, "\n%s (%s, %s)\n", object.Key, object.Status, object.Source)
+		for _, change := range object.Changes {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", change.Path, drift.FormatValue(change.Base), drift.FormatValue(change.Head))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"github.com/your-org/nobl9-action/pkg/owners"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+)
+
+// Drift command - compare live objects with the repository
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Report objects whose live Nobl9 definition drifted from the repository",
+	Long: `Fetch the live definition of every object declared in the repository, normalize both sides
+and report field-level differences, such as a description edited in the Nobl9 UI or a role
+binding deleted by hand. Fields Nobl9 sets itself (organization, status, created and updated
+timestamps) are ignored, as are the --ignore-fields rules. Nothing is changed in Nobl9.
+
+The command exits with an error when drift is found and sets the drift-detected output.
+--report-file writes a markdown report, e.g. to post on a pull request or scheduled issue.`,
+	Example: `  # Check the repository against Nobl9
+  nobl9-action drift --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"
+
+  # Write JSON and a markdown report for a scheduled workflow
+  nobl9-action drift --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --output json --report-file drift.md
+
+  # Ignore volatile fields of SLOs and every annotation
+  nobl9-action drift --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --ignore-fields 'slo:spec.objectives[*].rawMetric,metadata.annotations'`,
+	GroupID: groupCore,
+	RunE:    runDrift,
+}
+
+// runDrift compares the declared objects with their live definitions
+func runDrift(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	if config.Output != "text" && config.Output != "json" {
+		return configError(fmt.Errorf("invalid output format: %s", config.Output))
+	}
+	ignoreFields, err := driftIgnoreFields(config.IgnoreFields)
+	if err != nil {
+		return configError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	paths, err := inputFiles()
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+
+	desired, err := declaredObjects(ctx, client, paths)
+	if err != nil {
+		return err
+	}
+
+	live, err := listObjects(ctx, nobl9API(client), itemKinds(desired))
+	if err != nil {
+		return err
+	}
+
+	report, err := drift.NewWithIgnoreFields(ignoreFields).Detect(desired, live)
+	if err != nil {
+		return err
+	}
+
+	if err := writeDriftReport(cmd.OutOrStdout(), report, config.Output); err != nil {
+		return err
+	}
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, report.Markdown()); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote drift report")
+	}
+
+	setGitHubOutput("drift-detected", fmt.Sprintf("%t", report.HasDrift()))
+	setGitHubOutput("drifted-objects", fmt.Sprintf("%d", len(report.Drifted)))
+
+	logrus.WithFields(logrus.Fields{
+		"checked": report.Checked,
+		"drifted": len(report.Drifted),
+	}).Info("Drift detection completed")
+
+	if report.HasDrift() {
+		return fmt.Errorf("drift detected in %d of %d objects", len(report.Drifted), report.Checked)
+	}
+	return nil
+}
+
+// driftIgnoreFields returns the fields left out when comparing declared and
+// live objects: the defaults, the ownership label and the trace and audit
+// annotations, which are set when applying so manifests never declare them,
+// and the given comma separated extra fields
+func driftIgnoreFields(extra string) ([]drift.IgnoreField, error) {
+	marker, err := ownership.New(config.OwnerLabel, true, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner-label: %w", err)
+	}
+	ignored := append(append([]string{}, drift.DefaultIgnoreFields...), marker.IgnoreFields()...)
+	ignored = append(ignored, audit.IgnoreFields()...)
+	ignoreFields, err := drift.ParseIgnoreFields(strings.Join(ignored, ",") + "," + extra)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore-fields: %w", err)
+	}
+	return ignoreFields, nil
+}
+
+// declaredObjects parses the files and returns the objects they declare,
+// with role binding emails resolved to the user IDs Nobl9 stores. Files for
+// another organization and okta-group: and github-team: role bindings, which
+// only exist in Nobl9 once expanded, are left out.
+func declaredObjects(ctx context.Context, client *sdk.Client, paths []string) ([]planner.Item, error) {
+	var files []*parsedFile
+	for _, path := range paths {
+		parsed, err := parseRemoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, parsed)
+	}
+
+	var organization string
+	organizationRead := false
+	resolutions := resolveEmails(ctx, nobl9API(client), resolver.NewUserCache(config.UserCacheTTL), nil, collectEmails(files), nil)
+
+	var items []planner.Item
+	for _, file := range files {
+		if file.Meta != nil && file.Meta.Spec.Organization != "" {
+			if !organizationRead {
+				organization = organizationName(ctx, nobl9API(client), "")
+				organizationRead = true
+			}
+			if file.Meta.Spec.Organization != organization {
+				logrus.WithField("file", file.Path).Info("File targets another organization, skipping")
+				continue
+			}
+		}
+
+		objects, _ := nobl9client.SubstituteUserIDs(file.Objects, resolutions)
+		for _, obj := range objects {
+			if isGroupRoleBinding(obj) {
+				logrus.WithFields(logrus.Fields{
+					"file":         file.Path,
+					"role_binding": obj.GetName(),
+				}).Debug("Skipping group role binding")
+				continue
+			}
+			items = append(items, planner.Item{Object: obj, Source: file.Path})
+		}
+	}
+	return items, nil
+}
+
+// isGroupRoleBinding reports whether the object is an okta-group: or
+// github-team: role binding
+func isGroupRoleBinding(obj manifest.Object) bool {
+	roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+	return ok && roleBinding.Spec.User != nil && (okta.IsGroupReference(*roleBinding.Spec.User) || owners.IsTeamReference(*roleBinding.Spec.User))
+}
+
+// itemKinds returns the kinds of the items in the order they first appear
+func itemKinds(items []planner.Item) []manifest.Kind {
+	seen := make(map[manifest.Kind]bool)
+	var kinds []manifest.Kind
+	for _, item := range items {
+		if kind := item.Object.GetKind(); !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// writeDriftReport writes the drift report as text or JSON
+func writeDriftReport(w io.Writer, report *drift.Report, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	if !report.HasDrift() {
+		fmt.Fprintf(w, "No drift: all %d objects match the repository\n", report.Checked)
+		return nil
+	}
+
+	fmt.Fprintf(w, "%d of %d objects drifted from the repository\n", len(report.Drifted), report.Checked)
+	for _, object := range report.Drifted {
+		fmt.Fprintf(w, "\n%s (%s, %s)\n", object.Key, object.Status, object.Source)
+		for _, change := range object.Changes {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", change.Path, drift.FormatValue(change.Desired), drift.FormatValue(change.Live))
+		}
+	}
+	return nil
+}
+
+// writeMarkdownFile writes a markdown report, creating its directory
+func writeMarkdownFile(path, content string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+)
+
+// serverDryRun is the server-side dry run of a run. Once Nobl9 turns out
+// not to support dry runs, the rest of the run falls back to the local
+// validation the objects already passed.
+type serverDryRun struct {
+	unsupported atomic.Bool
+}
+
+// newServerDryRun returns the server-side dry run of a run, or nil when
+// objects are not sent to Nobl9 in dry runs
+func newServerDryRun(enabled bool) *serverDryRun {
+	if !enabled {
+		return nil
+	}
+	return &serverDryRun{}
+}
+
+// active reports whether objects are still sent to Nobl9 in dry runs
+func (d *serverDryRun) active() bool {
+	return d != nil && !d.unsupported.Load()
+}
+
+// apply sends objects read from a single file to Nobl9 with the dry-run
+// flag, so Nobl9 checks them as it would when applying them (quotas,
+// references, permissions) without changing anything
+func (d *serverDryRun) apply(ctx context.Context, client nobl9.Nobl9API, filePath string, objects []manifest.Object) error {
+	log := logrus.WithFields(logrus.Fields{
+		"file":         filePath,
+		"object_count": len(objects),
+	})
+
+	err := client.DryRunObjects(ctx, objects)
+	switch {
+	case err == nil:
+		log.Info("DRY RUN: Nobl9 accepted objects")
+		return nil
+	case serverDryRunUnsupported(err):
+		log.WithError(err).Warn("Nobl9 does not support dry runs, falling back to local validation")
+		d.unsupported.Store(true)
+		return nil
+	default:
+		return fmt.Errorf("nobl9 rejected objects in dry run: %w", err)
+	}
+}
+
+// serverDryRunUnsupported reports whether an error means Nobl9 does not
+// support dry-run applies, rather than rejecting the objects: a 405 or 501
+// response, or API errors naming the dry run. A 404 is not enough, as it
+// also means an object refers to one that does not exist.
+func serverDryRunUnsupported(err error) bool {
+	var httpErr *sdk.HTTPError
+	if !stderrors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	default:
+		// Only the API errors are checked, as the endpoint of the error holds
+		// the dry_run parameter too
+		message := strings.ToLower(httpErr.APIErrors.Error())
+		return strings.Contains(message, "dry_run") || strings.Contains(message, "dry-run") || strings.Contains(message, "dryrun")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// determineExitCode returns the exit code of an error returned by a command,
+// from the errors.ExitCodes table. Errors without a type, such as the ones
+// of the Nobl9 SDK, are typed by what they wrap, like the errors of the
+// results file.
+func determineExitCode(err error) int {
+	if err == nil {
+		return errors.ExitCodeSuccess
+	}
+	return errors.ExitCode(classifyError("", err))
+}
+
+// configError returns the error of a command whose configuration is invalid
+func configError(err error) error {
+	return errors.NewConfigError("configuration validation failed", err)
+}
+
+// typedError wraps err with a message and gives it an error type, so that
+// the command exits with the code of the type. An error that already has a
+// type, or that determineExitCode can type by what it wraps, such as a
+// rejected token or a timeout, keeps its own type.
+func typedError(errorType errors.ErrorType, message string, err error) error {
+	var nobl9Err *errors.Nobl9Error
+	if stderrors.As(err, &nobl9Err) {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	severity := errors.SeverityHigh
+	if described := describeError("", err); errors.ErrorType(described.Type) != errors.ErrorTypeNonRetryable {
+		errorType = errors.ErrorType(described.Type)
+		severity = errors.ErrorSeverity(described.Severity)
+	}
+	return errors.Wrap(err, errorType, severity, message)
+}
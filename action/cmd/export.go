@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/export"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"github.com/your-org/nobl9-action/pkg/owners"
+)
+
+// Export command - write live objects as manifests
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export projects and their objects from Nobl9 as YAML manifests",
+	Long: `Download the projects matching --project with their Services, AlertPolicies, SLOs and
+RoleBindings (or the kinds selected with --kinds) and write them to --out as one canonical
+multi-document YAML file per project, <project>.yaml. Fields Nobl9 sets, such as status and
+timestamps, are left out and role binding user IDs are written as the users' emails, so the
+files can be committed to bootstrap a repository or kept as a backup to apply again.
+
+Agents, Directs and alert methods hold credentials the API does not return and are never
+exported. Organization role bindings are not part of any project and are not exported.`,
+	Example: `  # Bootstrap the repository from the payments project
+  nobl9-action export --project payments --out nobl9/ \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"
+
+  # Back up every project
+  nobl9-action export --out backup/ \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupUtility,
+	RunE:    runExport,
+}
+
+// runExport exports the matching projects to the output directory
+func runExport(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	kinds, err := export.ParseKinds(config.ExportKinds)
+	if err != nil {
+		return configError(fmt.Errorf("invalid kinds: %w", err))
+	}
+	patterns := commalist.Split(config.ExportProjects)
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	files, err := exportFiles(ctx, client, patterns, kinds)
+	if err != nil {
+		return err
+	}
+
+	objects := 0
+	for _, file := range files {
+		path, err := file.Write(config.OutputDir)
+		if err != nil {
+			return err
+		}
+		objects += len(file.Objects)
+		logrus.WithFields(logrus.Fields{
+			"project": file.Project,
+			"objects": len(file.Objects),
+			"path":    path,
+		}).Info("Wrote project manifests")
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d objects of %d projects to %s\n", objects, len(files), config.OutputDir)
+	return nil
+}
+
+// exportFiles returns the files of the projects matching the patterns, with
+// role binding user IDs replaced by emails
+func exportFiles(ctx context.Context, client *sdk.Client, patterns []string, kinds []manifest.Kind) ([]*export.File, error) {
+	sources, err := exportProjects(ctx, client, patterns, kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []manifest.Object
+	for _, source := range sources {
+		objects = append(objects, source.Project)
+		objects = append(objects, source.Objects...)
+	}
+	objects, _ = nobl9client.SubstituteUserIDs(objects, userEmails(ctx, client, objects))
+
+	return export.Group(objects), nil
+}
+
+// userEmails looks up the emails of the users role bindings refer to, keyed
+// by user ID. Users that cannot be looked up keep their ID.
+func userEmails(ctx context.Context, client *sdk.Client, objects []manifest.Object) map[string]string {
+	emails := make(map[string]string)
+	looked := make(map[string]bool)
+	for _, obj := range objects {
+		roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || roleBinding.Spec.User == nil {
+			continue
+		}
+		userID := *roleBinding.Spec.User
+		if looked[userID] || strings.Contains(userID, "@") || okta.IsGroupReference(userID) || owners.IsTeamReference(userID) {
+			continue
+		}
+		looked[userID] = true
+
+		user, err := client.Users().V2().GetUser(ctx, userID)
+		switch {
+		case err != nil:
+			logrus.WithField("user_id", userID).WithError(err).Warn("Failed to look up user, exporting the user ID")
+		case user == nil || user.Email == "":
+			logrus.WithField("user_id", userID).Warn("User not found, exporting the user ID")
+		default:
+			emails[userID] = user.Email
+		}
+	}
+	return emails
+}
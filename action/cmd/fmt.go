@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/yamlfmt"
+)
+
+// Fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Rewrite the repository's manifests into a canonical form",
+	Long: `Rewrite the Nobl9 YAML manifests of the repository matching the file pattern into a canonical
+form: mapping keys sorted, block style with two space indentation, documents separated by a single
+--- and lists of emails, such as alert method recipients, sorted. Comments, quoting and literal
+blocks are kept, so the manifests declare the same objects, and reformatting is a no-op.
+
+Formatted manifests keep reordered keys and lists out of pull request diffs, and match the files
+the export command writes. With --check no file is written: the unformatted files are listed and
+the command fails, for a CI step. JSON files, rendered .jsonnet and .cue sources and YAML files
+that are not Nobl9 manifests are left alone; YAML that does not parse, such as a file templated
+with {{ }} blocks, is warned about and skipped.
+
+With --fix the users listed more than once in the spec.users of a role binding, compared by email
+or ID ignoring case, are also removed, keeping the first; with --check such files are listed as
+unformatted.`,
+	Example: `  # Format the manifests of the repository
+  nobl9-action fmt
+
+  # Fail a CI step when a manifest is not formatted
+  nobl9-action fmt --check --repo-path ./nobl9
+
+  # Format the manifests and remove duplicate role binding users
+  nobl9-action fmt --fix`,
+	Args:    cobra.NoArgs,
+	GroupID: groupUtility,
+	RunE:    runFmt,
+}
+
+// runFmt formats the manifests of the repository, or with --check lists the
+// unformatted ones
+func runFmt(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	files, err := scanFiles(config.RepoPath, config.FilePattern)
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+
+	var unformatted []string
+	checked := 0
+	for _, path := range files {
+		if !isYAMLFile(path) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return typedError(errors.ErrorTypeFileProcessing, "failed to read file", fmt.Errorf("%s: %w", path, err))
+		}
+		if !isNobl9File(content) {
+			continue
+		}
+		formatted, duplicates, err := formatManifest(content)
+		if err != nil {
+			logrus.WithError(err).WithField("file", relativePath(path)).Warn("Skipping file that is not valid YAML")
+			continue
+		}
+		checked++
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+		unformatted = append(unformatted, relativePath(path))
+		if config.FmtCheck {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return typedError(errors.ErrorTypeFileProcessing, "failed to stat file", err)
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return typedError(errors.ErrorTypeFileProcessing, "failed to write file", err)
+		}
+		log := logrus.WithField("file", relativePath(path))
+		if duplicates > 0 {
+			log = log.WithField("duplicate_users", duplicates)
+		}
+		log.Info("Formatted manifest")
+	}
+
+	out := cmd.OutOrStdout()
+	for _, path := range unformatted {
+		fmt.Fprintln(out, path)
+	}
+	setGitHubOutput("unformatted-files", strings.Join(unformatted, ","))
+	logrus.WithFields(logrus.Fields{
+		"files":       checked,
+		"unformatted": len(unformatted),
+		"check":       config.FmtCheck,
+		"fix":         config.FmtFix,
+	}).Info("Format completed")
+
+	if config.FmtCheck && len(unformatted) > 0 {
+		return typedError(errors.ErrorTypeValidation, "manifests are not formatted",
+			fmt.Errorf("%d of %d files need formatting, run nobl9-action fmt", len(unformatted), checked))
+	}
+	return nil
+}
+
+// formatManifest formats a manifest, and with --fix removes the duplicate
+// users of its role bindings, returning how many it removed
+func formatManifest(content []byte) ([]byte, int, error) {
+	if config.FmtFix {
+		return yamlfmt.Fix(content)
+	}
+	formatted, err := yamlfmt.Format(content)
+	return formatted, 0, err
+}
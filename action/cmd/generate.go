@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/generate"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+)
+
+// Generate command - expand team input into onboarding manifests
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate project onboarding manifests from team input",
+	Long: `Expand the teams of a YAML or CSV --input file into Project, RoleBinding and Service
+manifests, one project per team environment with the team owners as project owners.
+
+The manifests are printed, written to --output-dir as <team>.yaml, or applied to Nobl9 with
+--apply. The built-in templates can be replaced with a --templates directory of Go templates
+(*.tmpl) rendered for each team in file name order; see docs/generate.md for the data and
+functions available to them.`,
+	Example: `  # Preview the manifests of the teams in teams.yaml
+  nobl9-action generate --input teams.yaml
+
+  # Write nobl9/teams/<team>.yaml files to commit to the repository
+  nobl9-action generate --input teams.csv --output-dir nobl9/teams
+
+  # Apply the manifests directly, rendered with custom templates
+  nobl9-action generate --input teams.yaml --templates ./onboarding --apply \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupUtility,
+	RunE:    runGenerate,
+}
+
+// generatedHeader starts every written manifest
+const generatedHeader = "# Generated by nobl9-action generate from %s; change the team input and generate again instead of editing.\n"
+
+// runGenerate renders the manifests of every team and prints, writes or
+// applies them
+func runGenerate(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	if config.Apply && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("--apply requires --client-id and --client-secret"))
+	}
+	if _, err := ownership.New(config.OwnerLabel, config.TraceAnnotations, "", ""); err != nil {
+		return configError(fmt.Errorf("invalid owner-label: %w", err))
+	}
+	teams, err := generate.LoadTeams(config.GenerateInput)
+	if err != nil {
+		return configError(err)
+	}
+	templates, err := generate.LoadTemplates(config.Templates)
+	if err != nil {
+		return configError(err)
+	}
+
+	files := make([]*generate.File, 0, len(teams))
+	for _, team := range teams {
+		file, err := templates.Render(team)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		logrus.WithFields(logrus.Fields{
+			"team":    team.Name,
+			"objects": len(file.Objects),
+		}).Debug("Generated team manifests")
+	}
+
+	header := fmt.Sprintf(generatedHeader, filepath.Base(config.GenerateInput))
+	switch {
+	case config.OutputDir != "":
+		for _, file := range files {
+			if err := writeGeneratedFile(config.OutputDir, header, file, config.Overwrite); err != nil {
+				return err
+			}
+		}
+	case !config.Apply:
+		printGeneratedFiles(cmd.OutOrStdout(), header, files)
+	}
+
+	if !config.Apply {
+		return nil
+	}
+
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	return applyGenerated(ctx, client, files)
+}
+
+// printGeneratedFiles writes the manifests of every team, one after another
+func printGeneratedFiles(w io.Writer, header string, files []*generate.File) {
+	for i, file := range files {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n", file.Name())
+		fmt.Fprint(w, header)
+		fmt.Fprint(w, string(file.Content))
+	}
+}
+
+// writeGeneratedFile writes a team's manifest to dir. An existing file with
+// other content is only replaced when overwrite is set, so hand edits are
+// not lost.
+func writeGeneratedFile(dir, header string, file *generate.File, overwrite bool) error {
+	path := filepath.Join(dir, file.Name())
+	content := append([]byte(header), file.Content...)
+	log := logrus.WithFields(logrus.Fields{"team": file.Team, "path": path})
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		log.Info("Team manifest unchanged")
+		return nil
+	case err == nil && !overwrite:
+		return fmt.Errorf("%s already exists with other content, pass --overwrite to replace it", path)
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Info("Wrote team manifest")
+	return nil
+}
+
+// applyGenerated resolves the owner emails and applies the generated
+// objects in dependency order, the same way process applies files
+func applyGenerated(ctx context.Context, client *sdk.Client, files []*generate.File) error {
+	parsed := make([]*parsedFile, 0, len(files))
+	for _, file := range files {
+		parsed = append(parsed, &parsedFile{
+			Path:    file.Name(),
+			Meta:    file.Meta,
+			Objects: file.Objects,
+			Emails:  appendRoleBindingEmails(nil, file.Objects),
+		})
+	}
+
+	resolutions := resolveEmails(ctx, nobl9API(client), resolver.NewUserCache(config.UserCacheTTL), nil, collectEmails(parsed), nil)
+
+	prepared := make([]*preparedFile, 0, len(parsed))
+	for _, p := range parsed {
+		file, err := prepareFile(p, resolutions, nil, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Path, err)
+		}
+		prepared = append(prepared, file)
+	}
+
+	if err := applyPlanned(ctx, nobl9API(client), prepared, config.DryRun, nil, nil); err != nil {
+		return fmt.Errorf("failed to plan apply order: %w", err)
+	}
+
+	failed := 0
+	for _, file := range prepared {
+		if file.Err != nil {
+			logrus.WithField("file", file.Path).WithError(file.Err).Error("Failed to apply team manifests")
+			failed++
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"file":            file.Path,
+			"projects":        file.Result.ProjectsCreated,
+			"role_bindings":   file.Result.RoleBindingsCreated,
+			"unchanged":       file.Result.RoleBindingsUnchanged,
+			"objects_by_kind": file.Result.Kinds.String(),
+			"emails_resolved": file.Result.EmailsResolved,
+		}).Info("Team manifests applied")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to apply %d of %d teams", failed, len(prepared))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// applyBatch applies objects of a file in one call and records their
+// status: failed with the error, or dry run or applied. Objects whose live
+// definition would not change are left unchanged. Applied objects also
+// record their live version from before the apply, for rollbacks; role
+// bindings take theirs from liveBindings when skipUnchangedRoleBindings
+// read them.
+func applyBatch(ctx context.Context, client nobl9.Nobl9API, file *preparedFile, objects []manifest.Object, liveBindings map[compare.Key]manifest.Object, marker *ownership.Marker, auditLog *audit.Log, dryRun bool, serverCheck *serverDryRun) error {
+	applied := objects
+	if marker != nil {
+		applied = marker.Mark(objects, file.Path)
+	}
+	// The live versions are recorded to roll the run back and audit it
+	var live map[compare.Key]manifest.Object
+	if !dryRun {
+		var err error
+		if live, err = liveObjects(ctx, client, applied, liveBindings); err != nil {
+			logrus.WithField("file", file.Path).WithError(err).Warn("Failed to read live objects, they cannot be rolled back and are audited without their previous version")
+		}
+	}
+	// Objects that would not change are not applied, unless asked to
+	if live != nil && !config.ReapplyUnchanged {
+		if objects, applied = skipUnchangedLive(file, objects, applied, live); len(objects) == 0 {
+			return nil
+		}
+	}
+
+	if err := applyObjects(ctx, client, file.Path, applied, dryRun, serverCheck); err != nil {
+		file.setStatus(objects, statusFailed, err)
+		return err
+	}
+	if dryRun {
+		file.setStatus(objects, statusDryRun, nil)
+		runProgress.AddObjects(len(objects))
+		return nil
+	}
+	file.setStatus(objects, statusApplied, nil)
+	runProgress.AddObjects(len(objects))
+	if live != nil {
+		file.recordRollback(objects, live)
+	}
+	if auditLog != nil {
+		auditApplied(auditLog, file.Path, applied, live)
+	}
+	return nil
+}
+
+// applyFailures counts the objects of a file that were not applied with
+// project or object --apply-granularity and keeps the first failure
+type applyFailures struct {
+	count int
+	first error
+}
+
+// failuresOf returns the failures of a file, creating them on first use
+func failuresOf(failures map[string]*applyFailures, file *preparedFile) *applyFailures {
+	if failures[file.Path] == nil {
+		failures[file.Path] = &applyFailures{}
+	}
+	return failures[file.Path]
+}
+
+// add counts objects not applied because of err and sets the error of the
+// file, so the file is reported failed while the statuses of its objects
+// show which were applied
+func (f *applyFailures) add(file *preparedFile, objects int, err error) {
+	f.count += objects
+	if f.first == nil {
+		f.first = err
+	}
+	file.Err = fmt.Errorf("%d of %d objects were not applied: %w", f.count, len(file.Objects), f.first)
+}
+
+// skipFailedProjects leaves out the objects of projects that failed to
+// apply, marking them skipped with the project's failure
+func skipFailedProjects(file *preparedFile, objects []manifest.Object, failedProjects map[string]error, failures map[string]*applyFailures) []manifest.Object {
+	if len(failedProjects) == 0 {
+		return objects
+	}
+
+	var kept []manifest.Object
+	for _, obj := range objects {
+		project := planner.ProjectOf(obj)
+		projectErr, found := failedProjects[project]
+		if !found {
+			kept = append(kept, obj)
+			continue
+		}
+		err := fmt.Errorf("project %s failed to apply: %w", project, projectErr)
+		file.setStatus([]manifest.Object{obj}, statusSkipped, err)
+		failuresOf(failures, file).add(file, 1, err)
+		logrus.WithFields(logrus.Fields{
+			"file":    file.Path,
+			"kind":    obj.GetKind().String(),
+			"name":    obj.GetName(),
+			"project": project,
+		}).Warn("Object not applied, its project failed to apply")
+	}
+	return kept
+}
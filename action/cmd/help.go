@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Command groups shown in the root help output
+const (
+	groupCore    = "core"
+	groupUtility = "utility"
+)
+
+// flagGroupAnnotation is the pflag annotation used to group flags in help output
+const flagGroupAnnotation = "nobl9-action/flag-group"
+
+// Flag groups in the order they are rendered
+const (
+	flagGroupCredentials = "Credentials"
+	flagGroupRepository  = "Repository"
+	flagGroupProcessing  = "Processing"
+	flagGroupPolicy      = "Policy"
+	flagGroupEmail       = "Email"
+	flagGroupOkta        = "Okta"
+	flagGroupNotify      = "Notifications and Metrics"
+	flagGroupLogging     = "Logging"
+)
+
+var flagGroupOrder = []string{
+	flagGroupCredentials,
+	flagGroupRepository,
+	flagGroupProcessing,
+	flagGroupPolicy,
+	flagGroupEmail,
+	flagGroupOkta,
+	flagGroupNotify,
+	flagGroupLogging,
+}
+
+// usageTemplate is cobra's default usage template with the flag section
+// replaced by grouped flag usages
+const usageTemplate = `Usage:{{if .Runnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}{{$cmds := .Commands}}{{if eq (len .Groups) 0}}
+
+Available Commands:{{range $cmds}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{else}}{{range $group := .Groups}}
+
+{{.Title}}{{range $cmds}}{{if (and (eq .GroupID $group.ID) (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if not .AllChildCommandsHaveGroup}}
+
+Additional Commands:{{range $cmds}}{{if (and (eq .GroupID "") (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+{{groupedFlagUsages .}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`
+
+// setupHelp configures command groups and the grouped flag usage template
+func setupHelp(root *cobra.Command) {
+	root.AddGroup(
+		&cobra.Group{ID: groupCore, Title: "Core Commands:"},
+		&cobra.Group{ID: groupUtility, Title: "Utility Commands:"},
+	)
+	root.SetHelpCommandGroupID(groupUtility)
+	root.CompletionOptions.DisableDefaultCmd = true
+
+	cobra.AddTemplateFunc("groupedFlagUsages", groupedFlagUsages)
+	root.SetUsageTemplate(usageTemplate)
+}
+
+// setFlagGroup assigns the named flags to a help group
+func setFlagGroup(flags *pflag.FlagSet, group string, names ...string) {
+	for _, name := range names {
+		if err := flags.SetAnnotation(name, flagGroupAnnotation, []string{group}); err != nil {
+			panic(fmt.Sprintf("failed to set flag group for %s: %v", name, err))
+		}
+	}
+}
+
+// groupedFlagUsages renders the local flags of a command grouped by their help group
+func groupedFlagUsages(cmd *cobra.Command) string {
+	grouped := make(map[string]*pflag.FlagSet)
+	ungrouped := pflag.NewFlagSet("other", pflag.ContinueOnError)
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+
+		group := ""
+		if values := flag.Annotations[flagGroupAnnotation]; len(values) > 0 {
+			group = values[0]
+		}
+
+		if group == "" {
+			ungrouped.AddFlag(flag)
+			return
+		}
+
+		if grouped[group] == nil {
+			grouped[group] = pflag.NewFlagSet(group, pflag.ContinueOnError)
+		}
+		grouped[group].AddFlag(flag)
+	})
+
+	var sections []string
+	for _, group := range flagGroupOrder {
+		if flags, ok := grouped[group]; ok {
+			sections = append(sections, fmt.Sprintf("%s Flags:\n%s", group, strings.TrimRight(flags.FlagUsages(), " \n")))
+		}
+	}
+
+	if ungrouped.HasFlags() {
+		title := "Flags:"
+		if len(sections) > 0 {
+			title = "Other Flags:"
+		}
+		sections = append(sections, fmt.Sprintf("%s\n%s", title, strings.TrimRight(ungrouped.FlagUsages(), " \n")))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/history"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// Report command - groups reports built from recorded runs and manifests
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render reports from recorded runs and manifests",
+	Long: `Render reports from the run history that process runs record in their --state-file, and access
+review and SLO inventory reports from the manifests of the repository and Nobl9.`,
+	GroupID: groupUtility,
+}
+
+// Report history command
+var reportHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Render error rate, change volume and duration trends of recent runs as markdown",
+	Long: `Read the run history of a state file and render the last --runs runs as markdown: the error
+rate, objects changed, files with errors and median duration, compared with the runs before them,
+and a table of the runs. Every process run that is not a dry run records itself in the history of
+its --state-file, which keeps the last --history-size runs.
+
+The report is printed and, with --report-file, written to a file, e.g. for a scheduled workflow
+that updates a status issue or publishes a dashboard.`,
+	Example: `  # Print the trends of the last 30 runs
+  nobl9-action report history --state-file .nobl9/state.json
+
+  # Write the trends of the last 90 runs for a status issue
+  nobl9-action report history --state-file .nobl9/state.json --runs 90 --report-file history.md`,
+	Args: cobra.NoArgs,
+	RunE: runReportHistory,
+}
+
+// runReportHistory renders the run history of the state file
+func runReportHistory(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if config.StateFile == "" {
+		return configError(fmt.Errorf("state-file is required"))
+	}
+	if config.HistoryRuns <= 0 {
+		return configError(fmt.Errorf("runs must be positive"))
+	}
+
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	report := history.New(st.History, config.HistoryRuns)
+	markdown := report.Markdown()
+	fmt.Fprint(cmd.OutOrStdout(), markdown)
+
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, markdown); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote history report")
+	}
+
+	setGitHubOutput("history-runs", fmt.Sprintf("%d", report.Current.Runs))
+	setGitHubOutput("history-error-rate", fmt.Sprintf("%.1f", report.Current.ErrorRate()))
+	return nil
+}
+
+// recordRunHistory appends the run to the history of the state file. Dry
+// runs are not recorded, so the history reflects what was applied; a
+// history that cannot be saved only warns.
+func recordRunHistory(runStart time.Time, summary *runSummary) {
+	if config.StateFile == "" || config.DryRun || config.HistorySize <= 0 {
+		return
+	}
+
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		logrus.WithField("path", config.StateFile).WithError(err).Warn("Failed to load state, not recording the run")
+		return
+	}
+
+	objectsChanged := summary.projectsDeleted()
+	for _, count := range summary.ObjectsByKind {
+		objectsChanged += count
+	}
+	source := provenance.SourceFromEnv()
+	st.RecordRun(state.Run{
+		StartedAt:       runStart.UTC(),
+		DurationMs:      time.Since(runStart).Milliseconds(),
+		Commit:          source.SHA,
+		RunURL:          source.RunURL,
+		FilesProcessed:  summary.FilesProcessed,
+		FilesWithErrors: summary.FilesWithErrors,
+		ObjectsChanged:  objectsChanged,
+		Errors:          summary.FilesWithErrors + summary.StateErrors + summary.FilesAborted,
+		Aborted:         summary.AbortedBy != nil,
+	}, config.HistorySize)
+
+	if err := st.Save(config.StateFile); err != nil {
+		logrus.WithField("path", config.StateFile).WithError(err).Warn("Failed to record the run in the state history")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"path": config.StateFile, "runs": len(st.History)}).Debug("Recorded the run in the state history")
+}
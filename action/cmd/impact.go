@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/impact"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+)
+
+// checkBudgetImpact returns the objectives of the prepared SLOs whose error
+// budget shrinks by --budget-shrink-threshold percent or more compared with
+// the live SLO. The check only informs reviewers, so live SLOs that cannot
+// be read are logged and nothing is reported.
+func checkBudgetImpact(ctx context.Context, client nobl9.Nobl9API, files []*preparedFile) []impact.Change {
+	if config.BudgetShrinkThreshold <= 0 {
+		return nil
+	}
+
+	var slos []manifest.Object
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			if obj.GetKind() == manifest.KindSLO {
+				slos = append(slos, obj)
+			}
+		}
+	}
+	if len(slos) == 0 {
+		return nil
+	}
+
+	live, err := liveObjects(ctx, client, slos, nil)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read live SLOs, skipping the error budget check")
+		return nil
+	}
+
+	checker := impact.New(config.BudgetShrinkThreshold)
+	var changes []impact.Change
+	for _, file := range files {
+		for _, change := range checker.Check(file.Objects, live) {
+			change.Source = file.Path
+			logrus.WithFields(logrus.Fields{
+				"file":            file.Path,
+				"slo":             change.Object.Name,
+				"project":         change.Object.Project,
+				"objective":       change.Objective,
+				"previous_target": change.PreviousTarget,
+				"target":          change.Target,
+				"budget_shrink":   change.BudgetShrink,
+			}).Warn("High impact change: the SLO may breach as soon as it is applied")
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/inputs"
+	"github.com/your-org/nobl9-action/pkg/organizations"
+	"github.com/your-org/nobl9-action/pkg/parser"
+)
+
+// Inputs command - groups checks of the action's inputs
+var inputsCmd = &cobra.Command{
+	Use:     "inputs",
+	Short:   "Check the inputs of the GitHub Action",
+	Long:    `Check the inputs action.yml declares against the flags of the commands the action runs.`,
+	GroupID: groupUtility,
+}
+
+// Inputs verify command
+var inputsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Fail when action.yml inputs and the command flags drifted apart",
+	Long: `Compare the inputs of action.yml with the flags of the process, plan, apply, validate and drift
+commands the action runs. Every input must be passed on to a flag with the same default and the
+same required setting, and every flag must be exposed as an input, unless it is deliberately left
+out. Each difference is printed and the command fails when there is any.
+
+Run it in CI after adding or changing a flag, so the flag is not forgotten in action.yml.`,
+	Example: `  # Check the action.yml of the repository
+  nobl9-action inputs verify --action-file action/action.yml`,
+	Args: cobra.NoArgs,
+	RunE: runInputsVerify,
+}
+
+// actionCommands are the commands the entrypoint runs
+var actionCommands = []*cobra.Command{processCmd, planCmd, applyCmd, validateCmd, driftCmd}
+
+// entrypointMapping describes how entrypoint.sh hands the arguments of the
+// action on to the commands
+var entrypointMapping = inputs.Mapping{
+	Renamed: map[string]string{
+		"plan-out":            "out",
+		"plan-file":           "plan",
+		"drift-report-file":   "report-file",
+		"drift-ignore-fields": "ignore-fields",
+	},
+	Modes: map[string]bool{
+		"validate-only": true,
+		"drift-only":    true,
+	},
+	EnvFlags: map[string]string{
+		organizations.Env: "organizations",
+		parser.VarsEnv:    "vars",
+	},
+	Unexposed: map[string]string{
+		"output": "the action always writes the text drift report",
+	},
+	Optional: map[string]string{
+		"client-id":     "organizations bring their own credentials",
+		"client-secret": "organizations bring their own credentials",
+		"plan-out":      "the plan command only runs when it is set",
+		"plan-file":     "the apply command only runs when it is set",
+	},
+}
+
+// runInputsVerify compares action.yml with the flags of the action commands
+func runInputsVerify(cmd *cobra.Command, args []string) error {
+	action, err := inputs.Load(config.ActionFile)
+	if err != nil {
+		return configError(err)
+	}
+
+	problems := inputs.Verify(action, actionFlags(), entrypointMapping)
+	for _, problem := range problems {
+		fmt.Fprintln(cmd.OutOrStdout(), problem.String())
+	}
+	if len(problems) > 0 {
+		return errors.NewValidationError(fmt.Sprintf("%d differences between the inputs of %s and the command flags", len(problems), config.ActionFile), nil)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "The %d inputs of %s match the command flags\n", len(action.Inputs), config.ActionFile)
+	return nil
+}
+
+// actionFlags returns the flags of the commands the action runs; a flag
+// several commands share is taken from the first of them
+func actionFlags() map[string]inputs.Flag {
+	flags := make(map[string]inputs.Flag)
+	for _, command := range actionCommands {
+		command.Flags().VisitAll(func(flag *pflag.Flag) {
+			if _, found := flags[flag.Name]; found {
+				return
+			}
+			required := flag.Annotations[cobra.BashCompOneRequiredFlag]
+			flags[flag.Name] = inputs.Flag{
+				Name:     flag.Name,
+				Type:     flag.Value.Type(),
+				Default:  flag.DefValue,
+				Required: len(required) > 0 && required[0] == "true",
+			}
+		})
+	}
+	return flags
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/inventory"
+)
+
+// Report SLOs command
+var reportSLOsCmd = &cobra.Command{
+	Use:   "slos",
+	Short: "Render an inventory of the SLOs per project and service",
+	Long: `Collect the SLOs of the repository's manifests, or with --live of Nobl9, and render an inventory of
+them per project and service: their objectives, time window, data source and the alert policies
+attached. SLOs without an alert policy are listed as warnings, since their error budget can burn
+without anyone being told.
+
+The inventory is written as markdown or JSON to stdout and, with --report-file, to a file.`,
+	Example: `  # Inventory the SLOs of the repository
+  nobl9-action report slos
+
+  # Inventory the SLOs in Nobl9 as JSON
+  nobl9-action report slos --live --output json --report-file slos.json \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	Args: cobra.NoArgs,
+	RunE: runReportSLOs,
+}
+
+// runReportSLOs renders the SLO inventory of the repository or Nobl9
+func runReportSLOs(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	format, err := inventory.ParseFormat(config.InventoryFormat)
+	if err != nil {
+		return configError(err)
+	}
+	if config.InventoryLive && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("client-id and client-secret are required with live"))
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	var items []inventory.Item
+	if config.InventoryLive {
+		items, err = liveSLOs(ctx)
+	} else {
+		items, err = repoSLOs()
+	}
+	if err != nil {
+		return err
+	}
+
+	report := inventory.New(items, config.InventoryLive)
+	rendered, err := report.Render(format)
+	if err != nil {
+		return err
+	}
+	if _, err := cmd.OutOrStdout().Write(rendered); err != nil {
+		return err
+	}
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, string(rendered)); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote SLO inventory")
+	}
+
+	setGitHubOutput("slo-count", fmt.Sprintf("%d", report.SLOs))
+	setGitHubOutput("slos-without-alert-policy", fmt.Sprintf("%d", len(report.Warnings)))
+	logrus.WithFields(logrus.Fields{
+		"slos":     report.SLOs,
+		"projects": len(report.Projects),
+		"warnings": len(report.Warnings),
+	}).Info("SLO inventory completed")
+	return nil
+}
+
+// repoSLOs returns the objects of the repository's files with the file
+// declaring each
+func repoSLOs() ([]inventory.Item, error) {
+	paths, err := inputFiles()
+	if err != nil {
+		return nil, typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+	var items []inventory.Item
+	for _, path := range paths {
+		parsed, err := parseRemoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range parsed.Objects {
+			items = append(items, inventory.Item{Object: obj, Source: relativePath(path)})
+		}
+	}
+	return items, nil
+}
+
+// liveSLOs returns the SLOs of every project in Nobl9
+func liveSLOs(ctx context.Context) ([]inventory.Item, error) {
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return nil, typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+	objects, err := listObjects(ctx, nobl9API(client), []manifest.Kind{manifest.KindSLO})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]inventory.Item, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, inventory.Item{Object: obj})
+	}
+	return items, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/lint"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// lintSLOs lints the SLOs of the prepared files and logs each finding as a
+// warning. Findings inform reviewers and never fail the run.
+func lintSLOs(files []*preparedFile) []lint.Finding {
+	var items []planner.Item
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			items = append(items, planner.Item{Object: obj, Source: file.Path})
+		}
+	}
+	return warnLintFindings(items)
+}
+
+// runLintValidation lints the SLOs of the files and logs each finding as a
+// warning
+func runLintValidation(filePaths []string) error {
+	var items []planner.Item
+	for _, filePath := range filePaths {
+		parsed, err := parseRemoteFile(filePath)
+		if err != nil {
+			return err
+		}
+		for _, obj := range parsed.Objects {
+			items = append(items, planner.Item{Object: obj, Source: filePath})
+		}
+	}
+
+	findings := warnLintFindings(items)
+	logrus.WithField("warnings", len(findings)).Info("SLO lint completed")
+	return nil
+}
+
+// warnLintFindings lints the objects and logs each finding as a warning
+func warnLintFindings(items []planner.Item) []lint.Finding {
+	findings := lint.Lint(items)
+	for _, finding := range findings {
+		logrus.WithFields(logrus.Fields{
+			"file":    finding.Source,
+			"rule":    finding.Rule,
+			"kind":    finding.Kind,
+			"project": finding.Project,
+			"name":    finding.Name,
+		}).Warn("SLO lint: " + finding.Message)
+	}
+	return findings
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,16 +14,49 @@ import (
 	"github.com/nobl9/nobl9-go/manifest"
 	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
 	"github.com/nobl9/nobl9-go/sdk"
+	v2 "github.com/nobl9/nobl9-go/sdk/endpoints/users/v2"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"github.com/your-org/nobl9-action/pkg/access"
+	"github.com/your-org/nobl9-action/pkg/assertions"
+	"github.com/your-org/nobl9-action/pkg/checkpoint"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/history"
+	"github.com/your-org/nobl9-action/pkg/impact"
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/metrics"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/notify"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"github.com/your-org/nobl9-action/pkg/outputs"
+	"github.com/your-org/nobl9-action/pkg/owners"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/policy"
+	"github.com/your-org/nobl9-action/pkg/progress"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+	"github.com/your-org/nobl9-action/pkg/render"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/roles"
+	"github.com/your-org/nobl9-action/pkg/skiplist"
+	"github.com/your-org/nobl9-action/pkg/state"
+	"github.com/your-org/nobl9-action/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Root command
 var rootCmd = &cobra.Command{
-	Use:     "nobl9-action",
-	Short:   "Nobl9 GitHub Action for automated project and role management",
-	Long:    `A GitHub Action that processes Nobl9 YAML configurations, resolves email addresses to Okta User IDs, and deploys projects and role bindings to Nobl9.`,
+	Use:   "nobl9-action",
+	Short: "Nobl9 GitHub Action for automated project and role management",
+	Long:  `A GitHub Action that processes Nobl9 YAML configurations, resolves email addresses to Okta User IDs, and deploys projects and role bindings to Nobl9.`,
+	Example: `  nobl9-action validate --repo-path .
+  nobl9-action process --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" --dry-run
+  nobl9-action completion bash > /etc/bash_completion.d/nobl9-action`,
 	Version: "1.0.0",
 }
 
@@ -29,16 +64,40 @@ var rootCmd = &cobra.Command{
 var processCmd = &cobra.Command{
 	Use:   "process",
 	Short: "Process Nobl9 YAML files and deploy to Nobl9",
-	Long:  `Read Nobl9 YAML configurations from a repository, validate them, resolve email addresses to Okta User IDs, and deploy projects and role bindings to Nobl9.`,
-	RunE:  runProcess,
+	Long:  `Read Nobl9 YAML configurations from a repository, validate them, resolve email addresses to Okta User IDs, and deploy projects, role bindings and every other Nobl9 object kind to Nobl9.`,
+	Example: `  # Deploy all manifests under the current directory
+  nobl9-action process --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"
+
+  # Preview changes for a single directory with readable logs
+  nobl9-action process --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --repo-path ./nobl9 --dry-run --log-format text
+
+  # Apply only projects, role bindings and SLOs
+  nobl9-action process --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --kinds project,rolebinding,slo
+
+  # Expand okta-group: role binding users through Okta
+  nobl9-action process --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --okta-org acme --okta-token "$OKTA_API_TOKEN"`,
+	GroupID: groupCore,
+	RunE:    runProcess,
 }
 
 // Validate command - validation only
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate Nobl9 YAML files without deployment",
-	Long:  `Validate Nobl9 YAML configurations for syntax and structure without deploying to Nobl9.`,
-	RunE:  runValidate,
+	Long:  `Validate Nobl9 YAML configurations for syntax and structure without deploying to Nobl9. With --remote the configurations are also checked against live Nobl9 state, still without applying anything.`,
+	Example: `  # Validate all manifests under the current directory
+  nobl9-action validate
+
+  # Validate only files matching a pattern, with readable logs
+  nobl9-action validate --repo-path ./nobl9 --file-pattern "projects/**/*.yaml" --log-format text
+
+  # Also check references, users and roles against Nobl9
+  nobl9-action validate --remote --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupCore,
+	RunE:    runValidate,
 }
 
 // Configuration flags
@@ -59,29 +118,811 @@ var (
 		// Processing options
 		DryRun bool
 		Force  bool
+		Kinds  string
+		// Projects, or glob patterns, apply runs are restricted to (optional)
+		Projects []string
+		// Kinds and project/name patterns left out of apply runs (optional)
+		SkipKinds   string
+		SkipObjects string
+		// Dry run that sends objects to Nobl9 with the dry-run flag
+		ServerDryRun bool
+
+		// Environment whose ActionMeta overlays specialize the files (optional)
+		Environment string
+		// KEY=value variables substituted into manifests (optional)
+		Vars []string
+		// Values files merged in order for {{ .Values.path }} references (optional)
+		Values []string
+		// Name role bindings that omit metadata.name after their grant
+		GenerateRoleBindingNames bool
+		// Rename fields the n9 API renamed instead of failing the file
+		MigrateFields bool
+		// Engine rendering .jsonnet or .cue files into manifests (optional)
+		Render string
+		// Read the OWNERS files of project directories as role bindings,
+		// mapping their lists to roles with OwnersRoles
+		OwnersFiles bool
+		OwnersRoles string
+
+		// Email normalization applied before resolution and caching
+		EmailLowercase     bool
+		EmailStripPlus     bool
+		EmailDomainAliases string
+		// Kind:path fields whose emails are resolved to user IDs
+		ResolvePaths string
+		// What happens to role bindings whose email does not resolve, and
+		// the user group granted their role with the placeholder policy
+		OnUnresolvedUser    string
+		UnresolvedUserGroup string
+
+		// Okta integration (optional)
+		OktaOrg   string
+		OktaToken string
+
+		// GitHub token used to verify the owner teams of files and to
+		// expand github-team: role binding users (optional)
+		GitHubToken string
+		// Comma separated login=email pairs of GitHub team members
+		GitHubEmails string
+
+		// User resolution cache persisted between runs (optional)
+		UserCacheFile string
+		UserCacheTTL  time.Duration
+
+		// Managed project state and pruning (optional)
+		StateFile   string
+		Prune       bool
+		DeleteGrace string
+		// Deletions proceed without a confirmation prompt, as needed in CI
+		AutoApprove bool
+		// Runs kept in the history of the state file (0 = none)
+		HistorySize int
+		// Runs covered by the report history command
+		HistoryRuns int
+		// Role bindings, projects and format of the report access command
+		AccessSource   string
+		AccessProjects string
+		AccessFormat   string
+		// The report slos command inventories the SLOs in Nobl9 instead of
+		// the repository, in InventoryFormat
+		InventoryLive   bool
+		InventoryFormat string
+		// Git refs, or directories, the diff command compares
+		DiffBase     string
+		DiffHead     string
+		DiffBasePath string
+		DiffHeadPath string
+		// The fmt command lists unformatted manifests instead of rewriting
+		// them
+		FmtCheck bool
+		// The fmt command also removes the duplicate users of role
+		// bindings
+		FmtFix bool
+		// Project fields of the init command and the directory its
+		// files are written to
+		ScaffoldDisplayName string
+		ScaffoldDescription string
+		ScaffoldOwners      string
+		ScaffoldService     string
+		ScaffoldTeam        string
+		ScaffoldOutputDir   string
+
+		// Maximum Nobl9 API requests per second (0 = unlimited)
+		MaxRPS float64
+
+		// Circuit breaker for Nobl9 API calls (threshold 0 = disabled)
+		BreakerThreshold int
+		BreakerCooldown  time.Duration
+
+		// Deadline of each Nobl9 API call (0 = only the run deadline)
+		CallTimeout time.Duration
+
+		// Directory Nobl9 API calls are recorded to as fixtures, or served
+		// back from instead of calling Nobl9 (optional)
+		Record string
+		Replay string
+
+		// Structured results written for downstream steps (optional)
+		ResultsFile string
+		// SARIF file listing the invalid objects of validate and process (optional)
+		SarifFile string
+		// JSON file listing the emails that did not resolve (optional)
+		UnresolvedUsersFile string
+		// Provisional progress written while the run goes on (optional)
+		ProgressFile     string
+		ProgressInterval time.Duration
+
+		// Provenance policy (optional)
+		AllowedBranches string
+		AllowedEvents   string
+
+		// Guardrail policy file, or "default" for the embedded one (optional)
+		Policy string
+		// Comma separated Rego policy files or directories (optional)
+		RegoPolicy string
+		// Role catalog role bindings are checked against: "default" for the
+		// built-in roles, a file adding custom roles, or empty to disable
+		RoleCatalog string
+
+		// Ownership label and trace annotations set on applied objects;
+		// an empty label sets none
+		OwnerLabel       string
+		TraceAnnotations bool
+
+		// Audit annotations and the append-only audit log of applied and
+		// deleted objects (optional)
+		AuditAnnotations bool
+		AuditLog         string
+
+		// Organizations file of a run applying files to several
+		// organizations (optional)
+		Organizations string
+
+		// Share of an SLO objective's error budget, in percent, a change
+		// must remove to be flagged as high impact (0 disables the check)
+		BudgetShrinkThreshold float64
+
+		// Plan hash the run must match to apply, e.g. the one approved on
+		// the pull request (optional)
+		RequirePlanHash string
+
+		// Comma separated project,email,role CSV files read instead of
+		// scanning the repository (optional)
+		CSV string
+
+		// Plan file written by the plan command and applied by the apply command
+		PlanOut  string
+		PlanFile string
+		// Unified diff of the objects a dry run or plan would change (optional)
+		DiffFile string
+
+		// Markdown report and ignored fields of the drift command (optional)
+		ReportFile   string
+		IgnoreFields string
+
+		// Server-side checks of the validate command
+		Remote bool
+		// Also check that email recipients are Nobl9 users
+		CheckRecipients bool
+
+		// Live migration of the rename project command
+		Execute bool
+		Yes     bool
+
+		// Organizations and report of the compare-orgs command
+		SourceClientID     string
+		SourceClientSecret string
+		TargetClientID     string
+		TargetClientSecret string
+		CompareKinds       string
+		Output             string
+		FailOnDiff         bool
+
+		// Environments, projects and transforms of the promote command;
+		// credentials are shared with compare-orgs
+		PromoteFrom     string
+		PromoteTo       string
+		PromoteProjects string
+		PromoteKinds    string
+		Transforms      string
+
+		// Projects and kinds of the export command; files are written to
+		// OutputDir
+		ExportProjects string
+		ExportKinds    string
+
+		// Team input, templates and outputs of the generate command
+		GenerateInput string
+		Templates     string
+		OutputDir     string
+		Overwrite     bool
+		Apply         bool
+
+		// action.yml checked by the inputs verify command
+		ActionFile string
+
+		// Directory of the declarative tests run by the test command, and
+		// by validate when set. Both commands bind it, so it has no flag
+		// default; the test command falls back to assertions.DefaultDir.
+		TestsDir string
+
+		// How apply calls are grouped: file, project or object
+		ApplyGranularity string
+
+		// Roll back the applied objects when a critical error aborts the
+		// run, and the results file the rollback command reads
+		RollbackOnFailure bool
+		RollbackFrom      string
+
+		// Apply objects the state file records as applied with the same
+		// content
+		ReapplyUnchanged bool
+
+		// Where the run summary is posted after processing, and whether
+		// only failed runs are posted
+		SlackWebhookURL string
+		NotifyURL       string
+		NotifyOn        string
+
+		// Prometheus Pushgateway the run metrics are pushed to, and the job
+		// they are grouped under
+		PushgatewayURL string
+		MetricsJob     string
+
+		// Deadline of the whole run, and of its scan, resolve and apply
+		// phases (0 = only the run deadline)
+		Timeout        time.Duration
+		ScanTimeout    time.Duration
+		ResolveTimeout time.Duration
+		ApplyTimeout   time.Duration
+
+		// File recording the files a run completed, and whether to skip
+		// the ones an interrupted run completed
+		CheckpointFile string
+		Resume         bool
+
+		// Retry policies of user, apply and organization calls: attempts,
+		// base delay (0 = each profile's own) and operation=profile pairs
+		RetryMaxAttempts int
+		RetryBaseDelay   time.Duration
+		RetryPolicies    string
 	}
 )
 
 func init() {
+	// Configure command groups and grouped flag help
+	setupHelp(rootCmd)
+
+	// Embedded policies and templates are checked, and repository and
+	// organization variables replace flag defaults, before every command
+	rootCmd.PersistentPreRunE = preRun
+
+	// Errors are logged by main, where credentials are redacted, rather
+	// than printed by cobra
+	rootCmd.SilenceErrors = true
+
 	// Add commands to root
 	rootCmd.AddCommand(processCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(driftCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(fmtCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(compareOrgsCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(usersCmd)
+	rootCmd.AddCommand(inputsCmd)
+	rootCmd.AddCommand(completionCmd)
+	renameCmd.AddCommand(renameProjectCmd)
+	reportCmd.AddCommand(reportHistoryCmd)
+	reportCmd.AddCommand(reportAccessCmd)
+	reportCmd.AddCommand(reportSLOsCmd)
+	usersCmd.AddCommand(usersAuditCmd)
+	inputsCmd.AddCommand(inputsVerifyCmd)
 
 	// Process command flags
 	processCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
 	processCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
 	processCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
-	processCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML files")
+	processCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	processCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files converted into role bindings, read instead of the repository's YAML files")
+	processCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	processCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	processCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	processCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	processCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	processCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	processCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	processCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
 	processCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	processCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
 	processCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Perform dry run without making changes")
+	processCmd.Flags().BoolVar(&config.ServerDryRun, "server-dry-run", false, "Dry run that sends objects to Nobl9 with the dry-run flag, so quota, reference and permission errors surface without changing anything")
 	processCmd.Flags().BoolVar(&config.Force, "force", false, "Force processing even if validation fails")
+	processCmd.Flags().StringVar(&config.Kinds, "kinds", "all", "Comma separated object kinds to apply (e.g. project,rolebinding,slo)")
+	processCmd.Flags().StringSliceVar(&config.Projects, "project", nil, "Only apply the objects of this project; repeat it or separate projects with commas, glob patterns such as payments-* are allowed")
+	processCmd.Flags().StringVar(&config.SkipKinds, "skip-kinds", "", "Comma separated object kinds left out of the run, e.g. alertmethod,slo")
+	processCmd.Flags().StringVar(&config.SkipObjects, "skip-objects", "", "Comma separated project/name objects left out of the run; glob patterns such as payments/* are allowed")
+	processCmd.Flags().BoolVar(&config.EmailLowercase, "email-lowercase", false, "Lowercase emails before resolving them to users")
+	processCmd.Flags().BoolVar(&config.EmailStripPlus, "email-strip-plus", false, "Strip plus addressing (alice+nobl9@corp.com) from emails before resolving them")
+	processCmd.Flags().StringVar(&config.EmailDomainAliases, "email-domain-aliases", "", "Comma separated old=new email domains rewritten before resolving, e.g. old-corp.com=corp.com")
+	processCmd.Flags().StringVar(&config.ResolvePaths, "resolve-paths", "all", "Comma separated Kind:path fields whose emails are resolved to user IDs, e.g. RoleBinding:spec.user, a kind for all of its fields, all or none; alert method, annotation and report emails are never rewritten")
+	processCmd.Flags().StringVar(&config.OnUnresolvedUser, "on-unresolved-user", "warn", "What to do with role bindings whose email does not resolve to a Nobl9 user: fail the run, warn and apply the email as written, skip the role binding, or grant its role to the unresolved-user-group placeholder")
+	processCmd.Flags().StringVar(&config.UnresolvedUserGroup, "unresolved-user-group", "", "ID of the Nobl9 user group granted the roles of unresolved users with --on-unresolved-user placeholder")
+	processCmd.Flags().StringVar(&config.OktaOrg, "okta-org", "", "Okta org (e.g. acme or acme.okta.com) used to expand okta-group: role binding users")
+	processCmd.Flags().StringVar(&config.OktaToken, "okta-token", "", "Okta API token used to expand okta-group: role binding users")
+	processCmd.Flags().StringVar(&config.GitHubToken, "github-token", "", "GitHub token with read access to pull requests and read:org, used to verify the owner teams of files and expand github-team: role binding users")
+	processCmd.Flags().StringVar(&config.GitHubEmails, "github-emails", "", "Comma separated login=email pairs of github-team: members, used before the public email of their GitHub profile")
+	processCmd.Flags().StringVar(&config.UserCacheFile, "user-cache-file", "", "JSON file used to persist resolved users between runs (e.g. a path restored by actions/cache)")
+	processCmd.Flags().DurationVar(&config.UserCacheTTL, "user-cache-ttl", 24*time.Hour, "How long persisted user resolutions remain valid")
+	processCmd.Flags().Float64Var(&config.MaxRPS, "max-rps", 0, "Maximum Nobl9 API requests per second shared by all calls (0 = unlimited)")
+	processCmd.Flags().IntVar(&config.BreakerThreshold, "breaker-threshold", 5, "Consecutive Nobl9 API failures that open the circuit breaker (0 = disabled)")
+	processCmd.Flags().DurationVar(&config.BreakerCooldown, "breaker-cooldown", 30*time.Second, "How long an open circuit breaker fails Nobl9 API calls fast")
+	processCmd.Flags().DurationVar(&config.CallTimeout, "call-timeout", nobl9.DefaultCallTimeout, "How long a single Nobl9 API call may take before it fails and is retried (0 = only the run deadline)")
+	processCmd.Flags().StringVar(&config.Record, "record", "", "Directory the Nobl9 API calls of the run are recorded to as JSON fixtures, without credentials")
+	processCmd.Flags().StringVar(&config.Replay, "replay", "", "Directory of recorded fixtures answering the Nobl9 API calls of the run, without credentials or network access")
+	processCmd.Flags().IntVar(&config.RetryMaxAttempts, "retry-max-attempts", retry.DefaultMaxAttempts, "How many times a user lookup, apply or organization call is attempted before its error is reported")
+	processCmd.Flags().DurationVar(&config.RetryBaseDelay, "retry-base-delay", 0, "Delay before the first retry, doubling after each attempt (0 = the delay of each retry profile)")
+	processCmd.Flags().StringVar(&config.RetryPolicies, "retry-policies", retry.DefaultProfiles, "Comma separated operation=profile pairs choosing how users, apply and organization calls are retried; profiles are api, network, rate-limit and none")
+	processCmd.Flags().DurationVar(&config.Timeout, "timeout", defaultRunTimeout, "How long the whole run may take before it is aborted (0 = no limit)")
+	processCmd.Flags().DurationVar(&config.ScanTimeout, "scan-timeout", 0, "How long finding and parsing the files may take before the run is aborted (0 = only the run timeout)")
+	processCmd.Flags().DurationVar(&config.ResolveTimeout, "resolve-timeout", 0, "How long resolving emails to user IDs may take before the run is aborted (0 = only the run timeout)")
+	processCmd.Flags().DurationVar(&config.ApplyTimeout, "apply-timeout", 0, "How long applying the objects may take before the run is aborted (0 = only the run timeout)")
+	processCmd.Flags().StringVar(&config.CheckpointFile, "checkpoint-file", checkpoint.DefaultFile, "JSON file recording each file whose objects were all applied, kept when the run does not complete; empty records none")
+	processCmd.Flags().BoolVar(&config.Resume, "resume", false, "Skip the files the checkpoint file records as applied by an interrupted or failed run, unless their objects changed since")
+	processCmd.Flags().StringVar(&config.ApplyGranularity, "apply-granularity", planner.GranularityFile, "How apply calls are grouped: file, or project or object to apply each on its own and continue past failures")
+	processCmd.Flags().BoolVar(&config.RollbackOnFailure, "rollback-on-failure", false, "Restore the objects the run applied to their previous definitions, and delete the ones it created, when a critical error aborts the run")
+	processCmd.Flags().StringVar(&config.DiffFile, "diff-file", "", "File to write a unified diff of the live and desired YAML of every object a dry run would change to, e.g. nobl9-plan.diff (requires --dry-run)")
+	processCmd.Flags().StringVar(&config.ResultsFile, "results-file", "", "JSON file to write the complete run results to (per-file and per-object status, errors, durations)")
+	processCmd.Flags().StringVar(&config.SarifFile, "sarif-file", "", "SARIF file to write the file, line and column of every invalid object to, e.g. for GitHub code scanning")
+	processCmd.Flags().StringVar(&config.UnresolvedUsersFile, "unresolved-users-file", "", "JSON file listing the email, file and reason of every role binding user that did not resolve to a Nobl9 user")
+	processCmd.Flags().StringVar(&config.ProgressFile, "progress-file", "", "JSON file rewritten every progress-interval with the files done so far, for steps monitoring a long run or reading a cancelled one")
+	processCmd.Flags().DurationVar(&config.ProgressInterval, "progress-interval", progress.DefaultInterval, "How often the progress file is written while the run goes on (0 = only at the end)")
+	processCmd.Flags().StringVar(&config.StateFile, "state-file", "", "JSON file used to record managed projects and objects between runs (required by --prune)")
+	processCmd.Flags().BoolVar(&config.ReapplyUnchanged, "reapply-unchanged", false, "Apply objects that match their live definition or that the state file records as applied with the same content, e.g. to repair changes made in Nobl9")
+	processCmd.Flags().StringVar(&config.SlackWebhookURL, "slack-webhook-url", "", "Slack incoming webhook the run summary is posted to")
+	processCmd.Flags().StringVar(&config.NotifyURL, "notify-url", "", "HTTP endpoint the run summary is posted to as JSON")
+	processCmd.Flags().StringVar(&config.NotifyOn, "notify-on", notify.OnAlways, "Which runs are posted: always, or failure for failed runs only")
+	processCmd.Flags().StringVar(&config.PushgatewayURL, "pushgateway-url", "", "Prometheus Pushgateway the run metrics (objects, API calls and latency, retries, failures) are pushed to")
+	processCmd.Flags().StringVar(&config.MetricsJob, "metrics-job", metrics.DefaultJob, "Pushgateway job the run metrics are pushed under")
+	processCmd.Flags().BoolVar(&config.Prune, "prune", false, "Delete managed projects that are no longer declared, after the deletion grace period")
+	processCmd.Flags().StringVar(&config.AllowedBranches, "allowed-branches", "", "Comma separated branches (or glob patterns) allowed to apply, e.g. main,release/*; empty allows any branch")
+	processCmd.Flags().StringVar(&config.AllowedEvents, "allowed-events", "push", "Comma separated GitHub events allowed to apply when allowed-branches is set")
+	processCmd.Flags().StringVar(&config.Policy, "policy", "", "Guardrail policy file checked before applying, or \"default\" for the built-in rules")
+	processCmd.Flags().StringVar(&config.RegoPolicy, "rego-policy", "", "Comma separated Rego policy files or directories evaluated against each object before applying")
+	processCmd.Flags().StringVar(&config.RoleCatalog, "role-catalog", roles.DefaultName, "Roles role bindings may reference: \"default\" for the built-in roles, a YAML file adding custom roles, or empty to accept any role")
+	processCmd.Flags().Float64Var(&config.BudgetShrinkThreshold, "budget-shrink-threshold", impact.DefaultThreshold, "Flag SLO changes that shrink an objective's error budget by this percentage or more as high impact (0 disables the check)")
+	processCmd.Flags().StringVar(&config.Organizations, "organizations", "", "YAML file listing the Nobl9 organizations to apply files to, with their credentials and paths; defaults to the list in NOBL9_ORGANIZATIONS")
+	processCmd.Flags().StringVar(&config.RequirePlanHash, "require-plan-hash", "", "Only apply when the plan hash matches this one, e.g. the plan-hash output of the approved pull request run")
+	processCmd.Flags().BoolVar(&config.AutoApprove, "auto-approve", false, "Delete projects past their deletion grace period without asking for confirmation; required when not running in a terminal")
+	processCmd.Flags().StringVar(&config.DeleteGrace, "delete-grace", "7d", "How long a pruned project stays labeled pending-delete before it is deleted (e.g. 7d, 36h; 0 deletes immediately)")
+	processCmd.Flags().IntVar(&config.HistorySize, "history-size", state.DefaultHistorySize, "Runs kept in the history of the state file, read by report history (0 records none)")
+	processCmd.Flags().StringVar(&config.OwnerLabel, "owner-label", ownership.DefaultLabel, "key=value label set on applied objects to mark them as managed by the action; empty sets none")
+	processCmd.Flags().BoolVar(&config.TraceAnnotations, "trace-annotations", true, "Annotate applied objects with the repository, commit SHA and file they were applied from")
+	processCmd.Flags().BoolVar(&config.AuditAnnotations, "audit-annotations", false, "Annotate applied objects with the github.com/commit, github.com/author and github.com/workflow-run of the change")
+	processCmd.Flags().StringVar(&config.AuditLog, "audit-log", "", "Append-only JSON lines file recording every object created, updated or deleted, with hashes before and after")
 
 	// Validate command flags
 	validateCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
-	validateCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML files")
+	validateCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	validateCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files to validate instead of the repository's YAML files")
+	validateCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	validateCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	validateCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	validateCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	validateCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	validateCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	validateCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	validateCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
 	validateCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	validateCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+	validateCmd.Flags().StringVar(&config.Policy, "policy", "", "Guardrail policy file to check, or \"default\" for the built-in rules")
+	validateCmd.Flags().StringVar(&config.RegoPolicy, "rego-policy", "", "Comma separated Rego policy files or directories evaluated against each object")
+	validateCmd.Flags().StringVar(&config.RoleCatalog, "role-catalog", roles.DefaultName, "Roles --remote checks role bindings against: \"default\" for the built-in roles, a YAML file adding custom roles, or empty to accept any role")
+	validateCmd.Flags().StringVar(&config.SarifFile, "sarif-file", "", "SARIF file to write the file, line and column of every invalid object to, e.g. for GitHub code scanning")
+	validateCmd.Flags().StringVar(&config.TestsDir, "tests-dir", "", "Directory of declarative tests run against the valid files after validation; empty runs none")
+	validateCmd.Flags().BoolVar(&config.Remote, "remote", false, "Also check against live Nobl9 state: referenced projects, services, data sources, alert policies and alert methods exist, emails resolve and roles are valid")
+	validateCmd.Flags().BoolVar(&config.CheckRecipients, "check-recipients", false, "With --remote, also check that email alert method recipients are Nobl9 users")
+	validateCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required by --remote)")
+	validateCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required by --remote)")
+
+	// Test command flags
+	testCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	testCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	testCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files to test instead of the repository's YAML files")
+	testCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	testCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	testCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	testCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	testCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	testCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	testCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	testCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	testCmd.Flags().StringVar(&config.TestsDir, "tests-dir", "", "Directory of the test files, *.yaml and *.yml, to run (default "+assertions.DefaultDir+")")
+	testCmd.Flags().StringVar(&config.Output, "output", "text", "Report format (text, json)")
+	testCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	testCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Plan command flags
+	planCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
+	planCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
+	planCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	planCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	planCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files converted into role bindings, read instead of the repository's YAML files")
+	planCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	planCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	planCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	planCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	planCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	planCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	planCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	planCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	planCmd.Flags().StringVar(&config.PlanOut, "out", "", "File to write the plan to, e.g. plan.bin (required)")
+	planCmd.Flags().StringVar(&config.DiffFile, "diff-file", "", "File to write a unified diff of the live and planned YAML of every object the plan changes to, e.g. nobl9-plan.diff")
+	planCmd.Flags().StringVar(&config.Kinds, "kinds", "all", "Comma separated object kinds to plan (e.g. project,rolebinding,slo)")
+	planCmd.Flags().StringSliceVar(&config.Projects, "project", nil, "Only plan the objects of this project; repeat it or separate projects with commas, glob patterns such as payments-* are allowed")
+	planCmd.Flags().StringVar(&config.SkipKinds, "skip-kinds", "", "Comma separated object kinds left out of the plan, e.g. alertmethod,slo")
+	planCmd.Flags().StringVar(&config.SkipObjects, "skip-objects", "", "Comma separated project/name objects left out of the plan; glob patterns such as payments/* are allowed")
+	planCmd.Flags().BoolVar(&config.ServerDryRun, "server-dry-run", false, "Send the planned objects to Nobl9 with the dry-run flag, so quota, reference and permission errors surface before applying")
+	planCmd.Flags().BoolVar(&config.EmailLowercase, "email-lowercase", false, "Lowercase emails before resolving them to users")
+	planCmd.Flags().BoolVar(&config.EmailStripPlus, "email-strip-plus", false, "Strip plus addressing (alice+nobl9@corp.com) from emails before resolving them")
+	planCmd.Flags().StringVar(&config.EmailDomainAliases, "email-domain-aliases", "", "Comma separated old=new email domains rewritten before resolving, e.g. old-corp.com=corp.com")
+	planCmd.Flags().StringVar(&config.ResolvePaths, "resolve-paths", "all", "Comma separated Kind:path fields whose emails are resolved to user IDs, e.g. RoleBinding:spec.user, a kind for all of its fields, all or none; alert method, annotation and report emails are never rewritten")
+	planCmd.Flags().StringVar(&config.OnUnresolvedUser, "on-unresolved-user", "warn", "What to do with role bindings whose email does not resolve to a Nobl9 user: fail the run, warn and apply the email as written, skip the role binding, or grant its role to the unresolved-user-group placeholder")
+	planCmd.Flags().StringVar(&config.UnresolvedUserGroup, "unresolved-user-group", "", "ID of the Nobl9 user group granted the roles of unresolved users with --on-unresolved-user placeholder")
+	planCmd.Flags().StringVar(&config.OktaOrg, "okta-org", "", "Okta org (e.g. acme or acme.okta.com) used to expand okta-group: role binding users")
+	planCmd.Flags().StringVar(&config.OktaToken, "okta-token", "", "Okta API token used to expand okta-group: role binding users")
+	planCmd.Flags().StringVar(&config.GitHubToken, "github-token", "", "GitHub token with read access to pull requests and read:org, used to verify the owner teams of files and expand github-team: role binding users")
+	planCmd.Flags().StringVar(&config.GitHubEmails, "github-emails", "", "Comma separated login=email pairs of github-team: members, used before the public email of their GitHub profile")
+	planCmd.Flags().StringVar(&config.UserCacheFile, "user-cache-file", "", "JSON file used to persist resolved users between runs (e.g. a path restored by actions/cache)")
+	planCmd.Flags().DurationVar(&config.UserCacheTTL, "user-cache-ttl", 24*time.Hour, "How long persisted user resolutions remain valid")
+	planCmd.Flags().Float64Var(&config.MaxRPS, "max-rps", 0, "Maximum Nobl9 API requests per second shared by all calls (0 = unlimited)")
+	planCmd.Flags().IntVar(&config.BreakerThreshold, "breaker-threshold", 5, "Consecutive Nobl9 API failures that open the circuit breaker (0 = disabled)")
+	planCmd.Flags().DurationVar(&config.BreakerCooldown, "breaker-cooldown", 30*time.Second, "How long an open circuit breaker fails Nobl9 API calls fast")
+	planCmd.Flags().DurationVar(&config.CallTimeout, "call-timeout", nobl9.DefaultCallTimeout, "How long a single Nobl9 API call may take before it fails and is retried (0 = only the run deadline)")
+	planCmd.Flags().StringVar(&config.Record, "record", "", "Directory the Nobl9 API calls of the run are recorded to as JSON fixtures, without credentials")
+	planCmd.Flags().StringVar(&config.Replay, "replay", "", "Directory of recorded fixtures answering the Nobl9 API calls of the run, without credentials or network access")
+	planCmd.Flags().IntVar(&config.RetryMaxAttempts, "retry-max-attempts", retry.DefaultMaxAttempts, "How many times a user lookup, apply or organization call is attempted before its error is reported")
+	planCmd.Flags().DurationVar(&config.RetryBaseDelay, "retry-base-delay", 0, "Delay before the first retry, doubling after each attempt (0 = the delay of each retry profile)")
+	planCmd.Flags().StringVar(&config.RetryPolicies, "retry-policies", retry.DefaultProfiles, "Comma separated operation=profile pairs choosing how users, apply and organization calls are retried; profiles are api, network, rate-limit and none")
+	planCmd.Flags().DurationVar(&config.Timeout, "timeout", defaultRunTimeout, "How long the whole run may take before it is aborted (0 = no limit)")
+	planCmd.Flags().DurationVar(&config.ScanTimeout, "scan-timeout", 0, "How long finding and parsing the files may take before the run is aborted (0 = only the run timeout)")
+	planCmd.Flags().DurationVar(&config.ResolveTimeout, "resolve-timeout", 0, "How long resolving emails to user IDs may take before the run is aborted (0 = only the run timeout)")
+	planCmd.Flags().StringVar(&config.ResultsFile, "results-file", "", "JSON file to write the complete run results to (per-file and per-object status, errors, durations)")
+	planCmd.Flags().StringVar(&config.UnresolvedUsersFile, "unresolved-users-file", "", "JSON file listing the email, file and reason of every role binding user that did not resolve to a Nobl9 user")
+	planCmd.Flags().StringVar(&config.ProgressFile, "progress-file", "", "JSON file rewritten every progress-interval with the files done so far, for steps monitoring a long run or reading a cancelled one")
+	planCmd.Flags().DurationVar(&config.ProgressInterval, "progress-interval", progress.DefaultInterval, "How often the progress file is written while the run goes on (0 = only at the end)")
+	planCmd.Flags().StringVar(&config.Policy, "policy", "", "Guardrail policy file checked before planning, or \"default\" for the built-in rules")
+	planCmd.Flags().StringVar(&config.RegoPolicy, "rego-policy", "", "Comma separated Rego policy files or directories evaluated against each object before planning")
+	planCmd.Flags().StringVar(&config.RoleCatalog, "role-catalog", roles.DefaultName, "Roles role bindings may reference: \"default\" for the built-in roles, a YAML file adding custom roles, or empty to accept any role")
+	planCmd.Flags().Float64Var(&config.BudgetShrinkThreshold, "budget-shrink-threshold", impact.DefaultThreshold, "Flag SLO changes that shrink an objective's error budget by this percentage or more as high impact (0 disables the check)")
+	planCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	planCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Apply command flags
+	applyCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
+	applyCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
+	applyCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path the plan was made from")
+	applyCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern the plan was made with")
+	applyCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated CSV files the plan was made from")
+	applyCmd.Flags().StringVar(&config.Render, "render", "", "Engine the plan rendered .jsonnet or .cue files with")
+	applyCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Whether the plan read the OWNERS files of project directories")
+	applyCmd.Flags().StringVar(&config.PlanFile, "plan", "", "Plan file written by the plan command (required)")
+	applyCmd.Flags().Float64Var(&config.MaxRPS, "max-rps", 0, "Maximum Nobl9 API requests per second shared by all calls (0 = unlimited)")
+	applyCmd.Flags().IntVar(&config.BreakerThreshold, "breaker-threshold", 5, "Consecutive Nobl9 API failures that open the circuit breaker (0 = disabled)")
+	applyCmd.Flags().DurationVar(&config.BreakerCooldown, "breaker-cooldown", 30*time.Second, "How long an open circuit breaker fails Nobl9 API calls fast")
+	applyCmd.Flags().DurationVar(&config.CallTimeout, "call-timeout", nobl9.DefaultCallTimeout, "How long a single Nobl9 API call may take before it fails and is retried (0 = only the run deadline)")
+	applyCmd.Flags().StringVar(&config.Record, "record", "", "Directory the Nobl9 API calls of the run are recorded to as JSON fixtures, without credentials")
+	applyCmd.Flags().StringVar(&config.Replay, "replay", "", "Directory of recorded fixtures answering the Nobl9 API calls of the run, without credentials or network access")
+	applyCmd.Flags().IntVar(&config.RetryMaxAttempts, "retry-max-attempts", retry.DefaultMaxAttempts, "How many times a user lookup, apply or organization call is attempted before its error is reported")
+	applyCmd.Flags().DurationVar(&config.RetryBaseDelay, "retry-base-delay", 0, "Delay before the first retry, doubling after each attempt (0 = the delay of each retry profile)")
+	applyCmd.Flags().StringVar(&config.RetryPolicies, "retry-policies", retry.DefaultProfiles, "Comma separated operation=profile pairs choosing how users, apply and organization calls are retried; profiles are api, network, rate-limit and none")
+	applyCmd.Flags().DurationVar(&config.Timeout, "timeout", defaultRunTimeout, "How long the whole run may take before it is aborted (0 = no limit)")
+	applyCmd.Flags().DurationVar(&config.ApplyTimeout, "apply-timeout", 0, "How long applying the objects may take before the run is aborted (0 = only the run timeout)")
+	applyCmd.Flags().StringVar(&config.CheckpointFile, "checkpoint-file", checkpoint.DefaultFile, "JSON file recording each file whose objects were all applied, kept when the run does not complete; empty records none")
+	applyCmd.Flags().BoolVar(&config.Resume, "resume", false, "Skip the files the checkpoint file records as applied by an interrupted or failed run, unless their objects changed since")
+	applyCmd.Flags().StringVar(&config.ApplyGranularity, "apply-granularity", planner.GranularityFile, "How apply calls are grouped: file, or project or object to apply each on its own and continue past failures")
+	applyCmd.Flags().BoolVar(&config.RollbackOnFailure, "rollback-on-failure", false, "Restore the objects the run applied to their previous definitions, and delete the ones it created, when a critical error aborts the run")
+	applyCmd.Flags().BoolVar(&config.ReapplyUnchanged, "reapply-unchanged", false, "Apply objects that match their live definition, e.g. to repair changes made in Nobl9")
+	applyCmd.Flags().StringVar(&config.SlackWebhookURL, "slack-webhook-url", "", "Slack incoming webhook the run summary is posted to")
+	applyCmd.Flags().StringVar(&config.NotifyURL, "notify-url", "", "HTTP endpoint the run summary is posted to as JSON")
+	applyCmd.Flags().StringVar(&config.NotifyOn, "notify-on", notify.OnAlways, "Which runs are posted: always, or failure for failed runs only")
+	applyCmd.Flags().StringVar(&config.PushgatewayURL, "pushgateway-url", "", "Prometheus Pushgateway the run metrics (objects, API calls and latency, retries, failures) are pushed to")
+	applyCmd.Flags().StringVar(&config.MetricsJob, "metrics-job", metrics.DefaultJob, "Pushgateway job the run metrics are pushed under")
+	applyCmd.Flags().StringVar(&config.ResultsFile, "results-file", "", "JSON file to write the complete run results to (per-file and per-object status, errors, durations)")
+	applyCmd.Flags().StringVar(&config.ProgressFile, "progress-file", "", "JSON file rewritten every progress-interval with the files done so far, for steps monitoring a long run or reading a cancelled one")
+	applyCmd.Flags().DurationVar(&config.ProgressInterval, "progress-interval", progress.DefaultInterval, "How often the progress file is written while the run goes on (0 = only at the end)")
+	applyCmd.Flags().StringVar(&config.AllowedBranches, "allowed-branches", "", "Comma separated branches (or glob patterns) allowed to apply, e.g. main,release/*; empty allows any branch")
+	applyCmd.Flags().StringVar(&config.AllowedEvents, "allowed-events", "push", "Comma separated GitHub events allowed to apply when allowed-branches is set")
+	applyCmd.Flags().StringVar(&config.RequirePlanHash, "require-plan-hash", "", "Only apply when the plan hash matches this one, e.g. the plan-hash output of the approved plan run")
+	applyCmd.Flags().StringVar(&config.OwnerLabel, "owner-label", ownership.DefaultLabel, "key=value label set on applied objects to mark them as managed by the action; empty sets none")
+	applyCmd.Flags().BoolVar(&config.TraceAnnotations, "trace-annotations", true, "Annotate applied objects with the repository, commit SHA and file they were applied from")
+	applyCmd.Flags().BoolVar(&config.AuditAnnotations, "audit-annotations", false, "Annotate applied objects with the github.com/commit, github.com/author and github.com/workflow-run of the change")
+	applyCmd.Flags().StringVar(&config.AuditLog, "audit-log", "", "Append-only JSON lines file recording every object created, updated or deleted, with hashes before and after")
+	applyCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	applyCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Drift command flags
+	driftCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
+	driftCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
+	driftCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	driftCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	driftCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files to check instead of the repository's YAML files")
+	driftCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	driftCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	driftCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	driftCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	driftCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	driftCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	driftCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	driftCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	driftCmd.Flags().StringVar(&config.Output, "output", "text", "Report format (text, json)")
+	driftCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "Markdown file to write the drift report to")
+	driftCmd.Flags().StringVar(&config.IgnoreFields, "ignore-fields", "", "Comma separated [kind:]path fields to ignore besides the ones Nobl9 sets, e.g. slo:spec.objectives[*].rawMetric,..lastUpdated")
+	driftCmd.Flags().StringVar(&config.OwnerLabel, "owner-label", ownership.DefaultLabel, "key=value ownership label set when applying, ignored like the trace annotations")
+	driftCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	driftCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Rename project command flags
+	renameProjectCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID, required to plan and run the live migration")
+	renameProjectCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret, required to plan and run the live migration")
+	renameProjectCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	renameProjectCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	renameProjectCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	renameProjectCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+	renameProjectCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the plan without rewriting manifests")
+	renameProjectCmd.Flags().BoolVar(&config.Execute, "execute", false, "Run the live migration: create the new project, copy the objects and delete the old project")
+	renameProjectCmd.Flags().BoolVar(&config.Yes, "yes", false, "Run the live migration without asking for confirmation")
+	renameProjectCmd.MarkFlagsMutuallyExclusive("dry-run", "execute")
+
+	// Rollback command flags
+	rollbackCmd.Flags().StringVar(&config.RollbackFrom, "from", "", "Results file of the run to roll back, written by --results-file (required)")
+	rollbackCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID, required unless --dry-run")
+	rollbackCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret, required unless --dry-run")
+	rollbackCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "List the rollback steps without changing Nobl9 or the results file")
+	rollbackCmd.Flags().BoolVar(&config.AutoApprove, "auto-approve", false, "Delete the objects the run created without asking for confirmation; required when not running in a terminal")
+	rollbackCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rollbackCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Report history command flags
+	reportHistoryCmd.Flags().StringVar(&config.StateFile, "state-file", "", "State file whose run history is reported (required)")
+	reportHistoryCmd.Flags().IntVar(&config.HistoryRuns, "runs", history.DefaultWindow, "Number of recent runs to report, compared with the same number of runs before them")
+	reportHistoryCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "Markdown file to write the report to")
+	reportHistoryCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	reportHistoryCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Diff command flags
+	diffCmd.Flags().StringVar(&config.DiffBase, "base", "", "Git ref (branch, tag or commit) to compare against; required unless --base-path")
+	diffCmd.Flags().StringVar(&config.DiffHead, "head", "", "Git ref whose changes are reported (default HEAD unless --head-path)")
+	diffCmd.Flags().StringVar(&config.DiffBasePath, "base-path", "", "Directory whose manifests are the base, instead of a git ref")
+	diffCmd.Flags().StringVar(&config.DiffHeadPath, "head-path", "", "Directory whose manifests are the head, instead of a git ref")
+	diffCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	diffCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	diffCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	diffCmd.Flags().BoolVar(&config.GenerateRoleBindingNames, "generate-role-binding-names", false, "Name role bindings that omit metadata.name after their project, role and a hash of the grant, so re-applies update the same role binding")
+	diffCmd.Flags().BoolVar(&config.MigrateFields, "migrate-fields", false, "Rewrite fields the n9 API renamed, such as SLO spec.thresholds, to their new name instead of failing the file")
+	diffCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	diffCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Convert the approvers and reviewers of the OWNERS file in each project directory into role bindings of the project named after the directory")
+	diffCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	diffCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	diffCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	diffCmd.Flags().StringVar(&config.Output, "output", "text", "Report format (text, json)")
+	diffCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "Markdown file to write the diff report to")
+	diffCmd.Flags().StringVar(&config.IgnoreFields, "ignore-fields", "", "Comma separated [kind:]path fields to ignore besides the ones Nobl9 sets, e.g. slo:spec.objectives[*].rawMetric,metadata.annotations")
+	diffCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	diffCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Report access command flags
+	reportAccessCmd.Flags().StringVar(&config.AccessSource, "source", string(access.SourceRepo), "Role bindings to report: repo, live (Nobl9) or both, side by side with their differences")
+	reportAccessCmd.Flags().StringVar(&config.AccessProjects, "project", "", "Comma separated project names or glob patterns to report, e.g. payments-*; all projects and the organization by default")
+	reportAccessCmd.Flags().StringVar(&config.AccessFormat, "output", "markdown", "Report format (markdown, csv, json)")
+	reportAccessCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "File to write the report to")
+	reportAccessCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID, required for the live and both sources")
+	reportAccessCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret, required for the live and both sources")
+	reportAccessCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	reportAccessCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	reportAccessCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files to report instead of the repository's YAML files")
+	reportAccessCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	reportAccessCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	reportAccessCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Report the approvers and reviewers of the OWNERS file in each project directory too")
+	reportAccessCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; a user on several lists gets the role of the first")
+	reportAccessCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	reportAccessCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	reportAccessCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	reportAccessCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Fmt command flags
+	fmtCmd.Flags().BoolVar(&config.FmtCheck, "check", false, "List the manifests that are not formatted and fail instead of rewriting them")
+	fmtCmd.Flags().BoolVar(&config.FmtFix, "fix", false, "Also remove the users listed more than once in a role binding's spec.users")
+	fmtCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	fmtCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML files")
+	fmtCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	fmtCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Init command flags
+	initCmd.Flags().StringVar(&config.ScaffoldOwners, "owners", "", "Comma separated emails given the project-owner role (default a placeholder to replace)")
+	initCmd.Flags().StringVar(&config.ScaffoldTeam, "team", "", "Team owning the files, written to their ActionMeta document and team label")
+	initCmd.Flags().StringVar(&config.ScaffoldDisplayName, "display-name", "", "Display name of the project (default the project name)")
+	initCmd.Flags().StringVar(&config.ScaffoldDescription, "description", "", "Description of the project (default \"<display name> project\")")
+	initCmd.Flags().StringVar(&config.ScaffoldService, "service", "", "Service covered by the example SLO (default the project name)")
+	initCmd.Flags().StringVar(&config.Kinds, "kinds", "all", "Comma separated kinds to write manifests for: project, rolebinding, service, slo")
+	initCmd.Flags().StringVar(&config.ScaffoldOutputDir, "output-dir", ".", "Directory the <project> directory of manifests is written to")
+	initCmd.Flags().BoolVar(&config.Overwrite, "overwrite", false, "Replace existing manifests that differ from the scaffolded ones")
+	initCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	initCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Report SLOs command flags
+	reportSLOsCmd.Flags().BoolVar(&config.InventoryLive, "live", false, "Inventory the SLOs in Nobl9 instead of the repository's manifests")
+	reportSLOsCmd.Flags().StringVar(&config.InventoryFormat, "output", "markdown", "Report format (markdown, json)")
+	reportSLOsCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "File to write the inventory to")
+	reportSLOsCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID, required with --live")
+	reportSLOsCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret, required with --live")
+	reportSLOsCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	reportSLOsCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	reportSLOsCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	reportSLOsCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	reportSLOsCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	reportSLOsCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	reportSLOsCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	reportSLOsCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Users audit command flags
+	usersAuditCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
+	usersAuditCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
+	usersAuditCmd.Flags().StringVar(&config.RepoPath, "repo-path", ".", "Repository path to scan for YAML files")
+	usersAuditCmd.Flags().StringVar(&config.FilePattern, "file-pattern", "**/*.yaml", "File pattern to match Nobl9 YAML and JSON files")
+	usersAuditCmd.Flags().StringVar(&config.CSV, "csv", "", "Comma separated project,email,role CSV files to audit instead of the repository's YAML files")
+	usersAuditCmd.Flags().StringVar(&config.Environment, "environment", "", "Environment, e.g. staging, whose ActionMeta environments overlay specializes each file; empty uses the base manifests")
+	usersAuditCmd.Flags().StringVar(&config.Render, "render", "", "Render the .jsonnet or .cue files matching the file pattern into manifests before parsing: jsonnet or cue")
+	usersAuditCmd.Flags().BoolVar(&config.OwnersFiles, "owners-files", false, "Audit the approvers and reviewers of the OWNERS file in each project directory too")
+	usersAuditCmd.Flags().StringVar(&config.OwnersRoles, "owners-roles", nobl9client.DefaultOwnersRoles, "Comma separated list=role mapping of OWNERS file lists to project roles; only the mapped lists are audited")
+	usersAuditCmd.Flags().StringArrayVar(&config.Vars, "vars", nil, "KEY=value variable substituted for ${KEY} and {{ .Env.KEY }} in manifests, before environment variables; repeat it or give one pair per line")
+	usersAuditCmd.Flags().StringSliceVar(&config.Values, "values", nil, "Comma separated YAML values files whose keys are substituted for {{ .Values.key }} in manifests; later files override earlier ones")
+	usersAuditCmd.Flags().StringVar(&config.OktaOrg, "okta-org", "", "Okta org (e.g. acme or acme.okta.com) whose users the role binding emails are also checked against")
+	usersAuditCmd.Flags().StringVar(&config.OktaToken, "okta-token", "", "Okta API token used to look up the status of role binding users")
+	usersAuditCmd.Flags().StringVar(&config.ReportFile, "report-file", "", "Markdown file to write the audit report to")
+	usersAuditCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	usersAuditCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Inputs verify command flags
+	inputsVerifyCmd.Flags().StringVar(&config.ActionFile, "action-file", "action.yml", "action.yml whose inputs are compared with the command flags")
+
+	// Compare orgs command flags
+	compareOrgsCmd.Flags().StringVar(&config.SourceClientID, "source-client-id", "", "Nobl9 API client ID of the source organization (required)")
+	compareOrgsCmd.Flags().StringVar(&config.SourceClientSecret, "source-client-secret", "", "Nobl9 API client secret of the source organization (required)")
+	compareOrgsCmd.Flags().StringVar(&config.TargetClientID, "target-client-id", "", "Nobl9 API client ID of the target organization (required)")
+	compareOrgsCmd.Flags().StringVar(&config.TargetClientSecret, "target-client-secret", "", "Nobl9 API client secret of the target organization (required)")
+	compareOrgsCmd.Flags().StringVar(&config.CompareKinds, "kinds", "project,rolebinding,slo", "Comma separated object kinds to compare")
+	compareOrgsCmd.Flags().StringVar(&config.Output, "output", "text", "Report format (text, json)")
+	compareOrgsCmd.Flags().BoolVar(&config.FailOnDiff, "fail-on-diff", false, "Exit with an error when the organizations differ")
+	compareOrgsCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	compareOrgsCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Promote command flags
+	promoteCmd.Flags().StringVar(&config.PromoteFrom, "from", "", "Environment to promote from, e.g. staging (required)")
+	promoteCmd.Flags().StringVar(&config.PromoteTo, "to", "", "Environment to promote to, e.g. prod (required)")
+	promoteCmd.Flags().StringVar(&config.SourceClientID, "source-client-id", "", "Nobl9 API client ID of the --from organization (default $NOBL9_<FROM>_CLIENT_ID)")
+	promoteCmd.Flags().StringVar(&config.SourceClientSecret, "source-client-secret", "", "Nobl9 API client secret of the --from organization (default $NOBL9_<FROM>_CLIENT_SECRET)")
+	promoteCmd.Flags().StringVar(&config.TargetClientID, "target-client-id", "", "Nobl9 API client ID of the --to organization (default $NOBL9_<TO>_CLIENT_ID)")
+	promoteCmd.Flags().StringVar(&config.TargetClientSecret, "target-client-secret", "", "Nobl9 API client secret of the --to organization (default $NOBL9_<TO>_CLIENT_SECRET)")
+	promoteCmd.Flags().StringVar(&config.PromoteProjects, "projects", "", "Comma separated project names or glob patterns to promote, e.g. payments-* (required)")
+	promoteCmd.Flags().StringVar(&config.PromoteKinds, "kinds", "service,alertpolicy,slo", "Comma separated project-scoped kinds to promote with each project")
+	promoteCmd.Flags().StringVar(&config.Transforms, "transforms", "", "YAML file of project name prefixes and data source swaps applied on the way")
+	promoteCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the plan without applying it")
+	promoteCmd.Flags().BoolVar(&config.Yes, "yes", false, "Apply the plan without asking for confirmation")
+	promoteCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	promoteCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Generate command flags
+	generateCmd.Flags().StringVar(&config.GenerateInput, "input", "", "YAML or CSV file of teams with their owners and environments (required)")
+	generateCmd.Flags().StringVar(&config.Templates, "templates", "", "Directory of *.tmpl Go templates used instead of the built-in ones")
+	generateCmd.Flags().StringVar(&config.OutputDir, "output-dir", "", "Directory to write <team>.yaml manifests to instead of printing them")
+	generateCmd.Flags().BoolVar(&config.Overwrite, "overwrite", false, "Replace existing manifests in --output-dir that differ from the generated ones")
+	generateCmd.Flags().BoolVar(&config.Apply, "apply", false, "Apply the generated manifests to Nobl9")
+	generateCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "With --apply, log what would be applied without making changes")
+	generateCmd.Flags().StringVar(&config.OwnerLabel, "owner-label", ownership.DefaultLabel, "key=value label set on applied objects to mark them as managed by the action; empty sets none")
+	generateCmd.Flags().BoolVar(&config.TraceAnnotations, "trace-annotations", true, "Annotate applied objects with the repository, commit SHA and file they were applied from")
+	generateCmd.Flags().BoolVar(&config.AuditAnnotations, "audit-annotations", false, "Annotate applied objects with the github.com/commit, github.com/author and github.com/workflow-run of the change")
+	generateCmd.Flags().StringVar(&config.AuditLog, "audit-log", "", "Append-only JSON lines file recording every object created, updated or deleted, with hashes before and after")
+	generateCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required by --apply)")
+	generateCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required by --apply)")
+	generateCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	generateCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Export command flags
+	exportCmd.Flags().StringVar(&config.ClientID, "client-id", "", "Nobl9 API client ID (required)")
+	exportCmd.Flags().StringVar(&config.ClientSecret, "client-secret", "", "Nobl9 API client secret (required)")
+	exportCmd.Flags().StringVar(&config.ExportProjects, "project", "", "Comma separated project names or glob patterns to export, e.g. payments-*; all projects by default")
+	exportCmd.Flags().StringVar(&config.ExportKinds, "kinds", "service,alertpolicy,slo,rolebinding", "Comma separated project-scoped kinds to export with each project")
+	exportCmd.Flags().StringVar(&config.OutputDir, "out", "", "Directory to write <project>.yaml manifests to (required)")
+	exportCmd.Flags().StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	exportCmd.Flags().StringVar(&config.LogFormat, "log-format", "json", "Log format (json, text)")
+
+	// Group flags in help output
+	setFlagGroup(processCmd.Flags(), flagGroupCredentials, "client-id", "client-secret", "organizations", "github-token")
+	setFlagGroup(processCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(processCmd.Flags(), flagGroupProcessing, "dry-run", "server-dry-run", "diff-file", "force", "kinds", "project", "skip-kinds", "skip-objects", "user-cache-file", "user-cache-ttl", "max-rps", "breaker-threshold", "breaker-cooldown", "call-timeout", "record", "replay", "retry-max-attempts", "retry-base-delay", "retry-policies", "timeout", "scan-timeout", "resolve-timeout", "apply-timeout", "checkpoint-file", "resume", "apply-granularity", "rollback-on-failure", "results-file", "unresolved-users-file", "progress-file", "progress-interval", "state-file", "reapply-unchanged", "prune", "delete-grace", "auto-approve", "history-size", "owner-label", "trace-annotations", "audit-annotations", "audit-log")
+	setFlagGroup(processCmd.Flags(), flagGroupPolicy, "policy", "rego-policy", "role-catalog", "budget-shrink-threshold", "allowed-branches", "allowed-events", "require-plan-hash")
+	setFlagGroup(processCmd.Flags(), flagGroupEmail, "email-lowercase", "email-strip-plus", "email-domain-aliases", "resolve-paths", "on-unresolved-user", "unresolved-user-group", "github-emails")
+	setFlagGroup(processCmd.Flags(), flagGroupOkta, "okta-org", "okta-token")
+	setFlagGroup(processCmd.Flags(), flagGroupNotify, "slack-webhook-url", "notify-url", "notify-on", "pushgateway-url", "metrics-job")
+	setFlagGroup(processCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(validateCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(validateCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(validateCmd.Flags(), flagGroupProcessing, "remote", "check-recipients")
+	setFlagGroup(validateCmd.Flags(), flagGroupPolicy, "policy", "rego-policy", "role-catalog", "tests-dir")
+	setFlagGroup(validateCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(testCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(testCmd.Flags(), flagGroupProcessing, "tests-dir", "output")
+	setFlagGroup(testCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(planCmd.Flags(), flagGroupCredentials, "client-id", "client-secret", "github-token")
+	setFlagGroup(planCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(planCmd.Flags(), flagGroupProcessing, "out", "diff-file", "kinds", "project", "skip-kinds", "skip-objects", "server-dry-run", "user-cache-file", "user-cache-ttl", "max-rps", "breaker-threshold", "breaker-cooldown", "call-timeout", "record", "replay", "retry-max-attempts", "retry-base-delay", "retry-policies", "timeout", "scan-timeout", "resolve-timeout", "results-file", "unresolved-users-file", "progress-file", "progress-interval")
+	setFlagGroup(planCmd.Flags(), flagGroupPolicy, "policy", "rego-policy", "role-catalog", "budget-shrink-threshold")
+	setFlagGroup(planCmd.Flags(), flagGroupEmail, "email-lowercase", "email-strip-plus", "email-domain-aliases", "resolve-paths", "on-unresolved-user", "unresolved-user-group", "github-emails")
+	setFlagGroup(planCmd.Flags(), flagGroupOkta, "okta-org", "okta-token")
+	setFlagGroup(planCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(applyCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(applyCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "render", "owners-files")
+	setFlagGroup(applyCmd.Flags(), flagGroupProcessing, "plan", "max-rps", "breaker-threshold", "breaker-cooldown", "call-timeout", "record", "replay", "retry-max-attempts", "retry-base-delay", "retry-policies", "timeout", "apply-timeout", "checkpoint-file", "resume", "apply-granularity", "rollback-on-failure", "reapply-unchanged", "results-file", "progress-file", "progress-interval", "owner-label", "trace-annotations", "audit-annotations", "audit-log")
+	setFlagGroup(applyCmd.Flags(), flagGroupPolicy, "allowed-branches", "allowed-events", "require-plan-hash")
+	setFlagGroup(applyCmd.Flags(), flagGroupNotify, "slack-webhook-url", "notify-url", "notify-on", "pushgateway-url", "metrics-job")
+	setFlagGroup(applyCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(driftCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(driftCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(driftCmd.Flags(), flagGroupProcessing, "output", "report-file", "ignore-fields", "owner-label")
+	setFlagGroup(driftCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(renameProjectCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(renameProjectCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern")
+	setFlagGroup(renameProjectCmd.Flags(), flagGroupProcessing, "dry-run", "execute", "yes")
+	setFlagGroup(renameProjectCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(rollbackCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(rollbackCmd.Flags(), flagGroupProcessing, "from", "dry-run", "auto-approve")
+	setFlagGroup(rollbackCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(compareOrgsCmd.Flags(), flagGroupCredentials, "source-client-id", "source-client-secret", "target-client-id", "target-client-secret")
+	setFlagGroup(compareOrgsCmd.Flags(), flagGroupProcessing, "kinds", "output", "fail-on-diff")
+	setFlagGroup(compareOrgsCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(promoteCmd.Flags(), flagGroupCredentials, "source-client-id", "source-client-secret", "target-client-id", "target-client-secret")
+	setFlagGroup(promoteCmd.Flags(), flagGroupProcessing, "from", "to", "projects", "kinds", "transforms", "dry-run", "yes")
+	setFlagGroup(promoteCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(generateCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(generateCmd.Flags(), flagGroupProcessing, "input", "templates", "output-dir", "overwrite", "apply", "dry-run", "owner-label", "trace-annotations", "audit-annotations", "audit-log")
+	setFlagGroup(generateCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(exportCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(exportCmd.Flags(), flagGroupProcessing, "project", "kinds", "out")
+	setFlagGroup(exportCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(reportHistoryCmd.Flags(), flagGroupProcessing, "state-file", "runs", "report-file")
+	setFlagGroup(reportHistoryCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(diffCmd.Flags(), flagGroupRepository, "base", "head", "base-path", "head-path", "repo-path", "file-pattern", "environment", "vars", "values", "generate-role-binding-names", "migrate-fields", "render", "owners-files", "owners-roles")
+	setFlagGroup(diffCmd.Flags(), flagGroupProcessing, "output", "report-file", "ignore-fields")
+	setFlagGroup(diffCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(fmtCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern")
+	setFlagGroup(fmtCmd.Flags(), flagGroupProcessing, "check", "fix")
+	setFlagGroup(fmtCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(initCmd.Flags(), flagGroupProcessing, "owners", "team", "display-name", "description", "service", "kinds", "output-dir", "overwrite")
+	setFlagGroup(initCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(reportAccessCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(reportAccessCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "render", "owners-files", "owners-roles")
+	setFlagGroup(reportAccessCmd.Flags(), flagGroupProcessing, "source", "project", "output", "report-file")
+	setFlagGroup(reportAccessCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(reportSLOsCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(reportSLOsCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "environment", "vars", "values", "render")
+	setFlagGroup(reportSLOsCmd.Flags(), flagGroupProcessing, "live", "output", "report-file")
+	setFlagGroup(reportSLOsCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(usersAuditCmd.Flags(), flagGroupCredentials, "client-id", "client-secret")
+	setFlagGroup(usersAuditCmd.Flags(), flagGroupRepository, "repo-path", "file-pattern", "csv", "environment", "vars", "values", "render", "owners-files", "owners-roles")
+	setFlagGroup(usersAuditCmd.Flags(), flagGroupOkta, "okta-org", "okta-token")
+	setFlagGroup(usersAuditCmd.Flags(), flagGroupProcessing, "report-file")
+	setFlagGroup(usersAuditCmd.Flags(), flagGroupLogging, "log-level", "log-format")
+	setFlagGroup(inputsVerifyCmd.Flags(), flagGroupRepository, "action-file")
+
+	// Shell completion for enumerated flag values
+	registerFlagCompletions(processCmd)
+	registerFlagCompletions(validateCmd)
+	registerFlagCompletions(testCmd)
+	registerFlagCompletions(planCmd)
+	registerFlagCompletions(applyCmd)
+	registerFlagCompletions(driftCmd)
+	registerFlagCompletions(diffCmd)
+	registerFlagCompletions(fmtCmd)
+	registerFlagCompletions(initCmd)
+	registerFlagCompletions(renameProjectCmd)
+	registerFlagCompletions(rollbackCmd)
+	registerFlagCompletions(compareOrgsCmd)
+	registerFlagCompletions(promoteCmd)
+	registerFlagCompletions(generateCmd)
+	registerFlagCompletions(exportCmd)
+	registerFlagCompletions(reportAccessCmd)
+	registerFlagCompletions(reportSLOsCmd)
+	registerFlagCompletions(usersAuditCmd)
 
 	// Mark required flags
 	if err := processCmd.MarkFlagRequired("client-id"); err != nil {
@@ -90,6 +931,44 @@ func init() {
 	if err := processCmd.MarkFlagRequired("client-secret"); err != nil {
 		logrus.WithError(err).Fatal("Failed to mark client-secret as required")
 	}
+	for _, name := range []string{"client-id", "client-secret", "out"} {
+		if err := planCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"client-id", "client-secret", "plan"} {
+		if err := applyCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"client-id", "client-secret"} {
+		if err := driftCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"source-client-id", "source-client-secret", "target-client-id", "target-client-secret"} {
+		if err := compareOrgsCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"from", "to", "projects"} {
+		if err := promoteCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"client-id", "client-secret", "out"} {
+		if err := exportCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	for _, name := range []string{"client-id", "client-secret"} {
+		if err := usersAuditCmd.MarkFlagRequired(name); err != nil {
+			logrus.WithError(err).Fatalf("Failed to mark %s as required", name)
+		}
+	}
+	if err := generateCmd.MarkFlagRequired("input"); err != nil {
+		logrus.WithError(err).Fatal("Failed to mark input as required")
+	}
 }
 
 // setupLogging configures the logging system
@@ -113,36 +992,54 @@ func setupLogging() error {
 		return fmt.Errorf("invalid log format: %s", config.LogFormat)
 	}
 
+	// Scrub credentials from every log entry, including wrapped SDK errors
+	logger.InstallRedaction(logrus.StandardLogger())
+	logger.AddSecret(config.ClientSecret, config.OktaToken, config.GitHubToken, config.SourceClientSecret, config.TargetClientSecret, config.SlackWebhookURL, config.NotifyURL, config.PushgatewayURL)
+
+	logAppliedVariables()
 	return nil
 }
 
 // runProcess executes the main processing logic
 func runProcess(cmd *cobra.Command, args []string) error {
+	runStart := time.Now()
 	logrus.Info("Starting Nobl9 GitHub Action processing")
 
 	// Setup logging
 	if err := setupLogging(); err != nil {
-		return fmt.Errorf("failed to setup logging: %w", err)
+		return errors.NewConfigError("failed to setup logging", err)
 	}
 
 	// Validate configuration
 	if err := validateConfig(); err != nil {
-		return fmt.Errorf("configuration validation failed: %w", err)
+		return configError(err)
 	}
+	startMetrics()
+	defer startSARIF()()
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
+	// A server-side dry run changes nothing either
+	if config.ServerDryRun {
+		config.DryRun = true
+	}
 
-	// Step 1: Scan repository for YAML files
-	logrus.WithFields(logrus.Fields{
-		"repo_path":    config.RepoPath,
-		"file_pattern": config.FilePattern,
-	}).Info("Scanning for Nobl9 YAML files")
+	// Refuse to apply from branches or events the provenance policy does not allow
+	if err := checkProvenance(); err != nil {
+		return err
+	}
 
-	files, err := scanFiles(config.RepoPath, config.FilePattern)
+	// Export spans of the run when the OTEL_* environment configures it
+	defer startTracing()()
+
+	// Bound the run by --timeout
+	ctx, cancel := runContext(commandContext(cmd))
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "process", attribute.Bool("dry_run", config.DryRun))
+	defer span.End()
+
+	// Step 1: Scan repository for YAML files, or take the --csv files
+	files, err := inputFiles()
 	if err != nil {
-		return fmt.Errorf("failed to scan files: %w", err)
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
 	}
 
 	if len(files) == 0 {
@@ -152,88 +1049,494 @@ func runProcess(cmd *cobra.Command, args []string) error {
 
 	logrus.WithField("file_count", len(files)).Info("Found YAML files to process")
 
+	// Leave the files done so far behind while the run goes on
+	defer startProgress(ctx, len(files), runStart)()
+
+	// Files are split between organizations when several are configured
+	organizations, _ := loadOrganizations()
+	if organizations != nil {
+		return runOrganizations(ctx, runStart, organizations, files)
+	}
+
+	summary, results, err := processFiles(ctx, runStart, files)
+	if err != nil {
+		return err
+	}
+	return finishRun(summary, results)
+}
+
+// processFiles applies the files to the organization of the configured
+// credentials and returns the summary and results of the run. The error is
+// only set when the run could not be carried out at all; files that failed
+// are reported in the summary and results.
+func processFiles(ctx context.Context, runStart time.Time, files []string) (*runSummary, *runResults, error) {
 	// Step 2: Initialize Nobl9 client
 	nobl9Client, err := createNobl9Client(config.ClientID, config.ClientSecret)
 	if err != nil {
-		return fmt.Errorf("failed to create Nobl9 client: %w", err)
+		return nil, nil, typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+
+	// Initialize Okta client if group expansion is configured
+	groupExpander, err := createGroupExpander()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Okta client: %w", err)
+	}
+	teamExpander, err := createTeamExpander()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	// Load user resolutions persisted by previous runs
+	userCache := resolver.NewUserCache(config.UserCacheTTL)
+	if config.UserCacheFile != "" {
+		loaded, err := userCache.LoadFile(config.UserCacheFile)
+		if err != nil {
+			logrus.WithField("path", config.UserCacheFile).WithError(err).Warn("Failed to load user cache, starting empty")
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"path":    config.UserCacheFile,
+				"entries": loaded,
+			}).Info("Loaded user cache")
+		}
+	}
+
+	// Guardrails are checked after parsing, before anything is sent to Nobl9
+	policies, err := loadGuardrails(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Only objects of the selected kinds are applied; validateConfig already checked the list
+	kinds, _ := nobl9client.ParseKindFilter(config.Kinds)
+	logrus.WithField("kinds", kinds.String()).Debug("Selected object kinds")
+
+	// Objects of the skip list are left out, without editing the repository
+	skip, _ := skiplist.Parse(config.SkipKinds, config.SkipObjects)
+	if skip != nil {
+		logrus.WithFields(logrus.Fields{
+			"skip_kinds":   skip.Kinds(),
+			"skip_objects": skip.Objects(),
+		}).Warn("Skipping objects of the skip list; remove them from --skip-kinds and --skip-objects once fixed")
 	}
 
-	// Step 3: Process each file
-	var totalProcessed, totalErrors, projectsCreated, roleBindingsCreated, emailsResolved int
+	// Give each API call its own deadline so a hung connection fails that
+	// call, below the rate limiter so waiting for Retry-After does not count
+	callDeadline := nobl9.DeadlineAPICalls(nobl9Client.HTTP, config.CallTimeout, newLogger())
+
+	// Report each request sent, with its latency, to the run metrics
+	observeAPICalls(nobl9Client)
+
+	// Limit the request rate and wait out 429 responses, below the call
+	// counter so requests sent again after Retry-After are counted once
+	rateLimiter := nobl9.RateLimitAPICalls(nobl9Client.HTTP, retry.NewLimiter(config.MaxRPS), newLogger())
+
+	// Count API calls by endpoint for the final summary
+	apiCalls := nobl9.CountAPICalls(nobl9Client.HTTP)
+
+	// Fail API calls fast while the API keeps failing; calls rejected by the
+	// breaker never reach the call counter
+	circuitBreaker := nobl9.BreakAPICalls(nobl9Client.HTTP, retry.NewBreaker(config.BreakerThreshold, config.BreakerCooldown), newLogger())
+
+	// Trace each logical API call within the span of the file or phase
+	nobl9.TraceAPICalls(nobl9Client.HTTP)
+
+	// Answer repeated reads of the run from memory, above the other
+	// transports so cache hits are not counted or rate limited
+	apiCache := nobl9.CacheAPICalls(nobl9Client.HTTP, 0, newLogger())
+
+	// Objects are read and applied through the Nobl9API, so tests can run
+	// the processor against the in-memory double
+	api := nobl9API(nobl9Client)
 
+	// Step 3: Parse each file and expand Okta group role bindings
+	summary := newRunSummary(len(files), config.DryRun)
+	results := newRunResults(runStart, config.DryRun)
+
+	// Stop scheduling files once a critical error, such as rejected
+	// credentials, would make every remaining one fail as well
+	ctx, abort := abortOnCritical(ctx, results)
+	defer abort(nil)
+
+	// Warn when key settings changed since the run that saved the state
+	var settings map[string]string
+	if config.StateFile != "" {
+		settings = runSettings(ctx, api)
+		summary.SettingChanges = checkSettingsDrift(settings)
+	}
+
+	runProgress.SetPhase(phaseParse)
+	scanCtx, endScan := withPhaseTimeout(ctx, abort, timeoutPhaseScan, config.ScanTimeout)
+	var parsedFiles []*parsedFile
 	for _, filePath := range files {
+		if abortedBy(scanCtx) != nil {
+			summary.FilesAborted++
+			results.addAbortedFile(filePath)
+			continue
+		}
 		logrus.WithField("file", filePath).Info("Processing file")
 
-		result, err := processFile(ctx, nobl9Client, filePath, config.DryRun)
+		start := time.Now()
+		fileCtx, span := tracing.Start(scanCtx, "parse", attribute.String("file.path", filePath))
+		parsed, err := parseFile(fileCtx, groupExpander, teamExpander, filePath)
+		tracing.End(span, err)
 		if err != nil {
 			logrus.WithField("file", filePath).WithError(err).Error("Failed to process file")
-			totalErrors++
+			summary.FilesWithErrors++
+			results.addFailedFile(filePath, phaseParse, err, time.Since(start))
 			continue
 		}
+		parsed.Duration = time.Since(start)
+		parsedFiles = append(parsedFiles, parsed)
+	}
+	endScan()
+	parsedFiles = abortParsedFiles(ctx, parsedFiles, summary, results)
 
-		totalProcessed++
-		projectsCreated += result.ProjectsCreated
-		roleBindingsCreated += result.RoleBindingsCreated
-		emailsResolved += result.EmailsResolved
+	// Skip files meant for other organizations and check ticket requirements
+	parsedFiles = applyFileMeta(ctx, api, parsedFiles, summary, results)
 
-		logrus.WithFields(logrus.Fields{
-			"file":            filePath,
-			"projects":        result.ProjectsCreated,
-			"role_bindings":   result.RoleBindingsCreated,
-			"emails_resolved": result.EmailsResolved,
-		}).Info("File processed successfully")
+	// Keep the objects of the projects selected with --project; validateConfig
+	// already checked the patterns
+	projects, _ := scopedProjects()
+	parsedFiles = scopeToProjects(parsedFiles, projects, summary, results)
+
+	// Only owner teams may change files that declare owners
+	parsedFiles = checkFileOwners(ctx, parsedFiles, summary, results)
+
+	// Files violating the guardrail policy are not applied
+	if policies != nil {
+		runProgress.SetPhase(phasePolicy)
+		violations, err := checkGuardrails(ctx, policies, parsedFiles)
+		if err != nil {
+			return nil, nil, fmt.Errorf("policy check failed: %w", err)
+		}
+		compliant := parsedFiles[:0]
+		for _, parsed := range parsedFiles {
+			if fileViolations := violations[parsed.Path]; len(fileViolations) > 0 {
+				summary.FilesWithErrors++
+				results.addFailedFile(parsed.Path, phasePolicy, policy.Error(fileViolations), parsed.Duration)
+				continue
+			}
+			compliant = append(compliant, parsed)
+		}
+		parsedFiles = compliant
 	}
 
-	// Step 4: Log final summary
-	logrus.WithFields(logrus.Fields{
-		"total_files":           len(files),
-		"files_processed":       totalProcessed,
-		"files_with_errors":     totalErrors,
-		"projects_created":      projectsCreated,
-		"role_bindings_created": roleBindingsCreated,
-		"emails_resolved":       emailsResolved,
-		"dry_run":               config.DryRun,
-	}).Info("Processing completed")
+	// Files granting roles that do not exist in Nobl9 are not applied;
+	// validateConfig already checked the catalog
+	if catalog, _ := loadRoleCatalog(); catalog != nil {
+		unknown, err := checkRoles(ctx, api, catalog, parsedFiles)
+		if err != nil {
+			return nil, nil, fmt.Errorf("role check failed: %w", err)
+		}
+		known := parsedFiles[:0]
+		for _, parsed := range parsedFiles {
+			if fileUnknown := unknown[parsed.Path]; len(fileUnknown) > 0 {
+				for _, u := range fileUnknown {
+					logUnknownRole(parsed.Path, u)
+				}
+				summary.FilesWithErrors++
+				results.addFailedFile(parsed.Path, phasePrepare, roles.Error(fileUnknown), parsed.Duration)
+				continue
+			}
+			known = append(known, parsed)
+		}
+		parsedFiles = known
+	}
+
+	// Step 4: Resolve emails across all files, retrying transient failures once at the end
+	// Normalization rules were checked by validateConfig
+	runProgress.SetPhase(phaseResolve)
+	normalizer, _ := resolver.NewNormalizer(config.EmailLowercase, config.EmailStripPlus, config.EmailDomainAliases)
+	logrus.WithField("resolve_paths", resolutionEligibility().String()).Debug("Selected email resolution paths")
+	emails := collectEmails(parsedFiles)
+	resolveCtx, endResolve := withPhaseTimeout(ctx, abort, timeoutPhaseResolve, config.ResolveTimeout)
+	emailResolutions, resolutionFailures := resolveEmailsWithFailures(resolveCtx, api, userCache, normalizer, emails, results.aggregator)
+	endResolve()
+	summary.EmailsLookedUp, summary.EmailsUnresolved = len(emails), len(emails)-len(emailResolutions)
+	for _, email := range emails {
+		if _, found := emailResolutions[email]; !found {
+			summary.UnresolvedEmails = append(summary.UnresolvedEmails, email)
+		}
+	}
+	reportUnresolvedUsers(unresolvedUsersOf(parsedFiles, emailResolutions, resolutionFailures))
+	if abortedBy(ctx) == nil {
+		if err := handleUnresolvedUsers(parsedFiles, summary.UnresolvedEmails); err != nil {
+			return nil, nil, err
+		}
+	}
+	warnDuplicateGrants(parsedFiles, func(user string) string {
+		if userID, found := emailResolutions[user]; found {
+			return userID
+		}
+		return strings.ToLower(normalizer.Normalize(user))
+	})
+	parsedFiles = abortParsedFiles(ctx, parsedFiles, summary, results)
+
+	// Step 5: Substitute resolved user IDs and validate each file's objects
+	runProgress.SetPhase(phasePrepare)
+	var prepared []*preparedFile
+	for _, parsed := range parsedFiles {
+		start := time.Now()
+		_, span := tracing.Start(ctx, "validate", attribute.String("file.path", parsed.Path), attribute.Int("object.count", len(parsed.Objects)))
+		file, err := prepareFile(parsed, emailResolutions, kinds, skip)
+		tracing.End(span, err)
+		if err != nil {
+			logrus.WithField("file", parsed.Path).WithError(err).Error("Failed to process file")
+			summary.FilesWithErrors++
+			results.addFailedFile(parsed.Path, phasePrepare, err, parsed.Duration+time.Since(start))
+			continue
+		}
+		file.Duration += time.Since(start)
+		prepared = append(prepared, file)
+	}
+
+	// Apply each user+role+project grant once, however many files declare it
+	mergeDuplicateRoleBindings(prepared)
+
+	// Refuse to apply anything but the approved plan
+	hash, err := planHash(prepared)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to hash plan: %w", err)
+	}
+	summary.PlanHash = hash
+	results.PlanHash = hash
+	setGitHubOutput("plan-hash", hash)
+	logrus.WithField("plan_hash", hash).Info("Planned objects to apply")
+	if err := checkPlanHash(hash, config.RequirePlanHash); err != nil && abortedBy(ctx) == nil {
+		return nil, nil, err
+	}
+
+	// Flag SLO changes that may breach the SLO as soon as they are applied
+	summary.HighImpact = checkBudgetImpact(ctx, api, prepared)
+	results.HighImpact = summary.HighImpact
+
+	// Warn reviewers about likely mistakes in SLOs
+	summary.LintWarnings = lintSLOs(prepared)
+	results.LintWarnings = summary.LintWarnings
+
+	// The plan command saves the plan for a later apply instead of applying it
+	if config.PlanOut != "" {
+		if err := savePlan(ctx, api, files, prepared, summary.FilesWithErrors+summary.FilesAborted); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Dry runs can write what they would change as a unified diff
+	if config.DiffFile != "" {
+		if err := writePlanDiff(ctx, api, prepared); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Files the resumed run already applied are not applied again
+	startCheckpoint(runStart)
+	prepared = skipCompletedFiles(prepared, summary, results)
+
+	// Objects the state records as applied with the same content are not
+	// applied again
+	if config.StateFile != "" && !config.ReapplyUnchanged {
+		skipRecordedObjects(prepared)
+	}
+
+	// Step 6: Apply objects across files in dependency order; a server-side
+	// dry run sends them to Nobl9 until it turns out not to support it
+	runProgress.SetPhase(phaseApply)
+	serverCheck := newServerDryRun(config.DryRun && config.ServerDryRun)
+	applyCtx, endApply := withPhaseTimeout(ctx, abort, timeoutPhaseApply, config.ApplyTimeout)
+	err = applyPlanned(applyCtx, api, prepared, config.DryRun, serverCheck, results.aggregator)
+	endApply()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to plan apply order: %w", err)
+	}
+
+	recordApplied(prepared, summary, results)
+
+	// Record managed projects and prune the ones no longer declared, unless
+	// the run was aborted, in which case it may be rolled back instead
+	summary.AbortedBy = abortedBy(ctx)
+	rollbackOnFailure(ctx, api, summary, results)
+	finishCheckpoint(summary)
+	// A run scoped to some projects cannot tell removed projects and objects
+	// from the ones it left out, so it leaves the state alone
+	if config.StateFile != "" && len(projects) > 0 {
+		logrus.WithField("path", config.StateFile).Info("Run is restricted to some projects, leaving the state and pruning untouched")
+	}
+	if config.StateFile != "" && kinds.Allows(manifest.KindProject) && len(projects) == 0 && summary.AbortedBy == nil {
+		runProgress.SetPhase(phaseState)
+		if err := updateState(ctx, api, parsedFiles, prepared, kinds, skip, settings, summary.FilesWithErrors == 0, summary); err != nil {
+			logrus.WithField("path", config.StateFile).WithError(err).Error("Failed to update state")
+			summary.StateErrors++
+			results.addError(phaseState, err)
+		}
+	}
+
+	// Persist user resolutions for the next run
+	if config.UserCacheFile != "" {
+		saved, err := userCache.SaveFile(config.UserCacheFile)
+		if err != nil {
+			logrus.WithField("path", config.UserCacheFile).WithError(err).Warn("Failed to save user cache")
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"path":    config.UserCacheFile,
+				"entries": saved,
+			}).Info("Saved user cache")
+		}
+	}
+
+	// Step 7: Log final summary
+	summary.UserCache = userCache.GetStats()
+	summary.APICalls = apiCalls.Counts()
+	summary.RateLimited, summary.RateLimitWaited = rateLimiter.Throttled()
+	summary.CallTimeouts = callDeadline.Timeouts()
+	summary.Breaker = circuitBreaker.Stats()
+	summary.APICache = apiCache.Stats()
+	summary.ServerDryRun = serverCheck.active()
+
+	// Record the run for the history report
+	recordRunHistory(runStart, summary)
+	return summary, results, nil
+}
+
+// finishRun reports a finished run: it logs the summary with the
+// recommendations for the run, writes the job summary and the results file,
+// and sets the outputs. It returns an error when the run was aborted or any
+// file failed.
+func finishRun(summary *runSummary, results *runResults) error {
+	summary.Recommendations = recommendations(summary, results)
+
+	summary.Skipped.LogWarning()
+	newLogger().LogProcessingComplete(summary.stats())
+	writeJobSummary(summary)
+
+	totalErrors := summary.FilesWithErrors + summary.StateErrors + summary.FilesAborted
+
+	// Write the complete results for downstream steps
+	writeResultsFile(results, summary, totalErrors)
 
 	// Set GitHub Action outputs if running in GitHub Actions
-	setGitHubOutput("processed-files", fmt.Sprintf("%d", totalProcessed))
-	setGitHubOutput("projects-created", fmt.Sprintf("%d", projectsCreated))
-	setGitHubOutput("projects-updated", "0") // Not currently tracked
-	setGitHubOutput("role-bindings-created", fmt.Sprintf("%d", roleBindingsCreated))
-	setGitHubOutput("role-bindings-updated", "0") // Not currently tracked
-	setGitHubOutput("users-resolved", fmt.Sprintf("%d", emailsResolved))
-	setGitHubOutput("errors", fmt.Sprintf("%d", totalErrors))
-	setGitHubOutput("success", fmt.Sprintf("%t", totalErrors == 0))
+	setRunOutputs(summary, totalErrors)
+	notifyRun(summary, results, totalErrors)
+	pushMetrics(summary, results, totalErrors)
 
+	if summary.AbortedBy != nil {
+		return fmt.Errorf("processing %s: %w", abortedReason, summary.AbortedBy)
+	}
 	if totalErrors > 0 {
-		return fmt.Errorf("processing completed with %d errors", totalErrors)
+		// The collected errors are wrapped, so the file processing error
+		// still decides the exit code
+		return errors.NewFileProcessingError(fmt.Sprintf("processing completed with %d errors", totalErrors), results.aggregator.Err())
 	}
 
 	return nil
 }
 
+// recordApplied adds the outcome of every applied file to the summary and
+// the results
+func recordApplied(prepared []*preparedFile, summary *runSummary, results *runResults) {
+	for _, file := range prepared {
+		results.addFile(file)
+
+		if file.Err != nil {
+			logrus.WithField("file", file.Path).WithError(file.Err).Error("Failed to process file")
+			summary.FilesWithErrors++
+			continue
+		}
+		if file.Aborted {
+			logrus.WithField("file", file.Path).Warn("File not applied, the run was aborted")
+			summary.FilesAborted++
+			continue
+		}
+
+		summary.add(file.Result)
+
+		logrus.WithFields(logrus.Fields{
+			"file":            file.Path,
+			"projects":        file.Result.ProjectsCreated,
+			"role_bindings":   file.Result.RoleBindingsCreated,
+			"unchanged":       file.Result.RoleBindingsUnchanged,
+			"merged":          file.Result.RoleBindingsMerged,
+			"objects_by_kind": file.Result.Kinds.String(),
+			"emails_resolved": file.Result.EmailsResolved,
+		}).Info("File processed successfully")
+	}
+}
+
+// writeResultsFile writes the complete results to --results-file, if set
+func writeResultsFile(results *runResults, summary *runSummary, totalErrors int) {
+	if config.ResultsFile == "" {
+		return
+	}
+	results.finish(summary, totalErrors)
+	if err := results.write(config.ResultsFile); err != nil {
+		logrus.WithField("path", config.ResultsFile).WithError(err).Warn("Failed to write results file")
+	} else {
+		logrus.WithField("path", config.ResultsFile).Info("Wrote results file")
+	}
+}
+
+// setRunOutputs sets the GitHub Action outputs of an apply
+func setRunOutputs(summary *runSummary, totalErrors int) {
+	runOutputsSet = true
+	setGitHubOutput("processed-files", fmt.Sprintf("%d", summary.FilesProcessed))
+	setGitHubOutput("projects-created", fmt.Sprintf("%d", summary.ProjectsCreated))
+	setGitHubOutput("projects-updated", "0") // Not currently tracked
+	setGitHubOutput("role-bindings-created", fmt.Sprintf("%d", summary.RoleBindingsCreated))
+	setGitHubOutput("role-bindings-updated", "0") // Not currently tracked
+	setGitHubOutput("role-bindings-unchanged", fmt.Sprintf("%d", summary.RoleBindingsUnchanged))
+	setGitHubOutput("users-resolved", fmt.Sprintf("%d", summary.EmailsResolved))
+	setGitHubOutput("objects-skipped", fmt.Sprintf("%d", summary.Skipped.Total()+summary.ObjectsUnchanged))
+	setGitHubOutput("skipped-kinds", summary.Skipped.String())
+	setGitHubOutput("objects-by-kind", summary.ObjectsByKind.String())
+	setGitHubOutput("api-calls", fmt.Sprintf("%d", summary.apiCallTotal()))
+	setGitHubOutput("projects-pending-delete", fmt.Sprintf("%d", summary.projectsPendingDelete()))
+	setGitHubOutput("projects-deleted", fmt.Sprintf("%d", summary.projectsDeleted()))
+	setGitHubOutput("errors", fmt.Sprintf("%d", totalErrors))
+	// An interrupted run reports the work done before it was cancelled
+	cancelled := isCancelled(summary.AbortedBy)
+	setGitHubOutput("success", fmt.Sprintf("%t", totalErrors == 0 && !cancelled))
+	setGitHubOutput("partial", fmt.Sprintf("%t", cancelled))
+	setGitHubOutput("timed-out-phase", errors.TimedOutPhase(summary.AbortedBy))
+}
+
 // runValidate executes validation logic
 func runValidate(cmd *cobra.Command, args []string) error {
 	logrus.Info("Starting Nobl9 YAML validation")
 
 	// Setup logging
 	if err := setupLogging(); err != nil {
-		return fmt.Errorf("failed to setup logging: %w", err)
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	if config.Remote && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("--remote requires --client-id and --client-secret"))
 	}
+	if config.CheckRecipients && !config.Remote {
+		return configError(fmt.Errorf("--check-recipients requires --remote"))
+	}
+	defer startSARIF()()
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 5*time.Minute)
 	defer cancel()
 
-	// Step 1: Scan repository for YAML files
-	logrus.WithFields(logrus.Fields{
-		"repo_path":    config.RepoPath,
-		"file_pattern": config.FilePattern,
-	}).Info("Scanning for YAML files to validate")
+	policies, err := loadGuardrails(ctx)
+	if err != nil {
+		return configError(fmt.Errorf("invalid policy: %w", err))
+	}
+	if _, err := loadRoleCatalog(); err != nil {
+		return configError(fmt.Errorf("invalid role-catalog: %w", err))
+	}
+	var tests *assertions.Suite
+	if config.TestsDir != "" {
+		if tests, err = assertions.Load(config.TestsDir); err != nil {
+			return configError(fmt.Errorf("invalid tests-dir: %w", err))
+		}
+	}
 
-	files, err := scanFiles(config.RepoPath, config.FilePattern)
+	// Step 1: Scan repository for YAML files, or take the --csv files
+	files, err := inputFiles()
 	if err != nil {
-		return fmt.Errorf("failed to scan files: %w", err)
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
 	}
 
 	if len(files) == 0 {
@@ -245,6 +1548,7 @@ func runValidate(cmd *cobra.Command, args []string) error {
 
 	// Step 2: Validate each file
 	var totalValidated, totalErrors int
+	var validFiles []string
 
 	for _, filePath := range files {
 		logrus.WithField("file", filePath).Info("Validating file")
@@ -255,7 +1559,57 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		} else {
 			logrus.WithField("file", filePath).Info("File validation passed")
 			totalValidated++
+			validFiles = append(validFiles, filePath)
+		}
+	}
+
+	// Check the valid files against the guardrail policy
+	if policies != nil {
+		compliant, err := runPolicyValidation(ctx, policies, validFiles)
+		if err != nil {
+			return typedError(errors.ErrorTypeValidation, "policy validation failed", err)
+		}
+		totalValidated -= len(validFiles) - len(compliant)
+		totalErrors += len(validFiles) - len(compliant)
+		validFiles = compliant
+	}
+
+	// Warn about users granted the same role twice
+	if err := runDuplicateValidation(validFiles); err != nil {
+		return typedError(errors.ErrorTypeValidation, "duplicate check failed", err)
+	}
+
+	// Warn about likely mistakes in SLOs
+	if err := runLintValidation(validFiles); err != nil {
+		return typedError(errors.ErrorTypeValidation, "SLO lint failed", err)
+	}
+
+	// Warn about references to objects no file declares; --remote checks
+	// them against Nobl9 instead
+	if !config.Remote {
+		if err := runReferenceValidation(ctx, validFiles); err != nil {
+			return typedError(errors.ErrorTypeValidation, "reference check failed", err)
+		}
+	}
+
+	// Check the valid files against live Nobl9 state
+	if config.Remote {
+		failed, err := runRemoteValidation(ctx, validFiles)
+		if err != nil {
+			return typedError(errors.ErrorTypeValidation, "remote validation failed", err)
+		}
+		totalValidated -= failed
+		totalErrors += failed
+	}
+
+	// Run the declared tests against the valid files
+	var testsFailed int
+	if tests != nil {
+		report, err := runAssertions(tests, validFiles)
+		if err != nil {
+			return typedError(errors.ErrorTypeValidation, "tests could not run", err)
 		}
+		testsFailed = report.Failed
 	}
 
 	// Step 3: Log validation summary
@@ -267,16 +1621,26 @@ func runValidate(cmd *cobra.Command, args []string) error {
 
 	// Set GitHub Action outputs for validation
 	setGitHubOutput("processed-files", fmt.Sprintf("%d", totalValidated))
-	setGitHubOutput("projects-created", "0") // Validation mode
-	setGitHubOutput("projects-updated", "0") // Validation mode
-	setGitHubOutput("role-bindings-created", "0") // Validation mode
-	setGitHubOutput("role-bindings-updated", "0") // Validation mode
-	setGitHubOutput("users-resolved", "0") // Validation mode
+	setGitHubOutput("projects-created", "0")        // Validation mode
+	setGitHubOutput("projects-updated", "0")        // Validation mode
+	setGitHubOutput("role-bindings-created", "0")   // Validation mode
+	setGitHubOutput("role-bindings-updated", "0")   // Validation mode
+	setGitHubOutput("role-bindings-unchanged", "0") // Validation mode
+	setGitHubOutput("users-resolved", "0")          // Validation mode
+	setGitHubOutput("objects-skipped", "0")         // Validation mode
+	setGitHubOutput("skipped-kinds", "")            // Validation mode
+	setGitHubOutput("objects-by-kind", "")          // Validation mode
+	setGitHubOutput("api-calls", "0")               // Validation mode
+	setGitHubOutput("projects-pending-delete", "0") // Validation mode
+	setGitHubOutput("projects-deleted", "0")        // Validation mode
 	setGitHubOutput("errors", fmt.Sprintf("%d", totalErrors))
 	setGitHubOutput("success", fmt.Sprintf("%t", totalErrors == 0))
 
 	if totalErrors > 0 {
-		return fmt.Errorf("validation completed with %d errors", totalErrors)
+		return errors.NewValidationError(fmt.Sprintf("validation completed with %d errors", totalErrors), nil)
+	}
+	if testsFailed > 0 {
+		return errors.NewValidationError(fmt.Sprintf("%d tests failed", testsFailed), nil)
 	}
 
 	return nil
@@ -284,23 +1648,176 @@ func runValidate(cmd *cobra.Command, args []string) error {
 
 // validateConfig validates the application configuration
 func validateConfig() error {
-	if config.ClientID == "" {
+	// Organizations bring their own credentials
+	orgs, err := loadOrganizations()
+	if err != nil {
+		return fmt.Errorf("invalid organizations: %w", err)
+	}
+	// Replayed runs need no credentials
+	if orgs == nil && config.ClientID == "" && config.Replay == "" {
 		return fmt.Errorf("client-id is required")
 	}
-	if config.ClientSecret == "" {
+	if orgs == nil && config.ClientSecret == "" && config.Replay == "" {
 		return fmt.Errorf("client-secret is required")
 	}
+	if config.Record != "" && config.Replay != "" {
+		return fmt.Errorf("record and replay cannot be combined")
+	}
+	if orgs != nil && (config.Record != "" || config.Replay != "") {
+		return fmt.Errorf("record and replay cannot be combined with organizations: fixtures cover a single organization")
+	}
+	if orgs != nil && config.RequirePlanHash != "" {
+		return fmt.Errorf("require-plan-hash cannot be combined with organizations: approve the plan of each organization in its own run")
+	}
+	if orgs != nil && (config.PlanOut != "" || config.PlanFile != "") {
+		return fmt.Errorf("plan files cannot be combined with organizations: a plan file is applied to a single organization")
+	}
+	if orgs != nil && config.DiffFile != "" {
+		return fmt.Errorf("diff-file cannot be combined with organizations: the diff covers a single organization")
+	}
+	if config.DiffFile != "" && !config.DryRun && !config.ServerDryRun {
+		return fmt.Errorf("diff-file requires dry-run: applied changes are not diffed")
+	}
 	if config.RepoPath == "" {
 		return fmt.Errorf("repo-path cannot be empty")
 	}
+	if _, err := parseManifestVars(); err != nil {
+		return fmt.Errorf("invalid vars: %w", err)
+	}
+	if (config.OktaOrg == "") != (config.OktaToken == "") {
+		return fmt.Errorf("okta-org and okta-token must be provided together")
+	}
+	if config.UserCacheTTL <= 0 {
+		return fmt.Errorf("user-cache-ttl must be positive")
+	}
+	if _, err := resolver.ParseDomainAliases(config.EmailDomainAliases); err != nil {
+		return fmt.Errorf("invalid email-domain-aliases: %w", err)
+	}
+	if err := checkUnresolvedUserPolicy(); err != nil {
+		return err
+	}
+	if _, err := owners.ParseLoginEmails(config.GitHubEmails); err != nil {
+		return fmt.Errorf("invalid github-emails: %w", err)
+	}
+	if _, err := resolver.ParseEligibility(config.ResolvePaths); err != nil {
+		return fmt.Errorf("invalid resolve-paths: %w", err)
+	}
+	if _, err := nobl9client.ParseKindFilter(config.Kinds); err != nil {
+		return fmt.Errorf("invalid kinds: %w", err)
+	}
+	if _, err := skiplist.Parse(config.SkipKinds, config.SkipObjects); err != nil {
+		return fmt.Errorf("invalid skip list: %w", err)
+	}
+	if _, err := scopedProjects(); err != nil {
+		return fmt.Errorf("invalid project: %w", err)
+	}
+	if config.MaxRPS < 0 {
+		return fmt.Errorf("max-rps cannot be negative")
+	}
+	if config.BreakerThreshold < 0 {
+		return fmt.Errorf("breaker-threshold cannot be negative")
+	}
+	if config.BreakerThreshold > 0 && config.BreakerCooldown <= 0 {
+		return fmt.Errorf("breaker-cooldown must be positive")
+	}
+	if config.CallTimeout < 0 {
+		return fmt.Errorf("call-timeout cannot be negative")
+	}
+	if _, err := retry.ParseProfiles(config.RetryPolicies, config.RetryMaxAttempts, config.RetryBaseDelay); err != nil {
+		return fmt.Errorf("invalid retry settings: %w", err)
+	}
+	if err := checkTimeouts(); err != nil {
+		return err
+	}
+	if config.Resume && config.CheckpointFile == "" {
+		return fmt.Errorf("--resume requires --checkpoint-file")
+	}
+	if _, err := planner.ParseGranularity(config.ApplyGranularity); err != nil {
+		return fmt.Errorf("invalid apply-granularity: %w", err)
+	}
+	if _, err := newNotifier(); err != nil {
+		return err
+	}
+	if err := checkPushgatewayURL(config.PushgatewayURL); err != nil {
+		return err
+	}
+	if config.Prune && config.StateFile == "" {
+		return fmt.Errorf("prune requires state-file")
+	}
+	if config.CSV != "" && config.StateFile != "" {
+		return fmt.Errorf("csv cannot be combined with state-file: CSV files declare no projects, so every managed project would look removed")
+	}
+	if _, err := state.ParseDuration(config.DeleteGrace); err != nil {
+		return fmt.Errorf("invalid delete-grace: %w", err)
+	}
+	if config.HistorySize < 0 {
+		return fmt.Errorf("history-size cannot be negative")
+	}
+	if config.ProgressInterval < 0 {
+		return fmt.Errorf("progress-interval cannot be negative")
+	}
+	if _, err := ownership.New(config.OwnerLabel, config.TraceAnnotations, "", ""); err != nil {
+		return fmt.Errorf("invalid owner-label: %w", err)
+	}
+	if _, err := provenance.NewPolicy(config.AllowedBranches, config.AllowedEvents); err != nil {
+		return fmt.Errorf("invalid allowed-branches: %w", err)
+	}
+	if config.Policy != "" {
+		if _, err := policy.Load(config.Policy); err != nil {
+			return fmt.Errorf("invalid policy: %w", err)
+		}
+	}
+	if config.RegoPolicy != "" {
+		if _, err := policy.LoadRego(context.Background(), commalist.Split(config.RegoPolicy)); err != nil {
+			return fmt.Errorf("invalid rego-policy: %w", err)
+		}
+	}
+	if config.RoleCatalog != "" {
+		if _, err := roles.Load(config.RoleCatalog); err != nil {
+			return fmt.Errorf("invalid role-catalog: %w", err)
+		}
+	}
+	if err := impact.ValidateThreshold(config.BudgetShrinkThreshold); err != nil {
+		return fmt.Errorf("invalid budget-shrink-threshold: %w", err)
+	}
+
+	return nil
+}
+
+// checkProvenance enforces the allowed branches and events. Dry runs only
+// warn, so pull requests from other branches can still preview changes.
+func checkProvenance() error {
+	policy, _ := provenance.NewPolicy(config.AllowedBranches, config.AllowedEvents)
+	if !policy.Enabled() {
+		return nil
+	}
+
+	source := provenance.SourceFromEnv()
+	if err := policy.Check(source); err != nil {
+		if config.DryRun {
+			logrus.WithError(err).Warn("Provenance check failed, continuing because this is a dry run")
+			return nil
+		}
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"ref":   source.Ref,
+		"event": source.EventName,
+	}).Info("Provenance check passed")
 
 	return nil
 }
 
 // main function with proper error handling and exit codes
 func main() {
-	// Execute root command
-	if err := rootCmd.Execute(); err != nil {
+	// Execute root command; SIGINT and SIGTERM cancel its context, so an
+	// interrupted run still reports what it did (see handleSignals)
+	ctx, stopSignals := handleSignals(context.Background())
+	err := rootCmd.ExecuteContext(ctx)
+	stopSignals()
+	flushGitHubOutputs()
+	if err != nil {
 		// Log the error with detailed information
 		logrus.WithError(err).Error("Application failed")
 
@@ -312,45 +1829,7 @@ func main() {
 	}
 }
 
-// determineExitCode determines the appropriate exit code based on error type
-func determineExitCode(err error) int {
-	errStr := err.Error()
-
-	// Check for specific error patterns in the error message
-	switch {
-	case contains(errStr, "configuration", "config"):
-		return 2
-	case contains(errStr, "validation", "invalid"):
-		return 3
-	case contains(errStr, "nobl9", "api"):
-		return 4
-	case contains(errStr, "file", "processing"):
-		return 5
-	case contains(errStr, "auth", "credentials"):
-		return 6
-	case contains(errStr, "network", "connection"):
-		return 7
-	case contains(errStr, "rate limit"):
-		return 8
-	case contains(errStr, "timeout"):
-		return 9
-	default:
-		return 1
-	}
-}
-
-// contains checks if error message contains any of the keywords
-func contains(str string, keywords ...string) bool {
-	lowerStr := strings.ToLower(str)
-	for _, keyword := range keywords {
-		if strings.Contains(lowerStr, strings.ToLower(keyword)) {
-			return true
-		}
-	}
-	return false
-}
-
-// Helper functions implementing the core functionality
+// Helper functions implementing the core functionality
 
 // scanFiles scans for YAML files matching the given pattern
 func scanFiles(repoPath, filePattern string) ([]string, error) {
@@ -370,7 +1849,7 @@ func scanFiles(repoPath, filePattern string) ([]string, error) {
 			continue
 		}
 
-		if !info.IsDir() && isYAMLFile(match) {
+		if !info.IsDir() && (isYAMLFile(match) || isJSONFile(match) || renderEngine().IsSource(match)) {
 			files = append(files, match)
 		}
 	}
@@ -378,14 +1857,72 @@ func scanFiles(repoPath, filePattern string) ([]string, error) {
 	return files, nil
 }
 
+// inputFiles returns the role binding CSV files given with --csv, or else
+// the YAML files of the repository matching the file pattern and, with
+// --owners-files, its OWNERS files
+func inputFiles() ([]string, error) {
+	if config.CSV != "" {
+		files := commalist.Split(config.CSV)
+		logrus.WithField("csv_files", files).Info("Reading role bindings from CSV files")
+		for _, file := range files {
+			if !isCSVFile(file) {
+				return nil, fmt.Errorf("%s is not a CSV file", file)
+			}
+		}
+		return files, nil
+	}
+
+	if _, err := render.ParseEngine(config.Render); err != nil {
+		return nil, fmt.Errorf("invalid render: %w", err)
+	}
+	if _, err := nobl9client.ParseOwnersRoles(config.OwnersRoles); config.OwnersFiles && err != nil {
+		return nil, fmt.Errorf("invalid owners-roles: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repo_path":    config.RepoPath,
+		"file_pattern": config.FilePattern,
+	}).Info("Scanning for Nobl9 YAML files")
+	files, err := scanFiles(config.RepoPath, config.FilePattern)
+	if err != nil {
+		return nil, err
+	}
+	if config.OwnersFiles {
+		owners, err := scanOwnersFiles(config.RepoPath)
+		if err != nil {
+			return nil, err
+		}
+		logrus.WithField("owners_files", len(owners)).Info("Reading role bindings from OWNERS files")
+		files = append(files, owners...)
+	}
+	if config.TestsDir == "" {
+		return files, nil
+	}
+	return outsideDir(files, config.TestsDir), nil
+}
+
+// isCSVFile checks if the file has a CSV extension
+func isCSVFile(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".csv"
+}
+
 // isYAMLFile checks if the file has a YAML extension
 func isYAMLFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	return ext == ".yaml" || ext == ".yml"
 }
 
+// isJSONFile checks if the file has a JSON extension
+func isJSONFile(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".json"
+}
+
 // createNobl9Client creates and initializes a Nobl9 SDK client
 func createNobl9Client(clientID, clientSecret string) (*sdk.Client, error) {
+	if config.Replay != "" {
+		return createReplayClient(config.Replay)
+	}
+
 	// Set environment variables for the Nobl9 SDK (like your lambda)
 	os.Setenv("NOBL9_SDK_CLIENT_ID", clientID)
 	os.Setenv("NOBL9_SDK_CLIENT_SECRET", clientSecret)
@@ -401,93 +1938,405 @@ func createNobl9Client(clientID, clientSecret string) (*sdk.Client, error) {
 		return nil, fmt.Errorf("failed to initialize Nobl9 SDK client: %w", err)
 	}
 
+	// Redact and mask the access tokens derived from the credentials
+	nobl9.MaskAccessTokens(client.HTTP)
+
+	// Record each request sent, above the token masker so the token is
+	// already a secret redacted from fixtures
+	if config.Record != "" {
+		if _, err := nobl9.RecordAPICalls(client.HTTP, config.Record, newLogger()); err != nil {
+			return nil, fmt.Errorf("failed to record Nobl9 API calls: %w", err)
+		}
+		logrus.WithField("dir", config.Record).Info("Recording Nobl9 API calls")
+	}
+
 	return client, nil
 }
 
+// createReplayClient creates a Nobl9 SDK client answered from the fixtures
+// of a recorded run, for the organization and API URL they were recorded
+// from, without authenticating
+func createReplayClient(dir string) (*sdk.Client, error) {
+	client, err := sdk.NewClient(&sdk.Config{DisableOkta: true, Project: sdk.DefaultProject})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Nobl9 SDK client: %w", err)
+	}
+
+	replayer, err := nobl9.ReplayAPICalls(client.HTTP, dir, newLogger())
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay Nobl9 API calls: %w", err)
+	}
+	client.Config.URL = replayer.URL()
+	client.Config.Organization = replayer.Organization()
+
+	logrus.WithFields(logrus.Fields{
+		"dir":          dir,
+		"organization": replayer.Organization(),
+	}).Warn("Replaying recorded Nobl9 API calls; nothing is sent to Nobl9")
+	return client, nil
+}
+
+// nobl9API returns the Nobl9API of an SDK client created by
+// createNobl9Client. Its calls go through the client's transports and are
+// made once; retryCall retries those that need it.
+func nobl9API(client *sdk.Client) nobl9.Nobl9API {
+	return nobl9.Wrap(client, newLogger())
+}
+
+// createGroupExpander creates an Okta client when Okta credentials are configured
+func createGroupExpander() (nobl9client.GroupExpander, error) {
+	if config.OktaOrg == "" {
+		return nil, nil
+	}
+
+	return okta.New(&okta.Config{
+		OrgURL:   config.OktaOrg,
+		APIToken: config.OktaToken,
+	}, newLogger())
+}
+
+// createTeamExpander creates a GitHub client when a GitHub token is
+// configured. Teams named without an organization belong to the
+// repository's owner.
+func createTeamExpander() (nobl9client.GroupExpander, error) {
+	if config.GitHubToken == "" {
+		return nil, nil
+	}
+
+	client, err := owners.NewClient(&owners.Config{APIURL: os.Getenv("GITHUB_API_URL"), Token: config.GitHubToken})
+	if err != nil {
+		return nil, err
+	}
+	emails, err := owners.ParseLoginEmails(config.GitHubEmails)
+	if err != nil {
+		return nil, err
+	}
+	org, _, _ := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	return owners.NewTeamExpander(client, org, emails), nil
+}
+
+// newLogger creates a structured logger using the configured level and format
+func newLogger() *logger.Logger {
+	return logger.New(logger.Level(config.LogLevel), logger.Format(config.LogFormat))
+}
+
 // ProcessResult represents the result of processing a single file
 type ProcessResult struct {
-	ProjectsCreated     int
-	RoleBindingsCreated int
-	EmailsResolved      int
+	ProjectsCreated       int
+	RoleBindingsCreated   int
+	RoleBindingsUnchanged int
+	EmailsResolved        int
+	Kinds                 nobl9client.KindCounts
+	Skipped               nobl9client.SkippedObjects
+
+	// RoleBindingsMerged are role bindings left out because an earlier one
+	// already grants the same user the same role in the same project
+	RoleBindingsMerged int
+
+	// ObjectsUnchanged are objects not applied because the state file or
+	// their live definition showed they would not change
+	ObjectsUnchanged int
+}
+
+// parsedFile holds the objects decoded from a single file, the emails its
+// role bindings reference and every user each role binding lists
+type parsedFile struct {
+	Path     string
+	Meta     *parser.Meta
+	Objects  []manifest.Object
+	Emails   []string
+	Bindings []roles.Binding
+	Duration time.Duration
+}
+
+// preparedFile holds a file's validated objects ready to apply, its result
+// and the error that stopped it from being applied, if any
+type preparedFile struct {
+	Path     string
+	Objects  []manifest.Object
+	Result   *ProcessResult
+	Err      error
+	Duration time.Duration
+	// Aborted is set when a critical error stopped the run before all of
+	// the file's objects were applied
+	Aborted bool
+
+	// Outcomes track the status of every decoded object for the results file
+	Outcomes []*objectOutcome
+	outcomes map[string]*objectOutcome
 }
 
-// processFile processes a single YAML file using patterns from your lambda
-func processFile(ctx context.Context, client *sdk.Client, filePath string, dryRun bool) (*ProcessResult, error) {
-	result := &ProcessResult{}
+// resolutionRetryDelay is how long to wait before retrying emails whose
+// resolution failed with a transient error
+var resolutionRetryDelay = 10 * time.Second
+
+// parseFile reads a single YAML file and expands Okta group and GitHub team
+// role bindings
+func parseFile(ctx context.Context, groupExpander, teamExpander nobl9client.GroupExpander, filePath string) (*parsedFile, error) {
+	parsed := &parsedFile{Path: filePath}
 
 	// Read file content
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return result, fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Check if it contains Nobl9 configuration
-	if !isNobl9File(content) {
-		logrus.WithField("file", filePath).Debug("File does not contain Nobl9 configuration, skipping")
-		return result, nil
-	}
+	var objects []manifest.Object
+	var emails []string
+	if isCSVFile(filePath) {
+		// Convert project,email,role rows into role bindings
+		objects, err = nobl9client.ParseRoleBindingCSV(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role binding CSV: %w", err)
+		}
+	} else if isOwnersFile(filePath) {
+		// Convert approvers and reviewers into role bindings
+		objects, err = parseOwnersFile(filePath, content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OWNERS file: %w", err)
+		}
+	} else {
+		// Evaluate Jsonnet and CUE files into manifests
+		content, err = renderManifest(filePath, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render: %w", err)
+		}
 
-	// Parse YAML documents
-	objects, emailsToResolve, err := parseYAMLContent(content, filePath)
-	if err != nil {
-		return result, fmt.Errorf("failed to parse YAML: %w", err)
+		// Check if it contains Nobl9 configuration
+		if !isNobl9File(content) {
+			logrus.WithField("file", filePath).Debug("File does not contain Nobl9 configuration, skipping")
+			return parsed, nil
+		}
+
+		// Substitute variables before anything reads the YAML
+		content, err = substituteVariables(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to substitute variables: %w", err)
+		}
+
+		// Fail fields the SDK would drop rather than apply without them
+		content, err = checkCompatibility(content, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("incompatible manifest: %w", err)
+		}
+
+		// Separate the file's ActionMeta settings from its objects
+		parsed.Meta, content, err = parser.ExtractMeta(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", parser.MetaKind, err)
+		}
+
+		// Specialize the objects for the environment
+		content, err = overlayEnvironment(parsed.Meta, content, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", parser.MetaKind, err)
+		}
+
+		// Parse YAML documents
+		objects, emails, err = parseYAMLContent(content, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
 	}
 
 	if len(objects) == 0 {
 		logrus.WithField("file", filePath).Debug("No valid objects found in file")
-		return result, nil
+		return parsed, nil
 	}
 
-	// Resolve emails to user IDs
-	emailResolutions := make(map[string]string)
-	if len(emailsToResolve) > 0 {
-		logrus.WithField("email_count", len(emailsToResolve)).Debug("Resolving email addresses")
+	// Expand okta-group: role bindings into one role binding per group member
+	objects, err = nobl9client.ExpandGroupRoleBindings(ctx, objects, groupExpander)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, email := range emailsToResolve {
-			userID, err := resolveEmailToUserID(ctx, client, email)
-			if err != nil {
-				logrus.WithField("email", email).WithError(err).Warn("Failed to resolve email")
-				continue
+	// Expand github-team: role bindings into one role binding per member
+	objects, err = nobl9client.ExpandTeamRoleBindings(ctx, objects, teamExpander)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.Objects = objects
+	parsed.Emails = appendRoleBindingEmails(emails, objects)
+	parsed.Bindings = roleBindingsOf(content, filePath, objects)
+
+	return parsed, nil
+}
+
+// collectEmails returns the unique emails referenced by all parsed files
+func collectEmails(files []*parsedFile) []string {
+	var emails []string
+	seen := make(map[string]bool)
+
+	for _, file := range files {
+		for _, email := range file.Emails {
+			if !seen[email] {
+				seen[email] = true
+				emails = append(emails, email)
 			}
-			emailResolutions[email] = userID
-			result.EmailsResolved++
 		}
 	}
 
-	// Update role bindings with resolved user IDs
-	for _, obj := range objects {
-		if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok {
-			if rb.Spec.User != nil {
-				originalUser := *rb.Spec.User
-				if resolvedID, found := emailResolutions[originalUser]; found {
-					rb.Spec.User = &resolvedID
-					logrus.WithFields(logrus.Fields{
-						"email":   originalUser,
-						"user_id": resolvedID,
-					}).Debug("Email resolved for role binding")
-				}
+	return emails
+}
+
+// resolveEmails resolves emails to user IDs. Each email is normalized before
+// it is resolved and cached, while the returned map is keyed by the email as
+// written in the manifests. Emails that fail with a transient error (5xx,
+// timeouts) are retried once more after a backoff before they are reported
+// as unresolved. Failures are added to errs, if set, and resolution stops
+// once a critical one aborted the run.
+func resolveEmails(ctx context.Context, api nobl9.Nobl9API, userCache *resolver.UserCache, normalizer *resolver.Normalizer, emails []string, errs *errors.ErrorAggregator) map[string]string {
+	resolutions, _ := resolveEmailsWithFailures(ctx, api, userCache, normalizer, emails, errs)
+	return resolutions
+}
+
+// resolveEmailsWithFailures resolves emails like resolveEmails, and also
+// returns the error each email that did not resolve failed with last.
+// Emails left out because the run was aborted have no error.
+func resolveEmailsWithFailures(ctx context.Context, api nobl9.Nobl9API, userCache *resolver.UserCache, normalizer *resolver.Normalizer, emails []string, errs *errors.ErrorAggregator) (map[string]string, map[string]error) {
+	resolutions := make(map[string]string)
+	failures := make(map[string]error)
+	if len(emails) == 0 {
+		return resolutions, failures
+	}
+	ctx, span := tracing.Start(ctx, "resolve", attribute.Int("email.count", len(emails)))
+	defer func() {
+		span.SetAttributes(attribute.Int("email.resolved", len(resolutions)))
+		span.End()
+	}()
+
+	logrus.WithFields(logrus.Fields{
+		"email_count":   len(emails),
+		"normalization": normalizer.String(),
+	}).Debug("Resolving email addresses")
+
+	// normalized maps each email to the address that is resolved
+	normalized := make(map[string]string, len(emails))
+	for _, email := range emails {
+		normalized[email] = normalizer.Normalize(email)
+		if normalized[email] != email {
+			logrus.WithFields(logrus.Fields{
+				"email":      email,
+				"normalized": normalized[email],
+			}).Debug("Email normalized")
+		}
+	}
+
+	retryQueue := resolver.NewRetryQueue()
+	for _, email := range emails {
+		if abortedBy(ctx) != nil {
+			return resolutions, failures
+		}
+		userID, err := resolveEmailCached(ctx, api, userCache, normalized[email])
+		if err != nil {
+			failures[email] = err
+			if retryQueue.Add(email, err) {
+				logrus.WithField("email", email).WithError(err).Warn("Transient error resolving email, will retry at end of run")
+				continue
 			}
+			logrus.WithField("email", email).WithError(err).Warn("Failed to resolve email")
+			recordError(errs, phaseResolve, err)
+			continue
+		}
+		resolutions[email] = userID
+	}
+
+	if retryQueue.Len() == 0 {
+		return resolutions, failures
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"email_count": retryQueue.Len(),
+		"delay":       resolutionRetryDelay.String(),
+	}).Info("Retrying emails that failed with transient errors")
+
+	select {
+	case <-time.After(resolutionRetryDelay):
+	case <-ctx.Done():
+		logrus.WithError(ctx.Err()).Warn("Skipping email resolution retry")
+		return resolutions, failures
+	}
+
+	for _, email := range retryQueue.Drain() {
+		userID, err := resolveEmailCached(ctx, api, userCache, normalized[email])
+		if err != nil {
+			failures[email] = err
+			logrus.WithField("email", email).WithError(err).Warn("Failed to resolve email after retry")
+			recordError(errs, phaseResolve, err)
+			continue
 		}
+		delete(failures, email)
+		logrus.WithField("email", email).Info("Email resolved on retry")
+		resolutions[email] = userID
 	}
 
-	// Apply objects to Nobl9
-	if !dryRun {
-		logrus.WithField("object_count", len(objects)).Debug("Applying objects to Nobl9")
+	return resolutions, failures
+}
 
-		if err := client.Objects().V1().Apply(ctx, objects); err != nil {
-			// Check if the error is because objects already exist
-			if strings.Contains(err.Error(), "already exists") || strings.Contains(err.Error(), "conflict") {
-				logrus.WithField("file", filePath).Info("Some objects already exist")
-			} else {
-				return result, fmt.Errorf("failed to apply objects: %w", err)
-			}
+// prepareFile substitutes resolved user IDs into a parsed file's role
+// bindings and validates its objects of the selected kinds that are not on
+// the skip list
+func prepareFile(parsed *parsedFile, emailResolutions map[string]string, kinds nobl9client.KindFilter, skip *skiplist.List) (*preparedFile, error) {
+	result := &ProcessResult{
+		Kinds:   make(nobl9client.KindCounts),
+		Skipped: make(nobl9client.SkippedObjects),
+	}
+	file := &preparedFile{Path: parsed.Path, Result: result, Duration: parsed.Duration}
+
+	// Drop objects of kinds that are not selected or cannot be applied, and
+	// objects of the skip list
+	objects := make([]manifest.Object, 0, len(parsed.Objects))
+	for _, obj := range parsed.Objects {
+		if !kinds.Allows(obj.GetKind()) {
+			result.Skipped.Add(obj.GetKind().String(), obj.GetName())
+			file.addOutcome(newObjectOutcome(obj, statusSkipped))
+			continue
+		}
+		if skip.SkipsObject(obj) {
+			logrus.WithFields(logrus.Fields{
+				"file":    parsed.Path,
+				"kind":    obj.GetKind().String(),
+				"project": planner.ProjectOf(obj),
+				"name":    obj.GetName(),
+			}).Warn("Object skipped by the skip list")
+			result.Skipped.Add(obj.GetKind().String(), obj.GetName())
+			file.addOutcome(newObjectOutcome(obj, statusSkipped))
+			continue
+		}
+		file.addOutcome(newObjectOutcome(obj, statusPending))
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return file, nil
+	}
+
+	for _, email := range parsed.Emails {
+		if _, found := emailResolutions[email]; found {
+			result.EmailsResolved++
 		}
-	} else {
-		logrus.WithField("object_count", len(objects)).Info("DRY RUN: Would apply objects to Nobl9")
+	}
+
+	// Replace role binding emails with the resolved user IDs, unless the
+	// field is not eligible; the substituted objects are the ones validated
+	// and applied
+	if resolutionEligibility().Allows(manifest.KindRoleBinding.String(), "spec.user") {
+		objects, _ = nobl9client.SubstituteUserIDs(objects, emailResolutions)
+	}
+
+	// Validate every object before applying any of them; failing objects
+	// are located in the file as written
+	validationErrors := validateObjects(parsed.Path, objects)
+	validationErrors = append(validationErrors, invalidRecipients(objects)...)
+	if len(validationErrors) > 0 {
+		return nil, fmt.Errorf("invalid objects: %s", strings.Join(validationErrors, "; "))
 	}
 
 	// Count created objects
 	for _, obj := range objects {
+		result.Kinds.Add(obj.GetKind().String(), 1)
+
 		switch obj.GetKind() {
 		case manifest.KindProject:
 			result.ProjectsCreated++
@@ -496,38 +2345,235 @@ func processFile(ctx context.Context, client *sdk.Client, filePath string, dryRu
 		}
 	}
 
-	return result, nil
+	file.Objects = objects
+	return file, nil
 }
 
-// isNobl9File checks if file content contains Nobl9 configuration
-func isNobl9File(content []byte) bool {
-	contentStr := string(content)
+// applyPlanned applies the objects of all prepared files stage by stage, so
+// objects another file depends on (e.g. its project) are applied first. With
+// the default file --apply-granularity, a file whose objects fail to apply is
+// not applied in later stages. With project or object granularity each
+// project or object is applied in its own call and the file goes on past
+// failures; only the objects of a project that failed to apply are left out
+// of later stages. Applied objects carry the ownership marker, if one is
+// configured, and are recorded in the audit log.
+func applyPlanned(ctx context.Context, client nobl9.Nobl9API, files []*preparedFile, dryRun bool, serverCheck *serverDryRun, errs *errors.ErrorAggregator) error {
+	granularity, err := planner.ParseGranularity(config.ApplyGranularity)
+	if err != nil {
+		return err
+	}
+	byPath := make(map[string]*preparedFile, len(files))
+	var items []planner.Item
+	for _, file := range files {
+		byPath[file.Path] = file
+		for _, obj := range file.Objects {
+			items = append(items, planner.Item{Object: obj, Source: file.Path})
+		}
+	}
 
-	// Check for Nobl9-specific indicators based on the official YAML guide
-	nobl9Indicators := []string{
-		"apiVersion: n9/v1alpha",
-		"kind: Agent",
-		"kind: Alert",
-		"kind: AlertMethod",
-		"kind: AlertPolicy",
-		"kind: AlertSilence",
-		"kind: Annotation",
-		"kind: BudgetAdjustment",
-		"kind: DataExport",
-		"kind: Direct",
-		"kind: Objective",
-		"kind: Project",
-		"kind: Report",
-		"kind: RoleBinding",
-		"kind: Service",
-		"kind: SLO",
-		"kind: UserGroup",
-		// Composite SLO indicators
-		"composite:",
-		"maxDelay:",
-		"components:",
-		"whenDelayed:",
+	plan, err := planner.New().Plan(items)
+	if err != nil {
+		return err
+	}
+	ctx, span := tracing.Start(ctx, "apply", attribute.Int("stage.count", len(plan.Stages)), attribute.Bool("dry_run", dryRun))
+	defer span.End()
+	marker := newOwnershipMarker()
+	auditLog, err := openAuditLog(dryRun)
+	if err != nil {
+		return err
 	}
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+
+	// Projects whose objects failed to apply, with the failure, and the
+	// failures of each file
+	failedProjects := make(map[string]error)
+	failures := make(map[string]*applyFailures)
+
+	// Files are complete after the last stage with objects of theirs
+	lastStage := make(map[string]int)
+	for i, stage := range plan.Stages {
+		for _, group := range stage.BySource() {
+			lastStage[group.Source] = i
+		}
+	}
+
+	for i, stage := range plan.Stages {
+		logrus.WithFields(logrus.Fields{
+			"stage":        i + 1,
+			"kinds":        stage.KindNames(),
+			"object_count": len(stage.Items),
+		}).Debug("Applying stage")
+
+		for _, group := range stage.BySource() {
+			file := byPath[group.Source]
+			if file.Aborted || (file.Err != nil && granularity == planner.GranularityFile) {
+				continue
+			}
+			if abortedBy(ctx) != nil {
+				file.Aborted = true
+				continue
+			}
+			start := time.Now()
+			objects, liveBindings := skipUnchangedRoleBindings(ctx, client, file, withoutUnchanged(file, group.Objects))
+			for _, batch := range planner.Batches(objects, granularity) {
+				batch = skipFailedProjects(file, batch, failedProjects, failures)
+				if len(batch) == 0 {
+					continue
+				}
+				if abortedBy(ctx) != nil {
+					file.Aborted = true
+					break
+				}
+
+				batchCtx, batchSpan := tracing.Start(ctx, "apply file",
+					attribute.String("file.path", file.Path),
+					attribute.Int("stage", i+1),
+					attribute.Int("object.count", len(batch)),
+				)
+				// Once started, a call may finish when the run is interrupted
+				callCtx, release := withApplyGrace(batchCtx)
+				err := applyBatch(callCtx, client, file, batch, liveBindings, marker, auditLog, dryRun, serverCheck)
+				release()
+				tracing.End(batchSpan, err)
+				if err == nil {
+					continue
+				}
+				recordError(errs, phaseApply, err)
+				if granularity == planner.GranularityFile {
+					file.Err = err
+					break
+				}
+				failuresOf(failures, file).add(file, len(batch), err)
+				for _, obj := range batch {
+					if project := planner.ProjectOf(obj); project != "" && (granularity == planner.GranularityProject || obj.GetKind() == manifest.KindProject) {
+						if _, found := failedProjects[project]; !found {
+							failedProjects[project] = err
+						}
+					}
+				}
+			}
+			file.Duration += time.Since(start)
+			if i == lastStage[file.Path] && file.Err == nil && !file.Aborted && failures[file.Path] == nil {
+				runCheckpoint.complete(file)
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipUnchangedRoleBindings drops role bindings identical to the live ones
+// and counts them as unchanged in the file's result. It also returns the
+// live role bindings it read, by key, for applyBatch to reuse. If the live
+// role bindings cannot be read every object is applied and nil is returned.
+func skipUnchangedRoleBindings(ctx context.Context, client nobl9.Nobl9API, file *preparedFile, objects []manifest.Object) ([]manifest.Object, map[compare.Key]manifest.Object) {
+	remaining, unchanged, live, err := nobl9client.SkipUnchangedRoleBindings(ctx, client, objects)
+	if err != nil {
+		logrus.WithField("file", file.Path).WithError(err).Warn("Failed to compare role bindings with Nobl9, applying all of them")
+		return objects, nil
+	}
+
+	for _, name := range unchanged {
+		logrus.WithFields(logrus.Fields{
+			"file":         file.Path,
+			"role_binding": name,
+		}).Debug("Role binding unchanged, skipping apply")
+		file.setStatusByName(manifest.KindRoleBinding.String(), name, statusUnchanged, nil)
+	}
+	file.Result.RoleBindingsCreated -= len(unchanged)
+	file.Result.RoleBindingsUnchanged += len(unchanged)
+	file.Result.ObjectsUnchanged += len(unchanged)
+
+	liveBindings := make(map[compare.Key]manifest.Object, len(live))
+	for _, rb := range live {
+		liveBindings[compare.KeyOf(rb)] = rb
+	}
+	return remaining, liveBindings
+}
+
+// applyObjects applies objects read from a single file to Nobl9. A dry run
+// sends them to Nobl9 with the dry-run flag while serverCheck is active.
+func applyObjects(ctx context.Context, client nobl9.Nobl9API, filePath string, objects []manifest.Object, dryRun bool, serverCheck *serverDryRun) error {
+	if dryRun && serverCheck.active() {
+		return serverCheck.apply(ctx, client, filePath, objects)
+	}
+	if dryRun {
+		logrus.WithFields(logrus.Fields{
+			"file":         filePath,
+			"object_count": len(objects),
+		}).Info("DRY RUN: Would apply objects to Nobl9")
+		return nil
+	}
+
+	logrus.WithField("object_count", len(objects)).Debug("Applying objects to Nobl9")
+
+	err := retryCall(ctx, retry.OperationApply, "apply objects", func(ctx context.Context) error {
+		return client.ApplyObjects(ctx, objects)
+	})
+	if err != nil {
+		// Check if the error is because objects already exist
+		if strings.Contains(err.Error(), "already exists") || strings.Contains(err.Error(), "conflict") {
+			logrus.WithField("file", filePath).Info("Some objects already exist")
+			return nil
+		}
+		return fmt.Errorf("failed to apply objects: %w", err)
+	}
+
+	return nil
+}
+
+// nobl9Indicators are Nobl9-specific indicators based on the official YAML
+// guide
+var nobl9Indicators = []string{
+	"apiVersion: n9/v1alpha",
+	"kind: Agent",
+	"kind: Alert",
+	"kind: AlertMethod",
+	"kind: AlertPolicy",
+	"kind: AlertSilence",
+	"kind: Annotation",
+	"kind: BudgetAdjustment",
+	"kind: DataExport",
+	"kind: Direct",
+	"kind: Objective",
+	"kind: Project",
+	"kind: Report",
+	"kind: RoleBinding",
+	"kind: Service",
+	"kind: SLO",
+	"kind: UserGroup",
+	// Composite SLO indicators
+	"composite:",
+	"maxDelay:",
+	"components:",
+	"whenDelayed:",
+}
+
+// nobl9JSONIndicators are the indicators as written in JSON manifests, e.g.
+// "kind": "Project"
+var nobl9JSONIndicators = jsonIndicators(nobl9Indicators)
+
+// jsonIndicators returns the patterns matching "key: value" and "key:"
+// indicators written as JSON
+func jsonIndicators(indicators []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(indicators))
+	for _, indicator := range indicators {
+		key, value, _ := strings.Cut(indicator, ":")
+		pattern := `"` + regexp.QuoteMeta(key) + `"\s*:`
+		if value = strings.TrimSpace(value); value != "" {
+			pattern += `\s*"` + regexp.QuoteMeta(value) + `"`
+		}
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+	return patterns
+}
+
+// isNobl9File checks if file content contains Nobl9 configuration, written
+// as YAML or JSON
+func isNobl9File(content []byte) bool {
+	contentStr := string(content)
 
 	for _, indicator := range nobl9Indicators {
 		if strings.Contains(contentStr, indicator) {
@@ -535,94 +2581,97 @@ func isNobl9File(content []byte) bool {
 		}
 	}
 
+	for _, indicator := range nobl9JSONIndicators {
+		if indicator.Match(content) {
+			return true
+		}
+	}
+
 	return false
 }
 
 // parseYAMLContent parses YAML content and extracts Nobl9 objects and emails
 func parseYAMLContent(content []byte, source string) ([]manifest.Object, []string, error) {
-	var emails []string
-
 	// Parse using Nobl9 SDK first
 	manifests, err := sdk.DecodeObjects(content)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to decode objects: %w", err)
 	}
-
-	// Also manually parse to extract emails from role bindings
-	documents := strings.Split(string(content), "---")
-	for _, doc := range documents {
-		doc = strings.TrimSpace(doc)
-		if doc == "" {
-			continue
+	if config.GenerateRoleBindingNames {
+		var generated int
+		if manifests, generated = nobl9client.NameRoleBindings(manifests); generated > 0 {
+			logrus.WithFields(logrus.Fields{
+				"file":      source,
+				"generated": generated,
+			}).Debug("Named role bindings without metadata.name")
 		}
+	}
 
-		docEmails := extractEmailsFromDocument(doc)
-		emails = append(emails, docEmails...)
+	// Also walk the YAML documents to extract emails from role bindings
+	users, err := resolver.RoleBindingUsers(content, resolutionEligibility())
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Remove duplicates from emails
 	emailSet := make(map[string]bool)
-	uniqueEmails := []string{}
-	for _, email := range emails {
-		if !emailSet[email] {
-			emailSet[email] = true
-			uniqueEmails = append(uniqueEmails, email)
+	emails := []string{}
+	for _, user := range users {
+		if user != "" && isEmail(user) && !emailSet[user] {
+			emailSet[user] = true
+			emails = append(emails, user)
 		}
 	}
 
-	return manifests, uniqueEmails, nil
+	return manifests, emails, nil
 }
 
-// extractEmailsFromDocument extracts email addresses from a YAML document
-func extractEmailsFromDocument(docContent string) []string {
-	var emails []string
-
-	// Parse to find RoleBinding objects and extract user emails
-	var doc map[string]interface{}
-	if err := yaml.Unmarshal([]byte(docContent), &doc); err != nil {
+// appendRoleBindingEmails adds role binding user emails not yet in the list
+// (e.g. members produced by Okta group expansion)
+func appendRoleBindingEmails(emails []string, objects []manifest.Object) []string {
+	if !resolutionEligibility().Allows(manifest.KindRoleBinding.String(), "spec.user") {
 		return emails
 	}
 
-	kind, ok := doc["kind"].(string)
-	if !ok || kind != "RoleBinding" {
-		return emails
-	}
-
-	spec, ok := doc["spec"].(map[string]interface{})
-	if !ok {
-		return emails
+	seen := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		seen[email] = true
 	}
 
-	// Extract emails from different user fields
-	if user, exists := spec["user"]; exists {
-		if userStr, ok := user.(string); ok && isEmail(userStr) {
-			emails = append(emails, userStr)
+	for _, obj := range objects {
+		rb, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || rb.Spec.User == nil {
+			continue
 		}
-	}
-
-	if users, exists := spec["users"]; exists {
-		if usersList, ok := users.([]interface{}); ok {
-			for _, user := range usersList {
-				if userStr, ok := user.(string); ok && isEmail(userStr) {
-					emails = append(emails, userStr)
-				}
-			}
+		if user := *rb.Spec.User; isEmail(user) && !seen[user] {
+			seen[user] = true
+			emails = append(emails, user)
 		}
 	}
 
-	if userIDs, exists := spec["userIds"]; exists {
-		if userIDsStr, ok := userIDs.(string); ok {
-			csvUsers := strings.Split(userIDsStr, ",")
-			for _, user := range csvUsers {
-				user = strings.TrimSpace(user)
-				if user != "" && isEmail(user) {
-					emails = append(emails, user)
-				}
+	return emails
+}
+
+// invalidRecipients describes the email recipients of the objects, such as
+// those of email alert methods, that are not well-formed addresses.
+// Recipients are sent to Nobl9 as written, so they are never resolved.
+func invalidRecipients(objects []manifest.Object) []string {
+	var invalid []string
+	for _, obj := range objects {
+		for _, recipient := range resolver.Recipients(obj) {
+			if !resolver.ValidEmail(recipient.Email) {
+				invalid = append(invalid, fmt.Sprintf("%s is not a valid email address", recipient))
 			}
 		}
 	}
+	return invalid
+}
 
-	return emails
+// resolutionEligibility returns the fields whose emails are resolved to user
+// IDs; validateConfig already checked --resolve-paths
+func resolutionEligibility() resolver.Eligibility {
+	eligibility, _ := resolver.ParseEligibility(config.ResolvePaths)
+	return eligibility
 }
 
 // isEmail checks if string is an email
@@ -630,15 +2679,48 @@ func isEmail(s string) bool {
 	return strings.Contains(s, "@")
 }
 
+// resolveEmailCached resolves an email address, consulting the user cache first
+func resolveEmailCached(ctx context.Context, api nobl9.Nobl9API, userCache *resolver.UserCache, email string) (string, error) {
+	if cached := userCache.Get(email); cached != nil && cached.Found {
+		logrus.WithField("email", email).Debug("Email resolved from user cache")
+		return cached.UserID, nil
+	}
+
+	// Only the domain is recorded, to keep addresses out of traces
+	ctx, span := tracing.Start(ctx, "resolve email", attribute.String("email.domain", resolver.EmailDomain(email)))
+	userID, err := resolveEmailToUserID(ctx, api, email)
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+
+	userCache.Set(email, &resolver.UserInfo{
+		Email:  email,
+		UserID: userID,
+		Active: true,
+		Found:  true,
+	})
+
+	return userID, nil
+}
+
 // resolveEmailToUserID resolves an email address to a user ID using Nobl9 API
-func resolveEmailToUserID(ctx context.Context, client *sdk.Client, email string) (string, error) {
-	// Use Nobl9 SDK to get user by email (same as your lambda)
-	user, err := client.Users().V2().GetUser(ctx, email)
+func resolveEmailToUserID(ctx context.Context, api nobl9.Nobl9API, email string) (string, error) {
+	var user *v2.User
+	err := retryCall(ctx, retry.OperationUsers, "get user", func(ctx context.Context) error {
+		var err error
+		user, err = api.GetUser(ctx, email)
+		if stderrors.Is(err, errors.ErrUserNotFound) {
+			// An unknown email is not retried, it is reported below
+			user, err = nil, nil
+		}
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("error retrieving user '%s': %w", email, err)
 	}
 	if user == nil {
-		return "", fmt.Errorf("user with email '%s' not found in Nobl9", email)
+		return "", fmt.Errorf("%w with email '%s' in Nobl9", errors.ErrUserNotFound, email)
 	}
 
 	return user.UserID, nil
@@ -652,9 +2734,27 @@ func validateFile(ctx context.Context, filePath string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Check if it's a YAML file
-	if !isYAMLFile(filePath) {
-		return fmt.Errorf("file is not a YAML file")
+	// Role binding CSV files only need to convert
+	if isCSVFile(filePath) {
+		if _, err := nobl9client.ParseRoleBindingCSV(content); err != nil {
+			return fmt.Errorf("invalid role binding CSV: %w", err)
+		}
+		return nil
+	}
+	if isOwnersFile(filePath) {
+		if _, err := parseOwnersFile(filePath, content); err != nil {
+			return fmt.Errorf("invalid OWNERS file: %w", err)
+		}
+		return nil
+	}
+
+	// Check if it's a YAML or JSON file, or one rendered into YAML
+	if renderEngine().IsSource(filePath) {
+		if content, err = renderManifest(filePath, content); err != nil {
+			return fmt.Errorf("failed to render: %w", err)
+		}
+	} else if !isYAMLFile(filePath) && !isJSONFile(filePath) {
+		return fmt.Errorf("file is not a YAML or JSON file")
 	}
 
 	// Check if it contains Nobl9 configuration
@@ -662,35 +2762,63 @@ func validateFile(ctx context.Context, filePath string) error {
 		return fmt.Errorf("file does not contain Nobl9 configuration")
 	}
 
-	// Parse and validate YAML structure
-	_, err = sdk.DecodeObjects(content)
+	// Check the variables, the ActionMeta settings and the YAML structure
+	content, err = substituteVariables(content)
 	if err != nil {
-		return fmt.Errorf("invalid Nobl9 YAML: %w", err)
+		return fmt.Errorf("failed to substitute variables: %w", err)
+	}
+	content, err = checkCompatibility(content, filePath)
+	if err != nil {
+		return fmt.Errorf("incompatible manifest: %w", err)
+	}
+	meta, content, err := parser.ExtractMeta(content)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", parser.MetaKind, err)
+	}
+
+	// Without --environment the base manifests and every environment of
+	// the file are checked
+	environments := []string{config.Environment}
+	if config.Environment == "" {
+		environments = append(environments, meta.Environments()...)
+	}
+	for _, environment := range environments {
+		overlaid, err := meta.Overlay(content, environment)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", parser.MetaKind, err)
+		}
+		objects, err := sdk.DecodeObjects(overlaid)
+		if err != nil {
+			if environment != "" {
+				return fmt.Errorf("invalid Nobl9 YAML in environment %s: %w", environment, err)
+			}
+			return fmt.Errorf("invalid Nobl9 YAML: %w", err)
+		}
+		if invalid := validateObjects(filePath, objects); len(invalid) > 0 {
+			return fmt.Errorf("invalid objects: %s", strings.Join(invalid, "; "))
+		}
+		if invalid := invalidRecipients(objects); len(invalid) > 0 {
+			return fmt.Errorf("invalid email recipients: %s", strings.Join(invalid, "; "))
+		}
 	}
 
 	return nil
 }
 
-// setGitHubOutput sets a GitHub Action output variable
-func setGitHubOutput(name, value string) {
-	// Check if we're running in GitHub Actions
-	githubOutputFile := os.Getenv("GITHUB_OUTPUT")
-	if githubOutputFile == "" {
-		// Not running in GitHub Actions, skip
-		return
-	}
+// githubOutputs buffers the GitHub Action outputs of the command until main
+// flushes them
+var githubOutputs = outputs.NewWriter(os.Getenv(outputs.Env))
 
-	// Append to the GitHub output file
-	file, err := os.OpenFile(githubOutputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		logrus.WithField("error", err).Warn("Failed to open GitHub output file")
-		return
-	}
-	defer file.Close()
+// setGitHubOutput sets a GitHub Action output variable. Outputs are written
+// to the GitHub output file once the command finishes.
+func setGitHubOutput(name, value string) {
+	githubOutputs.Set(name, value)
+}
 
-	// Write the output in the format: name=value
-	_, err = fmt.Fprintf(file, "%s=%s\n", name, value)
-	if err != nil {
-		logrus.WithField("error", err).Warn("Failed to write GitHub output")
+// flushGitHubOutputs writes the outputs set by the command, whether it
+// succeeded or not
+func flushGitHubOutputs() {
+	if err := githubOutputs.Flush(); err != nil {
+		logrus.WithError(err).Warn("Failed to write GitHub outputs")
 	}
 }
@@ -0,0 +1,4035 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/assertions"
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/checkpoint"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/generate"
+	"github.com/your-org/nobl9-action/pkg/history"
+	"github.com/your-org/nobl9-action/pkg/lint"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9/nobl9test"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/organizations"
+	"github.com/your-org/nobl9-action/pkg/outputs"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/policy"
+	"github.com/your-org/nobl9-action/pkg/progress"
+	"github.com/your-org/nobl9-action/pkg/promote"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/roles"
+	"github.com/your-org/nobl9-action/pkg/rollback"
+	"github.com/your-org/nobl9-action/pkg/skiplist"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+const testManifest = `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+spec:
+  description: Payments team
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: payments
+`
+
+// newTestSDKClient returns an SDK client that sends requests to server
+// without authenticating
+func newTestSDKClient(t *testing.T, server *httptest.Server) *sdk.Client {
+	t.Helper()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client, err := sdk.NewClient(&sdk.Config{
+		URL:          serverURL,
+		DisableOkta:  true,
+		Organization: "acme",
+		Project:      sdk.DefaultProject,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client
+}
+
+// newTestAPI returns the Nobl9API of an SDK client sending its requests to
+// the test server
+func newTestAPI(t *testing.T, server *httptest.Server) nobl9.Nobl9API {
+	t.Helper()
+	return nobl9API(newTestSDKClient(t, server))
+}
+
+func TestResolvedUserIDsReachApply(t *testing.T) {
+	var applied string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			body, _ := io.ReadAll(r.Body)
+			applied = string(body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	parsed, err := parseFile(ctx, nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolutions := map[string]string{"alice@example.com": "00u1alice"}
+	file, err := prepareFile(parsed, resolutions, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The parsed objects are left untouched
+	for _, obj := range parsed.Objects {
+		if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok && *rb.Spec.User != "alice@example.com" {
+			t.Errorf("expected parsed role binding to keep the email, got %q", *rb.Spec.User)
+		}
+	}
+
+	if err := applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, false, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(applied, `"user":"00u1alice"`) {
+		t.Errorf("expected resolved user ID in applied objects, got %s", applied)
+	}
+	if strings.Contains(applied, "alice@example.com") {
+		t.Errorf("expected email not to be applied, got %s", applied)
+	}
+	if file.Result.EmailsResolved != 1 {
+		t.Errorf("expected 1 email resolved, got %d", file.Result.EmailsResolved)
+	}
+}
+
+func TestPrepareFileUnresolvedEmail(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kinds, err := nobl9client.ParseKindFilter("rolebinding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := prepareFile(parsed, map[string]string{}, kinds, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(file.Objects) != 1 {
+		t.Fatalf("expected only the role binding to be prepared, got %d objects", len(file.Objects))
+	}
+	rb := file.Objects[0].(v1alphaRoleBinding.RoleBinding)
+	if *rb.Spec.User != "alice@example.com" {
+		t.Errorf("expected unresolved email to be kept, got %q", *rb.Spec.User)
+	}
+	if file.Result.EmailsResolved != 0 {
+		t.Errorf("expected no emails resolved, got %d", file.Result.EmailsResolved)
+	}
+}
+
+func TestPrepareFileSkipList(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	skip, err := skiplist.Parse("", "payments/payments-*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, skip)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(file.Objects) != 1 || file.Objects[0].GetKind() != manifest.KindProject {
+		t.Fatalf("expected only the project to be prepared, got %v", file.Objects)
+	}
+	if file.Result.Skipped.String() != "RoleBinding=1" {
+		t.Errorf("expected the role binding to be counted as skipped, got %s", file.Result.Skipped.String())
+	}
+}
+
+func TestScopeToProjects(t *testing.T) {
+	dir := t.TempDir()
+	var files []*parsedFile
+	for name, content := range map[string]string{
+		"payments.yaml": testManifest + `---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: admin-bob
+spec:
+  user: bob@example.com
+  roleRef: organization-admin
+`,
+		"billing.yaml": strings.ReplaceAll(testManifest, "payments", "billing"),
+	} {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		files = append(files, parsed)
+	}
+
+	summary := newRunSummary(len(files), false)
+	results := newRunResults(time.Now(), false)
+	kept := scopeToProjects(files, []string{"pay*"}, summary, results)
+
+	if len(kept) != 1 || filepath.Base(kept[0].Path) != "payments.yaml" {
+		t.Fatalf("expected only the payments file to be kept, got %d files", len(kept))
+	}
+	if len(kept[0].Objects) != 2 {
+		t.Errorf("expected the organization role binding to be left out, got %d objects", len(kept[0].Objects))
+	}
+	if strings.Join(kept[0].Emails, ",") != "alice@example.com" {
+		t.Errorf("expected only the emails of the kept objects, got %v", kept[0].Emails)
+	}
+	if summary.FilesOutOfScope != 1 || len(results.Files) != 1 || results.Files[0].SkipReason != outOfScopeReason {
+		t.Errorf("expected the billing file to be skipped, got %d files out of scope", summary.FilesOutOfScope)
+	}
+	if !strings.Contains(summary.markdown(), "**Restricted to projects:** `pay*`") {
+		t.Errorf("expected the projects in the job summary, got %s", summary.markdown())
+	}
+}
+
+func TestPrepareFileLocatesInvalidObjects(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	manifest := `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+  labels:
+    Team: [payments]
+spec:
+  description: Payments
+`
+	if err := os.WriteFile(filePath, []byte(manifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := annotationOut
+	defer func() { annotationOut = previous }()
+	var annotations bytes.Buffer
+	annotationOut = &annotations
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = prepareFile(parsed, map[string]string{}, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "Project 'payments' at line 6, column 5:") {
+		t.Errorf("expected the invalid label to be located, got %v", err)
+	}
+	if !strings.HasPrefix(annotations.String(), "::error file=") || !strings.Contains(annotations.String(), ",line=6,col=5::Project 'payments': ") {
+		t.Errorf("expected a GitHub annotation at the invalid label, got %q", annotations.String())
+	}
+
+	// validate reports the same position
+	annotations.Reset()
+	err = validateFile(context.Background(), filePath)
+	if err == nil || !strings.Contains(err.Error(), "Project 'payments' at line 6, column 5:") {
+		t.Errorf("expected validate to locate the invalid label, got %v", err)
+	}
+	if !strings.Contains(annotations.String(), ",line=6,col=5::Project 'payments': ") {
+		t.Errorf("expected validate to annotate the invalid label, got %q", annotations.String())
+	}
+}
+
+func TestEscapeAnnotation(t *testing.T) {
+	if got := escapeAnnotationData("100% invalid\nsecond line"); got != "100%25 invalid%0Asecond line" {
+		t.Errorf("unexpected message escaping: %q", got)
+	}
+	if got := escapeAnnotationProperty("dir,a/b:c.yaml"); got != "dir%2Ca/b%3Ac.yaml" {
+		t.Errorf("unexpected property escaping: %q", got)
+	}
+}
+
+func TestValidateFileWritesSARIF(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	dir := t.TempDir()
+	config.SarifFile = filepath.Join(dir, "nobl9.sarif")
+
+	filePath := filepath.Join(dir, "payments.yaml")
+	manifest := `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+  labels:
+    Team: [payments]
+spec:
+  description: Payments
+`
+	if err := os.WriteFile(filePath, []byte(manifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeSARIF := startSARIF()
+	if err := validateFile(context.Background(), filePath); err == nil {
+		t.Fatal("expected the invalid label to fail validation")
+	}
+	writeSARIF()
+
+	data, err := os.ReadFile(config.SarifFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`"ruleId": "invalid-object"`, `"uri": "` + repositoryPath(filePath) + `"`, `"startLine": 6`, `"startColumn": 5`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in the SARIF file, got %s", want, data)
+		}
+	}
+}
+
+func TestPrepareFileResolvePaths(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	config.ResolvePaths = "none"
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Emails) != 0 {
+		t.Errorf("expected no emails to resolve, got %v", parsed.Emails)
+	}
+
+	// Emails resolved elsewhere are not substituted into ineligible fields
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, obj := range file.Objects {
+		if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok && *rb.Spec.User != "alice@example.com" {
+			t.Errorf("expected the email to be kept, got %q", *rb.Spec.User)
+		}
+	}
+
+	config.ClientID, config.ClientSecret, config.RepoPath = "id", "secret", "."
+	config.UserCacheTTL = time.Hour
+	config.ResolvePaths = "AlertMethod:spec.email.to"
+	if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "invalid resolve-paths") {
+		t.Errorf("expected alert method paths to be rejected, got %v", err)
+	}
+}
+
+func TestRoleBindingsOf(t *testing.T) {
+	content := []byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: team
+spec:
+  users:
+    - alice@example.com
+    - id: bob@example.com
+  userIds: carol@example.com, Alice@example.com
+  roleRef: project-viewer
+  projectRef: payments
+---
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: owner
+  spec:
+    user: bob@example.com
+    roleRef: project-owner
+    projectRef: payments
+`)
+	user := "dave@example.com"
+	expanded := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "team-dave"}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "project-viewer", ProjectRef: "payments"})
+
+	bindings := roleBindingsOf(content, "team.yaml", []manifest.Object{expanded})
+	if len(bindings) != 3 {
+		t.Fatalf("expected 3 role bindings, got %+v", bindings)
+	}
+	if strings.Join(bindings[0].Users, ",") != "alice@example.com,bob@example.com,carol@example.com,Alice@example.com" {
+		t.Errorf("unexpected users %v", bindings[0].Users)
+	}
+	if bindings[1].Name != "owner" || bindings[2].Name != "team-dave" || bindings[2].Source != "team.yaml" {
+		t.Errorf("unexpected role bindings %+v", bindings[1:])
+	}
+
+	if count := warnDuplicateGrants([]*parsedFile{{Bindings: bindings}}, strings.ToLower); count != 1 {
+		t.Errorf("expected 1 duplicate grant, got %d", count)
+	}
+}
+
+func TestParseYAMLContentEmails(t *testing.T) {
+	// A separator inside a comment does not split the role binding
+	content := []byte(`apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+spec:
+  description: Payments --- team
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+# owners --- see the wiki
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice-viewer
+spec:
+  user: alice@example.com
+  roleRef: project-viewer
+  projectRef: payments
+`)
+
+	objects, emails, err := parseYAMLContent(content, "payments.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("expected 3 objects, got %d", len(objects))
+	}
+	if strings.Join(emails, ",") != "alice@example.com" {
+		t.Errorf("expected the role binding email once, got %v", emails)
+	}
+}
+
+func TestParseFileGeneratesRoleBindingNames(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	config.GenerateRoleBindingNames = true
+
+	filePath := filepath.Join(t.TempDir(), "team.yaml")
+	content := `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata: {}
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: payments
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := nobl9client.RoleBindingName("payments", "project-owner", "alice@example.com")
+	if len(parsed.Objects) != 1 || parsed.Objects[0].GetName() != expected {
+		t.Fatalf("expected role binding %q, got %v", expected, parsed.Objects)
+	}
+	if len(parsed.Bindings) != 1 || parsed.Bindings[0].Name != expected {
+		t.Errorf("expected the raw role binding to get the same name, got %+v", parsed.Bindings)
+	}
+}
+
+func TestMergeDuplicateRoleBindings(t *testing.T) {
+	binding := func(name, user, role string) manifest.Object {
+		return v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: name}, v1alphaRoleBinding.Spec{User: &user, RoleRef: role, ProjectRef: "payments"})
+	}
+	prepare := func(path string, objects ...manifest.Object) *preparedFile {
+		file := &preparedFile{Path: path, Objects: objects, Result: &ProcessResult{Kinds: make(nobl9client.KindCounts)}}
+		for _, obj := range objects {
+			file.addOutcome(newObjectOutcome(obj, statusPending))
+			file.Result.Kinds.Add(obj.GetKind().String(), 1)
+			file.Result.RoleBindingsCreated++
+		}
+		return file
+	}
+
+	first := prepare("a.yaml", binding("alice-owner", "00u1alice", "project-owner"), binding("alice-owner", "00u1alice", "project-owner"))
+	second := prepare("b.yaml", binding("payments-alice", "00u1alice", "project-owner"), binding("payments-alice-viewer", "00u1alice", "project-viewer"))
+
+	if merged := mergeDuplicateRoleBindings([]*preparedFile{first, second}); merged != 2 {
+		t.Fatalf("expected 2 merged role bindings, got %d", merged)
+	}
+	if len(first.Objects) != 1 || first.Outcomes[0].Status != statusPending {
+		t.Errorf("expected the first grant to be kept, got %d objects with status %s", len(first.Objects), first.Outcomes[0].Status)
+	}
+	if len(second.Objects) != 1 || second.Objects[0].GetName() != "payments-alice-viewer" {
+		t.Errorf("expected only the other role to be kept, got %v", second.Objects)
+	}
+	if second.Outcomes[0].Status != statusSkipped || second.Result.RoleBindingsMerged != 1 || second.Result.RoleBindingsCreated != 1 || second.Result.Kinds["RoleBinding"] != 1 {
+		t.Errorf("expected the duplicate to be counted as merged, got %+v", second.Result)
+	}
+}
+
+func TestAlertMethodRecipients(t *testing.T) {
+	content := `apiVersion: n9/v1alpha
+kind: AlertMethod
+metadata:
+  name: oncall
+  project: payments
+spec:
+  email:
+    to:
+      - oncall@example.com
+    cc:
+      - %s
+`
+	filePath := filepath.Join(t.TempDir(), "alerts.yaml")
+	if err := os.WriteFile(filePath, []byte(fmt.Sprintf(content, "lead@example.com")), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	// Recipients are never resolved or substituted
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Emails) != 0 {
+		t.Errorf("expected no emails to resolve, got %v", parsed.Emails)
+	}
+	if _, err := prepareFile(parsed, map[string]string{"oncall@example.com": "00u1oncall"}, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte(fmt.Sprintf(content, "Lead <lead@example>")), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "spec.email.cc") {
+		t.Errorf("expected the malformed cc recipient to be reported, got %v", err)
+	}
+	parsed, err = parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := prepareFile(parsed, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "not a valid email address") {
+		t.Errorf("expected the malformed recipient to fail the file, got %v", err)
+	}
+}
+
+func TestMarkedProject(t *testing.T) {
+	project := v1alphaProject.New(
+		v1alphaProject.Metadata{
+			Name:   "legacy",
+			Labels: v1alpha.Labels{"team": {"payments"}},
+		},
+		v1alphaProject.Spec{Description: "Legacy project"},
+	)
+	deleteAfter := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	marked := markedProject(project, deleteAfter)
+
+	if values := marked.Metadata.Labels[state.PendingDeleteLabel]; len(values) != 1 || values[0] != "true" {
+		t.Errorf("expected pending-delete label, got %v", marked.Metadata.Labels)
+	}
+	if values := marked.Metadata.Labels["team"]; len(values) != 1 || values[0] != "payments" {
+		t.Errorf("expected existing labels to be kept, got %v", marked.Metadata.Labels)
+	}
+	if value := marked.Metadata.Annotations[state.DeleteAfterAnnotation]; value != "2024-05-08T12:00:00Z" {
+		t.Errorf("expected delete-after annotation, got %q", value)
+	}
+	if marked.Spec.Description != "Legacy project" {
+		t.Errorf("expected spec to be kept, got %+v", marked.Spec)
+	}
+	if _, ok := project.Metadata.Labels[state.PendingDeleteLabel]; ok {
+		t.Error("expected original project labels not to be modified")
+	}
+	if err := marked.Validate(); err != nil {
+		t.Errorf("expected marked project to be valid: %v", err)
+	}
+}
+
+func TestRunResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":[{"title":"invalid role"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kinds, err := nobl9client.ParseKindFilter("rolebinding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, kinds, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := newTestAPI(t, server)
+	if err := applyPlanned(context.Background(), client, []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := newRunResults(time.Now(), false)
+	results.addFile(file)
+	results.addFailedFile("broken.yaml", phaseParse, fmt.Errorf("failed to parse YAML"), time.Millisecond)
+	results.finish(newRunSummary(2, false), 1)
+
+	resultsPath := filepath.Join(t.TempDir(), "out", "results.json")
+	if err := results.write(resultsPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(resultsPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var written runResults
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if written.SchemaVersion != resultsSchemaVersion || written.Success {
+		t.Errorf("expected failed run with schema version %d, got %+v", resultsSchemaVersion, written)
+	}
+	if len(written.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(written.Files))
+	}
+
+	applied := written.Files[0]
+	if applied.Success || applied.Error == nil || applied.Error.Phase != phaseApply {
+		t.Errorf("expected apply failure, got %+v", applied)
+	}
+	statuses := make(map[string]string)
+	for _, object := range applied.Objects {
+		statuses[object.Kind+"/"+object.Name] = object.Status
+	}
+	if statuses["Project/payments"] != statusSkipped {
+		t.Errorf("expected project to be skipped, got %q", statuses["Project/payments"])
+	}
+	if statuses["RoleBinding/payments-alice"] != statusFailed {
+		t.Errorf("expected role binding to fail, got %q", statuses["RoleBinding/payments-alice"])
+	}
+
+	broken := written.Files[1]
+	if broken.Error == nil || broken.Error.Type != string(errors.ErrorTypeFileProcessing) {
+		t.Errorf("expected file processing error, got %+v", broken.Error)
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     string
+		err       error
+		errType   errors.ErrorType
+		retryable bool
+	}{
+		{name: "classified", phase: phaseApply, err: errors.NewAuthError("bad credentials", nil), errType: errors.ErrorTypeAuth},
+		{name: "timeout", phase: phaseApply, err: fmt.Errorf("apply: %w", context.DeadlineExceeded), errType: errors.ErrorTypeTimeout, retryable: true},
+		{name: "rate limited", phase: phaseApply, err: &sdk.HTTPError{StatusCode: http.StatusTooManyRequests}, errType: errors.ErrorTypeRateLimit, retryable: true},
+		{name: "server error", phase: phaseApply, err: &sdk.HTTPError{StatusCode: http.StatusBadGateway}, errType: errors.ErrorTypeNobl9API, retryable: true},
+		{name: "unclassified prepare", phase: phasePrepare, err: fmt.Errorf("invalid objects"), errType: errors.ErrorTypeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			described := describeError(tt.phase, tt.err)
+			if described.Type != string(tt.errType) || described.Retryable != tt.retryable {
+				t.Errorf("expected %s (retryable %v), got %+v", tt.errType, tt.retryable, described)
+			}
+			if described.Phase != tt.phase {
+				t.Errorf("expected phase %s, got %s", tt.phase, described.Phase)
+			}
+		})
+	}
+}
+
+func TestDetermineExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "configuration", err: configError(fmt.Errorf("invalid kinds")), code: errors.ExitCodes[errors.ErrorTypeConfig]},
+		{name: "policy below a wrapper", err: typedError(errors.ErrorTypeValidation, "policy validation failed", errors.NewPolicyError("refusing to apply", nil)), code: errors.ExitCodes[errors.ErrorTypePolicy]},
+		{name: "rejected credentials", err: typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", &sdk.HTTPError{StatusCode: http.StatusUnauthorized}), code: errors.ExitCodes[errors.ErrorTypeAuth]},
+		{name: "untyped timeout", err: fmt.Errorf("export: %w", context.DeadlineExceeded), code: errors.ExitCodes[errors.ErrorTypeTimeout]},
+		{name: "wrapped file error", err: typedError(errors.ErrorTypeFileProcessing, "failed to scan files", fmt.Errorf("bad pattern")), code: errors.ExitCodes[errors.ErrorTypeFileProcessing]},
+		// The message is never matched: this one used to exit with the
+		// configuration code
+		{name: "untyped", err: fmt.Errorf("invalid config of the nobl9 api"), code: errors.ExitCodeGeneral},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := determineExitCode(tt.err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d for %v", tt.code, code, tt.err)
+			}
+		})
+	}
+
+	// The errors collected by a run are returned with it, but do not change
+	// its exit code
+	aggregator := errors.NewErrorAggregator()
+	aggregator.AddError(errors.NewUserResolutionError("failed to resolve user", errors.ErrUserNotFound))
+	aggregator.AddError(errors.NewNobl9APIError("failed to apply objects", nil))
+	collected := errors.NewFileProcessingError("processing completed with 2 errors", aggregator.Err())
+	if code := determineExitCode(collected); code != errors.ExitCodes[errors.ErrorTypeFileProcessing] {
+		t.Errorf("expected the file processing exit code, got %d for %v", code, collected)
+	}
+	if !stderrors.Is(collected, errors.ErrUserNotFound) || !strings.Contains(collected.Error(), "failed to apply objects") {
+		t.Errorf("expected the run error to hold every collected error, got %v", collected)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	config.LogLevel = "info"
+	config.LogFormat = "json"
+	config.Remote = true
+	config.ClientID = ""
+	err := runValidate(validateCmd, nil)
+	if code := determineExitCode(err); code != errors.ExitCodes[errors.ErrorTypeConfig] {
+		t.Errorf("expected the configuration exit code, got %d for %v", code, err)
+	}
+}
+
+func TestCheckSettingsDrift(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	previous := state.New()
+	previous.SetSettings(map[string]string{"file_pattern": "**/*.yaml", "prune": "false", "organization": "acme"})
+	if err := previous.Save(statePath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stateFile := config.StateFile
+	config.StateFile = statePath
+	defer func() { config.StateFile = stateFile }()
+
+	changes := checkSettingsDrift(map[string]string{"file_pattern": "**/*.yaml", "prune": "true", "organization": "acme"})
+	if len(changes) != 1 || changes[0].Name != "prune" || changes[0].Previous != "false" || changes[0].Current != "true" {
+		t.Errorf("expected prune to be reported as changed, got %+v", changes)
+	}
+
+	summary := newRunSummary(1, false)
+	summary.SettingChanges = changes
+	if !strings.Contains(summary.markdown(), "| prune | `false` | `true` |") {
+		t.Errorf("expected the change in the job summary, got %s", summary.markdown())
+	}
+}
+
+func TestResolveEmailsNormalized(t *testing.T) {
+	userCache := resolver.NewUserCache(time.Hour)
+	userCache.Set("alice@corp.com", &resolver.UserInfo{Email: "alice@corp.com", UserID: "00u1alice", Found: true})
+
+	normalizer, err := resolver.NewNormalizer(true, true, "old-corp.com=corp.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Both spellings resolve through the cached, normalized address
+	emails := []string{"Alice@old-corp.com", "alice+nobl9@corp.com"}
+	resolutions := resolveEmails(context.Background(), nil, userCache, normalizer, emails, nil)
+
+	for _, email := range emails {
+		if resolutions[email] != "00u1alice" {
+			t.Errorf("expected %s to resolve to 00u1alice, got %q", email, resolutions[email])
+		}
+	}
+}
+
+func TestResolveEmailsWithTestDouble(t *testing.T) {
+	api := nobl9test.New().AddUser("alice@example.com", "00u1alice")
+	userCache := resolver.NewUserCache(time.Hour)
+	errs := errors.NewErrorAggregator()
+
+	emails := []string{"alice@example.com", "nobody@example.com"}
+	resolutions, failures := resolveEmailsWithFailures(context.Background(), api, userCache, nil, emails, errs)
+
+	if len(resolutions) != 1 || resolutions["alice@example.com"] != "00u1alice" {
+		t.Errorf("expected only alice@example.com to resolve, got %v", resolutions)
+	}
+	if !stderrors.Is(failures["nobody@example.com"], errors.ErrUserNotFound) {
+		t.Errorf("expected nobody@example.com to fail as not found, got %v", failures)
+	}
+	if !errs.HasErrors() {
+		t.Error("expected the unknown email to be recorded as an error")
+	}
+
+	// Resolved emails are answered from the user cache
+	resolveEmails(context.Background(), api, userCache, nil, emails[:1], nil)
+	if calls := api.Calls("GetUser"); calls != 2 {
+		t.Errorf("expected one user lookup per email, got %d", calls)
+	}
+}
+
+func TestValidateRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/usrmgmt/v2/users":
+			if r.URL.Query().Get("phrase") == "alice@example.com" {
+				fmt.Fprint(w, `{"users":[{"userId":"00u1alice"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"users":[]}`)
+		case "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"shared"}}]`)
+		case "/get/rolebinding":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","metadata":{"name":"custom"},"spec":{"user":"00u1","roleRef":"project-auditor","projectRef":"shared"}}]`)
+		case "/get/service":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Service","metadata":{"name":"checkout","project":"payments"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	manifests := testManifest + `---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: shared-bob
+spec:
+  user: bob@example.com
+  roleRef: project-auditor
+  projectRef: shared
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: missing-carol
+spec:
+  user: 00u1carol
+  roleRef: project-superuser
+  projectRef: missing
+---
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: checkout
+  project: payments
+spec:
+  service: checkout
+  budgetingMethod: Occurrences
+  objectives:
+    - displayName: Good
+      value: 1
+      target: 0.99
+      rawMetric:
+        query:
+          prometheus:
+            promql: up
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+`
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseRemoteFile(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	issues, err := validateRemote(context.Background(), newTestSDKClient(t, server), []*parsedFile{parsed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	expected := []string{
+		"spec.projectRef references Project missing, which is not declared in the repository nor present in Nobl9",
+		"spec.indicator.metricSource references Agent payments/prometheus, which is not declared in the repository nor present in Nobl9; expected in " + filePath,
+		"email 'bob@example.com' does not resolve to a Nobl9 user",
+		"role 'project-superuser' is not a known project role",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected issues:\n%s", strings.Join(messages, "\n"))
+	}
+}
+
+func TestCompareOrgsReport(t *testing.T) {
+	newServer := func(projects string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/get/project" {
+				fmt.Fprint(w, projects)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		}))
+	}
+	staging := newServer(`[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments"}},{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"sandbox"}}]`)
+	defer staging.Close()
+	prod := newServer(`[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments"}}]`)
+	defer prod.Close()
+
+	kinds := []manifest.Kind{manifest.KindProject, manifest.KindSLO}
+	ctx := context.Background()
+	source, err := listObjects(ctx, newTestAPI(t, staging), kinds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	target, err := listObjects(ctx, newTestAPI(t, prod), kinds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := &compare.Result{Source: "staging", Target: "prod", Kinds: compare.Objects(kinds, source, target)}
+	var report strings.Builder
+	if err := writeComparison(&report, result, "text"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `Comparing staging (source) with prod (target)
+
+Project: 1 in both, 1 only in staging, 0 only in prod
+  - Project sandbox (only in staging)
+
+SLO: 0 in both, 0 only in staging, 0 only in prod
+`
+	if report.String() != expected {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+}
+
+func TestRunPolicyValidation(t *testing.T) {
+	dir := t.TempDir()
+	unlabeled := filepath.Join(dir, "payments.yaml")
+	labeled := filepath.Join(dir, "billing.yaml")
+	if err := os.WriteFile(unlabeled, []byte(testManifest), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(labeled, []byte(`apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: billing
+  labels:
+    team: [billing]
+`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	regoPath := filepath.Join(dir, "bindings.rego")
+	if err := os.WriteFile(regoPath, []byte(`package nobl9
+
+deny_owner contains "project owners are granted in Nobl9" if {
+	input.kind == "RoleBinding"
+	input.spec.roleRef == "project-owner"
+}
+`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rego, err := policy.LoadRego(context.Background(), []string{regoPath})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checks := &guardrails{rules: policy.Default(), rego: rego}
+
+	violations, err := checkGuardrails(context.Background(), checks, []*parsedFile{mustParseRemoteFile(t, unlabeled)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations[unlabeled]) != 2 || violations[unlabeled][0].RuleID != policy.RuleRequiredProjectLabels || violations[unlabeled][1].RuleID != "deny_owner" {
+		t.Fatalf("expected required-project-labels and deny_owner violations, got %+v", violations)
+	}
+
+	compliant, err := runPolicyValidation(context.Background(), checks, []string{unlabeled, labeled})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(compliant) != 1 || compliant[0] != labeled {
+		t.Errorf("expected only %s to pass, got %v", labeled, compliant)
+	}
+}
+
+func TestRunAssertions(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	dir := t.TempDir()
+	testsDir := filepath.Join(dir, ".nobl9", "tests")
+	if err := os.MkdirAll(testsDir, 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(testManifest), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(testsDir, "owners.yaml"), []byte(`tests:
+  - name: payments has an owner
+    objects: RoleBinding
+    where: {spec.projectRef: payments, spec.roleRef: project-owner}
+    count: {min: 1}
+  - name: projects are described as teams
+    objects: Project
+    expect: {spec.description: Payments}
+`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.RepoPath = dir
+	config.FilePattern = "**/*.yaml"
+	config.TestsDir = testsDir
+	paths, err := inputFiles()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("expected the test file to be left out of the manifests, got %v", paths)
+	}
+
+	suite, err := assertions.Load(testsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, err := runAssertions(suite, paths)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var output strings.Builder
+	if err := writeTestReport(&output, report, "text"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testFile := filepath.Join(testsDir, "owners.yaml")
+	expected := fmt.Sprintf(`PASS payments has an owner (%[1]s)
+FAIL projects are described as teams (%[1]s)
+  Project payments (%[2]s): spec.description is "Payments team", expected "Payments"
+
+2 tests, 1 passed, 1 failed
+`, testFile, paths[0])
+	if output.String() != expected {
+		t.Errorf("unexpected report:\n%s", output.String())
+	}
+}
+
+func mustParseRemoteFile(t *testing.T, path string) *parsedFile {
+	t.Helper()
+	parsed, err := parseRemoteFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return parsed
+}
+
+func TestPromote(t *testing.T) {
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments-eu"}},{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"billing"}}]`)
+		case "/get/service":
+			if r.Header.Get(sdk.HeaderProject) == "payments-eu" {
+				fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Service","metadata":{"name":"checkout","project":"payments-eu"}}]`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer staging.Close()
+
+	var applied []string
+	prod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			body, _ := io.ReadAll(r.Body)
+			applied = append(applied, string(body))
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer prod.Close()
+
+	ctx := context.Background()
+	kinds := []manifest.Kind{manifest.KindService}
+	transforms := &promote.Transforms{ProjectPrefix: promote.ProjectPrefix{Add: "prod-"}}
+
+	sources, err := exportProjects(ctx, newTestSDKClient(t, staging), []string{"payments-*"}, kinds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	target := newTestSDKClient(t, prod)
+	existing, err := existingObjects(ctx, target, sources, transforms, kinds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan, err := promote.Build("staging", "prod", sources, existing, transforms)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var output strings.Builder
+	printPromotionPlan(&output, plan)
+	expected := `Promote from staging to prod: 2 to create, 0 to update, 0 skipped
+
+  1. create Project prod-payments-eu (from payments-eu)
+  2. create Service prod-payments-eu/checkout
+`
+	if output.String() != expected {
+		t.Errorf("unexpected plan:\n%s", output.String())
+	}
+
+	if err := executePromotionPlan(ctx, target, plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != 2 || !strings.Contains(applied[0], `"prod-payments-eu"`) || !strings.Contains(applied[1], `"checkout"`) {
+		t.Errorf("unexpected applies: %v", applied)
+	}
+
+	if _, err := exportProjects(ctx, newTestSDKClient(t, staging), []string{"orders-*"}, kinds); err == nil {
+		t.Error("expected an error when no project matches")
+	}
+}
+
+func TestEnvironmentVariableName(t *testing.T) {
+	if got := environmentVariableName("us-prod.2"); got != "US_PROD_2" {
+		t.Errorf("unexpected variable name: %s", got)
+	}
+}
+
+func TestApplyFileMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request":{"title":"PAY-7 Add checkout SLOs"}}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+
+	files := []*parsedFile{
+		{Path: "prod.yaml", Meta: &parser.Meta{Spec: parser.MetaSpec{Organization: "acme-prod"}}},
+		{Path: "payments.yaml", Meta: &parser.Meta{Spec: parser.MetaSpec{Organization: "acme", Owner: "team-payments", RequireTicket: true}}},
+		{Path: "ops.yaml", Meta: &parser.Meta{Spec: parser.MetaSpec{RequireTicket: true, TicketPattern: "OPS-[0-9]+"}}},
+		{Path: "plain.yaml"},
+	}
+
+	summary := newRunSummary(len(files), false)
+	results := newRunResults(time.Now(), false)
+	kept := applyFileMeta(context.Background(), newTestAPI(t, server), files, summary, results)
+
+	if len(kept) != 2 || kept[0].Path != "payments.yaml" || kept[1].Path != "plain.yaml" {
+		t.Fatalf("unexpected files kept: %v", kept)
+	}
+	if summary.FilesSkipped != 1 || summary.FilesWithErrors != 1 {
+		t.Errorf("expected 1 skipped and 1 failed file, got %d and %d", summary.FilesSkipped, summary.FilesWithErrors)
+	}
+
+	results.finish(summary, summary.FilesWithErrors)
+	if len(results.Files) != 2 {
+		t.Fatalf("expected 2 file results, got %+v", results.Files)
+	}
+	if results.Files[0].Path != "prod.yaml" || results.Files[0].SkipReason != "targets organization acme-prod" {
+		t.Errorf("unexpected skipped file: %+v", results.Files[0])
+	}
+	if failure := results.Files[1].Error; results.Files[1].Path != "ops.yaml" || failure == nil || failure.Type != string(errors.ErrorTypePolicy) {
+		t.Errorf("unexpected failed file: %+v", results.Files[1])
+	}
+	if results.owners["payments.yaml"] != "team-payments" {
+		t.Errorf("expected the owner to be recorded, got %v", results.owners)
+	}
+}
+
+func TestSkipPruneProjects(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := []*parsedFile{
+		{Path: "payments.yaml", Meta: &parser.Meta{Spec: parser.MetaSpec{SkipPrune: true}}, Objects: objects},
+		{Path: "other.yaml", Objects: objects[:1]},
+	}
+
+	projects := skipPruneProjects(files)
+	if len(projects) != 1 || !projects["payments"] {
+		t.Errorf("expected payments to skip pruning, got %v", projects)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var applied []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/usrmgmt/v2/users":
+			fmt.Fprint(w, `{"users":[{"userId":"00u1alice"}]}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/apply":
+			body, _ := io.ReadAll(r.Body)
+			applied = append(applied, string(body))
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "teams.csv")
+	if err := os.WriteFile(input, []byte("team,owners,environments\npayments,alice@example.com,prod\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	teams, err := generate.LoadTeams(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	templates, err := generate.LoadTemplates("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := templates.Render(teams[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Written manifests parse like any other file and are not replaced
+	// once edited unless overwrite is set
+	outputDir := filepath.Join(dir, "nobl9", "teams")
+	header := fmt.Sprintf(generatedHeader, "teams.csv")
+	if err := writeGeneratedFile(outputDir, header, file, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(outputDir, "payments.yaml")
+	parsed, err := parseFile(context.Background(), nil, nil, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Objects) != 3 || parsed.Meta == nil || parsed.Meta.Spec.Owner != "payments" {
+		t.Errorf("unexpected parsed file: %d objects, meta %+v", len(parsed.Objects), parsed.Meta)
+	}
+	if err := writeGeneratedFile(outputDir, header, file, false); err != nil {
+		t.Fatalf("expected an unchanged file to be accepted, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("# edited\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeGeneratedFile(outputDir, header, file, false); err == nil || !strings.Contains(err.Error(), "--overwrite") {
+		t.Fatalf("expected an overwrite error, got %v", err)
+	}
+	if err := writeGeneratedFile(outputDir, header, file, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := applyGenerated(context.Background(), newTestSDKClient(t, server), []*generate.File{file}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	all := strings.Join(applied, "\n")
+	for _, want := range []string{`"payments-prod"`, `"user":"00u1alice"`, `"kind":"Service"`} {
+		if !strings.Contains(all, want) {
+			t.Errorf("expected %s in applied objects, got %s", want, all)
+		}
+	}
+	if strings.Contains(all, "alice@example.com") || strings.Contains(all, parser.MetaKind) {
+		t.Errorf("expected only resolved Nobl9 objects to be applied, got %s", all)
+	}
+}
+
+func TestParseCSVFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "access.csv")
+	content := "project,email,role\npayments,alice@example.com,project-owner\npayments,bob@example.com,project-viewer\n"
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Objects) != 2 || parsed.Objects[0].GetName() != "payments-alice-example-com" {
+		t.Fatalf("unexpected role bindings: %v", parsed.Objects)
+	}
+	if strings.Join(parsed.Emails, ",") != "alice@example.com,bob@example.com" {
+		t.Errorf("unexpected emails: %v", parsed.Emails)
+	}
+
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice", "bob@example.com": "00u1bob"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file.Result.RoleBindingsCreated != 2 || file.Result.EmailsResolved != 2 {
+		t.Errorf("unexpected result: %+v", file.Result)
+	}
+
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("project,email,role\npayments,alice,project-owner\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a validation error for line 2, got %v", err)
+	}
+}
+
+func TestParseFileEnvironment(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	content := `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  environments:
+    dev:
+      projects:
+        payments: payments-dev
+    broken:
+      patches:
+        - target: {kind: Service, name: checkout}
+          patch:
+            spec: invalid
+---
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+---
+apiVersion: n9/v1alpha
+kind: Service
+metadata:
+  name: checkout
+  project: payments
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+
+	projects := func(environment string) string {
+		config.Environment = environment
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error in environment %q: %v", environment, err)
+		}
+		return parsed.Objects[0].GetName() + "," + parsed.Objects[1].(manifest.ProjectScopedObject).GetProject()
+	}
+
+	// Environments the file does not declare use the base manifest
+	if got := projects(""); got != "payments,payments" {
+		t.Errorf("expected the base manifest, got %s", got)
+	}
+	if got := projects("prod"); got != "payments,payments" {
+		t.Errorf("expected the base manifest, got %s", got)
+	}
+	if got := projects("dev"); got != "payments-dev,payments-dev" {
+		t.Errorf("expected the project and its service to be renamed, got %s", got)
+	}
+
+	// Without --environment every environment of the file is validated
+	config.Environment = ""
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "environment broken") {
+		t.Errorf("expected the broken overlay to fail validation, got %v", err)
+	}
+	config.Environment = "dev"
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestParseFileVariables(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	content := `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: ${TEAM}-{{ .Env.NOBL9_TEST_BRANCH }}
+spec:
+  description: Owned by ${TEAM}
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	t.Setenv("NOBL9_TEST_BRANCH", "main")
+
+	config.Vars = nil
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "undefined variables: TEAM") {
+		t.Errorf("expected the undefined variable to fail validation, got %v", err)
+	}
+
+	config.Vars = []string{"TEAM=payments"}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Objects) != 1 || parsed.Objects[0].GetName() != "payments-main" {
+		t.Errorf("unexpected objects: %v", parsed.Objects)
+	}
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestParseFileCompatibility(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "latency.yaml")
+	content := `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  owner: payments
+---
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: latency
+  project: payments
+spec:
+  service: api
+  budgetingMethod: Occurrences
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+  thresholds:
+    - displayName: Good
+      value: 200
+      target: 0.99
+      op: lte
+      rawMetric:
+        query:
+          prometheus:
+            promql: latency
+`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+
+	// Without --migrate-fields the objectives the SDK would drop fail the file
+	config.MigrateFields = false
+	if _, err := parseFile(context.Background(), nil, nil, filePath); err == nil || !strings.Contains(err.Error(), "line 21: SLO 'latency' spec.thresholds was renamed to spec.objectives") {
+		t.Errorf("expected the renamed field to fail the file, got %v", err)
+	}
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "--migrate-fields") {
+		t.Errorf("expected validation to suggest --migrate-fields, got %v", err)
+	}
+
+	config.MigrateFields = true
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Meta == nil || parsed.Meta.Spec.Owner != "payments" {
+		t.Errorf("expected the ActionMeta to survive the migration, got %+v", parsed.Meta)
+	}
+	slo, ok := parsed.Objects[0].(v1alphaSLO.SLO)
+	if len(parsed.Objects) != 1 || !ok || len(slo.Spec.Objectives) != 1 {
+		t.Errorf("expected the SLO to keep its objectives, got %v", parsed.Objects)
+	}
+}
+
+func TestParseFileJSON(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "generated.json")
+	content := `[
+  {
+    "apiVersion": "n9/v1alpha",
+    "kind": "Project",
+    "metadata": {"name": "${TEAM}"},
+    "spec": {"description": "Generated by Terraform"}
+  },
+  {
+    "apiVersion": "n9/v1alpha",
+    "kind": "RoleBinding",
+    "metadata": {"name": "payments-alice"},
+    "spec": {"user": "alice@example.com", "roleRef": "project-owner", "projectRef": "payments"}
+  }
+]`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "generator"}`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	config.Vars = []string{"TEAM=payments"}
+
+	files, err := scanFiles(dir, "**/*.{yaml,json}")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("expected both JSON files to be scanned, got %v (%v)", files, err)
+	}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Objects) != 2 || parsed.Objects[0].GetName() != "payments" {
+		t.Errorf("expected the project and role binding, got %v", parsed.Objects)
+	}
+	if len(parsed.Emails) != 1 || parsed.Emails[0] != "alice@example.com" {
+		t.Errorf("expected the role binding email, got %v", parsed.Emails)
+	}
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	// Other JSON files are skipped like YAML files without Nobl9 objects
+	parsed, err = parseFile(context.Background(), nil, nil, filepath.Join(dir, "package.json"))
+	if err != nil || len(parsed.Objects) != 0 {
+		t.Errorf("expected package.json to hold no objects, got %v (%v)", parsed, err)
+	}
+}
+
+func TestParseFileRender(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "projects.jsonnet")
+	content := `[
+  { apiVersion: 'nobl9-action/v1', kind: 'ActionMeta', spec: { owner: 'payments' } },
+] + [
+  { apiVersion: 'n9/v1alpha', kind: 'Project', metadata: { name: team }, spec: { description: 'Owned by ' + team } }
+  for team in ['payments', 'billing']
+]`
+	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+
+	// Without --render the sources are not manifests
+	config.Render = ""
+	if files, err := scanFiles(dir, "*.jsonnet"); err != nil || len(files) != 0 {
+		t.Errorf("expected no files without --render, got %v (%v)", files, err)
+	}
+
+	config.Render = "jsonnet"
+	if files, err := scanFiles(dir, "*.jsonnet"); err != nil || len(files) != 1 {
+		t.Errorf("expected the Jsonnet file to be scanned, got %v (%v)", files, err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Meta == nil || parsed.Meta.Spec.Owner != "payments" {
+		t.Errorf("expected the rendered ActionMeta, got %+v", parsed.Meta)
+	}
+	if len(parsed.Objects) != 2 || parsed.Objects[1].GetName() != "billing" {
+		t.Errorf("expected both rendered projects, got %v", parsed.Objects)
+	}
+	if err := validateFile(context.Background(), filePath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, []byte(`{ kind: error 'unknown team' }`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateFile(context.Background(), filePath); err == nil || !strings.Contains(err.Error(), "unknown team") {
+		t.Errorf("expected the evaluation error, got %v", err)
+	}
+
+	config.Render = "helm"
+	if _, err := inputFiles(); err == nil || !strings.Contains(err.Error(), "unknown render engine") {
+		t.Errorf("expected an unknown engine to be rejected, got %v", err)
+	}
+}
+
+func TestParseFileValues(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.yaml": "team:\n  name: platform\n  description: Shared services\n",
+		"team.yaml": "team:\n  name: payments\n",
+		"project.yaml": `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: "{{ .Values.team.name }}"
+spec:
+  description: "{{ .Values.team.description }} of ${OWNER}"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	config.Vars = []string{"OWNER=payments-team"}
+	config.Values = []string{filepath.Join(dir, "base.yaml"), filepath.Join(dir, "team.yaml"), ""}
+
+	parsed, err := parseFile(context.Background(), nil, nil, filepath.Join(dir, "project.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	project, ok := parsed.Objects[0].(v1alphaProject.Project)
+	if !ok || project.GetName() != "payments" || project.Spec.Description != "Shared services of payments-team" {
+		t.Errorf("expected the merged values to be substituted, got %+v", parsed.Objects)
+	}
+
+	config.Values = []string{filepath.Join(dir, "missing.yaml")}
+	if err := validateFile(context.Background(), filepath.Join(dir, "project.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read values file") {
+		t.Errorf("expected a missing values file to fail, got %v", err)
+	}
+}
+
+func TestParseFileOwners(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"payments/project.yaml": "apiVersion: n9/v1alpha\nkind: Project\nmetadata:\n  name: payments\n",
+		"payments/OWNERS":       "approvers:\n  - alice@example.com\nreviewers:\n  - alice@example.com\n  - bob@example.com\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	config.RepoPath = dir
+	config.FilePattern = "**/*.yaml"
+	config.CSV = ""
+	config.OwnersRoles = nobl9client.DefaultOwnersRoles
+
+	// OWNERS files are only read with --owners-files
+	config.OwnersFiles = false
+	if paths, err := inputFiles(); err != nil || len(paths) != 1 {
+		t.Errorf("expected only the manifest without --owners-files, got %v (%v)", paths, err)
+	}
+
+	config.OwnersFiles = true
+	paths, err := inputFiles()
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("expected the manifest and the OWNERS file, got %v (%v)", paths, err)
+	}
+	ownersPath := filepath.Join(dir, "payments", "OWNERS")
+	parsed, err := parseFile(context.Background(), nil, nil, ownersPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roles := make(map[string]string)
+	for _, obj := range parsed.Objects {
+		binding := obj.(v1alphaRoleBinding.RoleBinding)
+		if binding.Spec.ProjectRef != "payments" {
+			t.Errorf("expected role bindings of the directory's project, got %s", binding.Spec.ProjectRef)
+		}
+		roles[*binding.Spec.User] = binding.Spec.RoleRef
+	}
+	if len(roles) != 2 || roles["alice@example.com"] != "project-owner" || roles["bob@example.com"] != "project-viewer" {
+		t.Errorf("expected approvers to own and reviewers to view the project, got %v", roles)
+	}
+	if len(parsed.Emails) != 2 {
+		t.Errorf("expected both emails to be resolved, got %v", parsed.Emails)
+	}
+	if err := validateFile(context.Background(), ownersPath); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	config.OwnersRoles = "reviewers=project-editor"
+	parsed, err = parseFile(context.Background(), nil, nil, ownersPath)
+	if err != nil || len(parsed.Objects) != 2 || parsed.Objects[0].(v1alphaRoleBinding.RoleBinding).Spec.RoleRef != "project-editor" {
+		t.Errorf("expected only the reviewers as project editors, got %v (%v)", parsed, err)
+	}
+
+	config.OwnersRoles = "maintainers=project-owner"
+	if _, err := inputFiles(); err == nil || !strings.Contains(err.Error(), "unknown OWNERS list") {
+		t.Errorf("expected an unknown list to be rejected, got %v", err)
+	}
+}
+
+func TestHandleUnresolvedUsers(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	newFiles := func() []*parsedFile {
+		alice, bob := "alice@example.com", "bob@example.com"
+		return []*parsedFile{{Path: "payments.yaml", Objects: []manifest.Object{
+			v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{}),
+			v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-alice"}, v1alphaRoleBinding.Spec{User: &alice, RoleRef: "project-owner", ProjectRef: "payments"}),
+			v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-bob"}, v1alphaRoleBinding.Spec{User: &bob, RoleRef: "project-viewer", ProjectRef: "payments"}),
+		}}}
+	}
+	unresolved := []string{"bob@example.com"}
+
+	config.OnUnresolvedUser = "warn"
+	files := newFiles()
+	if err := handleUnresolvedUsers(files, unresolved); err != nil || len(files[0].Objects) != 3 {
+		t.Errorf("expected warn to keep the role binding, got %d objects (%v)", len(files[0].Objects), err)
+	}
+
+	config.OnUnresolvedUser = "skip"
+	files = newFiles()
+	if err := handleUnresolvedUsers(files, unresolved); err != nil || len(files[0].Objects) != 2 || files[0].Objects[1].GetName() != "payments-alice" {
+		t.Errorf("expected skip to drop bob's role binding, got %v (%v)", files[0].Objects, err)
+	}
+
+	config.OnUnresolvedUser = "placeholder"
+	config.UnresolvedUserGroup = "group-Q72HorLyjjCc"
+	files = newFiles()
+	if err := handleUnresolvedUsers(files, unresolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	placeholder := files[0].Objects[2].(v1alphaRoleBinding.RoleBinding)
+	if placeholder.Spec.User != nil || placeholder.Spec.GroupRef == nil || *placeholder.Spec.GroupRef != "group-Q72HorLyjjCc" || placeholder.Spec.RoleRef != "project-viewer" {
+		t.Errorf("expected the placeholder group to get bob's role, got %+v", placeholder.Spec)
+	}
+	if err := placeholder.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	config.OnUnresolvedUser = "fail"
+	err := handleUnresolvedUsers(newFiles(), unresolved)
+	if err == nil || !strings.Contains(err.Error(), "bob@example.com (role binding 'payments-bob' in payments.yaml)") {
+		t.Errorf("expected fail to name the unresolved user, got %v", err)
+	}
+	if determineExitCode(err) != 10 {
+		t.Errorf("expected the user resolution exit code, got %d", determineExitCode(err))
+	}
+
+	config.UnresolvedUserGroup = ""
+	config.OnUnresolvedUser = "placeholder"
+	if err := checkUnresolvedUserPolicy(); err == nil {
+		t.Error("expected placeholder to require a group")
+	}
+	config.OnUnresolvedUser = "ignore"
+	if err := checkUnresolvedUserPolicy(); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+}
+
+func TestReportUnresolvedUsers(t *testing.T) {
+	previous, previousOutputs, previousUsers := config, githubOutputs, unresolvedUsers
+	defer func() { config, githubOutputs, unresolvedUsers = previous, previousOutputs, previousUsers }()
+	githubOutputs = outputs.NewWriter("")
+	unresolvedUsers = nil
+	dir := t.TempDir()
+	config.RepoPath = dir
+	config.UnresolvedUsersFile = filepath.Join(dir, "artifacts", "unresolved-users.json")
+
+	files := []*parsedFile{
+		{Path: filepath.Join(dir, "payments.yaml"), Emails: []string{"alice@example.com", "bob@example.com"}},
+		{Path: filepath.Join(dir, "billing.yaml"), Emails: []string{"bob@example.com", "carol@example.com"}},
+	}
+	resolutions := map[string]string{"alice@example.com": "00u1alice"}
+	failures := map[string]error{"bob@example.com": fmt.Errorf("user not found with email 'bob@example.com' in Nobl9")}
+	reportUnresolvedUsers(unresolvedUsersOf(files, resolutions, failures))
+
+	if got, _ := githubOutputs.Get("unresolved-users"); got != "bob@example.com,carol@example.com" {
+		t.Errorf("expected each unresolved email once, got %q", got)
+	}
+	data, err := os.ReadFile(config.UnresolvedUsersFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var written []unresolvedUser
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []unresolvedUser{
+		{Email: "bob@example.com", File: "payments.yaml", Reason: "user not found with email 'bob@example.com' in Nobl9"},
+		{Email: "bob@example.com", File: "billing.yaml", Reason: "user not found with email 'bob@example.com' in Nobl9"},
+		{Email: "carol@example.com", File: "billing.yaml", Reason: "not looked up, the run was aborted"},
+	}
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("expected %+v, got %+v", expected, written)
+	}
+
+	// A run of another organization adds its users
+	reportUnresolvedUsers([]unresolvedUser{{Email: "dave@example.com", File: "ops.yaml", Reason: "not found"}})
+	if got, _ := githubOutputs.Get("unresolved-users"); got != "bob@example.com,carol@example.com,dave@example.com" {
+		t.Errorf("expected the users of both runs, got %q", got)
+	}
+}
+
+func TestUserReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payments.yaml")
+	content := `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-leads
+spec:
+  user: "okta-group:Payments Leads"
+  roleRef: project-viewer
+  projectRef: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-team
+spec:
+  user: "github-team:acme/payments"
+  roleRef: project-viewer
+  projectRef: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-bob
+spec:
+  user: 00u2y4e4atkzaYkXP4x8
+  roleRef: project-editor
+  projectRef: payments
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	references, err := userReferences([]string{path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var users []string
+	for _, reference := range references {
+		users = append(users, reference.User+"="+reference.RoleBinding)
+	}
+	if got := strings.Join(users, ","); got != "alice@example.com=payments-alice,00u2y4e4atkzaYkXP4x8=payments-bob" {
+		t.Errorf("expected the single user role bindings, got %s", got)
+	}
+}
+
+func TestDiffItems(t *testing.T) {
+	dir := t.TempDir()
+	content := "apiVersion: n9/v1alpha\nkind: Project\nmetadata:\n  name: payments\n"
+	if err := os.MkdirAll(filepath.Join(dir, "payments"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "payments", "project.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := config
+	defer func() { config = previous }()
+	config.RepoPath = "."
+	config.FilePattern = "**/*.yaml"
+	config.CSV = ""
+	config.OwnersFiles = false
+
+	items, err := diffItems(context.Background(), "", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Object.GetName() != "payments" || items[0].Source != filepath.Join("payments", "project.yaml") {
+		t.Errorf("expected the project named relative to the directory, got %+v", items)
+	}
+	if config.RepoPath != "." {
+		t.Errorf("expected the repo path to be restored, got %s", config.RepoPath)
+	}
+}
+
+func TestPlanHash(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	prepare := func(userID string) *preparedFile {
+		file, err := prepareFile(parsed, map[string]string{"alice@example.com": userID}, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return file
+	}
+
+	approved, err := planHash([]*preparedFile{prepare("00u1alice")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkPlanHash(approved, approved); err != nil {
+		t.Errorf("expected the approved plan to pass, got %v", err)
+	}
+	if err := checkPlanHash(approved, ""); err != nil {
+		t.Errorf("expected any plan to pass without an approved hash, got %v", err)
+	}
+
+	// The email now resolves to another user, so the plan changed
+	changed, err := planHash([]*preparedFile{prepare("00u1other")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = checkPlanHash(changed, approved)
+	if err == nil || !strings.Contains(err.Error(), "does not match the approved plan hash "+approved) {
+		t.Fatalf("expected a plan hash mismatch, got %v", err)
+	}
+	if code := determineExitCode(err); code != 12 {
+		t.Errorf("expected exit code 12, got %d", code)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/usrmgmt/v2/users":
+			fmt.Fprint(w, `{"users":[{"userId":"00u1alice"}]}`)
+		case "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","organization":"acme","metadata":{"name":"payments"},"spec":{"description":"Edited in the UI","createdAt":"2024-05-01T12:00:00Z"}}]`)
+		case "/get/rolebinding":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","organization":"acme","metadata":{"name":"payments-alice"},"spec":{"user":"00u1alice","roleRef":"project-owner","projectRef":"payments"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	manifests := testManifest + `---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-sre
+spec:
+  user: okta-group:sre
+  roleRef: project-editor
+  projectRef: payments
+`
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	client := newTestSDKClient(t, server)
+	desired, err := declaredObjects(ctx, client, []string{filePath})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(desired) != 2 {
+		t.Fatalf("expected the okta-group role binding to be skipped, got %d objects", len(desired))
+	}
+	live, err := listObjects(ctx, nobl9API(client), itemKinds(desired))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report, err := drift.New().Detect(desired, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Checked != 2 || len(report.Drifted) != 1 || report.Drifted[0].Kind != manifest.KindProject {
+		t.Fatalf("expected only the project to drift, got %+v", report)
+	}
+
+	var out strings.Builder
+	if err := writeDriftReport(&out, report, "text"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `spec.description: "Payments team" -> "Edited in the UI"`
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("expected %q in report:\n%s", expected, out.String())
+	}
+}
+
+func TestSavePlan(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prepared, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	client := newTestAPI(t, server)
+
+	previous := config.PlanOut
+	config.PlanOut = filepath.Join(dir, "plan.bin")
+	defer func() { config.PlanOut = previous }()
+
+	if err := savePlan(context.Background(), client, []string{filePath}, []*preparedFile{prepared}, 1); err == nil {
+		t.Fatal("expected no plan to be written when files failed")
+	}
+	if err := savePlan(context.Background(), client, []string{filePath}, []*preparedFile{prepared}, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved, err := planner.ReadSavedPlan(config.PlanOut)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The saved plan has the hash reported by the plan run
+	hash, err := planHash([]*preparedFile{prepared})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.Hash != hash {
+		t.Errorf("expected plan hash %s, got %s", hash, saved.Hash)
+	}
+	if err := saved.CheckInputs([]string{filePath}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	objects, err := saved.Files[0].DecodeObjects()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	roleBinding, ok := objects[1].(v1alphaRoleBinding.RoleBinding)
+	if !ok || *roleBinding.Spec.User != "00u1alice" {
+		t.Errorf("expected the resolved user ID in the plan, got %+v", objects[1])
+	}
+
+	// Refusals are policy errors
+	if code := determineExitCode(refusePlan("the repository changed", saved)); code != 12 {
+		t.Errorf("expected exit code 12, got %d", code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/usrmgmt/v2/users":
+			fmt.Fprint(w, `{"users":[{"userId":"00u1alice","email":"alice@example.com"}]}`)
+		case "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","organization":"acme","metadata":{"name":"payments"},"spec":{"description":"Payments team","createdAt":"2024-05-01T12:00:00Z"}}]`)
+		case "/get/service":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Service","organization":"acme","metadata":{"name":"checkout","project":"payments"},"spec":{"description":""},"status":{"sloCount":2}}]`)
+		case "/get/rolebinding":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","organization":"acme","metadata":{"name":"payments-alice"},"spec":{"user":"00u1alice","roleRef":"project-owner","projectRef":"payments"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestSDKClient(t, server)
+	files, err := exportFiles(context.Background(), client, []string{"pay*"}, []manifest.Kind{manifest.KindService, manifest.KindRoleBinding})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "payments.yaml" || len(files[0].Objects) != 3 {
+		t.Fatalf("expected payments.yaml with 3 objects, got %+v", files)
+	}
+
+	content, err := files[0].Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"user: alice@example.com", "name: checkout", "description: Payments team"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("expected %q in export:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range []string{"00u1alice", "createdAt", "status", "organization"} {
+		if strings.Contains(string(content), unexpected) {
+			t.Errorf("expected no %q in export:\n%s", unexpected, content)
+		}
+	}
+}
+
+func TestApplyTimeoutAbortsRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(250 * time.Millisecond):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var prepared []*preparedFile
+	for _, project := range []string{"billing", "payments"} {
+		filePath := filepath.Join(dir, project+".yaml")
+		content := strings.ReplaceAll(testManifest, "payments", project)
+		if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		prepared = append(prepared, file)
+	}
+
+	results := newRunResults(time.Now(), false)
+	ctx, abort := abortOnCritical(context.Background(), results)
+	defer abort(nil)
+
+	applyCtx, endApply := withPhaseTimeout(ctx, abort, timeoutPhaseApply, 50*time.Millisecond)
+	err := applyPlanned(applyCtx, newTestAPI(t, server), prepared, false, nil, results.aggregator)
+	endApply()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase := errors.TimedOutPhase(abortedBy(ctx)); phase != timeoutPhaseApply {
+		t.Fatalf("expected the apply phase timeout to abort the run, got %v", abortedBy(ctx))
+	}
+
+	summary := newRunSummary(len(prepared), false)
+	recordApplied(prepared, summary, results)
+	if summary.FilesWithErrors != 1 || summary.FilesAborted != 1 {
+		t.Errorf("expected 1 failed and 1 aborted file, got %d and %d", summary.FilesWithErrors, summary.FilesAborted)
+	}
+}
+
+func TestPhaseTimeoutInTime(t *testing.T) {
+	results := newRunResults(time.Now(), false)
+	ctx, abort := abortOnCritical(context.Background(), results)
+	defer abort(nil)
+
+	phaseCtx, endPhase := withPhaseTimeout(ctx, abort, timeoutPhaseResolve, time.Minute)
+	if _, found := phaseCtx.Deadline(); !found {
+		t.Error("expected the phase context to carry the phase deadline")
+	}
+	endPhase()
+	if abortedBy(ctx) != nil {
+		t.Errorf("expected a phase finishing in time not to abort the run, got %v", abortedBy(ctx))
+	}
+}
+
+func TestRetryCall(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	config.RetryMaxAttempts = 3
+	config.RetryBaseDelay = time.Millisecond
+	config.RetryPolicies = "users=none"
+
+	unavailable := &sdk.HTTPError{StatusCode: http.StatusServiceUnavailable, URL: "http://127.0.0.1:4040/apply"}
+	attempts := 0
+	err := retryCall(context.Background(), retry.OperationApply, "apply objects", func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return unavailable
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("expected apply to succeed on the third attempt, got %v after %d attempts", err, attempts)
+	}
+
+	// An HTTP error is matched by its status, not by the port in its URL
+	badRequest := &sdk.HTTPError{StatusCode: http.StatusBadRequest, URL: "http://127.0.0.1:5030/apply"}
+	attempts = 0
+	err = retryCall(context.Background(), retry.OperationApply, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return badRequest
+	})
+	if err != badRequest || attempts != 1 {
+		t.Errorf("expected the bad request to be returned unchanged after 1 attempt, got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	err = retryCall(context.Background(), retry.OperationUsers, "get user", func(ctx context.Context) error {
+		attempts++
+		return unavailable
+	})
+	if err != unavailable || attempts != 1 {
+		t.Errorf("expected the none profile to make a single attempt, got %v after %d", err, attempts)
+	}
+
+	// A retry that would pass the deadline gives up with a timeout error
+	config.RetryBaseDelay = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	attempts = 0
+	err = retryCall(ctx, retry.OperationApply, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return unavailable
+	})
+	if !retry.BudgetExhausted(err) || !stderrors.Is(err, unavailable) || attempts != 1 {
+		t.Errorf("expected the budget error after 1 attempt, got %v after %d", err, attempts)
+	}
+	if !errors.IsTimeoutError(err) || !strings.Contains(err.Error(), "apply objects gave up after 1 of 3 attempts") {
+		t.Errorf("expected a timeout error naming the attempts, got %v", err)
+	}
+}
+
+func TestWithApplyGrace(t *testing.T) {
+	previous := forceStop
+	defer func() { forceStop = previous }()
+	forceStop = make(chan struct{})
+
+	// A signal lets the call in flight go on until forced to stop
+	ctx, cancel := context.WithCancelCause(context.Background())
+	callCtx, release := withApplyGrace(ctx)
+	defer release()
+	cancel(errors.NewCancelledError("run cancelled by interrupt", nil))
+	select {
+	case <-callCtx.Done():
+		t.Fatal("expected the call to continue after the run was cancelled by a signal")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(forceStop)
+	select {
+	case <-callCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected a second signal to abort the call")
+	}
+	if !isCancelled(context.Cause(callCtx)) {
+		t.Errorf("expected the call to be cancelled by the signal, got %v", context.Cause(callCtx))
+	}
+
+	// Any other abort ends the call at once
+	ctx, cancel = context.WithCancelCause(context.Background())
+	callCtx, release = withApplyGrace(ctx)
+	defer release()
+	critical := errors.NewAuthError("invalid token", nil)
+	cancel(critical)
+	select {
+	case <-callCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected a critical error to abort the call at once")
+	}
+	if context.Cause(callCtx) != error(critical) {
+		t.Errorf("expected the call to be aborted by %v, got %v", critical, context.Cause(callCtx))
+	}
+}
+
+func TestCancelledRunOutputs(t *testing.T) {
+	previous := githubOutputs
+	defer func() { githubOutputs = previous }()
+	githubOutputs = outputs.NewWriter("")
+
+	summary := newRunSummary(2, false)
+	summary.AbortedBy = errors.NewCancelledError("run cancelled by terminated", nil)
+	setRunOutputs(summary, 0)
+	if got, _ := githubOutputs.Get("partial"); got != "true" {
+		t.Errorf("expected partial output true, got %q", got)
+	}
+	if got, _ := githubOutputs.Get("success"); got != "false" {
+		t.Errorf("expected success output false, got %q", got)
+	}
+	if !strings.Contains(summary.markdown(), "**Cancelled:**") {
+		t.Error("expected the job summary to report the cancellation")
+	}
+	if code := determineExitCode(fmt.Errorf("processing %s: %w", abortedReason, summary.AbortedBy)); code != errors.ExitCodes[errors.ErrorTypeCancelled] {
+		t.Errorf("expected exit code %d, got %d", errors.ExitCodes[errors.ErrorTypeCancelled], code)
+	}
+}
+
+func TestAbortOnCritical(t *testing.T) {
+	applies := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			applies++
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errors":[{"title":"invalid token"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var prepared []*preparedFile
+	for _, project := range []string{"billing", "payments"} {
+		filePath := filepath.Join(dir, project+".yaml")
+		content := strings.ReplaceAll(testManifest, "payments", project)
+		if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		prepared = append(prepared, file)
+	}
+
+	results := newRunResults(time.Now(), false)
+	ctx, abort := abortOnCritical(context.Background(), results)
+	defer abort(nil)
+
+	if err := applyPlanned(ctx, newTestAPI(t, server), prepared, false, nil, results.aggregator); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applies != 1 {
+		t.Errorf("expected the apply to stop after the first rejected request, got %d requests", applies)
+	}
+	if abortedBy(ctx) == nil {
+		t.Fatal("expected the run to be aborted")
+	}
+
+	summary := newRunSummary(len(prepared), false)
+	recordApplied(prepared, summary, results)
+	summary.AbortedBy = abortedBy(ctx)
+	if summary.FilesWithErrors != 1 || summary.FilesAborted != 1 {
+		t.Errorf("expected 1 failed and 1 aborted file, got %d and %d", summary.FilesWithErrors, summary.FilesAborted)
+	}
+	if !prepared[1].Aborted || results.Files[1].SkipReason != abortedReason || results.Files[1].Success {
+		t.Errorf("expected the second file to be reported as aborted, got %+v", results.Files[1])
+	}
+	if !strings.Contains(summary.markdown(), "Aborted early after critical error") {
+		t.Errorf("expected the summary to report the abort:\n%s", summary.markdown())
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	dir := t.TempDir()
+	config.CheckpointFile = filepath.Join(dir, "checkpoint.json")
+	config.DryRun = false
+	config.ApplyGranularity = "file"
+
+	failBilling := true
+	var applied []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			body, _ := io.ReadAll(r.Body)
+			if failBilling && strings.Contains(string(body), "billing") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":[{"title":"invalid project"}]}`))
+				return
+			}
+			for _, project := range []string{"billing", "payments"} {
+				if strings.Contains(string(body), project) {
+					applied = append(applied, project)
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	for _, project := range []string{"billing", "payments"} {
+		content := strings.ReplaceAll(testManifest, "payments", project)
+		if err := os.WriteFile(filepath.Join(dir, project+".yaml"), []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	run := func() (*runSummary, *runResults) {
+		var prepared []*preparedFile
+		for _, project := range []string{"billing", "payments"} {
+			parsed, err := parseFile(context.Background(), nil, nil, filepath.Join(dir, project+".yaml"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			prepared = append(prepared, file)
+		}
+
+		summary := newRunSummary(len(prepared), false)
+		results := newRunResults(time.Now(), false)
+		startCheckpoint(time.Now())
+		prepared = skipCompletedFiles(prepared, summary, results)
+		if err := applyPlanned(context.Background(), newTestAPI(t, server), prepared, false, nil, results.aggregator); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		recordApplied(prepared, summary, results)
+		finishCheckpoint(summary)
+		return summary, results
+	}
+
+	// The failed run keeps the checkpoint of the file it completed
+	if summary, _ := run(); summary.FilesWithErrors != 1 {
+		t.Fatalf("expected the billing file to fail, got %d failed files", summary.FilesWithErrors)
+	}
+	saved, err := checkpoint.Load(config.CheckpointFile)
+	if err != nil || saved == nil {
+		t.Fatalf("expected a checkpoint to be kept, got %v", err)
+	}
+	if saved.Len() != 1 || saved.Files[filepath.Join(dir, "payments.yaml")].Digest == "" {
+		t.Errorf("expected only the payments file to be checkpointed, got %+v", saved.Files)
+	}
+
+	// The resumed run only applies the file that failed
+	config.Resume = true
+	failBilling = false
+	applied = nil
+	summary, results := run()
+	if summary.FilesResumed != 1 || summary.FilesWithErrors != 0 {
+		t.Errorf("expected 1 resumed file and no errors, got %d and %d", summary.FilesResumed, summary.FilesWithErrors)
+	}
+	if len(applied) == 0 {
+		t.Error("expected the billing file to be applied again")
+	}
+	for _, project := range applied {
+		if project != "billing" {
+			t.Errorf("expected only the billing file to be applied again, got %v", applied)
+			break
+		}
+	}
+	if len(results.Files) == 0 || results.Files[0].SkipReason != resumedReason {
+		t.Errorf("expected the payments file to be reported as resumed, got %+v", results.Files)
+	}
+	if _, err := os.Stat(config.CheckpointFile); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed once the run completed, got %v", err)
+	}
+}
+
+func TestOwnershipMarkerReachesApply(t *testing.T) {
+	var applied string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			body, _ := io.ReadAll(r.Body)
+			applied += string(body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_REPOSITORY", "acme/nobl9-config")
+	t.Setenv("GITHUB_SHA", "0a1b2c3")
+	ownerLabel, traceAnnotations := config.OwnerLabel, config.TraceAnnotations
+	config.OwnerLabel, config.TraceAnnotations = "owner=platform-team", true
+	defer func() { config.OwnerLabel, config.TraceAnnotations = ownerLabel, traceAnnotations }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := applyPlanned(context.Background(), newTestAPI(t, server), []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`"owner":["platform-team"]`,
+		`"nobl9-action/repository":"acme/nobl9-config"`,
+		`"nobl9-action/commit":"0a1b2c3"`,
+	} {
+		if !strings.Contains(applied, expected) {
+			t.Errorf("expected %s in applied objects, got %s", expected, applied)
+		}
+	}
+	// The prepared objects, which the plan hash covers, are left untouched
+	if project := file.Objects[0].(v1alphaProject.Project); project.Metadata.Labels["owner"] != nil {
+		t.Errorf("expected prepared project not to be marked, got %v", project.Metadata.Labels)
+	}
+}
+
+func TestApplyGranularityIsolatesFailures(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: search
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: indexer
+    project: search
+`
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			calls++
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), `"name":"search"`) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "projects.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		granularity string
+		calls       int
+		statuses    []string
+	}{
+		{granularity: planner.GranularityFile, calls: 1, statuses: []string{statusFailed, statusFailed, statusPending, statusPending}},
+		{granularity: planner.GranularityProject, calls: 3, statuses: []string{statusApplied, statusFailed, statusApplied, statusSkipped}},
+		{granularity: planner.GranularityObject, calls: 3, statuses: []string{statusApplied, statusFailed, statusApplied, statusSkipped}},
+	} {
+		t.Run(tt.granularity, func(t *testing.T) {
+			calls = 0
+			config.ApplyGranularity = tt.granularity
+			parsed, err := parseFile(context.Background(), nil, nil, filePath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			file, err := prepareFile(parsed, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := applyPlanned(context.Background(), newTestAPI(t, server), []*preparedFile{file}, false, nil, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if file.Err == nil {
+				t.Error("expected the file to fail")
+			}
+			if calls != tt.calls {
+				t.Errorf("expected %d apply calls, got %d", tt.calls, calls)
+			}
+			var statuses []string
+			for _, outcome := range file.Outcomes {
+				statuses = append(statuses, outcome.Status)
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.statuses, ",") {
+				t.Errorf("expected statuses %v, got %v", tt.statuses, statuses)
+			}
+		})
+	}
+}
+
+func TestRollbackRestoresAppliedObjects(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec:
+    description: New description
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+`
+	var applied, deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/apply":
+			body, _ := io.ReadAll(r.Body)
+			applied = append(applied, string(body))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path+" "+r.Header.Get(sdk.HeaderProject)+"/"+r.URL.Query().Get("name"))
+		case r.URL.Path == "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments"},"spec":{"description":"Old description"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := newTestSDKClient(t, server)
+	if err := applyPlanned(context.Background(), nobl9API(client), []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Roll back from the results file, as the rollback command does
+	resultsPath := filepath.Join(t.TempDir(), "results.json")
+	written := newRunResults(time.Now(), false)
+	written.addFile(file)
+	if err := written.write(resultsPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, err := readResults(resultsPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan, err := rollback.NewPlan(rollbackEntries(results))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	applied = nil
+	result := executeRollback(context.Background(), nobl9API(client), results, plan, false)
+
+	if result.RolledBack != 2 || result.Failed != 0 || result.Unknown != 0 {
+		t.Errorf("unexpected rollback result: %+v", result)
+	}
+	if len(applied) != 1 || !strings.Contains(applied[0], "Old description") {
+		t.Errorf("expected the previous project to be applied again, got %v", applied)
+	}
+	if len(deleted) != 1 || deleted[0] != "/delete/service payments/checkout" {
+		t.Errorf("expected the created service to be deleted, got %v", deleted)
+	}
+	for _, object := range results.Files[0].Objects {
+		if object.Status != statusRolledBack {
+			t.Errorf("expected %s %s to be rolled back, got %s", object.Kind, object.Name, object.Status)
+		}
+	}
+
+	// Rolled back objects are not rolled back again
+	if plan, err = rollback.NewPlan(rollbackEntries(results)); err != nil || plan.Steps() != 0 {
+		t.Errorf("expected nothing left to roll back, got %v (%v)", plan, err)
+	}
+}
+
+func TestSkipRecordedObjects(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+`
+	applies := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			applies++
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	config.StateFile = filepath.Join(t.TempDir(), "state.json")
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prepare := func() *preparedFile {
+		t.Helper()
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		file, err := prepareFile(parsed, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return file
+	}
+	client := newTestAPI(t, server)
+
+	// The first run applies both objects and records them
+	file := prepare()
+	if skipped := skipRecordedObjects([]*preparedFile{file}); skipped != 0 {
+		t.Fatalf("expected nothing skipped without a state file, got %d", skipped)
+	}
+	if err := applyPlanned(context.Background(), client, []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st.RecordObjects(appliedObjects(st, []*preparedFile{file}, time.Now())...)
+	if len(st.Objects) != 2 {
+		t.Fatalf("expected 2 recorded objects, got %v", st.Objects)
+	}
+	if err := st.Save(config.StateFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The second run finds them unchanged and applies nothing
+	applies = 0
+	file = prepare()
+	if skipped := skipRecordedObjects([]*preparedFile{file}); skipped != 2 {
+		t.Errorf("expected 2 objects skipped, got %d", skipped)
+	}
+	if err := applyPlanned(context.Background(), client, []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applies != 0 {
+		t.Errorf("expected no applies, got %d", applies)
+	}
+	for _, outcome := range file.Outcomes {
+		if outcome.Status != statusUnchanged {
+			t.Errorf("expected %s %s to be unchanged, got %s", outcome.Kind, outcome.Name, outcome.Status)
+		}
+	}
+}
+
+func TestSkipUnchangedLiveObjects(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+  spec:
+    description: New description
+`
+	var applied []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/apply":
+			body, _ := io.ReadAll(r.Body)
+			applied = append(applied, string(body))
+		case r.URL.Path == "/get/project":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","organization":"acme","metadata":{"name":"payments"},"spec":{"description":"Payments team","createdAt":"2024-05-01T12:00:00Z"}}]`)
+		case r.URL.Path == "/get/service":
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Service","metadata":{"name":"checkout","project":"payments"},"spec":{"description":"Old description"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := applyPlanned(context.Background(), newTestAPI(t, server), []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(applied) != 1 || strings.Contains(applied[0], `"Project"`) || !strings.Contains(applied[0], "New description") {
+		t.Errorf("expected only the changed service to be applied, got %v", applied)
+	}
+	if file.Result.ObjectsUnchanged != 1 {
+		t.Errorf("expected 1 unchanged object, got %d", file.Result.ObjectsUnchanged)
+	}
+	statuses := make(map[string]string)
+	for _, outcome := range file.Outcomes {
+		statuses[outcome.Kind] = outcome.Status
+	}
+	if statuses["Project"] != statusUnchanged || statuses["Service"] != statusApplied {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+}
+
+func TestSkipUnchangedRoleBindingsReusesLiveBindings(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-viewer
+  spec:
+    user: 00u1abcd
+    roleRef: project-viewer
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u2efgh
+    roleRef: project-owner
+    projectRef: payments
+`
+	roleBindingGets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/apply":
+		case r.URL.Path == "/get/rolebinding":
+			roleBindingGets++
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","metadata":{"name":"payments-viewer"},"spec":{"user":"00u1abcd","roleRef":"project-viewer","projectRef":"payments"}},`+
+				`{"apiVersion":"n9/v1alpha","kind":"RoleBinding","metadata":{"name":"payments-owner"},"spec":{"user":"00u2efgh","roleRef":"project-viewer","projectRef":"payments"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := applyPlanned(context.Background(), newTestAPI(t, server), []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if roleBindingGets != 1 {
+		t.Errorf("expected the live role bindings to be read once, got %d reads", roleBindingGets)
+	}
+	if file.Result.RoleBindingsUnchanged != 1 || file.Result.ObjectsUnchanged != 1 {
+		t.Errorf("expected 1 unchanged role binding counted as unchanged object, got %d and %d", file.Result.RoleBindingsUnchanged, file.Result.ObjectsUnchanged)
+	}
+	for _, outcome := range file.Outcomes {
+		if outcome.Name == "payments-owner" && (outcome.Rollback == nil || outcome.Rollback.Created) {
+			t.Errorf("expected the live role binding to be recorded for rollbacks, got %+v", outcome.Rollback)
+		}
+	}
+}
+
+func TestPruneProjectsWithTestDouble(t *testing.T) {
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	config.AutoApprove = true
+	defer func() { config = previous }()
+
+	api := nobl9test.New().AddProject("payments", "billing", "legacy")
+	now := time.Now()
+	st := state.New()
+	st.SetDeclared([]string{"payments", "billing", "legacy"})
+	st.MarkForDeletion("legacy", time.Hour, now.Add(-2*time.Hour))
+
+	result, err := pruneProjects(context.Background(), api, st, []string{"payments"}, time.Hour, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Marked != 1 || result.Deleted != 1 {
+		t.Errorf("expected billing to be marked and legacy deleted, got %+v", result)
+	}
+
+	billing, err := api.GetProject(context.Background(), "billing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := billing.Metadata.Labels[state.PendingDeleteLabel]; !ok {
+		t.Errorf("expected billing to be labelled for deletion, got %v", billing.Metadata.Labels)
+	}
+	if exists, _ := api.ProjectExists(context.Background(), "legacy"); exists {
+		t.Error("expected legacy to be deleted")
+	}
+	if _, marked := st.Tombstones["billing"]; !marked {
+		t.Error("expected a tombstone for billing")
+	}
+
+	// A project already gone from Nobl9 is forgotten instead of marked
+	st.SetDeclared([]string{"payments", "archived"})
+	if _, err := pruneProjects(context.Background(), api, st, []string{"payments"}, time.Hour, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range st.Projects {
+		if name == "archived" {
+			t.Errorf("expected archived to be forgotten, got %v", st.Projects)
+		}
+	}
+}
+
+func TestApplyPlannedWithTestDouble(t *testing.T) {
+	const manifests = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-viewer
+  spec:
+    user: 00u1abcd
+    roleRef: project-viewer
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u2efgh
+    roleRef: project-owner
+    projectRef: payments
+`
+	previous := config
+	config.OwnerLabel, config.TraceAnnotations, config.AuditAnnotations, config.AuditLog = "", false, false, ""
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(manifests), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	viewer, ok := parsed.Objects[1].(v1alphaRoleBinding.RoleBinding)
+	if !ok {
+		t.Fatalf("expected a role binding, got %T", parsed.Objects[1])
+	}
+	api := nobl9test.New().AddRoleBinding(viewer)
+
+	file, err := prepareFile(parsed, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := applyPlanned(context.Background(), api, []*preparedFile{file}, true, newServerDryRun(true), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.Calls("DryRunObjects") == 0 || len(api.Applied()) != 0 {
+		t.Errorf("expected the dry run to send objects without applying them, got %d dry runs and %d applied", api.Calls("DryRunObjects"), len(api.Applied()))
+	}
+
+	file, err = prepareFile(parsed, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := applyPlanned(context.Background(), api, []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var applied []string
+	for _, obj := range api.Applied() {
+		applied = append(applied, obj.GetName())
+	}
+	if strings.Join(applied, ",") != "payments,payments-owner" {
+		t.Errorf("expected the project and the changed role binding to be applied, got %v", applied)
+	}
+	if file.Result.RoleBindingsUnchanged != 1 {
+		t.Errorf("expected 1 unchanged role binding, got %d", file.Result.RoleBindingsUnchanged)
+	}
+	if _, err := api.GetProject(context.Background(), "payments"); err != nil {
+		t.Errorf("expected the project to be stored: %v", err)
+	}
+}
+
+func TestNotifySummary(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "acme/slos")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_SERVER_URL", "")
+
+	results := newRunResults(time.Now(), false)
+	results.Files = []fileResult{
+		{Path: "payments.yaml", Success: true, Objects: []objectResult{
+			{Kind: "Project", Name: "payments", Status: statusApplied, Rollback: &rollback.Record{Created: true}},
+			{Kind: "Service", Name: "checkout", Status: statusApplied, Rollback: &rollback.Record{Previous: []byte(`{}`)}},
+			{Kind: "SLO", Name: "latency", Status: statusUnchanged},
+		}},
+		{Path: "billing.yaml", Objects: []objectResult{
+			{Kind: "SLO", Name: "invoices", Status: statusFailed},
+		}, Error: &resultError{Message: "failed to apply objects"}},
+	}
+	summary := newRunSummary(2, false)
+	summary.FilesProcessed, summary.FilesWithErrors = 1, 1
+	summary.UnresolvedEmails = []string{"bob@example.com"}
+
+	notification := notifySummary(summary, results, 1)
+	if notification.Success || notification.Created != 1 || notification.Updated != 1 || notification.Unchanged != 1 || notification.Failed != 1 {
+		t.Errorf("unexpected counts: %+v", notification)
+	}
+	if notification.Repository != "acme/slos" || notification.Ref != "main" || notification.RunURL != "https://github.com/acme/slos/actions/runs/42" {
+		t.Errorf("unexpected run details: %+v", notification)
+	}
+	if len(notification.Errors) != 1 || notification.Errors[0] != "billing.yaml: failed to apply objects" {
+		t.Errorf("unexpected errors: %v", notification.Errors)
+	}
+	if len(notification.UnresolvedUsers) != 1 {
+		t.Errorf("unexpected unresolved users: %v", notification.UnresolvedUsers)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(data)
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_REPOSITORY", "acme")
+	previous, previousMetrics := config, runMetrics
+	config.PushgatewayURL, config.MetricsJob = server.URL, "sync"
+	defer func() { config, runMetrics = previous, previousMetrics }()
+	startMetrics()
+
+	results := newRunResults(time.Now(), false)
+	results.Files = []fileResult{
+		{Path: "billing.yaml", Objects: []objectResult{
+			{Kind: "SLO", Name: "invoices", Status: statusFailed},
+			{Kind: "SLO", Name: "latency", Status: statusApplied},
+		}, Error: &resultError{Phase: "apply", Type: "nobl9_api", Message: "failed to apply objects"}},
+	}
+	summary := newRunSummary(1, false)
+	summary.RateLimited = 2
+
+	pushMetrics(summary, results, 1)
+
+	if path != "/metrics/job/sync/repository/acme" {
+		t.Errorf("unexpected push path %q", path)
+	}
+	for _, want := range []string{
+		`nobl9_action_objects_total{kind="SLO",status="applied"} 1`,
+		`nobl9_action_objects_total{kind="SLO",status="failed"} 1`,
+		`nobl9_action_failures_total{phase="apply",type="nobl9_api"} 1`,
+		`nobl9_action_api_retries_total{reason="rate_limited"} 2`,
+		`nobl9_action_run_success 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in pushed metrics:\n%s", want, body)
+		}
+	}
+}
+
+func TestAuditLogRecordsApply(t *testing.T) {
+	var applied, queried string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apply":
+			body, _ := io.ReadAll(r.Body)
+			applied += string(body)
+		case "/get/project":
+			queried = r.URL.Query().Get("name")
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments"},"spec":{"description":"Old description"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_SHA", "0a1b2c3")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	previous := config
+	config.AuditLog, config.AuditAnnotations, config.OwnerLabel, config.TraceAnnotations = auditPath, true, "", false
+	defer func() { config = previous }()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := applyPlanned(context.Background(), newTestAPI(t, server), []*preparedFile{file}, false, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if queried != "payments" {
+		t.Errorf("expected the live project to be read by name, got %q", queried)
+	}
+	if !strings.Contains(applied, `"github.com/author":"octocat"`) || !strings.Contains(applied, `"github.com/commit":"0a1b2c3"`) {
+		t.Errorf("expected audit annotations in applied objects, got %s", applied)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actions := make(map[string]audit.Action)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry audit.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entry.Source != filePath || entry.Author != "octocat" || entry.After == "" {
+			t.Errorf("unexpected entry: %+v", entry)
+		}
+		actions[entry.Object.String()] = entry.Action
+	}
+	expected := map[string]audit.Action{
+		"Project payments":           audit.ActionUpdate,
+		"RoleBinding payments-alice": audit.ActionCreate,
+	}
+	if len(actions) != len(expected) || actions["Project payments"] != audit.ActionUpdate || actions["RoleBinding payments-alice"] != audit.ActionCreate {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	repoPath := config.RepoPath
+	config.RepoPath = "repo"
+	defer func() { config.RepoPath = repoPath }()
+
+	summary := newRunSummary(3, false)
+	summary.EmailsLookedUp, summary.EmailsUnresolved = 10, 5
+	results := newRunResults(time.Now(), false)
+	results.addFailedFile(filepath.Join("repo", "examples", "slo.yaml"), phaseParse, fmt.Errorf("failed to decode objects"), 0)
+	results.addError(phaseApply, fmt.Errorf("failed to apply objects: cannot access the token"))
+
+	summary.Recommendations = recommendations(summary, results)
+	var rules []string
+	for _, recommendation := range summary.Recommendations {
+		rules = append(rules, recommendation.Rule)
+	}
+	if strings.Join(rules, ",") != "rotate-credentials,unresolved-emails,exclude-directory" {
+		t.Errorf("unexpected recommendations: %v", rules)
+	}
+
+	markdown := summary.markdown()
+	if !strings.Contains(markdown, "### Recommendations") || !strings.Contains(markdown, "- Files under examples/ failed to process") {
+		t.Errorf("expected recommendations in the job summary, got:\n%s", markdown)
+	}
+}
+
+func TestApplyVariables(t *testing.T) {
+	defer func() { appliedVariables = nil }()
+	t.Setenv("NOBL9_ACTION_VARS", `{"NOBL9_ACTION_FILE_PATTERN":"nobl9/**/*.yaml","NOBL9_ACTION_KINDS":"project","NOBL9_ACTION_PROJECT":"payments","NOBL9_ACTION_PRUNE":true}`)
+	t.Setenv("NOBL9_ACTION_CLIENT_SECRET", "from-variable")
+
+	var filePattern, kinds, project, clientSecret string
+	var prune bool
+	cmd := &cobra.Command{Use: "process"}
+	cmd.Flags().StringVar(&filePattern, "file-pattern", "**/*.yaml", "")
+	cmd.Flags().StringVar(&kinds, "kinds", "", "")
+	cmd.Flags().StringVar(&project, "project", "", "")
+	cmd.Flags().StringVar(&clientSecret, "client-secret", "", "")
+	cmd.Flags().BoolVar(&prune, "prune", false, "")
+	// The action passes on every input, including the defaults
+	if err := cmd.ParseFlags([]string{"--file-pattern=**/*.yaml", "--kinds=slo", "--prune=false"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := applyVariables(cmd, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filePattern != "nobl9/**/*.yaml" || !prune {
+		t.Errorf("expected variables to replace defaults, got file-pattern %q, prune %t", filePattern, prune)
+	}
+	if kinds != "slo" {
+		t.Errorf("expected the workflow's kinds to win, got %q", kinds)
+	}
+	if clientSecret != "" {
+		t.Errorf("expected secrets not to be set from variables, got %q", clientSecret)
+	}
+	if project != "" {
+		t.Errorf("expected the project variable not to restrict process runs, got %q", project)
+	}
+	if strings.Join(appliedVariables, ",") != "NOBL9_ACTION_FILE_PATTERN,NOBL9_ACTION_PRUNE" {
+		t.Errorf("unexpected applied variables: %v", appliedVariables)
+	}
+
+	t.Setenv("NOBL9_ACTION_PRUNE", "maybe")
+	prune = false
+	if err := applyVariables(cmd, nil); err == nil || !strings.Contains(err.Error(), "NOBL9_ACTION_PRUNE") {
+		t.Errorf("expected an invalid variable error, got %v", err)
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	var queried int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/get/rolebinding" {
+			queried++
+			fmt.Fprint(w, `[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","metadata":{"name":"custom"},"spec":{"user":"00u1","roleRef":"payments-auditor","projectRef":"shared"}}]`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var files []*parsedFile
+	for name, role := range map[string]string{"payments.yaml": "project-owner", "audit.yaml": "payments-auditor", "typo.yaml": "project-ownr"} {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte(strings.Replace(testManifest, "project-owner", role, 1)), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := parseFile(context.Background(), nil, nil, filePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		files = append(files, parsed)
+	}
+	client := newTestSDKClient(t, server)
+
+	// Built-in roles need no lookup
+	unknown, err := checkRoles(context.Background(), nobl9API(client), roles.Default(), files[:0])
+	if err != nil || len(unknown) != 0 || queried != 0 {
+		t.Fatalf("expected nothing to check, got %v, %v after %d lookups", unknown, err, queried)
+	}
+
+	unknown, err = checkRoles(context.Background(), nobl9API(client), roles.Default(), files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unknown) != 1 || queried != 1 {
+		t.Fatalf("expected only the typo to be unknown after one lookup, got %v after %d lookups", unknown, queried)
+	}
+	typo := unknown[filepath.Join(dir, "typo.yaml")]
+	if len(typo) != 1 || typo[0].Suggestion != "project-owner" {
+		t.Errorf("expected project-owner to be suggested, got %+v", typo)
+	}
+}
+
+func TestMergeOrganizationRuns(t *testing.T) {
+	repoPath := config.RepoPath
+	config.RepoPath = t.TempDir()
+	defer func() { config.RepoPath = repoPath }()
+
+	prodFile := filepath.Join(config.RepoPath, "teams", "payments.yaml")
+	if err := os.MkdirAll(filepath.Dir(prodFile), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	meta := "apiVersion: nobl9-action/v1\nkind: ActionMeta\nspec:\n  organization: acme-prod\n---\n"
+	if err := os.WriteFile(prodFile, []byte(meta+testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if org := fileOrganization(prodFile); org != "acme-prod" {
+		t.Errorf("expected the ActionMeta organization, got %q", org)
+	}
+	if path := relativePath(prodFile); path != filepath.Join("teams", "payments.yaml") {
+		t.Errorf("expected a path relative to the repository, got %q", path)
+	}
+
+	summary := newRunSummary(3, false)
+	results := newRunResults(time.Now(), false)
+	for _, name := range []string{"acme-prod", "acme-staging"} {
+		orgSummary := newRunSummary(1, false)
+		orgSummary.add(&ProcessResult{ProjectsCreated: 1, RoleBindingsCreated: 2, Kinds: nobl9client.KindCounts{"Project": 1}})
+		orgSummary.APICalls["PUT /apply"] = 1
+		orgSummary.UserCache = map[string]interface{}{"hits": 1, "misses": 1}
+		orgSummary.PlanHash = "sha256:" + name
+		orgResults := newRunResults(time.Now(), false)
+		orgResults.addSkippedFile(name+".yaml", "")
+
+		summary.merge(orgSummary)
+		results.merge(name, orgResults)
+		summary.Organizations = append(summary.Organizations, organizationRun{Name: name, Summary: orgSummary})
+	}
+
+	if summary.FilesProcessed != 2 || summary.ProjectsCreated != 2 || summary.ObjectsByKind["Project"] != 2 || summary.APICalls["PUT /apply"] != 2 {
+		t.Errorf("expected the totals of both organizations, got %+v", summary)
+	}
+	if summary.UserCache["hits"] != 2 || summary.UserCache["hit_rate"] != 0.5 {
+		t.Errorf("expected combined user cache statistics, got %v", summary.UserCache)
+	}
+	if hash := organizationsPlanHash(summary.Organizations); hash == "" || hash == organizationsPlanHash(summary.Organizations[:1]) {
+		t.Errorf("expected the plan hash to cover every organization, got %q", hash)
+	}
+	if len(results.Files) != 2 || results.Files[1].Organization != "acme-staging" {
+		t.Errorf("expected files marked with their organization, got %+v", results.Files)
+	}
+	if markdown := summary.markdown(); !strings.Contains(markdown, "### Organizations") || !strings.Contains(markdown, "| acme-staging | 1 | 1 | 0 | 0 | 1 | 2 |") {
+		t.Errorf("expected an organizations table in the job summary, got:\n%s", markdown)
+	}
+}
+
+func TestRunOrganizationsKeepsErrorType(t *testing.T) {
+	previous, previousOutputs := config, githubOutputs
+	defer func() { config, githubOutputs = previous, previousOutputs }()
+	githubOutputs = outputs.NewWriter("")
+	config.RepoPath = t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+
+	// The organization cannot be processed with a policy that does not load
+	config.Policy = filepath.Join(config.RepoPath, "missing-policy.yaml")
+	path := filepath.Join(config.RepoPath, "payments.yaml")
+	if err := os.WriteFile(path, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orgs := []organizations.Organization{{Name: "acme-prod", ClientID: "client", ClientSecret: "secret", Default: true}}
+
+	err := runOrganizations(context.Background(), time.Now(), orgs, []string{path})
+	if err == nil || !strings.Contains(err.Error(), "organization acme-prod") {
+		t.Fatalf("expected the organization's error, got %v", err)
+	}
+	if code := determineExitCode(err); code != errors.ExitCodes[errors.ErrorTypeConfig] {
+		t.Errorf("expected the exit code of the configuration error, got %d (%v)", code, err)
+	}
+}
+
+func TestCheckBudgetImpact(t *testing.T) {
+	const slo = `apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: checkout-latency
+  project: payments
+spec:
+  service: checkout
+  budgetingMethod: Occurrences
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+  objectives:
+    - name: fast
+      displayName: Fast
+      value: 200
+      target: %s
+      op: lte
+`
+	live, err := sdk.DecodeObjects([]byte(fmt.Sprintf(slo, "0.99")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desired, err := sdk.DecodeObjects([]byte(fmt.Sprintf(slo, "0.999")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/get/slo" {
+			json.NewEncoder(w).Encode(live)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	threshold := config.BudgetShrinkThreshold
+	config.BudgetShrinkThreshold = 50
+	defer func() { config.BudgetShrinkThreshold = threshold }()
+
+	files := []*preparedFile{{Path: "slos.yaml", Objects: desired}}
+	changes := checkBudgetImpact(context.Background(), newTestAPI(t, server), files)
+	if len(changes) != 1 || changes[0].Source != "slos.yaml" || changes[0].Objective != "fast" {
+		t.Fatalf("expected the tightened objective to be flagged, got %+v", changes)
+	}
+
+	summary := newRunSummary(1, true)
+	summary.HighImpact = changes
+	if markdown := summary.markdown(); !strings.Contains(markdown, "### High Impact Changes") || !strings.Contains(markdown, "| payments/checkout-latency | fast | 99% → 99.9% | -90% | slos.yaml |") {
+		t.Errorf("expected the change in the job summary, got:\n%s", markdown)
+	}
+}
+
+func TestLintSLOs(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(`apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: checkout-latency
+  displayName: Checkout latency
+  project: payments
+spec:
+  service: checkout
+  budgetingMethod: Occurrences
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+  objectives:
+    - name: fast
+      displayName: Fast
+      value: 200
+      target: 0.99
+      op: lte
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findings := lintSLOs([]*preparedFile{{Path: "slos.yaml", Objects: objects}})
+	if len(findings) != 1 || findings[0].Rule != lint.RuleMissingAlertPolicy || findings[0].Source != "slos.yaml" {
+		t.Fatalf("expected the missing alert policy to be flagged, got %+v", findings)
+	}
+
+	summary := newRunSummary(1, true)
+	summary.LintWarnings = findings
+	if markdown := summary.markdown(); !strings.Contains(markdown, "### SLO Lint Warnings") || !strings.Contains(markdown, "| payments/checkout-latency | `missing-alert-policy` | no alert policies, so nobody is notified when the error budget burns | slos.yaml |") {
+		t.Errorf("expected the warning in the job summary, got:\n%s", markdown)
+	}
+}
+
+func TestCheckFileOwners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/nobl9/pulls/42/reviews":
+			fmt.Fprint(w, `[{"user": {"login": "alice"}, "state": "APPROVED"}]`)
+		case "/repos/acme/nobl9/pulls/42/files":
+			fmt.Fprint(w, `[{"filename": "nobl9/payments.yaml"}, {"filename": "nobl9/billing.yaml"}]`)
+		case "/orgs/acme/teams/team-payments/memberships/alice":
+			fmt.Fprint(w, `{"state": "active"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	workspace := t.TempDir()
+	eventPath := filepath.Join(workspace, "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 42, "user": {"login": "bob"}}}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_REPOSITORY", "acme/nobl9")
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+
+	previous := config
+	defer func() { config = previous }()
+	config.GitHubToken = "token"
+
+	nobl9Dir := filepath.Join(workspace, "nobl9")
+	files := []*parsedFile{
+		// Approved by alice of team-payments
+		{Path: filepath.Join(nobl9Dir, "payments.yaml"), Meta: &parser.Meta{Spec: parser.MetaSpec{Owners: []string{"team-payments"}}}},
+		// Changed, but nobody behind the change is in team-billing
+		{Path: filepath.Join(nobl9Dir, "billing.yaml"), Meta: &parser.Meta{Spec: parser.MetaSpec{Owners: []string{"team-billing"}}}},
+		// Not changed by the pull request
+		{Path: filepath.Join(nobl9Dir, "checkout.yaml"), Meta: &parser.Meta{Spec: parser.MetaSpec{Owners: []string{"team-checkout"}}}},
+		{Path: filepath.Join(nobl9Dir, "shared.yaml")},
+	}
+
+	config.DryRun = true
+	summary := newRunSummary(len(files), true)
+	results := newRunResults(time.Now(), true)
+	if kept := checkFileOwners(context.Background(), append([]*parsedFile(nil), files...), summary, results); len(kept) != 4 {
+		t.Errorf("expected dry runs to keep every file, got %d", len(kept))
+	}
+
+	config.DryRun = false
+	summary = newRunSummary(len(files), false)
+	results = newRunResults(time.Now(), false)
+	kept := checkFileOwners(context.Background(), append([]*parsedFile(nil), files...), summary, results)
+	if len(kept) != 3 || summary.FilesWithErrors != 1 {
+		t.Fatalf("expected only billing.yaml to fail, got %d kept and %d errors", len(kept), summary.FilesWithErrors)
+	}
+	failure := results.Files[0]
+	if failure.Path != files[1].Path || failure.Error == nil || failure.Error.Type != string(errors.ErrorTypePolicy) ||
+		!strings.Contains(failure.Error.Message, "team-billing") {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	config.StateFile = filepath.Join(t.TempDir(), "state.json")
+	config.HistorySize = 2
+	config.LogLevel = "info"
+	config.LogFormat = "json"
+	config.HistoryRuns = history.DefaultWindow
+
+	failed := newRunSummary(2, false)
+	failed.FilesProcessed = 1
+	failed.FilesWithErrors = 1
+	succeeded := newRunSummary(2, false)
+	succeeded.FilesProcessed = 2
+	succeeded.ObjectsByKind["Project"] = 2
+
+	config.DryRun = true
+	recordRunHistory(time.Now(), succeeded)
+	config.DryRun = false
+	recordRunHistory(time.Now(), succeeded)
+	recordRunHistory(time.Now(), failed)
+	recordRunHistory(time.Now(), succeeded)
+
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(st.History) != 2 || !st.History[0].Failed() || st.History[1].ObjectsChanged != 2 {
+		t.Fatalf("expected the last 2 runs that were not dry runs, got %+v", st.History)
+	}
+
+	var out bytes.Buffer
+	reportHistoryCmd.SetOut(&out)
+	defer reportHistoryCmd.SetOut(nil)
+	if err := runReportHistory(reportHistoryCmd, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "| Error rate | 50% (1 failed run) |") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+	if rate, _ := githubOutputs.Get("history-error-rate"); rate != "50.0" {
+		t.Errorf("expected history-error-rate output 50.0, got %q", rate)
+	}
+}
+
+func TestServerDryRun(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	status := http.StatusOK
+	var dryRuns []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/apply" {
+			dryRuns = append(dryRuns, r.URL.Query().Get("dry_run"))
+			if status != http.StatusOK {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"errors":[{"title":"project quota exceeded"}]}`))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	serverCheck := newServerDryRun(true)
+	if err := applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, true, serverCheck); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dryRuns) != 1 || dryRuns[0] != "true" {
+		t.Fatalf("expected one apply with dry_run=true, got %v", dryRuns)
+	}
+
+	// Objects Nobl9 rejects fail the file
+	status = http.StatusBadRequest
+	err = applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, true, serverCheck)
+	if err == nil || !strings.Contains(err.Error(), "project quota exceeded") {
+		t.Fatalf("expected the rejection of Nobl9, got %v", err)
+	}
+
+	// A missing object is a rejection too
+	status = http.StatusNotFound
+	if err := applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, true, serverCheck); err == nil || !serverCheck.active() {
+		t.Fatalf("expected a 404 to fail the file and keep the server dry run, got %v", err)
+	}
+
+	// Without dry run support the run falls back to local validation
+	status = http.StatusNotImplemented
+	if err := applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, true, serverCheck); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if serverCheck.active() {
+		t.Error("expected the run's server dry run to be turned off")
+	}
+	if err := applyObjects(ctx, newTestAPI(t, server), filePath, file.Objects, true, serverCheck); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dryRuns) != 4 {
+		t.Errorf("expected no request after falling back, got %d requests", len(dryRuns))
+	}
+}
+
+func TestServerDryRunUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "not implemented", err: &sdk.HTTPError{StatusCode: http.StatusNotImplemented}, expected: true},
+		{name: "method not allowed", err: &sdk.HTTPError{StatusCode: http.StatusMethodNotAllowed}, expected: true},
+		{name: "not found", err: &sdk.HTTPError{StatusCode: http.StatusNotFound}, expected: false},
+		{name: "not found naming the dry run", err: &sdk.HTTPError{StatusCode: http.StatusNotFound, APIErrors: sdk.APIErrors{Errors: []sdk.APIError{{Title: "dry-run is not available"}}}}, expected: true},
+		{name: "unknown parameter", err: &sdk.HTTPError{StatusCode: http.StatusBadRequest, APIErrors: sdk.APIErrors{Errors: []sdk.APIError{{Title: "unknown query parameter dry_run"}}}}, expected: true},
+		{name: "invalid object", err: &sdk.HTTPError{StatusCode: http.StatusBadRequest, APIErrors: sdk.APIErrors{Errors: []sdk.APIError{{Title: "project quota exceeded"}}}}, expected: false},
+		{name: "forbidden", err: &sdk.HTTPError{StatusCode: http.StatusForbidden}, expected: false},
+		{name: "network", err: fmt.Errorf("connection refused"), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverDryRunUnsupported(tt.err); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReplayNobl9Client(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	var applies int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applies++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	filePath := filepath.Join(t.TempDir(), "payments.yaml")
+	if err := os.WriteFile(filePath, []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parseFile(context.Background(), nil, nil, filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := prepareFile(parsed, map[string]string{"alice@example.com": "00u1alice"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Record an apply against the server
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	client := newTestSDKClient(t, server)
+	if _, err := nobl9.RecordAPICalls(client.HTTP, dir, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	if err := applyObjects(ctx, nobl9API(client), filePath, file.Objects, false, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.Close()
+
+	// Replay it without credentials or the server
+	config.Replay = dir
+	config.Record = ""
+	replayed, err := createNobl9Client("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if organization, err := replayed.GetOrganization(ctx); err != nil || organization != "acme" {
+		t.Errorf("expected the recorded organization, got %q, %v", organization, err)
+	}
+	if err := applyObjects(ctx, nobl9API(replayed), filePath, file.Objects, false, nil); err != nil {
+		t.Fatalf("expected the recorded apply to be replayed, got %v", err)
+	}
+	if applies != 1 {
+		t.Errorf("expected the server to be called once, got %d", applies)
+	}
+
+	config.Record = dir
+	if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "record and replay cannot be combined") {
+		t.Errorf("expected record and replay to be rejected together, got %v", err)
+	}
+}
+
+func TestCheckEmbedded(t *testing.T) {
+	if err := checkEmbedded(); err != nil {
+		t.Fatalf("expected the embedded policy, role catalog and templates to be valid, got %v", err)
+	}
+}
+
+func TestApproveDeletion(t *testing.T) {
+	previous, previousIn, previousOut, previousInteractive := config, approvalIn, approvalOut, interactive
+	defer func() {
+		config, approvalIn, approvalOut, interactive = previous, previousIn, previousOut, previousInteractive
+	}()
+	projects := []string{"legacy", "sandbox"}
+
+	// Without a terminal deletions need --auto-approve
+	interactive = func() bool { return false }
+	if err := approveDeletion("project", projects); err == nil || !strings.Contains(err.Error(), "--auto-approve") {
+		t.Errorf("expected --auto-approve to be required, got %v", err)
+	}
+	config.AutoApprove = true
+	if err := approveDeletion("project", projects); err != nil {
+		t.Errorf("expected auto-approved deletion, got %v", err)
+	}
+
+	// In a terminal the user confirms the deletions
+	config.AutoApprove = false
+	interactive = func() bool { return true }
+	var out bytes.Buffer
+	approvalOut = &out
+	approvalIn = strings.NewReader("2\n")
+	if err := approveDeletion("project", projects); err != nil {
+		t.Errorf("expected confirmed deletion, got %v", err)
+	}
+	if !strings.Contains(out.String(), "  - legacy\n  - sandbox\n") {
+		t.Errorf("expected the projects to be listed, got %q", out.String())
+	}
+	approvalIn = strings.NewReader("no\n")
+	if err := approveDeletion("project", projects); err == nil || !strings.Contains(err.Error(), "not confirmed") {
+		t.Errorf("expected declined deletion, got %v", err)
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	previous, previousOutputs := config, githubOutputs
+	defer func() { config, githubOutputs = previous, previousOutputs }()
+	config.ProgressFile = filepath.Join(t.TempDir(), "progress.json")
+	config.ProgressInterval = time.Millisecond
+	githubOutputs = outputs.NewWriter("")
+
+	readProgress := func() progress.Snapshot {
+		var snapshot progress.Snapshot
+		data, err := os.ReadFile(config.ProgressFile)
+		if err == nil {
+			err = json.Unmarshal(data, &snapshot)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return snapshot
+	}
+
+	stop := startProgress(context.Background(), 3, time.Now())
+	results := newRunResults(time.Now(), false)
+	results.addFailedFile("projects/broken.yaml", phaseParse, fmt.Errorf("failed to parse YAML"), 0)
+	results.addSkippedFile("projects/other.yaml", unroutedReason)
+
+	// Provisional progress is written while the run goes on
+	deadline := time.Now().Add(5 * time.Second)
+	for snapshot := readProgress(); snapshot.FilesDone != 2; snapshot = readProgress() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected provisional progress with 2 files, got %+v", snapshot)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if snapshot := readProgress(); snapshot.Final || snapshot.FilesFailed != 1 || snapshot.TotalFiles != 3 {
+		t.Errorf("unexpected provisional progress %+v", snapshot)
+	}
+	if _, set := githubOutputs.Get("success"); set {
+		t.Error("expected no provisional outputs while the run goes on")
+	}
+
+	// A run returning before setting its outputs still gets final ones
+	stop()
+	if snapshot := readProgress(); !snapshot.Final || snapshot.FilesDone != 2 {
+		t.Errorf("expected final progress, got %+v", snapshot)
+	}
+	if runProgress != nil {
+		t.Error("expected the run's progress to be cleared")
+	}
+	for name, want := range map[string]string{"processed-files": "1", "errors": "1", "success": "false", "partial": "false"} {
+		if got, _ := githubOutputs.Get(name); got != want {
+			t.Errorf("expected final output %s=%s, got %q", name, want, got)
+		}
+	}
+}
+
+func TestRunProgressInterrupted(t *testing.T) {
+	previous, previousOutputs := config, githubOutputs
+	defer func() { config, githubOutputs = previous, previousOutputs }()
+	config.ProgressFile = filepath.Join(t.TempDir(), "progress.json")
+	config.ProgressInterval = 0
+	outputFile := filepath.Join(t.TempDir(), "github-output")
+	githubOutputs = outputs.NewWriter(outputFile)
+
+	// A signal cancelling the run writes provisional outputs right away
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stop := startProgress(ctx, 2, time.Now())
+	results := newRunResults(time.Now(), false)
+	results.addSkippedFile("projects/other.yaml", unroutedReason)
+	cancel(errors.NewCancelledError("run cancelled by interrupt", nil))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(outputFile)
+		if strings.Contains(string(data), "partial=true\n") && strings.Contains(string(data), "success=false\n") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected provisional outputs once the run is interrupted, got %q", data)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The final outputs of the run replace them
+	setRunOutputs(newRunSummary(2, false), 0)
+	stop()
+	if partial, _ := githubOutputs.Get("partial"); partial != "false" {
+		t.Errorf("expected the run's final outputs to be kept, got partial=%s", partial)
+	}
+	data, err := os.ReadFile(config.ProgressFile)
+	if err != nil || !strings.Contains(string(data), `"cancelled": true`) {
+		t.Errorf("expected the final progress to be cancelled, got %s (%v)", data, err)
+	}
+}
+
+func TestInputsVerify(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	// The action.yml of the repository matches the command flags
+	config.ActionFile = filepath.Join("..", "action.yml")
+	var out bytes.Buffer
+	inputsVerifyCmd.SetOut(&out)
+	defer inputsVerifyCmd.SetOut(nil)
+	if err := runInputsVerify(inputsVerifyCmd, nil); err != nil {
+		t.Fatalf("expected action.yml to match the flags, got %v:\n%s", err, out.String())
+	}
+
+	// A flag missing from action.yml is reported
+	data, err := os.ReadFile(config.ActionFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	drifted := strings.Replace(string(data), "    - '--progress-interval=${{ inputs.progress-interval }}'\n", "", 1)
+	drifted = strings.Replace(drifted, "\n  progress-interval:\n", "\n  progress-interval-removed:\n", 1)
+	config.ActionFile = filepath.Join(t.TempDir(), "action.yml")
+	if err := os.WriteFile(config.ActionFile, []byte(drifted), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+	err = runInputsVerify(inputsVerifyCmd, nil)
+	if code := determineExitCode(err); code != errors.ExitCodes[errors.ErrorTypeValidation] {
+		t.Errorf("expected the validation exit code, got %d for %v", code, err)
+	}
+	for _, expected := range []string{"input progress-interval-removed: never passed to the action", "flag --progress-interval: not exposed as an input"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q to be reported, got:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestValidateConfigDiffFile(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	config.ClientID = "id"
+	config.ClientSecret = "secret"
+	config.RepoPath = "."
+	config.UserCacheTTL = time.Hour
+	config.DiffFile = "nobl9-plan.diff"
+
+	config.DryRun = false
+	if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "diff-file requires dry-run") {
+		t.Errorf("expected diff-file to require a dry run, got %v", err)
+	}
+
+	config.DryRun = true
+	if err := validateConfig(); err != nil {
+		t.Errorf("expected a dry run to accept diff-file, got %v", err)
+	}
+}
+
+// executeCommand runs the CLI with the arguments, leaving every other flag
+// at its default as a user's run would, and returns what it printed
+func executeCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	}()
+
+	err := rootCmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestFmtFix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payments.yaml")
+	content := `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-owners
+spec:
+  projectRef: payments
+  roleRef: project-owner
+  users:
+    - alice@example.com
+    - Alice@example.com
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Duplicate users are formatted, not fixed, without --fix
+	if _, err := executeCommand(t, "fmt", "--check", "--repo-path", dir); err != nil {
+		t.Fatalf("expected the file to be formatted, got %v", err)
+	}
+	if _, err := executeCommand(t, "fmt", "--check", "--fix", "--repo-path", dir); err == nil {
+		t.Error("expected --check --fix to list the file")
+	}
+	if _, err := executeCommand(t, "fmt", "--check=false", "--fix", "--repo-path", dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fixed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(string(fixed), "example.com") != 1 {
+		t.Errorf("expected the duplicate user to be removed, got:\n%s", fixed)
+	}
+}
+
+func TestValidateDefaultFlags(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The test command's tests directory is not validate's default
+	if _, err := executeCommand(t, "validate", "--repo-path", dir); err != nil {
+		t.Fatalf("expected validate to pass without a tests directory, got %v", err)
+	}
+}
+
+func TestReportAccessDefaultFlags(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// compare-orgs binds --output too, with a default access rejects
+	out, err := executeCommand(t, "report", "access", "--repo-path", dir)
+	if err != nil {
+		t.Fatalf("expected report access to pass without --output, got %v", err)
+	}
+	if !strings.Contains(out, "## Nobl9 Access Review") {
+		t.Errorf("expected a markdown report, got %q", out)
+	}
+}
+
+func TestReportSLOsDefaultFlags(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(testManifest), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := executeCommand(t, "report", "slos", "--repo-path", dir)
+	if err != nil {
+		t.Fatalf("expected report slos to pass without --output, got %v", err)
+	}
+	if !strings.Contains(out, "## Nobl9 SLO Inventory") {
+		t.Errorf("expected a markdown inventory, got %q", out)
+	}
+}
+
+func TestInitDefaultFlags(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if _, err := executeCommand(t, "init", "payments", "--kinds", "project"); err != nil {
+		t.Fatalf("expected init to pass without --output-dir, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("payments", "project.yaml")); err != nil {
+		t.Errorf("expected the project manifest in the working directory: %v", err)
+	}
+	if config.ScaffoldOutputDir != "." || config.OutputDir != "" {
+		t.Errorf("expected init to keep its own output directory, got %q and %q", config.ScaffoldOutputDir, config.OutputDir)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/skiplist"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// skipRecordedObjects marks the objects the state file records as applied
+// with the same content unchanged, so they are not applied again, and
+// returns their number. If the state cannot be read every object is applied.
+func skipRecordedObjects(prepared []*preparedFile) int {
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		logrus.WithField("path", config.StateFile).WithError(err).Warn("Failed to load state, applying every object")
+		return 0
+	}
+
+	skipped := 0
+	now := time.Now()
+	for _, file := range prepared {
+		for _, obj := range file.Objects {
+			record, err := state.NewObjectRecord(obj, file.Path, now)
+			if err != nil || !st.Unchanged(record) {
+				continue
+			}
+			logrus.WithFields(logrus.Fields{
+				"file":   file.Path,
+				"object": record.Key(),
+			}).Debug("Object unchanged since it was last applied, skipping apply")
+			file.setStatus([]manifest.Object{obj}, statusUnchanged, nil)
+			file.Result.ObjectsUnchanged++
+			if obj.GetKind() == manifest.KindRoleBinding {
+				file.Result.RoleBindingsCreated--
+				file.Result.RoleBindingsUnchanged++
+			}
+			skipped++
+		}
+	}
+
+	if skipped > 0 {
+		logrus.WithField("objects", skipped).Info("Skipping objects unchanged since they were last applied")
+	}
+	return skipped
+}
+
+// withoutUnchanged drops the objects of a file already found unchanged
+func withoutUnchanged(file *preparedFile, objects []manifest.Object) []manifest.Object {
+	var remaining []manifest.Object
+	for _, obj := range objects {
+		if outcome, ok := file.outcomes[outcomeKey(obj.GetKind().String(), obj.GetName())]; ok && outcome.Status == statusUnchanged {
+			continue
+		}
+		remaining = append(remaining, obj)
+	}
+	return remaining
+}
+
+// declaredRecords returns the state records of every object of the files
+func declaredRecords(prepared []*preparedFile, now time.Time) []state.ObjectRecord {
+	var records []state.ObjectRecord
+	for _, file := range prepared {
+		for _, obj := range file.Objects {
+			record, err := state.NewObjectRecord(obj, file.Path, now)
+			if err != nil {
+				logrus.WithField("file", file.Path).WithError(err).Warn("Failed to hash object, not recording it in the state")
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// appliedObjects returns the state records of the objects the run applied
+// or found unchanged in Nobl9, leaving out the ones already recorded with
+// the same content so they keep when they were applied
+func appliedObjects(st *state.State, prepared []*preparedFile, now time.Time) []state.ObjectRecord {
+	var records []state.ObjectRecord
+	for _, file := range prepared {
+		for _, obj := range file.Objects {
+			outcome, ok := file.outcomes[outcomeKey(obj.GetKind().String(), obj.GetName())]
+			if !ok || (outcome.Status != statusApplied && outcome.Status != statusUnchanged) {
+				continue
+			}
+			record, err := state.NewObjectRecord(obj, file.Path, now)
+			if err != nil || st.Unchanged(record) {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// detectRenames logs the recorded objects that are declared again under
+// another name. The old object stays in Nobl9 until pruning deletes it.
+func detectRenames(st *state.State, declared []state.ObjectRecord) []state.Rename {
+	renames := st.Renames(declared)
+	for _, rename := range renames {
+		logrus.WithFields(logrus.Fields{
+			"from": rename.From.Key(),
+			"to":   rename.To.Key(),
+			"file": rename.To.Source,
+		}).Warn("Object renamed; the old object stays in Nobl9 until it is pruned")
+	}
+	return renames
+}
+
+// pruneObjects deletes the objects the state records that are no longer
+// declared, in two phases like projects: the first run records when they
+// were removed and a run after the grace period deletes them. Objects of
+// kinds excluded by --kinds, of the skip list and of projects that are no
+// longer declared, which pruning the project removes, are left alone.
+func pruneObjects(ctx context.Context, api nobl9.Nobl9API, st *state.State, declared []state.ObjectRecord, projects []string, kinds nobl9client.KindFilter, skip *skiplist.List, grace time.Duration, dryRun bool, result *pruneResult) error {
+	isProject := make(map[string]bool, len(projects))
+	for _, name := range projects {
+		isProject[name] = true
+	}
+	prunable := func(records []state.ObjectRecord) []state.ObjectRecord {
+		var kept []state.ObjectRecord
+		for _, record := range records {
+			kind, err := manifest.ParseKind(record.Kind)
+			if err != nil || !kinds.Allows(kind) || skip.Skips(kind, record.Project, record.Name) || (record.Project != "" && !isProject[record.Project]) {
+				continue
+			}
+			kept = append(kept, record)
+		}
+		return kept
+	}
+
+	now := time.Now()
+	plan := st.PlanObjectPrune(declared, grace, now)
+
+	for _, record := range prunable(plan.Pending) {
+		logrus.WithFields(logrus.Fields{
+			"object":       record.Key(),
+			"delete_after": record.RemovedAt.Add(grace).Format(time.RFC3339),
+		}).Warn("Object is pending deletion")
+		result.ObjectsPending++
+	}
+
+	for _, record := range prunable(plan.Mark) {
+		logrus.WithFields(logrus.Fields{
+			"object":       record.Key(),
+			"delete_after": now.Add(grace).Format(time.RFC3339),
+		}).Warn("Object is no longer declared and will be deleted after the grace period")
+		if !dryRun {
+			st.MarkRemoved(record.Key(), now)
+		}
+		result.ObjectsMarked++
+	}
+
+	toDelete := prunable(plan.Delete)
+	if len(toDelete) > 0 && !dryRun {
+		names := make([]string, 0, len(toDelete))
+		for _, record := range toDelete {
+			names = append(names, record.Key())
+		}
+		if err := approveDeletion("object", names); err != nil {
+			logrus.WithField("objects", names).WithError(err).Error("Objects past their deletion grace period were not deleted")
+			result.ObjectsPending += len(toDelete)
+			return err
+		}
+	}
+
+	for _, record := range toDelete {
+		if dryRun {
+			logrus.WithField("object", record.Key()).Info("DRY RUN: Would delete object")
+			result.ObjectsDeleted++
+			continue
+		}
+		kind, _ := manifest.ParseKind(record.Kind)
+		if err := api.DeleteObject(ctx, kind, record.Project, record.Name); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", record.Key(), err)
+		}
+		st.ForgetObject(record.Key())
+		result.ObjectsDeleted++
+
+		logrus.WithField("object", record.Key()).Warn("Deleted object past its deletion grace period")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// applyFileMeta applies the ActionMeta settings of the parsed files and
+// returns the files to process. Files meant for another organization are
+// skipped, and files requiring a ticket fail unless the change references
+// one. The run's organization and change description are only read when a
+// file needs them.
+func applyFileMeta(ctx context.Context, client nobl9.Nobl9API, files []*parsedFile, summary *runSummary, results *runResults) []*parsedFile {
+	var organization, description string
+	var organizationErr error
+	organizationRead, descriptionRead := false, false
+
+	kept := files[:0]
+	for _, file := range files {
+		meta := file.Meta
+		if meta == nil {
+			kept = append(kept, file)
+			continue
+		}
+		results.setOwner(file.Path, meta.Spec.Owner)
+		log := logrus.WithFields(logrus.Fields{"file": file.Path, "owner": meta.Spec.Owner})
+
+		if meta.Spec.Organization != "" {
+			if !organizationRead {
+				organization, organizationErr = getOrganization(ctx, client)
+				organizationRead = true
+			}
+			if organizationErr != nil {
+				err := errors.NewPolicyError(fmt.Sprintf("cannot confirm the file targets organization '%s'", meta.Spec.Organization), organizationErr)
+				log.WithError(err).Error("Failed to process file")
+				summary.FilesWithErrors++
+				results.addFailedFile(file.Path, phasePolicy, err, file.Duration)
+				continue
+			}
+			if meta.Spec.Organization != organization {
+				log.WithFields(logrus.Fields{
+					"file_organization": meta.Spec.Organization,
+					"organization":      organization,
+				}).Info("File targets another organization, skipping")
+				summary.FilesSkipped++
+				results.addSkippedFile(file.Path, fmt.Sprintf("targets organization %s", meta.Spec.Organization))
+				continue
+			}
+		}
+
+		if meta.Spec.RequireTicket {
+			if !descriptionRead {
+				description = provenance.SourceFromEnv().Description
+				descriptionRead = true
+			}
+			if err := checkTicket(meta, description); err != nil {
+				log.WithError(err).Error("Failed to process file")
+				summary.FilesWithErrors++
+				results.addFailedFile(file.Path, phasePolicy, err, file.Duration)
+				continue
+			}
+		}
+
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// checkTicket returns a policy error unless the change description
+// references a ticket matching the file's ticket pattern
+func checkTicket(meta *parser.Meta, description string) error {
+	// The pattern was checked when the file was parsed
+	pattern, _ := meta.TicketRegexp()
+	if ticket := pattern.FindString(description); ticket != "" {
+		logrus.WithField("ticket", ticket).Debug("Change references a ticket")
+		return nil
+	}
+
+	return errors.NewPolicyErrorWithDetails(
+		fmt.Sprintf("file requires a ticket: reference one matching %s in the commit message or pull request", pattern),
+		nil,
+		map[string]interface{}{"ticket_pattern": pattern.String(), "owner": meta.Spec.Owner},
+	)
+}
+
+// skipPruneProjects returns the projects declared by files whose ActionMeta
+// keeps them out of pruning
+func skipPruneProjects(files []*parsedFile) map[string]bool {
+	projects := make(map[string]bool)
+	for _, file := range files {
+		if file.Meta == nil || !file.Meta.Spec.SkipPrune {
+			continue
+		}
+		for _, name := range declaredProjects([]*parsedFile{file}) {
+			projects[name] = true
+		}
+	}
+	return projects
+}
+
+// overlayEnvironment specializes the content of a file for --environment.
+// Files without an overlay for the environment are used as they are.
+func overlayEnvironment(meta *parser.Meta, content []byte, path string) ([]byte, error) {
+	if config.Environment == "" || meta == nil || len(meta.Spec.Environments) == 0 {
+		return content, nil
+	}
+	if _, found := meta.Spec.Environments[config.Environment]; !found {
+		logrus.WithFields(logrus.Fields{
+			"file":         path,
+			"environment":  config.Environment,
+			"environments": meta.Environments(),
+		}).Debug("File has no overlay for the environment, using its base manifest")
+		return content, nil
+	}
+
+	logrus.WithFields(logrus.Fields{"file": path, "environment": config.Environment}).Debug("Applying environment overlay")
+	return meta.Overlay(content, config.Environment)
+}
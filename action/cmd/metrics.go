@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/metrics"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// metricsPushTimeout bounds pushing the metrics to the Pushgateway
+const metricsPushTimeout = 30 * time.Second
+
+// runMetrics collects the metrics of the running process or apply command;
+// it is nil when --pushgateway-url is not set
+var runMetrics *metrics.Collector
+
+// startMetrics starts collecting the metrics of a run when they are pushed
+func startMetrics() {
+	runMetrics = nil
+	if config.PushgatewayURL != "" {
+		runMetrics = metrics.New()
+	}
+}
+
+// checkPushgatewayURL accepts an empty URL or an absolute http(s) URL
+func checkPushgatewayURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid pushgateway-url: expected an http or https URL")
+	}
+	return nil
+}
+
+// observeAPICalls reports every request of the client to the run metrics.
+// Call it right after nobl9.DeadlineAPICalls, so requests sent again after
+// Retry-After are observed on their own.
+func observeAPICalls(client *sdk.Client) {
+	if runMetrics != nil {
+		nobl9.ObserveAPICalls(client.HTTP, runMetrics)
+	}
+}
+
+// pushMetrics records the outcome of the run and pushes the metrics to
+// --pushgateway-url. Like notifications, failures are logged and do not
+// fail the run.
+func pushMetrics(summary *runSummary, results *runResults, totalErrors int) {
+	if runMetrics == nil {
+		return
+	}
+
+	for _, file := range results.Files {
+		if file.Error != nil {
+			runMetrics.AddFailure(file.Error.Phase, file.Error.Type)
+		}
+		for _, object := range file.Objects {
+			runMetrics.AddObjects(object.Kind, object.Status, 1)
+		}
+	}
+	for _, runErr := range results.Errors {
+		runMetrics.AddFailure(runErr.Phase, runErr.Type)
+	}
+	runMetrics.AddRetries("rate_limited", summary.RateLimited)
+	finishedAt := time.Now()
+	runMetrics.SetRun(finishedAt.Sub(results.StartedAt), totalErrors == 0 && summary.AbortedBy == nil, finishedAt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+	defer cancel()
+	grouping := map[string]string{"repository": provenance.SourceFromEnv().Repository}
+	if err := runMetrics.Push(ctx, config.PushgatewayURL, config.MetricsJob, grouping, nil); err != nil {
+		logrus.WithError(err).Warn("Failed to push metrics")
+		return
+	}
+	logrus.WithField("job", config.MetricsJob).Info("Pushed metrics")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/notify"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// notifyTimeout bounds posting the run summary to every target
+const notifyTimeout = 30 * time.Second
+
+// newNotifier creates the notifier of --slack-webhook-url and --notify-url
+func newNotifier() (*notify.Notifier, error) {
+	return notify.New(&notify.Config{
+		SlackWebhookURL: config.SlackWebhookURL,
+		WebhookURL:      config.NotifyURL,
+		On:              config.NotifyOn,
+	})
+}
+
+// notifyRun posts the run summary to the configured targets. Notifications
+// are best effort: failures are logged and do not fail the run.
+func notifyRun(summary *runSummary, results *runResults, totalErrors int) {
+	notifier, err := newNotifier()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to set up notifications")
+		return
+	}
+	if !notifier.Enabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := notifier.Notify(ctx, notifySummary(summary, results, totalErrors)); err != nil {
+		logrus.WithError(err).Warn("Failed to post the run summary")
+		return
+	}
+	logrus.WithField("notify_on", config.NotifyOn).Debug("Posted the run summary")
+}
+
+// notifySummary describes the run for notifications
+func notifySummary(summary *runSummary, results *runResults, totalErrors int) *notify.Summary {
+	source := provenance.SourceFromEnv()
+	notification := &notify.Summary{
+		Repository:      source.Repository,
+		Ref:             strings.TrimPrefix(source.Ref, "refs/heads/"),
+		RunURL:          source.RunURL,
+		DryRun:          summary.DryRun,
+		Success:         totalErrors == 0 && summary.AbortedBy == nil,
+		FilesProcessed:  summary.FilesProcessed,
+		FilesWithErrors: summary.FilesWithErrors,
+		UnresolvedUsers: summary.UnresolvedEmails,
+	}
+	if summary.AbortedBy != nil {
+		notification.AbortedBy = summary.AbortedBy.Error()
+	}
+
+	for _, file := range results.Files {
+		if file.Error != nil {
+			notification.Errors = append(notification.Errors, fmt.Sprintf("%s: %s", file.Path, file.Error.Message))
+		}
+		for _, object := range file.Objects {
+			switch object.Status {
+			case statusApplied:
+				if object.Rollback != nil && object.Rollback.Created {
+					notification.Created++
+				} else {
+					notification.Updated++
+				}
+			case statusDryRun:
+				notification.Planned++
+			case statusUnchanged:
+				notification.Unchanged++
+			case statusFailed:
+				notification.Failed++
+			}
+		}
+	}
+	for _, runErr := range results.Errors {
+		notification.Errors = append(notification.Errors, runErr.Message)
+	}
+	return notification
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/organizations"
+	"github.com/your-org/nobl9-action/pkg/parser"
+)
+
+// unroutedReason is reported for files no organization claims
+const unroutedReason = "no organization claims the file"
+
+// loadOrganizations loads the organizations of --organizations, or else the
+// inline list of NOBL9_ORGANIZATIONS. It returns nil when neither is set and
+// the run applies files to the organization of client-id.
+func loadOrganizations() ([]organizations.Organization, error) {
+	if config.Organizations != "" {
+		return organizations.Load(config.Organizations)
+	}
+	if inline := os.Getenv(organizations.Env); strings.TrimSpace(inline) != "" {
+		return organizations.Parse([]byte(inline), os.Getenv)
+	}
+	return nil, nil
+}
+
+// runOrganizations applies the files to several organizations, one after
+// the other, each with its own credentials, state and user cache. Files no
+// organization claims are skipped. An organization that cannot be processed
+// fails its own files and the others still run; the error of the first one
+// is returned along with the run's, so its type decides the exit code.
+func runOrganizations(ctx context.Context, runStart time.Time, orgs []organizations.Organization, files []string) error {
+	for _, org := range orgs {
+		logger.AddSecret(org.ClientSecret)
+	}
+
+	summary := newRunSummary(len(files), config.DryRun)
+	results := newRunResults(runStart, config.DryRun)
+
+	var orgErr error
+	routed := make(map[string][]string)
+	for _, path := range files {
+		org := organizations.Route(orgs, relativePath(path), fileOrganization(path))
+		if org == nil {
+			logrus.WithField("file", path).Warn("No organization claims the file, skipping")
+			summary.FilesSkipped++
+			results.addSkippedFile(path, unroutedReason)
+			continue
+		}
+		routed[org.Name] = append(routed[org.Name], path)
+	}
+
+	for _, org := range orgs {
+		orgFiles := routed[org.Name]
+		log := logrus.WithFields(logrus.Fields{"organization": org.Name, "file_count": len(orgFiles)})
+		if len(orgFiles) == 0 {
+			log.Info("No files for organization, skipping")
+			continue
+		}
+		log.Info("Processing organization")
+
+		orgSummary, orgResults, err := processOrganization(ctx, runStart, org, orgFiles)
+		if err != nil {
+			log.WithError(err).Error("Failed to process organization")
+			if orgErr == nil {
+				orgErr = fmt.Errorf("organization %s: %w", org.Name, err)
+			}
+			orgSummary = newRunSummary(len(orgFiles), config.DryRun)
+			orgResults = newRunResults(runStart, config.DryRun)
+			for _, path := range orgFiles {
+				orgSummary.FilesWithErrors++
+				orgResults.addFailedFile(path, phaseApply, err, 0)
+			}
+		}
+
+		log.WithFields(logrus.Fields{
+			"files_processed":   orgSummary.FilesProcessed,
+			"files_with_errors": orgSummary.FilesWithErrors,
+			"plan_hash":         orgSummary.PlanHash,
+		}).Info("Organization processed")
+
+		summary.merge(orgSummary)
+		results.merge(org.Name, orgResults)
+		summary.Organizations = append(summary.Organizations, organizationRun{Name: org.Name, Summary: orgSummary})
+	}
+
+	summary.PlanHash = organizationsPlanHash(summary.Organizations)
+	results.PlanHash = summary.PlanHash
+	setGitHubOutput("plan-hash", summary.PlanHash)
+
+	err := finishRun(summary, results)
+	if err != nil && orgErr != nil {
+		return fmt.Errorf("%w; %w", orgErr, err)
+	}
+	return err
+}
+
+// processOrganization applies files with the credentials of an organization.
+// The state file, user cache and checkpoint file get per-organization paths,
+// so that the projects, users and completed files of one organization are
+// never mistaken for another's.
+func processOrganization(ctx context.Context, runStart time.Time, org organizations.Organization, files []string) (*runSummary, *runResults, error) {
+	previous := config
+	defer func() { config = previous }()
+
+	config.ClientID = org.ClientID
+	config.ClientSecret = org.ClientSecret
+	config.StateFile = organizations.PathFor(config.StateFile, org.Name)
+	config.UserCacheFile = organizations.PathFor(config.UserCacheFile, org.Name)
+	config.CheckpointFile = organizations.PathFor(config.CheckpointFile, org.Name)
+
+	return processFiles(ctx, runStart, files)
+}
+
+// relativePath returns a file path relative to the repository, or the path
+// itself when it is outside of it
+func relativePath(path string) string {
+	if relative, err := filepath.Rel(config.RepoPath, path); err == nil && !strings.HasPrefix(relative, "..") {
+		return relative
+	}
+	return path
+}
+
+// fileOrganization returns the organization named by a file's ActionMeta.
+// Files that cannot be read are routed by their path and fail when they are
+// processed.
+func fileOrganization(path string) string {
+	if isCSVFile(path) {
+		return ""
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	meta, _, err := parser.ExtractMeta(content)
+	if err != nil || meta == nil {
+		return ""
+	}
+	return meta.Spec.Organization
+}
+
+// organizationsPlanHash combines the plan hashes of the organizations into
+// the plan hash of the run
+func organizationsPlanHash(runs []organizationRun) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, run := range runs {
+		fmt.Fprintf(&b, "%s:%s\n", run.Name, run.Summary.PlanHash)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
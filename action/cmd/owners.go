@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/owners"
+)
+
+// checkFileOwners returns the files whose changes may be applied. Files
+// declaring owner teams, in their ActionMeta or with the owners annotation,
+// fail when the change touches them and neither its author nor an approver
+// belongs to one of the teams. Dry runs only warn, so pull requests still
+// preview their changes.
+func checkFileOwners(ctx context.Context, files []*parsedFile, summary *runSummary, results *runResults) []*parsedFile {
+	owned := make(map[*parsedFile][]string)
+	for _, file := range files {
+		var metaOwners []string
+		if file.Meta != nil {
+			metaOwners = file.Meta.Spec.Owners
+		}
+		if teams := owners.Of(metaOwners, file.Objects); len(teams) > 0 {
+			owned[file] = teams
+		}
+	}
+	if len(owned) == 0 {
+		return files
+	}
+
+	// The change is only read when a file has owners
+	verifier, verifierErr := newOwnersVerifier(ctx)
+
+	kept := files[:0]
+	for _, file := range files {
+		teams, found := owned[file]
+		if !found {
+			kept = append(kept, file)
+			continue
+		}
+		log := logrus.WithFields(logrus.Fields{"file": file.Path, "owners": strings.Join(teams, ",")})
+
+		err := verifierErr
+		if err == nil {
+			if !verifier.Changed(repositoryPath(file.Path)) {
+				log.Debug("File not changed, skipping the owner check")
+				kept = append(kept, file)
+				continue
+			}
+			err = verifier.Check(ctx, teams)
+		}
+		if err == nil {
+			log.Info("Owner check passed")
+			kept = append(kept, file)
+			continue
+		}
+
+		if config.DryRun {
+			log.WithError(err).Warn("Owner check failed, continuing because this is a dry run")
+			kept = append(kept, file)
+			continue
+		}
+		log.WithError(err).Error("Failed to process file")
+		summary.FilesWithErrors++
+		results.addFailedFile(file.Path, phasePolicy, err, file.Duration)
+	}
+	return kept
+}
+
+// newOwnersVerifier reads the change of the running workflow from GitHub.
+// Teams named without an organization belong to the repository's owner.
+func newOwnersVerifier(ctx context.Context) (*owners.Verifier, error) {
+	if config.GitHubToken == "" {
+		return nil, errors.NewConfigError("github-token is required to verify the owners of files", nil)
+	}
+	client, err := owners.NewClient(&owners.Config{APIURL: os.Getenv("GITHUB_API_URL"), Token: config.GitHubToken})
+	if err != nil {
+		return nil, err
+	}
+	change, err := client.ChangeFromEnv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the change from GitHub: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"pull_request": change.PullRequest,
+		"author":       change.Author,
+		"approvers":    strings.Join(change.Approvers, ","),
+	}).Debug("Read the change for the owner check")
+
+	org, _, _ := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	return owners.NewVerifier(client, org, change), nil
+}
+
+// repositoryPath returns a file path relative to the root of the repository
+// checkout (GITHUB_WORKSPACE, or --repo-path outside GitHub Actions), as
+// GitHub lists the files of a pull request
+func repositoryPath(path string) string {
+	root := os.Getenv("GITHUB_WORKSPACE")
+	if root == "" {
+		root = config.RepoPath
+	}
+	absRoot, rootErr := filepath.Abs(root)
+	absPath, pathErr := filepath.Abs(path)
+	if rootErr == nil && pathErr == nil {
+		if relative, err := filepath.Rel(absRoot, absPath); err == nil && !strings.HasPrefix(relative, "..") {
+			return relative
+		}
+	}
+	return path
+}
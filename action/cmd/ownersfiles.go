@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+)
+
+// isOwnersFile checks if the file is a Kubernetes-style OWNERS file
+func isOwnersFile(filename string) bool {
+	return filepath.Base(filename) == nobl9client.OwnersFileName
+}
+
+// scanOwnersFiles returns the OWNERS files of the repository, read with
+// --owners-files whatever the file pattern
+func scanOwnersFiles(repoPath string) ([]string, error) {
+	pattern := filepath.Join(repoPath, "**", nobl9client.OwnersFileName)
+	matches, err := doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob pattern %s: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// ownersProject returns the project of an OWNERS file, the name of the
+// directory holding it
+func ownersProject(filePath string) string {
+	if absolute, err := filepath.Abs(filePath); err == nil {
+		filePath = absolute
+	}
+	return filepath.Base(filepath.Dir(filePath))
+}
+
+// parseOwnersFile converts the approvers and reviewers of an OWNERS file
+// into role bindings of its directory's project, with the roles of
+// --owners-roles
+func parseOwnersFile(filePath string, content []byte) ([]manifest.Object, error) {
+	roles, err := nobl9client.ParseOwnersRoles(config.OwnersRoles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owners-roles: %w", err)
+	}
+	return nobl9client.ParseOwnersFile(content, ownersProject(filePath), roles)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// newOwnershipMarker returns the marker set by --owner-label,
+// --trace-annotations and --audit-annotations on applied objects, naming
+// the running workflow's repository, commit, author and run, or nil when
+// all are off. The label was checked by validateConfig.
+func newOwnershipMarker() *ownership.Marker {
+	source := provenance.SourceFromEnv()
+	marker, _ := ownership.New(config.OwnerLabel, config.TraceAnnotations, source.Repository, source.SHA)
+	if config.AuditAnnotations {
+		if marker == nil {
+			marker = &ownership.Marker{}
+		}
+		marker.Extra = audit.Annotations(source)
+	}
+	return marker
+}
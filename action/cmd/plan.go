@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Plan command - resolve and save the objects to apply
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan the objects to apply and save them to a plan file",
+	Long: `Parse the repository's manifests, check them against the guardrail policies, resolve emails to
+user IDs and write the resulting objects to a plan file, without changing Nobl9. The plan file
+also records a digest of every input file.
+
+Run apply with the plan file in a later job, e.g. one gated by an environment approval, to apply
+exactly the planned objects.`,
+	Example: `  # Plan and upload the plan as an artifact for the apply job
+  nobl9-action plan --out plan.bin \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupCore,
+	RunE:    runPlan,
+}
+
+// Apply command - apply a saved plan
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply exactly the objects of a plan file",
+	Long: `Apply the objects saved by the plan command. Nothing is parsed or resolved again: the planned
+objects are applied as they are, in dependency order.
+
+The apply is refused when the plan file was modified, when any input file was added, removed or
+changed since the plan was made, when the credentials belong to another organization than the
+plan's, or when --require-plan-hash does not match the plan.`,
+	Example: `  # Apply the approved plan
+  nobl9-action apply --plan plan.bin \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupCore,
+	RunE:    runApply,
+}
+
+// runPlan runs process as a dry run that saves its plan
+func runPlan(cmd *cobra.Command, args []string) error {
+	config.DryRun = true
+	return runProcess(cmd, args)
+}
+
+// savePlan writes the prepared files to the --out plan file. No plan is
+// written when any file failed before planning, since applying it would
+// silently leave those files out.
+func savePlan(ctx context.Context, client nobl9.Nobl9API, inputs []string, prepared []*preparedFile, failedFiles int) error {
+	if failedFiles > 0 {
+		return errors.NewFileProcessingError(fmt.Sprintf("not writing the plan: %d files failed or were not processed", failedFiles), nil)
+	}
+
+	saved, err := planner.NewSavedPlan(organizationName(ctx, client, ""), inputs)
+	if err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	for _, file := range prepared {
+		if err := saved.AddFile(file.Path, file.Objects, file.Result.EmailsResolved); err != nil {
+			return fmt.Errorf("failed to save plan: %w", err)
+		}
+	}
+	if err := saved.WriteFile(config.PlanOut); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":      config.PlanOut,
+		"files":     len(saved.Files),
+		"plan_hash": saved.Hash,
+	}).Info("Wrote plan file")
+	return nil
+}
+
+// writePlanDiff writes the --diff-file unified diff of what the prepared
+// objects would change, against their live definitions in Nobl9
+func writePlanDiff(ctx context.Context, client nobl9.Nobl9API, prepared []*preparedFile) error {
+	var items []planner.Item
+	for _, file := range prepared {
+		for _, obj := range file.Objects {
+			items = append(items, planner.Item{Object: obj, Source: file.Path})
+		}
+	}
+
+	ignoreFields, err := driftIgnoreFields("")
+	if err != nil {
+		return err
+	}
+	live, err := listObjects(ctx, client, itemKinds(items))
+	if err != nil {
+		return fmt.Errorf("failed to write plan diff: %w", err)
+	}
+	diff, changed, err := drift.NewWithIgnoreFields(ignoreFields).UnifiedDiff(items, live)
+	if err != nil {
+		return fmt.Errorf("failed to write plan diff: %w", err)
+	}
+	if err := os.WriteFile(config.DiffFile, []byte(diff), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan diff: %w", err)
+	}
+
+	setGitHubOutput("diff-file", config.DiffFile)
+	logrus.WithFields(logrus.Fields{
+		"path":            config.DiffFile,
+		"objects":         len(items),
+		"changed_objects": changed,
+	}).Info("Wrote plan diff")
+	return nil
+}
+
+// runApply applies the objects of a saved plan
+func runApply(cmd *cobra.Command, args []string) error {
+	runStart := time.Now()
+
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if err := validateConfig(); err != nil {
+		return configError(err)
+	}
+	startMetrics()
+	if err := checkProvenance(); err != nil {
+		return err
+	}
+
+	saved, err := planner.ReadSavedPlan(config.PlanFile)
+	if err != nil {
+		return configError(err)
+	}
+	if err := saved.Verify(); err != nil {
+		return refusePlan(err.Error(), saved)
+	}
+	if err := checkPlanHash(saved.Hash, config.RequirePlanHash); err != nil {
+		return err
+	}
+
+	paths, err := inputFiles()
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+	if err := saved.CheckInputs(paths); err != nil {
+		return refusePlan(err.Error()+"; plan again", saved)
+	}
+
+	defer startTracing()()
+	ctx, cancel := runContext(commandContext(cmd))
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "apply", attribute.String("plan.hash", saved.Hash), attribute.Int("file.count", len(saved.Files)))
+	defer span.End()
+
+	nobl9Client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+	api := nobl9API(nobl9Client)
+	if organization := organizationName(ctx, api, ""); saved.Organization != "" && organization != "" && organization != saved.Organization {
+		return refusePlan(fmt.Sprintf("the plan was made for organization %s, not %s", saved.Organization, organization), saved)
+	}
+
+	callDeadline := nobl9.DeadlineAPICalls(nobl9Client.HTTP, config.CallTimeout, newLogger())
+	observeAPICalls(nobl9Client)
+	rateLimiter := nobl9.RateLimitAPICalls(nobl9Client.HTTP, retry.NewLimiter(config.MaxRPS), newLogger())
+	apiCalls := nobl9.CountAPICalls(nobl9Client.HTTP)
+	circuitBreaker := nobl9.BreakAPICalls(nobl9Client.HTTP, retry.NewBreaker(config.BreakerThreshold, config.BreakerCooldown), newLogger())
+	nobl9.TraceAPICalls(nobl9Client.HTTP)
+
+	logrus.WithFields(logrus.Fields{
+		"path":       config.PlanFile,
+		"files":      len(saved.Files),
+		"plan_hash":  saved.Hash,
+		"planned_at": saved.CreatedAt.Format(time.RFC3339),
+	}).Info("Applying plan")
+
+	summary := newRunSummary(len(saved.Files), false)
+	results := newRunResults(runStart, false)
+	summary.PlanHash = saved.Hash
+	results.PlanHash = saved.Hash
+	setGitHubOutput("plan-hash", saved.Hash)
+	defer startProgress(ctx, len(saved.Files), runStart)()
+	runProgress.SetPhase(phaseApply)
+
+	ctx, abort := abortOnCritical(ctx, results)
+	defer abort(nil)
+
+	// The planned objects were validated when planning; prepareFile only
+	// sets up their results
+	var prepared []*preparedFile
+	for _, savedFile := range saved.Files {
+		objects, err := savedFile.DecodeObjects()
+		var file *preparedFile
+		if err == nil {
+			file, err = prepareFile(&parsedFile{Path: savedFile.Path, Objects: objects}, nil, nil, nil)
+		}
+		if err != nil {
+			logrus.WithField("file", savedFile.Path).WithError(err).Error("Failed to process file")
+			summary.FilesWithErrors++
+			results.addFailedFile(savedFile.Path, phasePrepare, err, 0)
+			continue
+		}
+		file.Result.EmailsResolved = savedFile.EmailsResolved
+		prepared = append(prepared, file)
+	}
+
+	// Files the resumed run already applied are not applied again
+	startCheckpoint(runStart)
+	prepared = skipCompletedFiles(prepared, summary, results)
+
+	applyCtx, endApply := withPhaseTimeout(ctx, abort, timeoutPhaseApply, config.ApplyTimeout)
+	err = applyPlanned(applyCtx, api, prepared, false, nil, results.aggregator)
+	endApply()
+	if err != nil {
+		return fmt.Errorf("failed to plan apply order: %w", err)
+	}
+	recordApplied(prepared, summary, results)
+	summary.AbortedBy = abortedBy(ctx)
+	rollbackOnFailure(ctx, api, summary, results)
+	finishCheckpoint(summary)
+
+	summary.APICalls = apiCalls.Counts()
+	summary.RateLimited, summary.RateLimitWaited = rateLimiter.Throttled()
+	summary.CallTimeouts = callDeadline.Timeouts()
+	summary.Breaker = circuitBreaker.Stats()
+	summary.Recommendations = recommendations(summary, results)
+
+	newLogger().LogProcessingComplete(summary.stats())
+	writeJobSummary(summary)
+
+	totalErrors := summary.FilesWithErrors + summary.FilesAborted
+	writeResultsFile(results, summary, totalErrors)
+	setRunOutputs(summary, totalErrors)
+	notifyRun(summary, results, totalErrors)
+	pushMetrics(summary, results, totalErrors)
+
+	if summary.AbortedBy != nil {
+		return fmt.Errorf("apply %s: %w", abortedReason, summary.AbortedBy)
+	}
+	if totalErrors > 0 {
+		return errors.NewFileProcessingError(fmt.Sprintf("apply completed with %d errors", totalErrors), results.aggregator.Err())
+	}
+	return nil
+}
+
+// refusePlan returns the policy error refusing to apply a saved plan
+func refusePlan(reason string, saved *planner.SavedPlan) error {
+	return errors.NewPolicyErrorWithDetails(
+		"refusing to apply the plan: "+reason,
+		nil,
+		map[string]interface{}{"plan_file": config.PlanFile, "plan_hash": saved.Hash},
+	)
+}
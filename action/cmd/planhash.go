@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// planHash returns the hash of the objects the prepared files would apply,
+// after user IDs were substituted
+func planHash(files []*preparedFile) (string, error) {
+	var objects []manifest.Object
+	for _, file := range files {
+		objects = append(objects, file.Objects...)
+	}
+	return planner.Hash(objects)
+}
+
+// checkPlanHash returns a policy error unless the plan hash matches the
+// approved one. An empty approved hash accepts any plan.
+func checkPlanHash(hash, approved string) error {
+	if approved == "" || hash == approved {
+		return nil
+	}
+	return errors.NewPolicyErrorWithDetails(
+		fmt.Sprintf("plan hash %s does not match the approved plan hash %s; the manifests or resolved users changed since the plan was approved", hash, approved),
+		nil,
+		map[string]interface{}{"plan_hash": hash, "approved_plan_hash": approved},
+	)
+}
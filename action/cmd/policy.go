@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/policy"
+)
+
+// guardrails are the policy rules and Rego policies checked before anything
+// is applied
+type guardrails struct {
+	rules *policy.Policy
+	rego  *policy.Rego
+}
+
+// loadGuardrails loads the configured policy file and Rego policies; it
+// returns nil when neither is configured
+func loadGuardrails(ctx context.Context) (*guardrails, error) {
+	if config.Policy == "" && config.RegoPolicy == "" {
+		return nil, nil
+	}
+
+	loaded := &guardrails{}
+	if config.Policy != "" {
+		rules, err := policy.Load(config.Policy)
+		if err != nil {
+			return nil, err
+		}
+		loaded.rules = rules
+		logrus.WithFields(logrus.Fields{
+			"policy": config.Policy,
+			"rules":  rules.Enabled(),
+		}).Info("Loaded guardrail policy")
+	}
+
+	if config.RegoPolicy != "" {
+		rego, err := policy.LoadRego(ctx, commalist.Split(config.RegoPolicy))
+		if err != nil {
+			return nil, err
+		}
+		loaded.rego = rego
+		logrus.WithField("modules", rego.Modules()).Info("Loaded Rego policies")
+	}
+
+	return loaded, nil
+}
+
+// checkGuardrails evaluates the policies across all files and returns the
+// violations and Rego deny verdicts of each file. Every violation is logged
+// with its rule ID and remediation hint; Rego warn verdicts are only logged.
+func checkGuardrails(ctx context.Context, checks *guardrails, files []*parsedFile) (map[string][]policy.Violation, error) {
+	var items []policy.Item
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			items = append(items, policy.Item{Object: obj, Source: file.Path})
+		}
+	}
+
+	denies, warnings, err := checks.rego.Evaluate(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		policyLogEntry(warning).Warn("Policy warning: " + warning.Message)
+	}
+
+	violations := make(map[string][]policy.Violation)
+	for _, violation := range append(checks.rules.Evaluate(items), denies...) {
+		policyLogEntry(violation).Error("Policy violation: " + violation.Message)
+		violations[violation.Source] = append(violations[violation.Source], violation)
+	}
+
+	return violations, nil
+}
+
+// policyLogEntry returns a log entry describing a violation
+func policyLogEntry(violation policy.Violation) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"file":        violation.Source,
+		"rule":        violation.RuleID,
+		"kind":        violation.Kind,
+		"name":        violation.Name,
+		"remediation": violation.Remediation,
+	})
+}
+
+// runPolicyValidation checks the files against the guardrails and returns
+// the files without violations
+func runPolicyValidation(ctx context.Context, checks *guardrails, filePaths []string) ([]string, error) {
+	var files []*parsedFile
+	for _, filePath := range filePaths {
+		parsed, err := parseRemoteFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, parsed)
+	}
+
+	violations, err := checkGuardrails(ctx, checks, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var compliant []string
+	for _, filePath := range filePaths {
+		if fileViolations := violations[filePath]; len(fileViolations) > 0 {
+			logrus.WithField("file", filePath).WithError(policy.Error(fileViolations)).Error("File violates the guardrail policy")
+			continue
+		}
+		compliant = append(compliant, filePath)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"files_checked":         len(filePaths),
+		"files_with_violations": len(filePaths) - len(compliant),
+	}).Info("Policy check completed")
+
+	return compliant, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/progress"
+)
+
+// runProgress follows the files of the running process, plan or apply
+// command; it is nil while no run is in progress
+var runProgress *progress.Tracker
+
+// runOutputsSet records that the running command set its final outputs
+// (see setRunOutputs)
+var runOutputsSet bool
+
+// startProgress starts following a run of the given number of files. Every
+// progress-interval the files done so far are written to the progress file.
+// When a signal cancels ctx (see handleSignals) they are also set as
+// provisional outputs, which are flushed right away since the runner kills
+// the action shortly after. The returned function stops following the run,
+// writes the final progress and, when the run returns before setting its
+// outputs, sets final outputs from the files done.
+func startProgress(ctx context.Context, totalFiles int, runStart time.Time) func() {
+	tracker := progress.New(totalFiles, runStart)
+	runProgress = tracker
+	runOutputsSet = false
+	tracker.Start(config.ProgressInterval, writeProgressFile)
+
+	var interrupted atomic.Bool
+	flushed := make(chan struct{})
+	stopFlush := context.AfterFunc(ctx, func() {
+		defer close(flushed)
+		if !isCancelled(context.Cause(ctx)) {
+			return
+		}
+		interrupted.Store(true)
+		tracker.Stop()
+		snapshot := tracker.Snapshot()
+		snapshot.Cancelled = true
+		logrus.WithField("files_done", snapshot.FilesDone).Warn("Run interrupted, writing progress so far")
+		setProgressOutputs(snapshot)
+		flushGitHubOutputs()
+		writeProgressFile(snapshot)
+	})
+
+	return func() {
+		if !stopFlush() {
+			<-flushed
+		}
+		tracker.Stop()
+		snapshot := tracker.Snapshot()
+		snapshot.Final = true
+		snapshot.Cancelled = interrupted.Load()
+		writeProgressFile(snapshot)
+		if !runOutputsSet {
+			setProgressOutputs(snapshot)
+		}
+		runProgress = nil
+	}
+}
+
+// setProgressOutputs sets the outputs of the files done in a snapshot, for
+// an interrupted run or one that ended before setting its outputs. The run
+// did not succeed; it is partial when interrupted.
+func setProgressOutputs(snapshot progress.Snapshot) {
+	setGitHubOutput("processed-files", fmt.Sprintf("%d", snapshot.FilesDone-snapshot.FilesFailed))
+	setGitHubOutput("errors", fmt.Sprintf("%d", snapshot.FilesFailed))
+	setGitHubOutput("success", "false")
+	setGitHubOutput("partial", fmt.Sprintf("%t", snapshot.Cancelled))
+}
+
+// writeProgressFile writes a snapshot to --progress-file, if set
+func writeProgressFile(snapshot progress.Snapshot) {
+	if config.ProgressFile == "" {
+		return
+	}
+	if err := snapshot.Write(config.ProgressFile); err != nil {
+		logrus.WithField("path", config.ProgressFile).WithError(err).Warn("Failed to write progress file")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/promote"
+)
+
+// Promote command - copy projects from one organization to another
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promote projects and their objects from one Nobl9 organization to another",
+	Long: `Export the projects matching --projects and their Services, AlertPolicies and SLOs (or the kinds
+selected with --kinds) from the --from organization, apply the environment transforms and apply
+them to the --to organization after printing the plan.
+
+Credentials default to the NOBL9_<ENV>_CLIENT_ID and NOBL9_<ENV>_CLIENT_SECRET environment
+variables of each environment, e.g. NOBL9_STAGING_CLIENT_ID for --from staging. Transforms
+rename projects by prefix and point SLOs at the target organization's data sources. Agents,
+Directs and alert methods hold credentials the API does not return and are never promoted.`,
+	Example: `  # Preview promoting the payments projects from staging to production
+  nobl9-action promote --from staging --to prod --projects 'payments-*' --dry-run
+
+  # Promote with project renames and data source swaps, without prompting
+  nobl9-action promote --from staging --to prod --projects 'stg-payments-*' \
+    --transforms promote.yaml --yes`,
+	GroupID: groupUtility,
+	RunE:    runPromote,
+}
+
+// runPromote plans the promotion and applies it after confirmation
+func runPromote(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	if config.PromoteFrom == config.PromoteTo {
+		return configError(fmt.Errorf("--from and --to must name different environments"))
+	}
+	kinds, err := promote.ParseKinds(config.PromoteKinds)
+	if err != nil {
+		return configError(fmt.Errorf("invalid kinds: %w", err))
+	}
+	transforms, err := promote.LoadTransforms(config.Transforms)
+	if err != nil {
+		return configError(err)
+	}
+	patterns := commalist.Split(config.PromoteProjects)
+	if len(patterns) == 0 {
+		return configError(fmt.Errorf("--projects needs at least one pattern"))
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	source, err := environmentClient(config.PromoteFrom, config.SourceClientID, config.SourceClientSecret)
+	if err != nil {
+		return err
+	}
+	target, err := environmentClient(config.PromoteTo, config.TargetClientID, config.TargetClientSecret)
+	if err != nil {
+		return err
+	}
+
+	sources, err := exportProjects(ctx, source, patterns, kinds)
+	if err != nil {
+		return fmt.Errorf("%s organization: %w", config.PromoteFrom, err)
+	}
+
+	existing, err := existingObjects(ctx, target, sources, transforms, kinds)
+	if err != nil {
+		return fmt.Errorf("%s organization: %w", config.PromoteTo, err)
+	}
+
+	plan, err := promote.Build(config.PromoteFrom, config.PromoteTo, sources, existing, transforms)
+	if err != nil {
+		return err
+	}
+
+	printPromotionPlan(cmd.OutOrStdout(), plan)
+
+	if config.DryRun {
+		logrus.Info("DRY RUN: Nothing was promoted")
+		return nil
+	}
+	if !config.Yes {
+		confirmed, err := confirmName(cmd.InOrStdin(), cmd.OutOrStdout(), "the target environment", config.PromoteTo, "apply the plan")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("promotion not confirmed")
+		}
+	}
+
+	return executePromotionPlan(ctx, target, plan)
+}
+
+// environmentClient creates a client for an environment, with the given
+// credentials or those of the environment's NOBL9_<ENV>_CLIENT_ID and
+// NOBL9_<ENV>_CLIENT_SECRET variables
+func environmentClient(environment, clientID, clientSecret string) (*sdk.Client, error) {
+	prefix := "NOBL9_" + environmentVariableName(environment)
+	if clientID == "" {
+		clientID = os.Getenv(prefix + "_CLIENT_ID")
+	}
+	if clientSecret == "" {
+		clientSecret = os.Getenv(prefix + "_CLIENT_SECRET")
+	}
+	if clientID == "" || clientSecret == "" {
+		return nil, configError(fmt.Errorf("no credentials for %s, set %s_CLIENT_ID and %s_CLIENT_SECRET or pass them as flags", environment, prefix, prefix))
+	}
+
+	client, err := createNobl9Client(clientID, clientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%s organization: %w", environment, err)
+	}
+	return client, nil
+}
+
+// environmentVariableName turns an environment name into the part of an
+// environment variable name, e.g. us-prod becomes US_PROD
+func environmentVariableName(environment string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, environment)
+}
+
+// exportProjects returns the projects matching the patterns with their
+// objects of the given kinds
+func exportProjects(ctx context.Context, client *sdk.Client, patterns []string, kinds []manifest.Kind) ([]promote.Source, error) {
+	projects, err := listObjects(ctx, nobl9API(client), []manifest.Kind{manifest.KindProject})
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := promote.MatchProjects(patterns, projects)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no projects match %s", strings.Join(patterns, ", "))
+	}
+
+	byName := make(map[string]manifest.Object, len(projects))
+	for _, project := range projects {
+		byName[project.GetName()] = project
+	}
+
+	sources := make([]promote.Source, 0, len(names))
+	for _, name := range names {
+		objects, err := getProjectObjects(ctx, client, name, kinds)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, promote.Source{Project: byName[name], Objects: objects})
+		logrus.WithFields(logrus.Fields{
+			"project": name,
+			"objects": len(objects),
+		}).Info("Exported project")
+	}
+	return sources, nil
+}
+
+// existingObjects returns the promoted projects already in the target
+// organization and their objects of the given kinds
+func existingObjects(ctx context.Context, client *sdk.Client, sources []promote.Source, transforms *promote.Transforms, kinds []manifest.Kind) ([]manifest.Object, error) {
+	promoted := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		promoted[transforms.ProjectName(source.Project.GetName())] = true
+	}
+
+	projects, err := listObjects(ctx, nobl9API(client), []manifest.Kind{manifest.KindProject})
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []manifest.Object
+	for _, project := range projects {
+		if !promoted[project.GetName()] {
+			continue
+		}
+		objects, err := getProjectObjects(ctx, client, project.GetName(), kinds)
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, project)
+		existing = append(existing, objects...)
+	}
+	return existing, nil
+}
+
+// printPromotionPlan writes a readable promotion plan
+func printPromotionPlan(w io.Writer, plan *promote.Plan) {
+	fmt.Fprintf(w, "Promote from %s to %s: %d to create, %d to update, %d skipped\n",
+		plan.From, plan.To, plan.Count(promote.ActionCreate), plan.Count(promote.ActionUpdate), plan.Count(promote.ActionSkip))
+
+	fmt.Fprintln(w)
+	for i, step := range plan.Steps {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+	}
+
+	if len(plan.Warnings) > 0 {
+		fmt.Fprintln(w, "\nWarnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(w, "  - %s\n", warning)
+		}
+	}
+}
+
+// executePromotionPlan applies the promoted objects in plan order, batching
+// consecutive objects of the same kind
+func executePromotionPlan(ctx context.Context, client *sdk.Client, plan *promote.Plan) error {
+	objects := plan.Objects()
+	for start := 0; start < len(objects); {
+		end := start + 1
+		for end < len(objects) && objects[end].GetKind() == objects[start].GetKind() {
+			end++
+		}
+
+		kind := objects[start].GetKind()
+		if err := client.Objects().V1().Apply(ctx, objects[start:end]); err != nil {
+			return fmt.Errorf("failed to apply %s objects to %s: %w", kind, plan.To, err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"kind":        kind.String(),
+			"environment": plan.To,
+			"count":       end - start,
+		}).Info("Promoted objects")
+		start = end
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"from":    plan.From,
+		"to":      plan.To,
+		"objects": len(objects),
+		"skipped": plan.Count(promote.ActionSkip),
+	}).Info("Promotion completed")
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/skiplist"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// pruneResult counts what pruning did with projects no longer declared
+type pruneResult struct {
+	Marked   int
+	Pending  int
+	Deleted  int
+	Restored int
+	// Released counts projects that another owner took over, which are no
+	// longer managed
+	Released int
+
+	// Objects* count what pruning did with the other objects the state
+	// records that are no longer declared
+	ObjectsMarked  int
+	ObjectsPending int
+	ObjectsDeleted int
+}
+
+// updateState records the declared projects, the applied objects and the
+// run's settings in the state file and, when pruning is enabled, prunes the
+// managed projects and objects that are no longer declared. Nothing is
+// pruned unless every file was processed, since a file that failed may
+// still declare the projects that look removed.
+func updateState(ctx context.Context, api nobl9.Nobl9API, files []*parsedFile, prepared []*preparedFile, kinds nobl9client.KindFilter, skip *skiplist.List, settings map[string]string, complete bool, summary *runSummary) error {
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	if !complete {
+		logrus.WithField("path", config.StateFile).Warn("Some files failed to process, leaving the state and projects untouched")
+		return nil
+	}
+
+	declared := declaredProjects(files)
+	now := time.Now()
+	objects := declaredRecords(prepared, now)
+	summary.Renames = detectRenames(st, objects)
+
+	var pruneErr error
+	if config.Prune {
+		grace, _ := state.ParseDuration(config.DeleteGrace)
+		summary.Prune, pruneErr = pruneProjects(ctx, api, st, declared, grace, config.DryRun)
+		if pruneErr == nil {
+			pruneErr = pruneObjects(ctx, api, st, objects, declared, kinds, skip, grace, config.DryRun, summary.Prune)
+		}
+	}
+
+	if config.DryRun {
+		return pruneErr
+	}
+
+	// Save even after a failed prune so completed deletions are not retried.
+	// Projects of skipPrune files stay declared above, so they are not
+	// marked, but are no longer recorded as managed.
+	unmanaged := skipPruneProjects(files)
+	managed := make([]string, 0, len(declared))
+	for _, name := range declared {
+		if !unmanaged[name] {
+			managed = append(managed, name)
+		}
+	}
+	st.SetDeclared(managed)
+	st.RecordObjects(appliedObjects(st, prepared, now)...)
+	st.SetSettings(settings)
+	if err := st.Save(config.StateFile); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":       config.StateFile,
+		"projects":   len(st.Projects),
+		"objects":    len(st.Objects),
+		"tombstones": len(st.Tombstones),
+	}).Info("Saved state")
+
+	return pruneErr
+}
+
+// declaredProjects returns the names of the projects declared by the parsed files
+func declaredProjects(files []*parsedFile) []string {
+	var names []string
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			if obj.GetKind() == manifest.KindProject {
+				names = append(names, obj.GetName())
+			}
+		}
+	}
+	return names
+}
+
+// pruneProjects deletes managed projects that are no longer declared in two
+// phases: the first run labels them pending-delete and records a tombstone,
+// and a run after the grace period deletes them. Tombstones of projects that
+// are declared again are dropped; applying the project removes its label.
+// Projects carrying the ownership marker of another owner are released from
+// the state instead of being marked or deleted. Marks and deletions are
+// recorded in the audit log.
+func pruneProjects(ctx context.Context, api nobl9.Nobl9API, st *state.State, declared []string, grace time.Duration, dryRun bool) (*pruneResult, error) {
+	marker := newOwnershipMarker()
+	auditLog, err := openAuditLog(dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+	now := time.Now()
+	plan := st.PlanPrune(declared, grace, now)
+	result := &pruneResult{Pending: len(plan.Pending)}
+
+	for _, name := range plan.Restore {
+		logrus.WithField("project", name).Info("Project declared again, cancelling pending deletion")
+		if !dryRun {
+			st.Restore(name)
+		}
+		result.Restored++
+	}
+
+	for _, name := range plan.Pending {
+		logrus.WithFields(logrus.Fields{
+			"project":      name,
+			"delete_after": st.Tombstones[name].DeleteAfter.Format(time.RFC3339),
+		}).Warn("Project is pending deletion")
+	}
+
+	for _, name := range plan.Mark {
+		deleteAfter := now.Add(grace)
+		if dryRun {
+			logrus.WithFields(logrus.Fields{
+				"project":      name,
+				"delete_after": deleteAfter.Format(time.RFC3339),
+			}).Info("DRY RUN: Would mark project for deletion")
+			result.Marked++
+			continue
+		}
+
+		project, err := getProject(ctx, api, name)
+		if err != nil {
+			return result, err
+		}
+		if project == nil {
+			logrus.WithField("project", name).Info("Project no longer exists in Nobl9, forgetting it")
+			st.Forget(name)
+			continue
+		}
+		if released(marker, st, *project) {
+			result.Released++
+			continue
+		}
+		if err := markProjectForDeletion(ctx, api, *project, deleteAfter); err != nil {
+			return result, err
+		}
+		if auditLog != nil {
+			live := map[compare.Key]manifest.Object{compare.KeyOf(*project): *project}
+			auditApplied(auditLog, "", []manifest.Object{markedProject(*project, deleteAfter)}, live)
+		}
+		st.MarkForDeletion(name, grace, now)
+		result.Marked++
+
+		logrus.WithFields(logrus.Fields{
+			"project":      name,
+			"delete_after": deleteAfter.Format(time.RFC3339),
+		}).Warn("Project is no longer declared and was marked for deletion")
+	}
+
+	// Deleting projects needs approval; projects that are not approved stay
+	// pending deletion until a later run
+	if len(plan.Delete) > 0 && !dryRun {
+		if err := approveDeletion("project", plan.Delete); err != nil {
+			logrus.WithField("projects", plan.Delete).WithError(err).Error("Projects past their deletion grace period were not deleted")
+			result.Pending += len(plan.Delete)
+			return result, err
+		}
+	}
+
+	for _, name := range plan.Delete {
+		if dryRun {
+			logrus.WithField("project", name).Info("DRY RUN: Would delete project")
+			result.Deleted++
+			continue
+		}
+
+		var project *v1alphaProject.Project
+		if marker != nil || auditLog != nil {
+			if project, err = getProject(ctx, api, name); err != nil {
+				return result, err
+			}
+			if project != nil && released(marker, st, *project) {
+				result.Released++
+				continue
+			}
+		}
+		if err := api.DeleteObject(ctx, manifest.KindProject, "", name); err != nil {
+			return result, fmt.Errorf("failed to delete project '%s': %w", name, err)
+		}
+		if auditLog != nil && project != nil {
+			auditDeleted(auditLog, *project)
+		}
+		st.Forget(name)
+		result.Deleted++
+
+		logrus.WithField("project", name).Warn("Deleted project past its deletion grace period")
+	}
+
+	return result, nil
+}
+
+// getProject returns the live project, or nil if it no longer exists
+func getProject(ctx context.Context, api nobl9.Nobl9API, name string) (*v1alphaProject.Project, error) {
+	project, err := api.GetProject(ctx, name)
+	if stderrors.Is(err, errors.ErrProjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project '%s': %w", name, err)
+	}
+	return project, nil
+}
+
+// released forgets a project the ownership marker shows another owner
+// took over and reports whether it did
+func released(marker *ownership.Marker, st *state.State, project v1alphaProject.Project) bool {
+	if marker == nil {
+		return false
+	}
+	reason := marker.Foreign(project)
+	if reason == "" {
+		return false
+	}
+	logrus.WithFields(logrus.Fields{
+		"project": project.GetName(),
+		"owner":   reason,
+	}).Warn("Project is managed by another owner, no longer pruning it")
+	st.Forget(project.GetName())
+	return true
+}
+
+// markProjectForDeletion labels the live project pending-delete and annotates
+// it with the time after which it will be deleted
+func markProjectForDeletion(ctx context.Context, api nobl9.Nobl9API, project v1alphaProject.Project, deleteAfter time.Time) error {
+	marked := markedProject(project, deleteAfter)
+	if err := api.ApplyObjects(ctx, []manifest.Object{marked}); err != nil {
+		return fmt.Errorf("failed to mark project '%s' for deletion: %w", project.GetName(), err)
+	}
+	return nil
+}
+
+// markedProject returns a copy of the project with the pending-delete label
+// and the delete-after annotation set
+func markedProject(project v1alphaProject.Project, deleteAfter time.Time) v1alphaProject.Project {
+	labels := make(v1alpha.Labels, len(project.Metadata.Labels)+1)
+	for key, values := range project.Metadata.Labels {
+		labels[key] = values
+	}
+	labels[state.PendingDeleteLabel] = []string{"true"}
+
+	annotations := make(v1alpha.MetadataAnnotations, len(project.Metadata.Annotations)+1)
+	for key, value := range project.Metadata.Annotations {
+		annotations[key] = value
+	}
+	annotations[state.DeleteAfterAnnotation] = deleteAfter.UTC().Format(time.RFC3339)
+
+	project.Metadata.Labels = labels
+	project.Metadata.Annotations = annotations
+
+	return project
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/recommend"
+)
+
+// recommendations evaluates the recommendation rules against the finished
+// run and logs each recommendation
+func recommendations(summary *runSummary, results *runResults) []recommend.Recommendation {
+	found := recommend.Evaluate(runFacts(summary, results))
+	for _, recommendation := range found {
+		logrus.WithField("rule", recommendation.Rule).Info("Recommendation: " + recommendation.Message)
+	}
+	return found
+}
+
+// runFacts collects the statistics of the run the recommendation rules
+// look at
+func runFacts(summary *runSummary, results *runResults) recommend.Facts {
+	misses, _ := summary.UserCache["misses"].(int)
+	facts := recommend.Facts{
+		Emails:                 summary.EmailsLookedUp,
+		UnresolvedEmails:       summary.EmailsUnresolved,
+		PersistentCache:        config.UserCacheFile != "",
+		CacheMisses:            misses,
+		RateLimited:            summary.RateLimited,
+		BreakerTrips:           summary.Breaker.Trips,
+		RetryableErrors:        len(results.aggregator.GetRetryableErrors()),
+		AuthErrors:             len(results.aggregator.GetErrorsByType(errors.ErrorTypeAuth)),
+		Files:                  summary.TotalFiles,
+		OtherOrganizationFiles: summary.FilesSkipped,
+		SkippedObjects:         summary.Skipped.Total(),
+		SkippedKinds:           summary.Skipped.Kinds(),
+	}
+	for _, file := range results.Files {
+		if file.Error == nil {
+			continue
+		}
+		path := file.Path
+		if relative, err := filepath.Rel(config.RepoPath, file.Path); err == nil {
+			path = relative
+		}
+		facts.FailedFiles = append(facts.FailedFiles, filepath.ToSlash(path))
+	}
+	return facts
+}
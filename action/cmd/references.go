@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	objectsV1 "github.com/nobl9/nobl9-go/sdk/endpoints/objects/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/references"
+)
+
+// runReferenceValidation warns about objects referencing objects no file of
+// the repository declares. Without --remote Nobl9 is not checked, so the
+// referenced objects may exist there and the warnings never fail the run.
+func runReferenceValidation(ctx context.Context, filePaths []string) error {
+	var files []*parsedFile
+	for _, filePath := range filePaths {
+		parsed, err := parseRemoteFile(filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, parsed)
+	}
+
+	issues, err := references.Check(ctx, referenceItems(files), nil)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fields := logrus.Fields{
+			"file":      issue.Source,
+			"kind":      issue.Kind,
+			"name":      issue.Name,
+			"reference": issue.Reference.String(),
+		}
+		if len(issue.Expected) > 0 {
+			fields["expected"] = strings.Join(issue.Expected, ",")
+		}
+		logrus.WithFields(fields).Warn(issue.Message())
+	}
+	log := logrus.WithField("warnings", len(issues))
+	if len(issues) > 0 {
+		log.Info("Reference check completed; undeclared objects must exist in Nobl9, which --remote checks")
+		return nil
+	}
+	log.Info("Reference check completed")
+	return nil
+}
+
+// referenceItems returns the objects of the files with their file
+func referenceItems(files []*parsedFile) []planner.Item {
+	var items []planner.Item
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			items = append(items, planner.Item{Object: obj, Source: file.Path})
+		}
+	}
+	return items
+}
+
+// liveReferences looks references up in Nobl9, with one call per kind and
+// project
+func liveReferences(client *sdk.Client) references.Lookup {
+	return func(ctx context.Context, refs []references.Reference) (map[references.Reference]bool, error) {
+		type scope struct {
+			kind    manifest.Kind
+			project string
+		}
+		names := make(map[scope][]string)
+		var scopes []scope
+		for _, ref := range refs {
+			key := scope{kind: ref.Kind, project: ref.Project}
+			if _, found := names[key]; !found {
+				scopes = append(scopes, key)
+			}
+			names[key] = append(names[key], ref.Name)
+		}
+
+		live := make(map[references.Reference]bool)
+		for _, key := range scopes {
+			found, err := liveObjectNames(ctx, client, key.kind, key.project, names[key])
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range found {
+				live[references.Reference{Kind: key.kind, Project: key.project, Name: name}] = true
+			}
+		}
+		return live, nil
+	}
+}
+
+// liveObjectNames returns which of the named objects of a kind exist in a
+// project of Nobl9
+func liveObjectNames(ctx context.Context, client *sdk.Client, kind manifest.Kind, project string, names []string) ([]string, error) {
+	header := http.Header{}
+	if project != "" {
+		header.Set(sdk.HeaderProject, project)
+	}
+	objects, err := client.Objects().V1().Get(ctx, kind, header, url.Values{objectsV1.QueryKeyName: names})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s objects: %w", kind, err)
+	}
+
+	found := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		found = append(found, obj.GetName())
+	}
+	return found, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/references"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+)
+
+// remoteIssue is a problem found by checking a file against live Nobl9 state
+type remoteIssue struct {
+	File    string
+	Kind    string
+	Name    string
+	Message string
+}
+
+// validateRemote checks the parsed files against live Nobl9 state without
+// applying anything: referenced projects, services, data sources, alert
+// policies and alert methods exist, emails resolve to users and role names
+// are known roles. Objects declared in the repository count as existing,
+// since process would apply them first.
+func validateRemote(ctx context.Context, client *sdk.Client, files []*parsedFile) ([]remoteIssue, error) {
+	var issues []remoteIssue
+
+	referenceIssues, err := checkRemoteReferences(ctx, client, files)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, referenceIssues...)
+
+	issues = append(issues, checkRemoteEmails(ctx, client, files)...)
+	if config.CheckRecipients {
+		issues = append(issues, checkRemoteRecipients(ctx, client, files)...)
+	}
+
+	roleIssues, err := checkRemoteRoles(ctx, client, files)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, roleIssues...)
+
+	return issues, nil
+}
+
+// checkRemoteReferences reports objects referencing objects that are
+// neither declared in the repository nor present in Nobl9
+func checkRemoteReferences(ctx context.Context, client *sdk.Client, files []*parsedFile) ([]remoteIssue, error) {
+	found, err := references.Check(ctx, referenceItems(files), liveReferences(client))
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]remoteIssue, 0, len(found))
+	for _, issue := range found {
+		issues = append(issues, remoteIssue{
+			File:    issue.Source,
+			Kind:    issue.Kind.String(),
+			Name:    issue.Name,
+			Message: issue.Message(),
+		})
+	}
+	return issues, nil
+}
+
+// checkRemoteEmails reports role binding emails that do not resolve to a
+// Nobl9 user
+func checkRemoteEmails(ctx context.Context, client *sdk.Client, files []*parsedFile) []remoteIssue {
+	resolutions := resolveEmails(ctx, nobl9API(client), resolver.NewUserCache(config.UserCacheTTL), nil, collectEmails(files), nil)
+
+	var issues []remoteIssue
+	for _, file := range files {
+		for _, email := range file.Emails {
+			if _, ok := resolutions[email]; ok {
+				continue
+			}
+			issues = append(issues, remoteIssue{
+				File:    file.Path,
+				Kind:    manifest.KindRoleBinding.String(),
+				Message: fmt.Sprintf("email '%s' does not resolve to a Nobl9 user", email),
+			})
+		}
+	}
+	return issues
+}
+
+// checkRemoteRecipients reports email recipients, such as those of email
+// alert methods, that are not Nobl9 users. Recipients are only looked up;
+// they are applied as written.
+func checkRemoteRecipients(ctx context.Context, client *sdk.Client, files []*parsedFile) []remoteIssue {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			for _, recipient := range resolver.Recipients(obj) {
+				if !seen[recipient.Email] {
+					seen[recipient.Email] = true
+					emails = append(emails, recipient.Email)
+				}
+			}
+		}
+	}
+	resolutions := resolveEmails(ctx, nobl9API(client), resolver.NewUserCache(config.UserCacheTTL), nil, emails, nil)
+
+	var issues []remoteIssue
+	for _, file := range files {
+		for _, obj := range file.Objects {
+			for _, recipient := range resolver.Recipients(obj) {
+				if _, ok := resolutions[recipient.Email]; ok {
+					continue
+				}
+				issues = append(issues, remoteIssue{
+					File:    file.Path,
+					Kind:    recipient.Kind,
+					Name:    recipient.Name,
+					Message: fmt.Sprintf("%s recipient '%s' is not a Nobl9 user", recipient.Field, recipient.Email),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// checkRemoteRoles reports role bindings whose role is neither in the role
+// catalog nor granted by a live role binding. Roles are not checked when
+// the catalog is disabled.
+func checkRemoteRoles(ctx context.Context, client *sdk.Client, files []*parsedFile) ([]remoteIssue, error) {
+	catalog, err := loadRoleCatalog()
+	if err != nil || catalog == nil {
+		return nil, err
+	}
+	unknown, err := checkRoles(ctx, nobl9API(client), catalog, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []remoteIssue
+	for _, file := range files {
+		for _, u := range unknown[file.Path] {
+			issues = append(issues, remoteIssue{
+				File:    file.Path,
+				Kind:    manifest.KindRoleBinding.String(),
+				Name:    u.Name,
+				Message: u.Error(),
+			})
+		}
+	}
+	return issues, nil
+}
+
+// logRemoteIssues logs each issue and returns the files that have any
+func logRemoteIssues(issues []remoteIssue) map[string]bool {
+	failed := make(map[string]bool)
+	for _, issue := range issues {
+		fields := logrus.Fields{"file": issue.File, "kind": issue.Kind}
+		if issue.Name != "" {
+			fields["name"] = issue.Name
+		}
+		logrus.WithFields(fields).Error(issue.Message)
+		failed[issue.File] = true
+	}
+	return failed
+}
+
+// runRemoteValidation checks the files against live Nobl9 state and returns
+// the number of files with issues
+func runRemoteValidation(ctx context.Context, filePaths []string) (int, error) {
+	if len(filePaths) == 0 {
+		return 0, nil
+	}
+
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return 0, err
+	}
+	// The checks read the same projects and users for many files
+	apiCache := nobl9.CacheAPICalls(client.HTTP, 0, newLogger())
+
+	var files []*parsedFile
+	for _, filePath := range filePaths {
+		parsed, err := parseRemoteFile(filePath)
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, parsed)
+	}
+
+	logrus.WithField("file_count", len(files)).Info("Checking files against Nobl9")
+
+	issues, err := validateRemote(ctx, client, files)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := logRemoteIssues(issues)
+	logrus.WithFields(logrus.Fields{
+		"issues":            len(issues),
+		"files_with_issues": len(failed),
+		"api_cache_hits":    apiCache.Stats().Hits,
+	}).Info("Remote validation completed")
+
+	return len(failed), nil
+}
+
+// parseRemoteFile decodes a file that passed offline validation. Okta group
+// role bindings are not expanded; their project and role are still checked.
+func parseRemoteFile(filePath string) (*parsedFile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	if isCSVFile(filePath) {
+		objects, err := nobl9client.ParseRoleBindingCSV(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role binding CSV %s: %w", filePath, err)
+		}
+		return &parsedFile{Path: filePath, Objects: objects, Emails: appendRoleBindingEmails(nil, objects), Bindings: roleBindingsOf(nil, filePath, objects)}, nil
+	}
+	if isOwnersFile(filePath) {
+		objects, err := parseOwnersFile(filePath, content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OWNERS file %s: %w", filePath, err)
+		}
+		return &parsedFile{Path: filePath, Objects: objects, Emails: appendRoleBindingEmails(nil, objects), Bindings: roleBindingsOf(nil, filePath, objects)}, nil
+	}
+
+	content, err = renderManifest(filePath, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", filePath, err)
+	}
+	content, err = substituteVariables(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute variables in %s: %w", filePath, err)
+	}
+	content, err = checkCompatibility(content, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("incompatible manifest %s: %w", filePath, err)
+	}
+
+	meta, content, err := parser.ExtractMeta(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", parser.MetaKind, filePath, err)
+	}
+	content, err = overlayEnvironment(meta, content, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", parser.MetaKind, filePath, err)
+	}
+
+	objects, emails, err := parseYAMLContent(content, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	return &parsedFile{
+		Path:     filePath,
+		Meta:     meta,
+		Objects:  objects,
+		Emails:   appendRoleBindingEmails(emails, objects),
+		Bindings: roleBindingsOf(content, filePath, objects),
+	}, nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	objectsV1 "github.com/nobl9/nobl9-go/sdk/endpoints/objects/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/kindlist"
+	"github.com/your-org/nobl9-action/pkg/rename"
+)
+
+// Rename command - groups rename operations
+var renameCmd = &cobra.Command{
+	Use:     "rename",
+	Short:   "Rename Nobl9 objects across manifests and Nobl9",
+	Long:    `Rename Nobl9 objects in repository manifests and plan or run the matching migration in Nobl9.`,
+	GroupID: groupUtility,
+}
+
+// Rename project command
+var renameProjectCmd = &cobra.Command{
+	Use:   "project <old> <new>",
+	Short: "Rename a project in manifests and migrate its objects in Nobl9",
+	Long: `Rewrite every repository manifest referencing the old project (metadata.project, projectRef
+and nested project references such as composite SLO components) to the new name, and print the
+migration plan for Nobl9.
+
+Nobl9 projects cannot be renamed in place. With credentials the plan lists the live-side steps:
+create the new project, copy every object of the old project into it and delete the old project.
+With --execute the plan is run after confirmation.`,
+	Example: `  # Preview the manifest changes and the live migration plan
+  nobl9-action rename project payments billing --dry-run \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"
+
+  # Rewrite the manifests only
+  nobl9-action rename project payments billing --repo-path ./nobl9
+
+  # Rewrite the manifests and migrate the live objects without prompting
+  nobl9-action rename project payments billing --execute --yes \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRenameProject,
+}
+
+// runRenameProject rewrites the manifests and plans, and optionally runs, the live migration
+func runRenameProject(cmd *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if oldName == newName {
+		return configError(fmt.Errorf("the new project name must differ from the old one"))
+	}
+	if config.Execute && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("--execute requires --client-id and --client-secret"))
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	files, err := scanFiles(config.RepoPath, config.FilePattern)
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+
+	plan := &rename.Plan{OldProject: oldName, NewProject: newName}
+	plan.Files, err = rewriteManifests(files, oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	var client *sdk.Client
+	if config.ClientID != "" && config.ClientSecret != "" {
+		client, err = createNobl9Client(config.ClientID, config.ClientSecret)
+		if err != nil {
+			return err
+		}
+		if err := planLiveMigration(ctx, client, plan); err != nil {
+			return err
+		}
+	} else {
+		plan.Warnings = append(plan.Warnings, "no Nobl9 credentials given, the live migration was not planned")
+	}
+
+	printRenamePlan(cmd.OutOrStdout(), plan)
+
+	if config.DryRun {
+		logrus.Info("DRY RUN: No manifests were rewritten")
+		return nil
+	}
+
+	for _, change := range plan.Files {
+		if err := writeManifest(change); err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{
+			"file":       change.Path,
+			"references": len(change.References),
+		}).Info("Rewrote manifest")
+	}
+
+	if !config.Execute {
+		return nil
+	}
+
+	if manual := plan.Count(rename.ActionManual); manual > 0 {
+		return fmt.Errorf("%d objects hold credentials and must be applied to project '%s' before the migration can run", manual, newName)
+	}
+	if !config.Yes {
+		confirmed, err := confirmName(cmd.InOrStdin(), cmd.OutOrStdout(), "the new project name", newName, "run the migration")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("rename not confirmed")
+		}
+	}
+
+	return executeRenamePlan(ctx, client, plan)
+}
+
+// rewriteManifests rewrites the references to the old project in every file
+// and returns the files that changed
+func rewriteManifests(files []string, oldName, newName string) ([]rename.FileChange, error) {
+	var changes []rename.FileChange
+	for _, filePath := range files {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+
+		rewritten, references, err := rename.RewriteProject(content, oldName, newName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite %s: %w", filePath, err)
+		}
+		if len(references) == 0 {
+			continue
+		}
+
+		changes = append(changes, rename.FileChange{
+			Path:       filePath,
+			References: references,
+			Content:    rewritten,
+		})
+	}
+	return changes, nil
+}
+
+// writeManifest writes a rewritten manifest, keeping its permissions
+func writeManifest(change rename.FileChange) error {
+	info, err := os.Stat(change.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", change.Path, err)
+	}
+	if err := os.WriteFile(change.Path, change.Content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", change.Path, err)
+	}
+	return nil
+}
+
+// planLiveMigration adds the live-side steps to the plan. The old project
+// must exist; the new one may already exist from an interrupted rename.
+func planLiveMigration(ctx context.Context, client *sdk.Client, plan *rename.Plan) error {
+	projects, err := client.Objects().V1().GetV1alphaProjects(ctx, objectsV1.GetProjectsRequest{
+		Names: []string{plan.OldProject, plan.NewProject},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	var live rename.Live
+	for _, project := range projects {
+		switch project.GetName() {
+		case plan.OldProject:
+			live.Project = project
+		case plan.NewProject:
+			live.NewProjectExists = true
+		}
+	}
+	if live.Project == nil {
+		return fmt.Errorf("project '%s' does not exist in Nobl9", plan.OldProject)
+	}
+
+	if live.Objects, err = getProjectObjects(ctx, client, plan.OldProject, kindlist.ProjectScoped); err != nil {
+		return err
+	}
+	if live.NewProjectExists {
+		if live.Existing, err = getProjectObjects(ctx, client, plan.NewProject, kindlist.ProjectScoped); err != nil {
+			return err
+		}
+	}
+
+	steps, warnings, err := rename.BuildSteps(live, plan.OldProject, plan.NewProject)
+	if err != nil {
+		return err
+	}
+	plan.Steps = steps
+	plan.Warnings = append(plan.Warnings, warnings...)
+
+	return nil
+}
+
+// getProjectObjects returns the live objects of the given kinds in the project
+func getProjectObjects(ctx context.Context, client *sdk.Client, project string, kinds []manifest.Kind) ([]manifest.Object, error) {
+	header := http.Header{sdk.HeaderProject: []string{project}}
+
+	var objects []manifest.Object
+	for _, kind := range kinds {
+		found, err := client.Objects().V1().Get(ctx, kind, header, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s objects of project '%s': %w", kind, project, err)
+		}
+		objects = append(objects, found...)
+	}
+	return objects, nil
+}
+
+// printRenamePlan writes a readable migration plan
+func printRenamePlan(w io.Writer, plan *rename.Plan) {
+	fmt.Fprintf(w, "Rename project %s to %s\n", plan.OldProject, plan.NewProject)
+
+	fmt.Fprintf(w, "\nManifests (%d files):\n", len(plan.Files))
+	for _, change := range plan.Files {
+		fmt.Fprintf(w, "  %s\n", change.Path)
+		for _, reference := range change.References {
+			fmt.Fprintf(w, "    %s\n", reference)
+		}
+	}
+
+	if len(plan.Steps) > 0 {
+		fmt.Fprintf(w, "\nNobl9 (%d steps):\n", len(plan.Steps))
+		for i, step := range plan.Steps {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+		}
+	}
+
+	if len(plan.Warnings) > 0 {
+		fmt.Fprintln(w, "\nWarnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(w, "  - %s\n", warning)
+		}
+	}
+}
+
+// confirmName asks the user to type name and reports whether they did.
+// prompt describes the name, e.g. "the new project name", and action what
+// confirming does.
+func confirmName(in io.Reader, out io.Writer, prompt, name, action string) (bool, error) {
+	fmt.Fprintf(out, "\nType %s (%s) to %s: ", prompt, name, action)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	return strings.TrimSpace(answer) == name, nil
+}
+
+// executeRenamePlan applies the create and copy steps in order, batching
+// consecutive objects of the same kind, and then deletes the old project
+func executeRenamePlan(ctx context.Context, client *sdk.Client, plan *rename.Plan) error {
+	var batch []manifest.Object
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		kind := batch[0].GetKind()
+		if err := client.Objects().V1().Apply(ctx, batch); err != nil {
+			return fmt.Errorf("failed to apply %s objects to project '%s': %w", kind, plan.NewProject, err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"kind":    kind.String(),
+			"project": plan.NewProject,
+			"count":   len(batch),
+		}).Info("Copied objects")
+		batch = nil
+		return nil
+	}
+
+	for _, step := range plan.Steps {
+		switch step.Action {
+		case rename.ActionCreate, rename.ActionCopy:
+			if len(batch) > 0 && batch[0].GetKind() != step.Kind {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			batch = append(batch, step.Object)
+		case rename.ActionDelete:
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := client.Objects().V1().DeleteByName(ctx, manifest.KindProject, "", step.Name); err != nil {
+				return fmt.Errorf("failed to delete project '%s': %w", step.Name, err)
+			}
+			logrus.WithField("project", step.Name).Warn("Deleted old project")
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"old_project": plan.OldProject,
+		"new_project": plan.NewProject,
+	}).Info("Project renamed")
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/render"
+)
+
+// renderEngine returns the engine of --render, checked when the files are
+// scanned
+func renderEngine() render.Engine {
+	engine, _ := render.ParseEngine(config.Render)
+	return engine
+}
+
+// renderManifest evaluates a .jsonnet or .cue file into YAML with --render,
+// and returns the content of any other file as read
+func renderManifest(filePath string, content []byte) ([]byte, error) {
+	engine := renderEngine()
+	if !engine.IsSource(filePath) {
+		return content, nil
+	}
+
+	rendered, err := engine.Render(filePath, content)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"file":   filePath,
+		"engine": engine,
+	}).Debug("Rendered manifest")
+	return rendered, nil
+}
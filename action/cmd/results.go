@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/impact"
+	"github.com/your-org/nobl9-action/pkg/lint"
+	"github.com/your-org/nobl9-action/pkg/progress"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/rollback"
+)
+
+// resultsSchemaVersion is the version of the results file format; it is
+// increased whenever a field is removed or changes meaning
+const resultsSchemaVersion = 1
+
+// Object statuses reported in the results file
+const (
+	statusApplied    = "applied"
+	statusDryRun     = "dry_run"
+	statusUnchanged  = "unchanged"
+	statusSkipped    = "skipped"
+	statusFailed     = "failed"
+	statusNotApplied = "not_applied"
+	statusPending    = "pending"
+	// statusRolledBack is set on applied objects restored to their previous
+	// definition, or deleted, by a rollback
+	statusRolledBack = "rolled_back"
+)
+
+// Processing phases a file can fail in
+const (
+	phaseParse   = "parse"
+	phasePolicy  = "policy"
+	phaseResolve = "resolve"
+	phasePrepare = "prepare"
+	phaseApply   = "apply"
+	phaseState   = "state"
+)
+
+// abortedReason is reported for files left unprocessed after a critical error
+const abortedReason = "aborted early after critical error"
+
+// runResults is the complete result of a process run written by --results-file
+type runResults struct {
+	SchemaVersion int            `json:"schema_version"`
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+	DurationMs    int64          `json:"duration_ms"`
+	DryRun        bool           `json:"dry_run"`
+	PlanHash      string         `json:"plan_hash,omitempty"`
+	Success       bool           `json:"success"`
+	Summary       resultsSummary `json:"summary"`
+	Files         []fileResult   `json:"files"`
+	Errors        []resultError  `json:"errors"`
+	// AbortedBy is the critical error that stopped the run early
+	AbortedBy *resultError `json:"aborted_by,omitempty"`
+	// HighImpact are the SLO changes flagged for shrinking error budgets
+	HighImpact []impact.Change `json:"high_impact,omitempty"`
+	// LintWarnings are the likely mistakes found in SLOs
+	LintWarnings []lint.Finding `json:"lint_warnings,omitempty"`
+	// Environment is the --environment the files were specialized for
+	Environment string `json:"environment,omitempty"`
+
+	// owners are the owner teams of files with ActionMeta, by path
+	owners map[string]string
+	// aggregator collects every error of the run; its first critical error
+	// aborts the remaining work
+	aggregator *errors.ErrorAggregator
+}
+
+// resultsSummary holds the run totals
+type resultsSummary struct {
+	TotalFiles            int            `json:"total_files"`
+	FilesProcessed        int            `json:"files_processed"`
+	FilesWithErrors       int            `json:"files_with_errors"`
+	FilesSkipped          int            `json:"files_skipped"`
+	FilesAborted          int            `json:"files_aborted"`
+	FilesResumed          int            `json:"files_resumed"`
+	FilesOutOfScope       int            `json:"files_out_of_scope"`
+	ProjectsCreated       int            `json:"projects_created"`
+	RoleBindingsCreated   int            `json:"role_bindings_created"`
+	RoleBindingsUnchanged int            `json:"role_bindings_unchanged"`
+	RoleBindingsMerged    int            `json:"role_bindings_merged"`
+	EmailsResolved        int            `json:"emails_resolved"`
+	ObjectsSkipped        int            `json:"objects_skipped"`
+	ObjectsByKind         map[string]int `json:"objects_by_kind"`
+	APICalls              int            `json:"api_calls"`
+}
+
+// fileResult is the result of a single file
+type fileResult struct {
+	Path  string `json:"path"`
+	Owner string `json:"owner,omitempty"`
+	// Organization is the organization the file was routed to, in runs
+	// applying files to several organizations
+	Organization string         `json:"organization,omitempty"`
+	Success      bool           `json:"success"`
+	SkipReason   string         `json:"skip_reason,omitempty"`
+	DurationMs   int64          `json:"duration_ms"`
+	Objects      []objectResult `json:"objects"`
+	Error        *resultError   `json:"error,omitempty"`
+}
+
+// objectResult is the status of a single object
+type objectResult struct {
+	Kind    string       `json:"kind"`
+	Name    string       `json:"name"`
+	Project string       `json:"project,omitempty"`
+	Status  string       `json:"status"`
+	Error   *resultError `json:"error,omitempty"`
+	// Rollback is the state of an applied object before the run, used to
+	// roll the run back
+	Rollback *rollback.Record `json:"rollback,omitempty"`
+}
+
+// resultError describes an error with its classification
+type resultError struct {
+	Phase     string `json:"phase,omitempty"`
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Retryable bool   `json:"retryable"`
+}
+
+// objectOutcome tracks the status of an object while a run progresses
+type objectOutcome struct {
+	Kind    string
+	Name    string
+	Project string
+	Status  string
+	Err     error
+	// Rollback is the state of the object before it was applied, or nil
+	// when it was not applied or could not be read
+	Rollback *rollback.Record
+}
+
+// newObjectOutcome creates the outcome of an object with the given status
+func newObjectOutcome(obj manifest.Object, status string) *objectOutcome {
+	outcome := &objectOutcome{
+		Kind:   obj.GetKind().String(),
+		Name:   obj.GetName(),
+		Status: status,
+	}
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok {
+		outcome.Project = scoped.GetProject()
+	}
+	return outcome
+}
+
+// recordRollback records the state of applied objects before the apply.
+// live holds the versions read before the apply; objects missing from it
+// were created.
+func (f *preparedFile) recordRollback(objects []manifest.Object, live map[compare.Key]manifest.Object) {
+	for _, obj := range objects {
+		outcome, ok := f.outcomes[outcomeKey(obj.GetKind().String(), obj.GetName())]
+		if !ok {
+			continue
+		}
+		record, err := rollback.NewRecord(obj, live[compare.KeyOf(obj)])
+		if err != nil {
+			logrus.WithField("file", f.Path).WithError(err).Warn("Failed to record the previous state of an applied object, it cannot be rolled back")
+			continue
+		}
+		outcome.Rollback = record
+	}
+}
+
+// outcomeKey identifies an object within a file
+func outcomeKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// addOutcome records the outcome of one of the file's objects
+func (f *preparedFile) addOutcome(outcome *objectOutcome) {
+	if f.outcomes == nil {
+		f.outcomes = make(map[string]*objectOutcome)
+	}
+	f.Outcomes = append(f.Outcomes, outcome)
+	f.outcomes[outcomeKey(outcome.Kind, outcome.Name)] = outcome
+}
+
+// setStatus updates the status of the given objects
+func (f *preparedFile) setStatus(objects []manifest.Object, status string, err error) {
+	for _, obj := range objects {
+		f.setStatusByName(obj.GetKind().String(), obj.GetName(), status, err)
+	}
+}
+
+// setStatusByName updates the status of the object with the given kind and name
+func (f *preparedFile) setStatusByName(kind, name, status string, err error) {
+	if outcome, ok := f.outcomes[outcomeKey(kind, name)]; ok {
+		outcome.Status = status
+		outcome.Err = err
+	}
+}
+
+// newRunResults creates the results of a run started at the given time
+func newRunResults(startedAt time.Time, dryRun bool) *runResults {
+	return &runResults{
+		SchemaVersion: resultsSchemaVersion,
+		StartedAt:     startedAt.UTC(),
+		DryRun:        dryRun,
+		Environment:   config.Environment,
+		Files:         []fileResult{},
+		Errors:        []resultError{},
+		aggregator:    errors.NewErrorAggregator(),
+	}
+}
+
+// addFailedFile records a file that failed before its objects were applied
+func (r *runResults) addFailedFile(path, phase string, err error, duration time.Duration) {
+	r.aggregator.AddError(classifyError(phase, err))
+	failure := describeError(phase, err)
+	runProgress.AddFile(path, progress.StatusFailed)
+	r.Files = append(r.Files, fileResult{
+		Path:       path,
+		DurationMs: duration.Milliseconds(),
+		Objects:    []objectResult{},
+		Error:      &failure,
+	})
+}
+
+// addSkippedFile records a file that was intentionally not processed
+func (r *runResults) addSkippedFile(path, reason string) {
+	runProgress.AddFile(path, progress.StatusSkipped)
+	r.Files = append(r.Files, fileResult{
+		Path:       path,
+		Success:    true,
+		SkipReason: reason,
+		Objects:    []objectResult{},
+	})
+}
+
+// addAbortedFile records a file that was not processed because a critical
+// error aborted the run
+func (r *runResults) addAbortedFile(path string) {
+	runProgress.AddFile(path, progress.StatusAborted)
+	r.Files = append(r.Files, fileResult{
+		Path:       path,
+		SkipReason: abortedReason,
+		Objects:    []objectResult{},
+	})
+}
+
+// setOwner records the owner team of a file, reported with its result
+func (r *runResults) setOwner(path, owner string) {
+	if owner == "" {
+		return
+	}
+	if r.owners == nil {
+		r.owners = make(map[string]string)
+	}
+	r.owners[path] = owner
+}
+
+// addFile records a file whose objects were planned for apply. Objects that
+// were still pending when the file failed are reported as not applied.
+func (r *runResults) addFile(file *preparedFile) {
+	result := fileResult{
+		Path:       file.Path,
+		Success:    file.Err == nil && !file.Aborted,
+		DurationMs: file.Duration.Milliseconds(),
+		Objects:    make([]objectResult, 0, len(file.Outcomes)),
+	}
+	if file.Aborted {
+		result.SkipReason = abortedReason
+	}
+
+	for _, outcome := range file.Outcomes {
+		object := objectResult{
+			Kind:     outcome.Kind,
+			Name:     outcome.Name,
+			Project:  outcome.Project,
+			Status:   outcome.Status,
+			Rollback: outcome.Rollback,
+		}
+		if object.Status == statusPending {
+			object.Status = statusNotApplied
+		}
+		if outcome.Err != nil {
+			objectErr := describeError(phaseApply, outcome.Err)
+			object.Error = &objectErr
+		}
+		result.Objects = append(result.Objects, object)
+	}
+
+	if file.Err != nil {
+		failure := describeError(phaseApply, file.Err)
+		result.Error = &failure
+	}
+
+	r.Files = append(r.Files, result)
+	status := progress.StatusSucceeded
+	switch {
+	case file.Aborted:
+		status = progress.StatusAborted
+	case file.Err != nil:
+		status = progress.StatusFailed
+	}
+	runProgress.AddFile(file.Path, status)
+}
+
+// addError records an error that does not belong to a single file
+func (r *runResults) addError(phase string, err error) {
+	r.aggregator.AddError(classifyError(phase, err))
+	r.Errors = append(r.Errors, describeError(phase, err))
+}
+
+// merge adds the files and errors of the run of an organization, marking
+// its files with the organization
+func (r *runResults) merge(organization string, other *runResults) {
+	for _, file := range other.Files {
+		file.Organization = organization
+		r.Files = append(r.Files, file)
+	}
+	r.Errors = append(r.Errors, other.Errors...)
+	r.HighImpact = append(r.HighImpact, other.HighImpact...)
+	r.LintWarnings = append(r.LintWarnings, other.LintWarnings...)
+	for path, owner := range other.owners {
+		r.setOwner(path, owner)
+	}
+	for _, err := range other.aggregator.GetErrors() {
+		r.aggregator.AddError(err)
+	}
+}
+
+// finish fills in the totals once the run is complete
+func (r *runResults) finish(summary *runSummary, totalErrors int) {
+	finishedAt := time.Now().UTC()
+	r.FinishedAt = finishedAt
+	r.DurationMs = finishedAt.Sub(r.StartedAt).Milliseconds()
+	r.Success = totalErrors == 0
+	r.Summary = resultsSummary{
+		TotalFiles:            summary.TotalFiles,
+		FilesProcessed:        summary.FilesProcessed,
+		FilesWithErrors:       summary.FilesWithErrors,
+		FilesSkipped:          summary.FilesSkipped,
+		FilesAborted:          summary.FilesAborted,
+		FilesResumed:          summary.FilesResumed,
+		FilesOutOfScope:       summary.FilesOutOfScope,
+		ProjectsCreated:       summary.ProjectsCreated,
+		RoleBindingsCreated:   summary.RoleBindingsCreated,
+		RoleBindingsUnchanged: summary.RoleBindingsUnchanged,
+		RoleBindingsMerged:    summary.RoleBindingsMerged,
+		EmailsResolved:        summary.EmailsResolved,
+		ObjectsSkipped:        summary.Skipped.Total(),
+		ObjectsByKind:         summary.ObjectsByKind,
+		APICalls:              summary.apiCallTotal(),
+	}
+	if summary.AbortedBy != nil {
+		abortedBy := describeError("", summary.AbortedBy)
+		r.AbortedBy = &abortedBy
+	}
+	for i := range r.Files {
+		r.Files[i].Owner = r.owners[r.Files[i].Path]
+	}
+}
+
+// write saves the results as JSON
+func (r *runResults) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create results directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+
+	return nil
+}
+
+// describeError classifies an error for the results file. Errors that are
+// not a Nobl9Error get the type typical for the phase they occurred in.
+func describeError(phase string, err error) resultError {
+	described := resultError{
+		Phase:    phase,
+		Message:  err.Error(),
+		Severity: string(errors.SeverityHigh),
+	}
+
+	var nobl9Err *errors.Nobl9Error
+	var httpErr *sdk.HTTPError
+	switch {
+	case stderrors.As(err, &nobl9Err):
+		described.Type = string(nobl9Err.Type)
+		described.Severity = string(nobl9Err.Severity)
+		described.Retryable = nobl9Err.Retryable
+	case isTokenRejected(err):
+		described.Type = string(errors.ErrorTypeAuth)
+		described.Severity = string(errors.SeverityCritical)
+	case isRateLimited(err):
+		described.Type = string(errors.ErrorTypeRateLimit)
+		described.Severity = string(errors.SeverityMedium)
+		described.Retryable = true
+	case stderrors.Is(err, context.DeadlineExceeded):
+		described.Type = string(errors.ErrorTypeTimeout)
+		described.Severity = string(errors.SeverityMedium)
+		described.Retryable = true
+	case stderrors.As(err, &httpErr):
+		converted := errors.FromSDKError(err)
+		described.Type = string(converted.Type)
+		described.Severity = string(converted.Severity)
+		described.Retryable = converted.Retryable
+	default:
+		described.Type = string(phaseErrorType(phase))
+	}
+
+	return described
+}
+
+// classifyError returns err as a Nobl9Error classified like describeError,
+// for the run's error aggregator
+func classifyError(phase string, err error) *errors.Nobl9Error {
+	var nobl9Err *errors.Nobl9Error
+	if stderrors.As(err, &nobl9Err) {
+		return nobl9Err
+	}
+	described := describeError(phase, err)
+	return errors.Wrap(err, errors.ErrorType(described.Type), errors.ErrorSeverity(described.Severity), phase+" failed")
+}
+
+// recordError adds an error to the aggregator, if there is one
+func recordError(aggregator *errors.ErrorAggregator, phase string, err error) {
+	if aggregator != nil {
+		aggregator.AddError(classifyError(phase, err))
+	}
+}
+
+// isTokenRejected reports whether the identity provider refused to issue an
+// access token for the client credentials
+func isTokenRejected(err error) bool {
+	return strings.Contains(err.Error(), "cannot access the token")
+}
+
+// isRateLimited reports whether the request failed after waiting out
+// Retry-After
+func isRateLimited(err error) bool {
+	_, ok := retry.RetryAfter(err)
+	return ok
+}
+
+// phaseErrorType returns the error type of an unclassified error in a phase
+func phaseErrorType(phase string) errors.ErrorType {
+	switch phase {
+	case phaseParse:
+		return errors.ErrorTypeFileProcessing
+	case phasePolicy:
+		return errors.ErrorTypePolicy
+	case phaseResolve:
+		return errors.ErrorTypeUserResolution
+	case phasePrepare:
+		return errors.ErrorTypeValidation
+	case phaseApply:
+		return errors.ErrorTypeNobl9API
+	default:
+		return errors.ErrorTypeNonRetryable
+	}
+}
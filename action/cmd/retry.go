@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+// retryCall calls fn, retrying its failures by the --retry-policies
+// profile of the operation class. The error of the last attempt is returned
+// unchanged, so it is classified and reported as if fn was called once,
+// unless the deadline cuts the retries short: that is a timeout error
+// naming the attempts made.
+func retryCall(ctx context.Context, operation, name string, fn func(ctx context.Context) error) error {
+	// validateConfig already checked the retry settings
+	profiles, _ := retry.ParseProfiles(config.RetryPolicies, config.RetryMaxAttempts, config.RetryBaseDelay)
+	policy := profiles.For(operation)
+	if policy.MaxAttempts <= 1 {
+		return fn(ctx)
+	}
+
+	err := retry.Do(ctx, policy, name, func(ctx context.Context) error {
+		if err := fn(ctx); err != nil {
+			return retryStatus{err}
+		}
+		return nil
+	}, func(attempt int, err error, delay time.Duration) {
+		fields := logrus.Fields{
+			"operation":    operation,
+			"call":         name,
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+			"delay":        delay.String(),
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			fields["remaining_budget"] = time.Until(deadline).Round(time.Millisecond).String()
+		}
+		logrus.WithFields(fields).WithError(stderrors.Unwrap(err)).Warn("Call failed, retrying")
+	})
+
+	if retry.BudgetExhausted(err) {
+		logrus.WithFields(logrus.Fields{
+			"operation":    operation,
+			"call":         name,
+			"max_attempts": policy.MaxAttempts,
+		}).WithError(err).Warn("Retry budget exhausted, giving up before the deadline")
+		return err
+	}
+	var status retryStatus
+	if stderrors.As(err, &status) {
+		return status.err
+	}
+	return err
+}
+
+// retryStatus is the error retry policies match their patterns against.
+// An HTTP error is matched by its status alone, since its message also
+// holds the URL and trace ID.
+type retryStatus struct {
+	err error
+}
+
+func (s retryStatus) Error() string {
+	var httpErr *sdk.HTTPError
+	if stderrors.As(s.err, &httpErr) {
+		return fmt.Sprintf("%d %s", httpErr.StatusCode, http.StatusText(httpErr.StatusCode))
+	}
+	return s.err.Error()
+}
+
+func (s retryStatus) Unwrap() error {
+	return s.err
+}
+
+// getOrganization reads the organization of the client's credentials,
+// retried by the organization policy
+func getOrganization(ctx context.Context, client nobl9.Nobl9API) (string, error) {
+	var organization string
+	err := retryCall(ctx, retry.OperationOrganization, "get organization", func(ctx context.Context) error {
+		var err error
+		organization, err = client.GetOrganization(ctx)
+		return err
+	})
+	return organization, err
+}
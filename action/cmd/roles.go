@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/roles"
+	"gopkg.in/yaml.v3"
+)
+
+// loadRoleCatalog loads the configured role catalog; it returns nil when
+// role checking is disabled
+func loadRoleCatalog() (*roles.Catalog, error) {
+	if config.RoleCatalog == "" {
+		return nil, nil
+	}
+
+	catalog, err := roles.Load(config.RoleCatalog)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"role_catalog":       config.RoleCatalog,
+		"project_roles":      len(catalog.Project),
+		"organization_roles": len(catalog.Organization),
+	}).Debug("Loaded role catalog")
+	return catalog, nil
+}
+
+// checkRoles returns the role bindings of each file whose role is not in the
+// catalog. Nobl9 has no API listing the roles of an organization, so roles
+// missing from the catalog are accepted when a live role binding already
+// grants them, as custom roles are; live role bindings are only read when
+// a role is not in the catalog.
+func checkRoles(ctx context.Context, api nobl9.Nobl9API, catalog *roles.Catalog, files []*parsedFile) (map[string][]roles.UnknownRole, error) {
+	unknown := make(map[string][]roles.UnknownRole)
+	for _, file := range files {
+		if fileUnknown := catalog.Check(file.Objects); len(fileUnknown) > 0 {
+			unknown[file.Path] = fileUnknown
+		}
+	}
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	live, err := api.ListRoleBindings(ctx, sdk.ProjectsWildcard)
+	if err != nil {
+		return nil, err
+	}
+	catalog.Learn(live)
+
+	for _, file := range files {
+		if _, ok := unknown[file.Path]; !ok {
+			continue
+		}
+		fileUnknown := catalog.Check(file.Objects)
+		if len(fileUnknown) == 0 {
+			delete(unknown, file.Path)
+			continue
+		}
+		unknown[file.Path] = fileUnknown
+	}
+	return unknown, nil
+}
+
+// logUnknownRole logs a role binding whose role is not known
+func logUnknownRole(file string, u roles.UnknownRole) {
+	logrus.WithFields(logrus.Fields{
+		"file":       file,
+		"name":       u.Name,
+		"role":       u.Role,
+		"suggestion": u.Suggestion,
+	}).Error("Unknown role: " + u.Error())
+}
+
+// roleBindingsOf returns the role bindings of a file with every user they
+// list. Decoding keeps only spec.user, so the users of the YAML documents
+// are read from content; role bindings not written in content, such as
+// those of CSV rows or Okta group members, are taken from the objects.
+func roleBindingsOf(content []byte, source string, objects []manifest.Object) []roles.Binding {
+	var bindings []roles.Binding
+	written := make(map[string]bool)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if !stderrors.Is(err, io.EOF) {
+				logrus.WithField("file", source).WithError(err).Debug("Failed to read role binding users")
+			}
+			break
+		}
+
+		documents, ok := doc.([]interface{})
+		if !ok {
+			documents = []interface{}{doc}
+		}
+		for _, document := range documents {
+			binding, ok := roleBindingDocument(document)
+			if !ok {
+				continue
+			}
+			binding.Source = source
+			written[binding.Name] = true
+			bindings = append(bindings, binding)
+		}
+	}
+
+	for _, obj := range objects {
+		rb, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || rb.Spec.User == nil || written[rb.Metadata.Name] {
+			continue
+		}
+		bindings = append(bindings, roles.Binding{
+			Source:  source,
+			Name:    rb.Metadata.Name,
+			Project: rb.Spec.ProjectRef,
+			Role:    rb.Spec.RoleRef,
+			Users:   []string{*rb.Spec.User},
+		})
+	}
+
+	return bindings
+}
+
+// roleBindingDocument reads a RoleBinding document with the users of its
+// spec.user, spec.users and spec.userIds fields
+func roleBindingDocument(document interface{}) (roles.Binding, bool) {
+	doc, ok := document.(map[string]interface{})
+	if !ok || doc["kind"] != "RoleBinding" {
+		return roles.Binding{}, false
+	}
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	spec, _ := doc["spec"].(map[string]interface{})
+
+	binding := roles.Binding{}
+	binding.Name, _ = metadata["name"].(string)
+	binding.Project, _ = spec["projectRef"].(string)
+	binding.Role, _ = spec["roleRef"].(string)
+
+	if user, ok := spec["user"].(string); ok {
+		binding.Users = append(binding.Users, strings.TrimSpace(user))
+	}
+	if users, ok := spec["users"].([]interface{}); ok {
+		for _, user := range users {
+			if entry, ok := user.(map[string]interface{}); ok {
+				user = entry["id"]
+			}
+			if id, ok := user.(string); ok {
+				binding.Users = append(binding.Users, strings.TrimSpace(id))
+			}
+		}
+	}
+	if ids, ok := spec["userIds"].(string); ok {
+		for _, id := range strings.Split(ids, ",") {
+			binding.Users = append(binding.Users, strings.TrimSpace(id))
+		}
+	}
+
+	// An unnamed role binding gets the name NameRoleBindings gives its object
+	if binding.Name == "" && config.GenerateRoleBindingNames {
+		if user, ok := spec["user"].(string); ok && user != "" {
+			binding.Name = nobl9client.RoleBindingName(binding.Project, binding.Role, user)
+		} else if group, ok := spec["groupRef"].(string); ok && group != "" {
+			binding.Name = nobl9client.RoleBindingName(binding.Project, binding.Role, "group:"+group)
+		}
+	}
+
+	return binding, true
+}
+
+// warnDuplicateGrants logs a warning for each user granted the same role
+// more than once, within a role binding or across the role bindings of a
+// project, and returns how many there are. identity maps a user as written
+// to the user it stands for.
+func warnDuplicateGrants(files []*parsedFile, identity func(user string) string) int {
+	var bindings []roles.Binding
+	for _, file := range files {
+		bindings = append(bindings, file.Bindings...)
+	}
+
+	duplicates := roles.FindDuplicates(bindings, identity)
+	for _, duplicate := range duplicates {
+		logrus.WithFields(logrus.Fields{
+			"file": duplicate.Binding.Source,
+			"name": duplicate.Binding.Name,
+			"role": duplicate.Role(),
+			"user": duplicate.User,
+		}).Warn("Duplicate role grant: " + duplicate.String())
+	}
+	return len(duplicates)
+}
+
+// runDuplicateValidation warns about users the files grant the same role
+// more than once. Emails are compared case-insensitively, since validate
+// does not resolve them.
+func runDuplicateValidation(filePaths []string) error {
+	var files []*parsedFile
+	for _, filePath := range filePaths {
+		parsed, err := parseRemoteFile(filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, parsed)
+	}
+
+	warnDuplicateGrants(files, func(user string) string {
+		return strings.ToLower(user)
+	})
+	return nil
+}
+
+// mergeDuplicateRoleBindings leaves out role bindings granting a user, or a
+// group, a role in a project that an earlier role binding already grants,
+// in the same file or an earlier one, so every grant is applied once. Users
+// are compared after email resolution. It returns how many role bindings
+// were left out.
+func mergeDuplicateRoleBindings(files []*preparedFile) int {
+	type grant struct{ project, role, user string }
+	type first struct{ source, name string }
+	granted := make(map[grant]first)
+
+	merged := 0
+	for _, file := range files {
+		kept := file.Objects[:0]
+		for _, obj := range file.Objects {
+			roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+			user := nobl9client.RoleBindingUser(roleBinding)
+			if !ok || user == "" {
+				kept = append(kept, obj)
+				continue
+			}
+
+			key := grant{project: roleBinding.Spec.ProjectRef, role: roleBinding.Spec.RoleRef, user: user}
+			existing, found := granted[key]
+			if !found {
+				granted[key] = first{source: file.Path, name: roleBinding.GetName()}
+				kept = append(kept, obj)
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"file":               file.Path,
+				"role_binding":       roleBinding.GetName(),
+				"first_file":         existing.source,
+				"first_role_binding": existing.name,
+				"role":               key.role,
+				"project":            key.project,
+				"user":               key.user,
+			}).Info("Merged duplicate role binding into the one that first grants the role")
+
+			// The same role binding declared twice in one file is one object
+			if existing.source != file.Path || existing.name != roleBinding.GetName() {
+				file.setStatusByName(manifest.KindRoleBinding.String(), roleBinding.GetName(), statusSkipped, nil)
+			}
+			file.Result.Kinds.Add(manifest.KindRoleBinding.String(), -1)
+			file.Result.RoleBindingsCreated--
+			file.Result.RoleBindingsMerged++
+			merged++
+		}
+		file.Objects = kept
+	}
+	return merged
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/rollback"
+)
+
+// rollbackTimeout bounds a rollback after a critical error; the run's own
+// deadline may already be used up
+const rollbackTimeout = 5 * time.Minute
+
+// Rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll back the objects a run applied",
+	Long: `Restore the objects a run applied to the definitions they had before the run, and delete the
+objects the run created, as recorded in the run's --results-file.
+
+Previous definitions are restored in dependency order and created objects are deleted in reverse
+dependency order, so projects are restored first and deleted last. Objects whose previous state was
+not recorded are left as they are and listed. Rolled back objects are marked rolled_back in the
+results file, so rolling back again does not repeat them.`,
+	Example: `  # Preview the rollback of a run
+  nobl9-action rollback --from nobl9-results.json --dry-run
+
+  # Roll the run back
+  nobl9-action rollback --from nobl9-results.json --auto-approve \
+    --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"`,
+	GroupID: groupUtility,
+	RunE:    runRollback,
+}
+
+// rollbackResult counts what a rollback did
+type rollbackResult struct {
+	RolledBack int
+	Failed     int
+	// Unknown counts applied objects whose previous state was not recorded
+	Unknown int
+}
+
+// runRollback rolls back the objects applied by the run of a results file
+func runRollback(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if config.RollbackFrom == "" {
+		return configError(fmt.Errorf("--from is required"))
+	}
+	if !config.DryRun && (config.ClientID == "" || config.ClientSecret == "") {
+		return configError(fmt.Errorf("rolling back requires --client-id and --client-secret, or --dry-run"))
+	}
+
+	results, err := readResults(config.RollbackFrom)
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to read results file", err)
+	}
+	for _, file := range results.Files {
+		if file.Organization != "" {
+			return configError(fmt.Errorf("%s records a run across several organizations, which cannot be rolled back with one set of credentials", config.RollbackFrom))
+		}
+	}
+	if results.DryRun {
+		logrus.WithField("path", config.RollbackFrom).Info("The run was a dry run, nothing to roll back")
+		return nil
+	}
+
+	plan, err := rollback.NewPlan(rollbackEntries(results))
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to plan rollback", err)
+	}
+	if plan.Steps() == 0 {
+		logUnknown(plan)
+		logrus.WithField("path", config.RollbackFrom).Info("Nothing to roll back")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	var api nobl9.Nobl9API
+	if !config.DryRun {
+		if len(plan.Delete) > 0 {
+			names := make([]string, 0, len(plan.Delete))
+			for _, target := range plan.Delete {
+				names = append(names, target.String())
+			}
+			if err := approveDeletion("object", names); err != nil {
+				return errors.NewPolicyError("rollback not approved", err)
+			}
+		}
+		client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+		if err != nil {
+			return typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+		}
+		api = nobl9API(client)
+	}
+
+	result := executeRollback(ctx, api, results, plan, config.DryRun)
+	if !config.DryRun {
+		if err := results.write(config.RollbackFrom); err != nil {
+			return typedError(errors.ErrorTypeFileProcessing, "failed to update results file", err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"rolled_back": result.RolledBack,
+		"failed":      result.Failed,
+		"unknown":     result.Unknown,
+		"dry_run":     config.DryRun,
+	}).Info("Rollback completed")
+	if result.Failed > 0 {
+		return errors.NewNobl9APIError(fmt.Sprintf("%d objects failed to roll back", result.Failed), nil)
+	}
+	return nil
+}
+
+// rollbackOnFailure rolls back the objects the run applied when
+// --rollback-on-failure is set and a critical error aborted the run
+func rollbackOnFailure(ctx context.Context, api nobl9.Nobl9API, summary *runSummary, results *runResults) {
+	if !config.RollbackOnFailure || summary.AbortedBy == nil || summary.DryRun {
+		return
+	}
+
+	plan, err := rollback.NewPlan(rollbackEntries(results))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to plan rollback, leaving the applied objects in place")
+		summary.Rollback = &rollbackResult{}
+		results.addError(phaseApply, fmt.Errorf("rollback: %w", err))
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"restore": len(plan.Restore),
+		"delete":  len(plan.Delete),
+	}).Warn("Rolling back the run after a critical error")
+
+	// The abort cancelled the run's context
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	summary.Rollback = executeRollback(ctx, api, results, plan, false)
+}
+
+// rollbackEntries lists the applied objects of the results to roll back
+func rollbackEntries(results *runResults) []rollback.Entry {
+	var entries []rollback.Entry
+	for _, file := range results.Files {
+		for _, object := range file.Objects {
+			if object.Status != statusApplied {
+				continue
+			}
+			entries = append(entries, rollback.Entry{
+				Kind:    object.Kind,
+				Project: object.Project,
+				Name:    object.Name,
+				Source:  file.Path,
+				Record:  object.Rollback,
+			})
+		}
+	}
+	return entries
+}
+
+// executeRollback restores the previous definitions and deletes the created
+// objects of the plan one at a time, so one failure does not stop the rest,
+// and marks the objects rolled back in the results
+func executeRollback(ctx context.Context, api nobl9.Nobl9API, results *runResults, plan *rollback.Plan, dryRun bool) *rollbackResult {
+	result := &rollbackResult{Unknown: len(plan.Unknown)}
+	logUnknown(plan)
+
+	step := func(target rollback.Target, action string, fn func() error) {
+		log := logrus.WithFields(logrus.Fields{"object": target.String(), "file": target.Source})
+		if dryRun {
+			log.Info("DRY RUN: Would " + action)
+			result.RolledBack++
+			return
+		}
+		if err := fn(); err != nil {
+			log.WithError(err).Error("Failed to " + action)
+			result.Failed++
+			return
+		}
+		log.Warn("Rolled back: " + action)
+		results.setRolledBack(target)
+		result.RolledBack++
+	}
+
+	for _, restore := range plan.Restore {
+		step(restore.Target, "restore the previous definition", func() error {
+			return api.ApplyObjects(ctx, []manifest.Object{restore.Object})
+		})
+	}
+	for _, target := range plan.Delete {
+		step(target, "delete the object the run created", func() error {
+			return api.DeleteObject(ctx, target.Kind, target.Project, target.Name)
+		})
+	}
+	return result
+}
+
+// logUnknown warns about applied objects that cannot be rolled back
+func logUnknown(plan *rollback.Plan) {
+	for _, target := range plan.Unknown {
+		logrus.WithFields(logrus.Fields{
+			"object": target.String(),
+			"file":   target.Source,
+		}).Warn("Previous state of the object was not recorded, it cannot be rolled back")
+	}
+}
+
+// setRolledBack marks an object of the results rolled back
+func (r *runResults) setRolledBack(target rollback.Target) {
+	for i := range r.Files {
+		if r.Files[i].Path != target.Source {
+			continue
+		}
+		for j := range r.Files[i].Objects {
+			object := &r.Files[i].Objects[j]
+			if object.Kind == target.Kind.String() && object.Name == target.Name && object.Status == statusApplied {
+				object.Status = statusRolledBack
+				return
+			}
+		}
+	}
+}
+
+// readResults reads a results file written by --results-file
+func readResults(path string) (*runResults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results runResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if results.SchemaVersion != resultsSchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, expected %d", path, results.SchemaVersion, resultsSchemaVersion)
+	}
+	return &results, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/scaffold"
+)
+
+// Init command - scaffold the manifests of a new project
+var initCmd = &cobra.Command{
+	Use:     "init <project>",
+	Aliases: []string{"scaffold"},
+	Short:   "Write example manifests for a new project",
+	Long: `Write example Project, RoleBinding, Service and SLO manifests for a new project to
+<output-dir>/<project>/, one file per kind, so a team can bootstrap its directory without copying
+the docs.
+
+The manifests follow the repository's conventions: a project-owner role binding per --owners
+email, named after the project and the email, an ActionMeta document and team label for --team,
+and the canonical form of the fmt command. They validate as they are; the fields the team has to
+fill in, such as the agent and queries of the example SLO, are marked with TODO comments.
+Existing files with other content are only replaced with --overwrite.`,
+	Example: `  # Scaffold the payments project with its owners
+  nobl9-action init payments --owners alice@example.com,bob@example.com --team payments \
+    --output-dir nobl9
+
+  # Only write the project and its role bindings
+  nobl9-action scaffold checkout --kinds project,rolebinding --output-dir nobl9`,
+	Args:    cobra.ExactArgs(1),
+	GroupID: groupUtility,
+	RunE:    runInit,
+}
+
+// runInit writes the scaffolded manifests of a project
+func runInit(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	kinds, err := scaffold.ParseKinds(config.Kinds)
+	if err != nil {
+		return configError(fmt.Errorf("invalid kinds: %w", err))
+	}
+
+	files, err := scaffold.Render(scaffold.Project{
+		Name:        args[0],
+		DisplayName: config.ScaffoldDisplayName,
+		Description: config.ScaffoldDescription,
+		Owners:      commalist.Split(config.ScaffoldOwners),
+		Service:     config.ScaffoldService,
+		Team:        config.ScaffoldTeam,
+	}, kinds)
+	if err != nil {
+		return configError(err)
+	}
+
+	// Check every file before writing any, so a refused file does not leave
+	// a partial scaffold behind
+	dir := filepath.Join(config.ScaffoldOutputDir, args[0])
+	var pending []*scaffold.File
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		existing, err := os.ReadFile(path)
+		switch {
+		case err == nil && bytes.Equal(existing, file.Content):
+			logrus.WithField("path", path).Info("Manifest unchanged")
+			continue
+		case err == nil && !config.Overwrite:
+			return configError(fmt.Errorf("%s already exists with other content, pass --overwrite to replace it", path))
+		case err != nil && !os.IsNotExist(err):
+			return typedError(errors.ErrorTypeFileProcessing, "failed to read file", err)
+		}
+		pending = append(pending, file)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to create directory", err)
+	}
+	out := cmd.OutOrStdout()
+	for _, file := range pending {
+		path := filepath.Join(dir, file.Name)
+		if err := os.WriteFile(path, file.Content, 0o644); err != nil {
+			return typedError(errors.ErrorTypeFileProcessing, "failed to write file", err)
+		}
+		fmt.Fprintln(out, path)
+		logrus.WithFields(logrus.Fields{
+			"path":    path,
+			"kind":    file.Kind,
+			"objects": len(file.Objects),
+		}).Info("Wrote manifest")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"project": args[0],
+		"dir":     dir,
+		"written": len(pending),
+	}).Info("Scaffold completed; fill in the TODO comments and run validate")
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/roles"
+)
+
+// outOfScopeReason is the skip reason of files declaring no objects of the
+// projects selected with --project
+const outOfScopeReason = "declares no objects of the selected projects"
+
+// scopedProjects returns the project names or glob patterns selected with
+// --project, or nil when the run covers every project
+func scopedProjects() ([]string, error) {
+	var patterns []string
+	for _, value := range config.Projects {
+		for _, pattern := range commalist.Split(value) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid project pattern %q: %w", pattern, err)
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// inScope reports whether an object belongs to one of the projects. Projects
+// belong to themselves and organization role bindings to no project.
+func inScope(obj manifest.Object, patterns []string) bool {
+	project := planner.ProjectOf(obj)
+	if project == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, project); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeToProjects keeps the objects of the selected projects, before emails
+// are resolved so users of other projects are not looked up. Files left
+// without objects are skipped.
+func scopeToProjects(files []*parsedFile, patterns []string, summary *runSummary, results *runResults) []*parsedFile {
+	if len(patterns) == 0 {
+		return files
+	}
+
+	kept := files[:0]
+	leftOut := 0
+	for _, file := range files {
+		objects := make([]manifest.Object, 0, len(file.Objects))
+		for _, obj := range file.Objects {
+			if inScope(obj, patterns) {
+				objects = append(objects, obj)
+			}
+		}
+		leftOut += len(file.Objects) - len(objects)
+		if len(objects) == 0 {
+			logrus.WithField("file", file.Path).Info("File declares no objects of the selected projects, skipping")
+			summary.FilesOutOfScope++
+			results.addSkippedFile(file.Path, outOfScopeReason)
+			continue
+		}
+
+		if len(objects) < len(file.Objects) {
+			file.Objects = objects
+			file.Emails = appendRoleBindingEmails(nil, objects)
+			file.Bindings = scopedBindings(file.Bindings, patterns)
+		}
+		kept = append(kept, file)
+	}
+
+	summary.Projects = patterns
+	logrus.WithFields(logrus.Fields{
+		"projects":           strings.Join(patterns, ","),
+		"objects_left_out":   leftOut,
+		"files_out_of_scope": summary.FilesOutOfScope,
+	}).Info("Restricted the run to the selected projects")
+	return kept
+}
+
+// scopedBindings returns the role bindings of the selected projects
+func scopedBindings(bindings []roles.Binding, patterns []string) []roles.Binding {
+	var kept []roles.Binding
+	for _, binding := range bindings {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, binding.Project); matched && binding.Project != "" {
+				kept = append(kept, binding)
+				break
+			}
+		}
+	}
+	return kept
+}
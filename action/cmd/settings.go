@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// runSettings returns the key settings of this run. They are recorded in the
+// state file and compared between runs, since a setting changed by accident
+// (a narrower file pattern, pruning switched on, another organization's
+// credentials) can cause surprising applies.
+func runSettings(ctx context.Context, client nobl9.Nobl9API) map[string]string {
+	settings := map[string]string{
+		"repo_path":        config.RepoPath,
+		"file_pattern":     config.FilePattern,
+		"kinds":            config.Kinds,
+		"prune":            strconv.FormatBool(config.Prune),
+		"delete_grace":     config.DeleteGrace,
+		"allowed_branches": config.AllowedBranches,
+	}
+
+	organization, err := getOrganization(ctx, client)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read the target organization, it is not compared between runs")
+	} else {
+		settings["organization"] = organization
+	}
+
+	return settings
+}
+
+// checkSettingsDrift warns about key settings that changed since the run
+// that last saved the state
+func checkSettingsDrift(settings map[string]string) []state.SettingChange {
+	st, err := state.Load(config.StateFile)
+	if err != nil {
+		logrus.WithField("path", config.StateFile).WithError(err).Warn("Failed to load state, not comparing settings with the last run")
+		return nil
+	}
+
+	changes := st.CompareSettings(settings)
+	for _, change := range changes {
+		logrus.WithFields(logrus.Fields{
+			"setting":  change.Name,
+			"previous": change.Previous,
+			"current":  change.Current,
+		}).Warn("Setting changed since the last run")
+	}
+
+	return changes
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// applyGracePeriod is how long apply calls in flight when the run is
+// interrupted may take to finish. A cancelled workflow run is sent SIGINT,
+// then SIGTERM 7.5 seconds later and killed 2.5 seconds after that.
+const applyGracePeriod = 5 * time.Second
+
+// forceStop is closed by the second signal, which aborts the apply calls
+// still in flight; it is nil until handleSignals is called
+var forceStop chan struct{}
+
+// handleSignals returns a context cancelled with a cancelled error by the
+// first SIGINT or SIGTERM, so that the command stops scheduling work, lets
+// apply calls in flight finish and reports what it did. A second signal
+// aborts the apply calls in flight, and a third exits at once. The returned
+// function stops handling signals.
+func handleSignals(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := make(chan struct{})
+	forceStop = make(chan struct{})
+
+	signals := make(chan os.Signal, 3)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := 0
+		for {
+			select {
+			case sig := <-signals:
+				received++
+				switch received {
+				case 1:
+					logrus.WithField("signal", sig.String()).Warn("Run interrupted, finishing apply calls in flight and writing the results so far")
+					cancel(errors.NewCancelledError(fmt.Sprintf("run cancelled by %s", sig), nil))
+				case 2:
+					logrus.WithField("signal", sig.String()).Warn("Interrupted again, aborting apply calls in flight")
+					close(forceStop)
+				default:
+					logrus.WithField("signal", sig.String()).Error("Interrupted again, exiting")
+					flushGitHubOutputs()
+					os.Exit(errors.ExitCodes[errors.ErrorTypeCancelled])
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(stop)
+		cancel(nil)
+	}
+}
+
+// commandContext returns the context of a command, cancelled by signals
+// when the command was started by main
+func commandContext(cmd *cobra.Command) context.Context {
+	if cmd != nil && cmd.Context() != nil {
+		return cmd.Context()
+	}
+	return context.Background()
+}
+
+// isCancelled reports whether err is the cancelled error of a signal,
+// rather than a critical error or a timeout
+func isCancelled(err error) bool {
+	var nobl9Err *errors.Nobl9Error
+	return stderrors.As(err, &nobl9Err) && nobl9Err.Type == errors.ErrorTypeCancelled
+}
+
+// withApplyGrace returns the context of an apply call. When a signal
+// cancels ctx, the call may still finish within applyGracePeriod, unless a
+// second signal arrives, so an interrupted run does not leave the objects
+// of a call half applied; any other cancellation of ctx ends the call at
+// once. The returned function releases the context.
+func withApplyGrace(ctx context.Context) (context.Context, func()) {
+	graceCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	force := forceStop
+	stopAfter := context.AfterFunc(ctx, func() {
+		if isCancelled(context.Cause(ctx)) {
+			timer := time.NewTimer(applyGracePeriod)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-force:
+			case <-graceCtx.Done():
+			}
+		}
+		cancel(context.Cause(ctx))
+	})
+
+	return graceCtx, func() {
+		stopAfter()
+		cancel(context.Canceled)
+	}
+}
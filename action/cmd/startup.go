@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/generate"
+	"github.com/your-org/nobl9-action/pkg/policy"
+	"github.com/your-org/nobl9-action/pkg/roles"
+)
+
+// preRun runs before every command: it checks the policy, role catalog and
+// templates built into the binary, then applies repository and organization
+// variables
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := checkEmbedded(); err != nil {
+		return configError(err)
+	}
+	return applyVariables(cmd, args)
+}
+
+// checkEmbedded validates the default policy, role catalog and templates
+// embedded in the binary. They are used deep inside a run, so a broken one
+// fails every command at startup instead of after hundreds of files.
+func checkEmbedded() error {
+	if err := policy.ValidateDefault(); err != nil {
+		return fmt.Errorf("embedded policy: %w", err)
+	}
+	if err := roles.ValidateDefault(); err != nil {
+		return fmt.Errorf("embedded role catalog: %w", err)
+	}
+	if _, err := generate.LoadTemplates(""); err != nil {
+		return fmt.Errorf("embedded templates: %w", err)
+	}
+	return nil
+}
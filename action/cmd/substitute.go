@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/your-org/nobl9-action/pkg/parser"
+)
+
+// manifestVariables returns the variables substituted into manifests: the
+// --vars pairs, the pairs of NOBL9_VARS, then environment variables, and
+// the values of the --values files
+func manifestVariables() (*parser.Variables, error) {
+	vars, err := parseManifestVars()
+	if err != nil {
+		return nil, err
+	}
+	values, err := loadValues()
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewVariables(vars, os.LookupEnv).WithValues(values), nil
+}
+
+// loadValues reads the --values files and merges them in order
+func loadValues() (map[string]interface{}, error) {
+	files := make([]map[string]interface{}, 0, len(config.Values))
+	for _, path := range config.Values {
+		// The action turns newlines into commas, which may leave empty entries
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+		values, err := parser.ParseValues(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid values file %s: %w", path, err)
+		}
+		files = append(files, values)
+	}
+	return parser.MergeValues(files...), nil
+}
+
+// parseManifestVars parses the pairs of NOBL9_VARS and --vars; --vars
+// replace variables of the same name
+func parseManifestVars() (map[string]string, error) {
+	return parser.ParseVars(append([]string{os.Getenv(parser.VarsEnv)}, config.Vars...))
+}
+
+// substituteVariables replaces the variable references of a YAML file
+func substituteVariables(content []byte) ([]byte, error) {
+	variables, err := manifestVariables()
+	if err != nil {
+		return nil, err
+	}
+	return variables.Substitute(content)
+}
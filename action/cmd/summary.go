@@ -0,0 +1,486 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/impact"
+	"github.com/your-org/nobl9-action/pkg/lint"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9client"
+	"github.com/your-org/nobl9-action/pkg/recommend"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// runSummary collects the totals reported at the end of a process run
+type runSummary struct {
+	TotalFiles            int
+	FilesProcessed        int
+	FilesWithErrors       int
+	FilesSkipped          int
+	FilesAborted          int
+	ProjectsCreated       int
+	RoleBindingsCreated   int
+	RoleBindingsUnchanged int
+	RoleBindingsMerged    int
+	EmailsResolved        int
+	ObjectsByKind         nobl9client.KindCounts
+	Skipped               nobl9client.SkippedObjects
+	DryRun                bool
+	StateErrors           int
+	// ServerDryRun is set when Nobl9 checked the objects of a dry run
+	ServerDryRun bool
+	// EmailsLookedUp counts the distinct emails resolved across files, of
+	// which EmailsUnresolved did not resolve to a user
+	EmailsLookedUp   int
+	EmailsUnresolved int
+	// UnresolvedEmails are the emails that did not resolve, in the order
+	// they were looked up
+	UnresolvedEmails []string
+	// PlanHash identifies the objects the run applied, or would apply
+	PlanHash string
+	// AbortedBy is the critical error that stopped the run before
+	// FilesAborted files were processed, or nil
+	AbortedBy error
+
+	// FilesResumed counts files not applied again because the run resumed
+	// by --resume applied them
+	FilesResumed int
+
+	// Projects are the projects the run was restricted to with --project,
+	// of which FilesOutOfScope files declare no objects
+	Projects        []string
+	FilesOutOfScope int
+
+	// ObjectsUnchanged counts objects that were not applied again because
+	// the state file or their live definition showed they would not change
+	ObjectsUnchanged int
+	// Renames are recorded objects declared again under another name
+	Renames []state.Rename
+	// Prune reports what pruning did, or nil when pruning did not run
+	Prune *pruneResult
+	// Rollback reports what rolling back an aborted run did, or nil when
+	// the run was not rolled back
+	Rollback *rollbackResult
+	// SettingChanges are key settings that differ from the last recorded run
+	SettingChanges []state.SettingChange
+	// UserCache holds the resolver cache statistics (hits, misses, hit_rate, ...)
+	UserCache map[string]interface{}
+	// APICalls counts Nobl9 API calls by "METHOD /path"
+	APICalls map[string]int
+	// RateLimited counts 429 responses waited out using Retry-After
+	RateLimited     int
+	RateLimitWaited time.Duration
+	// CallTimeouts counts API calls that hit their own deadline
+	CallTimeouts int
+	// Breaker reports the circuit breaker state at the end of the run
+	Breaker retry.BreakerStats
+	// APICache counts the GET requests answered from the response cache
+	APICache nobl9.CacheStats
+	// Recommendations are actions suggested by the run's statistics
+	Recommendations []recommend.Recommendation
+	// HighImpact are SLO objectives whose error budget the run shrinks
+	// enough that they may breach once applied
+	HighImpact []impact.Change
+	// LintWarnings are likely mistakes in the SLOs of the run
+	LintWarnings []lint.Finding
+	// Organizations are the summaries of the organizations of a run applying
+	// files to several organizations, in the order they were processed
+	Organizations []organizationRun
+}
+
+// organizationRun is the part of a run applied to one organization
+type organizationRun struct {
+	Name    string
+	Summary *runSummary
+}
+
+// newRunSummary creates an empty summary for the given number of files
+func newRunSummary(totalFiles int, dryRun bool) *runSummary {
+	return &runSummary{
+		TotalFiles:    totalFiles,
+		ObjectsByKind: make(nobl9client.KindCounts),
+		Skipped:       make(nobl9client.SkippedObjects),
+		DryRun:        dryRun,
+		UserCache:     map[string]interface{}{},
+		APICalls:      map[string]int{},
+		Breaker:       retry.BreakerStats{State: retry.BreakerClosed},
+	}
+}
+
+// add accumulates the result of a successfully processed file
+func (s *runSummary) add(result *ProcessResult) {
+	s.FilesProcessed++
+	s.ProjectsCreated += result.ProjectsCreated
+	s.RoleBindingsCreated += result.RoleBindingsCreated
+	s.RoleBindingsUnchanged += result.RoleBindingsUnchanged
+	s.RoleBindingsMerged += result.RoleBindingsMerged
+	s.EmailsResolved += result.EmailsResolved
+	s.ObjectsByKind.Merge(result.Kinds)
+	s.Skipped.Merge(result.Skipped)
+	s.ObjectsUnchanged += result.ObjectsUnchanged
+}
+
+// merge adds the totals of the run of another organization. The first
+// critical error that aborted an organization is kept.
+func (s *runSummary) merge(other *runSummary) {
+	s.FilesProcessed += other.FilesProcessed
+	s.FilesWithErrors += other.FilesWithErrors
+	s.FilesSkipped += other.FilesSkipped
+	s.FilesAborted += other.FilesAborted
+	s.FilesResumed += other.FilesResumed
+	s.FilesOutOfScope += other.FilesOutOfScope
+	if s.Projects == nil {
+		s.Projects = other.Projects
+	}
+	s.ProjectsCreated += other.ProjectsCreated
+	s.RoleBindingsCreated += other.RoleBindingsCreated
+	s.RoleBindingsUnchanged += other.RoleBindingsUnchanged
+	s.RoleBindingsMerged += other.RoleBindingsMerged
+	s.EmailsResolved += other.EmailsResolved
+	s.ObjectsByKind.Merge(other.ObjectsByKind)
+	s.Skipped.Merge(other.Skipped)
+	s.StateErrors += other.StateErrors
+	s.EmailsLookedUp += other.EmailsLookedUp
+	s.EmailsUnresolved += other.EmailsUnresolved
+	s.UnresolvedEmails = append(s.UnresolvedEmails, other.UnresolvedEmails...)
+	if s.AbortedBy == nil {
+		s.AbortedBy = other.AbortedBy
+	}
+
+	if other.Prune != nil {
+		if s.Prune == nil {
+			s.Prune = &pruneResult{}
+		}
+		s.Prune.Marked += other.Prune.Marked
+		s.Prune.Pending += other.Prune.Pending
+		s.Prune.Deleted += other.Prune.Deleted
+		s.Prune.Restored += other.Prune.Restored
+		s.Prune.Released += other.Prune.Released
+		s.Prune.ObjectsMarked += other.Prune.ObjectsMarked
+		s.Prune.ObjectsPending += other.Prune.ObjectsPending
+		s.Prune.ObjectsDeleted += other.Prune.ObjectsDeleted
+	}
+	s.ObjectsUnchanged += other.ObjectsUnchanged
+	s.Renames = append(s.Renames, other.Renames...)
+	if other.Rollback != nil {
+		if s.Rollback == nil {
+			s.Rollback = &rollbackResult{}
+		}
+		s.Rollback.RolledBack += other.Rollback.RolledBack
+		s.Rollback.Failed += other.Rollback.Failed
+		s.Rollback.Unknown += other.Rollback.Unknown
+	}
+	s.SettingChanges = append(s.SettingChanges, other.SettingChanges...)
+	s.HighImpact = append(s.HighImpact, other.HighImpact...)
+	s.LintWarnings = append(s.LintWarnings, other.LintWarnings...)
+
+	for _, stat := range []string{"size", "hits", "misses", "evictions", "expirations"} {
+		if value, ok := other.UserCache[stat].(int); ok {
+			current, _ := s.UserCache[stat].(int)
+			s.UserCache[stat] = current + value
+		}
+	}
+	hits, _ := s.UserCache["hits"].(int)
+	misses, _ := s.UserCache["misses"].(int)
+	if hits+misses > 0 {
+		s.UserCache["hit_rate"] = float64(hits) / float64(hits+misses)
+	}
+
+	for endpoint, count := range other.APICalls {
+		s.APICalls[endpoint] += count
+	}
+	s.RateLimited += other.RateLimited
+	s.RateLimitWaited += other.RateLimitWaited
+	s.CallTimeouts += other.CallTimeouts
+	s.APICache.Hits += other.APICache.Hits
+	s.APICache.Revalidated += other.APICache.Revalidated
+	s.APICache.Misses += other.APICache.Misses
+	s.Breaker.Trips += other.Breaker.Trips
+	s.Breaker.Rejected += other.Breaker.Rejected
+	if other.Breaker.State != retry.BreakerClosed {
+		s.Breaker.State = other.Breaker.State
+	}
+}
+
+// apiCallTotal returns the total number of Nobl9 API calls
+func (s *runSummary) apiCallTotal() int {
+	total := 0
+	for _, count := range s.APICalls {
+		total += count
+	}
+	return total
+}
+
+// projectsPendingDelete returns the number of projects marked for deletion
+// that have not been deleted yet
+func (s *runSummary) projectsPendingDelete() int {
+	if s.Prune == nil {
+		return 0
+	}
+	return s.Prune.Marked + s.Prune.Pending
+}
+
+// projectsDeleted returns the number of projects deleted by pruning
+func (s *runSummary) projectsDeleted() int {
+	if s.Prune == nil {
+		return 0
+	}
+	return s.Prune.Deleted
+}
+
+// objectsRolledBack returns the number of objects restored or deleted by
+// rolling back an aborted run
+func (s *runSummary) objectsRolledBack() int {
+	if s.Rollback == nil {
+		return 0
+	}
+	return s.Rollback.RolledBack
+}
+
+// stats returns the summary as fields for LogProcessingComplete
+func (s *runSummary) stats() map[string]interface{} {
+	return map[string]interface{}{
+		"total_files":             s.TotalFiles,
+		"files_processed":         s.FilesProcessed,
+		"files_with_errors":       s.FilesWithErrors,
+		"files_skipped":           s.FilesSkipped,
+		"files_aborted":           s.FilesAborted,
+		"files_resumed":           s.FilesResumed,
+		"files_out_of_scope":      s.FilesOutOfScope,
+		"projects_created":        s.ProjectsCreated,
+		"role_bindings_created":   s.RoleBindingsCreated,
+		"role_bindings_unchanged": s.RoleBindingsUnchanged,
+		"role_bindings_merged":    s.RoleBindingsMerged,
+		"emails_resolved":         s.EmailsResolved,
+		"emails_unresolved":       s.EmailsUnresolved,
+		"objects_by_kind":         s.ObjectsByKind,
+		"objects_skipped":         s.Skipped.Total(),
+		"dry_run":                 s.DryRun,
+		"server_dry_run":          s.ServerDryRun,
+		"state_errors":            s.StateErrors,
+		"plan_hash":               s.PlanHash,
+		"aborted_early":           s.AbortedBy != nil,
+		"projects_pending_delete": s.projectsPendingDelete(),
+		"projects_deleted":        s.projectsDeleted(),
+		"objects_unchanged":       s.ObjectsUnchanged,
+		"objects_renamed":         len(s.Renames),
+		"objects_rolled_back":     s.objectsRolledBack(),
+		"settings_changed":        len(s.SettingChanges),
+		"user_cache":              s.UserCache,
+		"api_calls":               s.APICalls,
+		"api_calls_total":         s.apiCallTotal(),
+		"rate_limited":            s.RateLimited,
+		"rate_limit_waited":       s.RateLimitWaited.String(),
+		"call_timeouts":           s.CallTimeouts,
+		"api_cache_hits":          s.APICache.Hits + s.APICache.Revalidated,
+		"recommendations":         len(s.Recommendations),
+		"organizations":           len(s.Organizations),
+		"high_impact_changes":     len(s.HighImpact),
+		"lint_warnings":           len(s.LintWarnings),
+		"circuit_breaker": map[string]interface{}{
+			"state":    s.Breaker.State,
+			"trips":    s.Breaker.Trips,
+			"rejected": s.Breaker.Rejected,
+		},
+	}
+}
+
+// markdown renders the summary for the GitHub job summary
+func (s *runSummary) markdown() string {
+	var b strings.Builder
+
+	title := "Nobl9 Processing Summary"
+	switch {
+	case s.ServerDryRun:
+		title += " (dry run checked by Nobl9)"
+	case s.DryRun:
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&b, "## %s\n\n", title)
+
+	switch {
+	case isCancelled(s.AbortedBy):
+		fmt.Fprintf(&b, "**Cancelled:** %s; the results cover the work done before\n\n", s.AbortedBy)
+	case s.AbortedBy != nil:
+		fmt.Fprintf(&b, "**Aborted early after critical error:** %s\n\n", s.AbortedBy)
+	}
+	if len(s.Projects) > 0 {
+		fmt.Fprintf(&b, "**Restricted to projects:** `%s`; objects of other projects were left out\n\n", strings.Join(s.Projects, "`, `"))
+	}
+
+	b.WriteString("| Metric | Value |\n|--------|-------|\n")
+	fmt.Fprintf(&b, "| Files processed | %d of %d |\n", s.FilesProcessed, s.TotalFiles)
+	fmt.Fprintf(&b, "| Files with errors | %d |\n", s.FilesWithErrors)
+	if s.FilesSkipped > 0 {
+		fmt.Fprintf(&b, "| Files for other organizations | %d |\n", s.FilesSkipped)
+	}
+	if s.FilesAborted > 0 {
+		fmt.Fprintf(&b, "| Files not processed after abort | %d |\n", s.FilesAborted)
+	}
+	if s.FilesOutOfScope > 0 {
+		fmt.Fprintf(&b, "| Files outside the selected projects | %d |\n", s.FilesOutOfScope)
+	}
+	if s.FilesResumed > 0 {
+		fmt.Fprintf(&b, "| Files applied by the resumed run | %d |\n", s.FilesResumed)
+	}
+	fmt.Fprintf(&b, "| Projects | %d |\n", s.ProjectsCreated)
+	fmt.Fprintf(&b, "| Role bindings | %d |\n", s.RoleBindingsCreated)
+	fmt.Fprintf(&b, "| Role bindings unchanged | %d |\n", s.RoleBindingsUnchanged)
+	if s.RoleBindingsMerged > 0 {
+		fmt.Fprintf(&b, "| Duplicate role bindings merged | %d |\n", s.RoleBindingsMerged)
+	}
+	fmt.Fprintf(&b, "| Emails resolved | %d |\n", s.EmailsResolved)
+	fmt.Fprintf(&b, "| Objects skipped | %d |\n", s.Skipped.Total())
+	if s.ObjectsUnchanged > 0 {
+		fmt.Fprintf(&b, "| Objects unchanged, not applied | %d |\n", s.ObjectsUnchanged)
+	}
+	if s.PlanHash != "" {
+		fmt.Fprintf(&b, "| Plan hash | `%s` |\n", s.PlanHash)
+	}
+	if s.Prune != nil {
+		fmt.Fprintf(&b, "| Projects pending deletion | %d |\n", s.projectsPendingDelete())
+		fmt.Fprintf(&b, "| Projects deleted | %d |\n", s.projectsDeleted())
+		if s.Prune.ObjectsMarked+s.Prune.ObjectsPending+s.Prune.ObjectsDeleted > 0 {
+			fmt.Fprintf(&b, "| Objects pending deletion | %d |\n", s.Prune.ObjectsMarked+s.Prune.ObjectsPending)
+			fmt.Fprintf(&b, "| Objects deleted | %d |\n", s.Prune.ObjectsDeleted)
+		}
+	}
+	if s.Rollback != nil {
+		fmt.Fprintf(&b, "| Objects rolled back | %d |\n", s.Rollback.RolledBack)
+		fmt.Fprintf(&b, "| Objects that failed to roll back | %d |\n", s.Rollback.Failed)
+		if s.Rollback.Unknown > 0 {
+			fmt.Fprintf(&b, "| Objects without a recorded previous state | %d |\n", s.Rollback.Unknown)
+		}
+	}
+
+	if len(s.HighImpact) > 0 {
+		b.WriteString("\n### High Impact Changes\n\nThese SLO changes shrink the error budget enough that the SLO may breach as soon as they are applied.\n\n")
+		b.WriteString("| SLO | Objective | Target | Error budget | File |\n|-----|-----------|--------|--------------|------|\n")
+		for _, change := range s.HighImpact {
+			fmt.Fprintf(&b, "| %s/%s | %s | %s → %s | -%.0f%% | %s |\n", change.Object.Project, change.Object.Name, change.Objective,
+				impact.Percent(change.PreviousTarget), impact.Percent(change.Target), change.BudgetShrink, change.Source)
+		}
+	}
+
+	if len(s.Renames) > 0 {
+		b.WriteString("\n### Renamed Objects\n\nThese objects are declared under a new name with unchanged content. The old objects stay in Nobl9 until they are pruned.\n\n")
+		b.WriteString("| Previous | Renamed to | File |\n|----------|------------|------|\n")
+		for _, rename := range s.Renames {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", rename.From.Key(), rename.To.Key(), rename.To.Source)
+		}
+	}
+
+	if len(s.LintWarnings) > 0 {
+		b.WriteString("\n### SLO Lint Warnings\n\nThese SLOs are valid and are applied, but look like mistakes.\n\n")
+		b.WriteString("| SLO | Rule | Warning | File |\n|-----|------|---------|------|\n")
+		for _, finding := range s.LintWarnings {
+			fmt.Fprintf(&b, "| %s/%s | `%s` | %s | %s |\n", finding.Project, finding.Name, finding.Rule,
+				strings.ReplaceAll(finding.Message, "|", "\\|"), finding.Source)
+		}
+	}
+
+	if len(s.Organizations) > 0 {
+		b.WriteString("\n### Organizations\n\n| Organization | Files | Processed | Errors | Skipped | Projects | Role bindings |\n|--------------|-------|-----------|--------|---------|----------|---------------|\n")
+		for _, org := range s.Organizations {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n", org.Name, org.Summary.TotalFiles,
+				org.Summary.FilesProcessed, org.Summary.FilesWithErrors, org.Summary.FilesSkipped,
+				org.Summary.ProjectsCreated, org.Summary.RoleBindingsCreated)
+		}
+	}
+
+	if len(s.Recommendations) > 0 {
+		b.WriteString("\n### Recommendations\n\n")
+		for _, recommendation := range s.Recommendations {
+			fmt.Fprintf(&b, "- %s\n", recommendation.Message)
+		}
+	}
+
+	if len(s.SettingChanges) > 0 {
+		b.WriteString("\n### Settings Changed Since Last Run\n\n| Setting | Previous | Current |\n|---------|----------|---------|\n")
+		for _, change := range s.SettingChanges {
+			fmt.Fprintf(&b, "| %s | `%s` | `%s` |\n", change.Name, change.Previous, change.Current)
+		}
+	}
+
+	if len(s.ObjectsByKind) > 0 {
+		kinds := make([]string, 0, len(s.ObjectsByKind))
+		for kind := range s.ObjectsByKind {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		b.WriteString("\n### Objects by Kind\n\n| Kind | Objects |\n|------|---------|\n")
+		for _, kind := range kinds {
+			fmt.Fprintf(&b, "| %s | %d |\n", kind, s.ObjectsByKind[kind])
+		}
+	}
+
+	b.WriteString("\n### User Cache\n\n")
+	b.WriteString("| Hits | Misses | Hit rate | Entries |\n|------|--------|----------|---------|\n")
+	hitRate, _ := s.UserCache["hit_rate"].(float64)
+	fmt.Fprintf(&b, "| %v | %v | %.0f%% | %v |\n",
+		valueOrZero(s.UserCache["hits"]), valueOrZero(s.UserCache["misses"]), hitRate*100, valueOrZero(s.UserCache["size"]))
+
+	fmt.Fprintf(&b, "\n### API Calls (%d)\n\n", s.apiCallTotal())
+	if s.Breaker.Trips > 0 {
+		fmt.Fprintf(&b, "Circuit breaker opened %d times and failed %d calls fast; it ended %s.\n\n",
+			s.Breaker.Trips, s.Breaker.Rejected, strings.ReplaceAll(string(s.Breaker.State), "_", " "))
+	}
+	if s.RateLimited > 0 {
+		fmt.Fprintf(&b, "Rate limited %d times, waited %s for Retry-After.\n\n", s.RateLimited, s.RateLimitWaited)
+	}
+	if s.CallTimeouts > 0 {
+		fmt.Fprintf(&b, "%d calls timed out and were retried or failed.\n\n", s.CallTimeouts)
+	}
+	if hits := s.APICache.Hits + s.APICache.Revalidated; hits > 0 {
+		fmt.Fprintf(&b, "%d repeated reads were answered from the response cache.\n\n", hits)
+	}
+	if len(s.APICalls) > 0 {
+		endpoints := make([]string, 0, len(s.APICalls))
+		for endpoint := range s.APICalls {
+			endpoints = append(endpoints, endpoint)
+		}
+		sort.Strings(endpoints)
+
+		b.WriteString("| Endpoint | Calls |\n|----------|-------|\n")
+		for _, endpoint := range endpoints {
+			fmt.Fprintf(&b, "| `%s` | %d |\n", endpoint, s.APICalls[endpoint])
+		}
+	}
+
+	return b.String()
+}
+
+// valueOrZero renders missing statistics as 0
+func valueOrZero(value interface{}) interface{} {
+	if value == nil {
+		return 0
+	}
+	return value
+}
+
+// writeJobSummary appends the summary to the GitHub job summary if running in GitHub Actions
+func writeJobSummary(s *runSummary) {
+	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryFile == "" {
+		// Not running in GitHub Actions, skip
+		return
+	}
+
+	file, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.WithField("error", err).Warn("Failed to open GitHub job summary file")
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(s.markdown()); err != nil {
+		logrus.WithField("error", err).Warn("Failed to write GitHub job summary")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/assertions"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Test command - check declarative expectations about the manifests
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run declarative tests against the manifests",
+	Long: `Run the tests declared in the test files of --tests-dir against the parsed manifests and report
+which pass and which fail. Each test selects the objects of one kind whose where fields match and
+expects a number of them (count) or field values in each of them (expect), e.g. that project
+payments has an owner role binding or that every SLO uses a 28 day rolling window. Nothing is sent
+to Nobl9.`,
+	Example: `  # Run the tests of .nobl9/tests against the manifests of the repository
+  nobl9-action test
+
+  # Run the tests of another directory and print the results as JSON
+  nobl9-action test --tests-dir ci/nobl9-tests --output json`,
+	GroupID: groupCore,
+	RunE:    runTest,
+}
+
+// runTest evaluates the declared tests against the manifests and fails when
+// any test fails
+func runTest(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+
+	if config.Output != "text" && config.Output != "json" {
+		return configError(fmt.Errorf("invalid output format: %s", config.Output))
+	}
+	if config.TestsDir == "" {
+		config.TestsDir = assertions.DefaultDir
+	}
+	suite, err := assertions.Load(config.TestsDir)
+	if err != nil {
+		return configError(err)
+	}
+
+	paths, err := inputFiles()
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+
+	report, err := runAssertions(suite, paths)
+	if err != nil {
+		return typedError(errors.ErrorTypeValidation, "tests could not run", err)
+	}
+	if err := writeTestReport(cmd.OutOrStdout(), report, config.Output); err != nil {
+		return err
+	}
+
+	if report.Failed > 0 {
+		return errors.NewValidationError(fmt.Sprintf("%d of %d tests failed", report.Failed, len(report.Results)), nil)
+	}
+	return nil
+}
+
+// runAssertions evaluates the tests against the objects of the files,
+// logs each failed test and sets the tests-passed and tests-failed outputs
+func runAssertions(suite *assertions.Suite, paths []string) (*assertions.Report, error) {
+	var items []planner.Item
+	for _, path := range paths {
+		parsed, err := parseRemoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range parsed.Objects {
+			items = append(items, planner.Item{Object: obj, Source: path})
+		}
+	}
+
+	report, err := suite.Evaluate(items)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range report.Results {
+		if !result.Passed {
+			logrus.WithFields(logrus.Fields{
+				"test":     result.Name,
+				"file":     result.Source,
+				"failures": result.Failures,
+			}).Error("Test failed")
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"tests":   len(report.Results),
+		"passed":  report.Passed,
+		"failed":  report.Failed,
+		"objects": len(items),
+	}).Info("Tests completed")
+
+	setGitHubOutput("tests-passed", fmt.Sprintf("%d", report.Passed))
+	setGitHubOutput("tests-failed", fmt.Sprintf("%d", report.Failed))
+	return report, nil
+}
+
+// writeTestReport writes the test results as text or JSON
+func writeTestReport(w io.Writer, report *assertions.Report, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	for _, result := range report.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", status, result.Name, result.Source)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "  %s\n", failure)
+		}
+	}
+	fmt.Fprintf(w, "\n%d tests, %d passed, %d failed\n", len(report.Results), report.Passed, report.Failed)
+	return nil
+}
+
+// outsideDir drops the files inside dir, so test files under the repository
+// path are not read as manifests
+func outsideDir(files []string, dir string) []string {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return files
+	}
+
+	var kept []string
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err == nil && strings.HasPrefix(path, root+string(filepath.Separator)) {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
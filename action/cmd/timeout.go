@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// defaultRunTimeout is how long a run may take unless --timeout is set
+const defaultRunTimeout = 10 * time.Minute
+
+// Phases bounded by their own timeouts; the run phase stands for --timeout
+const (
+	timeoutPhaseRun     = "run"
+	timeoutPhaseScan    = "scan"
+	timeoutPhaseResolve = "resolve"
+	timeoutPhaseApply   = "apply"
+)
+
+// checkTimeouts rejects negative run and phase timeouts
+func checkTimeouts() error {
+	for flag, timeout := range map[string]time.Duration{
+		"timeout":         config.Timeout,
+		"scan-timeout":    config.ScanTimeout,
+		"resolve-timeout": config.ResolveTimeout,
+		"apply-timeout":   config.ApplyTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("%s cannot be negative", flag)
+		}
+	}
+	return nil
+}
+
+// runContext returns the context of a run bounded by --timeout. Once it
+// passes, the context is cancelled with a run phase timeout error, which
+// aborts the run like any critical error (see abortedBy); a signal
+// cancelling parent aborts it the same way.
+func runContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if config.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeoutCause(parent, config.Timeout, errors.NewPhaseTimeoutError(timeoutPhaseRun, config.Timeout))
+}
+
+// withPhaseTimeout bounds a phase of the run by its timeout; 0 leaves only
+// the run deadline. The phase's Nobl9 API calls and loops see the deadline
+// through the returned context. The returned function ends the phase: when
+// the timeout passed, it aborts the run with the phase timeout error, so
+// the remaining phases are skipped and the error is reported.
+func withPhaseTimeout(ctx context.Context, abort context.CancelCauseFunc, phase string, timeout time.Duration) (context.Context, func()) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	timeoutErr := errors.NewPhaseTimeoutError(phase, timeout)
+	phaseCtx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	return phaseCtx, func() {
+		if context.Cause(phaseCtx) == error(timeoutErr) {
+			logrus.WithFields(logrus.Fields{
+				"phase":   phase,
+				"timeout": timeout.String(),
+			}).Error("Phase timed out, aborting the remaining work")
+			abort(timeoutErr)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/tracing"
+	"github.com/your-org/nobl9-action/version"
+)
+
+// tracingShutdownTimeout bounds exporting the spans left when the run ends
+const tracingShutdownTimeout = 10 * time.Second
+
+// startTracing exports the spans of the run over OTLP when the standard
+// OTEL_* environment configures an endpoint. The returned function exports
+// the remaining spans; tracing is best effort and never fails the run.
+func startTracing() func() {
+	shutdown, err := tracing.Setup(context.Background(), version.Version)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to set up tracing, spans are not exported")
+		return func() {}
+	}
+	if tracing.Enabled() {
+		logrus.WithField("protocol", tracing.Protocol()).Info("Exporting traces over OTLP")
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to export traces")
+		}
+	}
+}
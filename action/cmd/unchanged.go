@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/audit"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+)
+
+// unchangedDetector compares objects about to be applied with their live
+// definitions. Besides the fields Nobl9 sets, it ignores the annotations
+// that change with every commit, but not the owner label and repository
+// annotation, so objects missing them are still applied to get them.
+func unchangedDetector() (*drift.Detector, error) {
+	ignored := append([]string{}, drift.DefaultIgnoreFields...)
+	ignored = append(ignored, audit.IgnoreFields()...)
+	for _, key := range []string{ownership.CommitAnnotation, ownership.SourceAnnotation} {
+		ignored = append(ignored, "metadata.annotations."+key)
+	}
+	fields, err := drift.ParseIgnoreFields(strings.Join(ignored, ","))
+	if err != nil {
+		return nil, err
+	}
+	return drift.NewWithIgnoreFields(fields), nil
+}
+
+// skipUnchangedLive marks the objects whose live definition hashes the same
+// as the version about to be applied unchanged, and returns the others
+// along with their versions to apply. Role bindings are left to
+// skipUnchangedRoleBindings.
+func skipUnchangedLive(file *preparedFile, objects, applied []manifest.Object, live map[compare.Key]manifest.Object) ([]manifest.Object, []manifest.Object) {
+	detector, err := unchangedDetector()
+	if err != nil {
+		logrus.WithField("file", file.Path).WithError(err).Warn("Failed to compare objects with Nobl9, applying all of them")
+		return objects, applied
+	}
+
+	var remaining, remainingApplied []manifest.Object
+	for i, obj := range applied {
+		liveObject, found := live[compare.KeyOf(obj)]
+		if found && obj.GetKind() != manifest.KindRoleBinding && sameContent(detector, obj, liveObject) {
+			logrus.WithFields(logrus.Fields{
+				"file":   file.Path,
+				"object": compare.KeyOf(obj).String(),
+			}).Debug("Object unchanged in Nobl9, skipping apply")
+			file.setStatus(objects[i:i+1], statusUnchanged, nil)
+			file.Result.ObjectsUnchanged++
+			continue
+		}
+		remaining = append(remaining, objects[i])
+		remainingApplied = append(remainingApplied, obj)
+	}
+	return remaining, remainingApplied
+}
+
+// sameContent reports whether two objects hash the same; objects that
+// cannot be hashed are treated as different
+func sameContent(detector *drift.Detector, obj, live manifest.Object) bool {
+	objHash, err := detector.Hash(obj)
+	if err != nil {
+		return false
+	}
+	liveHash, err := detector.Hash(live)
+	return err == nil && objHash == liveHash
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// Policies of --on-unresolved-user for role bindings whose email did not
+// resolve to a Nobl9 user
+const (
+	// unresolvedFail aborts the run before anything is applied
+	unresolvedFail = "fail"
+	// unresolvedWarn applies the role binding with the email as written
+	unresolvedWarn = "warn"
+	// unresolvedSkip leaves the role binding out
+	unresolvedSkip = "skip"
+	// unresolvedPlaceholder grants the role to --unresolved-user-group
+	// instead
+	unresolvedPlaceholder = "placeholder"
+)
+
+// unresolvedUserPolicies are the values --on-unresolved-user accepts
+var unresolvedUserPolicies = []string{unresolvedFail, unresolvedWarn, unresolvedSkip, unresolvedPlaceholder}
+
+// checkUnresolvedUserPolicy checks --on-unresolved-user and the placeholder
+// group it needs
+func checkUnresolvedUserPolicy() error {
+	switch config.OnUnresolvedUser {
+	case "", unresolvedFail, unresolvedWarn, unresolvedSkip:
+		return nil
+	case unresolvedPlaceholder:
+		if config.UnresolvedUserGroup == "" {
+			return fmt.Errorf("on-unresolved-user placeholder requires unresolved-user-group")
+		}
+		return nil
+	}
+	return fmt.Errorf("on-unresolved-user must be one of %s, got '%s'", strings.Join(unresolvedUserPolicies, ", "), config.OnUnresolvedUser)
+}
+
+// handleUnresolvedUsers applies --on-unresolved-user to the role bindings
+// whose user is one of the unresolved emails. With fail it returns an error
+// naming the emails and their files; with skip and placeholder it drops the
+// role bindings, or grants their role to the placeholder group, in place.
+func handleUnresolvedUsers(files []*parsedFile, unresolved []string) error {
+	if len(unresolved) == 0 {
+		return nil
+	}
+	isUnresolved := make(map[string]bool, len(unresolved))
+	for _, email := range unresolved {
+		isUnresolved[email] = true
+	}
+
+	var failed []string
+	for _, file := range files {
+		objects := make([]manifest.Object, 0, len(file.Objects))
+		for _, obj := range file.Objects {
+			roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+			if !ok || roleBinding.Spec.User == nil || !isUnresolved[*roleBinding.Spec.User] {
+				objects = append(objects, obj)
+				continue
+			}
+
+			email := *roleBinding.Spec.User
+			log := logrus.WithFields(logrus.Fields{
+				"file":         file.Path,
+				"role_binding": roleBinding.Metadata.Name,
+				"email":        email,
+			})
+			switch config.OnUnresolvedUser {
+			case unresolvedFail:
+				failed = append(failed, fmt.Sprintf("%s (role binding '%s' in %s)", email, roleBinding.Metadata.Name, file.Path))
+				objects = append(objects, obj)
+			case unresolvedSkip:
+				log.Warn("Skipping role binding of an unresolved user")
+			case unresolvedPlaceholder:
+				group := config.UnresolvedUserGroup
+				roleBinding.Spec.User = nil
+				roleBinding.Spec.GroupRef = &group
+				objects = append(objects, roleBinding)
+				log.WithField("group", group).Warn("Granting the role of an unresolved user to the placeholder group")
+			default:
+				log.Warn("Applying role binding with an unresolved email as written")
+				objects = append(objects, obj)
+			}
+		}
+		file.Objects = objects
+	}
+
+	if len(failed) > 0 {
+		return errors.NewUserResolutionErrorWithDetails(
+			fmt.Sprintf("%d role binding users did not resolve to Nobl9 users: %s", len(failed), strings.Join(failed, "; ")),
+			nil,
+			map[string]interface{}{"unresolved_emails": unresolved},
+		)
+	}
+	return nil
+}
+
+// unresolvedUser is an email of a file that did not resolve to a Nobl9
+// user, as listed by --unresolved-users-file
+type unresolvedUser struct {
+	Email  string `json:"email"`
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// unresolvedUsers are the unresolved users reported by the run so far,
+// across organizations
+var unresolvedUsers []unresolvedUser
+
+// unresolvedUsersOf returns one entry per file and email of the files that
+// did not resolve, with the error its last lookup failed with
+func unresolvedUsersOf(files []*parsedFile, resolutions map[string]string, failures map[string]error) []unresolvedUser {
+	var users []unresolvedUser
+	for _, file := range files {
+		for _, email := range file.Emails {
+			if _, found := resolutions[email]; found {
+				continue
+			}
+			reason := "not looked up, the run was aborted"
+			if err, failed := failures[email]; failed {
+				reason = err.Error()
+			}
+			users = append(users, unresolvedUser{Email: email, File: relativePath(file.Path), Reason: reason})
+		}
+	}
+	return users
+}
+
+// reportUnresolvedUsers adds users to the comma separated unresolved-users
+// output and rewrites --unresolved-users-file, if set. The output and file
+// are written even when every email resolved, so later steps can rely on
+// them.
+func reportUnresolvedUsers(users []unresolvedUser) {
+	unresolvedUsers = append(unresolvedUsers, users...)
+
+	var emails []string
+	seen := make(map[string]bool)
+	for _, user := range unresolvedUsers {
+		if !seen[user.Email] {
+			seen[user.Email] = true
+			emails = append(emails, user.Email)
+		}
+	}
+	setGitHubOutput("unresolved-users", strings.Join(emails, ","))
+
+	if config.UnresolvedUsersFile == "" {
+		return
+	}
+	log := logrus.WithField("path", config.UnresolvedUsersFile)
+	if err := writeUnresolvedUsers(config.UnresolvedUsersFile, unresolvedUsers); err != nil {
+		log.WithError(err).Warn("Failed to write unresolved users file")
+		return
+	}
+	log.WithField("unresolved_users", len(emails)).Info("Wrote unresolved users file")
+}
+
+// writeUnresolvedUsers writes the unresolved users as a JSON array
+func writeUnresolvedUsers(path string, users []unresolvedUser) error {
+	if users == nil {
+		users = []unresolvedUser{}
+	}
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode unresolved users: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create unresolved users directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write unresolved users file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	v2 "github.com/nobl9/nobl9-go/sdk/endpoints/users/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"github.com/your-org/nobl9-action/pkg/owners"
+	"github.com/your-org/nobl9-action/pkg/retry"
+	"github.com/your-org/nobl9-action/pkg/useraudit"
+)
+
+// Users command - groups commands about the users of role bindings
+var usersCmd = &cobra.Command{
+	Use:     "users",
+	Short:   "Check the users the repository's role bindings grant roles to",
+	Long:    `Check the users the role bindings of the repository grant roles to.`,
+	GroupID: groupUtility,
+}
+
+// Users audit command
+var usersAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report role binding users that no longer exist in Nobl9 or are deactivated in Okta",
+	Long: `List every user the role bindings of the repository grant a role to, by email or user ID, look
+each of them up in Nobl9 and report those Nobl9 no longer knows. With --okta-org and --okta-token
+the emails are also looked up in Okta, and users Okta has not, or has suspended or deprovisioned,
+are reported too, so stale access can be cleaned up before it is noticed in an audit.
+
+okta-group: and github-team: role bindings are expanded when applied and are not audited. The
+report is printed as markdown and, with --report-file, written to a file, e.g. for a scheduled
+workflow that opens a cleanup issue. The command fails when it reports any user.`,
+	Example: `  # Audit the users of the repository's role bindings
+  nobl9-action users audit --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET"
+
+  # Also report users deactivated in Okta, for a cleanup issue
+  nobl9-action users audit --client-id "$NOBL9_CLIENT_ID" --client-secret "$NOBL9_CLIENT_SECRET" \
+    --okta-org acme --okta-token "$OKTA_API_TOKEN" --report-file stale-users.md`,
+	Args: cobra.NoArgs,
+	RunE: runUsersAudit,
+}
+
+// runUsersAudit reports the stale users of the repository's role bindings
+func runUsersAudit(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return errors.NewConfigError("failed to setup logging", err)
+	}
+	if (config.OktaOrg == "") != (config.OktaToken == "") {
+		return configError(fmt.Errorf("okta-org and okta-token must be provided together"))
+	}
+
+	ctx, cancel := context.WithTimeout(commandContext(cmd), 10*time.Minute)
+	defer cancel()
+
+	paths, err := inputFiles()
+	if err != nil {
+		return typedError(errors.ErrorTypeFileProcessing, "failed to scan files", err)
+	}
+	references, err := userReferences(paths)
+	if err != nil {
+		return err
+	}
+
+	client, err := createNobl9Client(config.ClientID, config.ClientSecret)
+	if err != nil {
+		return typedError(errors.ErrorTypeNobl9API, "failed to create Nobl9 client", err)
+	}
+	var status useraudit.StatusLookup
+	if config.OktaOrg != "" {
+		oktaClient, err := okta.New(&okta.Config{OrgURL: config.OktaOrg, APIToken: config.OktaToken}, newLogger())
+		if err != nil {
+			return err
+		}
+		status = oktaClient.UserStatus
+	}
+
+	report, err := useraudit.Audit(ctx, references, nobl9UserExists(client), status)
+	if err != nil {
+		return err
+	}
+
+	markdown := report.Markdown()
+	fmt.Fprint(cmd.OutOrStdout(), markdown)
+	if config.ReportFile != "" {
+		if err := writeMarkdownFile(config.ReportFile, markdown); err != nil {
+			return err
+		}
+		logrus.WithField("path", config.ReportFile).Info("Wrote user audit report")
+	}
+
+	setGitHubOutput("stale-users", strings.Join(report.Users(), ","))
+	setGitHubOutput("stale-user-count", fmt.Sprintf("%d", len(report.Stale)))
+
+	logrus.WithFields(logrus.Fields{
+		"checked": report.Checked,
+		"stale":   len(report.Stale),
+	}).Info("User audit completed")
+
+	if len(report.Stale) > 0 {
+		return fmt.Errorf("%d of %d role binding users no longer exist or are deactivated", len(report.Stale), report.Checked)
+	}
+	return nil
+}
+
+// userReferences parses the files and returns their role bindings that
+// grant a role to a single user, by email or user ID
+func userReferences(paths []string) ([]useraudit.Reference, error) {
+	var references []useraudit.Reference
+	for _, path := range paths {
+		parsed, err := parseRemoteFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range parsed.Objects {
+			roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+			if !ok || roleBinding.Spec.User == nil {
+				continue
+			}
+			user := strings.TrimSpace(*roleBinding.Spec.User)
+			if user == "" || okta.IsGroupReference(user) || owners.IsTeamReference(user) {
+				continue
+			}
+			references = append(references, useraudit.Reference{
+				User:        user,
+				RoleBinding: roleBinding.Metadata.Name,
+				File:        relativePath(path),
+			})
+		}
+	}
+	return references, nil
+}
+
+// nobl9UserExists looks users up in Nobl9 by email or user ID
+func nobl9UserExists(client *sdk.Client) useraudit.UserLookup {
+	return func(ctx context.Context, user string) (bool, error) {
+		var found *v2.User
+		err := retryCall(ctx, retry.OperationUsers, "get user", func(ctx context.Context) error {
+			var err error
+			found, err = client.Users().V2().GetUser(ctx, user)
+			return err
+		})
+		return found != nil, err
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	actionconfig "github.com/your-org/nobl9-action/pkg/config"
+)
+
+// variableFlags are the flags repository and organization variables may
+// set. Credentials and per-workflow settings such as paths and plan files
+// are left out.
+var variableFlags = map[string]bool{
+	"file-pattern":            true,
+	"environment":             true,
+	"render":                  true,
+	"owners-files":            true,
+	"owners-roles":            true,
+	"kinds":                   true,
+	"project":                 true,
+	"policy":                  true,
+	"rego-policy":             true,
+	"role-catalog":            true,
+	"budget-shrink-threshold": true,
+	"allowed-branches":        true,
+	"allowed-events":          true,
+	"email-lowercase":         true,
+	"email-strip-plus":        true,
+	"email-domain-aliases":    true,
+	"on-unresolved-user":      true,
+	"unresolved-user-group":   true,
+	"okta-org":                true,
+	"github-emails":           true,
+	"user-cache-ttl":          true,
+	"max-rps":                 true,
+	"breaker-threshold":       true,
+	"breaker-cooldown":        true,
+	"call-timeout":            true,
+	"retry-max-attempts":      true,
+	"retry-base-delay":        true,
+	"retry-policies":          true,
+	"timeout":                 true,
+	"scan-timeout":            true,
+	"resolve-timeout":         true,
+	"apply-timeout":           true,
+	"checkpoint-file":         true,
+	"resume":                  true,
+	"apply-granularity":       true,
+	"rollback-on-failure":     true,
+	"prune":                   true,
+	"delete-grace":            true,
+	"history-size":            true,
+	"reapply-unchanged":       true,
+	"notify-on":               true,
+	"metrics-job":             true,
+	"owner-label":             true,
+	"trace-annotations":       true,
+	"audit-annotations":       true,
+	"ignore-fields":           true,
+	"log-level":               true,
+	"log-format":              true,
+}
+
+// variableExclusions are flags variables do not set on some commands: a
+// project variable meant for export or report access must not restrict
+// every process and plan run to that project
+var variableExclusions = map[string][]string{
+	"project": {"process", "plan"},
+}
+
+// appliedVariables are the variables that set flags of the running command,
+// logged once logging is set up
+var appliedVariables []string
+
+// applyVariables sets the command's flags from NOBL9_ACTION_ repository or
+// organization variables. A variable replaces a flag's default, including
+// an empty value or the default passed on by the action, but never a value
+// the workflow chose.
+func applyVariables(cmd *cobra.Command, args []string) error {
+	values, err := actionconfig.Variables(os.Environ())
+	if err != nil {
+		return configError(err)
+	}
+
+	var problems []string
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		value, found := values[flag.Name]
+		if !found || !variableFlags[flag.Name] || excludedVariable(cmd, flag.Name) {
+			return
+		}
+		if current := flag.Value.String(); current != flag.DefValue && current != "" {
+			return
+		}
+		if err := cmd.Flags().Set(flag.Name, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", actionconfig.VariableName(flag.Name), err))
+			return
+		}
+		appliedVariables = append(appliedVariables, actionconfig.VariableName(flag.Name))
+	})
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return configError(fmt.Errorf("invalid variable %s", problems[0]))
+	}
+	return nil
+}
+
+// excludedVariable reports whether variables may not set the flag of the
+// command
+func excludedVariable(cmd *cobra.Command, flag string) bool {
+	for _, name := range variableExclusions[flag] {
+		if cmd.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// logAppliedVariables reports the flags set by variables
+func logAppliedVariables() {
+	if len(appliedVariables) == 0 {
+		return
+	}
+	sort.Strings(appliedVariables)
+	logrus.WithField("variables", appliedVariables).Info("Applied configuration from repository variables")
+}
@@ -1,24 +1,174 @@
 #!/bin/sh
 set -e
+# Arguments are word split when passed on below; never expand them as globs
+set -f
 
-# Parse the validate-only flag to determine which command to run
+# Parse the validate-only, drift-only, plan-out and plan-file flags to
+# determine which command to run
 VALIDATE_ONLY="false"
+DRIFT_ONLY="false"
+PLAN_OUT=""
+PLAN_FILE=""
 COMMAND_ARGS=""
+POLICY_ARGS=""
+PROCESS_ARGS=""
+PLAN_ARGS=""
+APPLY_ARGS=""
+VALIDATE_ARGS=""
+DRIFT_ARGS=""
 
-# Process arguments to extract validate-only flag
+# Sort arguments into those shared by all commands and those only some commands
+# accept; the mode flags may come after them, so the command is chosen at the end
 while [ $# -gt 0 ]; do
   case $1 in
     --validate-only)
       VALIDATE_ONLY="$2"
       shift 2
       ;;
+    --drift-only)
+      DRIFT_ONLY="$2"
+      shift 2
+      ;;
     --client-id|--client-secret)
-      # Only add credentials for process command
-      if [ "$VALIDATE_ONLY" != "true" ]; then
-        COMMAND_ARGS="$COMMAND_ARGS $1 $2"
-      fi
+      # Credentials are used by every command but validate, which needs them for --remote
+      COMMAND_ARGS="$COMMAND_ARGS $1 $2"
+      shift 2
+      ;;
+    --dry-run|--force)
+      PROCESS_ARGS="$PROCESS_ARGS $1=$2"
       shift 2
       ;;
+    --server-dry-run=*|--diff-file=*)
+      # Server-side dry runs and the diff cover the objects process and plan would apply
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      shift
+      ;;
+    --remote=*|--check-recipients=*|--tests-dir=*)
+      # Server-side checks and declarative tests only apply to the validate command
+      VALIDATE_ARGS="$VALIDATE_ARGS $1"
+      shift
+      ;;
+    --plan-out=*)
+      PLAN_OUT="${1#--plan-out=}"
+      shift
+      ;;
+    --plan-file=*)
+      PLAN_FILE="${1#--plan-file=}"
+      shift
+      ;;
+    --drift-report-file=*)
+      # The markdown drift report only applies to the drift command
+      DRIFT_ARGS="$DRIFT_ARGS --report-file=${1#--drift-report-file=}"
+      shift
+      ;;
+    --drift-ignore-fields=*)
+      # Newlines and spaces would split the rules into separate arguments
+      IGNORE_FIELDS=$(printf '%s' "${1#--drift-ignore-fields=}" | tr '\n' ',' | tr -d ' ')
+      DRIFT_ARGS="$DRIFT_ARGS --ignore-fields=$IGNORE_FIELDS"
+      shift
+      ;;
+    --values=*)
+      # Newlines and spaces would split the files into separate arguments; every command reading manifests substitutes the values
+      VALUES=$(printf '%s' "${1#--values=}" | tr '\n' ',' | tr -d ' ')
+      PROCESS_ARGS="$PROCESS_ARGS --values=$VALUES"
+      PLAN_ARGS="$PLAN_ARGS --values=$VALUES"
+      VALIDATE_ARGS="$VALIDATE_ARGS --values=$VALUES"
+      DRIFT_ARGS="$DRIFT_ARGS --values=$VALUES"
+      shift
+      ;;
+    --policy=*|--rego-policy=*|--role-catalog=*)
+      # Policies are enforced by the validate, process and plan commands
+      POLICY_ARGS="$POLICY_ARGS $1"
+      shift
+      ;;
+    --sarif-file=*)
+      # Invalid objects are found by the process and validate commands
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      VALIDATE_ARGS="$VALIDATE_ARGS $1"
+      shift
+      ;;
+    --budget-shrink-threshold=*)
+      # High impact SLO changes are flagged in the report of every planning run
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      shift
+      ;;
+    --environment=*|--generate-role-binding-names=*|--migrate-fields=*|--owners-roles=*)
+      # Environment overlays, generated names, migrated fields and OWNERS roles shape the objects of every command reading the manifests
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      VALIDATE_ARGS="$VALIDATE_ARGS $1"
+      DRIFT_ARGS="$DRIFT_ARGS $1"
+      shift
+      ;;
+    --github-token=*)
+      # File owners are checked by every run that plans or applies
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      shift
+      ;;
+    --kinds=*|--project=*|--skip-kinds=*|--skip-objects=*|--email-lowercase=*|--email-strip-plus=*|--email-domain-aliases=*|--resolve-paths=*|--on-unresolved-user=*|--unresolved-user-group=*|--unresolved-users-file=*|--okta-org=*|--okta-token=*|--github-emails=*|--user-cache-file=*|--user-cache-ttl=*)
+      # Kind selection, project scope, skip lists, email resolution and Okta group and GitHub team expansion decide what is planned
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      shift
+      ;;
+    --max-rps=*|--breaker-threshold=*|--breaker-cooldown=*|--call-timeout=*|--record=*|--replay=*|--retry-max-attempts=*|--retry-base-delay=*|--retry-policies=*|--timeout=*|--results-file=*|--progress-file=*|--progress-interval=*)
+      # API limits, the run timeout, the results file and the progress file apply to every command that calls Nobl9 to apply
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --scan-timeout=*|--resolve-timeout=*)
+      # Files are scanned and emails resolved by the commands that plan
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      PLAN_ARGS="$PLAN_ARGS $1"
+      shift
+      ;;
+    --apply-timeout=*|--checkpoint-file=*|--resume=*)
+      # Objects are applied, and completed files checkpointed, by the process and apply commands
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --allowed-branches=*|--allowed-events=*|--require-plan-hash=*)
+      # The provenance policy and plan approval guard applying
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --owner-label=*)
+      # The ownership label is set when applying and scopes drift detection
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      DRIFT_ARGS="$DRIFT_ARGS $1"
+      shift
+      ;;
+    --apply-granularity=*|--rollback-on-failure=*|--reapply-unchanged=*)
+      # How apply calls are grouped, skipped and rolled back matters wherever objects are applied
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --trace-annotations=*|--audit-annotations=*|--audit-log=*)
+      # Trace and audit annotations and the audit log are written when applying
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --slack-webhook-url=*|--notify-url=*|--notify-on=*|--pushgateway-url=*|--metrics-job=*)
+      # The run summary and metrics are posted by the commands that apply
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      APPLY_ARGS="$APPLY_ARGS $1"
+      shift
+      ;;
+    --state-file=*|--prune=*|--delete-grace=*|--auto-approve=*|--history-size=*|--organizations=*)
+      # Pruning, run history and runs across several organizations only apply to the process command
+      PROCESS_ARGS="$PROCESS_ARGS $1"
+      shift
+      ;;
     *)
       COMMAND_ARGS="$COMMAND_ARGS $1"
       shift
@@ -26,7 +176,7 @@ while [ $# -gt 0 ]; do
   esac
 done
 
-# Determine which command to run based on validate-only flag
+# Determine which command to run based on the mode flags
 BINARY_PATH="/app/nobl9-action"
 
 # For local testing, use current directory binary if /app doesn't exist
@@ -34,10 +184,19 @@ if [ ! -f "$BINARY_PATH" ]; then
   BINARY_PATH="./nobl9-action"
 fi
 
-if [ "$VALIDATE_ONLY" = "true" ]; then
+if [ -n "$PLAN_FILE" ]; then
+  echo "Running apply mode..."
+  exec $BINARY_PATH apply $COMMAND_ARGS $APPLY_ARGS --plan="$PLAN_FILE"
+elif [ -n "$PLAN_OUT" ]; then
+  echo "Running plan mode..."
+  exec $BINARY_PATH plan $COMMAND_ARGS $POLICY_ARGS $PLAN_ARGS --out="$PLAN_OUT"
+elif [ "$DRIFT_ONLY" = "true" ]; then
+  echo "Running drift detection mode..."
+  exec $BINARY_PATH drift $COMMAND_ARGS $DRIFT_ARGS
+elif [ "$VALIDATE_ONLY" = "true" ]; then
   echo "Running validation mode..."
-  exec $BINARY_PATH validate $COMMAND_ARGS
+  exec $BINARY_PATH validate $COMMAND_ARGS $POLICY_ARGS $VALIDATE_ARGS
 else
   echo "Running process mode..."
-  exec $BINARY_PATH process $COMMAND_ARGS
+  exec $BINARY_PATH process $COMMAND_ARGS $POLICY_ARGS $PROCESS_ARGS
 fi
\ No newline at end of file
@@ -0,0 +1,306 @@
+package access
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/your-org/nobl9-action/pkg/reportfmt"
+)
+
+// Source is where the role bindings of a report come from
+type Source string
+
+const (
+	// SourceRepo reports the role bindings of the repository
+	SourceRepo Source = "repo"
+	// SourceLive reports the role bindings in Nobl9
+	SourceLive Source = "live"
+	// SourceBoth reports both side by side, with their differences
+	SourceBoth Source = "both"
+)
+
+// ParseSource parses the value of --source
+func ParseSource(value string) (Source, error) {
+	switch source := Source(strings.ToLower(strings.TrimSpace(value))); source {
+	case SourceRepo, SourceLive, SourceBoth:
+		return source, nil
+	}
+	return "", fmt.Errorf("unknown source '%s', expected repo, live or both", value)
+}
+
+// Repo reports whether the source includes the repository
+func (s Source) Repo() bool {
+	return s == SourceRepo || s == SourceBoth
+}
+
+// Live reports whether the source includes Nobl9
+func (s Source) Live() bool {
+	return s == SourceLive || s == SourceBoth
+}
+
+// Formats are the formats a report can be written in
+var Formats = []string{"markdown", "csv", "json"}
+
+// ParseFormat parses the value of --output
+func ParseFormat(value string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	for _, known := range Formats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format '%s', expected %s", value, strings.Join(Formats, ", "))
+}
+
+// GroupPrefix marks a principal that is a Nobl9 user group rather than a
+// user
+const GroupPrefix = "group:"
+
+// Grant is a role a role binding grants a user or group in a project. The
+// project of an organization role binding is empty.
+type Grant struct {
+	Project   string
+	Principal string
+	Role      string
+}
+
+// Grants returns the grants of the role bindings among the objects. Users
+// are lowercased so emails written in another case match; groups are
+// prefixed with GroupPrefix.
+func Grants(objects []manifest.Object) []Grant {
+	var grants []Grant
+	for _, obj := range objects {
+		roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok {
+			continue
+		}
+		var principal string
+		switch {
+		case roleBinding.Spec.User != nil:
+			principal = strings.ToLower(strings.TrimSpace(*roleBinding.Spec.User))
+		case roleBinding.Spec.GroupRef != nil:
+			principal = GroupPrefix + *roleBinding.Spec.GroupRef
+		default:
+			continue
+		}
+		grants = append(grants, Grant{Project: roleBinding.Spec.ProjectRef, Principal: principal, Role: roleBinding.Spec.RoleRef})
+	}
+	return grants
+}
+
+// FilterProjects returns the grants of the projects matching any of the
+// glob patterns (e.g. payments-*). Organization grants are kept only when
+// there are no patterns.
+func FilterProjects(grants []Grant, patterns []string) ([]Grant, error) {
+	if len(patterns) == 0 {
+		return grants, nil
+	}
+	var filtered []Grant
+	for _, grant := range grants {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, grant.Project)
+			if err != nil {
+				return nil, fmt.Errorf("invalid project pattern '%s': %w", pattern, err)
+			}
+			if ok && grant.Project != "" {
+				filtered = append(filtered, grant)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// Entry is the role of a user or group in a project, in the repository and
+// in Nobl9. A role the report does not cover, or the principal does not
+// have, is empty; several roles are joined with a comma.
+type Entry struct {
+	Principal string `json:"principal"`
+	RepoRole  string `json:"repo_role,omitempty"`
+	LiveRole  string `json:"live_role,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+// Project is the access matrix of a project, sorted by principal
+type Project struct {
+	// Name is empty for the organization
+	Name    string  `json:"name"`
+	Entries []Entry `json:"entries"`
+}
+
+// Report is the access matrix of every project with a role binding
+type Report struct {
+	Source   Source    `json:"source"`
+	Projects []Project `json:"projects"`
+}
+
+// New builds the access matrix of the repository and live grants. With
+// SourceBoth each entry has a status: in sync, only in the repository, only
+// in Nobl9 or role differs. Projects are sorted by name, the organization
+// first.
+func New(source Source, repo, live []Grant) *Report {
+	type key struct{ project, principal string }
+	repoRoles := make(map[key][]string)
+	liveRoles := make(map[key][]string)
+	keys := make(map[key]bool)
+	add := func(roles map[key][]string, grants []Grant) {
+		for _, grant := range grants {
+			k := key{grant.Project, grant.Principal}
+			keys[k] = true
+			roles[k] = appendRole(roles[k], grant.Role)
+		}
+	}
+	if source.Repo() {
+		add(repoRoles, repo)
+	}
+	if source.Live() {
+		add(liveRoles, live)
+	}
+
+	byProject := make(map[string][]Entry)
+	for k := range keys {
+		entry := Entry{
+			Principal: k.principal,
+			RepoRole:  strings.Join(repoRoles[k], ", "),
+			LiveRole:  strings.Join(liveRoles[k], ", "),
+		}
+		if source == SourceBoth {
+			entry.Status = status(entry)
+		}
+		byProject[k.project] = append(byProject[k.project], entry)
+	}
+
+	report := &Report{Source: source}
+	for name, entries := range byProject {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Principal < entries[j].Principal })
+		report.Projects = append(report.Projects, Project{Name: name, Entries: entries})
+	}
+	sort.Slice(report.Projects, func(i, j int) bool { return report.Projects[i].Name < report.Projects[j].Name })
+	return report
+}
+
+// appendRole adds a role once, keeping the roles sorted
+func appendRole(roles []string, role string) []string {
+	for _, existing := range roles {
+		if existing == role {
+			return roles
+		}
+	}
+	roles = append(roles, role)
+	sort.Strings(roles)
+	return roles
+}
+
+// status compares the repository and live roles of an entry
+func status(entry Entry) string {
+	switch {
+	case entry.LiveRole == "":
+		return "only in repository"
+	case entry.RepoRole == "":
+		return "only in Nobl9"
+	case entry.RepoRole != entry.LiveRole:
+		return "role differs"
+	default:
+		return "in sync"
+	}
+}
+
+// Principals returns the number of distinct users and groups of the report
+func (r *Report) Principals() int {
+	seen := make(map[string]bool)
+	for _, project := range r.Projects {
+		for _, entry := range project.Entries {
+			seen[entry.Principal] = true
+		}
+	}
+	return len(seen)
+}
+
+// Render writes the report in one of Formats
+func (r *Report) Render(format string) ([]byte, error) {
+	switch format {
+	case "markdown":
+		return []byte(r.Markdown()), nil
+	case "csv":
+		return r.CSV()
+	case "json":
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode access report: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+	_, err := ParseFormat(format)
+	return nil, err
+}
+
+// Markdown renders the report for an access review
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Nobl9 Access Review\n\n")
+	if len(r.Projects) == 0 {
+		b.WriteString("No role bindings found.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d users and groups hold roles in %s, from %s.\n", r.Principals(), reportfmt.Plural(len(r.Projects), "project"), r.sourceName())
+
+	for _, project := range r.Projects {
+		name := project.Name
+		if name == "" {
+			name = "Organization"
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", name)
+		if r.Source == SourceBoth {
+			b.WriteString("| User | Repository role | Nobl9 role | Status |\n|------|-----------------|------------|--------|\n")
+			for _, entry := range project.Entries {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", entry.Principal, reportfmt.OrDash(entry.RepoRole), reportfmt.OrDash(entry.LiveRole), entry.Status)
+			}
+			continue
+		}
+		b.WriteString("| User | Role |\n|------|------|\n")
+		for _, entry := range project.Entries {
+			fmt.Fprintf(&b, "| %s | %s |\n", entry.Principal, entry.RepoRole+entry.LiveRole)
+		}
+	}
+	return b.String()
+}
+
+// CSV renders the report with one row per project and principal. The
+// columns are the same whatever the source, so reviews can be compared.
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"project", "user", "repo_role", "live_role", "status"}); err != nil {
+		return nil, err
+	}
+	for _, project := range r.Projects {
+		for _, entry := range project.Entries {
+			if err := writer.Write([]string{project.Name, entry.Principal, entry.RepoRole, entry.LiveRole, entry.Status}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode access report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sourceName describes where the role bindings come from
+func (r *Report) sourceName() string {
+	switch r.Source {
+	case SourceLive:
+		return "Nobl9"
+	case SourceBoth:
+		return "the repository and Nobl9"
+	}
+	return "the repository"
+}
@@ -0,0 +1,168 @@
+package access
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+)
+
+// roleBinding returns a role binding of a user, or of a group when the
+// principal starts with GroupPrefix
+func roleBinding(name, project, principal, role string) manifest.Object {
+	spec := v1alphaRoleBinding.Spec{RoleRef: role, ProjectRef: project}
+	if group, found := strings.CutPrefix(principal, GroupPrefix); found {
+		spec.GroupRef = &group
+	} else {
+		spec.User = &principal
+	}
+	return v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: name}, spec)
+}
+
+func TestGrants(t *testing.T) {
+	grants := Grants([]manifest.Object{
+		roleBinding("payments-alice", "payments", "Alice@Example.com", "project-owner"),
+		roleBinding("payments-sre", "payments", "group:sre", "project-viewer"),
+		roleBinding("org-bob", "", "bob@example.com", "organization-admin"),
+	})
+
+	expected := []Grant{
+		{Project: "payments", Principal: "alice@example.com", Role: "project-owner"},
+		{Project: "payments", Principal: "group:sre", Role: "project-viewer"},
+		{Project: "", Principal: "bob@example.com", Role: "organization-admin"},
+	}
+	if len(grants) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, grants)
+	}
+	for i := range expected {
+		if grants[i] != expected[i] {
+			t.Errorf("expected %+v at %d, got %+v", expected[i], i, grants[i])
+		}
+	}
+}
+
+func TestFilterProjects(t *testing.T) {
+	grants := []Grant{
+		{Project: "payments-api", Principal: "alice@example.com", Role: "project-owner"},
+		{Project: "checkout", Principal: "bob@example.com", Role: "project-owner"},
+		{Project: "", Principal: "carol@example.com", Role: "organization-admin"},
+	}
+
+	filtered, err := FilterProjects(grants, []string{"payments-*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].Project != "payments-api" {
+		t.Errorf("expected the payments-api grant, got %v", filtered)
+	}
+
+	if all, _ := FilterProjects(grants, nil); len(all) != 3 {
+		t.Errorf("expected every grant without patterns, got %v", all)
+	}
+	if _, err := FilterProjects(grants, []string{"["}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+}
+
+func TestNewBoth(t *testing.T) {
+	repo := []Grant{
+		{Project: "payments", Principal: "alice@example.com", Role: "project-owner"},
+		{Project: "payments", Principal: "bob@example.com", Role: "project-editor"},
+		{Project: "payments", Principal: "carol@example.com", Role: "project-viewer"},
+	}
+	live := []Grant{
+		{Project: "payments", Principal: "alice@example.com", Role: "project-owner"},
+		{Project: "payments", Principal: "bob@example.com", Role: "project-viewer"},
+		{Project: "payments", Principal: "dave@example.com", Role: "project-owner"},
+		{Project: "", Principal: "erin@example.com", Role: "organization-admin"},
+	}
+
+	report := New(SourceBoth, repo, live)
+	if len(report.Projects) != 2 || report.Projects[0].Name != "" || report.Projects[1].Name != "payments" {
+		t.Fatalf("expected the organization and payments, got %+v", report.Projects)
+	}
+	statuses := make(map[string]string)
+	for _, entry := range report.Projects[1].Entries {
+		statuses[entry.Principal] = entry.Status
+	}
+	expected := map[string]string{
+		"alice@example.com": "in sync",
+		"bob@example.com":   "role differs",
+		"carol@example.com": "only in repository",
+		"dave@example.com":  "only in Nobl9",
+	}
+	for principal, status := range expected {
+		if statuses[principal] != status {
+			t.Errorf("expected %s to be %s, got %s", principal, status, statuses[principal])
+		}
+	}
+	if report.Principals() != 5 {
+		t.Errorf("expected 5 principals, got %d", report.Principals())
+	}
+
+	markdown := report.Markdown()
+	for _, line := range []string{"### Organization", "### payments", "| bob@example.com | project-editor | project-viewer | role differs |", "| carol@example.com | project-viewer | - | only in repository |"} {
+		if !strings.Contains(markdown, line) {
+			t.Errorf("expected %q in markdown:\n%s", line, markdown)
+		}
+	}
+}
+
+func TestNewRepo(t *testing.T) {
+	repo := []Grant{
+		{Project: "payments", Principal: "alice@example.com", Role: "project-owner"},
+		{Project: "payments", Principal: "alice@example.com", Role: "project-viewer"},
+	}
+	live := []Grant{{Project: "payments", Principal: "bob@example.com", Role: "project-owner"}}
+
+	report := New(SourceRepo, repo, live)
+	if len(report.Projects) != 1 || len(report.Projects[0].Entries) != 1 {
+		t.Fatalf("expected only the repository grants, got %+v", report.Projects)
+	}
+	entry := report.Projects[0].Entries[0]
+	if entry.RepoRole != "project-owner, project-viewer" || entry.LiveRole != "" || entry.Status != "" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if markdown := report.Markdown(); !strings.Contains(markdown, "| alice@example.com | project-owner, project-viewer |") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
+
+func TestRender(t *testing.T) {
+	report := New(SourceBoth,
+		[]Grant{{Project: "payments", Principal: "alice@example.com", Role: "project-owner"}},
+		[]Grant{{Project: "payments", Principal: "alice@example.com", Role: "project-viewer"}})
+
+	data, err := report.Render("csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "project,user,repo_role,live_role,status\npayments,alice@example.com,project-owner,project-viewer,role differs\n"
+	if string(data) != expected {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expected, data)
+	}
+
+	data, err = report.Render("json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Source != SourceBoth || len(decoded.Projects) != 1 {
+		t.Errorf("unexpected JSON %s (%v)", data, err)
+	}
+
+	if _, err := report.Render("html"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	if source, err := ParseSource(" Both "); err != nil || source != SourceBoth || !source.Repo() || !source.Live() {
+		t.Errorf("expected both, got %q (%v)", source, err)
+	}
+	if _, err := ParseSource("git"); err == nil {
+		t.Error("expected error for an unknown source")
+	}
+}
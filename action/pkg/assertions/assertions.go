@@ -0,0 +1,414 @@
+package assertions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/fieldpath"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDir is the directory the test command reads test files from
+const DefaultDir = ".nobl9/tests"
+
+// Suite is the tests declared in the test files of a directory
+type Suite struct {
+	Tests []*Test
+}
+
+// Test is an expectation about the objects of one kind, e.g. that a project
+// has an owner role binding or that every SLO uses a 28 day window. The
+// objects of the kind matching every where field are selected; the test
+// passes when their number is within count and each of them has every
+// expect field.
+type Test struct {
+	Name string `yaml:"name" json:"name"`
+	// Objects is the kind the test selects, e.g. RoleBinding
+	Objects string                 `yaml:"objects" json:"objects"`
+	Where   map[string]interface{} `yaml:"where,omitempty" json:"where,omitempty"`
+	Expect  map[string]interface{} `yaml:"expect,omitempty" json:"expect,omitempty"`
+	Count   *Count                 `yaml:"count,omitempty" json:"count,omitempty"`
+
+	// Source is the test file declaring the test
+	Source string `yaml:"-" json:"source"`
+
+	kind   manifest.Kind
+	where  []field
+	expect []field
+}
+
+// Count bounds the number of selected objects; a nil bound is not checked
+type Count struct {
+	Min *int `yaml:"min,omitempty" json:"min,omitempty"`
+	Max *int `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// Result is the outcome of a test
+type Result struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Passed bool   `json:"passed"`
+	// Selected is the number of objects the test selected
+	Selected int      `json:"selected"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Report is the outcome of every test of a suite, in the order the tests
+// were declared
+type Report struct {
+	Results []Result `json:"results"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+}
+
+// file is the content of a test file
+type file struct {
+	Tests []*Test `yaml:"tests"`
+}
+
+// field is a where or expect entry: a parsed path and the expected value
+// as plain JSON
+type field struct {
+	path     string
+	segments []fieldpath.Segment
+	value    interface{}
+}
+
+// Load reads the test files, *.yaml and *.yml, of a directory and its
+// subdirectories. Unknown keys are rejected so a typo does not silently
+// disable a test.
+func Load(dir string) (*Suite, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("failed to read tests directory %s", dir), err)
+	}
+	if !info.IsDir() {
+		return nil, errors.NewConfigError(fmt.Sprintf("tests directory %s is not a directory", dir), nil)
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("failed to read tests directory %s", dir), err)
+	}
+	sort.Strings(paths)
+
+	suite := &Suite{}
+	names := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("failed to read test file %s", path), err)
+		}
+		tests, err := parse(data, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, test := range tests {
+			if previous, found := names[test.Name]; found {
+				return nil, errors.NewConfigError(fmt.Sprintf("invalid test file %s: test %q is already declared in %s", path, test.Name, previous), nil)
+			}
+			names[test.Name] = path
+			suite.Tests = append(suite.Tests, test)
+		}
+	}
+	return suite, nil
+}
+
+// Parse parses and checks the tests of a test file
+func Parse(data []byte) ([]*Test, error) {
+	return parse(data, "")
+}
+
+// parse parses and checks the tests of a test file. Errors name the file,
+// if any, and the test at fault.
+func parse(data []byte, path string) ([]*Test, error) {
+	invalid := "invalid test file"
+	if path != "" {
+		invalid = fmt.Sprintf("invalid test file %s", path)
+	}
+
+	var content file
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&content); err != nil {
+		return nil, errors.NewConfigError(invalid, err)
+	}
+
+	for i, test := range content.Tests {
+		if test == nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("%s: test %d is empty", invalid, i+1), nil)
+		}
+		if err := test.compile(); err != nil {
+			name := test.Name
+			if name == "" {
+				name = strconv.Itoa(i + 1)
+			}
+			return nil, errors.NewConfigError(fmt.Sprintf("%s: test %s: %v", invalid, name, err), nil)
+		}
+		test.Source = path
+	}
+	return content.Tests, nil
+}
+
+// compile checks a test and parses its fields
+func (t *Test) compile() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	kind, err := manifest.ParseKind(t.Objects)
+	if err != nil {
+		return fmt.Errorf("objects: unknown kind %q", t.Objects)
+	}
+	t.kind = kind
+	if t.Count == nil && len(t.Expect) == 0 {
+		return fmt.Errorf("count or expect is required")
+	}
+	if t.Count != nil {
+		if t.Count.Min == nil && t.Count.Max == nil {
+			return fmt.Errorf("count needs min or max")
+		}
+		if (t.Count.Min != nil && *t.Count.Min < 0) || (t.Count.Max != nil && *t.Count.Max < 0) {
+			return fmt.Errorf("count cannot be negative")
+		}
+		if t.Count.Min != nil && t.Count.Max != nil && *t.Count.Min > *t.Count.Max {
+			return fmt.Errorf("count min %d exceeds max %d", *t.Count.Min, *t.Count.Max)
+		}
+	}
+	if t.where, err = compileFields(t.Where); err != nil {
+		return fmt.Errorf("where: %w", err)
+	}
+	if t.expect, err = compileFields(t.Expect); err != nil {
+		return fmt.Errorf("expect: %w", err)
+	}
+	return nil
+}
+
+// compileFields parses the paths of where or expect entries, sorted by path
+func compileFields(entries map[string]interface{}) ([]field, error) {
+	fields := make([]field, 0, len(entries))
+	for path, value := range entries {
+		segments, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		normalized, err := plain(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		fields = append(fields, field{path: path, segments: segments, value: normalized})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].path < fields[j].path })
+	return fields, nil
+}
+
+// parsePath splits a dotted path, e.g. spec.timeWindows[*].unit, into
+// segments; .. is not supported, as every path starts at the top of the
+// object
+func parsePath(path string) ([]fieldpath.Segment, error) {
+	segments, err := fieldpath.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	for _, segment := range segments {
+		if segment.Recursive {
+			return nil, fmt.Errorf("invalid path %q: empty field name", path)
+		}
+	}
+	return segments, nil
+}
+
+// Evaluate runs every test against the objects
+func (s *Suite) Evaluate(items []planner.Item) (*Report, error) {
+	type object struct {
+		item  planner.Item
+		value interface{}
+	}
+	objects := make(map[manifest.Kind][]object)
+	for _, item := range items {
+		value, err := plain(item.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s %s: %w", item.Object.GetKind(), item.Object.GetName(), err)
+		}
+		kind := item.Object.GetKind()
+		objects[kind] = append(objects[kind], object{item: item, value: value})
+	}
+
+	report := &Report{Results: make([]Result, 0, len(s.Tests))}
+	for _, test := range s.Tests {
+		result := Result{Name: test.Name, Source: test.Source}
+		for _, obj := range objects[test.kind] {
+			if !selects(test.where, obj.value) {
+				continue
+			}
+			result.Selected++
+			for _, expected := range test.expect {
+				if failure := check(expected, obj.value); failure != "" {
+					result.Failures = append(result.Failures, fmt.Sprintf("%s (%s): %s", describe(obj.item.Object), obj.item.Source, failure))
+				}
+			}
+		}
+		if test.Count != nil {
+			if failure := test.Count.check(result.Selected, test.kind); failure != "" {
+				result.Failures = append(result.Failures, failure)
+			}
+		}
+
+		result.Passed = len(result.Failures) == 0
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// check describes how the count of selected objects misses its bounds, or
+// returns "" when it is within them
+func (c *Count) check(selected int, kind manifest.Kind) string {
+	if c.Min != nil && selected < *c.Min {
+		return fmt.Sprintf("found %d matching %s objects, expected at least %d", selected, kind, *c.Min)
+	}
+	if c.Max != nil && selected > *c.Max {
+		return fmt.Sprintf("found %d matching %s objects, expected at most %d", selected, kind, *c.Max)
+	}
+	return ""
+}
+
+// selects reports whether an object matches every where field. A field
+// with [*] matches when any element has the value.
+func selects(where []field, object interface{}) bool {
+	for _, f := range where {
+		matched := false
+		for _, value := range resolve(object, f.segments, "") {
+			if reflect.DeepEqual(value.value, f.value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// check describes the first value of an expect field that differs from the
+// expected one, or returns "" when every value matches. A field with [*]
+// must have the value in every element.
+func check(expected field, object interface{}) string {
+	values := resolve(object, expected.segments, "")
+	if len(values) == 0 {
+		return fmt.Sprintf("%s is not set, expected %s", expected.path, format(expected.value))
+	}
+	for _, value := range values {
+		if !reflect.DeepEqual(value.value, expected.value) {
+			return fmt.Sprintf("%s is %s, expected %s", value.path, format(value.value), format(expected.value))
+		}
+	}
+	return ""
+}
+
+// resolved is a value found at a path, with the path of the element it was
+// found in
+type resolved struct {
+	path  string
+	value interface{}
+}
+
+// resolve returns the values at the path below value; missing fields and
+// elements resolve to nothing
+func resolve(value interface{}, segments []fieldpath.Segment, path string) []resolved {
+	if len(segments) == 0 {
+		return []resolved{{path: path, value: value}}
+	}
+
+	current, rest := segments[0], segments[1:]
+	switch {
+	case current.Wildcard || current.IsIndex:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		if current.IsIndex {
+			if current.Index >= len(list) {
+				return nil
+			}
+			return resolve(list[current.Index], rest, fmt.Sprintf("%s[%d]", path, current.Index))
+		}
+		var values []resolved
+		for i, element := range list {
+			values = append(values, resolve(element, rest, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return values
+	default:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		child, found := fields[current.Name]
+		if !found {
+			return nil
+		}
+		if path != "" {
+			path += "."
+		}
+		return resolve(child, rest, path+current.Name)
+	}
+}
+
+// plain converts a value to plain JSON values, so objects and values
+// written in test files compare equal regardless of their Go types
+func plain(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var converted interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// format renders a value for failure messages
+func format(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// describe names an object for failure messages, e.g. SLO payments/latency
+func describe(obj manifest.Object) string {
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok && scoped.GetProject() != "" {
+		return fmt.Sprintf("%s %s/%s", obj.GetKind(), scoped.GetProject(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+}
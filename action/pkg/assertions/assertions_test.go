@@ -0,0 +1,184 @@
+package assertions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+const manifests = `- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: alice@example.com
+    roleRef: project-owner
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout-latency
+    project: payments
+  spec:
+    service: checkout
+    budgetingMethod: Occurrences
+    indicator:
+      metricSource:
+        name: prometheus
+    timeWindows:
+      - unit: Day
+        count: 28
+        isRolling: true
+    objectives:
+      - name: fast
+        displayName: Fast
+        value: 200
+        target: 0.99
+        op: lte
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: search-latency
+    project: search
+  spec:
+    service: search
+    budgetingMethod: Occurrences
+    indicator:
+      metricSource:
+        name: prometheus
+    timeWindows:
+      - unit: Day
+        count: 7
+        isRolling: true
+    objectives:
+      - name: fast
+        displayName: Fast
+        value: 200
+        target: 0.99
+        op: lte
+`
+
+const tests = `tests:
+  - name: payments has an owner
+    objects: RoleBinding
+    where:
+      spec.projectRef: payments
+      spec.roleRef: project-owner
+    count:
+      min: 1
+  - name: search has an owner
+    objects: RoleBinding
+    where:
+      spec.projectRef: search
+      spec.roleRef: project-owner
+    count:
+      min: 1
+  - name: SLOs use 28 day rolling windows
+    objects: SLO
+    expect:
+      spec.timeWindows[*].isRolling: true
+      spec.timeWindows[*].count: 28
+      spec.timeWindows[*].unit: Day
+  - name: payments SLOs use the fast objective
+    objects: slo
+    where:
+      metadata.project: payments
+    expect:
+      spec.objectives[0].name: fast
+`
+
+func items(t *testing.T) []planner.Item {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(manifests))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := make([]planner.Item, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, planner.Item{Object: obj, Source: "nobl9.yaml"})
+	}
+	return result
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "slo"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "slo", "windows.yaml"), []byte(tests), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a test"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	suite, err := Load(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suite.Tests) != 4 || suite.Tests[0].Source != filepath.Join(dir, "slo", "windows.yaml") {
+		t.Fatalf("expected the 4 tests of the test file, got %+v", suite.Tests)
+	}
+
+	report, err := suite.Evaluate(items(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Passed != 2 || report.Failed != 2 {
+		t.Fatalf("expected 2 passed and 2 failed tests, got %+v", report)
+	}
+
+	expected := map[string]string{
+		"payments has an owner":                "",
+		"search has an owner":                  "found 0 matching RoleBinding objects, expected at least 1",
+		"SLOs use 28 day rolling windows":      "SLO search/search-latency (nobl9.yaml): spec.timeWindows[0].count is 7, expected 28",
+		"payments SLOs use the fast objective": "",
+	}
+	for _, result := range report.Results {
+		failure := strings.Join(result.Failures, "; ")
+		if failure != expected[result.Name] {
+			t.Errorf("test %q: expected failures %q, got %q", result.Name, expected[result.Name], failure)
+		}
+		if result.Passed != (failure == "") {
+			t.Errorf("test %q: passed is %t with failures %q", result.Name, result.Passed, failure)
+		}
+	}
+	if report.Results[2].Selected != 2 || report.Results[3].Selected != 1 {
+		t.Errorf("expected the SLO tests to select 2 and 1 objects, got %+v", report.Results)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for content, message := range map[string]string{
+		"tests:\n  - name: a\n    objects: SLO\n    expects: {}\n":                                   "field expects not found",
+		"tests:\n  - objects: SLO\n    count: {min: 1}\n":                                            "test 1: name cannot be empty",
+		"tests:\n  - name: a\n    objects: Widget\n    count: {min: 1}\n":                            "unknown kind",
+		"tests:\n  - name: a\n    objects: SLO\n":                                                    "count or expect is required",
+		"tests:\n  - name: a\n    objects: SLO\n    count: {min: 2, max: 1}\n":                       "min 2 exceeds max 1",
+		"tests:\n  - name: a\n    objects: SLO\n    expect: {\"spec.windows[x]\": 1}\n":              "must be a number or *",
+		"tests:\n  - name: a\n    objects: SLO\n    expect: {spec..name: 1}\n":                       "empty field name",
+		"tests:\n  - name: a\n    objects: SLO\n    where: {\"spec.a[0\": 1}\n    count: {max: 1}\n": "unclosed [",
+	} {
+		if _, err := Parse([]byte(content)); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q for %q, got %v", message, content, err)
+		}
+	}
+
+	dir := t.TempDir()
+	test := "tests:\n  - name: a\n    objects: SLO\n    count: {max: 1}\n"
+	for _, name := range []string{"a.yaml", "b.yml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(test), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Errorf("expected a duplicate test name error, got %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+// Annotations recording the change, person and workflow run that last
+// applied an object
+const (
+	// CommitAnnotation is the commit SHA the object was applied from
+	CommitAnnotation = "github.com/commit"
+	// AuthorAnnotation is the GitHub user who triggered the workflow run
+	AuthorAnnotation = "github.com/author"
+	// WorkflowRunAnnotation links to the workflow run that applied the object
+	WorkflowRunAnnotation = "github.com/workflow-run"
+)
+
+// Action is what happened to an object
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionApply is recorded when the live object could not be read, so
+	// whether it was created or updated is unknown
+	ActionApply Action = "apply"
+)
+
+// Entry is one line of the audit log. Before and After are hashes of the
+// object in Nobl9 before and after the change; a created object has no
+// Before hash and a deleted one no After hash.
+type Entry struct {
+	Time        time.Time   `json:"time"`
+	Action      Action      `json:"action"`
+	Object      compare.Key `json:"object"`
+	Source      string      `json:"source,omitempty"`
+	Before      string      `json:"before,omitempty"`
+	After       string      `json:"after,omitempty"`
+	Commit      string      `json:"commit,omitempty"`
+	Author      string      `json:"author,omitempty"`
+	WorkflowRun string      `json:"workflow_run,omitempty"`
+}
+
+// Log appends entries to an audit log file, one JSON object per line.
+// Existing entries are never rewritten.
+type Log struct {
+	mu     sync.Mutex
+	file   *os.File
+	source provenance.Source
+}
+
+// Annotations returns the audit annotations of the workflow source; values
+// the environment does not provide are empty
+func Annotations(source provenance.Source) map[string]string {
+	return map[string]string{
+		CommitAnnotation:      source.SHA,
+		AuthorAnnotation:      source.Actor,
+		WorkflowRunAnnotation: source.RunURL,
+	}
+}
+
+// IgnoreFields returns the drift rules of the audit annotations, which
+// manifests do not declare
+func IgnoreFields() []string {
+	var fields []string
+	for _, key := range []string{CommitAnnotation, AuthorAnnotation, WorkflowRunAnnotation} {
+		fields = append(fields, fmt.Sprintf("metadata.annotations[%q]", key))
+	}
+	return fields
+}
+
+// Open opens the audit log at path for appending, creating the file and its
+// directory. Entries are stamped with the commit, author and workflow run
+// of source.
+func Open(path string, source provenance.Source) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: file, source: source}, nil
+}
+
+// Record appends the entries to the log, setting their time and workflow
+// source
+func (l *Log) Record(entries ...Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		entry.Time = now
+		entry.Commit = l.source.SHA
+		entry.Author = l.source.Actor
+		entry.WorkflowRun = l.source.RunURL
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit entry: %w", err)
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Applied returns the entries of objects applied from a source file, given
+// the live objects before the change. Objects identical to their live
+// version were not changed and get no entry. When live is nil the live
+// objects could not be read and every object is recorded as ActionApply.
+func Applied(objects []manifest.Object, live map[compare.Key]manifest.Object, source string) ([]Entry, error) {
+	var entries []Entry
+	for _, obj := range objects {
+		after, err := Hash(obj)
+		if err != nil {
+			return nil, err
+		}
+		entry := Entry{Action: ActionApply, Object: compare.KeyOf(obj), Source: source, After: after}
+
+		if live != nil {
+			previous, found := live[entry.Object]
+			if !found {
+				entry.Action = ActionCreate
+			} else {
+				if entry.Before, err = Hash(previous); err != nil {
+					return nil, err
+				}
+				if entry.Before == after {
+					continue
+				}
+				entry.Action = ActionUpdate
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Deleted returns the entry of a deleted live object
+func Deleted(obj manifest.Object) (Entry, error) {
+	before, err := Hash(obj)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Action: ActionDelete, Object: compare.KeyOf(obj), Before: before}, nil
+}
+
+// Hash returns the sha256 hash of an object without the fields Nobl9 sets,
+// such as status and timestamps, so a declared object and its live version
+// hash the same
+func Hash(obj manifest.Object) (string, error) {
+	value, err := drift.New().Normalize(obj)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
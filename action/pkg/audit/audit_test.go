@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/provenance"
+)
+
+const objects = `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: billing
+  spec:
+    description: Billing team
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: checkout
+  spec:
+    description: Checkout team
+`
+
+var source = provenance.Source{
+	SHA:    "0a1b2c3",
+	Actor:  "octocat",
+	RunURL: "https://github.com/acme/nobl9-config/actions/runs/42",
+}
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	decoded, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return decoded
+}
+
+func TestApplied(t *testing.T) {
+	desired := decode(t, objects)
+
+	changed := desired[1].(v1alphaProject.Project)
+	changed.Spec.Description = "Old description"
+	live := map[compare.Key]manifest.Object{
+		compare.KeyOf(desired[0]): desired[0],
+		compare.KeyOf(changed):    changed,
+	}
+
+	entries, err := Applied(desired, live, "payments.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the unchanged project to be left out, got %+v", entries)
+	}
+	if entries[0].Action != ActionUpdate || entries[0].Object.Name != "billing" ||
+		entries[0].Before == "" || entries[0].Before == entries[0].After {
+		t.Errorf("expected billing to be updated, got %+v", entries[0])
+	}
+	if entries[1].Action != ActionCreate || entries[1].Object.Name != "checkout" || entries[1].Before != "" {
+		t.Errorf("expected checkout to be created, got %+v", entries[1])
+	}
+
+	entries, err = Applied(desired, nil, "payments.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Action != ActionApply {
+			t.Errorf("expected an unknown live object to be applied, got %+v", entry)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	project := decode(t, objects)[0]
+	deleted, err := Deleted(project)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every run appends to the entries of earlier runs
+	for i := 0; i < 2; i++ {
+		log, err := Open(path, source)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := log.Record(deleted); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := log.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	entry := entries[1]
+	if entry.Action != ActionDelete || entry.Object.Name != "payments" || entry.After != "" || !strings.HasPrefix(entry.Before, "sha256:") {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Commit != source.SHA || entry.Author != source.Actor || entry.WorkflowRun != source.RunURL || entry.Time.IsZero() {
+		t.Errorf("expected the entry to be stamped with the workflow source, got %+v", entry)
+	}
+}
+
+func TestAnnotationsAreNotDrift(t *testing.T) {
+	desired := decode(t, objects)[:1]
+	marker := &ownership.Marker{Extra: Annotations(source)}
+	live := marker.Mark(desired, "payments.yaml")
+	if annotations := live[0].(v1alphaProject.Project).Metadata.Annotations; annotations[WorkflowRunAnnotation] != source.RunURL {
+		t.Fatalf("expected audit annotations, got %v", annotations)
+	}
+
+	fields, err := drift.ParseIgnoreFields(strings.Join(IgnoreFields(), ","))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, err := drift.NewWithIgnoreFields(fields).Detect([]planner.Item{{Object: desired[0]}}, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.HasDrift() {
+		t.Errorf("expected audit annotations to be ignored, got %+v", report.Drifted)
+	}
+}
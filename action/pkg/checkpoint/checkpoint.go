@@ -0,0 +1,145 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileVersion is the version of the checkpoint file format
+const fileVersion = 1
+
+// DefaultFile is where a run records its checkpoints unless configured
+// otherwise, relative to the workspace
+const DefaultFile = ".nobl9-checkpoint.json"
+
+// Checkpoint records the files a run completed, so that a run resumed after
+// a timeout or crash can skip them. It is safe for concurrent use.
+type Checkpoint struct {
+	mu sync.Mutex
+
+	Version   int       `json:"version"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Commit is the commit the run applied the files of
+	Commit string `json:"commit,omitempty"`
+	// Files are the completed files, by path
+	Files map[string]File `json:"files"`
+}
+
+// File is a file whose objects were all applied
+type File struct {
+	// Digest identifies the objects that were applied; a file whose objects
+	// changed since is not complete anymore
+	Digest      string    `json:"digest"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// New creates an empty checkpoint of a run of the given commit
+func New(commit string, startedAt time.Time) *Checkpoint {
+	return &Checkpoint{
+		Version:   fileVersion,
+		StartedAt: startedAt.UTC(),
+		Commit:    commit,
+		Files:     make(map[string]File),
+	}
+}
+
+// Load reads a checkpoint from a JSON file. A missing file is not an error
+// and yields nil, since the previous run may have completed or never
+// started.
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file: %w", err)
+	}
+	if c.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported checkpoint file version %d", c.Version)
+	}
+	if c.Files == nil {
+		c.Files = make(map[string]File)
+	}
+	return &c, nil
+}
+
+// Completed reports whether the file was completed with the same objects
+func (c *Checkpoint) Completed(path, digest string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, found := c.Files[path]
+	return found && file.Digest == digest
+}
+
+// Complete records a file whose objects were all applied
+func (c *Checkpoint) Complete(path, digest string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Files[path] = File{Digest: digest, CompletedAt: at.UTC()}
+}
+
+// Len returns the number of completed files
+func (c *Checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Files)
+}
+
+// Save writes the checkpoint to a JSON file. The file is replaced in a
+// single rename, so a run killed while saving leaves the previous
+// checkpoint behind rather than a truncated one.
+func (c *Checkpoint) Save(path string) error {
+	c.mu.Lock()
+	c.Version = fileVersion
+	c.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint file: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	temp, err := os.CreateTemp(dir, ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a checkpoint file; a missing file is not an error
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ci", "checkpoint.json")
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	c := New("abc123", started)
+	c.Complete("projects/payments.yaml", "sha256:1", started.Add(time.Minute))
+	if err := c.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Commit != "abc123" || !loaded.StartedAt.Equal(started) || loaded.Len() != 1 {
+		t.Errorf("unexpected checkpoint %+v", loaded)
+	}
+	if !loaded.Completed("projects/payments.yaml", "sha256:1") {
+		t.Error("expected the file to be completed")
+	}
+	if loaded.Completed("projects/payments.yaml", "sha256:2") {
+		t.Error("expected a file whose objects changed not to be completed")
+	}
+	if loaded.Completed("projects/billing.yaml", "sha256:1") {
+		t.Error("expected an unrecorded file not to be completed")
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary file left behind, got %d entries", len(entries))
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	c, err := Load(path)
+	if err != nil || c != nil {
+		t.Fatalf("expected no checkpoint and no error, got %+v, %v", c, err)
+	}
+	if c.Completed("projects/payments.yaml", "sha256:1") || c.Len() != 0 {
+		t.Error("expected a nil checkpoint to complete no file")
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("expected removing a missing file to succeed, got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := os.WriteFile(path, []byte(`{"version": 2, "files": {}}`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an unsupported version to be rejected")
+	}
+}
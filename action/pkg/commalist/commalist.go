@@ -0,0 +1,17 @@
+// Package commalist splits the comma separated lists of action inputs and
+// command flags, such as --export-projects or allowed-branches.
+package commalist
+
+import "strings"
+
+// Split splits a comma separated list into its trimmed items, dropping
+// empty ones, so "a, ,b," is [a b]. An empty list returns nil.
+func Split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,20 @@
+package commalist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := map[string][]string{
+		"":                       nil,
+		" , ":                    nil,
+		"main":                   {"main"},
+		"main, release/*, ,dev,": {"main", "release/*", "dev"},
+	}
+	for value, expected := range tests {
+		if items := Split(value); !reflect.DeepEqual(items, expected) {
+			t.Errorf("Split(%q) = %q, expected %q", value, items, expected)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/kindlist"
+)
+
+// DefaultKinds are the kinds compared between organizations by default
+var DefaultKinds = []manifest.Kind{
+	manifest.KindProject,
+	manifest.KindRoleBinding,
+	manifest.KindSLO,
+}
+
+// Key identifies an object within an organization
+type Key struct {
+	Kind    manifest.Kind `json:"kind"`
+	Project string        `json:"project,omitempty"`
+	Name    string        `json:"name"`
+}
+
+// String returns the key as kind project/name, or kind name for objects
+// outside a project
+func (k Key) String() string {
+	if k.Project == "" {
+		return fmt.Sprintf("%s %s", k.Kind, k.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", k.Kind, k.Project, k.Name)
+}
+
+// KindResult is the comparison of one kind
+type KindResult struct {
+	Kind         manifest.Kind `json:"kind"`
+	OnlyInSource []Key         `json:"only_in_source"`
+	OnlyInTarget []Key         `json:"only_in_target"`
+	Common       int           `json:"common"`
+}
+
+// Result is the comparison of two organizations
+type Result struct {
+	Source string       `json:"source"`
+	Target string       `json:"target"`
+	Kinds  []KindResult `json:"kinds"`
+}
+
+// ParseKinds parses a comma separated list of kinds to compare. An empty
+// list selects DefaultKinds.
+func ParseKinds(spec string) ([]manifest.Kind, error) {
+	kinds, err := kindlist.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kinds) == 0 {
+		return DefaultKinds, nil
+	}
+	return kinds, nil
+}
+
+// KeyOf returns the key of an object. Role bindings are keyed by name only,
+// since their names are unique within an organization.
+func KeyOf(obj manifest.Object) Key {
+	key := Key{Kind: obj.GetKind(), Name: obj.GetName()}
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok && obj.GetKind() != manifest.KindRoleBinding {
+		key.Project = scoped.GetProject()
+	}
+	return key
+}
+
+// Objects compares the objects of the given kinds in the source and target
+// organizations. Keys are sorted so results are stable between runs.
+func Objects(kinds []manifest.Kind, source, target []manifest.Object) []KindResult {
+	sourceKeys := keysByKind(source)
+	targetKeys := keysByKind(target)
+
+	results := make([]KindResult, 0, len(kinds))
+	for _, kind := range kinds {
+		result := KindResult{Kind: kind}
+		for key := range sourceKeys[kind] {
+			if targetKeys[kind][key] {
+				result.Common++
+			} else {
+				result.OnlyInSource = append(result.OnlyInSource, key)
+			}
+		}
+		for key := range targetKeys[kind] {
+			if !sourceKeys[kind][key] {
+				result.OnlyInTarget = append(result.OnlyInTarget, key)
+			}
+		}
+		sortKeys(result.OnlyInSource)
+		sortKeys(result.OnlyInTarget)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Differences returns the number of objects present in only one organization
+func (r *Result) Differences() int {
+	count := 0
+	for _, kind := range r.Kinds {
+		count += len(kind.OnlyInSource) + len(kind.OnlyInTarget)
+	}
+	return count
+}
+
+// keysByKind indexes object keys by kind
+func keysByKind(objects []manifest.Object) map[manifest.Kind]map[Key]bool {
+	keys := make(map[manifest.Kind]map[Key]bool)
+	for _, obj := range objects {
+		key := KeyOf(obj)
+		if keys[key.Kind] == nil {
+			keys[key.Kind] = make(map[Key]bool)
+		}
+		keys[key.Kind][key] = true
+	}
+	return keys
+}
+
+// sortKeys sorts keys by project, then name
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Project != keys[j].Project {
+			return keys[i].Project < keys[j].Project
+		}
+		return keys[i].Name < keys[j].Name
+	})
+}
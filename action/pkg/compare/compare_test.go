@@ -0,0 +1,94 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return objects
+}
+
+func TestObjects(t *testing.T) {
+	staging := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: sandbox
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u1
+    roleRef: project-owner
+    projectRef: payments
+`)
+	prod := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: billing
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u2
+    roleRef: project-owner
+    projectRef: payments
+`)
+
+	results := Objects(DefaultKinds, staging, prod)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 kinds, got %d", len(results))
+	}
+
+	projects := results[0]
+	if projects.Common != 1 || len(projects.OnlyInSource) != 1 || len(projects.OnlyInTarget) != 1 {
+		t.Fatalf("unexpected project comparison: %+v", projects)
+	}
+	if projects.OnlyInSource[0].Name != "sandbox" || projects.OnlyInTarget[0].Name != "billing" {
+		t.Errorf("unexpected project differences: %+v", projects)
+	}
+
+	// Role bindings match by name even when their users differ
+	if roleBindings := results[1]; roleBindings.Common != 1 || len(roleBindings.OnlyInSource)+len(roleBindings.OnlyInTarget) != 0 {
+		t.Errorf("unexpected role binding comparison: %+v", roleBindings)
+	}
+
+	// Kinds that were not requested are ignored
+	result := &Result{Kinds: results}
+	if result.Differences() != 2 {
+		t.Errorf("expected 2 differences, got %d", result.Differences())
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	if got := (Key{Kind: manifest.KindSLO, Project: "payments", Name: "latency"}).String(); got != "SLO payments/latency" {
+		t.Errorf("unexpected key: %s", got)
+	}
+	if got := (Key{Kind: manifest.KindProject, Name: "payments"}).String(); got != "Project payments" {
+		t.Errorf("unexpected key: %s", got)
+	}
+}
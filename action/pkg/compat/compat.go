@@ -0,0 +1,353 @@
+package compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// SupportedAPIVersion is the only Nobl9 API version the pinned SDK decodes
+const SupportedAPIVersion = "n9/v1alpha"
+
+// IssueType is the kind of incompatibility found in a manifest
+type IssueType string
+
+const (
+	// IssueAPIVersion is an object written for another n9 API version
+	IssueAPIVersion IssueType = "api_version"
+	// IssueRenamedField is a field the API has renamed
+	IssueRenamedField IssueType = "renamed_field"
+	// IssueUnsupportedField is a field the pinned SDK does not know, whose
+	// value would be dropped when the object is applied
+	IssueUnsupportedField IssueType = "unsupported_field"
+)
+
+// ActionFields are fields the SDK does not decode that the action reads
+// itself, so they are not reported as unsupported
+var ActionFields = map[string][]string{
+	"RoleBinding": {"spec.users", "spec.userIds"},
+}
+
+// Issue is an incompatibility of a manifest with the pinned SDK
+type Issue struct {
+	Type     IssueType
+	Document int    // 1-based index of the YAML document
+	Line     int    // line of the field, or of the object
+	Kind     string // kind of the object
+	Name     string // name of the object
+	Field    string // path of the field, e.g. "spec.thresholds"
+	Message  string
+	// Migrated is true when the field was rewritten by Check, so the
+	// issue no longer fails the manifest
+	Migrated bool
+}
+
+// String describes the issue for errors and logs, e.g. "document 2, line 9:
+// SLO 'latency' spec.thresholds was renamed to spec.objectives"
+func (i Issue) String() string {
+	object := i.Kind
+	if i.Name != "" {
+		object += " '" + i.Name + "'"
+	}
+	if i.Field != "" {
+		object += " " + i.Field
+	}
+	return fmt.Sprintf("document %d, line %d: %s %s", i.Document, i.Line, object, i.Message)
+}
+
+// Result is the outcome of checking a manifest
+type Result struct {
+	// Content is the manifest with the renamed fields migrated, or the
+	// content checked when nothing was migrated
+	Content []byte
+	Issues  []Issue
+}
+
+// Migrated returns the issues Check fixed by migrating a field
+func (r *Result) Migrated() []Issue {
+	var migrated []Issue
+	for _, issue := range r.Issues {
+		if issue.Migrated {
+			migrated = append(migrated, issue)
+		}
+	}
+	return migrated
+}
+
+// Err returns an error listing the issues that were not migrated, or nil
+// when the manifest is compatible
+func (r *Result) Err() error {
+	var failed []string
+	for _, issue := range r.Issues {
+		if !issue.Migrated {
+			failed = append(failed, issue.String())
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("manifest is not compatible with the %s API of the pinned Nobl9 SDK: %s", SupportedAPIVersion, strings.Join(failed, "; "))
+}
+
+// Check checks the n9 objects of a manifest against the API version and
+// the fields the pinned SDK decodes. The SDK silently drops fields it does
+// not know, so every such field with a value is reported rather than lost.
+// With migrate, renamed fields listed in Renames are rewritten to their new
+// name; the returned content is then re-encoded. Documents that are not n9
+// objects, such as ActionMeta, are left alone, and documents the SDK cannot
+// decode are left for it to report.
+func Check(content []byte, migrate bool) (*Result, error) {
+	result := &Result{Content: content}
+	var documents []*yaml.Node
+	migrated := false
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for document := 1; ; document++ {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse document %d: %w", document, err)
+		}
+		documents = append(documents, &root)
+		if len(root.Content) == 0 {
+			continue
+		}
+
+		objects := []*yaml.Node{root.Content[0]}
+		if root.Content[0].Kind == yaml.SequenceNode {
+			objects = root.Content[0].Content
+		}
+		for _, object := range objects {
+			issues, changed := checkObject(object, document, migrate)
+			result.Issues = append(result.Issues, issues...)
+			migrated = migrated || changed
+		}
+	}
+
+	if migrated {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		for _, document := range documents {
+			// A document read from JSON must not start with [ or { once
+			// encoded, or it would be decoded as JSON again
+			if len(document.Content) > 0 {
+				document.Content[0].Style &^= yaml.FlowStyle
+			}
+			if err := encoder.Encode(document); err != nil {
+				return nil, fmt.Errorf("failed to encode migrated manifest: %w", err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode migrated manifest: %w", err)
+		}
+		result.Content = buf.Bytes()
+	}
+	return result, nil
+}
+
+// checkObject checks a single object, migrating its renamed fields with
+// migrate, and reports whether it changed
+func checkObject(object *yaml.Node, document int, migrate bool) ([]Issue, bool) {
+	if object.Kind != yaml.MappingNode {
+		return nil, false
+	}
+	apiVersion := yamlnode.ScalarValue(yamlnode.MappingValue(object, "apiVersion"))
+	if !strings.HasPrefix(apiVersion, "n9/") {
+		return nil, false
+	}
+	kind := yamlnode.ScalarValue(yamlnode.MappingValue(object, "kind"))
+	issue := Issue{
+		Document: document,
+		Line:     object.Line,
+		Kind:     kind,
+		Name:     yamlnode.ScalarValue(yamlnode.MappingValue(yamlnode.MappingValue(object, "metadata"), "name")),
+	}
+
+	if apiVersion != SupportedAPIVersion {
+		issue.Type = IssueAPIVersion
+		issue.Field = "apiVersion"
+		issue.Line = mappingKey(object, "apiVersion").Line
+		issue.Message = fmt.Sprintf("is %s, %s than the %s the pinned Nobl9 SDK supports", apiVersion, versionAge(apiVersion), SupportedAPIVersion)
+		return []Issue{issue}, false
+	}
+
+	var issues []Issue
+	changed := false
+	reported := make(map[string]bool)
+	for _, rename := range Renames {
+		if rename.Kind.String() != kind {
+			continue
+		}
+		renamed, ok := rename.apply(object, migrate)
+		if !ok {
+			continue
+		}
+		renamed.Document, renamed.Kind, renamed.Name = document, issue.Kind, issue.Name
+		issues = append(issues, renamed)
+		reported[rename.From] = true
+		changed = changed || renamed.Migrated
+	}
+
+	// Compare the fields written with the fields the SDK decoded
+	encoded, err := yaml.Marshal(object)
+	if err != nil {
+		return issues, changed
+	}
+	decoded, err := sdk.DecodeObjects(encoded)
+	if err != nil || len(decoded) != 1 {
+		return issues, changed
+	}
+	data, err := json.Marshal(decoded[0])
+	if err != nil {
+		return issues, changed
+	}
+	var kept interface{}
+	if err := json.Unmarshal(data, &kept); err != nil {
+		return issues, changed
+	}
+	droppedFields(object, kept, "", func(field string, key *yaml.Node) {
+		if reported[field] || isActionField(kind, field) {
+			return
+		}
+		unsupported := issue
+		unsupported.Type = IssueUnsupportedField
+		unsupported.Field = field
+		unsupported.Line = key.Line
+		unsupported.Message = fmt.Sprintf("is not supported by %s of the pinned Nobl9 SDK and would be dropped", SupportedAPIVersion)
+		issues = append(issues, unsupported)
+	})
+	return issues, changed
+}
+
+// droppedFields calls dropped for every field of node with a value that is
+// missing from kept, the object as the SDK decoded it. Fields holding zero
+// values are skipped: the SDK omits those when encoding whether it knows
+// them or not.
+func droppedFields(node *yaml.Node, kept interface{}, path string, dropped func(field string, key *yaml.Node)) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		droppedFields(node.Alias, kept, path, dropped)
+	case yaml.MappingNode:
+		fields, ok := kept.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			field := yamlnode.JoinPath(path, key.Value)
+			keptValue, ok := fields[key.Value]
+			if !ok {
+				if !isEmpty(value) {
+					dropped(field, key)
+				}
+				continue
+			}
+			droppedFields(value, keptValue, field, dropped)
+		}
+	case yaml.SequenceNode:
+		items, ok := kept.([]interface{})
+		if !ok || len(items) != len(node.Content) {
+			return
+		}
+		for i, item := range node.Content {
+			droppedFields(item, items[i], fmt.Sprintf("%s[%d]", path, i), dropped)
+		}
+	}
+}
+
+// isActionField reports whether field is or is below one of the
+// ActionFields of kind
+func isActionField(kind, field string) bool {
+	for _, actionField := range ActionFields[kind] {
+		if field == actionField || strings.HasPrefix(field, actionField+".") || strings.HasPrefix(field, actionField+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether node holds a zero value
+func isEmpty(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return isEmpty(node.Alias)
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!null":
+			return true
+		case "!!bool":
+			return node.Value == "false"
+		case "!!int", "!!float":
+			number, err := strconv.ParseFloat(node.Value, 64)
+			return err == nil && number == 0
+		}
+		return node.Value == ""
+	}
+	return false
+}
+
+// apiVersionPattern matches versions such as n9/v1alpha, n9/v1beta2 or n9/v2
+var apiVersionPattern = regexp.MustCompile(`^n9/v(\d+)(?:(alpha|beta)(\d*))?$`)
+
+// versionAge returns whether apiVersion is "older" or "newer" than
+// SupportedAPIVersion, ordering alpha before beta before stable releases of
+// the same major version, or "different" when it cannot be ordered
+func versionAge(apiVersion string) string {
+	version, ok := parseVersion(apiVersion)
+	supported, _ := parseVersion(SupportedAPIVersion)
+	if !ok {
+		return "different"
+	}
+	for i := range version {
+		if version[i] != supported[i] {
+			if version[i] < supported[i] {
+				return "older"
+			}
+			return "newer"
+		}
+	}
+	return "different"
+}
+
+// parseVersion returns the major version, stability (0 alpha, 1 beta, 2
+// stable) and revision of an n9 API version
+func parseVersion(apiVersion string) ([3]int, bool) {
+	match := apiVersionPattern.FindStringSubmatch(apiVersion)
+	if match == nil {
+		return [3]int{}, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	stability := map[string]int{"alpha": 0, "beta": 1, "": 2}[match[2]]
+	revision, _ := strconv.Atoi(match[3])
+	return [3]int{major, stability, revision}, true
+}
+
+// mappingKey returns the key node of key in a mapping node, or nil
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
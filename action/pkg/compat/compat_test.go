@@ -0,0 +1,161 @@
+package compat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sloManifest = `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  owner: payments
+---
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: latency
+  project: payments
+spec:
+  service: api
+  budgetingMethod: Occurrences
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+  thresholds:
+    - displayName: Good
+      value: 200
+      target: 0.99
+      op: lte
+      rawMetric:
+        query:
+          prometheus:
+            promql: latency
+`
+
+func TestCheckCompatibleManifest(t *testing.T) {
+	content := []byte(strings.Replace(sloManifest, "thresholds:", "objectives:", 1))
+	result, err := Check(content, false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Issues)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, content, result.Content)
+}
+
+func TestCheckRenamedField(t *testing.T) {
+	result, err := Check([]byte(sloManifest), false)
+	require.NoError(t, err)
+	require.Len(t, result.Issues, 1)
+	issue := result.Issues[0]
+	assert.Equal(t, IssueRenamedField, issue.Type)
+	assert.Equal(t, "document 2, line 21: SLO 'latency' spec.thresholds was renamed to spec.objectives", issue.String())
+	assert.False(t, issue.Migrated)
+	assert.ErrorContains(t, result.Err(), "spec.thresholds was renamed to spec.objectives")
+
+	result, err = Check([]byte(sloManifest), true)
+	require.NoError(t, err)
+	require.Len(t, result.Migrated(), 1)
+	assert.NoError(t, result.Err())
+
+	// The migrated manifest keeps the ActionMeta and the objectives the
+	// SDK would otherwise drop
+	assert.Contains(t, string(result.Content), "kind: ActionMeta")
+	objects, err := sdk.DecodeObjects(result.Content[strings.Index(string(result.Content), "apiVersion: n9/"):])
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Contains(t, mustJSON(t, objects[0]), `"promql":"latency"`)
+}
+
+func TestCheckRenamedFieldAlsoSet(t *testing.T) {
+	content := sloManifest + `  objectives:
+    - displayName: Good
+      value: 100
+      target: 0.9
+      op: lte
+      rawMetric:
+        query:
+          prometheus:
+            promql: latency
+`
+	result, err := Check([]byte(content), true)
+	require.NoError(t, err)
+	require.Len(t, result.Issues, 1)
+	assert.False(t, result.Issues[0].Migrated)
+	assert.ErrorContains(t, result.Err(), "which is also set")
+}
+
+func TestCheckUnsupportedFields(t *testing.T) {
+	result, err := Check([]byte(`- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    owner: payments-team
+  spec:
+    description: Payments
+    Description: Upper case keys are not decoded
+    retention: 0
+    archived: false
+`), false)
+	require.NoError(t, err)
+
+	var fields []string
+	for _, issue := range result.Issues {
+		assert.Equal(t, IssueUnsupportedField, issue.Type)
+		fields = append(fields, issue.Field)
+	}
+	// Zero values are not reported, the SDK omits them either way
+	assert.Equal(t, []string{"metadata.owner", "spec.Description"}, fields)
+	assert.Equal(t, 5, result.Issues[0].Line)
+	assert.ErrorContains(t, result.Err(), "would be dropped")
+}
+
+func TestCheckActionFields(t *testing.T) {
+	result, err := Check([]byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-owners
+spec:
+  projectRef: payments
+  roleRef: project-owner
+  users:
+    - id: alice@example.com
+`), false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Issues)
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	for apiVersion, age := range map[string]string{
+		"n9/v1beta":   "newer",
+		"n9/v1":       "newer",
+		"n9/v0alpha":  "older",
+		"n9/v1alpha0": "different",
+		"n9/latest":   "different",
+	} {
+		result, err := Check([]byte("apiVersion: "+apiVersion+"\nkind: Project\nmetadata:\n  name: payments\n"), true)
+		require.NoError(t, err)
+		require.Len(t, result.Issues, 1, apiVersion)
+		assert.Equal(t, IssueAPIVersion, result.Issues[0].Type)
+		assert.Contains(t, result.Issues[0].Message, age+" than the n9/v1alpha", apiVersion)
+	}
+}
+
+func TestCheckInvalidYAML(t *testing.T) {
+	_, err := Check([]byte("apiVersion: [n9/v1alpha"), false)
+	assert.Error(t, err)
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
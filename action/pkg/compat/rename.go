@@ -0,0 +1,64 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// Rename is a field the n9 API renamed. To names the new key under the
+// same parent as From.
+type Rename struct {
+	Kind manifest.Kind
+	From string // path of the old field, e.g. "spec.thresholds"
+	To   string // path of the new field, e.g. "spec.objectives"
+}
+
+// Renames are the known renamed fields Check migrates
+var Renames = []Rename{
+	{Kind: manifest.KindSLO, From: "spec.thresholds", To: "spec.objectives"},
+}
+
+// apply reports the renamed field when object holds it, renaming it with
+// migrate. A field is not migrated when the new field is also set, since
+// one of them would be lost.
+func (r Rename) apply(object *yaml.Node, migrate bool) (Issue, bool) {
+	parents, fromKey := splitParent(r.From)
+	_, toKey := splitParent(r.To)
+	parent := object
+	for _, segment := range parents {
+		if parent = yamlnode.MappingValue(parent, segment); parent == nil {
+			return Issue{}, false
+		}
+	}
+	key := mappingKey(parent, fromKey)
+	if key == nil {
+		return Issue{}, false
+	}
+
+	issue := Issue{
+		Type:    IssueRenamedField,
+		Line:    key.Line,
+		Field:   r.From,
+		Message: fmt.Sprintf("was renamed to %s", r.To),
+	}
+	if migrate {
+		if mappingKey(parent, toKey) != nil {
+			issue.Message = fmt.Sprintf("was renamed to %s, which is also set", r.To)
+		} else {
+			key.Value = toKey
+			issue.Migrated = true
+			issue.Message = fmt.Sprintf("was migrated to %s", r.To)
+		}
+	}
+	return issue, true
+}
+
+// splitParent splits a dotted path into its parent keys and last key
+func splitParent(path string) ([]string, string) {
+	keys := strings.Split(path, ".")
+	return keys[:len(keys)-1], keys[len(keys)-1]
+}
@@ -34,6 +34,12 @@ type Config struct {
 		Format string
 	}
 
+	// Okta integration (optional)
+	Okta struct {
+		Org   string
+		Token string
+	}
+
 	// GitHub Actions specific
 	GitHub struct {
 		Workspace string
@@ -66,6 +72,11 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load logging configuration: %w", err)
 	}
 
+	// Load Okta configuration
+	if err := config.loadOktaConfig(); err != nil {
+		return nil, fmt.Errorf("failed to load Okta configuration: %w", err)
+	}
+
 	// Load GitHub Actions configuration
 	if err := config.loadGitHubConfig(); err != nil {
 		return nil, fmt.Errorf("failed to load GitHub configuration: %w", err)
@@ -149,6 +160,18 @@ func (c *Config) loadLoggingConfig() error {
 	return nil
 }
 
+// loadOktaConfig loads the optional Okta integration configuration
+func (c *Config) loadOktaConfig() error {
+	c.Okta.Org = getEnv("INPUT_OKTA_ORG", getEnv("OKTA_ORG", ""))
+	c.Okta.Token = getEnv("INPUT_OKTA_TOKEN", getEnv("OKTA_TOKEN", ""))
+
+	if (c.Okta.Org == "") != (c.Okta.Token == "") {
+		return fmt.Errorf("okta org and okta token must be provided together")
+	}
+
+	return nil
+}
+
 // loadGitHubConfig loads GitHub Actions specific configuration
 func (c *Config) loadGitHubConfig() error {
 	c.GitHub.Workspace = getEnv("GITHUB_WORKSPACE", "")
@@ -229,6 +252,11 @@ func (c *Config) GetNobl9Credentials() (string, string) {
 	return c.Nobl9.ClientID, c.Nobl9.ClientSecret
 }
 
+// IsOktaEnabled returns true if Okta group expansion is configured
+func (c *Config) IsOktaEnabled() bool {
+	return c.Okta.Org != "" && c.Okta.Token != ""
+}
+
 // GetRepositoryPath returns the full repository path
 func (c *Config) GetRepositoryPath() string {
 	if c.IsGitHubActions() {
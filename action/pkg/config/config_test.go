@@ -59,6 +59,28 @@ func TestLoadConfig(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "okta org and token",
+			envVars: map[string]string{
+				"INPUT_CLIENT_ID":     "test-client-id",
+				"INPUT_CLIENT_SECRET": "test-client-secret",
+				"GITHUB_WORKSPACE":    "/workspace",
+				"INPUT_OKTA_ORG":      "acme",
+				"INPUT_OKTA_TOKEN":    "okta-token",
+			},
+			expectError: false,
+		},
+		{
+			name: "okta org without token",
+			envVars: map[string]string{
+				"INPUT_CLIENT_ID":     "test-client-id",
+				"INPUT_CLIENT_SECRET": "test-client-secret",
+				"GITHUB_WORKSPACE":    "/workspace",
+				"INPUT_OKTA_ORG":      "acme",
+			},
+			expectError: true,
+			errorMsg:    "okta org and okta token must be provided together",
+		},
 	}
 
 	for _, tt := range tests {
@@ -237,3 +259,38 @@ func TestGetRepositoryPath(t *testing.T) {
 		})
 	}
 }
+
+func TestVariables(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		`NOBL9_ACTION_VARS={"NOBL9_ACTION_FILE_PATTERN":"nobl9/**/*.yaml","NOBL9_ACTION_PRUNE":true,"NOBL9_ACTION_KINDS":"project","OTHER_VAR":"x","NOBL9_ACTION_POLICY":""}`,
+		"NOBL9_ACTION_KINDS=project,rolebinding",
+		"NOBL9_ACTION_MAX_RPS=5",
+	}
+
+	values, err := Variables(environ)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"file-pattern": "nobl9/**/*.yaml",
+		"prune":        "true",
+		"kinds":        "project,rolebinding",
+		"max-rps":      "5",
+	}
+	if len(values) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("expected %s=%s, got %q", name, value, values[name])
+		}
+	}
+
+	if _, err := Variables([]string{"NOBL9_ACTION_VARS=not json"}); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if name := VariableName("file-pattern"); name != "NOBL9_ACTION_FILE_PATTERN" {
+		t.Errorf("expected NOBL9_ACTION_FILE_PATTERN, got %s", name)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// VariablePrefix starts the names of the repository or organization
+// variables that configure the action, e.g. NOBL9_ACTION_FILE_PATTERN
+const VariablePrefix = "NOBL9_ACTION_"
+
+// VariablesEnv holds all variables of the workflow as a JSON object, set
+// with env: NOBL9_ACTION_VARS: ${{ toJSON(vars) }}
+const VariablesEnv = VariablePrefix + "VARS"
+
+// Variables returns the settings of the prefixed variables, keyed by flag
+// name: NOBL9_ACTION_FILE_PATTERN sets file-pattern. Variables are read from
+// the JSON object in NOBL9_ACTION_VARS and from environment variables of the
+// same names, which take precedence. environ is in the form of os.Environ.
+// Variables with an empty value are left out.
+func Variables(environ []string) (map[string]string, error) {
+	values := make(map[string]string)
+	env := make(map[string]string)
+	for _, entry := range environ {
+		name, value, found := strings.Cut(entry, "=")
+		if found && strings.HasPrefix(name, VariablePrefix) {
+			env[name] = value
+		}
+	}
+
+	if raw := env[VariablesEnv]; raw != "" {
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+			return nil, fmt.Errorf("%s is not a JSON object: %w", VariablesEnv, err)
+		}
+		for name, value := range vars {
+			if strings.HasPrefix(name, VariablePrefix) {
+				values[flagName(name)] = fmt.Sprint(value)
+			}
+		}
+	}
+	for name, value := range env {
+		if name != VariablesEnv {
+			values[flagName(name)] = value
+		}
+	}
+
+	for name, value := range values {
+		if strings.TrimSpace(value) == "" {
+			delete(values, name)
+		}
+	}
+	return values, nil
+}
+
+// VariableName returns the variable setting a flag, e.g.
+// NOBL9_ACTION_FILE_PATTERN for file-pattern
+func VariableName(flag string) string {
+	return VariablePrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// flagName returns the flag a variable sets
+func flagName(variable string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(variable, VariablePrefix), "_", "-"))
+}
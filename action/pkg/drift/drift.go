@@ -0,0 +1,285 @@
+package drift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+)
+
+// Statuses of drifted objects
+const (
+	// StatusChanged is an object whose live fields differ from the repository
+	StatusChanged = "changed"
+	// StatusMissing is an object declared in the repository but absent from Nobl9
+	StatusMissing = "missing"
+)
+
+// Change is a field whose live value differs from the repository. Desired is
+// nil for fields only set in Nobl9 and Live is nil for fields missing from
+// Nobl9.
+type Change struct {
+	Path    string      `json:"path"`
+	Desired interface{} `json:"desired"`
+	Live    interface{} `json:"live"`
+}
+
+// Object is a drifted object with the file declaring it
+type Object struct {
+	compare.Key
+	Source  string   `json:"source"`
+	Status  string   `json:"status"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Report is the drift of the objects declared in the repository
+type Report struct {
+	Checked int      `json:"checked"`
+	Drifted []Object `json:"drifted"`
+}
+
+// Detector compares declared objects with their live definitions
+type Detector struct {
+	ignore []IgnoreField
+}
+
+// New creates a detector ignoring the DefaultIgnoreFields
+func New() *Detector {
+	fields, err := ParseIgnoreFields(strings.Join(DefaultIgnoreFields, ","))
+	if err != nil {
+		panic(err)
+	}
+	return NewWithIgnoreFields(fields)
+}
+
+// NewWithIgnoreFields creates a detector ignoring the given fields only
+func NewWithIgnoreFields(fields []IgnoreField) *Detector {
+	return &Detector{ignore: fields}
+}
+
+// Detect compares each declared object with its live definition. Both are
+// normalized first: ignored fields, empty values and key order do not
+// count. Drifted objects are sorted by kind, project and name.
+func (d *Detector) Detect(desired []planner.Item, live []manifest.Object) (*Report, error) {
+	liveByKey := make(map[compare.Key]manifest.Object, len(live))
+	for _, obj := range live {
+		liveByKey[compare.KeyOf(obj)] = obj
+	}
+
+	report := &Report{Drifted: []Object{}}
+	for _, item := range desired {
+		key := compare.KeyOf(item.Object)
+		report.Checked++
+
+		liveObject, found := liveByKey[key]
+		if !found {
+			report.Drifted = append(report.Drifted, Object{Key: key, Source: item.Source, Status: StatusMissing})
+			continue
+		}
+
+		changes, err := d.Diff(item.Object, liveObject)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if len(changes) > 0 {
+			report.Drifted = append(report.Drifted, Object{Key: key, Source: item.Source, Status: StatusChanged, Changes: changes})
+		}
+	}
+
+	sort.SliceStable(report.Drifted, func(i, j int) bool {
+		a, b := report.Drifted[i].Key, report.Drifted[j].Key
+		if a.Kind != b.Kind {
+			return a.Kind.String() < b.Kind.String()
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// Diff returns the field-level differences between a declared object and
+// its live definition, sorted by path
+func (d *Detector) Diff(desired, live manifest.Object) ([]Change, error) {
+	desiredValue, err := d.Normalize(desired)
+	if err != nil {
+		return nil, err
+	}
+	liveValue, err := d.Normalize(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	diffValues("", desiredValue, liveValue, &changes)
+	return changes, nil
+}
+
+// Hash digests the normalized form of an object, so a declared object and
+// its live definition hash the same when they do not differ
+func (d *Detector) Hash(obj manifest.Object) (string, error) {
+	value, err := d.Normalize(obj)
+	if err != nil {
+		return "", err
+	}
+	// encoding/json sorts map keys, so the encoding is stable
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HasDrift reports whether any object drifted
+func (r *Report) HasDrift() bool {
+	return len(r.Drifted) > 0
+}
+
+// Markdown renders the report for a pull request comment or job summary
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Nobl9 Drift Report\n\n")
+	if !r.HasDrift() {
+		fmt.Fprintf(&b, "No drift: all %d objects match the repository.\n", r.Checked)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%d of %d objects drifted from the repository.\n", len(r.Drifted), r.Checked)
+	for _, object := range r.Drifted {
+		fmt.Fprintf(&b, "\n### %s\n\n", object.Key)
+		fmt.Fprintf(&b, "Declared in `%s`.", object.Source)
+		if object.Status == StatusMissing {
+			b.WriteString(" Missing from Nobl9.\n")
+			continue
+		}
+		b.WriteString("\n\n| Field | Repository | Nobl9 |\n|-------|------------|-------|\n")
+		for _, change := range object.Changes {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", change.Path, MarkdownValue(change.Desired), MarkdownValue(change.Live))
+		}
+	}
+	return b.String()
+}
+
+// FormatValue renders a field value for reports, or "(not set)" for nil
+func FormatValue(value interface{}) string {
+	if value == nil {
+		return "(not set)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// MarkdownValue renders a field value for a markdown table cell, as the
+// drift and manifest diff reports show it
+func MarkdownValue(value interface{}) string {
+	if value == nil {
+		return "_not set_"
+	}
+	return "`" + strings.ReplaceAll(FormatValue(value), "|", "\\|") + "`"
+}
+
+// Normalize converts an object to plain JSON values without the ignored
+// fields and without empty values
+func (d *Detector) Normalize(obj manifest.Object) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	removeIgnored(d.ignore, obj.GetKind(), value)
+	return prune(value), nil
+}
+
+// prune drops empty strings, maps and lists, returning nil when nothing is
+// left
+func prune(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if pruned := prune(field); pruned == nil {
+				delete(v, key)
+			} else {
+				v[key] = pruned
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, element := range v {
+			v[i] = prune(element)
+		}
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// diffValues appends the differences between two normalized values
+func diffValues(path string, desired, live interface{}, changes *[]Change) {
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	liveMap, liveIsMap := live.(map[string]interface{})
+	if desiredIsMap && liveIsMap {
+		keys := make(map[string]bool, len(desiredMap)+len(liveMap))
+		for key := range desiredMap {
+			keys[key] = true
+		}
+		for key := range liveMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			diffValues(yamlnode.JoinPath(path, key), desiredMap[key], liveMap[key], changes)
+		}
+		return
+	}
+
+	desiredList, desiredIsList := desired.([]interface{})
+	liveList, liveIsList := live.([]interface{})
+	if desiredIsList && liveIsList {
+		for i := 0; i < len(desiredList) || i < len(liveList); i++ {
+			var desiredElement, liveElement interface{}
+			if i < len(desiredList) {
+				desiredElement = desiredList[i]
+			}
+			if i < len(liveList) {
+				liveElement = liveList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), desiredElement, liveElement, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(desired, live) {
+		*changes = append(*changes, Change{Path: path, Desired: desired, Live: live})
+	}
+}
@@ -0,0 +1,285 @@
+package drift
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return objects
+}
+
+func items(objects []manifest.Object, source string) []planner.Item {
+	result := make([]planner.Item, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, planner.Item{Object: obj, Source: source})
+	}
+	return result
+}
+
+const desiredObjects = `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-alice
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+`
+
+func TestDetect(t *testing.T) {
+	live := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  organization: acme
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+      cost-center: [cc-1]
+  spec:
+    description: Payments
+    createdAt: "2024-05-01T12:00:00Z"
+    createdBy: 00u1admin
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  organization: acme
+  metadata:
+    name: payments-alice
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+`)
+
+	report, err := New().Detect(items(decode(t, desiredObjects), "nobl9/payments.yaml"), live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.Checked != 3 || !report.HasDrift() || len(report.Drifted) != 2 {
+		t.Fatalf("expected 2 of 3 objects to drift, got %+v", report)
+	}
+
+	project := report.Drifted[0]
+	if project.Kind != manifest.KindProject || project.Status != StatusChanged || project.Source != "nobl9/payments.yaml" {
+		t.Fatalf("unexpected project drift: %+v", project)
+	}
+	var changes []string
+	for _, change := range project.Changes {
+		changes = append(changes, change.Path+"="+FormatValue(change.Desired)+">"+FormatValue(change.Live))
+	}
+	expected := []string{
+		`metadata.labels.cost-center=(not set)>["cc-1"]`,
+		`spec.description="Payments team">"Payments"`,
+	}
+	if strings.Join(changes, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+
+	service := report.Drifted[1]
+	if service.Kind != manifest.KindService || service.Project != "payments" || service.Status != StatusMissing {
+		t.Errorf("unexpected service drift: %+v", service)
+	}
+
+	markdown := report.Markdown()
+	for _, want := range []string{
+		"2 of 3 objects drifted",
+		"### Project payments",
+		"| `spec.description` | `\"Payments team\"` | `\"Payments\"` |",
+		"| `metadata.labels.cost-center` | _not set_ | `[\"cc-1\"]` |",
+		"### Service payments/checkout",
+		"Missing from Nobl9.",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in report:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestDetectNoDrift(t *testing.T) {
+	desired := decode(t, desiredObjects)
+
+	report, err := New().Detect(items(desired, "nobl9/payments.yaml"), desired)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.HasDrift() {
+		t.Fatalf("expected no drift, got %+v", report.Drifted)
+	}
+	if !strings.Contains(report.Markdown(), "No drift: all 3 objects match") {
+		t.Errorf("unexpected report:\n%s", report.Markdown())
+	}
+}
+
+func TestHash(t *testing.T) {
+	desired := decode(t, desiredObjects)[0]
+	live := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  organization: acme
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Payments team
+    createdAt: "2024-05-01T12:00:00Z"
+    createdBy: 00u1admin
+`)[0]
+	changed := decode(t, strings.Replace(desiredObjects, "Payments team", "Payments", 1))[0]
+
+	hash := func(obj manifest.Object) string {
+		t.Helper()
+		value, err := New().Hash(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return value
+	}
+	if hash(desired) != hash(live) {
+		t.Error("expected fields set by Nobl9 not to change the hash")
+	}
+	if hash(desired) == hash(changed) {
+		t.Error("expected a changed description to change the hash")
+	}
+}
+
+func TestDiffLists(t *testing.T) {
+	desired := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments, billing]
+`)
+	live := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+`)
+
+	changes, err := New().Diff(desired[0], live[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "metadata.labels.team[1]" || changes[0].Desired != "billing" || changes[0].Live != nil {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+}
+
+func TestIgnoreFields(t *testing.T) {
+	desired := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Checkout
+`)
+	live := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments, billing]
+      cost-center: [cc-1]
+  spec:
+    description: Edited in the UI
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+    labels:
+      team: [checkout]
+  spec:
+    description: Checkout
+`)
+
+	fields, err := ParseIgnoreFields("project:metadata.labels.cost-center\nproject:$.metadata.labels.team[1], ..description")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report, err := NewWithIgnoreFields(fields).Detect(items(desired, "nobl9/payments.yaml"), live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the service label drifted: the project fields and every
+	// description are ignored, and the rules for projects do not apply to
+	// services
+	if len(report.Drifted) != 1 || report.Drifted[0].Kind != manifest.KindService {
+		t.Fatalf("expected only the service to drift, got %+v", report.Drifted)
+	}
+	if changes := report.Drifted[0].Changes; len(changes) != 1 || changes[0].Path != "metadata.labels.team[0]" {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+}
+
+func TestParseIgnoreFields(t *testing.T) {
+	for _, spec := range []string{"unknown:spec", "spec.objectives[x]", "spec[0", "metadata..", "slo:", `metadata.annotations["github.com`} {
+		if _, err := ParseIgnoreFields(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+
+	fields, err := ParseIgnoreFields(" slo:spec.objectives[*].rawMetric ,, ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fields) != 1 || fields[0].Kind != manifest.KindSLO || fields[0].Path != "spec.objectives[*].rawMetric" {
+		t.Errorf("unexpected fields: %+v", fields)
+	}
+
+	fields, err = ParseIgnoreFields(`metadata.annotations["github.com/commit"].x`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, segment := range fields[0].segments {
+		names = append(names, segment.Name)
+	}
+	if strings.Join(names, "|") != "metadata|annotations|github.com/commit|x" {
+		t.Errorf("expected the quoted name to be one segment, got %v", names)
+	}
+}
@@ -0,0 +1,119 @@
+package drift
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/fieldpath"
+)
+
+// IgnoreField is a field left out of comparisons. Kind is zero for fields
+// ignored in objects of every kind.
+type IgnoreField struct {
+	Kind manifest.Kind
+	Path string
+
+	segments []fieldpath.Segment
+}
+
+// DefaultIgnoreFields are set by Nobl9 rather than by manifests: the
+// organization, source and status at the top level of an object and the
+// audit timestamps at any depth
+var DefaultIgnoreFields = []string{
+	"organization",
+	"manifestSrc",
+	"status",
+	"..createdAt",
+	"..createdBy",
+	"..updatedAt",
+	"..updatedBy",
+}
+
+// ParseIgnoreFields parses comma or newline separated [kind:]path rules.
+// Paths are dotted field names starting at the top of the object, e.g.
+// spec.description; [*] or * matches any list element or field, [n] the
+// element at index n, ["name"] a field whose name contains dots, and a
+// leading .. matches the path at any depth.
+// Rules without a kind apply to every kind, e.g.
+//
+//	slo:spec.objectives[*].rawMetric, ..lastUpdated, metadata.annotations
+func ParseIgnoreFields(spec string) ([]IgnoreField, error) {
+	var fields []IgnoreField
+	for _, rule := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		var field IgnoreField
+		if kindName, path, found := strings.Cut(rule, ":"); found {
+			kind, err := manifest.ParseKind(strings.TrimSpace(kindName))
+			if err != nil {
+				return nil, fmt.Errorf("ignore field '%s': unknown kind '%s'", rule, kindName)
+			}
+			field.Kind = kind
+			rule = strings.TrimSpace(path)
+		}
+
+		segments, err := fieldpath.Parse(rule)
+		if err != nil {
+			return nil, fmt.Errorf("ignore field '%s': %w", rule, err)
+		}
+		field.Path = rule
+		field.segments = segments
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// removeIgnored deletes the ignored fields from a normalized object of the
+// given kind
+func removeIgnored(fields []IgnoreField, kind manifest.Kind, value interface{}) {
+	for _, field := range fields {
+		if field.Kind == 0 || field.Kind == kind {
+			removePath(value, field.segments)
+		}
+	}
+}
+
+// removePath deletes the fields matching the path below value. Removed
+// list elements are set to nil so the indexes of the others do not change.
+func removePath(value interface{}, path []fieldpath.Segment) {
+	if len(path) == 0 {
+		return
+	}
+	current := path[0]
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if current.Recursive {
+				removePath(child, path)
+			}
+			if !current.Matches(key) {
+				continue
+			}
+			if len(path) == 1 {
+				delete(v, key)
+			} else {
+				removePath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if current.Recursive {
+				removePath(child, path)
+			}
+			if !current.Matches(strconv.Itoa(i)) {
+				continue
+			}
+			if len(path) == 1 {
+				v[i] = nil
+			} else {
+				removePath(child, path[1:])
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"gopkg.in/yaml.v3"
+)
+
+// UnifiedDiffContext is the number of unchanged lines shown around each
+// change of a unified diff
+const UnifiedDiffContext = 3
+
+// UnifiedDiff renders what applying the desired objects would change as a
+// unified diff, one file section per object from its live YAML (a/) to its
+// desired YAML (b/). Objects missing from Nobl9 are diffed against
+// /dev/null and unchanged objects are left out. Both sides are normalized
+// like Detect, so ignored fields and key order make no difference. It
+// returns the diff and the number of objects it covers.
+func (d *Detector) UnifiedDiff(desired []planner.Item, live []manifest.Object) (string, int, error) {
+	liveByKey := make(map[compare.Key]manifest.Object, len(live))
+	for _, obj := range live {
+		liveByKey[compare.KeyOf(obj)] = obj
+	}
+
+	sorted := append([]planner.Item(nil), desired...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := compare.KeyOf(sorted[i].Object), compare.KeyOf(sorted[j].Object)
+		if a.Kind != b.Kind {
+			return a.Kind.String() < b.Kind.String()
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Name < b.Name
+	})
+
+	var b strings.Builder
+	changed := 0
+	for _, item := range sorted {
+		key := compare.KeyOf(item.Object)
+		path := diffPath(key)
+
+		desiredYAML, err := d.yamlOf(item.Object)
+		if err != nil {
+			return "", 0, err
+		}
+
+		fromFile, liveYAML := "/dev/null", ""
+		if liveObject, found := liveByKey[key]; found {
+			fromFile = "a/" + path
+			if liveYAML, err = d.yamlOf(liveObject); err != nil {
+				return "", 0, err
+			}
+		}
+		if liveYAML == desiredYAML {
+			continue
+		}
+
+		text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(liveYAML),
+			B:        difflib.SplitLines(desiredYAML),
+			FromFile: fromFile,
+			ToFile:   "b/" + path,
+			Context:  UnifiedDiffContext,
+		})
+		if err != nil {
+			return "", 0, fmt.Errorf("%s: %w", key, err)
+		}
+		b.WriteString(text)
+		changed++
+	}
+	return b.String(), changed, nil
+}
+
+// yamlOf renders the normalized object as YAML with sorted keys
+func (d *Detector) yamlOf(obj manifest.Object) (string, error) {
+	value, err := d.Normalize(obj)
+	if err != nil {
+		return "", err
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return string(data), nil
+}
+
+// diffPath names the object in the diff as Kind/project/name, or Kind/name
+// for objects without a project
+func diffPath(key compare.Key) string {
+	if key.Project == "" {
+		return key.Kind.String() + "/" + key.Name
+	}
+	return key.Kind.String() + "/" + key.Project + "/" + key.Name
+}
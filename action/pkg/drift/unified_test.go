@@ -0,0 +1,60 @@
+package drift
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	live := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  organization: acme
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Payments
+    createdAt: "2024-05-01T12:00:00Z"
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  organization: acme
+  metadata:
+    name: payments-alice
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+`)
+
+	diff, changed, err := New().UnifiedDiff(items(decode(t, desiredObjects), "nobl9/payments.yaml"), live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("expected 2 changed objects, got %d", changed)
+	}
+
+	for _, want := range []string{
+		"--- a/Project/payments\n+++ b/Project/payments\n",
+		"-    description: Payments\n+    description: Payments team\n",
+		"--- /dev/null\n+++ b/Service/payments/checkout\n",
+		"+    project: payments\n",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expected %q in diff:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "RoleBinding") || strings.Contains(diff, "createdAt") {
+		t.Errorf("expected unchanged objects and ignored fields to be left out:\n%s", diff)
+	}
+	if strings.Index(diff, "Project/payments") > strings.Index(diff, "Service/payments/checkout") {
+		t.Errorf("expected objects sorted by kind:\n%s", diff)
+	}
+
+	diff, changed, err = New().UnifiedDiff(items(live, "nobl9/payments.yaml"), live)
+	if err != nil || diff != "" || changed != 0 {
+		t.Errorf("expected an empty diff, got %d objects, %v:\n%s", changed, err, diff)
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"strings"
 	"time"
@@ -45,6 +46,12 @@ const (
 
 	// Non-retryable errors
 	ErrorTypeNonRetryable ErrorType = "non_retryable"
+
+	// Policy errors
+	ErrorTypePolicy ErrorType = "policy"
+
+	// Cancelled runs, e.g. by a cancelled workflow run
+	ErrorTypeCancelled ErrorType = "cancelled"
 )
 
 // ErrorSeverity represents the severity of an error
@@ -226,6 +233,34 @@ func NewTimeoutErrorWithDetails(message string, err error, details map[string]in
 	return NewWithDetails(ErrorTypeTimeout, SeverityMedium, message, err, details)
 }
 
+// NewPhaseTimeoutError creates the error of a run phase, such as resolve or
+// apply, that ran out of time. It is critical, since the rest of the run
+// cannot finish in time either.
+func NewPhaseTimeoutError(phase string, timeout time.Duration) *Nobl9Error {
+	return NewWithDetails(ErrorTypeTimeout, SeverityCritical, fmt.Sprintf("%s phase timed out after %s", phase, timeout), nil, map[string]interface{}{
+		"phase":   phase,
+		"timeout": timeout.String(),
+	})
+}
+
+// TimedOutPhase returns the phase of a phase timeout error, or "" for any
+// other error
+func TimedOutPhase(err error) string {
+	var nobl9Err *Nobl9Error
+	if !stderrors.As(err, &nobl9Err) || nobl9Err.Type != ErrorTypeTimeout {
+		return ""
+	}
+	phase, _ := nobl9Err.Details["phase"].(string)
+	return phase
+}
+
+// NewCancelledError creates the error of a run cancelled from outside, such
+// as by SIGINT or SIGTERM. It is critical, since the run stops scheduling
+// work.
+func NewCancelledError(message string, err error) *Nobl9Error {
+	return New(ErrorTypeCancelled, SeverityCritical, message, err)
+}
+
 // User resolution errors
 func NewUserResolutionError(message string, err error) *Nobl9Error {
 	return New(ErrorTypeUserResolution, SeverityMedium, message, err)
@@ -262,16 +297,39 @@ func NewNonRetryableErrorWithDetails(message string, err error, details map[stri
 	return NewWithDetails(ErrorTypeNonRetryable, SeverityHigh, message, err, details)
 }
 
+// Policy errors
+func NewPolicyError(message string, err error) *Nobl9Error {
+	return New(ErrorTypePolicy, SeverityCritical, message, err)
+}
+
+func NewPolicyErrorWithDetails(message string, err error, details map[string]interface{}) *Nobl9Error {
+	return NewWithDetails(ErrorTypePolicy, SeverityCritical, message, err, details)
+}
+
 // Error categorization functions
 func IsNobl9Error(err error) bool {
 	_, ok := err.(*Nobl9Error)
 	return ok
 }
 
+// IsRetryableError reports whether the operation that failed with err is
+// worth retrying. A Nobl9 API response is judged by its status code and
+// errors that know whether they are retryable are asked; only other errors
+// are matched against common retryable error patterns.
 func IsRetryableError(err error) bool {
 	if nobl9Err, ok := err.(*Nobl9Error); ok {
 		return nobl9Err.IsRetryable()
 	}
+	if status, ok := HTTPStatus(err); ok {
+		return retryableStatus(status)
+	}
+	var retryable interface{ IsRetryable() bool }
+	if stderrors.As(err, &retryable) {
+		return retryable.IsRetryable()
+	}
+	if isTimeout(err) {
+		return true
+	}
 
 	// Check for common retryable error patterns
 	errorMsg := strings.ToLower(err.Error())
@@ -301,10 +359,15 @@ func IsRetryableError(err error) bool {
 	return false
 }
 
+// IsAuthError reports whether err is a rejected authentication, by the
+// status code of a Nobl9 API response or else by its message
 func IsAuthError(err error) bool {
 	if nobl9Err, ok := err.(*Nobl9Error); ok {
 		return nobl9Err.GetType() == ErrorTypeAuth
 	}
+	if status, ok := HTTPStatus(err); ok {
+		return statusErrorType(status) == ErrorTypeAuth
+	}
 
 	errorMsg := strings.ToLower(err.Error())
 	authPatterns := []string{
@@ -329,6 +392,9 @@ func IsRateLimitError(err error) bool {
 	if nobl9Err, ok := err.(*Nobl9Error); ok {
 		return nobl9Err.GetType() == ErrorTypeRateLimit
 	}
+	if status, ok := HTTPStatus(err); ok {
+		return statusErrorType(status) == ErrorTypeRateLimit
+	}
 
 	errorMsg := strings.ToLower(err.Error())
 	rateLimitPatterns := []string{
@@ -351,6 +417,12 @@ func IsTimeoutError(err error) bool {
 	if nobl9Err, ok := err.(*Nobl9Error); ok {
 		return nobl9Err.GetType() == ErrorTypeTimeout
 	}
+	if status, ok := HTTPStatus(err); ok {
+		return statusErrorType(status) == ErrorTypeTimeout
+	}
+	if isTimeout(err) {
+		return true
+	}
 
 	errorMsg := strings.ToLower(err.Error())
 	timeoutPatterns := []string{
@@ -370,9 +442,21 @@ func IsTimeoutError(err error) bool {
 	return false
 }
 
+// AbortsRun reports whether the error makes the rest of a run fail as well.
+// Critical errors such as rejected credentials do, since every later request
+// is rejected too; critical policy errors only concern the file they were
+// raised for.
+func (e *Nobl9Error) AbortsRun() bool {
+	return e.Severity == SeverityCritical && e.Type != ErrorTypePolicy
+}
+
 // Error aggregation
 type ErrorAggregator struct {
 	errors []*Nobl9Error
+
+	// critical is the first error that aborts the run, passed to onCritical
+	critical   *Nobl9Error
+	onCritical func(*Nobl9Error)
 }
 
 func NewErrorAggregator() *ErrorAggregator {
@@ -383,16 +467,34 @@ func NewErrorAggregator() *ErrorAggregator {
 
 func (ea *ErrorAggregator) AddError(err *Nobl9Error) {
 	ea.errors = append(ea.errors, err)
+	if ea.critical == nil && err.AbortsRun() {
+		ea.critical = err
+		if ea.onCritical != nil {
+			ea.onCritical(err)
+		}
+	}
 }
 
 func (ea *ErrorAggregator) AddErrorFromErr(err error, errorType ErrorType, severity ErrorSeverity, message string) {
 	if nobl9Err, ok := err.(*Nobl9Error); ok {
-		ea.errors = append(ea.errors, nobl9Err)
+		ea.AddError(nobl9Err)
 	} else {
-		ea.errors = append(ea.errors, New(errorType, severity, message, err))
+		ea.AddError(New(errorType, severity, message, err))
 	}
 }
 
+// OnCritical registers a function called with the first added error that
+// aborts the run, so the caller can stop scheduling work that would fail
+// the same way
+func (ea *ErrorAggregator) OnCritical(fn func(*Nobl9Error)) {
+	ea.onCritical = fn
+}
+
+// CriticalError returns the first added error that aborts the run, or nil
+func (ea *ErrorAggregator) CriticalError() *Nobl9Error {
+	return ea.critical
+}
+
 func (ea *ErrorAggregator) GetErrors() []*Nobl9Error {
 	return ea.errors
 }
@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -154,6 +155,8 @@ func TestSpecificErrorConstructors(t *testing.T) {
 		{"manifest error", "manifest invalid", fmt.Errorf("parse error"), ErrorTypeManifest},
 		{"retryable error", "retryable", fmt.Errorf("temporary"), ErrorTypeRetryable},
 		{"non retryable error", "permanent", fmt.Errorf("fatal"), ErrorTypeNonRetryable},
+		{"policy error", "refusing to apply", nil, ErrorTypePolicy},
+		{"cancelled error", "run cancelled", nil, ErrorTypeCancelled},
 	}
 
 	for _, tt := range tests {
@@ -185,6 +188,10 @@ func TestSpecificErrorConstructors(t *testing.T) {
 				nobl9Err = NewRetryableError(tt.message, tt.err)
 			case ErrorTypeNonRetryable:
 				nobl9Err = NewNonRetryableError(tt.message, tt.err)
+			case ErrorTypePolicy:
+				nobl9Err = NewPolicyError(tt.message, tt.err)
+			case ErrorTypeCancelled:
+				nobl9Err = NewCancelledError(tt.message, tt.err)
 			}
 
 			assert.Equal(t, tt.expected, nobl9Err.Type)
@@ -344,6 +351,16 @@ func TestTimeoutErrorDetection(t *testing.T) {
 	}
 }
 
+func TestPhaseTimeoutError(t *testing.T) {
+	err := NewPhaseTimeoutError("resolve", 2*time.Minute)
+	assert.Equal(t, "[timeout] resolve phase timed out after 2m0s", err.Error())
+	assert.True(t, err.AbortsRun())
+	assert.Equal(t, "resolve", TimedOutPhase(err))
+	assert.Equal(t, "resolve", TimedOutPhase(fmt.Errorf("processing aborted: %w", err)))
+	assert.Empty(t, TimedOutPhase(NewTimeoutError("timeout", nil)))
+	assert.Empty(t, TimedOutPhase(nil))
+}
+
 func TestErrorAggregator(t *testing.T) {
 	aggregator := NewErrorAggregator()
 
@@ -406,6 +423,29 @@ func TestErrorAggregator_AddErrorFromErr(t *testing.T) {
 	assert.Equal(t, standardErr, errors[0].Err)
 }
 
+func TestErrorAggregator_OnCritical(t *testing.T) {
+	aggregator := NewErrorAggregator()
+
+	var aborted []*Nobl9Error
+	aggregator.OnCritical(func(err *Nobl9Error) {
+		aborted = append(aborted, err)
+	})
+
+	// Critical policy errors only concern their file
+	aggregator.AddError(NewPolicyError("file requires a ticket", nil))
+	aggregator.AddError(NewValidationError("invalid slo", nil))
+	assert.Empty(t, aborted)
+	assert.Nil(t, aggregator.CriticalError())
+
+	authErr := NewAuthError("credentials rejected", nil)
+	aggregator.AddError(authErr)
+	aggregator.AddErrorFromErr(NewAuthError("credentials rejected again", nil), ErrorTypeAuth, SeverityCritical, "")
+
+	assert.Equal(t, []*Nobl9Error{authErr}, aborted)
+	assert.Equal(t, authErr, aggregator.CriticalError())
+	assert.Len(t, aggregator.GetErrors(), 4)
+}
+
 func TestFormatError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -486,6 +526,8 @@ func TestErrorTypes(t *testing.T) {
 	assert.Equal(t, ErrorType("manifest"), ErrorTypeManifest)
 	assert.Equal(t, ErrorType("retryable"), ErrorTypeRetryable)
 	assert.Equal(t, ErrorType("non_retryable"), ErrorTypeNonRetryable)
+	assert.Equal(t, ErrorType("policy"), ErrorTypePolicy)
+	assert.Equal(t, ErrorType("cancelled"), ErrorTypeCancelled)
 }
 
 func TestErrorAggregator_EmptyState(t *testing.T) {
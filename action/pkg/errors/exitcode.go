@@ -0,0 +1,49 @@
+package errors
+
+import (
+	stderrors "errors"
+)
+
+const (
+	// ExitCodeSuccess is the exit code of a run without errors
+	ExitCodeSuccess = 0
+	// ExitCodeGeneral is the exit code of errors whose type has no exit code
+	// of its own, and of errors without a type
+	ExitCodeGeneral = 1
+)
+
+// ExitCodes maps error types to the exit code of the action. The codes are
+// part of the action's interface, as workflows branch on them; a code is
+// never reused for another type. Retryable and non-retryable errors exit
+// with ExitCodeGeneral.
+var ExitCodes = map[ErrorType]int{
+	ErrorTypeConfig:         2,
+	ErrorTypeValidation:     3,
+	ErrorTypeNobl9API:       4,
+	ErrorTypeFileProcessing: 5,
+	ErrorTypeAuth:           6,
+	ErrorTypeNetwork:        7,
+	ErrorTypeRateLimit:      8,
+	ErrorTypeTimeout:        9,
+	ErrorTypeUserResolution: 10,
+	ErrorTypeManifest:       11,
+	ErrorTypePolicy:         12,
+	ErrorTypeCancelled:      13,
+}
+
+// ExitCode returns the exit code of an error: ExitCodeSuccess for nil, and
+// otherwise the code of the type of the outermost Nobl9Error it wraps. The
+// message of the error is never looked at.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+	var nobl9Err *Nobl9Error
+	if !stderrors.As(err, &nobl9Err) {
+		return ExitCodeGeneral
+	}
+	if code, found := ExitCodes[nobl9Err.Type]; found {
+		return code
+	}
+	return ExitCodeGeneral
+}
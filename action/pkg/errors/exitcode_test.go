@@ -0,0 +1,35 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitCodeSuccess, ExitCode(nil))
+	assert.Equal(t, ExitCodeGeneral, ExitCode(fmt.Errorf("invalid configuration from the nobl9 api")))
+	assert.Equal(t, ExitCodeGeneral, ExitCode(NewRetryableError("temporary failure", nil)))
+
+	for errorType, code := range ExitCodes {
+		err := fmt.Errorf("run failed: %w", New(errorType, SeverityHigh, "failed", nil))
+		assert.Equal(t, code, ExitCode(err), "exit code of %s", errorType)
+	}
+
+	// The outermost type wins
+	err := NewConfigError("configuration validation failed", NewPolicyError("refusing to apply", nil))
+	assert.Equal(t, 2, ExitCode(err))
+	assert.Equal(t, 12, ExitCode(fmt.Errorf("processing aborted: %w", err.Err)))
+}
+
+func TestExitCodesUnique(t *testing.T) {
+	types := make(map[int]ErrorType)
+	for errorType, code := range ExitCodes {
+		assert.NotContains(t, []int{ExitCodeSuccess, ExitCodeGeneral}, code, "exit code of %s", errorType)
+		if other, found := types[code]; found {
+			t.Errorf("exit code %d is used by both %s and %s", code, other, errorType)
+		}
+		types[code] = errorType
+	}
+}
@@ -0,0 +1,107 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+// HTTPStatus returns the status code of the Nobl9 API response err reports,
+// and false when err does not wrap an SDK HTTPError
+func HTTPStatus(err error) (int, bool) {
+	if httpErr := asHTTPError(err); httpErr != nil {
+		return httpErr.StatusCode, true
+	}
+	return 0, false
+}
+
+// asHTTPError returns the SDK HTTPError err wraps, by pointer or by value,
+// or nil
+func asHTTPError(err error) *sdk.HTTPError {
+	var httpErr *sdk.HTTPError
+	if stderrors.As(err, &httpErr) {
+		return httpErr
+	}
+	var httpErrValue sdk.HTTPError
+	if stderrors.As(err, &httpErrValue) {
+		return &httpErrValue
+	}
+	return nil
+}
+
+// FromSDKError converts an error returned by the Nobl9 SDK to a Nobl9Error,
+// classified by the status code of the API response rather than by its
+// message. A Nobl9Error is returned as is, and nil converts to nil.
+func FromSDKError(err error) *Nobl9Error {
+	if err == nil {
+		return nil
+	}
+	var nobl9Err *Nobl9Error
+	if stderrors.As(err, &nobl9Err) {
+		return nobl9Err
+	}
+
+	if httpErr := asHTTPError(err); httpErr != nil {
+		details := map[string]interface{}{
+			"status_code": httpErr.StatusCode,
+			"method":      httpErr.Method,
+			"url":         httpErr.URL,
+		}
+		if httpErr.TraceID != "" {
+			details["trace_id"] = httpErr.TraceID
+		}
+		message := fmt.Sprintf("Nobl9 API responded %d %s", httpErr.StatusCode, http.StatusText(httpErr.StatusCode))
+		converted := NewWithDetails(statusErrorType(httpErr.StatusCode), SeverityHigh, message, err, details)
+		switch converted.Type {
+		case ErrorTypeAuth:
+			converted.Severity = SeverityCritical
+		case ErrorTypeRateLimit, ErrorTypeTimeout:
+			converted.Severity = SeverityMedium
+		case ErrorTypeNobl9API:
+			converted.Retryable = httpErr.StatusCode >= 500
+		}
+		return converted
+	}
+
+	var netErr net.Error
+	switch {
+	case isTimeout(err):
+		return NewTimeoutError("Nobl9 API request timed out", err)
+	case stderrors.As(err, &netErr):
+		return NewNetworkError("Nobl9 API request failed", err)
+	}
+
+	converted := NewNobl9APIError("Nobl9 API request failed", err)
+	converted.Retryable = IsRetryableError(err)
+	return converted
+}
+
+// statusErrorType returns the error type of a Nobl9 API response status
+func statusErrorType(status int) ErrorType {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorTypeAuth
+	case http.StatusTooManyRequests:
+		return ErrorTypeRateLimit
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrorTypeTimeout
+	default:
+		return ErrorTypeNobl9API
+	}
+}
+
+// retryableStatus reports whether a request answered with status is worth
+// sending again: server errors, rate limits and timeouts
+func retryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// isTimeout reports whether err is a missed deadline or a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return stderrors.Is(err, context.DeadlineExceeded) || (stderrors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,103 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// httpError returns an SDK error for status whose URL holds digits that
+// look like other status codes
+func httpError(status int) error {
+	return fmt.Errorf("failed to apply objects: %w", &sdk.HTTPError{
+		StatusCode: status,
+		Method:     http.MethodPut,
+		URL:        "http://127.0.0.1:5030/apply?trace=401429",
+		APIErrors:  sdk.APIErrors{Errors: []sdk.APIError{{Title: "request failed"}}},
+	})
+}
+
+func TestHTTPStatusClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+		auth      bool
+		rateLimit bool
+		timeout   bool
+	}{
+		{status: http.StatusBadRequest},
+		{status: http.StatusUnauthorized, auth: true},
+		{status: http.StatusForbidden, auth: true},
+		{status: http.StatusNotFound},
+		{status: http.StatusRequestTimeout, retryable: true, timeout: true},
+		{status: http.StatusTooManyRequests, retryable: true, rateLimit: true},
+		{status: http.StatusInternalServerError, retryable: true},
+		{status: http.StatusServiceUnavailable, retryable: true},
+		{status: http.StatusGatewayTimeout, retryable: true, timeout: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := httpError(tt.status)
+			status, ok := HTTPStatus(err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.retryable, IsRetryableError(err), "retryable")
+			assert.Equal(t, tt.auth, IsAuthError(err), "auth")
+			assert.Equal(t, tt.rateLimit, IsRateLimitError(err), "rate limit")
+			assert.Equal(t, tt.timeout, IsTimeoutError(err), "timeout")
+		})
+	}
+}
+
+func TestHTTPStatusByValue(t *testing.T) {
+	status, ok := HTTPStatus(fmt.Errorf("wrapped: %w", sdk.HTTPError{StatusCode: http.StatusForbidden}))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	_, ok = HTTPStatus(fmt.Errorf("401 Unauthorized"))
+	assert.False(t, ok)
+}
+
+func TestFromSDKError(t *testing.T) {
+	assert.Nil(t, FromSDKError(nil))
+
+	existing := NewConfigError("config", nil)
+	assert.Same(t, existing, FromSDKError(fmt.Errorf("wrapped: %w", existing)))
+
+	tests := []struct {
+		name      string
+		err       error
+		errorType ErrorType
+		severity  ErrorSeverity
+		retryable bool
+	}{
+		{"unauthorized", httpError(http.StatusUnauthorized), ErrorTypeAuth, SeverityCritical, false},
+		{"rate limited", httpError(http.StatusTooManyRequests), ErrorTypeRateLimit, SeverityMedium, true},
+		{"gateway timeout", httpError(http.StatusGatewayTimeout), ErrorTypeTimeout, SeverityMedium, true},
+		{"unavailable", httpError(http.StatusServiceUnavailable), ErrorTypeNobl9API, SeverityHigh, true},
+		{"bad request", httpError(http.StatusBadRequest), ErrorTypeNobl9API, SeverityHigh, false},
+		{"deadline", fmt.Errorf("get user: %w", context.DeadlineExceeded), ErrorTypeTimeout, SeverityMedium, true},
+		{"message only", fmt.Errorf("503 service unavailable"), ErrorTypeNobl9API, SeverityHigh, true},
+		{"unknown", fmt.Errorf("unexpected end of JSON input"), ErrorTypeNobl9API, SeverityHigh, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted := FromSDKError(tt.err)
+			assert.Equal(t, tt.errorType, converted.Type)
+			assert.Equal(t, tt.severity, converted.Severity)
+			assert.Equal(t, tt.retryable, converted.IsRetryable())
+			assert.ErrorIs(t, converted, tt.err)
+		})
+	}
+
+	converted := FromSDKError(httpError(http.StatusForbidden))
+	assert.Equal(t, http.StatusForbidden, converted.Details["status_code"])
+	assert.Equal(t, http.MethodPut, converted.Details["method"])
+	assert.Contains(t, converted.Error(), "Nobl9 API responded 403 Forbidden")
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors wrapped by the errors of lookups that found nothing, to
+// be checked with errors.Is
+var (
+	ErrUserNotFound        = stderrors.New("user not found")
+	ErrProjectNotFound     = stderrors.New("project not found")
+	ErrRoleBindingNotFound = stderrors.New("role binding not found")
+)
+
+// Kind returns a sentinel that errors.Is matches against every Nobl9Error
+// of the type and severity. An empty type or severity matches any, e.g.
+// errors.Is(err, Kind(ErrorTypeAuth, "")) finds an authentication error.
+func Kind(errorType ErrorType, severity ErrorSeverity) *Nobl9Error {
+	return &Nobl9Error{Type: errorType, Severity: severity}
+}
+
+// Is reports whether target is the error itself or a Kind sentinel the
+// error's type and severity match
+func (e *Nobl9Error) Is(target error) bool {
+	kind, ok := target.(*Nobl9Error)
+	if !ok {
+		return false
+	}
+	if kind == e {
+		return true
+	}
+	if kind.Message != "" || kind.Err != nil {
+		return false
+	}
+	return (kind.Type == "" || kind.Type == e.Type) && (kind.Severity == "" || kind.Severity == e.Severity)
+}
+
+// AggregateError holds every error an ErrorAggregator collected. It unwraps
+// to all of them, so errors.Is and errors.As look through each.
+type AggregateError struct {
+	Errors []*Nobl9Error
+}
+
+// Error returns the single collected error as is, or a count of the errors
+// by severity followed by the errors joined by errors.Join, one per line
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	counts := make(map[ErrorSeverity]int)
+	for _, err := range e.Errors {
+		counts[err.GetSeverity()]++
+	}
+	var bySeverity []string
+	for _, severity := range []ErrorSeverity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow} {
+		if counts[severity] > 0 {
+			bySeverity = append(bySeverity, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	return fmt.Sprintf("%d errors (%s):\n%s", len(e.Errors), strings.Join(bySeverity, ", "), stderrors.Join(e.Unwrap()...))
+}
+
+// Unwrap returns the collected errors
+func (e *AggregateError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns the collected errors as an AggregateError, or nil when there
+// are none, so callers can return every error of a run at once
+func (ea *ErrorAggregator) Err() error {
+	if len(ea.errors) == 0 {
+		return nil
+	}
+	return &AggregateError{Errors: append([]*Nobl9Error(nil), ea.errors...)}
+}
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNobl9ErrorIsKind(t *testing.T) {
+	err := fmt.Errorf("apply failed: %w", NewAuthError("token rejected", nil))
+
+	assert.True(t, stderrors.Is(err, Kind(ErrorTypeAuth, "")))
+	assert.True(t, stderrors.Is(err, Kind("", SeverityCritical)))
+	assert.True(t, stderrors.Is(err, Kind(ErrorTypeAuth, SeverityCritical)))
+	assert.False(t, stderrors.Is(err, Kind(ErrorTypeAuth, SeverityLow)))
+	assert.False(t, stderrors.Is(err, Kind(ErrorTypeRateLimit, "")))
+
+	// An error with a message only matches itself
+	other := NewAuthError("token rejected", nil)
+	assert.False(t, stderrors.Is(err, other))
+	assert.True(t, stderrors.Is(other, other))
+
+	var nobl9Err *Nobl9Error
+	assert.True(t, stderrors.As(err, &nobl9Err))
+	assert.Equal(t, "token rejected", nobl9Err.Message)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	err := NewNobl9APIError("project payments not found", ErrProjectNotFound)
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+	assert.NotErrorIs(t, err, ErrUserNotFound)
+
+	err = NewUserResolutionError("failed to resolve user", fmt.Errorf("%w with email 'bob@example.com' in Nobl9", ErrUserNotFound))
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, err, Kind(ErrorTypeUserResolution, ""))
+}
+
+func TestErrorAggregator_Err(t *testing.T) {
+	aggregator := NewErrorAggregator()
+	assert.NoError(t, aggregator.Err())
+
+	aggregator.AddError(NewUserResolutionError("failed to resolve user", ErrUserNotFound))
+	assert.Equal(t, "[user_resolution] failed to resolve user: user not found", aggregator.Err().Error())
+
+	aggregator.AddError(NewRateLimitError("rate limited", nil))
+	aggregator.AddError(NewAuthError("token rejected", nil))
+	err := aggregator.Err()
+	assert.Equal(t, "3 errors (1 critical, 2 medium):\n"+
+		"[user_resolution] failed to resolve user: user not found\n"+
+		"[rate_limit] rate limited\n"+
+		"[authentication] token rejected", err.Error())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, err, Kind(ErrorTypeRateLimit, ""))
+	assert.NotErrorIs(t, err, Kind(ErrorTypeConfig, ""))
+
+	var aggregate *AggregateError
+	assert.True(t, stderrors.As(err, &aggregate))
+	assert.Len(t, aggregate.Unwrap(), 3)
+
+	// Errors added later do not change an error already returned
+	aggregator.AddError(NewConfigError("config", nil))
+	assert.Len(t, aggregate.Errors, 3)
+}
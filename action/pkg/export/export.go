@@ -0,0 +1,151 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/kindlist"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultKinds are the project-scoped kinds exported with each project
+var DefaultKinds = []manifest.Kind{
+	manifest.KindService,
+	manifest.KindAlertPolicy,
+	manifest.KindSLO,
+	manifest.KindRoleBinding,
+}
+
+// secretKinds hold credentials the API never returns, so exported copies
+// could not be applied again
+var secretKinds = map[manifest.Kind]bool{
+	manifest.KindAgent:       true,
+	manifest.KindDirect:      true,
+	manifest.KindAlertMethod: true,
+}
+
+// Header starts every exported file
+const Header = "# Exported from Nobl9 by nobl9-action export\n"
+
+// File holds the exported objects of one project
+type File struct {
+	Project string
+	Objects []manifest.Object
+}
+
+// ParseKinds parses a comma separated list of project-scoped kinds to
+// export with each project. An empty list selects DefaultKinds.
+func ParseKinds(spec string) ([]manifest.Kind, error) {
+	kinds, err := kindlist.Parse(spec, exportable, kindlist.RequireProjectScoped)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kinds) == 0 {
+		return DefaultKinds, nil
+	}
+	return kinds, nil
+}
+
+// exportable rejects the kinds holding credentials
+func exportable(kind manifest.Kind) error {
+	if secretKinds[kind] {
+		return fmt.Errorf("kind '%s' holds credentials the API does not return and cannot be exported", kind)
+	}
+	return nil
+}
+
+// Group sorts objects into one file per project, sorted by project name.
+// Role bindings belong to the project they grant access to; organization
+// role bindings are left out.
+func Group(objects []manifest.Object) []*File {
+	byProject := make(map[string]*File)
+	for _, obj := range objects {
+		project := planner.ProjectOf(obj)
+		if project == "" {
+			continue
+		}
+		file, found := byProject[project]
+		if !found {
+			file = &File{Project: project}
+			byProject[project] = file
+		}
+		file.Objects = append(file.Objects, obj)
+	}
+
+	files := make([]*File, 0, len(byProject))
+	for _, file := range byProject {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Project < files[j].Project })
+	return files
+}
+
+// Name is the file name of the project's objects
+func (f *File) Name() string {
+	return f.Project + ".yaml"
+}
+
+// Render writes the objects as canonical multi-document YAML: in apply
+// order, then by kind and name, with keys sorted and without the fields
+// Nobl9 sets or empty values. Exporting unchanged objects again produces
+// the same content.
+func (f *File) Render() ([]byte, error) {
+	levels, err := planner.New().Levels()
+	if err != nil {
+		return nil, err
+	}
+	objects := append([]manifest.Object(nil), f.Objects...)
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if levels[a.GetKind()] != levels[b.GetKind()] {
+			return levels[a.GetKind()] < levels[b.GetKind()]
+		}
+		if a.GetKind() != b.GetKind() {
+			return a.GetKind().String() < b.GetKind().String()
+		}
+		return a.GetName() < b.GetName()
+	})
+
+	detector := drift.New()
+	var buf bytes.Buffer
+	buf.WriteString(Header)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, obj := range objects {
+		value, err := detector.Normalize(obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write renders the file into dir, creating the directory, and returns the
+// path written
+func (f *File) Write(dir string) (string, error) {
+	content, err := f.Render()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, f.Name())
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return objects
+}
+
+const liveObjects = `
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  organization: acme
+  metadata:
+    name: payments-alice
+  spec:
+    user: alice@example.com
+    roleRef: project-owner
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  organization: acme
+  metadata:
+    name: checkout
+    project: payments
+  spec:
+    description: ""
+  status:
+    sloCount: 2
+- apiVersion: n9/v1alpha
+  kind: Project
+  organization: acme
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+  spec:
+    description: Payments team
+    createdAt: "2024-05-01T12:00:00Z"
+    createdBy: 00u1admin
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: org-admin
+  spec:
+    user: 00u1admin
+    roleRef: organization-admin
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: checkout
+`
+
+func TestGroupAndRender(t *testing.T) {
+	files := Group(decode(t, liveObjects))
+	if len(files) != 2 || files[0].Project != "checkout" || files[1].Project != "payments" {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	if len(files[1].Objects) != 3 {
+		t.Fatalf("expected 3 payments objects without the organization role binding, got %d", len(files[1].Objects))
+	}
+
+	content, err := files[1].Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Header + `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  labels:
+    team:
+      - payments
+  name: payments
+spec:
+  description: Payments team
+---
+apiVersion: n9/v1alpha
+kind: Service
+metadata:
+  name: checkout
+  project: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+spec:
+  projectRef: payments
+  roleRef: project-owner
+  user: alice@example.com
+`
+	if string(content) != expected {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+
+	// The rendered objects can be decoded and exported again unchanged
+	again, err := (&File{Project: "payments", Objects: decode(t, string(content))}).Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(again) != string(content) {
+		t.Errorf("expected a stable export, got:\n%s", again)
+	}
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	path, err := files[1].Write(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil || string(written) != expected || path != filepath.Join(dir, "payments.yaml") {
+		t.Errorf("unexpected file %s: %v", path, err)
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds("")
+	if err != nil || len(kinds) != len(DefaultKinds) {
+		t.Errorf("expected the default kinds, got %v, %v", kinds, err)
+	}
+
+	kinds, err = ParseKinds("slo, rolebinding, slo")
+	if err != nil || len(kinds) != 2 || kinds[0] != manifest.KindSLO || kinds[1] != manifest.KindRoleBinding {
+		t.Errorf("unexpected kinds: %v, %v", kinds, err)
+	}
+
+	for _, spec := range []string{"agent", "project", "unknown"} {
+		if _, err := ParseKinds(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
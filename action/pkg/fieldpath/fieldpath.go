@@ -0,0 +1,96 @@
+// Package fieldpath parses the dotted field paths, such as
+// spec.objectives[*].rawMetric, that select values of objects decoded to
+// plain JSON, for drift ignore rules and object tests
+package fieldpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is one step of a path: a field name, a list index, or any field
+// or element when Wildcard is set. A recursive segment, written after ..,
+// matches at any depth.
+type Segment struct {
+	Name      string
+	Index     int
+	IsIndex   bool
+	Wildcard  bool
+	Recursive bool
+}
+
+// Parse splits a path into segments. A leading $ is allowed; [n] selects
+// the element at index n, [*] or * any element or field, and ["name"] or
+// ['name'] a field whose name contains dots.
+func Parse(path string) ([]Segment, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var segments []Segment
+	recursive := false
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+			continue
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			if rest == "" || strings.HasPrefix(rest, "[") {
+				return nil, fmt.Errorf("empty field name")
+			}
+			continue
+		case strings.HasPrefix(rest, `["`), strings.HasPrefix(rest, "['"):
+			quote := rest[1:2]
+			end := strings.Index(rest[2:], quote+"]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [%s", quote)
+			}
+			segments = append(segments, Segment{Name: rest[2 : 2+end], Recursive: recursive})
+			rest = rest[2+end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			index := rest[1:end]
+			switch n, err := strconv.Atoi(index); {
+			case index == "*":
+				segments = append(segments, Segment{Name: index, Wildcard: true, Recursive: recursive})
+			case err == nil && n >= 0:
+				segments = append(segments, Segment{Name: index, Index: n, IsIndex: true, Recursive: recursive})
+			default:
+				return nil, fmt.Errorf("list index must be a number or *, got '%s'", index)
+			}
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			segments = append(segments, Segment{Name: name, Wildcard: name == "*", Recursive: recursive})
+			rest = rest[end:]
+			recursive = false
+			continue
+		}
+		if rest != "" && rest[0] != '.' && rest[0] != '[' {
+			return nil, fmt.Errorf("unexpected '%s' after ]", rest)
+		}
+		recursive = false
+	}
+
+	if recursive {
+		return nil, fmt.Errorf("path cannot end with ..")
+	}
+	return segments, nil
+}
+
+// Matches reports whether the segment selects the field or element key;
+// list elements are keyed by their index
+func (s Segment) Matches(key string) bool {
+	return s.Wildcard || s.Name == key
+}
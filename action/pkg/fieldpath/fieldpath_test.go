@@ -0,0 +1,48 @@
+package fieldpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	segments, err := Parse(`$..metadata.annotations["github.com/commit"].spec[2][*].*`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, segment := range segments {
+		names = append(names, segment.Name)
+	}
+	if strings.Join(names, "|") != "metadata|annotations|github.com/commit|spec|2|*|*" {
+		t.Fatalf("unexpected segments: %v", names)
+	}
+	if !segments[0].Recursive || segments[1].Recursive {
+		t.Errorf("expected only the first segment to be recursive, got %+v", segments)
+	}
+	if !segments[4].IsIndex || segments[4].Index != 2 {
+		t.Errorf("expected index 2, got %+v", segments[4])
+	}
+	if !segments[5].Wildcard || !segments[6].Wildcard || !segments[6].Matches("anything") {
+		t.Errorf("expected [*] and * to match anything, got %+v", segments[5:])
+	}
+	if segments[1].Matches("labels") || !segments[1].Matches("annotations") {
+		t.Errorf("expected a field name to match only itself")
+	}
+
+	for path, want := range map[string]string{
+		"":                 "empty path",
+		"spec.":            "empty field name",
+		"spec.[0]":         "empty field name",
+		"spec[0":           "unclosed [",
+		`metadata["github`: `unclosed ["`,
+		"spec[x]":          "must be a number or *",
+		"spec[-1]":         "must be a number or *",
+		"spec[0]x":         "unexpected 'x' after ]",
+		"metadata..":       "cannot end with ..",
+	} {
+		if _, err := Parse(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", path, want, err)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package generate
+
+import (
+	"bytes"
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateSuffix is the suffix of template files. Templates whose name
+// starts with an underscore only hold shared {{ define }} blocks and are
+// not rendered on their own.
+const TemplateSuffix = ".tmpl"
+
+// csvListSeparator separates the owners and environments in a CSV cell
+const csvListSeparator = ";"
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// sampleTeam is rendered when templates are loaded, so that templates
+// referencing unknown fields or functions fail before any team is rendered
+var sampleTeam = Team{
+	Name:         "example",
+	DisplayName:  "Example",
+	Description:  "Example team",
+	Owners:       []string{"owner@example.com"},
+	Environments: []string{"prod"},
+}
+
+// nameRegexp matches the team and environment names allowed in object names
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Team is the onboarding input of one team
+type Team struct {
+	// Name of the team, used in project and object names
+	Name string `yaml:"name"`
+	// DisplayName defaults to the name
+	DisplayName string `yaml:"displayName"`
+	// Description defaults to "<display name> team"
+	Description string `yaml:"description"`
+	// Owners are the emails given the project-owner role
+	Owners []string `yaml:"owners"`
+	// Environments get one project each, e.g. payments-staging
+	Environments []string `yaml:"environments"`
+}
+
+// ProjectName returns the name of the team's project in an environment
+func (t Team) ProjectName(environment string) string {
+	return t.Name + "-" + environment
+}
+
+// input is the YAML onboarding file
+type input struct {
+	Teams []Team `yaml:"teams"`
+}
+
+// File is the manifest generated for one team
+type File struct {
+	Team    string
+	Content []byte
+	Meta    *parser.Meta
+	Objects []manifest.Object
+}
+
+// Name returns the file name of the manifest, <team>.yaml
+func (f *File) Name() string {
+	return f.Team + ".yaml"
+}
+
+// Templates is a set of Go templates rendered in file name order for each
+// team, each producing YAML documents
+type Templates struct {
+	set   *template.Template
+	names []string
+}
+
+// LoadTeams reads the teams from a YAML file with a top-level teams list, or
+// from a CSV file (.csv) with team, owners and environments columns and
+// optional display_name and description columns. Owners and environments
+// are separated by semicolons in CSV cells.
+func LoadTeams(path string) ([]Team, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read teams file %s: %w", path, err)
+	}
+
+	var teams []Team
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		teams, err = parseCSV(data)
+	} else {
+		teams, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid teams file %s: %w", path, err)
+	}
+
+	if len(teams) == 0 {
+		return nil, fmt.Errorf("invalid teams file %s: no teams", path)
+	}
+	seen := make(map[string]bool, len(teams))
+	for i := range teams {
+		team := &teams[i]
+		if err := team.normalize(); err != nil {
+			return nil, fmt.Errorf("invalid teams file %s: team %d: %w", path, i+1, err)
+		}
+		if seen[team.Name] {
+			return nil, fmt.Errorf("invalid teams file %s: team %s is listed twice", path, team.Name)
+		}
+		seen[team.Name] = true
+	}
+	return teams, nil
+}
+
+// LoadTemplates parses the *.tmpl files of a directory, or the built-in
+// templates when dir is empty, and executes them once for a sample team.
+// Errors name the template, line and column at fault.
+func LoadTemplates(dir string) (*Templates, error) {
+	var fsys fs.FS = defaultTemplates
+	pattern := "templates/*" + TemplateSuffix
+	if dir != "" {
+		fsys = os.DirFS(dir)
+		pattern = "*" + TemplateSuffix
+	}
+
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s templates found in %s", TemplateSuffix, dir)
+	}
+
+	set, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").ParseFS(fsys, files...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	templates := &Templates{set: set}
+	for _, file := range files {
+		name := filepath.Base(file)
+		if !strings.HasPrefix(name, "_") {
+			templates.names = append(templates.names, name)
+		}
+	}
+	sort.Strings(templates.names)
+
+	for _, name := range templates.names {
+		if err := templates.set.ExecuteTemplate(io.Discard, name, sampleTeam); err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return templates, nil
+}
+
+// Render expands the templates for a team and checks that the result
+// decodes as Nobl9 objects
+func (t *Templates) Render(team Team) (*File, error) {
+	var content bytes.Buffer
+	for _, name := range t.names {
+		var rendered bytes.Buffer
+		if err := t.set.ExecuteTemplate(&rendered, name, team); err != nil {
+			return nil, fmt.Errorf("team %s: %w", team.Name, err)
+		}
+		documents := strings.TrimSpace(rendered.String())
+		if documents == "" {
+			continue
+		}
+		if !strings.HasPrefix(documents, "---") {
+			content.WriteString("---\n")
+		}
+		content.WriteString(documents)
+		content.WriteString("\n")
+	}
+
+	file := &File{Team: team.Name, Content: content.Bytes()}
+
+	meta, objects, err := parser.ExtractMeta(file.Content)
+	if err != nil {
+		return nil, fmt.Errorf("team %s: invalid %s: %w", team.Name, parser.MetaKind, err)
+	}
+	file.Meta = meta
+	file.Objects, err = sdk.DecodeObjects(objects)
+	if err != nil {
+		return nil, fmt.Errorf("team %s: templates produced invalid Nobl9 YAML: %w", team.Name, err)
+	}
+	if len(file.Objects) == 0 {
+		return nil, fmt.Errorf("team %s: templates produced no objects", team.Name)
+	}
+	return file, nil
+}
+
+// templateFuncs are the functions available to templates besides the
+// text/template built-ins
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"join":  func(separator string, values []string) string { return strings.Join(values, separator) },
+	"slug":  Slug,
+	// quote renders a YAML double-quoted string
+	"quote": strconv.Quote,
+}
+
+// Slug turns a value such as an email into a name fragment, e.g.
+// alice@example.com becomes alice-example-com
+func Slug(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// normalize fills in the defaults of a team and checks its fields
+func (t *Team) normalize() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if !nameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("name '%s' must be lowercase letters, digits and dashes", t.Name)
+	}
+	if t.DisplayName == "" {
+		t.DisplayName = t.Name
+	}
+	if t.Description == "" {
+		t.Description = t.DisplayName + " team"
+	}
+
+	if len(t.Owners) == 0 {
+		return fmt.Errorf("team %s needs at least one owner", t.Name)
+	}
+	for i, owner := range t.Owners {
+		t.Owners[i] = strings.TrimSpace(owner)
+		if !strings.Contains(t.Owners[i], "@") {
+			return fmt.Errorf("team %s: owner '%s' is not an email", t.Name, owner)
+		}
+	}
+
+	if len(t.Environments) == 0 {
+		return fmt.Errorf("team %s needs at least one environment", t.Name)
+	}
+	for i, environment := range t.Environments {
+		t.Environments[i] = strings.TrimSpace(environment)
+		if !nameRegexp.MatchString(t.Environments[i]) {
+			return fmt.Errorf("team %s: environment '%s' must be lowercase letters, digits and dashes", t.Name, environment)
+		}
+	}
+	return nil
+}
+
+// parseYAML decodes a YAML teams file, rejecting unknown fields
+func parseYAML(data []byte) ([]Team, error) {
+	var in input
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return in.Teams, nil
+}
+
+// parseCSV decodes a CSV teams file with a header row
+func parseCSV(data []byte) ([]Team, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"team", "owners", "environments"} {
+		if _, found := columns[required]; !found {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	for name := range columns {
+		switch name {
+		case "team", "owners", "environments", "display_name", "description":
+		default:
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+	}
+
+	cell := func(record []string, name string) string {
+		if i, found := columns[name]; found {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	teams := make([]Team, 0, len(records)-1)
+	for _, record := range records[1:] {
+		teams = append(teams, Team{
+			Name:         cell(record, "team"),
+			DisplayName:  cell(record, "display_name"),
+			Description:  cell(record, "description"),
+			Owners:       splitCell(cell(record, "owners")),
+			Environments: splitCell(cell(record, "environments")),
+		})
+	}
+	return teams, nil
+}
+
+// splitCell splits a semicolon separated CSV cell, dropping empty values
+func splitCell(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, csvListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,192 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoadTeams(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := writeFile(t, dir, "teams.yaml", `
+teams:
+  - name: payments
+    displayName: Payments
+    owners: [alice@example.com, bob@example.com]
+    environments: [staging, prod]
+`)
+	csvPath := writeFile(t, dir, "teams.csv", `team,owners,environments,display_name
+payments,alice@example.com; bob@example.com,staging;prod,Payments
+`)
+
+	for _, path := range []string{yamlPath, csvPath} {
+		teams, err := LoadTeams(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		if len(teams) != 1 {
+			t.Fatalf("%s: expected 1 team, got %d", path, len(teams))
+		}
+		team := teams[0]
+		if team.Name != "payments" || team.DisplayName != "Payments" || team.Description != "Payments team" {
+			t.Errorf("%s: unexpected team %+v", path, team)
+		}
+		if strings.Join(team.Owners, ",") != "alice@example.com,bob@example.com" {
+			t.Errorf("%s: unexpected owners %v", path, team.Owners)
+		}
+		if strings.Join(team.Environments, ",") != "staging,prod" {
+			t.Errorf("%s: unexpected environments %v", path, team.Environments)
+		}
+	}
+}
+
+func TestLoadTeamsErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]struct {
+		name    string
+		content string
+		want    string
+	}{
+		"no teams":        {"empty.yaml", "teams: []\n", "no teams"},
+		"unknown field":   {"typo.yaml", "teams:\n  - name: payments\n    owner: [alice@example.com]\n", "field owner not found"},
+		"invalid name":    {"name.yaml", "teams:\n  - name: Payments\n    owners: [alice@example.com]\n    environments: [prod]\n", "name 'Payments'"},
+		"no owners":       {"owners.yaml", "teams:\n  - name: payments\n    environments: [prod]\n", "at least one owner"},
+		"owner not email": {"email.yaml", "teams:\n  - name: payments\n    owners: [alice]\n    environments: [prod]\n", "not an email"},
+		"no environments": {"envs.yaml", "teams:\n  - name: payments\n    owners: [alice@example.com]\n", "at least one environment"},
+		"duplicate team":  {"dup.csv", "team,owners,environments\npayments,alice@example.com,prod\npayments,bob@example.com,prod\n", "listed twice"},
+		"missing column":  {"column.csv", "team,owners\npayments,alice@example.com\n", "missing environments column"},
+		"unknown column":  {"extra.csv", "team,owners,environments,slack\npayments,alice@example.com,prod,#payments\n", "unknown column slack"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadTeams(writeFile(t, dir, tt.name, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	team := Team{
+		Name:         "payments",
+		DisplayName:  "Payments",
+		Description:  "Payments team",
+		Owners:       []string{"alice@example.com"},
+		Environments: []string{"staging", "prod"},
+	}
+	file, err := templates.Render(team)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if file.Name() != "payments.yaml" {
+		t.Errorf("expected payments.yaml, got %s", file.Name())
+	}
+	if file.Meta == nil || file.Meta.Spec.Owner != "payments" {
+		t.Errorf("expected an ActionMeta owned by payments, got %+v", file.Meta)
+	}
+
+	var got []string
+	for _, obj := range file.Objects {
+		got = append(got, obj.GetKind().String()+"/"+obj.GetName())
+	}
+	want := []string{
+		"Project/payments-staging",
+		"Project/payments-prod",
+		"RoleBinding/payments-staging-alice-example-com",
+		"RoleBinding/payments-prod-alice-example-com",
+		"Service/payments",
+		"Service/payments",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected objects %v, got %v", want, got)
+	}
+
+	for _, obj := range file.Objects {
+		if obj.GetKind() != manifest.KindRoleBinding {
+			continue
+		}
+		binding := obj.(v1alphaRoleBinding.RoleBinding)
+		if binding.Spec.User == nil || *binding.Spec.User != "alice@example.com" || binding.Spec.RoleRef != "project-owner" {
+			t.Errorf("unexpected role binding spec %+v", binding.Spec)
+		}
+	}
+}
+
+func TestRenderCustomTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "_helpers.tmpl", `{{ define "project" }}{{ .Name }}{{ end }}`)
+	writeFile(t, dir, "project.yaml.tmpl", `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: {{ template "project" . }}
+  labels:
+    owners: [{{ join ", " .Owners | lower }}]
+`)
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := templates.Render(Team{Name: "payments", Owners: []string{"Alice@Example.com"}, Environments: []string{"prod"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(file.Objects) != 1 || file.Objects[0].GetName() != "payments" {
+		t.Fatalf("expected project payments, got %v", file.Objects)
+	}
+	if !strings.Contains(string(file.Content), "owners: [alice@example.com]") {
+		t.Errorf("expected lowercased owners, got:\n%s", file.Content)
+	}
+
+	writeFile(t, dir, "broken.yaml.tmpl", "kind: [\n")
+	templates, err = LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := templates.Render(Team{Name: "payments"}); err == nil || !strings.Contains(err.Error(), "team payments") {
+		t.Fatalf("expected a rendering error for team payments, got %v", err)
+	}
+
+	// Templates that cannot execute fail when they are loaded, with their
+	// location
+	writeFile(t, dir, "broken.yaml.tmpl", "kind: Project\nname: {{ .Nmae }}\n")
+	if _, err := LoadTemplates(dir); err == nil || !strings.Contains(err.Error(), "broken.yaml.tmpl:2") {
+		t.Fatalf("expected the location of the unknown field, got %v", err)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"alice@example.com":        "alice-example-com",
+		"Bob.Smith+nobl9@corp.com": "bob-smith-nobl9-corp-com",
+		"--team--":                 "team",
+	}
+	for value, want := range tests {
+		if got := Slug(value); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gitref
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Extract writes the files of a git ref (a branch, tag or commit) into dir,
+// which must exist. The files are those of repoPath at the ref, relative to
+// repoPath, as git archive writes them. Symbolic links are left out, so a
+// ref cannot make the caller read files outside dir.
+func Extract(ctx context.Context, repoPath, ref, dir string) error {
+	if strings.TrimSpace(ref) == "" {
+		return fmt.Errorf("git ref cannot be empty")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git ref '%s'", ref)
+	}
+	absolute, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", repoPath, err)
+	}
+
+	// The workspace of a container job is often owned by another user, which
+	// git refuses to read unless the directory is marked safe
+	cmd := exec.CommandContext(ctx, "git", "-c", "safe.directory="+absolute, "archive", "--format=tar", ref)
+	cmd.Dir = absolute
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git: %w", err)
+	}
+
+	extractErr := extractTar(stdout, dir)
+	// Drain the archive so git is not blocked writing it
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to read git ref %s: %s", ref, strings.TrimSpace(firstLine(stderr.String(), err.Error())))
+	}
+	if extractErr != nil {
+		return fmt.Errorf("failed to extract git ref %s: %w", ref, extractErr)
+	}
+	return nil
+}
+
+// extractTar writes the directories and regular files of a tar archive
+// into dir
+func extractTar(r io.Reader, dir string) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("archive entry %s is outside the archive", header.Name)
+		}
+		target := filepath.Join(dir, header.Name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, reader); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// firstLine returns the first line of output, or fallback when it is empty
+func firstLine(output, fallback string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return fallback
+	}
+	line, _, _ := strings.Cut(output, "\n")
+	return line
+}
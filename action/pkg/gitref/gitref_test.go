@@ -0,0 +1,58 @@
+package gitref
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// git runs a git command in dir, failing the test when it fails
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git(t, repo, "init", "-q")
+	if err := os.MkdirAll(filepath.Join(repo, "nobl9"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "nobl9", "project.yaml"), []byte("first\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "first")
+	git(t, repo, "tag", "v1")
+	if err := os.WriteFile(filepath.Join(repo, "nobl9", "project.yaml"), []byte("second\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	git(t, repo, "commit", "-q", "-am", "second")
+
+	dir := t.TempDir()
+	if err := Extract(context.Background(), repo, "v1", dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "nobl9", "project.yaml"))
+	if err != nil || string(content) != "first\n" {
+		t.Errorf("expected the file at v1, got %q (%v)", content, err)
+	}
+
+	if err := Extract(context.Background(), repo, "missing", t.TempDir()); err == nil {
+		t.Error("expected error for an unknown ref")
+	}
+	if err := Extract(context.Background(), repo, "--output=/tmp/x", t.TempDir()); err == nil {
+		t.Error("expected error for a ref that looks like an option")
+	}
+}
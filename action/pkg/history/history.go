@@ -0,0 +1,190 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/reportfmt"
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// DefaultWindow is the number of runs a report covers by default
+const DefaultWindow = 30
+
+// Window aggregates consecutive runs
+type Window struct {
+	Runs            int           `json:"runs"`
+	Failed          int           `json:"failed"`
+	ObjectsChanged  int           `json:"objects_changed"`
+	FilesWithErrors int           `json:"files_with_errors"`
+	MedianDuration  time.Duration `json:"median_duration"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+}
+
+// ErrorRate returns the share of failed runs, in percent
+func (w Window) ErrorRate() float64 {
+	if w.Runs == 0 {
+		return 0
+	}
+	return float64(w.Failed) / float64(w.Runs) * 100
+}
+
+// ObjectsPerRun returns the average number of objects changed per run
+func (w Window) ObjectsPerRun() float64 {
+	if w.Runs == 0 {
+		return 0
+	}
+	return float64(w.ObjectsChanged) / float64(w.Runs)
+}
+
+// Report compares the last runs with the runs before them
+type Report struct {
+	// Window is the number of runs compared
+	Window int `json:"window"`
+	// Current are the last Window runs and Previous the Window runs before
+	// them; Previous is empty when the history is too short
+	Current  Window `json:"current"`
+	Previous Window `json:"previous"`
+	// Runs are the runs of Current, newest first
+	Runs []state.Run `json:"runs"`
+}
+
+// New creates a report of the last window runs of a history, oldest first
+func New(runs []state.Run, window int) *Report {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	end := len(runs)
+	start := max(end-window, 0)
+	previousStart := max(start-window, 0)
+
+	report := &Report{
+		Window:   window,
+		Current:  aggregate(runs[start:end]),
+		Previous: aggregate(runs[previousStart:start]),
+	}
+	for i := end - 1; i >= start; i-- {
+		report.Runs = append(report.Runs, runs[i])
+	}
+	return report
+}
+
+// aggregate sums a window of runs, oldest first
+func aggregate(runs []state.Run) Window {
+	window := Window{Runs: len(runs)}
+	if len(runs) == 0 {
+		return window
+	}
+
+	durations := make([]time.Duration, 0, len(runs))
+	for _, run := range runs {
+		if run.Failed() {
+			window.Failed++
+		}
+		window.ObjectsChanged += run.ObjectsChanged
+		window.FilesWithErrors += run.FilesWithErrors
+		durations = append(durations, run.Duration())
+	}
+	window.From = runs[0].StartedAt
+	window.To = runs[len(runs)-1].StartedAt
+	window.MedianDuration = median(durations)
+	return window
+}
+
+// median returns the median of durations
+func median(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	middle := len(durations) / 2
+	if len(durations)%2 == 1 {
+		return durations[middle]
+	}
+	return (durations[middle-1] + durations[middle]) / 2
+}
+
+// Markdown renders the report for a status issue or dashboard
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Nobl9 Run History\n\n")
+	if r.Current.Runs == 0 {
+		b.WriteString("No runs recorded yet.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Last %s, from %s to %s.\n\n", reportfmt.Plural(r.Current.Runs, "run"),
+		r.Current.From.UTC().Format(time.DateOnly), r.Current.To.UTC().Format(time.DateOnly))
+
+	current, previous := r.Current, r.Previous
+	if previous.Runs == 0 {
+		b.WriteString("| Metric | Value |\n|--------|-------|\n")
+		fmt.Fprintf(&b, "| Error rate | %.0f%% (%s) |\n", current.ErrorRate(), reportfmt.Plural(current.Failed, "failed run"))
+		fmt.Fprintf(&b, "| Objects changed | %d (%.1f per run) |\n", current.ObjectsChanged, current.ObjectsPerRun())
+		fmt.Fprintf(&b, "| Files with errors | %d |\n", current.FilesWithErrors)
+		fmt.Fprintf(&b, "| Median duration | %s |\n", formatDuration(current.MedianDuration))
+	} else {
+		fmt.Fprintf(&b, "| Metric | Last %d runs | Previous %d runs | Trend |\n|--------|------|----------|-------|\n", current.Runs, previous.Runs)
+		fmt.Fprintf(&b, "| Error rate | %.0f%% (%s) | %.0f%% (%s) | %s |\n",
+			current.ErrorRate(), reportfmt.Plural(current.Failed, "failed run"), previous.ErrorRate(), reportfmt.Plural(previous.Failed, "failed run"),
+			trend(current.ErrorRate(), previous.ErrorRate(), "worse", "better"))
+		fmt.Fprintf(&b, "| Objects changed | %d (%.1f per run) | %d (%.1f per run) | %s |\n",
+			current.ObjectsChanged, current.ObjectsPerRun(), previous.ObjectsChanged, previous.ObjectsPerRun(),
+			trend(current.ObjectsPerRun(), previous.ObjectsPerRun(), "up", "down"))
+		fmt.Fprintf(&b, "| Files with errors | %d | %d | %s |\n", current.FilesWithErrors, previous.FilesWithErrors,
+			trend(float64(current.FilesWithErrors), float64(previous.FilesWithErrors), "worse", "better"))
+		fmt.Fprintf(&b, "| Median duration | %s | %s | %s |\n", formatDuration(current.MedianDuration), formatDuration(previous.MedianDuration),
+			trend(current.MedianDuration.Seconds(), previous.MedianDuration.Seconds(), "slower", "faster"))
+	}
+
+	b.WriteString("\n### Runs\n\n| Started | Commit | Files | Objects changed | Errors | Duration | Result |\n|---------|--------|-------|-----------------|--------|----------|--------|\n")
+	for _, run := range r.Runs {
+		result := "succeeded"
+		switch {
+		case run.Aborted:
+			result = "aborted"
+		case run.Failed():
+			result = "failed"
+		}
+		commit := "-"
+		if run.Commit != "" {
+			commit = "`" + shortCommit(run.Commit) + "`"
+		}
+		if run.RunURL != "" {
+			result = fmt.Sprintf("[%s](%s)", result, run.RunURL)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %s | %s |\n", run.StartedAt.UTC().Format("2006-01-02 15:04"), commit,
+			run.FilesProcessed, run.ObjectsChanged, run.Errors, formatDuration(run.Duration()), result)
+	}
+	return b.String()
+}
+
+// trend describes how a metric moved from the previous window
+func trend(current, previous float64, up, down string) string {
+	const tolerance = 0.05
+	switch {
+	case current > previous*(1+tolerance) && current-previous > 1e-9:
+		return up
+	case current < previous*(1-tolerance):
+		return down
+	default:
+		return "steady"
+	}
+}
+
+// formatDuration rounds a duration for reports, e.g. 1m12s
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// shortCommit returns the abbreviated form of a commit SHA
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,75 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/state"
+)
+
+// runs returns count runs an hour apart; failed reports which fail
+func runs(count int, failed func(i int) bool) []state.Run {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	history := make([]state.Run, count)
+	for i := range history {
+		history[i] = state.Run{
+			StartedAt:      start.Add(time.Duration(i) * time.Hour),
+			DurationMs:     int64(10+i) * 1000,
+			FilesProcessed: 2,
+			ObjectsChanged: i,
+		}
+		if failed(i) {
+			history[i].Errors = 1
+			history[i].FilesWithErrors = 1
+		}
+	}
+	return history
+}
+
+func TestNew(t *testing.T) {
+	// The first 4 runs succeed, 1 of the next 4 fails
+	report := New(runs(8, func(i int) bool { return i == 7 }), 4)
+
+	if report.Current.Runs != 4 || report.Current.Failed != 1 || report.Current.ErrorRate() != 25 {
+		t.Errorf("unexpected current window: %+v", report.Current)
+	}
+	if report.Previous.Runs != 4 || report.Previous.Failed != 0 || report.Previous.ObjectsChanged != 6 {
+		t.Errorf("unexpected previous window: %+v", report.Previous)
+	}
+	if report.Current.MedianDuration != 15500*time.Millisecond {
+		t.Errorf("unexpected median duration: %s", report.Current.MedianDuration)
+	}
+	if len(report.Runs) != 4 || report.Runs[0].ObjectsChanged != 7 {
+		t.Errorf("expected the current runs newest first, got %+v", report.Runs)
+	}
+
+	// A short history has no previous window
+	short := New(runs(3, func(int) bool { return false }), 30)
+	if short.Current.Runs != 3 || short.Previous.Runs != 0 {
+		t.Errorf("unexpected windows: %+v, %+v", short.Current, short.Previous)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	history := runs(4, func(i int) bool { return i >= 2 })
+	history[3].Commit = "0123456789abcdef"
+	history[3].RunURL = "https://github.com/acme/nobl9/actions/runs/42"
+
+	markdown := New(history, 2).Markdown()
+	for _, expected := range []string{
+		"Last 2 runs, from 2026-10-01 to 2026-10-01.",
+		"| Error rate | 100% (2 failed runs) | 0% (0 failed runs) | worse |",
+		"| Objects changed | 5 (2.5 per run) | 1 (0.5 per run) | up |",
+		"| Median duration | 13s | 11s | slower |",
+		"| 2026-10-01 15:00 | `0123456` | 2 | 3 | 1 | 13s | [failed](https://github.com/acme/nobl9/actions/runs/42) |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in\n%s", expected, markdown)
+		}
+	}
+
+	if empty := New(nil, 30).Markdown(); !strings.Contains(empty, "No runs recorded yet.") {
+		t.Errorf("unexpected report of an empty history:\n%s", empty)
+	}
+}
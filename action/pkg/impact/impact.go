@@ -0,0 +1,121 @@
+package impact
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/your-org/nobl9-action/pkg/compare"
+)
+
+// DefaultThreshold is the share of an objective's error budget, in percent,
+// a change must remove to be reported
+const DefaultThreshold = 25.0
+
+// Change is an SLO objective whose error budget a change shrinks enough
+// that the SLO may breach as soon as it is applied
+type Change struct {
+	Object    compare.Key `json:"object"`
+	Objective string      `json:"objective"`
+	// Source is the file declaring the SLO
+	Source string `json:"source,omitempty"`
+	// PreviousTarget and Target are the live and declared targets, e.g. 0.99
+	PreviousTarget float64 `json:"previous_target"`
+	Target         float64 `json:"target"`
+	// BudgetShrink is the share of the error budget removed, in percent
+	BudgetShrink float64 `json:"budget_shrink"`
+}
+
+// String describes the change, e.g. "SLO payments/latency objective fast:
+// target 99% -> 99.5% shrinks the error budget by 50%"
+func (c Change) String() string {
+	return fmt.Sprintf("%s objective %s: target %s -> %s shrinks the error budget by %.0f%%",
+		c.Object, c.Objective, Percent(c.PreviousTarget), Percent(c.Target), c.BudgetShrink)
+}
+
+// Percent formats a target as a percentage, e.g. 0.995 as 99.5%. It is
+// rounded to four decimals to hide floating point noise.
+func Percent(target float64) string {
+	return fmt.Sprintf("%g%%", math.Round(target*1e6)/1e4)
+}
+
+// Checker reports SLO objectives whose error budget shrinks by Threshold
+// percent or more
+type Checker struct {
+	Threshold float64
+}
+
+// New creates a checker reporting budgets that shrink by threshold percent
+// or more
+func New(threshold float64) *Checker {
+	return &Checker{Threshold: threshold}
+}
+
+// ValidateThreshold checks that a threshold is a percentage; 0 disables the
+// check
+func ValidateThreshold(threshold float64) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("threshold must be between 0 and 100 percent, got %g", threshold)
+	}
+	return nil
+}
+
+// Check compares the declared SLOs with their live versions. Objectives are
+// matched by name; new SLOs, new objectives and objectives without a target,
+// such as composite ones, are not reported.
+func (c *Checker) Check(desired []manifest.Object, live map[compare.Key]manifest.Object) []Change {
+	if c.Threshold <= 0 {
+		return nil
+	}
+
+	var changes []Change
+	for _, obj := range desired {
+		slo, ok := obj.(v1alphaSLO.SLO)
+		if !ok {
+			continue
+		}
+		key := compare.KeyOf(obj)
+		previous, ok := live[key].(v1alphaSLO.SLO)
+		if !ok {
+			continue
+		}
+
+		targets := make(map[string]float64, len(previous.Spec.Objectives))
+		for _, objective := range previous.Spec.Objectives {
+			if objective.BudgetTarget != nil {
+				targets[objective.Name] = *objective.BudgetTarget
+			}
+		}
+
+		for _, objective := range slo.Spec.Objectives {
+			previousTarget, found := targets[objective.Name]
+			if !found || objective.BudgetTarget == nil {
+				continue
+			}
+			shrink := BudgetShrink(previousTarget, *objective.BudgetTarget)
+			if shrink < c.Threshold || shrink <= 0 {
+				continue
+			}
+			changes = append(changes, Change{
+				Object:         key,
+				Objective:      objective.Name,
+				PreviousTarget: previousTarget,
+				Target:         *objective.BudgetTarget,
+				BudgetShrink:   shrink,
+			})
+		}
+	}
+	return changes
+}
+
+// BudgetShrink returns the share of the error budget, in percent, removed by
+// moving a target from previous to target. Raising 99% to 99.5% halves the
+// budget and returns 50; loosening a target returns a negative share.
+func BudgetShrink(previous, target float64) float64 {
+	previousBudget := 1 - previous
+	if previousBudget <= 0 {
+		return 0
+	}
+	return (previousBudget - (1 - target)) / previousBudget * 100
+}
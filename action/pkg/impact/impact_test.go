@@ -0,0 +1,93 @@
+package impact
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/compare"
+)
+
+const slo = `
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: checkout-latency
+  project: payments
+spec:
+  service: checkout
+  budgetingMethod: Occurrences
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+  objectives:
+    - name: fast
+      displayName: Fast
+      value: 200
+      target: %s
+      op: lte
+    - name: slow
+      displayName: Slow
+      value: 1000
+      target: %s
+      op: lte
+`
+
+func decode(t *testing.T, fast, slow string) manifest.Object {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(fmt.Sprintf(slo, fast, slow)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return objects[0]
+}
+
+func TestCheck(t *testing.T) {
+	live := decode(t, "0.99", "0.999")
+	desired := decode(t, "0.995", "0.998")
+	liveObjects := map[compare.Key]manifest.Object{compare.KeyOf(live): live}
+
+	changes := New(DefaultThreshold).Check([]manifest.Object{desired}, liveObjects)
+	if len(changes) != 1 {
+		t.Fatalf("expected only the tightened objective, got %+v", changes)
+	}
+	change := changes[0]
+	if change.Objective != "fast" || change.Object.Project != "payments" || math.Abs(change.BudgetShrink-50) > 1e-9 {
+		t.Errorf("unexpected change: %+v", change)
+	}
+	if text := change.String(); text != "SLO payments/checkout-latency objective fast: target 99% -> 99.5% shrinks the error budget by 50%" {
+		t.Errorf("unexpected description: %s", text)
+	}
+
+	// A higher threshold and new SLOs report nothing
+	if changes := New(60).Check([]manifest.Object{desired}, liveObjects); len(changes) != 0 {
+		t.Errorf("expected no change above the threshold, got %+v", changes)
+	}
+	if changes := New(DefaultThreshold).Check([]manifest.Object{desired}, nil); len(changes) != 0 {
+		t.Errorf("expected new SLOs not to be reported, got %+v", changes)
+	}
+	if changes := New(0).Check([]manifest.Object{desired}, liveObjects); len(changes) != 0 {
+		t.Errorf("expected a zero threshold to disable the check, got %+v", changes)
+	}
+}
+
+func TestBudgetShrink(t *testing.T) {
+	for _, test := range []struct {
+		previous, target, expected float64
+	}{
+		{0.99, 0.995, 50},
+		{0.99, 0.999, 90},
+		{0.99, 0.98, -100},
+		{1, 1, 0},
+	} {
+		if shrink := BudgetShrink(test.previous, test.target); math.Abs(shrink-test.expected) > 1e-9 {
+			t.Errorf("%g -> %g: expected %g, got %g", test.previous, test.target, test.expected, shrink)
+		}
+	}
+}
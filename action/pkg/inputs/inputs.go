@@ -0,0 +1,236 @@
+package inputs
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// inputExpression matches the ${{ inputs.<name> }} expressions of action.yml
+var inputExpression = regexp.MustCompile(`\$\{\{\s*inputs\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// Input is an input of the action
+type Input struct {
+	Name     string
+	Default  string
+	Required bool
+}
+
+// Action is what action.yml declares about the inputs of the action and how
+// they reach the command
+type Action struct {
+	// Inputs in the order action.yml declares them
+	Inputs []Input
+	// Args maps inputs passed as arguments to the flag they are passed as
+	Args map[string]string
+	// Env maps inputs passed as environment variables to the variable
+	Env map[string]string
+}
+
+// actionFile is the part of action.yml read by Load
+type actionFile struct {
+	Inputs yaml.Node `yaml:"inputs"`
+	Runs   struct {
+		Env  map[string]string `yaml:"env"`
+		Args []string          `yaml:"args"`
+	} `yaml:"runs"`
+}
+
+// Load reads the inputs of an action.yml file and the arguments and
+// environment variables they are passed on as
+func Load(path string) (*Action, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses the inputs of action.yml content and the arguments and
+// environment variables they are passed on as
+func Parse(data []byte) (*Action, error) {
+	var file actionFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse action file: %w", err)
+	}
+
+	action := &Action{Args: make(map[string]string), Env: make(map[string]string)}
+	if file.Inputs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(file.Inputs.Content); i += 2 {
+			var input struct {
+				Default  string `yaml:"default"`
+				Required bool   `yaml:"required"`
+			}
+			if err := file.Inputs.Content[i+1].Decode(&input); err != nil {
+				return nil, fmt.Errorf("invalid input %s: %w", file.Inputs.Content[i].Value, err)
+			}
+			action.Inputs = append(action.Inputs, Input{
+				Name:     file.Inputs.Content[i].Value,
+				Default:  input.Default,
+				Required: input.Required,
+			})
+		}
+	}
+
+	// Arguments are either --flag=${{ inputs.name }} or --flag followed by
+	// ${{ inputs.name }}
+	for i, arg := range file.Runs.Args {
+		match := inputExpression.FindStringSubmatch(arg)
+		if match == nil {
+			continue
+		}
+		flag, _, found := strings.Cut(arg, "=")
+		if !found {
+			if i == 0 || !strings.HasPrefix(file.Runs.Args[i-1], "--") {
+				return nil, fmt.Errorf("input %s is passed without a flag", match[1])
+			}
+			flag = file.Runs.Args[i-1]
+		}
+		action.Args[match[1]] = strings.TrimPrefix(flag, "--")
+	}
+	for name, value := range file.Runs.Env {
+		if match := inputExpression.FindStringSubmatch(value); match != nil {
+			action.Env[match[1]] = name
+		}
+	}
+
+	return action, nil
+}
+
+// Flag is a flag of a command the action runs
+type Flag struct {
+	Name string
+	// Type is the pflag type, e.g. string, bool or duration
+	Type     string
+	Default  string
+	Required bool
+}
+
+// Mapping describes how the entrypoint hands the arguments of the action on
+// to the commands
+type Mapping struct {
+	// Renamed maps arguments to the flag of the command they are passed as
+	Renamed map[string]string
+	// Modes are arguments that choose the command rather than set a flag
+	Modes map[string]bool
+	// EnvFlags maps environment variables to the flag they stand in for
+	EnvFlags map[string]string
+	// Unexposed are flags that are deliberately not inputs, with the reason
+	Unexposed map[string]string
+	// Optional are inputs that are deliberately optional although the flag
+	// they reach is required, with the reason
+	Optional map[string]string
+}
+
+// Problem is a difference between the inputs and the flags
+type Problem struct {
+	Input   string
+	Flag    string
+	Message string
+}
+
+// String formats the problem with the input or flag it concerns
+func (p Problem) String() string {
+	switch {
+	case p.Input != "" && p.Flag != "":
+		return fmt.Sprintf("input %s (--%s): %s", p.Input, p.Flag, p.Message)
+	case p.Input != "":
+		return fmt.Sprintf("input %s: %s", p.Input, p.Message)
+	default:
+		return fmt.Sprintf("flag --%s: %s", p.Flag, p.Message)
+	}
+}
+
+// Verify compares the inputs of the action with the flags of the commands
+// it runs. Every input must reach a flag with the same default and the same
+// required setting, unless the mapping lists it as optional, and every flag
+// must be exposed as an input unless the mapping lists it as unexposed.
+func Verify(action *Action, flags map[string]Flag, mapping Mapping) []Problem {
+	var problems []Problem
+	exposed := make(map[string]bool)
+
+	for _, input := range action.Inputs {
+		arg, passed := action.Args[input.Name]
+		variable, inEnv := action.Env[input.Name]
+		if !passed && !inEnv {
+			problems = append(problems, Problem{Input: input.Name, Message: "never passed to the action"})
+			continue
+		}
+		if inEnv {
+			if flag, found := mapping.EnvFlags[variable]; found {
+				exposed[flag] = true
+			}
+		}
+		if !passed || mapping.Modes[arg] {
+			continue
+		}
+
+		name := arg
+		if renamed, found := mapping.Renamed[arg]; found {
+			name = renamed
+		}
+		flag, found := flags[name]
+		if !found {
+			problems = append(problems, Problem{Input: input.Name, Flag: name, Message: "no command has this flag"})
+			continue
+		}
+		exposed[name] = true
+
+		if !sameDefault(flag, input.Default) {
+			problems = append(problems, Problem{
+				Input:   input.Name,
+				Flag:    name,
+				Message: fmt.Sprintf("default %q differs from the flag default %q", input.Default, flag.Default),
+			})
+		}
+		if _, optional := mapping.Optional[input.Name]; input.Required != flag.Required && !(optional && !input.Required) {
+			problems = append(problems, Problem{
+				Input:   input.Name,
+				Flag:    name,
+				Message: fmt.Sprintf("required is %t but the flag's is %t", input.Required, flag.Required),
+			})
+		}
+	}
+
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, unexposed := mapping.Unexposed[name]; !exposed[name] && !unexposed {
+			problems = append(problems, Problem{Flag: name, Message: "not exposed as an input"})
+		}
+	}
+
+	return problems
+}
+
+// sameDefault reports whether an input default sets the flag to its
+// default, comparing values of typed flags by their value rather than text
+func sameDefault(flag Flag, value string) bool {
+	switch flag.Type {
+	case "bool":
+		want, errWant := strconv.ParseBool(flag.Default)
+		got, errGot := strconv.ParseBool(value)
+		return errWant == nil && errGot == nil && want == got
+	case "duration":
+		want, errWant := time.ParseDuration(flag.Default)
+		got, errGot := time.ParseDuration(value)
+		return errWant == nil && errGot == nil && want == got
+	case "int", "float64":
+		want, errWant := strconv.ParseFloat(flag.Default, 64)
+		got, errGot := strconv.ParseFloat(value, 64)
+		return errWant == nil && errGot == nil && want == got
+	case "stringArray", "stringSlice":
+		return strings.Trim(flag.Default, "[]") == value
+	default:
+		return flag.Default == value
+	}
+}
@@ -0,0 +1,125 @@
+package inputs
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAction = `name: test
+inputs:
+  client-id:
+    description: 'Client ID'
+    required: true
+  dry-run:
+    description: 'Dry run'
+    default: 'false'
+  user-cache-ttl:
+    default: '24h'
+  max-rps:
+    default: '0'
+  kinds:
+    default: 'project'
+  vars:
+    default: ''
+  plan-out:
+    default: ''
+  validate-only:
+    default: 'false'
+  forgotten:
+    default: ''
+runs:
+  using: docker
+  env:
+    NOBL9_VARS: ${{ inputs.vars }}
+  args:
+    - '--client-id'
+    - '${{ inputs.client-id }}'
+    - '--dry-run=${{ inputs.dry-run }}'
+    - '--user-cache-ttl=${{ inputs.user-cache-ttl }}'
+    - '--max-rps=${{ inputs.max-rps }}'
+    - '--kinds=${{ inputs.kinds }}'
+    - '--plan-out=${{ inputs.plan-out }}'
+    - '--validate-only'
+    - '${{ inputs.validate-only }}'
+`
+
+func TestParse(t *testing.T) {
+	action, err := Parse([]byte(testAction))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(action.Inputs) != 9 || action.Inputs[0] != (Input{Name: "client-id", Required: true}) {
+		t.Errorf("unexpected inputs %+v", action.Inputs)
+	}
+	if action.Inputs[2].Default != "24h" {
+		t.Errorf("expected the user-cache-ttl default 24h, got %q", action.Inputs[2].Default)
+	}
+	for input, flag := range map[string]string{"client-id": "client-id", "dry-run": "dry-run", "plan-out": "plan-out", "validate-only": "validate-only"} {
+		if action.Args[input] != flag {
+			t.Errorf("expected input %s to be passed as --%s, got %q", input, flag, action.Args[input])
+		}
+	}
+	if action.Env["vars"] != "NOBL9_VARS" {
+		t.Errorf("expected vars to be passed as NOBL9_VARS, got %+v", action.Env)
+	}
+
+	if _, err := Parse([]byte("runs:\n  args:\n    - '${{ inputs.orphan }}'\n")); err == nil {
+		t.Error("expected an error for an input passed without a flag")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	action, err := Parse([]byte(testAction))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flags := map[string]Flag{
+		"client-id":      {Name: "client-id", Type: "string", Required: true},
+		"dry-run":        {Name: "dry-run", Type: "bool", Default: "false"},
+		"user-cache-ttl": {Name: "user-cache-ttl", Type: "duration", Default: "24h0m0s"},
+		"max-rps":        {Name: "max-rps", Type: "float64", Default: "0"},
+		"kinds":          {Name: "kinds", Type: "string", Default: "all"},
+		"vars":           {Name: "vars", Type: "stringArray", Default: "[]"},
+		"out":            {Name: "out", Type: "string", Required: true},
+		"log-level":      {Name: "log-level", Type: "string", Default: "info"},
+		"output":         {Name: "output", Type: "string", Default: "text"},
+	}
+	mapping := Mapping{
+		Renamed:   map[string]string{"plan-out": "out"},
+		Modes:     map[string]bool{"validate-only": true},
+		EnvFlags:  map[string]string{"NOBL9_VARS": "vars"},
+		Unexposed: map[string]string{"output": "not needed"},
+	}
+
+	var got []string
+	for _, problem := range Verify(action, flags, mapping) {
+		got = append(got, problem.String())
+	}
+	expected := []string{
+		`input kinds (--kinds): default "project" differs from the flag default "all"`,
+		`input plan-out (--out): required is false but the flag's is true`,
+		`input forgotten: never passed to the action`,
+		`flag --log-level: not exposed as an input`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected problems:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Deliberately optional inputs and matching flags leave nothing to report
+	mapping.Optional = map[string]string{"plan-out": "selects the plan command"}
+	mapping.Unexposed["log-level"] = "not needed"
+	flags["kinds"] = Flag{Name: "kinds", Type: "string", Default: "project"}
+	flags["forgotten"] = Flag{Name: "forgotten", Type: "string"}
+	action.Args["forgotten"] = "forgotten"
+	if problems := Verify(action, flags, mapping); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	// An input passed as a flag no command has
+	delete(flags, "dry-run")
+	problems := Verify(action, flags, mapping)
+	if len(problems) != 1 || problems[0].String() != "input dry-run (--dry-run): no command has this flag" {
+		t.Errorf("unexpected problems %v", problems)
+	}
+}
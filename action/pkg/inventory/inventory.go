@@ -0,0 +1,248 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/your-org/nobl9-action/pkg/reportfmt"
+)
+
+// Formats are the formats an inventory can be written in
+var Formats = []string{"markdown", "json"}
+
+// ParseFormat parses the value of --output
+func ParseFormat(value string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	for _, known := range Formats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format '%s', expected %s", value, strings.Join(Formats, ", "))
+}
+
+// Item is an SLO with the file declaring it, empty for a live SLO
+type Item struct {
+	Object manifest.Object
+	Source string
+}
+
+// Objective is an objective of an SLO
+type Objective struct {
+	Name     string   `json:"name"`
+	Target   float64  `json:"target"`
+	Value    *float64 `json:"value,omitempty"`
+	Operator string   `json:"operator,omitempty"`
+}
+
+// SLO is an inventoried SLO
+type SLO struct {
+	Name            string      `json:"name"`
+	DisplayName     string      `json:"display_name,omitempty"`
+	Service         string      `json:"service"`
+	Objectives      []Objective `json:"objectives"`
+	TimeWindow      string      `json:"time_window,omitempty"`
+	BudgetingMethod string      `json:"budgeting_method,omitempty"`
+	DataSource      string      `json:"data_source,omitempty"`
+	AlertPolicies   []string    `json:"alert_policies"`
+	Source          string      `json:"source,omitempty"`
+}
+
+// Service groups the SLOs of a service, sorted by name
+type Service struct {
+	Name string `json:"name"`
+	SLOs []SLO  `json:"slos"`
+}
+
+// Project groups the services of a project, sorted by name
+type Project struct {
+	Name     string    `json:"name"`
+	Services []Service `json:"services"`
+}
+
+// Warning is a problem of an SLO worth a look, such as a missing alert
+// policy
+type Warning struct {
+	Project string `json:"project"`
+	SLO     string `json:"slo"`
+	Message string `json:"message"`
+}
+
+// Report is the inventory of the SLOs of the repository or of Nobl9
+type Report struct {
+	Live     bool      `json:"live"`
+	SLOs     int       `json:"slos"`
+	Projects []Project `json:"projects"`
+	Warnings []Warning `json:"warnings"`
+}
+
+// New builds the inventory of the SLOs among the items; other kinds are
+// ignored. An SLO without an alert policy is warned about, since its
+// budget can burn without anyone being told.
+func New(items []Item, live bool) *Report {
+	byProject := make(map[string]map[string][]SLO)
+	report := &Report{Live: live, Warnings: []Warning{}}
+	for _, item := range items {
+		obj, ok := item.Object.(v1alphaSLO.SLO)
+		if !ok {
+			continue
+		}
+		slo := inventorySLO(obj, item.Source)
+		project := obj.Metadata.Project
+		if byProject[project] == nil {
+			byProject[project] = make(map[string][]SLO)
+		}
+		byProject[project][slo.Service] = append(byProject[project][slo.Service], slo)
+		report.SLOs++
+
+		if len(slo.AlertPolicies) == 0 {
+			report.Warnings = append(report.Warnings, Warning{Project: project, SLO: slo.Name, Message: "no alert policy attached"})
+		}
+	}
+
+	for name, services := range byProject {
+		project := Project{Name: name}
+		for service, slos := range services {
+			sort.Slice(slos, func(i, j int) bool { return slos[i].Name < slos[j].Name })
+			project.Services = append(project.Services, Service{Name: service, SLOs: slos})
+		}
+		sort.Slice(project.Services, func(i, j int) bool { return project.Services[i].Name < project.Services[j].Name })
+		report.Projects = append(report.Projects, project)
+	}
+	sort.Slice(report.Projects, func(i, j int) bool { return report.Projects[i].Name < report.Projects[j].Name })
+	sort.Slice(report.Warnings, func(i, j int) bool {
+		if report.Warnings[i].Project != report.Warnings[j].Project {
+			return report.Warnings[i].Project < report.Warnings[j].Project
+		}
+		return report.Warnings[i].SLO < report.Warnings[j].SLO
+	})
+	return report
+}
+
+// inventorySLO summarizes an SLO
+func inventorySLO(obj v1alphaSLO.SLO, source string) SLO {
+	slo := SLO{
+		Name:            obj.Metadata.Name,
+		DisplayName:     obj.Metadata.DisplayName,
+		Service:         obj.Spec.Service,
+		BudgetingMethod: obj.Spec.BudgetingMethod,
+		AlertPolicies:   append([]string{}, obj.Spec.AlertPolicies...),
+		Source:          source,
+	}
+	for _, objective := range obj.Spec.Objectives {
+		o := Objective{Name: objective.Name, Target: objective.GetBudgetTarget(), Value: objective.Value}
+		if objective.Operator != nil {
+			o.Operator = *objective.Operator
+		}
+		slo.Objectives = append(slo.Objectives, o)
+	}
+	if len(obj.Spec.TimeWindows) > 0 {
+		slo.TimeWindow = timeWindow(obj.Spec.TimeWindows[0])
+	}
+	if obj.Spec.Indicator != nil {
+		slo.DataSource = dataSource(obj.Spec.Indicator.MetricSource, obj.Metadata.Project)
+	}
+	return slo
+}
+
+// timeWindow describes a time window, e.g. "28 days rolling"
+func timeWindow(window v1alphaSLO.TimeWindow) string {
+	unit := strings.ToLower(window.Unit)
+	if window.Count != 1 {
+		unit += "s"
+	}
+	kind := "calendar"
+	if window.IsRolling {
+		kind = "rolling"
+	}
+	return fmt.Sprintf("%d %s %s", window.Count, unit, kind)
+}
+
+// dataSource describes the agent or direct of an SLO, naming its project
+// when it is not the SLO's, e.g. "Agent prometheus" or
+// "Direct shared/datadog"
+func dataSource(source v1alphaSLO.MetricSourceSpec, project string) string {
+	kind := source.Kind
+	if kind == 0 {
+		kind = manifest.KindAgent
+	}
+	name := source.Name
+	if source.Project != "" && source.Project != project {
+		name = source.Project + "/" + name
+	}
+	return kind.String() + " " + name
+}
+
+// Render writes the report in one of Formats
+func (r *Report) Render(format string) ([]byte, error) {
+	switch format {
+	case "markdown":
+		return []byte(r.Markdown()), nil
+	case "json":
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode SLO inventory: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+	_, err := ParseFormat(format)
+	return nil, err
+}
+
+// Markdown renders the inventory, one table per project
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Nobl9 SLO Inventory\n\n")
+	source := "the repository"
+	if r.Live {
+		source = "Nobl9"
+	}
+	if r.SLOs == 0 {
+		fmt.Fprintf(&b, "No SLOs found in %s.\n", source)
+		return b.String()
+	}
+
+	services := 0
+	for _, project := range r.Projects {
+		services += len(project.Services)
+	}
+	fmt.Fprintf(&b, "%s of %s in %s, from %s; %s.\n", reportfmt.Plural(r.SLOs, "SLO"), reportfmt.Plural(services, "service"),
+		reportfmt.Plural(len(r.Projects), "project"), source, reportfmt.Plural(len(r.Warnings), "warning"))
+
+	for _, project := range r.Projects {
+		fmt.Fprintf(&b, "\n### %s\n\n", project.Name)
+		b.WriteString("| Service | SLO | Objectives | Time window | Data source | Alert policies |\n|---------|-----|------------|-------------|-------------|----------------|\n")
+		for _, service := range project.Services {
+			for _, slo := range service.SLOs {
+				objectives := make([]string, 0, len(slo.Objectives))
+				for _, objective := range slo.Objectives {
+					objectives = append(objectives, objective.String())
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", service.Name, slo.Name, reportfmt.OrDash(strings.Join(objectives, "<br>")),
+					reportfmt.OrDash(slo.TimeWindow), reportfmt.OrDash(slo.DataSource), reportfmt.OrDash(strings.Join(slo.AlertPolicies, ", ")))
+			}
+		}
+	}
+
+	if len(r.Warnings) > 0 {
+		b.WriteString("\n### Warnings\n\n")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(&b, "- `%s/%s`: %s\n", warning.Project, warning.SLO, warning.Message)
+		}
+	}
+	return b.String()
+}
+
+// String describes an objective, e.g. "fast 99.5% (lte 200)"
+func (o Objective) String() string {
+	description := o.Name + " " + strconv.FormatFloat(o.Target*100, 'f', -1, 64) + "%"
+	if o.Value != nil && o.Operator != "" {
+		description += fmt.Sprintf(" (%s %s)", o.Operator, strconv.FormatFloat(*o.Value, 'f', -1, 64))
+	}
+	return strings.TrimSpace(description)
+}
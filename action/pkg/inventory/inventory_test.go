@@ -0,0 +1,85 @@
+package inventory
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaService "github.com/nobl9/nobl9-go/manifest/v1alpha/service"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+)
+
+// slo returns an SLO of a service with one objective and the alert policies
+func slo(project, service, name string, alertPolicies ...string) manifest.Object {
+	target, value, operator := 0.995, 200.0, "lte"
+	return v1alphaSLO.New(v1alphaSLO.Metadata{Name: name, Project: project}, v1alphaSLO.Spec{
+		Service:         service,
+		BudgetingMethod: "Occurrences",
+		Indicator:       &v1alphaSLO.Indicator{MetricSource: v1alphaSLO.MetricSourceSpec{Name: "prometheus", Project: "shared"}},
+		TimeWindows:     []v1alphaSLO.TimeWindow{{Unit: "Day", Count: 28, IsRolling: true}},
+		Objectives: []v1alphaSLO.Objective{{
+			ObjectiveBase: v1alphaSLO.ObjectiveBase{Name: "fast", Value: &value},
+			BudgetTarget:  &target,
+			Operator:      &operator,
+		}},
+		AlertPolicies: alertPolicies,
+	})
+}
+
+func TestNew(t *testing.T) {
+	items := []Item{
+		{Object: slo("payments", "api", "latency", "fast-burn"), Source: "payments.yaml"},
+		{Object: slo("payments", "api", "availability"), Source: "payments.yaml"},
+		{Object: slo("checkout", "web", "errors", "fast-burn", "slow-burn"), Source: "checkout.yaml"},
+		{Object: v1alphaService.New(v1alphaService.Metadata{Name: "api", Project: "payments"}, v1alphaService.Spec{}), Source: "payments.yaml"},
+	}
+
+	report := New(items, false)
+	if report.SLOs != 3 || len(report.Projects) != 2 || report.Projects[0].Name != "checkout" {
+		t.Fatalf("unexpected inventory: %+v", report)
+	}
+	api := report.Projects[1].Services[0]
+	if api.Name != "api" || len(api.SLOs) != 2 || api.SLOs[0].Name != "availability" {
+		t.Fatalf("expected the SLOs of the api service sorted by name, got %+v", api)
+	}
+	latency := api.SLOs[1]
+	if latency.TimeWindow != "28 days rolling" || latency.DataSource != "Agent shared/prometheus" || latency.Source != "payments.yaml" {
+		t.Errorf("unexpected SLO: %+v", latency)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].SLO != "availability" || report.Warnings[0].Project != "payments" {
+		t.Errorf("expected a warning for the SLO without an alert policy, got %+v", report.Warnings)
+	}
+
+	markdown := report.Markdown()
+	for _, expected := range []string{
+		"3 SLOs of 2 services in 2 projects, from the repository; 1 warning.",
+		"| web | errors | fast 99.5% (lte 200) | 28 days rolling | Agent shared/prometheus | fast-burn, slow-burn |",
+		"| api | availability | fast 99.5% (lte 200) | 28 days rolling | Agent shared/prometheus | - |",
+		"- `payments/availability`: no alert policy attached",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in markdown:\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	report := New([]Item{{Object: slo("payments", "api", "latency", "fast-burn")}}, true)
+
+	data, err := report.Render("json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Live || decoded.SLOs != 1 || len(decoded.Warnings) != 0 {
+		t.Errorf("unexpected JSON %s (%v)", data, err)
+	}
+
+	if markdown := New(nil, true).Markdown(); !strings.Contains(markdown, "No SLOs found in Nobl9.") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+	if _, err := report.Render("csv"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
@@ -0,0 +1,72 @@
+// Package kindlist parses the comma separated lists of object kinds the
+// commands take, such as --export-kinds, and tells which kinds live inside
+// a project.
+package kindlist
+
+import (
+	"fmt"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+)
+
+// ProjectScoped are the kinds whose objects live inside a project, so they
+// are exported, promoted and copied on a rename along with it
+var ProjectScoped = []manifest.Kind{
+	manifest.KindService,
+	manifest.KindAgent,
+	manifest.KindDirect,
+	manifest.KindAlertMethod,
+	manifest.KindAlertPolicy,
+	manifest.KindSLO,
+	manifest.KindAlertSilence,
+	manifest.KindAnnotation,
+	manifest.KindRoleBinding,
+	manifest.KindDataExport,
+}
+
+// Check rejects a kind a list may not hold
+type Check func(kind manifest.Kind) error
+
+// Parse parses a comma separated list of kinds such as "slo, rolebinding"
+// in the order given, dropping repeats. Every kind has to pass the checks,
+// in order. An empty list returns nil, for the caller to pick its defaults.
+func Parse(spec string, checks ...Check) ([]manifest.Kind, error) {
+	seen := make(map[manifest.Kind]bool)
+	var kinds []manifest.Kind
+	for _, name := range commalist.Split(spec) {
+		kind, err := manifest.ParseKind(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown kind '%s'", name)
+		}
+		for _, check := range checks {
+			if err := check(kind); err != nil {
+				return nil, err
+			}
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// IsProjectScoped reports whether objects of the kind live inside a project
+func IsProjectScoped(kind manifest.Kind) bool {
+	for _, scoped := range ProjectScoped {
+		if scoped == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireProjectScoped is a Check rejecting kinds that do not live inside
+// a project
+func RequireProjectScoped(kind manifest.Kind) error {
+	if !IsProjectScoped(kind) {
+		return fmt.Errorf("kind '%s' is not project-scoped", kind)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package kindlist
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+)
+
+func TestParse(t *testing.T) {
+	kinds, err := Parse(" ")
+	if err != nil || kinds != nil {
+		t.Errorf("expected no kinds for an empty list, got %v, %v", kinds, err)
+	}
+
+	kinds, err = Parse("slo, Project,slo")
+	if err != nil || len(kinds) != 2 || kinds[0] != manifest.KindSLO || kinds[1] != manifest.KindProject {
+		t.Errorf("unexpected kinds: %v, %v", kinds, err)
+	}
+
+	if _, err := Parse("slo,widget"); err == nil || err.Error() != "unknown kind 'widget'" {
+		t.Errorf("expected an unknown kind error, got %v", err)
+	}
+
+	noAgents := func(kind manifest.Kind) error {
+		if kind == manifest.KindAgent {
+			return fmt.Errorf("no agents")
+		}
+		return nil
+	}
+	if _, err := Parse("slo,agent", RequireProjectScoped, noAgents); err == nil || err.Error() != "no agents" {
+		t.Errorf("expected the check to reject agents, got %v", err)
+	}
+	if _, err := Parse("project", RequireProjectScoped, noAgents); err == nil || err.Error() != "kind 'Project' is not project-scoped" {
+		t.Errorf("expected a project-scoped error, got %v", err)
+	}
+}
+
+func TestIsProjectScoped(t *testing.T) {
+	if !IsProjectScoped(manifest.KindRoleBinding) || IsProjectScoped(manifest.KindProject) || IsProjectScoped(manifest.KindUserGroup) {
+		t.Error("unexpected project scopes")
+	}
+}
@@ -0,0 +1,319 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/your-org/nobl9-action/pkg/impact"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Rule IDs reported with findings
+const (
+	RuleObjectiveTargets    = "objective-targets"
+	RuleTimeWindow          = "time-window"
+	RuleMissingAlertPolicy  = "missing-alert-policy"
+	RuleCompositeComponents = "composite-components"
+	RuleDisplayName         = "display-name"
+)
+
+// Thresholds of the checks
+const (
+	// MinErrorBudget is the smallest error budget per time window an
+	// objective should leave; a smaller one is spent by a single bad minute
+	MinErrorBudget = time.Minute
+	// MinRollingWindow is the shortest rolling time window that smooths out
+	// daily traffic patterns
+	MinRollingWindow = 24 * time.Hour
+	// MaxDisplayNameLength is the longest display name Nobl9 shows in full
+	MaxDisplayNameLength = 63
+)
+
+// Finding is a likely mistake in an SLO. Findings are warnings: the SLO is
+// valid and is still applied.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Kind    string `json:"kind"`
+	Project string `json:"project,omitempty"`
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// String describes the finding, e.g. SLO payments/latency: message
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s/%s: %s", f.Kind, f.Project, f.Name, f.Message)
+}
+
+// Lint checks the SLOs among the objects. Composite SLO components are
+// checked against every declared SLO, so all objects of a run are linted
+// together. Findings are sorted by file, project, name and rule.
+func Lint(items []planner.Item) []Finding {
+	declared := make(map[string]v1alphaSLO.SLO)
+	for _, item := range items {
+		if slo, ok := item.Object.(v1alphaSLO.SLO); ok {
+			declared[sloKey(slo.Metadata.Project, slo.Metadata.Name)] = slo
+		}
+	}
+
+	var findings []Finding
+	displayNames := make(map[string]string)
+	for _, item := range items {
+		slo, ok := item.Object.(v1alphaSLO.SLO)
+		if !ok {
+			continue
+		}
+		report := func(rule, format string, args ...interface{}) {
+			findings = append(findings, Finding{
+				Rule:    rule,
+				Kind:    manifest.KindSLO.String(),
+				Project: slo.Metadata.Project,
+				Name:    slo.Metadata.Name,
+				Source:  item.Source,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		checkTargets(slo, report)
+		checkThresholds(slo, report)
+		checkTimeWindows(slo, report)
+		if len(slo.Spec.AlertPolicies) == 0 {
+			report(RuleMissingAlertPolicy, "no alert policies, so nobody is notified when the error budget burns")
+		}
+		checkComposite(slo, declared, report)
+		checkDisplayNames(slo, displayNames, report)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Rule < b.Rule
+	})
+	return findings
+}
+
+// reporter records a finding of the SLO being checked
+type reporter func(rule, format string, args ...interface{})
+
+// checkTargets flags objectives whose target leaves less than
+// MinErrorBudget of error budget per time window
+func checkTargets(slo v1alphaSLO.SLO, report reporter) {
+	if len(slo.Spec.TimeWindows) == 0 {
+		return
+	}
+	window := windowDuration(slo.Spec.TimeWindows[0])
+	if window == 0 {
+		return
+	}
+
+	for _, objective := range slo.Spec.Objectives {
+		if objective.BudgetTarget == nil || *objective.BudgetTarget <= 0 || *objective.BudgetTarget > 1 {
+			continue
+		}
+		target := *objective.BudgetTarget
+		budget := time.Duration((1 - target) * float64(window))
+		if budget < MinErrorBudget {
+			report(RuleObjectiveTargets, "objective %s target %s leaves %s of error budget per %s window, less than %s",
+				objectiveName(objective), impact.Percent(target), budget.Round(time.Second), windowName(slo.Spec.TimeWindows[0]), MinErrorBudget)
+		}
+	}
+}
+
+// checkThresholds compares the threshold objectives of an SLO. A looser
+// threshold is met by every event meeting a stricter one, so it needs a
+// higher target; otherwise it can never breach first and only adds noise.
+// Objectives sharing a threshold are flagged too.
+func checkThresholds(slo v1alphaSLO.SLO, report reporter) {
+	type threshold struct {
+		objective v1alphaSLO.Objective
+		value     float64
+		target    float64
+	}
+	byOperator := make(map[string][]threshold)
+	for _, objective := range slo.Spec.Objectives {
+		if objective.RawMetric == nil || objective.Operator == nil || objective.Value == nil || objective.BudgetTarget == nil {
+			continue
+		}
+		op := *objective.Operator
+		byOperator[op] = append(byOperator[op], threshold{objective: objective, value: *objective.Value, target: *objective.BudgetTarget})
+	}
+
+	operators := make([]string, 0, len(byOperator))
+	for op := range byOperator {
+		operators = append(operators, op)
+	}
+	sort.Strings(operators)
+	for _, op := range operators {
+		thresholds := byOperator[op]
+		// Order from the strictest threshold to the loosest
+		lower := op == "lt" || op == "lte"
+		sort.SliceStable(thresholds, func(i, j int) bool {
+			if lower {
+				return thresholds[i].value < thresholds[j].value
+			}
+			return thresholds[i].value > thresholds[j].value
+		})
+
+		for i := 1; i < len(thresholds); i++ {
+			stricter, looser := thresholds[i-1], thresholds[i]
+			switch {
+			case stricter.value == looser.value:
+				report(RuleObjectiveTargets, "objectives %s and %s share the threshold %g",
+					objectiveName(stricter.objective), objectiveName(looser.objective), looser.value)
+			case looser.target <= stricter.target:
+				report(RuleObjectiveTargets, "objective %s (%s %g) has a target of %s, not above the %s of the stricter objective %s (%s %g), so it can never breach first",
+					objectiveName(looser.objective), op, looser.value, impact.Percent(looser.target),
+					impact.Percent(stricter.target), objectiveName(stricter.objective), op, stricter.value)
+			}
+		}
+	}
+}
+
+// checkTimeWindows flags rolling windows too short to smooth out daily
+// traffic and calendar windows whose budget resets mid-day
+func checkTimeWindows(slo v1alphaSLO.SLO, report reporter) {
+	for _, window := range slo.Spec.TimeWindows {
+		if window.IsRolling {
+			if duration := windowDuration(window); duration > 0 && duration < MinRollingWindow {
+				report(RuleTimeWindow, "rolling %s window is shorter than a day, so the error budget follows daily traffic patterns", windowName(window))
+			}
+			continue
+		}
+
+		if window.Calendar == nil {
+			continue
+		}
+		start, err := time.Parse("2006-01-02 15:04:05", window.Calendar.StartTime)
+		if err == nil && (start.Hour() != 0 || start.Minute() != 0 || start.Second() != 0) {
+			report(RuleTimeWindow, "calendar window starts at %s, so the error budget resets mid-day", start.Format("15:04:05"))
+		}
+	}
+}
+
+// checkComposite flags composite components referencing SLOs the
+// repository does not declare, objectives a declared SLO does not have and
+// components listed twice
+func checkComposite(slo v1alphaSLO.SLO, declared map[string]v1alphaSLO.SLO, report reporter) {
+	for _, objective := range slo.Spec.Objectives {
+		if objective.Composite == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, component := range objective.Composite.Objectives {
+			ref := fmt.Sprintf("%s/%s", component.Project, component.SLO)
+			if seen[ref+"/"+component.Objective] {
+				report(RuleCompositeComponents, "component %s objective %s is listed more than once", ref, component.Objective)
+				continue
+			}
+			seen[ref+"/"+component.Objective] = true
+
+			target, found := declared[sloKey(component.Project, component.SLO)]
+			if !found {
+				report(RuleCompositeComponents, "component SLO %s is not declared in the repository; make sure it exists in Nobl9", ref)
+				continue
+			}
+			if !hasObjective(target, component.Objective) {
+				report(RuleCompositeComponents, "component SLO %s has no objective %s", ref, component.Objective)
+			}
+		}
+	}
+}
+
+// checkDisplayNames flags SLOs and objectives without a display name, with
+// surrounding or repeated spaces or longer than Nobl9 shows, and SLOs of a
+// project sharing a display name. displayNames records the SLO first using
+// each display name of a project.
+func checkDisplayNames(slo v1alphaSLO.SLO, displayNames map[string]string, report reporter) {
+	displayName := slo.Metadata.DisplayName
+	if displayName == "" {
+		report(RuleDisplayName, "no displayName, so Nobl9 shows the name %s", slo.Metadata.Name)
+	} else {
+		key := sloKey(slo.Metadata.Project, strings.ToLower(strings.TrimSpace(displayName)))
+		if first, found := displayNames[key]; found && first != slo.Metadata.Name {
+			report(RuleDisplayName, "displayName %q is also used by SLO %s", displayName, first)
+		} else {
+			displayNames[key] = slo.Metadata.Name
+		}
+	}
+	checkDisplayName("displayName", displayName, report)
+
+	for _, objective := range slo.Spec.Objectives {
+		field := fmt.Sprintf("objective %s displayName", objective.Name)
+		if objective.DisplayName == "" {
+			report(RuleDisplayName, "objective %s has no displayName", objective.Name)
+		}
+		checkDisplayName(field, objective.DisplayName, report)
+	}
+}
+
+// checkDisplayName flags a display name with surrounding or repeated spaces
+// or longer than MaxDisplayNameLength
+func checkDisplayName(field, displayName string, report reporter) {
+	if displayName == "" {
+		return
+	}
+	if strings.TrimSpace(displayName) != displayName || strings.Contains(displayName, "  ") {
+		report(RuleDisplayName, "%s %q has surrounding or repeated spaces", field, displayName)
+	}
+	if len(displayName) > MaxDisplayNameLength {
+		report(RuleDisplayName, "%s is %d characters, longer than the %d Nobl9 shows", field, len(displayName), MaxDisplayNameLength)
+	}
+}
+
+// windowDuration returns the length of a time window, approximating
+// calendar months, quarters and years, or 0 for an unknown unit
+func windowDuration(window v1alphaSLO.TimeWindow) time.Duration {
+	day := 24 * time.Hour
+	units := map[string]time.Duration{
+		"Second":  time.Second,
+		"Minute":  time.Minute,
+		"Hour":    time.Hour,
+		"Day":     day,
+		"Week":    7 * day,
+		"Month":   30 * day,
+		"Quarter": 91 * day,
+		"Year":    365 * day,
+	}
+	return time.Duration(window.Count) * units[window.Unit]
+}
+
+// windowName describes a time window, e.g. 28 Day
+func windowName(window v1alphaSLO.TimeWindow) string {
+	return fmt.Sprintf("%d %s", window.Count, window.Unit)
+}
+
+// objectiveName names an objective by its name, or its display name when
+// it has none
+func objectiveName(objective v1alphaSLO.Objective) string {
+	if objective.Name != "" {
+		return objective.Name
+	}
+	return objective.DisplayName
+}
+
+// hasObjective reports whether an SLO has an objective of the given name
+func hasObjective(slo v1alphaSLO.SLO, name string) bool {
+	for _, objective := range slo.Spec.Objectives {
+		if objective.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sloKey identifies an SLO by project and name
+func sloKey(project, name string) string {
+	return project + "/" + name
+}
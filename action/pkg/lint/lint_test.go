@@ -0,0 +1,152 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+const slos = `- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout-latency
+    displayName: Checkout latency
+    project: payments
+  spec:
+    service: checkout
+    budgetingMethod: Occurrences
+    alertPolicies: [fast-burn]
+    indicator:
+      metricSource:
+        name: prometheus
+    timeWindows:
+      - unit: Day
+        count: 28
+        isRolling: true
+    objectives:
+      - name: fast
+        displayName: Fast
+        value: 200
+        target: 0.99
+        op: lte
+        rawMetric:
+          query:
+            prometheus:
+              promql: latency
+      - name: slow
+        displayName: Slow
+        value: 1000
+        target: 0.995
+        op: lte
+        rawMetric:
+          query:
+            prometheus:
+              promql: latency
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: search-latency
+    displayName: "Checkout  latency"
+    project: payments
+  spec:
+    service: search
+    budgetingMethod: Occurrences
+    indicator:
+      metricSource:
+        name: prometheus
+    timeWindows:
+      - unit: Hour
+        count: 1
+        isRolling: true
+    objectives:
+      - name: fast
+        value: 200
+        target: 0.99999
+        op: lte
+        rawMetric:
+          query:
+            prometheus:
+              promql: latency
+      - name: slow
+        displayName: Slow
+        value: 1000
+        target: 0.95
+        op: lte
+        rawMetric:
+          query:
+            prometheus:
+              promql: latency
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout
+    displayName: Checkout
+    project: payments
+  spec:
+    service: checkout
+    budgetingMethod: Occurrences
+    alertPolicies: [fast-burn]
+    timeWindows:
+      - unit: Month
+        count: 1
+        isRolling: false
+        calendar:
+          startTime: "2024-01-01 12:00:00"
+          timeZone: UTC
+    objectives:
+      - name: composite
+        displayName: Composite
+        target: 0.99
+        composite:
+          maxDelay: 10m
+          components:
+            objectives:
+              - project: payments
+                slo: checkout-latency
+                objective: fast
+                weight: 1
+                whenDelayed: CountAsGood
+              - project: payments
+                slo: checkout-latency
+                objective: medium
+                weight: 1
+                whenDelayed: CountAsGood
+              - project: payments
+                slo: checkout-errors
+                objective: errors
+                weight: 1
+                whenDelayed: CountAsGood
+`
+
+func TestLint(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(slos))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := make([]planner.Item, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, planner.Item{Object: obj, Source: "slos.yaml"})
+	}
+
+	var found []string
+	for _, finding := range Lint(items) {
+		found = append(found, finding.Rule+" "+finding.Name+": "+finding.Message)
+	}
+
+	expected := []string{
+		"composite-components checkout: component SLO payments/checkout-latency has no objective medium",
+		"composite-components checkout: component SLO payments/checkout-errors is not declared in the repository; make sure it exists in Nobl9",
+		"time-window checkout: calendar window starts at 12:00:00, so the error budget resets mid-day",
+		"display-name search-latency: displayName \"Checkout  latency\" has surrounding or repeated spaces",
+		"display-name search-latency: objective fast has no displayName",
+		"missing-alert-policy search-latency: no alert policies, so nobody is notified when the error budget burns",
+		"objective-targets search-latency: objective fast target 99.999% leaves 0s of error budget per 1 Hour window, less than 1m0s",
+		"objective-targets search-latency: objective slow (lte 1000) has a target of 95%, not above the 99.999% of the stricter objective fast (lte 200), so it can never breach first",
+		"time-window search-latency: rolling 1 Hour window is shorter than a day, so the error budget follows daily traffic patterns",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected findings:\n%s", strings.Join(found, "\n"))
+	}
+}
@@ -87,6 +87,9 @@ func New(level Level, format Format) *Logger {
 	// Set output to stdout
 	logger.SetOutput(os.Stdout)
 
+	// Scrub credentials from every entry
+	logger.AddHook(NewRedactHook(defaultRedactor))
+
 	return &Logger{
 		Logger: logger,
 		fields: make(logrus.Fields),
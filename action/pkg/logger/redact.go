@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces secrets in log messages and fields
+const Redacted = "[REDACTED]"
+
+// minSecretLength is the length below which a value is not treated as a
+// secret, so that short values such as "1" do not blank out unrelated text
+const minSecretLength = 8
+
+// secretPatterns match credentials that look like credentials, whether or
+// not their value was registered: authorization headers, bearer tokens,
+// secret and token parameters, and JWTs such as Nobl9 access tokens. The
+// last group of each pattern is the value that is replaced.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)authorization["']?\s*[:=]\s*["'\[]?(?:(?:bearer|basic|ssws)\s+)?([^\s"',}\]]+)`),
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	regexp.MustCompile(`(?i)\b(?:client[_-]?secret|access[_-]?token|refresh[_-]?token|id[_-]?token|api[_-]?token|password)["']?\s*[:=]\s*["']?([^\s"'&,}\]]+)`),
+	regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*)`),
+}
+
+// sensitiveFieldNames are parts of field names whose values are always
+// redacted, whatever they hold
+var sensitiveFieldNames = []string{"secret", "token", "password", "authorization"}
+
+// Redactor scrubs secrets from log output. Registered secrets are replaced
+// wherever they appear; values that look like credentials are replaced even
+// when they were never registered.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+	// out receives ::add-mask:: commands when mask is set
+	out  io.Writer
+	mask bool
+}
+
+// NewRedactor creates a redactor. When mask is set, every registered secret
+// is also written to out as a GitHub Actions ::add-mask:: command, so the
+// runner hides it from output the redactor never sees.
+func NewRedactor(out io.Writer, mask bool) *Redactor {
+	return &Redactor{out: out, mask: mask}
+}
+
+// defaultRedactor scrubs the loggers created by New and those passed to
+// InstallRedaction
+var defaultRedactor = NewRedactor(os.Stdout, isGitHubActions())
+
+// AddSecret registers secrets with the default redactor
+func AddSecret(secrets ...string) {
+	defaultRedactor.Add(secrets...)
+}
+
+// Redact scrubs secrets from s with the default redactor
+func Redact(s string) string {
+	return defaultRedactor.Redact(s)
+}
+
+// InstallRedaction adds the hook of the default redactor to a logrus logger,
+// such as logrus.StandardLogger(). Installing it more than once has no
+// effect.
+func InstallRedaction(logger *logrus.Logger) {
+	for _, hook := range logger.Hooks[logrus.PanicLevel] {
+		if redact, ok := hook.(*RedactHook); ok && redact.redactor == defaultRedactor {
+			return
+		}
+	}
+	logger.AddHook(NewRedactHook(defaultRedactor))
+}
+
+// Add registers secrets to be replaced. Empty and short values are ignored.
+// Secrets spanning several lines are registered line by line, as runners
+// mask output one line at a time.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		for _, line := range strings.Split(secret, "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) < minSecretLength || r.known(line) {
+				continue
+			}
+			r.secrets = append(r.secrets, line)
+			if r.mask && r.out != nil {
+				fmt.Fprintf(r.out, "::add-mask::%s\n", line)
+			}
+		}
+	}
+
+	// Longer secrets first, so a secret containing another is replaced whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// known reports whether a secret is registered; the caller holds the lock
+func (r *Redactor) known(secret string) bool {
+	for _, existing := range r.secrets {
+		if existing == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns s with registered secrets and credential-like values
+// replaced by Redacted
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	r.mu.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = replaceValues(s, pattern)
+	}
+	return s
+}
+
+// replaceValues replaces the last group of every match of pattern with
+// Redacted, leaving values that are already redacted as they are
+func replaceValues(s string, pattern *regexp.Regexp) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := match[len(match)-2], match[len(match)-1]
+		if start < 0 || strings.HasPrefix(s[start:], Redacted) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Redacted)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// RedactValue scrubs a log field value. Strings and errors, including the
+// messages of every error they wrap, are redacted as text. Other values are
+// replaced by their redacted text only when formatting them reveals a
+// secret, so numbers, times and booleans keep their type.
+func (r *Redactor) RedactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.Redact(v)
+	case error:
+		return r.Redact(v.Error())
+	case map[string]interface{}:
+		return r.redactFields(v)
+	case logrus.Fields:
+		return logrus.Fields(r.redactFields(v))
+	case Fields:
+		return Fields(r.redactFields(v))
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = r.Redact(s)
+		}
+		return redacted
+	default:
+		text := fmt.Sprint(v)
+		if redacted := r.Redact(text); redacted != text {
+			return redacted
+		}
+		return value
+	}
+}
+
+// redactFields returns a copy of fields with their values scrubbed
+func (r *Redactor) redactFields(fields map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if sensitiveField(key) {
+			if text, ok := value.(string); ok && text != "" {
+				redacted[key] = Redacted
+				continue
+			}
+		}
+		redacted[key] = r.RedactValue(value)
+	}
+	return redacted
+}
+
+// sensitiveField reports whether a field name marks its value as secret
+func sensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range sensitiveFieldNames {
+		if strings.Contains(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHook is a logrus hook that scrubs the message and fields of every
+// entry before it is formatted
+type RedactHook struct {
+	redactor *Redactor
+}
+
+// NewRedactHook creates a hook scrubbing entries with redactor
+func NewRedactHook(redactor *Redactor) *RedactHook {
+	return &RedactHook{redactor: redactor}
+}
+
+// Levels returns all levels, as any entry may carry a secret
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the entry in place
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.Redact(entry.Message)
+	if len(entry.Data) > 0 {
+		entry.Data = h.redactor.redactFields(entry.Data)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/expfmt"
+)
+
+// DefaultJob is the Pushgateway job the metrics are pushed under
+const DefaultJob = "nobl9-action"
+
+// namespace prefixes every metric name
+const namespace = "nobl9_action"
+
+// Collector records the metrics of a run for a Prometheus Pushgateway. A nil
+// collector records nothing, so callers need not check whether metrics are
+// enabled.
+type Collector struct {
+	registry *prometheus.Registry
+
+	objects     *prometheus.CounterVec
+	apiCalls    *prometheus.CounterVec
+	apiLatency  *prometheus.HistogramVec
+	retries     *prometheus.CounterVec
+	failures    *prometheus.CounterVec
+	runDuration prometheus.Gauge
+	runSuccess  prometheus.Gauge
+	lastRun     prometheus.Gauge
+}
+
+// New creates a collector with its own registry
+func New() *Collector {
+	c := &Collector{
+		registry: prometheus.NewRegistry(),
+		objects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "objects_total",
+			Help:      "Objects processed by the run, by kind and status (applied, unchanged, failed, ...).",
+		}, []string{"kind", "status"}),
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_calls_total",
+			Help:      "Nobl9 API requests sent, by method, endpoint and status code (0 when no response was received).",
+		}, []string{"method", "endpoint", "code"}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_call_duration_seconds",
+			Help:      "Latency of Nobl9 API requests, by method and endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_retries_total",
+			Help:      "Nobl9 API requests sent again, by reason.",
+		}, []string{"reason"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failures_total",
+			Help:      "Errors of the run, by processing phase and error type.",
+		}, []string{"phase", "type"}),
+		runDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Duration of the run.",
+		}),
+		runSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "run_success",
+			Help:      "1 if the run completed without errors, 0 otherwise.",
+		}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix time the run finished.",
+		}),
+	}
+	c.registry.MustRegister(c.objects, c.apiCalls, c.apiLatency, c.retries, c.failures, c.runDuration, c.runSuccess, c.lastRun)
+	return c
+}
+
+// ObserveAPICall records a Nobl9 API request; status is 0 when the request
+// failed without a response
+func (c *Collector) ObserveAPICall(method, endpoint string, status int, duration time.Duration) {
+	if c == nil {
+		return
+	}
+	c.apiCalls.WithLabelValues(method, endpoint, strconv.Itoa(status)).Inc()
+	c.apiLatency.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+}
+
+// AddObjects counts objects of a kind that ended the run with a status
+func (c *Collector) AddObjects(kind, status string, count int) {
+	if c == nil || count <= 0 {
+		return
+	}
+	c.objects.WithLabelValues(kind, status).Add(float64(count))
+}
+
+// AddRetries counts requests sent again for a reason, e.g. rate_limited
+func (c *Collector) AddRetries(reason string, count int) {
+	if c == nil || count <= 0 {
+		return
+	}
+	c.retries.WithLabelValues(reason).Add(float64(count))
+}
+
+// AddFailure counts an error of a processing phase
+func (c *Collector) AddFailure(phase, errType string) {
+	if c == nil {
+		return
+	}
+	c.failures.WithLabelValues(phase, errType).Inc()
+}
+
+// SetRun records the duration and outcome of the finished run
+func (c *Collector) SetRun(duration time.Duration, success bool, finishedAt time.Time) {
+	if c == nil {
+		return
+	}
+	c.runDuration.Set(duration.Seconds())
+	c.runSuccess.Set(0)
+	if success {
+		c.runSuccess.Set(1)
+	}
+	c.lastRun.Set(float64(finishedAt.Unix()))
+}
+
+// Push replaces the metrics of the job and grouping labels on the
+// Pushgateway at url with the collected ones
+func (c *Collector) Push(ctx context.Context, url, job string, grouping map[string]string, httpClient *http.Client) error {
+	if c == nil {
+		return nil
+	}
+	if job == "" {
+		job = DefaultJob
+	}
+	pusher := push.New(url, job).
+		Gatherer(c.registry).
+		Format(expfmt.NewFormat(expfmt.TypeTextPlain))
+	if httpClient != nil {
+		pusher = pusher.Client(httpClient)
+	}
+	for name, value := range grouping {
+		if value != "" {
+			pusher = pusher.Grouping(name, value)
+		}
+	}
+	return pusher.PushContext(ctx)
+}
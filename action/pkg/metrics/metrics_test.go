@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPush(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New()
+	c.ObserveAPICall("PUT", "/api/apply", 200, 150*time.Millisecond)
+	c.ObserveAPICall("PUT", "/api/apply", 429, 10*time.Millisecond)
+	c.AddObjects("SLO", "applied", 3)
+	c.AddObjects("SLO", "failed", 1)
+	c.AddObjects("Service", "unchanged", 0)
+	c.AddRetries("rate_limited", 1)
+	c.AddFailure("apply", "nobl9_api")
+	c.SetRun(90*time.Second, false, time.Unix(1714564800, 0))
+
+	if err := c.Push(context.Background(), server.URL, "", map[string]string{"repository": "acme", "organization": ""}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/metrics/job/nobl9-action/repository/acme" {
+		t.Errorf("expected a PUT to the job and repository group, got %s %s", method, path)
+	}
+	for _, want := range []string{
+		`nobl9_action_api_calls_total{code="200",endpoint="/api/apply",method="PUT"} 1`,
+		`nobl9_action_api_calls_total{code="429",endpoint="/api/apply",method="PUT"} 1`,
+		`nobl9_action_api_call_duration_seconds_count{endpoint="/api/apply",method="PUT"} 2`,
+		`nobl9_action_objects_total{kind="SLO",status="applied"} 3`,
+		`nobl9_action_objects_total{kind="SLO",status="failed"} 1`,
+		`nobl9_action_api_retries_total{reason="rate_limited"} 1`,
+		`nobl9_action_failures_total{phase="apply",type="nobl9_api"} 1`,
+		`nobl9_action_run_duration_seconds 90`,
+		`nobl9_action_run_success 0`,
+		`nobl9_action_last_run_timestamp_seconds 1.7145648e+09`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in pushed metrics:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `kind="Service"`) {
+		t.Errorf("expected empty counts to be left out:\n%s", body)
+	}
+}
+
+func TestPushFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "push rejected", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := New().Push(context.Background(), server.URL, "sync", nil, nil); err == nil {
+		t.Fatal("expected an error for a rejected push")
+	}
+}
+
+func TestNilCollector(t *testing.T) {
+	var c *Collector
+	c.ObserveAPICall("GET", "/api/get/project", 200, time.Millisecond)
+	c.AddObjects("Project", "applied", 1)
+	c.AddRetries("rate_limited", 1)
+	c.AddFailure("parse", "validation")
+	c.SetRun(time.Second, true, time.Now())
+	if err := c.Push(context.Background(), "http://127.0.0.1:0", "", nil, nil); err != nil {
+		t.Errorf("expected a nil collector to push nothing, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package nobl9
+
+import (
+	"context"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	v2 "github.com/nobl9/nobl9-go/sdk/endpoints/users/v2"
+	"github.com/your-org/nobl9-action/pkg/roles"
+)
+
+// Nobl9API is the Nobl9 API as the validator, resolver and other packages
+// use it. Client implements it against Nobl9; tests pass the in-memory
+// double of pkg/nobl9/nobl9test instead, so they run without credentials.
+type Nobl9API interface {
+	// GetOrganization returns the organization of the credentials
+	GetOrganization(ctx context.Context) (string, error)
+
+	// GetProject returns a project, or an error wrapping
+	// errors.ErrProjectNotFound
+	GetProject(ctx context.Context, name string) (*project.Project, error)
+	CreateProject(ctx context.Context, projectObj *project.Project) error
+	UpdateProject(ctx context.Context, projectObj *project.Project) error
+	DeleteProject(ctx context.Context, name string) error
+	ListProjects(ctx context.Context) ([]project.Project, error)
+	ProjectExists(ctx context.Context, name string) (bool, error)
+	ProjectsExist(ctx context.Context, names []string) (map[string]bool, error)
+
+	// GetRoleBinding returns a role binding, or an error wrapping
+	// errors.ErrRoleBindingNotFound
+	GetRoleBinding(ctx context.Context, projectName, name string) (*rolebinding.RoleBinding, error)
+	CreateRoleBinding(ctx context.Context, roleBindingObj *rolebinding.RoleBinding) error
+	UpdateRoleBinding(ctx context.Context, roleBindingObj *rolebinding.RoleBinding) error
+	DeleteRoleBinding(ctx context.Context, projectName, name string) error
+	ListRoleBindings(ctx context.Context, projectName string) ([]rolebinding.RoleBinding, error)
+	GetOrganizationRoles(ctx context.Context) (*roles.Catalog, error)
+
+	// GetUser returns the user with the email; an unknown email is an error
+	// whose message contains "not found"
+	GetUser(ctx context.Context, email string) (*v2.User, error)
+	ListUsers(ctx context.Context) ([]*v2.User, error)
+
+	// GetObjects returns the live objects of a kind in a project, or in
+	// every project with sdk.ProjectsWildcard; names limits them when given
+	GetObjects(ctx context.Context, kind manifest.Kind, projectName string, names []string) ([]manifest.Object, error)
+
+	ApplyManifest(ctx context.Context, manifest []byte) error
+	ApplyObjects(ctx context.Context, objects []manifest.Object) error
+
+	// DeleteObject deletes an object of a kind by name; projectName is empty
+	// for objects outside projects
+	DeleteObject(ctx context.Context, kind manifest.Kind, projectName, name string) error
+
+	// DryRunObjects sends objects to Nobl9 with the dry-run flag, so they are
+	// checked as they would be applied without changing anything
+	DryRunObjects(ctx context.Context, objects []manifest.Object) error
+	ValidateManifest(ctx context.Context, manifest []byte) error
+}
+
+// Client is the Nobl9API used outside tests
+var _ Nobl9API = (*Client)(nil)
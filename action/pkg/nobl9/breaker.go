@@ -0,0 +1,80 @@
+package nobl9
+
+import (
+	"net/http"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+// CircuitBreaker is an http.RoundTripper that fails Nobl9 API requests fast
+// while its breaker is open, so a run does not keep calling an API that is
+// down
+type CircuitBreaker struct {
+	next    http.RoundTripper
+	breaker *retry.Breaker
+	logger  *logger.Logger
+}
+
+// BreakAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) with the breaker. Network errors and server errors count
+// as failures; other responses, including client errors, count as successes
+// since they show the API is up.
+func BreakAPICalls(httpClient *http.Client, breaker *retry.Breaker, log *logger.Logger) *CircuitBreaker {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	circuitBreaker := &CircuitBreaker{
+		next:    next,
+		breaker: breaker,
+		logger:  log,
+	}
+	httpClient.Transport = circuitBreaker
+
+	return circuitBreaker
+}
+
+// RoundTrip sends the request unless the breaker is open
+func (c *CircuitBreaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := c.breaker.Allow(); err != nil {
+		if c.logger != nil {
+			c.logger.Debug("Circuit breaker open, failing fast", logger.Fields{
+				"method":   req.Method,
+				"endpoint": req.URL.Path,
+			})
+		}
+		return nil, err
+	}
+
+	resp, err := c.next.RoundTrip(req)
+
+	// A cancelled request says nothing about the API
+	success := err == nil && resp.StatusCode < http.StatusInternalServerError
+	if err != nil && req.Context().Err() != nil {
+		success = true
+	}
+
+	from, to := c.breaker.Record(success)
+	if from != to && c.logger != nil {
+		fields := logger.Fields{
+			"from":     string(from),
+			"to":       string(to),
+			"endpoint": req.URL.Path,
+		}
+		if to == retry.BreakerOpen {
+			fields["cooldown"] = c.breaker.Cooldown().String()
+			c.logger.Warn("Circuit breaker opened, failing Nobl9 API calls fast", fields)
+		} else {
+			c.logger.Info("Circuit breaker state changed", fields)
+		}
+	}
+
+	return resp, err
+}
+
+// Stats returns the breaker's state and counters
+func (c *CircuitBreaker) Stats() retry.BreakerStats {
+	return c.breaker.Stats()
+}
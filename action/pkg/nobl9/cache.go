@@ -0,0 +1,208 @@
+package nobl9
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+// ResponseCache is an http.RoundTripper that answers repeated Nobl9 API GET
+// requests from memory, so reading the same project, role binding or user
+// several times in a run costs one call
+type ResponseCache struct {
+	next    http.RoundTripper
+	ttl     time.Duration
+	logger  *logger.Logger
+	entries map[string]*cachedResponse
+	stats   CacheStats
+	mutex   sync.Mutex
+}
+
+// CacheStats counts how GET requests were answered
+type CacheStats struct {
+	// Hits were answered from the cache without calling the API
+	Hits int
+	// Revalidated were answered from the cache after the API confirmed,
+	// with a 304 response to If-None-Match, that they had not changed
+	Revalidated int
+	// Misses were sent to the API
+	Misses int
+}
+
+// cachedResponse is a response kept by the cache
+type cachedResponse struct {
+	status   int
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// CacheAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) with a cache of successful and 404 GET responses, keyed
+// by URL and the project and organization headers. Responses are fresh for
+// ttl, or for the client's lifetime when ttl is 0. Stale responses with an
+// ETag are revalidated with If-None-Match. Any other request, such as an
+// apply or delete, empties the cache of its host, so reads after a write see
+// the change.
+func CacheAPICalls(httpClient *http.Client, ttl time.Duration, log *logger.Logger) *ResponseCache {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	cache := &ResponseCache{
+		next:    next,
+		ttl:     ttl,
+		logger:  log,
+		entries: make(map[string]*cachedResponse),
+	}
+	httpClient.Transport = cache
+
+	return cache
+}
+
+// RoundTrip answers GET requests from the cache when it can and sends the
+// others on
+func (c *ResponseCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		c.invalidate(req.URL.Host)
+		return c.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	c.mutex.Lock()
+	entry := c.entries[key]
+	if entry != nil && c.fresh(entry) {
+		c.stats.Hits++
+		c.mutex.Unlock()
+		c.debug("Answered Nobl9 API call from the cache", req)
+		return entry.response(req), nil
+	}
+	c.mutex.Unlock()
+
+	sent := req
+	if etag := entryETag(entry); etag != "" {
+		sent = req.Clone(req.Context())
+		sent.Header.Set("If-None-Match", etag)
+	}
+	resp, err := c.next.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		drain(resp)
+		c.mutex.Lock()
+		entry.storedAt = time.Now()
+		c.stats.Revalidated++
+		c.mutex.Unlock()
+		c.debug("Nobl9 API confirmed the cached response is unchanged", req)
+		return entry.response(req), nil
+	}
+
+	c.mutex.Lock()
+	c.stats.Misses++
+	c.mutex.Unlock()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry = &cachedResponse{
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     body,
+		storedAt: time.Now(),
+	}
+	c.mutex.Lock()
+	c.entries[key] = entry
+	c.mutex.Unlock()
+
+	return entry.response(req), nil
+}
+
+// Stats returns how the GET requests so far were answered
+func (c *ResponseCache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+// Reset empties the cache, e.g. after objects were changed outside the client
+func (c *ResponseCache) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]*cachedResponse)
+}
+
+// fresh reports whether an entry may be returned without calling the API
+func (c *ResponseCache) fresh(entry *cachedResponse) bool {
+	return c.ttl <= 0 || time.Since(entry.storedAt) < c.ttl
+}
+
+// invalidate drops the entries of a host
+func (c *ResponseCache) invalidate(host string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if keyHost(key) == host {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// debug logs a request answered from the cache
+func (c *ResponseCache) debug(message string, req *http.Request) {
+	if c.logger != nil {
+		c.logger.Debug(message, logger.Fields{
+			"method":   req.Method,
+			"endpoint": req.URL.Path,
+		})
+	}
+}
+
+// response returns a copy of the cached response for the request
+func (r *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		StatusCode:    r.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// cacheKey identifies a GET request by its host, URL and the headers that
+// select what the API returns
+func cacheKey(req *http.Request) string {
+	return req.URL.Host + " " + req.URL.String() + " " + req.Header.Get(sdk.HeaderProject) + " " + req.Header.Get(sdk.HeaderOrganization)
+}
+
+// keyHost returns the host of a cache key
+func keyHost(key string) string {
+	host, _, _ := strings.Cut(key, " ")
+	return host
+}
+
+// entryETag returns the ETag of an entry, or "" without entry or ETag
+func entryETag(entry *cachedResponse) string {
+	if entry == nil {
+		return ""
+	}
+	return entry.header.Get("ETag")
+}
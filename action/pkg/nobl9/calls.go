@@ -0,0 +1,81 @@
+package nobl9
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// CallCounter is an http.RoundTripper that counts Nobl9 API calls by endpoint
+type CallCounter struct {
+	next  http.RoundTripper
+	calls map[string]int
+	mutex sync.Mutex
+}
+
+// CountAPICalls wraps the transport of the given HTTP client (e.g. sdk.Client.HTTP)
+// so every request is counted by method and path. Retries performed by the
+// SDK's own transport are counted once per logical call.
+func CountAPICalls(httpClient *http.Client) *CallCounter {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	counter := &CallCounter{
+		next:  next,
+		calls: make(map[string]int),
+	}
+	httpClient.Transport = counter
+
+	return counter
+}
+
+// RoundTrip counts the request and delegates to the wrapped transport
+func (c *CallCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mutex.Lock()
+	c.calls[req.Method+" "+req.URL.Path]++
+	c.mutex.Unlock()
+
+	return c.next.RoundTrip(req)
+}
+
+// Counts returns a copy of the call counts keyed by "METHOD /path"
+func (c *CallCounter) Counts() map[string]int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counts := make(map[string]int, len(c.calls))
+	for endpoint, count := range c.calls {
+		counts[endpoint] = count
+	}
+
+	return counts
+}
+
+// Total returns the total number of API calls
+func (c *CallCounter) Total() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	total := 0
+	for _, count := range c.calls {
+		total += count
+	}
+
+	return total
+}
+
+// Endpoints returns the called endpoints in alphabetical order
+func (c *CallCounter) Endpoints() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	endpoints := make([]string, 0, len(c.calls))
+	for endpoint := range c.calls {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	return endpoints
+}
@@ -3,6 +3,8 @@ package nobl9
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/nobl9/nobl9-go/manifest"
@@ -22,6 +24,15 @@ type Client struct {
 	logger    *logger.Logger
 	config    *Config
 	retryOp   *retry.RetryableAPIOperation
+	profiles  *retry.Profiles
+	calls     *CallCounter
+	cache     *ResponseCache
+	projects  projectCache
+	roles     roleCache
+
+	// callOnce makes every call once, leaving retries to the caller; set by
+	// Wrap
+	callOnce bool
 }
 
 // Config holds Nobl9 client configuration
@@ -30,6 +41,31 @@ type Config struct {
 	ClientSecret  string
 	Timeout       time.Duration
 	RetryAttempts int
+	MaxRPS        float64 // Maximum API requests per second; 0 means unlimited
+
+	// CallTimeout bounds each API request on its own, below Timeout; 0
+	// disables it
+	CallTimeout time.Duration
+
+	// Consecutive API failures that open the circuit breaker (0 disables it)
+	// and how long it then fails calls fast
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Observer is told of every API request, e.g. to record metrics; nil
+	// disables it
+	Observer CallObserver
+
+	// RetryProfiles are the retry policies of user, apply and organization
+	// calls; nil gives each its retry.DefaultProfiles profile with
+	// RetryAttempts attempts
+	RetryProfiles *retry.Profiles
+
+	// DisableCache sends every GET request to the API instead of answering
+	// repeated ones from memory; CacheTTL is how long a response stays
+	// fresh, 0 for the client's lifetime
+	DisableCache bool
+	CacheTTL     time.Duration
 }
 
 // New creates a new Nobl9 client
@@ -60,15 +96,48 @@ func New(config *Config, log *logger.Logger) (*Client, error) {
 		return nil, errors.NewConfigError("failed to create Nobl9 SDK client", err)
 	}
 
-	// Create retry policy for API operations
+	// Create retry policy for API operations, and the policies of the
+	// operation classes
 	retryPolicy := retry.CreatePolicyForAPI(config.RetryAttempts)
 	retryOp := retry.NewRetryableAPIOperation(retryPolicy, log)
+	profiles := config.RetryProfiles
+	if profiles == nil {
+		if profiles, err = retry.ParseProfiles(retry.DefaultProfiles, config.RetryAttempts, 0); err != nil {
+			return nil, errors.NewConfigError("invalid retry policies", err)
+		}
+	}
+
+	// Give every request its own deadline below the rate limiter, so waiting
+	// for Retry-After does not count against it
+	DeadlineAPICalls(sdkClient.HTTP, config.CallTimeout, log)
+
+	// Report every request sent, including those sent again after
+	// Retry-After
+	if config.Observer != nil {
+		ObserveAPICalls(sdkClient.HTTP, config.Observer)
+	}
+
+	// Rate limit below the call counter so retries after Retry-After are
+	// counted once per logical call
+	RateLimitAPICalls(sdkClient.HTTP, retry.NewLimiter(config.MaxRPS), log)
 
 	client := &Client{
 		sdkClient: sdkClient,
 		logger:    log,
 		config:    config,
 		retryOp:   retryOp,
+		profiles:  profiles,
+		calls:     CountAPICalls(sdkClient.HTTP),
+	}
+	BreakAPICalls(sdkClient.HTTP, retry.NewBreaker(config.BreakerThreshold, config.BreakerCooldown), log)
+
+	// Trace each logical call, including calls the breaker rejects
+	TraceAPICalls(sdkClient.HTTP)
+
+	// Answer repeated reads above everything else, so cache hits are not
+	// counted, rate limited or traced as API calls
+	if !config.DisableCache {
+		client.cache = CacheAPICalls(sdkClient.HTTP, config.CacheTTL, log)
 	}
 
 	// Test connection
@@ -79,11 +148,27 @@ func New(config *Config, log *logger.Logger) (*Client, error) {
 	log.Info("Nobl9 client created successfully", logger.Fields{
 		"timeout":        config.Timeout.String(),
 		"retry_attempts": config.RetryAttempts,
+		"retry_policies": profiles.String(),
+		"max_rps":        config.MaxRPS,
 	})
 
 	return client, nil
 }
 
+// Wrap returns a Client around an SDK client the caller already created and
+// set its transports up for, e.g. rate limiting and call counting. Calls are
+// made once and not retried, as the caller retries them by its own policies,
+// and the connection is not tested.
+func Wrap(sdkClient *sdk.Client, log *logger.Logger) *Client {
+	return &Client{
+		sdkClient: sdkClient,
+		logger:    log,
+		config:    &Config{},
+		retryOp:   retry.NewRetryableAPIOperation(retry.CreatePolicyForAPI(1), log),
+		callOnce:  true,
+	}
+}
+
 // validateConfig validates the client configuration
 func validateConfig(config *Config) error {
 	if config == nil {
@@ -121,7 +206,7 @@ func (c *Client) testConnection() error {
 		return c.sdkClient.GetOrganization(ctx)
 	}
 
-	result, err := c.retryOp.Execute(ctx, "test connection", fn)
+	result, err := c.execute(ctx, retry.OperationOrganization, "test connection", fn)
 	if err != nil {
 		c.logger.LogDetailedError(err, "test connection", map[string]interface{}{
 			"endpoint": "/organizations",
@@ -154,7 +239,7 @@ func (c *Client) GetOrganization(ctx context.Context) (string, error) {
 		return c.sdkClient.GetOrganization(ctx)
 	}
 
-	result, err := c.retryOp.Execute(ctx, "get organization", fn)
+	result, err := c.execute(ctx, retry.OperationOrganization, "get organization", fn)
 	if err != nil {
 		c.logger.LogDetailedError(err, "get organization", map[string]interface{}{
 			"endpoint": "/organizations",
@@ -187,7 +272,7 @@ func (c *Client) GetProject(ctx context.Context, name string) (*project.Project,
 		return c.sdkClient.Objects().V1().GetV1alphaProjects(ctx, params)
 	}
 
-	result, err := c.retryOp.Execute(ctx, fmt.Sprintf("get project %s", name), fn)
+	result, err := c.executeDefault(ctx, fmt.Sprintf("get project %s", name), fn)
 	if err != nil {
 		c.logger.LogDetailedError(err, "get project", map[string]interface{}{
 			"endpoint":     "/projects/" + name,
@@ -202,7 +287,7 @@ func (c *Client) GetProject(ctx context.Context, name string) (*project.Project,
 
 	projects := result.([]project.Project)
 	if len(projects) == 0 {
-		c.logger.LogDetailedError(fmt.Errorf("project not found"), "get project", map[string]interface{}{
+		c.logger.LogDetailedError(errors.ErrProjectNotFound, "get project", map[string]interface{}{
 			"endpoint":     "/projects/" + name,
 			"method":       "GET",
 			"project_name": name,
@@ -210,7 +295,7 @@ func (c *Client) GetProject(ctx context.Context, name string) (*project.Project,
 		}, logger.Fields{
 			"error": "project not found",
 		})
-		return nil, errors.NewNobl9APIError(fmt.Sprintf("project %s not found", name), fmt.Errorf("project not found"))
+		return nil, errors.NewNobl9APIError(fmt.Sprintf("project %s not found", name), errors.ErrProjectNotFound)
 	}
 
 	project := &projects[0]
@@ -233,7 +318,7 @@ func (c *Client) CreateProject(ctx context.Context, projectObj *project.Project)
 		return nil, c.sdkClient.Objects().V1().Apply(ctx, objects)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("create project %s", projectObj.Metadata.Name), fn)
+	_, err := c.execute(ctx, retry.OperationApply, fmt.Sprintf("create project %s", projectObj.Metadata.Name), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("POST", "/projects", false, time.Since(start), logger.Fields{
 			"project_name": projectObj.Metadata.Name,
@@ -250,6 +335,7 @@ func (c *Client) CreateProject(ctx context.Context, projectObj *project.Project)
 	c.logger.LogProjectOperation("create", projectObj.Metadata.Name, true, logger.Fields{
 		"project_id": projectObj.Metadata.Name,
 	})
+	c.rememberProject(projectObj.Metadata.Name)
 
 	return nil
 }
@@ -264,7 +350,7 @@ func (c *Client) UpdateProject(ctx context.Context, projectObj *project.Project)
 		return nil, c.sdkClient.Objects().V1().Apply(ctx, objects)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("update project %s", projectObj.Metadata.Name), fn)
+	_, err := c.execute(ctx, retry.OperationApply, fmt.Sprintf("update project %s", projectObj.Metadata.Name), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("PUT", "/projects/"+projectObj.Metadata.Name, false, time.Since(start), logger.Fields{
 			"project_name": projectObj.Metadata.Name,
@@ -294,7 +380,7 @@ func (c *Client) DeleteProject(ctx context.Context, name string) error {
 		return nil, c.sdkClient.Objects().V1().DeleteByName(ctx, manifest.KindProject, "", name)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("delete project %s", name), fn)
+	_, err := c.executeDefault(ctx, fmt.Sprintf("delete project %s", name), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("DELETE", "/projects/"+name, false, time.Since(start), logger.Fields{
 			"project_name": name,
@@ -322,7 +408,7 @@ func (c *Client) ListProjects(ctx context.Context) ([]project.Project, error) {
 		return c.sdkClient.Objects().V1().GetV1alphaProjects(ctx, params)
 	}
 
-	result, err := c.retryOp.Execute(ctx, "list projects", fn)
+	result, err := c.executeDefault(ctx, "list projects", fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("GET", "/projects", false, time.Since(start), logger.Fields{
 			"error": err.Error(),
@@ -352,7 +438,7 @@ func (c *Client) GetRoleBinding(ctx context.Context, projectName, name string) (
 		return c.sdkClient.Objects().V1().GetV1alphaRoleBindings(ctx, params)
 	}
 
-	result, err := c.retryOp.Execute(ctx, fmt.Sprintf("get role binding %s in project %s", name, projectName), fn)
+	result, err := c.executeDefault(ctx, fmt.Sprintf("get role binding %s in project %s", name, projectName), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("GET", "/projects/"+projectName+"/rolebindings/"+name, false, time.Since(start), logger.Fields{
 			"project_name":      projectName,
@@ -369,7 +455,7 @@ func (c *Client) GetRoleBinding(ctx context.Context, projectName, name string) (
 			"role_binding_name": name,
 			"error":             "role binding not found",
 		})
-		return nil, fmt.Errorf("role binding %s not found in project %s", name, projectName)
+		return nil, fmt.Errorf("%w: %s in project %s", errors.ErrRoleBindingNotFound, name, projectName)
 	}
 
 	roleBinding := &roleBindings[0]
@@ -395,7 +481,7 @@ func (c *Client) CreateRoleBinding(ctx context.Context, roleBindingObj *rolebind
 		return nil, c.sdkClient.Objects().V1().Apply(ctx, objects)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("create role binding %s in project %s", roleBindingObj.Metadata.Name, projectName), fn)
+	_, err := c.execute(ctx, retry.OperationApply, fmt.Sprintf("create role binding %s in project %s", roleBindingObj.Metadata.Name, projectName), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("POST", "/projects/"+projectName+"/rolebindings", false, time.Since(start), logger.Fields{
 			"project_name":      projectName,
@@ -430,7 +516,7 @@ func (c *Client) UpdateRoleBinding(ctx context.Context, roleBindingObj *rolebind
 		return nil, c.sdkClient.Objects().V1().Apply(ctx, objects)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("update role binding %s in project %s", roleBindingObj.Metadata.Name, projectName), fn)
+	_, err := c.execute(ctx, retry.OperationApply, fmt.Sprintf("update role binding %s in project %s", roleBindingObj.Metadata.Name, projectName), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("PUT", "/projects/"+projectName+"/rolebindings/"+roleBindingObj.Metadata.Name, false, time.Since(start), logger.Fields{
 			"project_name":      projectName,
@@ -462,7 +548,7 @@ func (c *Client) DeleteRoleBinding(ctx context.Context, projectName, name string
 		return nil, c.sdkClient.Objects().V1().DeleteByName(ctx, manifest.KindRoleBinding, projectName, name)
 	}
 
-	_, err := c.retryOp.Execute(ctx, fmt.Sprintf("delete role binding %s in project %s", name, projectName), fn)
+	_, err := c.executeDefault(ctx, fmt.Sprintf("delete role binding %s in project %s", name, projectName), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("DELETE", "/projects/"+projectName+"/rolebindings/"+name, false, time.Since(start), logger.Fields{
 			"project_name":      projectName,
@@ -494,7 +580,7 @@ func (c *Client) ListRoleBindings(ctx context.Context, projectName string) ([]ro
 		return c.sdkClient.Objects().V1().GetV1alphaRoleBindings(ctx, params)
 	}
 
-	result, err := c.retryOp.Execute(ctx, fmt.Sprintf("list role bindings in project %s", projectName), fn)
+	result, err := c.executeDefault(ctx, fmt.Sprintf("list role bindings in project %s", projectName), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("GET", "/projects/"+projectName+"/rolebindings", false, time.Since(start), logger.Fields{
 			"project_name": projectName,
@@ -522,7 +608,7 @@ func (c *Client) GetUser(ctx context.Context, email string) (*v2.User, error) {
 		return c.sdkClient.Users().V2().GetUser(ctx, email)
 	}
 
-	result, err := c.retryOp.Execute(ctx, fmt.Sprintf("get user %s", email), fn)
+	result, err := c.execute(ctx, retry.OperationUsers, fmt.Sprintf("get user %s", email), fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("GET", "/users/"+email, false, time.Since(start), logger.Fields{
 			"email": email,
@@ -532,6 +618,13 @@ func (c *Client) GetUser(ctx context.Context, email string) (*v2.User, error) {
 	}
 
 	user := result.(*v2.User)
+	if user == nil {
+		c.logger.LogNobl9APICall("GET", "/users/"+email, false, time.Since(start), logger.Fields{
+			"email": email,
+			"error": "user not found",
+		})
+		return nil, fmt.Errorf("failed to get user %s: %w", email, errors.ErrUserNotFound)
+	}
 
 	c.logger.LogNobl9APICall("GET", "/users/"+email, true, time.Since(start), logger.Fields{
 		"email":   email,
@@ -565,28 +658,122 @@ func (c *Client) ListUsers(ctx context.Context) ([]*v2.User, error) {
 
 // ApplyManifest applies a Nobl9 manifest
 func (c *Client) ApplyManifest(ctx context.Context, manifest []byte) error {
+	objects, err := sdk.DecodeObjects(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to apply manifest: failed to decode manifest: %w", err)
+	}
+
+	return c.ApplyObjects(ctx, objects)
+}
+
+// ApplyObjects applies already decoded objects, so changes made after decoding
+// (such as role binding users resolved from emails) are what reaches Nobl9
+func (c *Client) ApplyObjects(ctx context.Context, objects []manifest.Object) error {
 	start := time.Now()
 
 	fn := func(ctx context.Context) (interface{}, error) {
-		// Decode manifest objects and apply them
-		objects, err := sdk.DecodeObjects(manifest)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode manifest: %w", err)
-		}
 		return nil, c.sdkClient.Objects().V1().Apply(ctx, objects)
 	}
 
-	_, err := c.retryOp.Execute(ctx, "apply manifest", fn)
+	_, err := c.execute(ctx, retry.OperationApply, "apply objects", fn)
 	if err != nil {
-		c.logger.LogNobl9APICall("POST", "/manifests", false, time.Since(start), logger.Fields{
-			"manifest_size": len(manifest),
-			"error":         err.Error(),
+		c.logger.LogNobl9APICall("PUT", "/apply", false, time.Since(start), logger.Fields{
+			"object_count": len(objects),
+			"error":        err.Error(),
 		})
 		return fmt.Errorf("failed to apply manifest: %w", err)
 	}
 
-	c.logger.LogNobl9APICall("POST", "/manifests", true, time.Since(start), logger.Fields{
-		"manifest_size": len(manifest),
+	c.logger.LogNobl9APICall("PUT", "/apply", true, time.Since(start), logger.Fields{
+		"object_count": len(objects),
+	})
+
+	return nil
+}
+
+// DryRunObjects sends objects to Nobl9 with the dry-run flag, so Nobl9 checks
+// them as it would when applying them (quotas, references, permissions)
+// without changing anything
+func (c *Client) DryRunObjects(ctx context.Context, objects []manifest.Object) error {
+	start := time.Now()
+
+	// The flag is set on a copy of the SDK client, so nothing this call
+	// sends can change Nobl9 state
+	fn := func(ctx context.Context) (interface{}, error) {
+		return nil, c.sdkClient.WithDryRun().Objects().V1().Apply(ctx, objects)
+	}
+
+	_, err := c.execute(ctx, retry.OperationApply, "dry run objects", fn)
+	if err != nil {
+		c.logger.LogNobl9APICall("PUT", "/apply?dry_run=true", false, time.Since(start), logger.Fields{
+			"object_count": len(objects),
+			"error":        err.Error(),
+		})
+		return fmt.Errorf("failed to dry run objects: %w", err)
+	}
+
+	c.logger.LogNobl9APICall("PUT", "/apply?dry_run=true", true, time.Since(start), logger.Fields{
+		"object_count": len(objects),
+	})
+
+	return nil
+}
+
+// GetObjects returns the live objects of a kind in a project, or in every
+// project with sdk.ProjectsWildcard, limited to names when any are given
+func (c *Client) GetObjects(ctx context.Context, kind manifest.Kind, projectName string, names []string) ([]manifest.Object, error) {
+	start := time.Now()
+
+	header := http.Header{sdk.HeaderProject: []string{projectName}}
+	var query url.Values
+	if len(names) > 0 {
+		query = url.Values{v1.QueryKeyName: names}
+	}
+	fn := func(ctx context.Context) (interface{}, error) {
+		return c.sdkClient.Objects().V1().Get(ctx, kind, header, query)
+	}
+
+	result, err := c.executeDefault(ctx, fmt.Sprintf("get %s objects in project %s", kind, projectName), fn)
+	if err != nil {
+		c.logger.LogNobl9APICall("GET", "/get/"+kind.ToLower(), false, time.Since(start), logger.Fields{
+			"project_name": projectName,
+			"error":        err.Error(),
+		})
+		return nil, fmt.Errorf("failed to get %s objects: %w", kind, err)
+	}
+
+	objects := result.([]manifest.Object)
+
+	c.logger.LogNobl9APICall("GET", "/get/"+kind.ToLower(), true, time.Since(start), logger.Fields{
+		"project_name": projectName,
+		"object_count": len(objects),
+	})
+
+	return objects, nil
+}
+
+// DeleteObject deletes an object of a kind by name; projectName is empty for
+// objects outside projects
+func (c *Client) DeleteObject(ctx context.Context, kind manifest.Kind, projectName, name string) error {
+	start := time.Now()
+
+	fn := func(ctx context.Context) (interface{}, error) {
+		return nil, c.sdkClient.Objects().V1().DeleteByName(ctx, kind, projectName, name)
+	}
+
+	_, err := c.executeDefault(ctx, fmt.Sprintf("delete %s %s", kind, name), fn)
+	if err != nil {
+		c.logger.LogNobl9APICall("DELETE", "/delete/"+kind.ToLower(), false, time.Since(start), logger.Fields{
+			"project_name": projectName,
+			"name":         name,
+			"error":        err.Error(),
+		})
+		return fmt.Errorf("failed to delete %s %s: %w", kind, name, err)
+	}
+
+	c.logger.LogNobl9APICall("DELETE", "/delete/"+kind.ToLower(), true, time.Since(start), logger.Fields{
+		"project_name": projectName,
+		"name":         name,
 	})
 
 	return nil
@@ -617,7 +804,7 @@ func (c *Client) ValidateManifest(ctx context.Context, manifest []byte) error {
 		return nil, nil
 	}
 
-	_, err := c.retryOp.Execute(ctx, "validate manifest", fn)
+	_, err := c.executeDefault(ctx, "validate manifest", fn)
 	if err != nil {
 		c.logger.LogNobl9APICall("POST", "/manifests/validate", false, time.Since(start), logger.Fields{
 			"manifest_size": len(manifest),
@@ -649,12 +836,54 @@ func (c *Client) GetSDKClient() *sdk.Client {
 	return c.sdkClient
 }
 
-// GetRetryPolicy returns the current retry policy
+// GetAPICallCounts returns the number of API calls made so far, by endpoint
+func (c *Client) GetAPICallCounts() map[string]int {
+	if c.calls == nil {
+		return map[string]int{}
+	}
+	return c.calls.Counts()
+}
+
+// GetCacheStats returns how the GET requests so far were answered, or
+// zero stats when the cache is disabled
+func (c *Client) GetCacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.Stats()
+}
+
+// GetRetryPolicy returns the retry policy of calls outside the operation
+// classes, such as reading projects
 func (c *Client) GetRetryPolicy() *retry.Policy {
 	return c.retryOp.GetPolicy()
 }
 
-// SetRetryPolicy sets a new retry policy
+// SetRetryPolicy sets the retry policy of calls outside the operation
+// classes
 func (c *Client) SetRetryPolicy(policy *retry.Policy) {
 	c.retryOp.SetPolicy(policy)
 }
+
+// GetRetryProfiles returns the retry policies of the operation classes
+func (c *Client) GetRetryProfiles() *retry.Profiles {
+	return c.profiles
+}
+
+// execute runs a call of an operation class with the retry policy of the
+// class
+func (c *Client) execute(ctx context.Context, operation, name string, fn retry.RetryableFunc) (interface{}, error) {
+	if c.callOnce {
+		return fn(ctx)
+	}
+	return c.retryOp.ExecuteWithCustomPolicy(ctx, c.profiles.For(operation), name, fn)
+}
+
+// executeDefault runs a call outside the operation classes with the
+// client's retry policy
+func (c *Client) executeDefault(ctx context.Context, name string, fn retry.RetryableFunc) (interface{}, error) {
+	if c.callOnce {
+		return fn(ctx)
+	}
+	return c.retryOp.Execute(ctx, name, fn)
+}
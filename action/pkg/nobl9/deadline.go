@@ -0,0 +1,135 @@
+package nobl9
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+// DefaultCallTimeout is how long a single Nobl9 API call may take
+const DefaultCallTimeout = 30 * time.Second
+
+// CallDeadline is an http.RoundTripper that gives every Nobl9 API request its
+// own deadline, so one hung connection fails that call instead of using up
+// the deadline of the whole run
+type CallDeadline struct {
+	next    http.RoundTripper
+	timeout time.Duration
+	logger  *logger.Logger
+
+	timeouts int
+	mutex    sync.Mutex
+}
+
+// DeadlineAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) so each request, including reading its response body,
+// must complete within timeout; 0 disables the deadline. A request that
+// times out while its caller's context is still live fails with a
+// retry.CallTimeoutError, which is retryable.
+func DeadlineAPICalls(httpClient *http.Client, timeout time.Duration, log *logger.Logger) *CallDeadline {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	deadline := &CallDeadline{
+		next:    next,
+		timeout: timeout,
+		logger:  log,
+	}
+	httpClient.Transport = deadline
+
+	return deadline
+}
+
+// RoundTrip sends the request with its own deadline
+func (d *CallDeadline) RoundTrip(req *http.Request) (*http.Response, error) {
+	if d.timeout <= 0 {
+		return d.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), d.timeout)
+	resp, err := d.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if d.timedOut(ctx, req) {
+			return nil, d.record(req)
+		}
+		return nil, err
+	}
+
+	// The deadline covers reading the body; closing it releases the context
+	resp.Body = &deadlineBody{ReadCloser: resp.Body, deadline: d, req: req, ctx: ctx, cancel: cancel}
+	return resp, nil
+}
+
+// Timeouts returns how many calls hit their deadline
+func (d *CallDeadline) Timeouts() int {
+	if d == nil {
+		return 0
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.timeouts
+}
+
+// timedOut reports whether the call's own deadline, rather than the caller's
+// context, ended the request
+func (d *CallDeadline) timedOut(ctx context.Context, req *http.Request) bool {
+	return ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil
+}
+
+// record counts a timed out call and returns its error
+func (d *CallDeadline) record(req *http.Request) error {
+	d.mutex.Lock()
+	d.timeouts++
+	d.mutex.Unlock()
+
+	if d.logger != nil {
+		d.logger.Warn("Nobl9 API call timed out", logger.Fields{
+			"method":   req.Method,
+			"endpoint": req.URL.Path,
+			"timeout":  d.timeout.String(),
+		})
+	}
+
+	return &retry.CallTimeoutError{Timeout: d.timeout, Method: req.Method, URL: req.URL.Path}
+}
+
+// deadlineBody is a response body read under the call's deadline
+type deadlineBody struct {
+	io.ReadCloser
+	deadline *CallDeadline
+	req      *http.Request
+	ctx      context.Context
+	cancel   context.CancelFunc
+	failed   bool
+}
+
+// Read reads the body, reporting a read cut off by the deadline as a call
+// timeout
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.deadline.timedOut(b.ctx, b.req) {
+		if !b.failed {
+			b.failed = true
+			return n, b.deadline.record(b.req)
+		}
+		return n, &retry.CallTimeoutError{Timeout: b.deadline.timeout, Method: b.req.Method, URL: b.req.URL.Path}
+	}
+	return n, err
+}
+
+// Close closes the body and releases the call's deadline
+func (b *deadlineBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,52 @@
+package nobl9
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+// TokenMasker is an http.RoundTripper that registers the access tokens the
+// SDK derives from the client credentials as secrets, so they are redacted
+// from logs and masked by the GitHub Actions runner
+type TokenMasker struct {
+	next  http.RoundTripper
+	last  string
+	mutex sync.Mutex
+}
+
+// MaskAccessTokens wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP). The SDK sets the Authorization header below this
+// transport, on the same request, so the token is read once the request
+// has been sent.
+func MaskAccessTokens(httpClient *http.Client) *TokenMasker {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	masker := &TokenMasker{next: next}
+	httpClient.Transport = masker
+
+	return masker
+}
+
+// RoundTrip delegates to the wrapped transport and registers a new bearer
+// token of the request
+func (m *TokenMasker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := m.next.RoundTrip(req)
+
+	token := strings.TrimSpace(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+	if token != "" {
+		m.mutex.Lock()
+		if token != m.last {
+			m.last = token
+			logger.AddSecret(token)
+		}
+		m.mutex.Unlock()
+	}
+
+	return resp, err
+}
@@ -0,0 +1,519 @@
+// Package nobl9test provides an in-memory nobl9.Nobl9API for tests, so code
+// reading projects, role bindings and users from Nobl9 can be tested without
+// credentials or network access.
+package nobl9test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	v2 "github.com/nobl9/nobl9-go/sdk/endpoints/users/v2"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/roles"
+)
+
+// DefaultOrganization is the organization of a new Client
+const DefaultOrganization = "test-organization"
+
+// Client is an in-memory Nobl9 organization. Projects, role bindings and
+// users are seeded with AddProject, AddRoleBinding and AddUser; applied
+// projects and role bindings are stored like Nobl9 would and every applied
+// object is kept for Applied. Deleted objects are no longer returned. FailOn makes a method return an error, and
+// Calls counts how often a method was called. A Client is safe for
+// concurrent use.
+type Client struct {
+	// Organization is returned by GetOrganization
+	Organization string
+
+	mutex        sync.Mutex
+	projects     map[string]project.Project
+	roleBindings map[string]rolebinding.RoleBinding
+	users        map[string]*v2.User
+	applied      []manifest.Object
+	deleted      map[string]bool
+	failures     map[string]error
+	calls        map[string]int
+}
+
+var _ nobl9.Nobl9API = (*Client)(nil)
+
+// New returns an empty organization
+func New() *Client {
+	return &Client{
+		Organization: DefaultOrganization,
+		projects:     make(map[string]project.Project),
+		roleBindings: make(map[string]rolebinding.RoleBinding),
+		users:        make(map[string]*v2.User),
+		deleted:      make(map[string]bool),
+		failures:     make(map[string]error),
+		calls:        make(map[string]int),
+	}
+}
+
+// AddProject adds empty projects
+func (c *Client) AddProject(names ...string) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, name := range names {
+		c.projects[name] = project.New(project.Metadata{Name: name}, project.Spec{})
+	}
+	return c
+}
+
+// AddUser adds a user; emails are matched case-insensitively
+func (c *Client) AddUser(email, userID string) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.users[strings.ToLower(email)] = &v2.User{UserID: userID, Email: email}
+	return c
+}
+
+// AddRoleBinding adds a role binding; one without projectRef is an
+// organization role binding
+func (c *Client) AddRoleBinding(binding rolebinding.RoleBinding) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.roleBindings[bindingKey(binding.Spec.ProjectRef, binding.Metadata.Name)] = binding
+	return c
+}
+
+// FailOn makes the method of the given name (e.g. "GetUser") return err
+// until FailOn is called again with a nil error
+func (c *Client) FailOn(method string, err error) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err == nil {
+		delete(c.failures, method)
+	} else {
+		c.failures[method] = err
+	}
+	return c
+}
+
+// Calls returns how often the method of the given name was called
+func (c *Client) Calls(method string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.calls[method]
+}
+
+// Applied returns the objects applied so far, in order
+func (c *Client) Applied() []manifest.Object {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]manifest.Object(nil), c.applied...)
+}
+
+// GetOrganization returns Organization
+func (c *Client) GetOrganization(ctx context.Context) (string, error) {
+	if err := c.call("GetOrganization"); err != nil {
+		return "", err
+	}
+	return c.Organization, nil
+}
+
+// GetProject returns a project
+func (c *Client) GetProject(ctx context.Context, name string) (*project.Project, error) {
+	if err := c.call("GetProject"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	found, ok := c.projects[name]
+	if !ok {
+		return nil, errors.NewNobl9APIError(fmt.Sprintf("project %s not found", name), errors.ErrProjectNotFound)
+	}
+	return &found, nil
+}
+
+// CreateProject stores a project
+func (c *Client) CreateProject(ctx context.Context, projectObj *project.Project) error {
+	if err := c.call("CreateProject"); err != nil {
+		return err
+	}
+	c.apply([]manifest.Object{*projectObj})
+	return nil
+}
+
+// UpdateProject stores a project
+func (c *Client) UpdateProject(ctx context.Context, projectObj *project.Project) error {
+	if err := c.call("UpdateProject"); err != nil {
+		return err
+	}
+	c.apply([]manifest.Object{*projectObj})
+	return nil
+}
+
+// DeleteProject removes a project and its role bindings
+func (c *Client) DeleteProject(ctx context.Context, name string) error {
+	if err := c.call("DeleteProject"); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.deleteProject(name)
+	return nil
+}
+
+// ListProjects returns the projects ordered by name
+func (c *Client) ListProjects(ctx context.Context) ([]project.Project, error) {
+	if err := c.call("ListProjects"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	projects := make([]project.Project, 0, len(c.projects))
+	for _, stored := range c.projects {
+		projects = append(projects, stored)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Metadata.Name < projects[j].Metadata.Name
+	})
+	return projects, nil
+}
+
+// ProjectExists reports whether a project exists
+func (c *Client) ProjectExists(ctx context.Context, name string) (bool, error) {
+	if err := c.call("ProjectExists"); err != nil {
+		return false, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.projects[name]
+	return ok, nil
+}
+
+// ProjectsExist reports for each project whether it exists
+func (c *Client) ProjectsExist(ctx context.Context, names []string) (map[string]bool, error) {
+	if err := c.call("ProjectsExist"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	exists := make(map[string]bool, len(names))
+	for _, name := range names {
+		_, exists[name] = c.projects[name]
+	}
+	return exists, nil
+}
+
+// GetRoleBinding returns a role binding of a project, or of the
+// organization when projectName is empty
+func (c *Client) GetRoleBinding(ctx context.Context, projectName, name string) (*rolebinding.RoleBinding, error) {
+	if err := c.call("GetRoleBinding"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	found, ok := c.roleBindings[bindingKey(projectName, name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in project %s", errors.ErrRoleBindingNotFound, name, projectName)
+	}
+	return &found, nil
+}
+
+// CreateRoleBinding stores a role binding
+func (c *Client) CreateRoleBinding(ctx context.Context, roleBindingObj *rolebinding.RoleBinding) error {
+	if err := c.call("CreateRoleBinding"); err != nil {
+		return err
+	}
+	c.apply([]manifest.Object{*roleBindingObj})
+	return nil
+}
+
+// UpdateRoleBinding stores a role binding
+func (c *Client) UpdateRoleBinding(ctx context.Context, roleBindingObj *rolebinding.RoleBinding) error {
+	if err := c.call("UpdateRoleBinding"); err != nil {
+		return err
+	}
+	c.apply([]manifest.Object{*roleBindingObj})
+	return nil
+}
+
+// DeleteRoleBinding removes a role binding
+func (c *Client) DeleteRoleBinding(ctx context.Context, projectName, name string) error {
+	if err := c.call("DeleteRoleBinding"); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.roleBindings, bindingKey(projectName, name))
+	return nil
+}
+
+// ListRoleBindings returns the role bindings of a project ordered by name,
+// or of every project with sdk.ProjectsWildcard
+func (c *Client) ListRoleBindings(ctx context.Context, projectName string) ([]rolebinding.RoleBinding, error) {
+	if err := c.call("ListRoleBindings"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.listRoleBindings(projectName), nil
+}
+
+// GetOrganizationRoles returns the default roles and the custom roles the
+// stored role bindings grant
+func (c *Client) GetOrganizationRoles(ctx context.Context) (*roles.Catalog, error) {
+	if err := c.call("GetOrganizationRoles"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	catalog := roles.Default()
+	catalog.Learn(c.listRoleBindings(sdk.ProjectsWildcard))
+	return catalog, nil
+}
+
+// GetUser returns the user with the email
+func (c *Client) GetUser(ctx context.Context, email string) (*v2.User, error) {
+	if err := c.call("GetUser"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	user, ok := c.users[strings.ToLower(email)]
+	if !ok {
+		return nil, fmt.Errorf("failed to get user %s: %w", email, errors.ErrUserNotFound)
+	}
+	found := *user
+	return &found, nil
+}
+
+// ListUsers returns the users ordered by email
+func (c *Client) ListUsers(ctx context.Context) ([]*v2.User, error) {
+	if err := c.call("ListUsers"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	users := make([]*v2.User, 0, len(c.users))
+	for _, user := range c.users {
+		found := *user
+		users = append(users, &found)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Email) < strings.ToLower(users[j].Email)
+	})
+	return users, nil
+}
+
+// GetObjects returns the stored projects and role bindings, and the last
+// applied version of objects of other kinds, of a project or of every
+// project with sdk.ProjectsWildcard, ordered by project and name
+func (c *Client) GetObjects(ctx context.Context, kind manifest.Kind, projectName string, names []string) ([]manifest.Object, error) {
+	if err := c.call("GetObjects"); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	found := make(map[string]manifest.Object)
+	switch kind {
+	case manifest.KindProject:
+		for name, stored := range c.projects {
+			found[bindingKey("", name)] = stored
+		}
+	case manifest.KindRoleBinding:
+		for _, binding := range c.listRoleBindings(projectName) {
+			found[bindingKey(binding.Spec.ProjectRef, binding.Metadata.Name)] = binding
+		}
+	default:
+		for _, obj := range c.applied {
+			if obj.GetKind() != kind {
+				continue
+			}
+			if projectScoped, ok := obj.(manifest.ProjectScopedObject); ok &&
+				projectName != sdk.ProjectsWildcard && projectScoped.GetProject() != projectName {
+				continue
+			}
+			key := bindingKey(projectOf(obj), obj.GetName())
+			if c.deleted[kind.String()+"/"+key] {
+				continue
+			}
+			found[key] = obj
+		}
+	}
+
+	keys := make([]string, 0, len(found))
+	for key, obj := range found {
+		if len(names) == 0 || containsName(names, obj.GetName()) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	objects := make([]manifest.Object, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, found[key])
+	}
+	return objects, nil
+}
+
+// ApplyManifest decodes and applies a manifest
+func (c *Client) ApplyManifest(ctx context.Context, data []byte) error {
+	if err := c.call("ApplyManifest"); err != nil {
+		return err
+	}
+
+	objects, err := sdk.DecodeObjects(data)
+	if err != nil {
+		return fmt.Errorf("failed to apply manifest: failed to decode manifest: %w", err)
+	}
+	c.apply(objects)
+	return nil
+}
+
+// ApplyObjects applies objects
+func (c *Client) ApplyObjects(ctx context.Context, objects []manifest.Object) error {
+	if err := c.call("ApplyObjects"); err != nil {
+		return err
+	}
+	c.apply(objects)
+	return nil
+}
+
+// DeleteObject deletes a stored project or role binding, or an applied
+// object of another kind. Deleting a project deletes its role bindings.
+func (c *Client) DeleteObject(ctx context.Context, kind manifest.Kind, projectName, name string) error {
+	if err := c.call("DeleteObject"); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	switch kind {
+	case manifest.KindProject:
+		c.deleteProject(name)
+	case manifest.KindRoleBinding:
+		delete(c.roleBindings, bindingKey(projectName, name))
+	default:
+		c.deleted[kind.String()+"/"+bindingKey(projectName, name)] = true
+	}
+	return nil
+}
+
+// DryRunObjects accepts objects without storing them
+func (c *Client) DryRunObjects(ctx context.Context, objects []manifest.Object) error {
+	return c.call("DryRunObjects")
+}
+
+// ValidateManifest decodes a manifest and validates its objects
+func (c *Client) ValidateManifest(ctx context.Context, data []byte) error {
+	if err := c.call("ValidateManifest"); err != nil {
+		return err
+	}
+
+	objects, err := sdk.DecodeObjects(data)
+	if err != nil {
+		return fmt.Errorf("failed to validate manifest: failed to decode manifest: %w", err)
+	}
+	for _, obj := range objects {
+		if err := obj.Validate(); err != nil {
+			return fmt.Errorf("failed to validate manifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// call counts a call of the method and returns the error set with FailOn
+func (c *Client) call(method string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.calls[method]++
+	return c.failures[method]
+}
+
+// apply records objects and stores the projects and role bindings among them
+func (c *Client) apply(objects []manifest.Object) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, obj := range objects {
+		c.applied = append(c.applied, obj)
+		delete(c.deleted, obj.GetKind().String()+"/"+bindingKey(projectOf(obj), obj.GetName()))
+		switch object := obj.(type) {
+		case project.Project:
+			c.projects[object.Metadata.Name] = object
+		case *project.Project:
+			c.projects[object.Metadata.Name] = *object
+		case rolebinding.RoleBinding:
+			c.roleBindings[bindingKey(object.Spec.ProjectRef, object.Metadata.Name)] = object
+		case *rolebinding.RoleBinding:
+			c.roleBindings[bindingKey(object.Spec.ProjectRef, object.Metadata.Name)] = *object
+		}
+	}
+}
+
+// deleteProject deletes a project and its role bindings; the caller holds
+// the mutex
+func (c *Client) deleteProject(name string) {
+	delete(c.projects, name)
+	for key, binding := range c.roleBindings {
+		if binding.Spec.ProjectRef == name {
+			delete(c.roleBindings, key)
+		}
+	}
+}
+
+// listRoleBindings returns the role bindings of a project ordered by name;
+// the caller holds the mutex
+func (c *Client) listRoleBindings(projectName string) []rolebinding.RoleBinding {
+	bindings := make([]rolebinding.RoleBinding, 0)
+	for _, binding := range c.roleBindings {
+		if projectName == sdk.ProjectsWildcard || binding.Spec.ProjectRef == projectName {
+			bindings = append(bindings, binding)
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindingKey(bindings[i].Spec.ProjectRef, bindings[i].Metadata.Name) <
+			bindingKey(bindings[j].Spec.ProjectRef, bindings[j].Metadata.Name)
+	})
+	return bindings
+}
+
+// projectOf returns the project of a project scoped object, or ""
+func projectOf(obj manifest.Object) string {
+	if projectScoped, ok := obj.(manifest.ProjectScopedObject); ok {
+		return projectScoped.GetProject()
+	}
+	return ""
+}
+
+// containsName reports whether names holds name
+func containsName(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bindingKey identifies a role binding by project and name
+func bindingKey(projectName, name string) string {
+	return projectName + "/" + name
+}
@@ -0,0 +1,129 @@
+package nobl9test
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	client := New().AddProject("payments").AddUser("Alice@Example.com", "00u1alice")
+
+	if _, err := client.GetProject(ctx, "billing"); !stderrors.Is(err, errors.ErrProjectNotFound) {
+		t.Errorf("expected ErrProjectNotFound, got %v", err)
+	}
+	if user, err := client.GetUser(ctx, "alice@example.com"); err != nil || user.UserID != "00u1alice" {
+		t.Errorf("expected alice to be found, got %v, %v", user, err)
+	}
+	if _, err := client.GetUser(ctx, "bob@example.com"); !stderrors.Is(err, errors.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+
+	manifest := []byte(`apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: billing
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: billing-alice
+spec:
+  user: 00u1alice
+  roleRef: billing-auditor
+  projectRef: billing
+`)
+	if err := client.ApplyManifest(ctx, manifest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.Applied()) != 2 {
+		t.Errorf("expected 2 applied objects, got %d", len(client.Applied()))
+	}
+	exists, _ := client.ProjectsExist(ctx, []string{"payments", "billing", "checkout"})
+	if !exists["payments"] || !exists["billing"] || exists["checkout"] {
+		t.Errorf("unexpected projects %v", exists)
+	}
+	if _, err := client.GetRoleBinding(ctx, "billing", "billing-alice"); err != nil {
+		t.Errorf("expected the applied role binding to be stored, got %v", err)
+	}
+	bindings, _ := client.ListRoleBindings(ctx, sdk.ProjectsWildcard)
+	if len(bindings) != 1 {
+		t.Errorf("expected 1 role binding, got %d", len(bindings))
+	}
+	catalog, _ := client.GetOrganizationRoles(ctx)
+	if !catalog.Known("billing-auditor", true) {
+		t.Error("expected the custom role of a stored role binding to be known")
+	}
+
+	client.AddRoleBinding(rolebinding.New(rolebinding.Metadata{Name: "admins"}, rolebinding.Spec{RoleRef: "organization-admin"}))
+	if err := client.DeleteProject(ctx, "billing"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bindings, _ := client.ListRoleBindings(ctx, sdk.ProjectsWildcard); len(bindings) != 1 || bindings[0].Metadata.Name != "admins" {
+		t.Errorf("expected only the organization role binding to remain, got %v", bindings)
+	}
+
+	failure := stderrors.New("connection reset")
+	client.FailOn("ListProjects", failure)
+	if _, err := client.ListProjects(ctx); !stderrors.Is(err, failure) {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	if client.Calls("ListProjects") != 1 || client.Calls("GetUser") != 2 {
+		t.Errorf("unexpected call counts %d, %d", client.Calls("ListProjects"), client.Calls("GetUser"))
+	}
+}
+
+func TestClientGetObjects(t *testing.T) {
+	ctx := context.Background()
+	client := New().AddProject("payments", "billing")
+
+	data := []byte(`apiVersion: n9/v1alpha
+kind: Service
+metadata:
+  name: checkout
+  project: payments
+---
+apiVersion: n9/v1alpha
+kind: Service
+metadata:
+  name: invoices
+  project: billing
+`)
+	if err := client.ApplyManifest(ctx, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	services, err := client.GetObjects(ctx, manifest.KindService, sdk.ProjectsWildcard, nil)
+	if err != nil || len(services) != 2 || services[0].GetName() != "invoices" {
+		t.Errorf("expected both services ordered by project, got %v, %v", services, err)
+	}
+	if services, _ := client.GetObjects(ctx, manifest.KindService, "payments", nil); len(services) != 1 || services[0].GetName() != "checkout" {
+		t.Errorf("expected the service of payments, got %v", services)
+	}
+	if projects, _ := client.GetObjects(ctx, manifest.KindProject, sdk.ProjectsWildcard, []string{"billing", "checkout"}); len(projects) != 1 || projects[0].GetName() != "billing" {
+		t.Errorf("expected the named project, got %v", projects)
+	}
+
+	if err := client.DryRunObjects(ctx, services); err != nil || len(client.Applied()) != 2 {
+		t.Errorf("expected the dry run to store nothing, got %d applied, %v", len(client.Applied()), err)
+	}
+
+	if err := client.DeleteObject(ctx, manifest.KindService, "payments", "checkout"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.DeleteObject(ctx, manifest.KindProject, "", "billing"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if services, _ := client.GetObjects(ctx, manifest.KindService, "payments", nil); len(services) != 0 {
+		t.Errorf("expected the deleted service to be gone, got %v", services)
+	}
+	if exists, _ := client.ProjectExists(ctx, "billing"); exists {
+		t.Error("expected the deleted project to be gone")
+	}
+}
@@ -0,0 +1,55 @@
+package nobl9
+
+import (
+	"net/http"
+	"time"
+)
+
+// CallObserver records the outcome of Nobl9 API requests, e.g. as metrics
+type CallObserver interface {
+	// ObserveAPICall records a request; status is 0 when it failed
+	// without a response
+	ObserveAPICall(method, endpoint string, status int, duration time.Duration)
+}
+
+// ObservedCalls is an http.RoundTripper that reports every Nobl9 API request
+// with its status and latency to a CallObserver
+type ObservedCalls struct {
+	next     http.RoundTripper
+	observer CallObserver
+}
+
+// ObserveAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) so every request is reported to observer. Wrapped right
+// above DeadlineAPICalls, each request sent again after Retry-After is
+// reported on its own and waiting for Retry-After does not count towards
+// the latency.
+func ObserveAPICalls(httpClient *http.Client, observer CallObserver) *ObservedCalls {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	observed := &ObservedCalls{
+		next:     next,
+		observer: observer,
+	}
+	httpClient.Transport = observed
+
+	return observed
+}
+
+// RoundTrip sends the request and reports it once the response headers
+// arrive or the request fails
+func (o *ObservedCalls) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := o.next.RoundTrip(req)
+
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	o.observer.ObserveAPICall(req.Method, req.URL.Path, status, time.Since(start))
+
+	return resp, err
+}
@@ -0,0 +1,106 @@
+package nobl9
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1 "github.com/nobl9/nobl9-go/sdk/endpoints/objects/v1"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+// projectCache memoizes which projects exist. The projects of the
+// organization are listed once, on the first check, instead of getting the
+// project of every object that references it.
+type projectCache struct {
+	mutex    sync.Mutex
+	loaded   bool
+	projects map[string]bool
+}
+
+// ProjectExists reports whether a project exists in Nobl9. The first call
+// lists every project of the organization; later calls, including
+// concurrent ones, are answered from that list without calling Nobl9.
+// Projects created through the client are added to the list.
+func (c *Client) ProjectExists(ctx context.Context, name string) (bool, error) {
+	c.projects.mutex.Lock()
+	defer c.projects.mutex.Unlock()
+
+	if err := c.loadProjects(ctx); err != nil {
+		return false, err
+	}
+	return c.projects.projects[name], nil
+}
+
+// ProjectsExist reports for each project whether it exists in Nobl9, with a
+// single call to Nobl9 at most
+func (c *Client) ProjectsExist(ctx context.Context, names []string) (map[string]bool, error) {
+	c.projects.mutex.Lock()
+	defer c.projects.mutex.Unlock()
+
+	if err := c.loadProjects(ctx); err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(names))
+	for _, name := range names {
+		exists[name] = c.projects.projects[name]
+	}
+	return exists, nil
+}
+
+// ResetProjectCache forgets the listed projects, so the next check lists
+// them again, e.g. after projects were created outside the client
+func (c *Client) ResetProjectCache() {
+	c.projects.mutex.Lock()
+	defer c.projects.mutex.Unlock()
+
+	c.projects.loaded = false
+	c.projects.projects = nil
+}
+
+// rememberProject records a project created through the client, if the
+// projects were already listed
+func (c *Client) rememberProject(name string) {
+	c.projects.mutex.Lock()
+	defer c.projects.mutex.Unlock()
+
+	if c.projects.loaded {
+		c.projects.projects[name] = true
+	}
+}
+
+// loadProjects lists the projects of the organization unless they were
+// already listed; the caller holds the cache mutex. A failed list is not
+// remembered, so the next check tries again.
+func (c *Client) loadProjects(ctx context.Context) error {
+	if c.projects.loaded {
+		return nil
+	}
+	start := time.Now()
+
+	fn := func(ctx context.Context) (interface{}, error) {
+		return c.sdkClient.Objects().V1().GetV1alphaProjects(ctx, v1.GetProjectsRequest{})
+	}
+
+	result, err := c.executeDefault(ctx, "list projects", fn)
+	if err != nil {
+		c.logger.LogNobl9APICall("GET", "/projects", false, time.Since(start), logger.Fields{
+			"error": err.Error(),
+		})
+		return errors.NewNobl9APIError("failed to list projects", err)
+	}
+
+	projects := result.([]project.Project)
+	c.projects.projects = make(map[string]bool, len(projects))
+	for _, p := range projects {
+		c.projects.projects[p.Metadata.Name] = true
+	}
+	c.projects.loaded = true
+
+	c.logger.LogNobl9APICall("GET", "/projects", true, time.Since(start), logger.Fields{
+		"project_count": len(projects),
+	})
+	return nil
+}
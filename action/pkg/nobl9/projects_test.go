@@ -0,0 +1,79 @@
+package nobl9
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+func TestProjectExists(t *testing.T) {
+	var lists int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&lists, 1)
+			_, _ = w.Write([]byte(`[{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"payments"}},{"apiVersion":"n9/v1alpha","kind":"Project","metadata":{"name":"checkout"}}]`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	sdkClient, err := sdk.NewClient(&sdk.Config{URL: serverURL, DisableOkta: true, Organization: "acme"})
+	require.NoError(t, err)
+
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	client := &Client{
+		sdkClient: sdkClient,
+		logger:    log,
+		config:    &Config{Timeout: time.Second},
+		retryOp:   retry.NewRetryableAPIOperation(retry.CreatePolicyForAPI(1), log),
+	}
+	ctx := context.Background()
+
+	// Concurrent checks share a single list
+	var wg sync.WaitGroup
+	for _, name := range []string{"payments", "checkout", "payments", "missing"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			exists, err := client.ProjectExists(ctx, name)
+			assert.NoError(t, err)
+			assert.Equal(t, name != "missing", exists, name)
+		}(name)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lists))
+
+	exists, err := client.ProjectsExist(ctx, []string{"checkout", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"checkout": true, "missing": false}, exists)
+
+	// Projects created through the client are remembered
+	created := project.New(project.Metadata{Name: "missing"}, project.Spec{})
+	require.NoError(t, client.CreateProject(ctx, &created))
+	found, err := client.ProjectExists(ctx, "missing")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lists))
+
+	// A reset lists the projects again
+	client.ResetProjectCache()
+	found, err = client.ProjectExists(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lists))
+}
@@ -0,0 +1,142 @@
+package nobl9
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+// DefaultRetryAfterAttempts is how many times a rate limited request is sent
+// again after waiting for its Retry-After
+const DefaultRetryAfterAttempts = 3
+
+// RateLimiter is an http.RoundTripper that makes every Nobl9 API request wait
+// for a shared token bucket and honors Retry-After on 429 responses
+type RateLimiter struct {
+	next     http.RoundTripper
+	limiter  *retry.Limiter
+	attempts int
+	logger   *logger.Logger
+
+	throttled int
+	waited    time.Duration
+	mutex     sync.Mutex
+}
+
+// RateLimitAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP). Requests wait for the limiter, which may be nil to only
+// honor Retry-After. A 429 response with a Retry-After header is retried
+// after exactly that delay; once the attempts are used up the request fails
+// with a retry.RetryAfterError so callers still know how long to wait.
+func RateLimitAPICalls(httpClient *http.Client, limiter *retry.Limiter, log *logger.Logger) *RateLimiter {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	rateLimiter := &RateLimiter{
+		next:     next,
+		limiter:  limiter,
+		attempts: DefaultRetryAfterAttempts,
+		logger:   log,
+	}
+	httpClient.Transport = rateLimiter
+
+	return rateLimiter
+}
+
+// RoundTrip waits for the limiter and sends the request, waiting out 429
+// responses
+func (r *RateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := r.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+
+		// Without Retry-After the SDK's HTTPError is returned unchanged and
+		// callers fall back to their own backoff
+		delay, ok := retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			return resp, nil
+		}
+		drain(resp)
+
+		rateLimitErr := &retry.RetryAfterError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: delay,
+			Method:     req.Method,
+			URL:        req.URL.Path,
+		}
+
+		// Give up when out of attempts, when the body cannot be sent again or
+		// when the wait would outlast the request's deadline
+		if attempt > r.attempts || (req.Body != nil && req.GetBody == nil) {
+			return nil, rateLimitErr
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, rateLimitErr
+		}
+
+		r.record(delay)
+		if r.logger != nil {
+			r.logger.Warn("Nobl9 API rate limit exceeded, waiting for Retry-After", logger.Fields{
+				"method":      req.Method,
+				"endpoint":    req.URL.Path,
+				"retry_after": delay.String(),
+				"attempt":     attempt,
+			})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// record counts a rate limited response and the time spent waiting for it
+func (r *RateLimiter) record(delay time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.throttled++
+	r.waited += delay
+}
+
+// Throttled returns how many responses were rate limited and how long
+// requests waited for Retry-After in total
+func (r *RateLimiter) Throttled() (int, time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.throttled, r.waited
+}
+
+// drain discards and closes a response body so the connection can be reused
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,320 @@
+package nobl9
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+// Fixture is a recorded Nobl9 API request and its response, stored as one
+// JSON file so it can be read, edited and shared in a bug report
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is the recorded part of a request; credentials are never
+// recorded
+type FixtureRequest struct {
+	Method       string `json:"method"`
+	URL          string `json:"url"`
+	Project      string `json:"project,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Body         string `json:"body,omitempty"`
+}
+
+// FixtureResponse is a recorded response
+type FixtureResponse struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body,omitempty"`
+}
+
+// recordedHeaders are the response headers kept in fixtures
+var recordedHeaders = []string{"Content-Type", "ETag", "Retry-After"}
+
+// Recorder is an http.RoundTripper that writes every Nobl9 API request and
+// its response to a fixture file, for Replayer to serve back later
+type Recorder struct {
+	next     http.RoundTripper
+	dir      string
+	logger   *logger.Logger
+	sequence int
+	mutex    sync.Mutex
+}
+
+// RecordAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) with a recorder writing fixtures to dir, which is
+// created if needed. Wrap it first, directly above the SDK, so every
+// request sent is recorded once. The Authorization header is not recorded
+// and secrets registered with logger.AddSecret are redacted from fixtures;
+// they still hold the objects and users the run read, so review them before
+// sharing.
+func RecordAPICalls(httpClient *http.Client, dir string, log *logger.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory %s: %w", dir, err)
+	}
+
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	recorder := &Recorder{
+		next:   next,
+		dir:    dir,
+		logger: log,
+	}
+	httpClient.Transport = recorder
+
+	return recorder, nil
+}
+
+// RoundTrip sends the request on and records it with its response. Requests
+// failing without a response are not recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	fixture := Fixture{
+		Request: FixtureRequest{
+			Method:       req.Method,
+			URL:          logger.Redact(req.URL.String()),
+			Project:      req.Header.Get(sdk.HeaderProject),
+			Organization: req.Header.Get(sdk.HeaderOrganization),
+			Body:         logger.Redact(string(requestBody)),
+		},
+		Response: FixtureResponse{
+			Status: resp.StatusCode,
+			Header: make(map[string]string),
+			Body:   logger.Redact(string(responseBody)),
+		},
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			fixture.Response.Header[name] = value
+		}
+	}
+
+	if err := r.write(req, fixture); err != nil && r.logger != nil {
+		r.logger.Warn("Failed to record Nobl9 API call", logger.Fields{
+			"method":   req.Method,
+			"endpoint": req.URL.Path,
+			"error":    err.Error(),
+		})
+	}
+
+	return resp, nil
+}
+
+// Recorded returns the number of fixtures written
+func (r *Recorder) Recorded() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.sequence
+}
+
+// write stores a fixture under the next sequence number, so fixtures sort
+// in the order the requests were answered
+func (r *Recorder) write(req *http.Request, fixture Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sequence++
+	name := fmt.Sprintf("%04d-%s%s.json", r.sequence, strings.ToLower(req.Method), pathSlug(req.URL.Path))
+	return os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o644)
+}
+
+// Replayer is an http.RoundTripper that answers Nobl9 API requests with the
+// fixtures of a recorded run, without network access or credentials
+type Replayer struct {
+	recorded     map[string][]*Fixture
+	served       map[string]int
+	organization string
+	baseURL      *url.URL
+	logger       *logger.Logger
+	mutex        sync.Mutex
+}
+
+// ReplayAPICalls replaces the transport of the given HTTP client with the
+// fixtures recorded to dir. Requests are matched by method, path, query and
+// project header; requests matching several fixtures get them in recorded
+// order, and the last one again once they are used up. A request without a
+// fixture fails.
+func ReplayAPICalls(httpClient *http.Client, dir string, log *logger.Logger) (*Replayer, error) {
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	replayer := &Replayer{
+		recorded: make(map[string][]*Fixture),
+		served:   make(map[string]int),
+		logger:   log,
+	}
+	for i := range fixtures {
+		fixture := &fixtures[i]
+		requestURL, err := url.Parse(fixture.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture URL %s: %w", fixture.Request.URL, err)
+		}
+		key := replayKey(fixture.Request.Method, requestURL, fixture.Request.Project)
+		replayer.recorded[key] = append(replayer.recorded[key], fixture)
+
+		if replayer.baseURL == nil {
+			replayer.baseURL = apiBaseURL(requestURL)
+		}
+		if replayer.organization == "" {
+			replayer.organization = fixture.Request.Organization
+		}
+	}
+	httpClient.Transport = replayer
+
+	return replayer, nil
+}
+
+// LoadFixtures reads the fixtures of dir in recorded order
+func LoadFixtures(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %w", path, err)
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// RoundTrip answers the request with its next fixture
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	key := replayKey(req.Method, req.URL, req.Header.Get(sdk.HeaderProject))
+	r.mutex.Lock()
+	fixtures := r.recorded[key]
+	if len(fixtures) == 0 {
+		r.mutex.Unlock()
+		if r.logger != nil {
+			r.logger.Warn("No recorded Nobl9 API call matches the request", logger.Fields{
+				"method":   req.Method,
+				"endpoint": req.URL.Path,
+				"query":    req.URL.RawQuery,
+			})
+		}
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+	index := r.served[key]
+	if index >= len(fixtures) {
+		index = len(fixtures) - 1
+	}
+	r.served[key]++
+	fixture := fixtures[index]
+	r.mutex.Unlock()
+
+	header := make(http.Header)
+	for name, value := range fixture.Response.Header {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(fixture.Response.Body)),
+		ContentLength: int64(len(fixture.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Organization returns the organization the fixtures were recorded for
+func (r *Replayer) Organization() string {
+	return r.organization
+}
+
+// URL returns the base URL of the Nobl9 API the fixtures were recorded from
+func (r *Replayer) URL() *url.URL {
+	return r.baseURL
+}
+
+// replayKey identifies the fixtures answering a request
+func replayKey(method string, requestURL *url.URL, project string) string {
+	return method + " " + requestURL.Path + "?" + requestURL.Query().Encode() + " " + project
+}
+
+// apiBaseURL returns the base URL of the API a request was sent to, up to
+// the /api path the SDK joins its endpoints to, or the host of an API
+// served without it
+func apiBaseURL(requestURL *url.URL) *url.URL {
+	base := &url.URL{Scheme: requestURL.Scheme, Host: requestURL.Host}
+	if i := strings.Index(requestURL.Path, "/api/"); i >= 0 {
+		base.Path = requestURL.Path[:i+len("/api")]
+	}
+	return base
+}
+
+// pathSlug turns a request path into a file name part, e.g. -api-get-project
+func pathSlug(path string) string {
+	var slug strings.Builder
+	for _, char := range strings.ToLower(path) {
+		switch {
+		case char >= 'a' && char <= 'z', char >= '0' && char <= '9':
+			slug.WriteRune(char)
+		default:
+			slug.WriteRune('-')
+		}
+	}
+	return strings.TrimRight(slug.String(), "-")
+}
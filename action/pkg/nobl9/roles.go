@@ -0,0 +1,41 @@
+package nobl9
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/roles"
+)
+
+// roleCache memoizes the roles of the organization
+type roleCache struct {
+	mutex   sync.Mutex
+	catalog *roles.Catalog
+}
+
+// GetOrganizationRoles returns the roles role bindings of the organization
+// may reference: the built-in project roles (project-owner, project-editor,
+// project-viewer, ...) and organization roles, and the custom roles live
+// role bindings already grant. Nobl9 has no API listing the roles of an
+// organization, so custom roles are learned from its role bindings. The
+// roles are read once per client.
+func (c *Client) GetOrganizationRoles(ctx context.Context) (*roles.Catalog, error) {
+	c.roles.mutex.Lock()
+	defer c.roles.mutex.Unlock()
+
+	if c.roles.catalog != nil {
+		return c.roles.catalog, nil
+	}
+
+	bindings, err := c.ListRoleBindings(ctx, sdk.ProjectsWildcard)
+	if err != nil {
+		return nil, errors.NewNobl9APIError("failed to get organization roles", err)
+	}
+
+	catalog := roles.Default()
+	catalog.Learn(bindings)
+	c.roles.catalog = catalog
+	return catalog, nil
+}
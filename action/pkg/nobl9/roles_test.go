@@ -0,0 +1,49 @@
+package nobl9
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/retry"
+)
+
+func TestGetOrganizationRoles(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`[{"apiVersion":"n9/v1alpha","kind":"RoleBinding","metadata":{"name":"payments-reviewers"},"spec":{"user":"00u1alice","roleRef":"slo-reviewer","projectRef":"payments"}}]`))
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	sdkClient, err := sdk.NewClient(&sdk.Config{URL: serverURL, DisableOkta: true, Organization: "acme"})
+	require.NoError(t, err)
+
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	client := &Client{
+		sdkClient: sdkClient,
+		logger:    log,
+		config:    &Config{Timeout: time.Second},
+		retryOp:   retry.NewRetryableAPIOperation(retry.CreatePolicyForAPI(1), log),
+	}
+
+	catalog, err := client.GetOrganizationRoles(context.Background())
+	require.NoError(t, err)
+	assert.True(t, catalog.Known("project-owner", true))
+	assert.True(t, catalog.Known("slo-reviewer", true))
+	assert.False(t, catalog.Known("slo-reviewer", false))
+
+	// The roles are read once
+	_, err = client.GetOrganizationRoles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
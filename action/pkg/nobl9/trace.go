@@ -0,0 +1,68 @@
+package nobl9
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of the Nobl9 API request spans
+const tracerName = "github.com/your-org/nobl9-action/pkg/nobl9"
+
+// TracedCalls is an http.RoundTripper that records a client span for every
+// Nobl9 API request, as a child of the span in the request context
+type TracedCalls struct {
+	next   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// TraceAPICalls wraps the transport of the given HTTP client (e.g.
+// sdk.Client.HTTP) so every request is recorded as a span. Spans go to the
+// global tracer provider and are dropped unless tracing is set up. Wrapped
+// last, above the circuit breaker, each span covers a logical call with its
+// retries after Retry-After.
+func TraceAPICalls(httpClient *http.Client) *TracedCalls {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	traced := &TracedCalls{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+	httpClient.Transport = traced
+
+	return traced
+}
+
+// RoundTrip sends the request within a span named after its method and
+// endpoint
+func (t *TracedCalls) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), fmt.Sprintf("%s %s", req.Method, req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
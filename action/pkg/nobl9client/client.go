@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,12 +13,20 @@ import (
 	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
 	"github.com/nobl9/nobl9-go/sdk"
 	"github.com/sirupsen/logrus"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/logger"
+	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"github.com/your-org/nobl9-action/pkg/owners"
+	"github.com/your-org/nobl9-action/pkg/planner"
 )
 
 // Client wraps the Nobl9 SDK client with additional functionality
 type Client struct {
 	sdkClient *sdk.Client
+	api       nobl9.Nobl9API
 	timeout   time.Duration
+	kinds     KindFilter
 }
 
 // ProcessedObject represents a processed Nobl9 object
@@ -29,6 +38,7 @@ type ProcessedObject struct {
 	UserEmails  []string
 	ResolvedIDs map[string]string // email -> userID mapping
 	Applied     bool
+	Unchanged   bool // identical to the live object, so it was not applied
 	Error       error
 }
 
@@ -36,11 +46,23 @@ type ProcessedObject struct {
 type ProcessResult struct {
 	Projects       []ProcessedObject
 	RoleBindings   []ProcessedObject
+	Others         []ProcessedObject // objects of every other applicable kind
 	EmailsResolved map[string]string
+	Skipped        SkippedObjects
 	Errors         []error
 	Summary        string
 }
 
+// SkippedObjects records decoded objects that were not applied, keyed by kind
+type SkippedObjects map[string][]string
+
+// KindCounts counts objects by kind
+type KindCounts map[string]int
+
+// KindFilter selects the object kinds that are applied; a nil filter allows
+// every applicable kind
+type KindFilter map[manifest.Kind]bool
+
 // Valid roles (from your lambda) - currently unused but kept for future validation
 // var validRoles = map[string]bool{
 //	"project-owner":  true,
@@ -67,11 +89,17 @@ func NewClient(clientID, clientSecret string) (*Client, error) {
 
 	return &Client{
 		sdkClient: client,
+		api:       nobl9.Wrap(client, logger.New(logger.LevelInfo, logger.FormatText)),
 		timeout:   60 * time.Second,
 	}, nil
 }
 
-// ProcessObjects processes parsed objects (projects and role bindings)
+// SetKinds restricts processing to the given kinds (nil processes every applicable kind)
+func (c *Client) SetKinds(kinds KindFilter) {
+	c.kinds = kinds
+}
+
+// ProcessObjects processes parsed objects of every applicable kind
 func (c *Client) ProcessObjects(ctx context.Context, objects []ParsedObject, dryRun bool) (*ProcessResult, error) {
 	// Create a context with timeout
 	processCtx, cancel := context.WithTimeout(ctx, c.timeout)
@@ -80,7 +108,9 @@ func (c *Client) ProcessObjects(ctx context.Context, objects []ParsedObject, dry
 	result := &ProcessResult{
 		Projects:       make([]ProcessedObject, 0),
 		RoleBindings:   make([]ProcessedObject, 0),
+		Others:         make([]ProcessedObject, 0),
 		EmailsResolved: make(map[string]string),
+		Skipped:        make(SkippedObjects),
 		Errors:         make([]error, 0),
 	}
 
@@ -119,15 +149,27 @@ func (c *Client) ProcessObjects(ctx context.Context, objects []ParsedObject, dry
 	// Step 3: Process projects first
 	var projectObjects []ParsedObject
 	var roleBindingObjects []ParsedObject
+	var otherObjects []ParsedObject
 
 	for _, obj := range objects {
-		if obj.Kind == "Project" {
+		kind, err := manifest.ParseKind(obj.Kind)
+		if err != nil || !c.kinds.Allows(kind) {
+			result.Skipped.Add(obj.Kind, obj.Name)
+			continue
+		}
+
+		switch kind {
+		case manifest.KindProject:
 			projectObjects = append(projectObjects, obj)
-		} else if obj.Kind == "RoleBinding" {
+		case manifest.KindRoleBinding:
 			roleBindingObjects = append(roleBindingObjects, obj)
+		default:
+			otherObjects = append(otherObjects, obj)
 		}
 	}
 
+	result.Skipped.LogWarning()
+
 	// Process projects
 	for _, obj := range projectObjects {
 		processed := c.processProject(processCtx, obj, dryRun)
@@ -137,6 +179,24 @@ func (c *Client) ProcessObjects(ctx context.Context, objects []ParsedObject, dry
 		}
 	}
 
+	// Process every other kind once the projects it may belong to exist,
+	// ordered so that e.g. services are applied before their SLOs
+	if levels, err := planner.New().Levels(); err == nil {
+		sort.SliceStable(otherObjects, func(i, j int) bool {
+			ki, _ := manifest.ParseKind(otherObjects[i].Kind)
+			kj, _ := manifest.ParseKind(otherObjects[j].Kind)
+			return levels[ki] < levels[kj]
+		})
+	}
+
+	for _, obj := range otherObjects {
+		processed := c.processObject(processCtx, obj, dryRun)
+		result.Others = append(result.Others, processed)
+		if processed.Error != nil {
+			result.Errors = append(result.Errors, processed.Error)
+		}
+	}
+
 	// Process role bindings (with resolved emails)
 	for _, obj := range roleBindingObjects {
 		processed := c.processRoleBinding(processCtx, obj, result.EmailsResolved, dryRun)
@@ -152,7 +212,10 @@ func (c *Client) ProcessObjects(ctx context.Context, objects []ParsedObject, dry
 	logrus.WithFields(logrus.Fields{
 		"projects_processed":      len(result.Projects),
 		"role_bindings_processed": len(result.RoleBindings),
+		"other_objects_processed": len(result.Others),
+		"objects_by_kind":         result.AppliedKinds().String(),
 		"emails_resolved":         len(result.EmailsResolved),
+		"objects_skipped":         result.Skipped.Total(),
 		"errors":                  len(result.Errors),
 		"dry_run":                 dryRun,
 	}).Info("Nobl9 object processing completed")
@@ -170,7 +233,7 @@ func (c *Client) resolveEmailToUserID(ctx context.Context, email string) (string
 		return "", fmt.Errorf("error retrieving user '%s': %w", email, err)
 	}
 	if user == nil {
-		return "", fmt.Errorf("user with email '%s' not found in Nobl9", email)
+		return "", fmt.Errorf("%w with email '%s' in Nobl9", errors.ErrUserNotFound, email)
 	}
 
 	return user.UserID, nil
@@ -218,6 +281,51 @@ func (c *Client) processProject(ctx context.Context, obj ParsedObject, dryRun bo
 	return processed
 }
 
+// processObject validates and applies a single object of any other kind
+func (c *Client) processObject(ctx context.Context, obj ParsedObject, dryRun bool) ProcessedObject {
+	processed := ProcessedObject{
+		Object:      obj.Object,
+		Kind:        obj.Kind,
+		Name:        obj.Name,
+		Project:     obj.Project,
+		UserEmails:  obj.UserEmails,
+		ResolvedIDs: make(map[string]string),
+		Applied:     false,
+	}
+
+	fields := logrus.Fields{
+		"kind":    obj.Kind,
+		"name":    obj.Name,
+		"project": obj.Project,
+		"dry_run": dryRun,
+	}
+	logrus.WithFields(fields).Info("Processing object")
+
+	if obj.Object != nil {
+		if err := obj.Object.Validate(); err != nil {
+			processed.Error = fmt.Errorf("invalid %s '%s': %w", obj.Kind, obj.Name, err)
+			logrus.WithError(processed.Error).Error("Object validation failed")
+			return processed
+		}
+	}
+
+	if dryRun {
+		logrus.WithFields(fields).Info("DRY RUN: Would apply object")
+		processed.Applied = true
+		return processed
+	}
+
+	if err := c.sdkClient.Objects().V1().Apply(ctx, []manifest.Object{obj.Object}); err != nil {
+		processed.Error = fmt.Errorf("failed to apply %s '%s': %w", obj.Kind, obj.Name, err)
+		logrus.WithError(processed.Error).Error("Failed to apply object")
+		return processed
+	}
+
+	processed.Applied = true
+	logrus.WithFields(fields).Info("Object applied successfully")
+	return processed
+}
+
 // processRoleBinding processes a single role binding
 func (c *Client) processRoleBinding(ctx context.Context, obj ParsedObject, emailResolution map[string]string, dryRun bool) ProcessedObject {
 	processed := ProcessedObject{
@@ -261,6 +369,19 @@ func (c *Client) processRoleBinding(ctx context.Context, obj ParsedObject, email
 		}
 	}
 
+	// Skip the apply when the live role binding already matches
+	live, err := LiveRoleBindings(ctx, c.api, []v1alphaRoleBinding.RoleBinding{roleBinding})
+	if err != nil {
+		logrus.WithError(err).WithField("role_binding_name", obj.Name).Warn("Failed to get live role binding, applying it")
+	} else if existing, found := live[roleBinding.Metadata.Name]; found && RoleBindingUnchanged(roleBinding, existing) {
+		logrus.WithFields(logrus.Fields{
+			"role_binding_name": obj.Name,
+			"project":           obj.Project,
+		}).Info("Role binding unchanged, skipping apply")
+		processed.Unchanged = true
+		return processed
+	}
+
 	if dryRun {
 		logrus.WithFields(logrus.Fields{
 			"role_binding_name": obj.Name,
@@ -290,6 +411,7 @@ func (c *Client) processRoleBinding(ctx context.Context, obj ParsedObject, email
 func (c *Client) generateSummary(result *ProcessResult) string {
 	successfulProjects := 0
 	successfulRoleBindings := 0
+	unchangedRoleBindings := 0
 
 	for _, proj := range result.Projects {
 		if proj.Applied && proj.Error == nil {
@@ -301,10 +423,283 @@ func (c *Client) generateSummary(result *ProcessResult) string {
 		if rb.Applied && rb.Error == nil {
 			successfulRoleBindings++
 		}
+		if rb.Unchanged {
+			unchangedRoleBindings++
+		}
 	}
 
-	return fmt.Sprintf("Processing completed: %d projects, %d role bindings, %d emails resolved, %d errors",
+	summary := fmt.Sprintf("Processing completed: %d projects, %d role bindings, %d emails resolved, %d errors",
 		successfulProjects, successfulRoleBindings, len(result.EmailsResolved), len(result.Errors))
+
+	if unchangedRoleBindings > 0 {
+		summary += fmt.Sprintf(", %d role bindings unchanged", unchangedRoleBindings)
+	}
+
+	if skipped := result.Skipped.Total(); skipped > 0 {
+		summary += fmt.Sprintf(", %d objects skipped (%s)", skipped, result.Skipped.String())
+	}
+
+	return summary
+}
+
+// RoleBindingUnchanged reports whether the live role binding already grants
+// the same role to the same user or group in the same project
+func RoleBindingUnchanged(desired, live v1alphaRoleBinding.RoleBinding) bool {
+	return equalRef(desired.Spec.User, live.Spec.User) &&
+		equalRef(desired.Spec.GroupRef, live.Spec.GroupRef) &&
+		desired.Spec.RoleRef == live.Spec.RoleRef &&
+		desired.Spec.ProjectRef == live.Spec.ProjectRef
+}
+
+// equalRef compares optional references, treating nil and empty as equal
+func equalRef(a, b *string) bool {
+	var av, bv string
+	if a != nil {
+		av = *a
+	}
+	if b != nil {
+		bv = *b
+	}
+	return av == bv
+}
+
+// LiveRoleBindings fetches the existing role bindings named like the given
+// ones, keyed by name. Organization role bindings are looked up across all
+// projects.
+func LiveRoleBindings(ctx context.Context, api nobl9.Nobl9API, bindings []v1alphaRoleBinding.RoleBinding) (map[string]v1alphaRoleBinding.RoleBinding, error) {
+	namesByProject := make(map[string][]string)
+	for _, rb := range bindings {
+		project := rb.Spec.ProjectRef
+		if project == "" {
+			project = sdk.ProjectsWildcard
+		}
+		namesByProject[project] = append(namesByProject[project], rb.Metadata.Name)
+	}
+
+	live := make(map[string]v1alphaRoleBinding.RoleBinding, len(bindings))
+	for project, names := range namesByProject {
+		found, err := api.GetObjects(ctx, manifest.KindRoleBinding, project, names)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role bindings in project '%s': %w", project, err)
+		}
+		for _, obj := range found {
+			if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok {
+				live[rb.Metadata.Name] = rb
+			}
+		}
+	}
+
+	return live, nil
+}
+
+// SkipUnchangedRoleBindings drops the role bindings that match their live
+// counterpart from objects and returns the remaining objects together with
+// the names of the role bindings that were dropped and the live role
+// bindings read, keyed by name, so they need not be read again
+func SkipUnchangedRoleBindings(ctx context.Context, api nobl9.Nobl9API, objects []manifest.Object) ([]manifest.Object, []string, map[string]v1alphaRoleBinding.RoleBinding, error) {
+	var bindings []v1alphaRoleBinding.RoleBinding
+	for _, obj := range objects {
+		if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok {
+			bindings = append(bindings, rb)
+		}
+	}
+	if len(bindings) == 0 {
+		return objects, nil, nil, nil
+	}
+
+	live, err := LiveRoleBindings(ctx, api, bindings)
+	if err != nil {
+		return objects, nil, nil, err
+	}
+
+	remaining, unchanged := filterUnchangedRoleBindings(objects, live)
+	return remaining, unchanged, live, nil
+}
+
+// filterUnchangedRoleBindings splits objects into those to apply and the
+// names of role bindings identical to the live ones
+func filterUnchangedRoleBindings(objects []manifest.Object, live map[string]v1alphaRoleBinding.RoleBinding) ([]manifest.Object, []string) {
+	remaining := make([]manifest.Object, 0, len(objects))
+	var unchanged []string
+	for _, obj := range objects {
+		if rb, ok := obj.(v1alphaRoleBinding.RoleBinding); ok {
+			if existing, found := live[rb.Metadata.Name]; found && RoleBindingUnchanged(rb, existing) {
+				unchanged = append(unchanged, rb.Metadata.Name)
+				continue
+			}
+		}
+		remaining = append(remaining, obj)
+	}
+	return remaining, unchanged
+}
+
+// AppliedKinds counts the successfully applied objects by kind
+func (r *ProcessResult) AppliedKinds() KindCounts {
+	counts := make(KindCounts)
+	for _, group := range [][]ProcessedObject{r.Projects, r.RoleBindings, r.Others} {
+		for _, obj := range group {
+			if obj.Applied && obj.Error == nil {
+				counts.Add(obj.Kind, 1)
+			}
+		}
+	}
+	return counts
+}
+
+// ParseKindFilter parses a comma separated list of kinds such as
+// "project,rolebinding,slo". An empty list or "all" allows every applicable kind.
+func ParseKindFilter(spec string) (KindFilter, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		return nil, nil
+	}
+
+	filter := make(KindFilter)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		kind, err := manifest.ParseKind(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown kind '%s'", name)
+		}
+		if !kind.Applicable() {
+			return nil, fmt.Errorf("kind '%s' cannot be applied", kind)
+		}
+		filter[kind] = true
+	}
+
+	if len(filter) == 0 {
+		return nil, fmt.Errorf("no kinds selected")
+	}
+
+	return filter, nil
+}
+
+// Allows reports whether objects of the given kind should be applied
+func (f KindFilter) Allows(kind manifest.Kind) bool {
+	if !kind.Applicable() {
+		return false
+	}
+	if f == nil {
+		return true
+	}
+	return f[kind]
+}
+
+// String lists the selected kinds in alphabetical order, or "all"
+func (f KindFilter) String() string {
+	if f == nil {
+		return "all"
+	}
+
+	names := make([]string, 0, len(f))
+	for kind := range f {
+		names = append(names, kind.String())
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
+
+// Add increments the count for the given kind
+func (k KindCounts) Add(kind string, count int) {
+	k[kind] += count
+}
+
+// Merge adds all counts in other
+func (k KindCounts) Merge(other KindCounts) {
+	for kind, count := range other {
+		k[kind] += count
+	}
+}
+
+// Total returns the number of objects across all kinds
+func (k KindCounts) Total() int {
+	total := 0
+	for _, count := range k {
+		total += count
+	}
+	return total
+}
+
+// String formats the counts per kind, e.g. "Project=1,SLO=3"
+func (k KindCounts) String() string {
+	kinds := make([]string, 0, len(k))
+	for kind := range k {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, k[kind]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Add records a skipped object of the given kind
+func (s SkippedObjects) Add(kind, name string) {
+	s[kind] = append(s[kind], name)
+}
+
+// Merge adds all objects skipped in other
+func (s SkippedObjects) Merge(other SkippedObjects) {
+	for kind, names := range other {
+		s[kind] = append(s[kind], names...)
+	}
+}
+
+// Total returns the number of skipped objects across all kinds
+func (s SkippedObjects) Total() int {
+	total := 0
+	for _, names := range s {
+		total += len(names)
+	}
+	return total
+}
+
+// Kinds returns the skipped kinds in alphabetical order
+func (s SkippedObjects) Kinds() []string {
+	kinds := make([]string, 0, len(s))
+	for kind := range s {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// String formats the skipped counts per kind, e.g. "SLO=3,Service=1"
+func (s SkippedObjects) String() string {
+	parts := make([]string, 0, len(s))
+	for _, kind := range s.Kinds() {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, len(s[kind])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// LogWarning emits one warning per skipped kind so objects that are scanned
+// but never deployed (unknown, read-only or filtered out kinds, or objects of
+// the skip list) do not go unnoticed
+func (s SkippedObjects) LogWarning() {
+	if s.Total() == 0 {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"objects_skipped": s.Total(),
+		"skipped_kinds":   s.String(),
+	}).Warn("Some decoded objects were not applied")
+
+	for _, kind := range s.Kinds() {
+		logrus.WithFields(logrus.Fields{
+			"kind":    kind,
+			"count":   len(s[kind]),
+			"objects": s[kind],
+		}).Warn("Skipped objects of unselected kind or on the skip list")
+	}
 }
 
 // ParsedObject represents a parsed object that needs processing
@@ -317,6 +712,123 @@ type ParsedObject struct {
 	UserEmails []string
 }
 
+// GroupExpander expands a directory group into the emails of its members
+type GroupExpander interface {
+	GetGroupMemberEmails(ctx context.Context, groupName string) ([]string, error)
+}
+
+// ExpandGroupRoleBindings replaces every role binding whose user references an
+// Okta group with one role binding per group member (as in the lambda)
+func ExpandGroupRoleBindings(ctx context.Context, objects []manifest.Object, expander GroupExpander) ([]manifest.Object, error) {
+	return expandRoleBindings(ctx, objects, expander, groupSource{
+		isReference: okta.IsGroupReference,
+		name:        okta.GroupName,
+		label:       "Okta group",
+		reference:   "an Okta group",
+		unavailable: "Okta is not configured",
+		logField:    "okta_group",
+	})
+}
+
+// ExpandTeamRoleBindings replaces every role binding whose user references a
+// GitHub team with one role binding per team member with a known email
+func ExpandTeamRoleBindings(ctx context.Context, objects []manifest.Object, expander GroupExpander) ([]manifest.Object, error) {
+	return expandRoleBindings(ctx, objects, expander, groupSource{
+		isReference: owners.IsTeamReference,
+		name:        owners.TeamName,
+		label:       "GitHub team",
+		reference:   "a GitHub team",
+		unavailable: "no GitHub token is configured",
+		logField:    "github_team",
+	})
+}
+
+// groupSource describes the group references one expander resolves
+type groupSource struct {
+	isReference func(user string) bool
+	name        func(user string) string
+	label       string
+	reference   string
+	unavailable string
+	logField    string
+}
+
+// expandRoleBindings replaces the role bindings referencing a group of
+// source with one role binding per member the expander returns
+func expandRoleBindings(ctx context.Context, objects []manifest.Object, expander GroupExpander, source groupSource) ([]manifest.Object, error) {
+	expanded := make([]manifest.Object, 0, len(objects))
+
+	for _, obj := range objects {
+		roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || roleBinding.Spec.User == nil || !source.isReference(*roleBinding.Spec.User) {
+			expanded = append(expanded, obj)
+			continue
+		}
+
+		if expander == nil {
+			return nil, fmt.Errorf("role binding '%s' references %s but %s", roleBinding.Metadata.Name, source.reference, source.unavailable)
+		}
+
+		groupName := source.name(*roleBinding.Spec.User)
+		emails, err := expander.GetGroupMemberEmails(ctx, groupName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s '%s' for role binding '%s': %w", source.label, groupName, roleBinding.Metadata.Name, err)
+		}
+
+		for _, email := range emails {
+			member := email
+			memberBinding := roleBinding
+			memberBinding.Metadata.Name = groupMemberBindingName(roleBinding.Metadata.Name, member)
+			memberBinding.Spec.User = &member
+			expanded = append(expanded, memberBinding)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"role_binding":  roleBinding.Metadata.Name,
+			source.logField: groupName,
+			"members":       len(emails),
+		}).Info("Expanded " + source.label + " role binding")
+	}
+
+	return expanded, nil
+}
+
+// SubstituteUserIDs returns a copy of objects in which every role binding user
+// that is a resolved email is replaced by its user ID, and the number of role
+// bindings that were changed. Decoded objects are values, so the substituted
+// role bindings must replace the originals in the slice that is applied.
+func SubstituteUserIDs(objects []manifest.Object, resolutions map[string]string) ([]manifest.Object, int) {
+	substituted := make([]manifest.Object, len(objects))
+	changed := 0
+
+	for i, obj := range objects {
+		substituted[i] = obj
+
+		roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || roleBinding.Spec.User == nil {
+			continue
+		}
+
+		email := *roleBinding.Spec.User
+		userID, found := resolutions[email]
+		if !found {
+			continue
+		}
+
+		roleBinding.Spec.User = &userID
+		substituted[i] = roleBinding
+		changed++
+
+		logrus.WithFields(logrus.Fields{
+			"role_binding": roleBinding.Metadata.Name,
+			"email":        email,
+			"user_id":      userID,
+		}).Debug("Email resolved for role binding")
+	}
+
+	return substituted, changed
+}
+
 // Helper functions from your lambda
 
 
@@ -2,9 +2,14 @@ package nobl9client
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
 	"github.com/nobl9/nobl9-go/sdk"
 )
 
@@ -70,6 +75,118 @@ func TestProcessObjects(t *testing.T) {
 	if result.Errors == nil {
 		t.Error("expected errors slice to be initialized")
 	}
+
+	// Every applicable kind is processed; read-only and unknown kinds are skipped
+	objects = []ParsedObject{
+		{Kind: "SLO", Name: "latency"},
+		{Kind: "SLO", Name: "availability"},
+		{Kind: "Service", Name: "api"},
+		{Kind: "Alert", Name: "fired"},
+		{Kind: "Widget", Name: "unknown"},
+	}
+	result, err = client.ProcessObjects(ctx, objects, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if applied := result.AppliedKinds().String(); applied != "SLO=2,Service=1" {
+		t.Errorf("expected applied kinds %q, got %q", "SLO=2,Service=1", applied)
+	}
+	if skipped := result.Skipped.String(); skipped != "Alert=1,Widget=1" {
+		t.Errorf("expected skipped kinds %q, got %q", "Alert=1,Widget=1", skipped)
+	}
+
+	// Kinds outside the filter are reported as skipped
+	client.SetKinds(KindFilter{manifest.KindSLO: true})
+	result, err = client.ProcessObjects(ctx, objects[:3], true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if applied := result.AppliedKinds().String(); applied != "SLO=2" {
+		t.Errorf("expected applied kinds %q, got %q", "SLO=2", applied)
+	}
+	if skipped := result.Skipped.String(); skipped != "Service=1" {
+		t.Errorf("expected skipped kinds %q, got %q", "Service=1", skipped)
+	}
+}
+
+func TestParseKindFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		expected    string
+		expectError bool
+	}{
+		{name: "empty selects all", spec: "", expected: "all"},
+		{name: "all keyword", spec: "all", expected: "all"},
+		{name: "case insensitive list", spec: "project, RoleBinding,slo", expected: "Project,RoleBinding,SLO"},
+		{name: "unknown kind", spec: "project,widget", expectError: true},
+		{name: "read-only kind", spec: "alert", expectError: true},
+		{name: "only separators", spec: ",,", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseKindFilter(tt.spec)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if filter.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, filter.String())
+			}
+		})
+	}
+
+	var all KindFilter
+	if !all.Allows(manifest.KindAlertPolicy) {
+		t.Error("expected nil filter to allow applicable kinds")
+	}
+	if all.Allows(manifest.KindUserGroup) {
+		t.Error("expected nil filter not to allow read-only kinds")
+	}
+}
+
+func TestKindCounts(t *testing.T) {
+	counts := make(KindCounts)
+	counts.Add("SLO", 2)
+	counts.Merge(KindCounts{"Project": 1, "SLO": 1})
+
+	if counts.Total() != 4 {
+		t.Errorf("expected total 4, got %d", counts.Total())
+	}
+	if s := counts.String(); s != "Project=1,SLO=3" {
+		t.Errorf("expected %q, got %q", "Project=1,SLO=3", s)
+	}
+}
+
+func TestSkippedObjects(t *testing.T) {
+	skipped := make(SkippedObjects)
+	if skipped.Total() != 0 || skipped.String() != "" {
+		t.Errorf("expected empty report, got %d (%q)", skipped.Total(), skipped.String())
+	}
+
+	skipped.Add("Service", "api")
+	skipped.Merge(SkippedObjects{"AlertPolicy": {"slow"}, "Service": {"web"}})
+
+	if skipped.Total() != 3 {
+		t.Errorf("expected 3 skipped objects, got %d", skipped.Total())
+	}
+
+	kinds := skipped.Kinds()
+	if len(kinds) != 2 || kinds[0] != "AlertPolicy" || kinds[1] != "Service" {
+		t.Errorf("expected sorted kinds [AlertPolicy Service], got %v", kinds)
+	}
+
+	if s := skipped.String(); s != "AlertPolicy=1,Service=2" {
+		t.Errorf("expected %q, got %q", "AlertPolicy=1,Service=2", s)
+	}
 }
 
 func TestGenerateSummary(t *testing.T) {
@@ -123,6 +240,30 @@ func TestGenerateSummary(t *testing.T) {
 			},
 			expected: "Processing completed: 1 projects, 0 role bindings, 0 emails resolved, 2 errors",
 		},
+		{
+			name: "with skipped objects",
+			result: &ProcessResult{
+				Projects:       []ProcessedObject{},
+				RoleBindings:   []ProcessedObject{},
+				EmailsResolved: map[string]string{},
+				Skipped:        SkippedObjects{"SLO": {"latency", "availability"}},
+				Errors:         []error{},
+			},
+			expected: "Processing completed: 0 projects, 0 role bindings, 0 emails resolved, 0 errors, 2 objects skipped (SLO=2)",
+		},
+		{
+			name: "with unchanged role bindings",
+			result: &ProcessResult{
+				Projects: []ProcessedObject{},
+				RoleBindings: []ProcessedObject{
+					{Applied: true},
+					{Unchanged: true},
+				},
+				EmailsResolved: map[string]string{},
+				Errors:         []error{},
+			},
+			expected: "Processing completed: 0 projects, 1 role bindings, 0 emails resolved, 0 errors, 1 role bindings unchanged",
+		},
 	}
 
 	for _, tt := range tests {
@@ -136,6 +277,123 @@ func TestGenerateSummary(t *testing.T) {
 	}
 }
 
+func TestRoleBindingUnchanged(t *testing.T) {
+	ref := func(value string) *string { return &value }
+	binding := func(user, group *string, role, project string) v1alphaRoleBinding.RoleBinding {
+		return v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "binding"},
+			v1alphaRoleBinding.Spec{User: user, GroupRef: group, RoleRef: role, ProjectRef: project},
+		)
+	}
+
+	live := binding(ref("00u1"), nil, "project-viewer", "payments")
+
+	tests := []struct {
+		name     string
+		desired  v1alphaRoleBinding.RoleBinding
+		expected bool
+	}{
+		{name: "identical", desired: binding(ref("00u1"), nil, "project-viewer", "payments"), expected: true},
+		{name: "different user", desired: binding(ref("00u2"), nil, "project-viewer", "payments"), expected: false},
+		{name: "different role", desired: binding(ref("00u1"), nil, "project-editor", "payments"), expected: false},
+		{name: "different project", desired: binding(ref("00u1"), nil, "project-viewer", "billing"), expected: false},
+		{name: "group instead of user", desired: binding(nil, ref("platform"), "project-viewer", "payments"), expected: false},
+		{name: "unresolved email", desired: binding(ref("alice@example.com"), nil, "project-viewer", "payments"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := RoleBindingUnchanged(tt.desired, live); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	// Empty and missing references are the same
+	empty := ""
+	if !RoleBindingUnchanged(binding(ref("00u1"), &empty, "project-viewer", "payments"), live) {
+		t.Error("expected empty group reference to match a missing one")
+	}
+}
+
+func TestFilterUnchangedRoleBindings(t *testing.T) {
+	userID := "00u1"
+	newBinding := func(name, role string) v1alphaRoleBinding.RoleBinding {
+		return v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: name},
+			v1alphaRoleBinding.Spec{User: &userID, RoleRef: role, ProjectRef: "payments"},
+		)
+	}
+
+	objects := []manifest.Object{
+		v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{}),
+		newBinding("same", "project-viewer"),
+		newBinding("changed", "project-editor"),
+		newBinding("new", "project-viewer"),
+	}
+	live := map[string]v1alphaRoleBinding.RoleBinding{
+		"same":    newBinding("same", "project-viewer"),
+		"changed": newBinding("changed", "project-viewer"),
+	}
+
+	remaining, unchanged := filterUnchangedRoleBindings(objects, live)
+
+	if len(unchanged) != 1 || unchanged[0] != "same" {
+		t.Errorf("expected only %q to be unchanged, got %v", "same", unchanged)
+	}
+	if len(remaining) != 3 {
+		t.Fatalf("expected 3 objects to apply, got %d", len(remaining))
+	}
+	for _, obj := range remaining {
+		if obj.GetName() == "same" {
+			t.Error("expected unchanged role binding not to be applied")
+		}
+	}
+}
+
+func TestSubstituteUserIDs(t *testing.T) {
+	alice := "alice@example.com"
+	bob := "bob@example.com"
+	newBinding := func(name string, user *string) v1alphaRoleBinding.RoleBinding {
+		return v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: name},
+			v1alphaRoleBinding.Spec{User: user, RoleRef: "project-viewer", ProjectRef: "payments"},
+		)
+	}
+
+	objects := []manifest.Object{
+		v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{}),
+		newBinding("alice", &alice),
+		newBinding("bob", &bob),
+		newBinding("group", nil),
+	}
+
+	substituted, changed := SubstituteUserIDs(objects, map[string]string{alice: "00u1alice"})
+
+	if changed != 1 {
+		t.Errorf("expected 1 role binding changed, got %d", changed)
+	}
+	if len(substituted) != len(objects) {
+		t.Fatalf("expected %d objects, got %d", len(objects), len(substituted))
+	}
+
+	expected := map[string]string{"alice": "00u1alice", "bob": bob}
+	for _, obj := range substituted {
+		rb, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || rb.Spec.User == nil {
+			continue
+		}
+		if *rb.Spec.User != expected[rb.Metadata.Name] {
+			t.Errorf("expected user %q for %s, got %q", expected[rb.Metadata.Name], rb.Metadata.Name, *rb.Spec.User)
+		}
+	}
+
+	// The original objects are not modified
+	if user := *objects[1].(v1alphaRoleBinding.RoleBinding).Spec.User; user != alice {
+		t.Errorf("expected original role binding to keep %q, got %q", alice, user)
+	}
+}
+
 func TestSanitizeName(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -224,9 +482,348 @@ func TestTruncate(t *testing.T) {
 	}
 }
 
+type fakeGroupExpander struct {
+	groups map[string][]string
+}
+
+func (f *fakeGroupExpander) GetGroupMemberEmails(ctx context.Context, groupName string) ([]string, error) {
+	members, ok := f.groups[groupName]
+	if !ok {
+		return nil, fmt.Errorf("group %s not found", groupName)
+	}
+	return members, nil
+}
+
+func TestExpandGroupRoleBindings(t *testing.T) {
+	groupRef := "okta-group:Platform Team"
+	directUser := "dave@example.com"
+
+	objects := []manifest.Object{
+		v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{}),
+		v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "payments-owners"},
+			v1alphaRoleBinding.Spec{User: &groupRef, RoleRef: "project-owner", ProjectRef: "payments"},
+		),
+		v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "payments-dave"},
+			v1alphaRoleBinding.Spec{User: &directUser, RoleRef: "project-viewer", ProjectRef: "payments"},
+		),
+	}
+
+	expander := &fakeGroupExpander{groups: map[string][]string{
+		"Platform Team": {"alice@example.com", "bob.smith@example.com"},
+	}}
+
+	expanded, err := ExpandGroupRoleBindings(context.Background(), objects, expander)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(expanded) != 4 {
+		t.Fatalf("expected 4 objects after expansion, got %d", len(expanded))
+	}
+
+	expected := map[string]string{
+		"payments-owners-alice-example-com-ff8d9819fc":     "alice@example.com",
+		"payments-owners-bob-smith-example-com-a39860817a": "bob.smith@example.com",
+		"payments-dave": "dave@example.com",
+	}
+
+	for _, obj := range expanded {
+		rb, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok {
+			continue
+		}
+		user, found := expected[rb.Metadata.Name]
+		if !found {
+			t.Errorf("unexpected role binding %s", rb.Metadata.Name)
+			continue
+		}
+		if *rb.Spec.User != user {
+			t.Errorf("expected user %s for %s, got %s", user, rb.Metadata.Name, *rb.Spec.User)
+		}
+		if rb.Spec.ProjectRef != "payments" {
+			t.Errorf("expected project ref to be preserved, got %s", rb.Spec.ProjectRef)
+		}
+	}
+
+	// The original object must not be mutated
+	original := objects[1].(v1alphaRoleBinding.RoleBinding)
+	if *original.Spec.User != groupRef {
+		t.Errorf("expected original role binding to keep group reference, got %s", *original.Spec.User)
+	}
+}
+
+func TestExpandGroupRoleBindingsErrors(t *testing.T) {
+	groupRef := "okta-group:Unknown"
+	objects := []manifest.Object{
+		v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "owners"},
+			v1alphaRoleBinding.Spec{User: &groupRef, RoleRef: "project-owner", ProjectRef: "payments"},
+		),
+	}
+
+	if _, err := ExpandGroupRoleBindings(context.Background(), objects, nil); err == nil {
+		t.Error("expected error when Okta is not configured")
+	}
+
+	if _, err := ExpandGroupRoleBindings(context.Background(), objects, &fakeGroupExpander{}); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestExpandTeamRoleBindings(t *testing.T) {
+	teamRef := "github-team:acme/payments"
+	oktaRef := "okta-group:Payments"
+	objects := []manifest.Object{
+		v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "payments-owners"},
+			v1alphaRoleBinding.Spec{User: &teamRef, RoleRef: "project-owner", ProjectRef: "payments"},
+		),
+		v1alphaRoleBinding.New(
+			v1alphaRoleBinding.Metadata{Name: "payments-viewers"},
+			v1alphaRoleBinding.Spec{User: &oktaRef, RoleRef: "project-viewer", ProjectRef: "payments"},
+		),
+	}
+
+	if _, err := ExpandTeamRoleBindings(context.Background(), objects, nil); err == nil || !strings.Contains(err.Error(), "references a GitHub team but no GitHub token is configured") {
+		t.Errorf("expected an error when no GitHub token is configured, got %v", err)
+	}
+
+	expander := &fakeGroupExpander{groups: map[string][]string{"acme/payments": {"alice@example.com"}}}
+	expanded, err := ExpandTeamRoleBindings(context.Background(), objects, expander)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expanded) != 2 {
+		t.Fatalf("expected the team member and the untouched Okta group, got %d objects", len(expanded))
+	}
+	member := expanded[0].(v1alphaRoleBinding.RoleBinding)
+	if member.Metadata.Name != "payments-owners-alice-example-com-ff8d9819fc" || *member.Spec.User != "alice@example.com" {
+		t.Errorf("unexpected member role binding %s %+v", member.Metadata.Name, member.Spec)
+	}
+	if user := *expanded[1].(v1alphaRoleBinding.RoleBinding).Spec.User; user != oktaRef {
+		t.Errorf("expected the Okta group to be left for the Okta expander, got %s", user)
+	}
+}
+
+func TestGroupMemberBindingName(t *testing.T) {
+	long := "a-very-long-role-binding-name-that-is-close-to-the-limit"
+	name := groupMemberBindingName(long, "someone@example.com")
+	if len(name) > 63 {
+		t.Errorf("expected name to be at most 63 characters, got %d", len(name))
+	}
+	if name[len(name)-1] == '-' {
+		t.Errorf("expected name not to end with a hyphen, got %s", name)
+	}
+
+	// Members whose emails only differ past the length limit get names of
+	// their own, so applying one does not overwrite the other's binding
+	prefix := "platform-engineering-on-call-rotation-primary-escalation-team-member"
+	first := groupMemberBindingName("owners", prefix+".alice@example.com")
+	second := groupMemberBindingName("owners", prefix+".bob@example.com")
+	if first == second {
+		t.Errorf("expected different names for different members, got %s for both", first)
+	}
+	if len(first) > 63 || len(second) > 63 {
+		t.Errorf("expected names of at most 63 characters, got %s and %s", first, second)
+	}
+	if again := groupMemberBindingName("owners", " "+strings.ToUpper(prefix)+".Alice@Example.com"); again != first {
+		t.Errorf("expected the same name for the same email in another case, got %s and %s", first, again)
+	}
+}
 
 type mockError struct{}
 
 func (m *mockError) Error() string {
 	return "mock error"
 }
+
+func TestParseRoleBindingCSV(t *testing.T) {
+	content := `# Exported from the access spreadsheet
+Email,Project,Role
+alice@example.com,payments,project-owner
+Bob.Smith@example.com, payments ,project-viewer
+
+alice@example.com,payments,project-owner
+okta-group:sre,checkout,project-editor
+`
+
+	objects, err := ParseRoleBindingCSV([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct{ name, user, role, project string }{
+		{"payments-alice-example-com", "alice@example.com", "project-owner", "payments"},
+		{"payments-bob-smith-example-com", "Bob.Smith@example.com", "project-viewer", "payments"},
+		{"checkout-okta-group-sre", "okta-group:sre", "project-editor", "checkout"},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d role bindings, got %d", len(expected), len(objects))
+	}
+	for i, want := range expected {
+		binding := objects[i].(v1alphaRoleBinding.RoleBinding)
+		if binding.Metadata.Name != want.name || *binding.Spec.User != want.user ||
+			binding.Spec.RoleRef != want.role || binding.Spec.ProjectRef != want.project {
+			t.Errorf("role binding %d: expected %+v, got %s %+v", i, want, binding.Metadata.Name, binding.Spec)
+		}
+		if err := binding.Validate(); err != nil {
+			t.Errorf("role binding %d: unexpected validation error: %v", i, err)
+		}
+	}
+}
+
+func TestParseRoleBindingCSVErrors(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"empty":           {"", "missing header row"},
+		"missing column":  {"project,email\npayments,alice@example.com\n", "missing role column"},
+		"missing project": {"project,email,role\n,alice@example.com,project-owner\n", "line 2: project is required"},
+		"missing role":    {"project,email,role\npayments,alice@example.com,\n", "line 2: role is required"},
+		"not an email":    {"project,email,role\npayments,alice,project-owner\n", "line 2: 'alice' is not an email"},
+		"two roles":       {"project,email,role\npayments,alice@example.com,project-owner\npayments,alice@example.com,project-viewer\n", "line 3: alice@example.com already has role project-owner"},
+		"name collision":  {"project,email,role\npayments,alice.b@example.com,project-owner\npayments,alice-b@example.com,project-owner\n", "line 3: role binding name payments-alice-b-example-com is already used"},
+		"wrong fields":    {"project,email,role\npayments,alice@example.com\n", "wrong number of fields"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRoleBindingCSV([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseOwnersFile(t *testing.T) {
+	content := `# Reviewed by the payments team
+approvers:
+  - alice@example.com
+reviewers:
+  - alice@example.com
+  - Bob.Smith@example.com
+  - okta-group:payments-readers
+emeritus_approvers:
+  - carol@example.com
+options:
+  no_parent_owners: true
+`
+	roles, err := ParseOwnersRoles(DefaultOwnersRoles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	objects, err := ParseOwnersFile([]byte(content), "payments", roles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct{ name, user, role string }{
+		{"payments-alice-example-com", "alice@example.com", "project-owner"},
+		{"payments-bob-smith-example-com", "Bob.Smith@example.com", "project-viewer"},
+		{"payments-okta-group-payments-readers", "okta-group:payments-readers", "project-viewer"},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d role bindings, got %d", len(expected), len(objects))
+	}
+	for i, want := range expected {
+		binding := objects[i].(v1alphaRoleBinding.RoleBinding)
+		if binding.Metadata.Name != want.name || *binding.Spec.User != want.user ||
+			binding.Spec.RoleRef != want.role || binding.Spec.ProjectRef != "payments" {
+			t.Errorf("role binding %d: expected %+v, got %s %+v", i, want, binding.Metadata.Name, binding.Spec)
+		}
+		if err := binding.Validate(); err != nil {
+			t.Errorf("role binding %d: unexpected validation error: %v", i, err)
+		}
+	}
+
+	// The first list mapped wins for users on both lists
+	roles, err = ParseOwnersRoles("reviewers=project-viewer, approvers=project-owner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	objects, err = ParseOwnersFile([]byte(content), "payments", roles)
+	if err != nil || objects[0].(v1alphaRoleBinding.RoleBinding).Spec.RoleRef != "project-viewer" {
+		t.Errorf("expected alice to be a project viewer, got %v (%v)", objects, err)
+	}
+}
+
+func TestParseOwnersFileErrors(t *testing.T) {
+	roles := []OwnersRole{{List: "approvers", Role: "project-owner"}}
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"not a mapping":   {"- alice@example.com\n", "line 1: expected a mapping"},
+		"not a list":      {"approvers: alice@example.com\n", "line 1: approvers must be a list"},
+		"github username": {"approvers:\n  - alice\n", "line 2: 'alice' is not an email"},
+		"name collision":  {"approvers:\n  - alice.b@example.com\n  - alice-b@example.com\n", "line 3: role binding name payments-alice-b-example-com is already used"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseOwnersFile([]byte(tt.content), "payments", roles)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseOwnersRolesErrors(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                          "no OWNERS list is mapped",
+		"approvers":                 "not a list=role mapping",
+		"maintainers=project-owner": "unknown OWNERS list 'maintainers'",
+		"approvers=project-owner,approvers=project-viewer": "mapped more than once",
+	} {
+		if _, err := ParseOwnersRoles(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", value, want, err)
+		}
+	}
+}
+
+func TestRoleBindingName(t *testing.T) {
+	name := RoleBindingName("payments", "project-owner", "Alice@Example.com")
+	if !strings.HasPrefix(name, "payments-project-owner-") || len(name) != len("payments-project-owner-")+10 {
+		t.Errorf("unexpected name %q", name)
+	}
+	if again := RoleBindingName("payments", "project-owner", " alice@example.com"); again != name {
+		t.Errorf("expected the same grant to get the same name, got %q and %q", name, again)
+	}
+	if other := RoleBindingName("payments", "project-viewer", "alice@example.com"); other == name {
+		t.Errorf("expected another grant to get another name, got %q", other)
+	}
+
+	long := RoleBindingName(strings.Repeat("very-long-project-", 5), "organization-admin", "bob@example.com")
+	if len(long) > 63 || strings.Contains(long, "--") {
+		t.Errorf("expected an RFC-1123 name of at most 63 characters, got %q", long)
+	}
+	if org := RoleBindingName("", "organization-admin", "bob@example.com"); !strings.HasPrefix(org, "organization-admin-") {
+		t.Errorf("expected an organization role binding named after its role, got %q", org)
+	}
+}
+
+func TestNameRoleBindings(t *testing.T) {
+	user := "alice@example.com"
+	unnamed := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "project-owner", ProjectRef: "payments"})
+	named := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-alice"}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "project-viewer", ProjectRef: "payments"})
+	userless := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{}, v1alphaRoleBinding.Spec{RoleRef: "project-owner", ProjectRef: "payments"})
+
+	objects, generated := NameRoleBindings([]manifest.Object{unnamed, named, userless})
+	if generated != 1 {
+		t.Fatalf("expected 1 generated name, got %d", generated)
+	}
+	if objects[0].GetName() != RoleBindingName("payments", "project-owner", user) {
+		t.Errorf("unexpected generated name %q", objects[0].GetName())
+	}
+	if objects[1].GetName() != "payments-alice" || objects[2].GetName() != "" {
+		t.Errorf("expected other role bindings to keep their names, got %q and %q", objects[1].GetName(), objects[2].GetName())
+	}
+	if unnamed.GetName() != "" {
+		t.Error("expected the input objects to be left unchanged")
+	}
+}
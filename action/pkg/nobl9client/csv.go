@@ -0,0 +1,103 @@
+package nobl9client
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/your-org/nobl9-action/pkg/okta"
+)
+
+// RoleBindingCSVColumns are the columns of a role binding CSV file, in any
+// order
+var RoleBindingCSVColumns = []string{"project", "email", "role"}
+
+// ParseRoleBindingCSV converts the rows of a project,email,role CSV file,
+// such as an access list exported from a spreadsheet, into role bindings
+// named <project>-<email> (sanitized). The header row is required; empty
+// lines and lines starting with # are ignored, and repeated rows are only
+// kept once.
+func ParseRoleBindingCSV(content []byte) ([]manifest.Object, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("missing header row %s", strings.Join(RoleBindingCSVColumns, ","))
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := roleBindingCSVColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []manifest.Object
+	rows := make(map[string]v1alphaRoleBinding.Spec)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		project := strings.TrimSpace(record[columns["project"]])
+		email := strings.TrimSpace(record[columns["email"]])
+		role := strings.TrimSpace(record[columns["role"]])
+		switch {
+		case project == "":
+			return nil, fmt.Errorf("line %d: project is required", line)
+		case role == "":
+			return nil, fmt.Errorf("line %d: role is required", line)
+		case !strings.Contains(email, "@") && !okta.IsGroupReference(email):
+			return nil, fmt.Errorf("line %d: '%s' is not an email or %s reference", line, email, okta.GroupPrefix)
+		}
+
+		name := roleBindingCSVName(project, email)
+		spec := v1alphaRoleBinding.Spec{User: &email, RoleRef: role, ProjectRef: project}
+		if previous, found := rows[name]; found {
+			switch {
+			case previous.ProjectRef != project || *previous.User != email:
+				return nil, fmt.Errorf("line %d: role binding name %s is already used by %s in project %s", line, name, *previous.User, previous.ProjectRef)
+			case previous.RoleRef != role:
+				return nil, fmt.Errorf("line %d: %s already has role %s in project %s, a user has one role per project", line, email, previous.RoleRef, project)
+			}
+			continue
+		}
+		rows[name] = spec
+
+		objects = append(objects, v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: name}, spec))
+	}
+
+	return objects, nil
+}
+
+// roleBindingCSVColumns maps the role binding columns to their index in the
+// header row
+func roleBindingCSVColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range RoleBindingCSVColumns {
+		if _, found := columns[name]; !found {
+			return nil, fmt.Errorf("missing %s column, the header row must name the columns %s", name, strings.Join(RoleBindingCSVColumns, ","))
+		}
+	}
+	return columns, nil
+}
+
+// roleBindingCSVName derives the name of a role binding imported from CSV
+func roleBindingCSVName(project, email string) string {
+	return strings.Trim(truncate(sanitizeName(project+"-"+email), 63), "-")
+}
@@ -0,0 +1,97 @@
+package nobl9client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/sirupsen/logrus"
+)
+
+// roleBindingNameHashLength is the number of hex digits of the grant hash
+// ending generated role binding names
+const roleBindingNameHashLength = 10
+
+// RoleBindingName derives a role binding name from the grant: the project
+// and role, sanitized to RFC-1123, followed by a hash of the project, role
+// and user. The same grant always gets the same name, so re-applying a
+// manifest updates its role binding instead of creating another one, and
+// different grants never share a name. Users are hashed case-insensitively
+// as written, before email resolution.
+func RoleBindingName(project, role, user string) string {
+	sum := sha256.Sum256([]byte(project + "\n" + role + "\n" + strings.ToLower(strings.TrimSpace(user))))
+	hash := hex.EncodeToString(sum[:])[:roleBindingNameHashLength]
+
+	prefix := role
+	if project != "" {
+		prefix = project + "-" + role
+	}
+	prefix = strings.Trim(truncate(sanitizeName(prefix), 63-roleBindingNameHashLength-1), "-")
+	if prefix == "" {
+		return "rb-" + hash
+	}
+	return prefix + "-" + hash
+}
+
+// groupMemberBindingName derives the role binding name of one member of an
+// expanded group: the group's binding name and the member email, sanitized
+// to RFC-1123, followed by a hash of the lowercased email as
+// RoleBindingName hashes users. Members whose emails only differ past the length limit, or in
+// characters sanitizing drops, still get role bindings of their own.
+func groupMemberBindingName(bindingName, email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	sum := sha256.Sum256([]byte(email))
+	hash := hex.EncodeToString(sum[:])[:roleBindingNameHashLength]
+
+	prefix := strings.Trim(truncate(sanitizeName(bindingName+"-"+email), 63-roleBindingNameHashLength-1), "-")
+	return prefix + "-" + hash
+}
+
+// NameRoleBindings gives role bindings without metadata.name the name
+// RoleBindingName derives from their grant. Role bindings with neither a
+// user nor a group are left unnamed, so validation still reports them. It
+// returns the objects and the number of generated names.
+func NameRoleBindings(objects []manifest.Object) ([]manifest.Object, int) {
+	named := make([]manifest.Object, len(objects))
+	generated := 0
+
+	for i, obj := range objects {
+		named[i] = obj
+
+		roleBinding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok || roleBinding.Metadata.Name != "" {
+			continue
+		}
+		user := RoleBindingUser(roleBinding)
+		if user == "" {
+			continue
+		}
+
+		roleBinding.Metadata.Name = RoleBindingName(roleBinding.Spec.ProjectRef, roleBinding.Spec.RoleRef, user)
+		named[i] = roleBinding
+		generated++
+
+		logrus.WithFields(logrus.Fields{
+			"role_binding": roleBinding.Metadata.Name,
+			"project":      roleBinding.Spec.ProjectRef,
+			"role":         roleBinding.Spec.RoleRef,
+		}).Debug("Generated role binding name")
+	}
+
+	return named, generated
+}
+
+// RoleBindingUser returns the user of a role binding, or its group as
+// group:<ref>, or "" when it has neither
+func RoleBindingUser(roleBinding v1alphaRoleBinding.RoleBinding) string {
+	switch {
+	case roleBinding.Spec.User != nil && *roleBinding.Spec.User != "":
+		return *roleBinding.Spec.User
+	case roleBinding.Spec.GroupRef != nil && *roleBinding.Spec.GroupRef != "":
+		return "group:" + *roleBinding.Spec.GroupRef
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,130 @@
+package nobl9client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/your-org/nobl9-action/pkg/okta"
+	"gopkg.in/yaml.v3"
+)
+
+// OwnersFileName is the name of the Kubernetes-style OWNERS files read from
+// project directories
+const OwnersFileName = "OWNERS"
+
+// OwnersLists are the lists of an OWNERS file that can be mapped to roles
+var OwnersLists = []string{"approvers", "reviewers"}
+
+// DefaultOwnersRoles maps approvers to project owners and reviewers to
+// project viewers
+const DefaultOwnersRoles = "approvers=project-owner,reviewers=project-viewer"
+
+// OwnersRole maps a list of an OWNERS file to the project role of its users
+type OwnersRole struct {
+	List string
+	Role string
+}
+
+// ParseOwnersRoles parses a comma separated list=role mapping such as
+// DefaultOwnersRoles. The order matters: a user on several lists gets the
+// role of the first of them, since a user has one role per project.
+func ParseOwnersRoles(value string) ([]OwnersRole, error) {
+	var roles []OwnersRole
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		list, role, found := strings.Cut(entry, "=")
+		list, role = strings.ToLower(strings.TrimSpace(list)), strings.TrimSpace(role)
+		switch {
+		case !found || role == "":
+			return nil, fmt.Errorf("'%s' is not a list=role mapping", entry)
+		case !isOwnersList(list):
+			return nil, fmt.Errorf("unknown OWNERS list '%s', expected %s", list, strings.Join(OwnersLists, " or "))
+		case seen[list]:
+			return nil, fmt.Errorf("OWNERS list %s is mapped more than once", list)
+		}
+		seen[list] = true
+		roles = append(roles, OwnersRole{List: list, Role: role})
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("no OWNERS list is mapped to a role")
+	}
+	return roles, nil
+}
+
+// ParseOwnersFile converts the approvers and reviewers of an OWNERS file
+// into role bindings of project, named like the role bindings of a CSV file
+// so a user moved between the two keeps their role binding. Entries must be
+// emails or Okta group references; lists without a role in roles, and the
+// other keys of the file such as options, are ignored.
+func ParseOwnersFile(content []byte, project string, roles []OwnersRole) ([]manifest.Object, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of approvers and reviewers", document.Line)
+	}
+
+	var objects []manifest.Object
+	assigned := make(map[string]string)
+	for _, mapping := range roles {
+		users := ownersList(document, mapping.List)
+		if users == nil || users.Tag == "!!null" {
+			continue
+		}
+		if users.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("line %d: %s must be a list of emails", users.Line, mapping.List)
+		}
+		for _, user := range users.Content {
+			email := strings.TrimSpace(user.Value)
+			if user.Kind != yaml.ScalarNode || (!strings.Contains(email, "@") && !okta.IsGroupReference(email)) {
+				return nil, fmt.Errorf("line %d: '%s' is not an email or %s reference", user.Line, email, okta.GroupPrefix)
+			}
+
+			name := roleBindingCSVName(project, email)
+			if previous, found := assigned[name]; found {
+				if previous != email {
+					return nil, fmt.Errorf("line %d: role binding name %s is already used by %s in project %s", user.Line, name, previous, project)
+				}
+				continue
+			}
+			assigned[name] = email
+
+			spec := v1alphaRoleBinding.Spec{User: &email, RoleRef: mapping.Role, ProjectRef: project}
+			objects = append(objects, v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: name}, spec))
+		}
+	}
+
+	return objects, nil
+}
+
+// ownersList returns the value of an OWNERS list, or nil when the file does
+// not have it
+func ownersList(document *yaml.Node, list string) *yaml.Node {
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if document.Content[i].Value == list {
+			return document.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isOwnersList reports whether list is one of OwnersLists
+func isOwnersList(list string) bool {
+	for _, known := range OwnersLists {
+		if list == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// Notification targets
+const (
+	TargetSlack   = "slack"
+	TargetWebhook = "webhook"
+)
+
+// Notification filters
+const (
+	// OnAlways notifies after every run
+	OnAlways = "always"
+	// OnFailure notifies only after runs that failed
+	OnFailure = "failure"
+)
+
+// maxListed caps the errors and unresolved users listed in Slack messages
+const maxListed = 10
+
+// Summary is what a notification reports of a run
+type Summary struct {
+	Repository string `json:"repository,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	RunURL     string `json:"run_url,omitempty"`
+	DryRun     bool   `json:"dry_run"`
+	Success    bool   `json:"success"`
+
+	FilesProcessed  int `json:"files_processed"`
+	FilesWithErrors int `json:"files_with_errors"`
+	// Created and Updated count applied objects by whether they existed
+	// before the run; objects whose previous state could not be read count
+	// as updated
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+	// Planned counts the objects a dry run would apply
+	Planned int `json:"planned,omitempty"`
+
+	// Errors describe what failed, e.g. "nobl9/payments.yaml: invalid SLO"
+	Errors []string `json:"errors,omitempty"`
+	// UnresolvedUsers are the emails that did not resolve to Nobl9 users
+	UnresolvedUsers []string `json:"unresolved_users,omitempty"`
+	// AbortedBy is the critical error that stopped the run early
+	AbortedBy string `json:"aborted_by,omitempty"`
+}
+
+// Sender posts a run summary to one target
+type Sender interface {
+	Target() string
+	Send(ctx context.Context, summary *Summary) error
+}
+
+// Config holds notification configuration
+type Config struct {
+	// SlackWebhookURL is a Slack incoming webhook
+	SlackWebhookURL string
+	// WebhookURL receives the summary as JSON
+	WebhookURL string
+	// On is OnAlways or OnFailure; it defaults to OnAlways
+	On      string
+	Timeout time.Duration
+}
+
+// Notifier posts run summaries to every configured target
+type Notifier struct {
+	senders []Sender
+	on      string
+}
+
+// New creates a notifier for the configured targets. A notifier without
+// targets sends nothing.
+func New(config *Config) (*Notifier, error) {
+	if config == nil {
+		return nil, errors.NewConfigError("notify config cannot be nil", nil)
+	}
+	on := config.On
+	if on == "" {
+		on = OnAlways
+	}
+	if on != OnAlways && on != OnFailure {
+		return nil, errors.NewConfigError(fmt.Sprintf("invalid notify-on %q, expected %s or %s", on, OnAlways, OnFailure), nil)
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	httpClient := &http.Client{Timeout: timeout}
+
+	notifier := &Notifier{on: on}
+	if config.SlackWebhookURL != "" {
+		if err := checkURL(config.SlackWebhookURL); err != nil {
+			return nil, errors.NewConfigError("invalid slack-webhook-url", err)
+		}
+		notifier.senders = append(notifier.senders, &SlackSender{url: config.SlackWebhookURL, httpClient: httpClient})
+	}
+	if config.WebhookURL != "" {
+		if err := checkURL(config.WebhookURL); err != nil {
+			return nil, errors.NewConfigError("invalid notify-url", err)
+		}
+		notifier.senders = append(notifier.senders, &WebhookSender{url: config.WebhookURL, httpClient: httpClient})
+	}
+	return notifier, nil
+}
+
+// Enabled reports whether any target is configured
+func (n *Notifier) Enabled() bool {
+	return len(n.senders) > 0
+}
+
+// Notify posts the summary to every target, unless the notifier only
+// notifies failures and the run succeeded. Every target is tried; the
+// failures are returned together.
+func (n *Notifier) Notify(ctx context.Context, summary *Summary) error {
+	if n.on == OnFailure && summary.Success {
+		return nil
+	}
+	var errs []error
+	for _, sender := range n.senders {
+		if err := sender.Send(ctx, summary); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sender.Target(), err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// SlackSender posts run summaries to a Slack incoming webhook
+type SlackSender struct {
+	url        string
+	httpClient *http.Client
+}
+
+// Target returns TargetSlack
+func (s *SlackSender) Target() string {
+	return TargetSlack
+}
+
+// Send posts the summary as a Slack message
+func (s *SlackSender) Send(ctx context.Context, summary *Summary) error {
+	return post(ctx, s.httpClient, s.url, map[string]string{"text": SlackText(summary)})
+}
+
+// WebhookSender posts run summaries as JSON to an HTTP endpoint
+type WebhookSender struct {
+	url        string
+	httpClient *http.Client
+}
+
+// Target returns TargetWebhook
+func (w *WebhookSender) Target() string {
+	return TargetWebhook
+}
+
+// Send posts the summary as JSON
+func (w *WebhookSender) Send(ctx context.Context, summary *Summary) error {
+	return post(ctx, w.httpClient, w.url, summary)
+}
+
+// SlackText renders the summary as a Slack mrkdwn message
+func SlackText(summary *Summary) string {
+	var b strings.Builder
+	status := ":white_check_mark: Nobl9 sync succeeded"
+	if !summary.Success {
+		status = ":x: Nobl9 sync failed"
+	}
+	b.WriteString(status)
+	if summary.Repository != "" {
+		fmt.Fprintf(&b, " for *%s*", summary.Repository)
+		if summary.Ref != "" {
+			fmt.Fprintf(&b, " (`%s`)", summary.Ref)
+		}
+	}
+	if summary.DryRun {
+		b.WriteString(" _(dry run)_")
+	}
+	b.WriteString("\n")
+
+	if summary.DryRun {
+		fmt.Fprintf(&b, "Would apply %d · Unchanged %d · Failed %d\n", summary.Planned, summary.Unchanged, summary.Failed)
+	} else {
+		fmt.Fprintf(&b, "Created %d · Updated %d · Unchanged %d · Failed %d\n", summary.Created, summary.Updated, summary.Unchanged, summary.Failed)
+	}
+	fmt.Fprintf(&b, "Files: %d processed, %d with errors\n", summary.FilesProcessed, summary.FilesWithErrors)
+
+	if summary.AbortedBy != "" {
+		fmt.Fprintf(&b, "Aborted: %s\n", summary.AbortedBy)
+	}
+	if len(summary.UnresolvedUsers) > 0 {
+		fmt.Fprintf(&b, "Unresolved users (%d): %s\n", len(summary.UnresolvedUsers), listed(summary.UnresolvedUsers, ", "))
+	}
+	if len(summary.Errors) > 0 {
+		fmt.Fprintf(&b, "Errors (%d):\n• %s\n", len(summary.Errors), listed(summary.Errors, "\n• "))
+	}
+	if summary.RunURL != "" {
+		fmt.Fprintf(&b, "<%s|View the workflow run>\n", summary.RunURL)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// listed joins up to maxListed items, noting how many were left out
+func listed(items []string, sep string) string {
+	if len(items) <= maxListed {
+		return strings.Join(items, sep)
+	}
+	return strings.Join(items[:maxListed], sep) + fmt.Sprintf("%sand %d more", sep, len(items)-maxListed)
+}
+
+// checkURL accepts absolute http and https URLs
+func checkURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("expected an http or https URL")
+	}
+	return nil
+}
+
+// post sends body as JSON and fails on non-2xx responses. The URL is left
+// out of errors, since webhook URLs embed their credentials.
+func post(ctx context.Context, httpClient *http.Client, endpoint string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if stderrors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
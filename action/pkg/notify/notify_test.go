@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotify(t *testing.T) {
+	var slackText string
+	var webhook Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/slack":
+			var message map[string]string
+			if err := json.Unmarshal(body, &message); err != nil {
+				t.Errorf("unexpected Slack payload: %s", body)
+			}
+			slackText = message["text"]
+		case "/webhook":
+			if err := json.Unmarshal(body, &webhook); err != nil {
+				t.Errorf("unexpected webhook payload: %s", body)
+			}
+		}
+	}))
+	defer server.Close()
+
+	notifier, err := New(&Config{SlackWebhookURL: server.URL + "/slack", WebhookURL: server.URL + "/webhook"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	summary := &Summary{
+		Repository:      "acme/slos",
+		RunURL:          "https://github.com/acme/slos/actions/runs/42",
+		Created:         2,
+		Updated:         1,
+		Failed:          1,
+		FilesProcessed:  3,
+		FilesWithErrors: 1,
+		Errors:          []string{"nobl9/billing.yaml: invalid SLO"},
+		UnresolvedUsers: []string{"bob@example.com"},
+	}
+	if err := notifier.Notify(context.Background(), summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		":x: Nobl9 sync failed for *acme/slos*",
+		"Created 2 · Updated 1 · Unchanged 0 · Failed 1",
+		"Unresolved users (1): bob@example.com",
+		"• nobl9/billing.yaml: invalid SLO",
+		"<https://github.com/acme/slos/actions/runs/42|View the workflow run>",
+	} {
+		if !strings.Contains(slackText, want) {
+			t.Errorf("expected %q in Slack message:\n%s", want, slackText)
+		}
+	}
+	if webhook.Repository != "acme/slos" || webhook.Created != 2 || len(webhook.Errors) != 1 {
+		t.Errorf("unexpected webhook summary: %+v", webhook)
+	}
+}
+
+func TestNotifyOnFailure(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	notifier, err := New(&Config{WebhookURL: server.URL, On: OnFailure})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), &Summary{Success: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), &Summary{Success: false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected only the failed run to be notified, got %d calls", calls)
+	}
+}
+
+func TestNotifyErrorsHideURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	secretURL := server.URL + "/services/T000/B000/s3cr3t"
+	notifier, err := New(&Config{SlackWebhookURL: secretURL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = notifier.Notify(context.Background(), &Summary{})
+	if err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Fatalf("expected the rejection to be reported, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("expected the webhook URL to be left out of %q", err)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []*Config{
+		{SlackWebhookURL: "hooks.slack.com/services/T000"},
+		{WebhookURL: "ftp://example.com/hook"},
+		{On: "sometimes"},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
+
+func TestSlackTextTruncatesLists(t *testing.T) {
+	summary := &Summary{Success: true, DryRun: true, Planned: 4}
+	for i := 0; i < 12; i++ {
+		summary.UnresolvedUsers = append(summary.UnresolvedUsers, fmt.Sprintf("user%d@example.com", i))
+	}
+	text := SlackText(summary)
+	for _, want := range []string{
+		":white_check_mark: Nobl9 sync succeeded _(dry run)_",
+		"Would apply 4",
+		"Unresolved users (12):",
+		"user9@example.com, and 2 more",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in Slack message:\n%s", want, text)
+		}
+	}
+}
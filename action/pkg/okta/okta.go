@@ -0,0 +1,239 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+// GroupPrefix marks a role binding user as a reference to an Okta group
+// (e.g. "okta-group:Platform Engineering") instead of a single email address
+const GroupPrefix = "okta-group:"
+
+// Client is a minimal Okta API client used to expand groups into member emails
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// Config holds Okta client configuration
+type Config struct {
+	OrgURL   string
+	APIToken string
+	Timeout  time.Duration
+}
+
+// group represents the subset of the Okta group object used by the action
+type group struct {
+	ID      string `json:"id"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+}
+
+// user represents the subset of the Okta user object used by the action
+type user struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Profile struct {
+		Email string `json:"email"`
+		Login string `json:"login"`
+	} `json:"profile"`
+}
+
+// New creates a new Okta client
+func New(config *Config, log *logger.Logger) (*Client, error) {
+	if config == nil {
+		return nil, errors.NewConfigError("okta config cannot be nil", nil)
+	}
+
+	if log == nil {
+		return nil, errors.NewConfigError("logger cannot be nil", nil)
+	}
+
+	if config.OrgURL == "" {
+		return nil, errors.NewConfigError("okta org is required", nil)
+	}
+
+	if config.APIToken == "" {
+		return nil, errors.NewConfigError("okta token is required", nil)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &Client{
+		baseURL:    normalizeOrgURL(config.OrgURL),
+		token:      config.APIToken,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     log,
+	}, nil
+}
+
+// IsGroupReference returns true if the role binding user references an Okta group
+func IsGroupReference(user string) bool {
+	return strings.HasPrefix(strings.TrimSpace(user), GroupPrefix)
+}
+
+// GroupName extracts the Okta group name from a group reference
+func GroupName(user string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(user), GroupPrefix))
+}
+
+// GetGroupMemberEmails returns the emails of all active members of the named Okta group
+func (c *Client) GetGroupMemberEmails(ctx context.Context, groupName string) ([]string, error) {
+	start := time.Now()
+
+	groupID, err := c.findGroupID(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0)
+	skipped := 0
+
+	next := fmt.Sprintf("%s/api/v1/groups/%s/users?limit=200", c.baseURL, url.PathEscape(groupID))
+	for next != "" {
+		var members []user
+		link, err := c.get(ctx, next, &members)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list members of okta group %s: %w", groupName, err)
+		}
+
+		for _, member := range members {
+			if member.Status != "ACTIVE" || member.Profile.Email == "" {
+				skipped++
+				continue
+			}
+			emails = append(emails, strings.ToLower(member.Profile.Email))
+		}
+
+		next = nextLink(link)
+	}
+
+	c.logger.Info("Expanded Okta group", logger.Fields{
+		"group":         groupName,
+		"group_id":      groupID,
+		"member_count":  len(emails),
+		"skipped_count": skipped,
+		"duration":      time.Since(start).String(),
+	})
+
+	return emails, nil
+}
+
+// UserStatus returns the status of the Okta user with the email, e.g. ACTIVE,
+// SUSPENDED or DEPROVISIONED, or an empty status when there is no such user
+func (c *Client) UserStatus(ctx context.Context, email string) (string, error) {
+	filter := fmt.Sprintf(`profile.email eq "%s"`, strings.ReplaceAll(email, `"`, `\"`))
+	endpoint := fmt.Sprintf("%s/api/v1/users?search=%s&limit=2", c.baseURL, url.QueryEscape(filter))
+
+	var users []user
+	if _, err := c.get(ctx, endpoint, &users); err != nil {
+		return "", fmt.Errorf("failed to search okta user %s: %w", email, err)
+	}
+
+	// Several users may share an email; any active one keeps it in use
+	status := ""
+	for _, u := range users {
+		if !strings.EqualFold(u.Profile.Email, email) {
+			continue
+		}
+		if status == "" || u.Status == "ACTIVE" {
+			status = u.Status
+		}
+	}
+	return status, nil
+}
+
+// findGroupID looks up the ID of the group with the exact given name
+func (c *Client) findGroupID(ctx context.Context, groupName string) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/groups?q=%s", c.baseURL, url.QueryEscape(groupName))
+
+	var groups []group
+	if _, err := c.get(ctx, endpoint, &groups); err != nil {
+		return "", fmt.Errorf("failed to search okta group %s: %w", groupName, err)
+	}
+
+	// The q parameter is a prefix search, so require an exact match
+	for _, g := range groups {
+		if g.Profile.Name == groupName {
+			return g.ID, nil
+		}
+	}
+
+	return "", errors.NewUserResolutionError(fmt.Sprintf("okta group %s not found", groupName), nil)
+}
+
+// get performs an authenticated GET request and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, endpoint string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "SSWS "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", errors.NewNetworkError("okta request failed", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", errors.NewAuthError(fmt.Sprintf("okta returned %d", resp.StatusCode), nil)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", errors.NewRateLimitError("okta rate limit exceeded", nil)
+	case resp.StatusCode >= 300:
+		return "", errors.NewNetworkError(fmt.Sprintf("okta returned %d", resp.StatusCode), nil)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("failed to decode okta response: %w", err)
+	}
+
+	return resp.Header.Get("Link"), nil
+}
+
+// nextLink extracts the rel="next" URL from an Okta Link header
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeOrgURL accepts "acme", "acme.okta.com" or "https://acme.okta.com"
+func normalizeOrgURL(org string) string {
+	org = strings.TrimRight(strings.TrimSpace(org), "/")
+
+	if strings.HasPrefix(org, "http://") || strings.HasPrefix(org, "https://") {
+		return org
+	}
+
+	if !strings.Contains(org, ".") {
+		org += ".okta.com"
+	}
+
+	return "https://" + org
+}
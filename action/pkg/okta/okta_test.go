@@ -0,0 +1,202 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+func TestNew(t *testing.T) {
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+
+	tests := []struct {
+		name        string
+		config      *Config
+		expectError bool
+	}{
+		{
+			name:        "valid configuration",
+			config:      &Config{OrgURL: "acme", APIToken: "token"},
+			expectError: false,
+		},
+		{
+			name:        "missing org",
+			config:      &Config{APIToken: "token"},
+			expectError: true,
+		},
+		{
+			name:        "missing token",
+			config:      &Config{OrgURL: "acme"},
+			expectError: true,
+		},
+		{
+			name:        "nil config",
+			config:      nil,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(tt.config, log)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client.httpClient.Timeout == 0 {
+				t.Error("expected default timeout to be set")
+			}
+		})
+	}
+}
+
+func TestNormalizeOrgURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"acme", "https://acme.okta.com"},
+		{"acme.okta.com", "https://acme.okta.com"},
+		{"acme.oktapreview.com/", "https://acme.oktapreview.com"},
+		{"https://acme.okta.com", "https://acme.okta.com"},
+		{"http://localhost:8080", "http://localhost:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := normalizeOrgURL(tt.input); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGroupReference(t *testing.T) {
+	if !IsGroupReference("okta-group:Platform Team") {
+		t.Error("expected okta-group reference to be detected")
+	}
+	if IsGroupReference("user@example.com") {
+		t.Error("expected email not to be a group reference")
+	}
+	if name := GroupName(" okta-group: Platform Team "); name != "Platform Team" {
+		t.Errorf("expected group name %q, got %q", "Platform Team", name)
+	}
+}
+
+func TestGetGroupMemberEmails(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "SSWS test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/api/v1/groups":
+			fmt.Fprint(w, `[{"id":"g2","profile":{"name":"Platform Team Leads"}},{"id":"g1","profile":{"name":"Platform Team"}}]`)
+		case r.URL.Path == "/api/v1/groups/g1/users" && r.URL.Query().Get("after") == "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/groups/g1/users?after=u2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[{"id":"u1","status":"ACTIVE","profile":{"email":"Alice@Example.com"}},{"id":"u2","status":"DEPROVISIONED","profile":{"email":"bob@example.com"}}]`)
+		case r.URL.Path == "/api/v1/groups/g1/users":
+			fmt.Fprint(w, `[{"id":"u3","status":"ACTIVE","profile":{"email":"carol@example.com"}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	client, err := New(&Config{OrgURL: server.URL, APIToken: "test-token"}, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	emails, err := client.GetGroupMemberEmails(context.Background(), "Platform Team")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"alice@example.com", "carol@example.com"}
+	if len(emails) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, emails)
+	}
+	for i := range expected {
+		if emails[i] != expected[i] {
+			t.Errorf("expected %q at %d, got %q", expected[i], i, emails[i])
+		}
+	}
+
+	if _, err := client.GetGroupMemberEmails(context.Background(), "Missing"); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestGetGroupMemberEmailsUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	client, err := New(&Config{OrgURL: server.URL, APIToken: "bad"}, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.GetGroupMemberEmails(context.Background(), "Platform Team"); err == nil {
+		t.Error("expected error for unauthorized request")
+	}
+}
+
+func TestUserStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("search") {
+		case `profile.email eq "alice@example.com"`:
+			fmt.Fprint(w, `[{"id":"u1","status":"ACTIVE","profile":{"email":"Alice@Example.com"}}]`)
+		case `profile.email eq "bob@example.com"`:
+			fmt.Fprint(w, `[{"id":"u2","status":"DEPROVISIONED","profile":{"email":"bob@example.com"}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	client, err := New(&Config{OrgURL: server.URL, APIToken: "test-token"}, log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for email, expected := range map[string]string{
+		"alice@example.com": "ACTIVE",
+		"bob@example.com":   "DEPROVISIONED",
+		"carol@example.com": "",
+	} {
+		status, err := client.UserStatus(context.Background(), email)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", email, err)
+		}
+		if status != expected {
+			t.Errorf("expected status %q for %s, got %q", expected, email, status)
+		}
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	header := `<https://acme.okta.com/api/v1/groups/g1/users?limit=200>; rel="self", <https://acme.okta.com/api/v1/groups/g1/users?after=abc>; rel="next"`
+	if next := nextLink(header); next != "https://acme.okta.com/api/v1/groups/g1/users?after=abc" {
+		t.Errorf("unexpected next link %q", next)
+	}
+	if next := nextLink(`<https://acme.okta.com/x>; rel="self"`); next != "" {
+		t.Errorf("expected no next link, got %q", next)
+	}
+}
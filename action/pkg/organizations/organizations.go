@@ -0,0 +1,134 @@
+package organizations
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Env holds the organizations inline, as the YAML list a file would hold;
+// it is read when no organizations file is given
+const Env = "NOBL9_ORGANIZATIONS"
+
+// Organization is a Nobl9 organization a run applies files to, with the
+// credentials of an access key of the organization
+type Organization struct {
+	// Name is the Nobl9 organization ID, matched against the organization
+	// of a file's ActionMeta
+	Name         string `yaml:"name"`
+	ClientID     string `yaml:"clientId"`
+	ClientSecret string `yaml:"clientSecret"`
+	// Paths are patterns, relative to the repository, of the files applied
+	// to the organization, e.g. prod/**/*.yaml
+	Paths []string `yaml:"paths"`
+	// Default makes the organization receive the files no other one claims
+	Default bool `yaml:"default"`
+}
+
+// Load reads the organizations file at path. ${VAR} references in the file
+// are replaced by environment variables, so credentials can stay in
+// secrets.
+func Load(path string) ([]Organization, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("failed to read organizations file %s", path), err)
+	}
+	return Parse(data, os.Getenv)
+}
+
+// Parse parses a YAML list of organizations, expanding ${VAR} references
+// with getenv, and checks that every organization can be used
+func Parse(data []byte, getenv func(string) string) ([]Organization, error) {
+	var organizations []Organization
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&organizations); err != nil {
+		return nil, errors.NewConfigError("invalid organizations", err)
+	}
+	if len(organizations) == 0 {
+		return nil, errors.NewConfigError("invalid organizations: no organization is listed", nil)
+	}
+
+	names := make(map[string]bool)
+	defaults := 0
+	for i := range organizations {
+		org := &organizations[i]
+		org.Name = strings.TrimSpace(os.Expand(org.Name, getenv))
+		org.ClientID = strings.TrimSpace(os.Expand(org.ClientID, getenv))
+		org.ClientSecret = strings.TrimSpace(os.Expand(org.ClientSecret, getenv))
+
+		switch {
+		case org.Name == "":
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid organizations: organization %d has no name", i+1), nil)
+		case names[org.Name]:
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid organizations: %s is listed twice", org.Name), nil)
+		case org.ClientID == "" || org.ClientSecret == "":
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid organizations: %s needs a clientId and clientSecret", org.Name), nil)
+		}
+		names[org.Name] = true
+
+		for _, pattern := range org.Paths {
+			if !doublestar.ValidatePattern(filepath.ToSlash(pattern)) {
+				return nil, errors.NewConfigError(fmt.Sprintf("invalid organizations: %s has an invalid path pattern %q", org.Name, pattern), nil)
+			}
+		}
+		if org.Default {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		return nil, errors.NewConfigError("invalid organizations: only one organization can be the default", nil)
+	}
+
+	return organizations, nil
+}
+
+// Route returns the organization a file is applied to, or nil when none
+// claims it. path is relative to the repository and metaOrganization is the
+// organization of the file's ActionMeta, if any. The ActionMeta decides
+// first, then the first organization with a matching path, then the
+// default organization.
+func Route(organizations []Organization, path, metaOrganization string) *Organization {
+	if metaOrganization != "" {
+		for i := range organizations {
+			if organizations[i].Name == metaOrganization {
+				return &organizations[i]
+			}
+		}
+		return nil
+	}
+
+	path = filepath.ToSlash(path)
+	for i := range organizations {
+		for _, pattern := range organizations[i].Paths {
+			// Patterns were checked by Parse
+			if matched, _ := doublestar.Match(filepath.ToSlash(pattern), path); matched {
+				return &organizations[i]
+			}
+		}
+	}
+
+	for i := range organizations {
+		if organizations[i].Default {
+			return &organizations[i]
+		}
+	}
+	return nil
+}
+
+// PathFor returns the per-organization variant of a file path, such as
+// .nobl9/state.prod.json for .nobl9/state.json, so organizations do not
+// share state or caches
+func PathFor(path, organization string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + organization + ext
+}
@@ -0,0 +1,90 @@
+package organizations
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const organizations = `
+- name: acme-prod
+  clientId: ${PROD_CLIENT_ID}
+  clientSecret: ${PROD_CLIENT_SECRET}
+  paths: ["prod/**/*.yaml"]
+- name: acme-staging
+  clientId: staging-id
+  clientSecret: staging-secret
+  default: true
+`
+
+func getenv(name string) string {
+	return map[string]string{"PROD_CLIENT_ID": "prod-id", "PROD_CLIENT_SECRET": "prod-secret"}[name]
+}
+
+func TestParse(t *testing.T) {
+	orgs, err := Parse([]byte(organizations), getenv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orgs) != 2 || orgs[0].ClientID != "prod-id" || orgs[0].ClientSecret != "prod-secret" || !orgs[1].Default {
+		t.Errorf("unexpected organizations: %+v", orgs)
+	}
+
+	for name, data := range map[string]string{
+		"no organization":    "[]",
+		"missing name":       "- clientId: id\n  clientSecret: secret\n",
+		"duplicate":          "- {name: a, clientId: id, clientSecret: secret}\n- {name: a, clientId: id, clientSecret: secret}\n",
+		"missing secret":     "- {name: a, clientId: id, clientSecret: ${UNSET}}\n",
+		"invalid pattern":    "- {name: a, clientId: id, clientSecret: secret, paths: ['prod/[']}\n",
+		"two defaults":       "- {name: a, clientId: id, clientSecret: secret, default: true}\n- {name: b, clientId: id, clientSecret: secret, default: true}\n",
+		"unknown field":      "- {name: a, clientId: id, clientSecret: secret, project: x}\n",
+		"not a list of orgs": "name: a\n",
+	} {
+		if _, err := Parse([]byte(data), getenv); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	orgs, err := Parse([]byte(organizations), getenv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, test := range []struct {
+		path, meta, expected string
+	}{
+		{filepath.Join("prod", "payments", "slo.yaml"), "", "acme-prod"},
+		{filepath.Join("staging", "slo.yaml"), "", "acme-staging"},
+		// The ActionMeta organization decides over the path
+		{filepath.Join("prod", "slo.yaml"), "acme-staging", "acme-staging"},
+		{filepath.Join("prod", "slo.yaml"), "acme-dev", ""},
+	} {
+		org := Route(orgs, test.path, test.meta)
+		name := ""
+		if org != nil {
+			name = org.Name
+		}
+		if name != test.expected {
+			t.Errorf("%s (%q): expected %q, got %q", test.path, test.meta, test.expected, name)
+		}
+	}
+
+	// Without a default, unmatched files belong to no organization
+	if org := Route(orgs[:1], "staging/slo.yaml", ""); org != nil {
+		t.Errorf("expected no organization, got %s", org.Name)
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if path := PathFor(filepath.Join(".nobl9", "state.json"), "acme-prod"); path != filepath.Join(".nobl9", "state.acme-prod.json") {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if path := PathFor("", "acme-prod"); path != "" {
+		t.Errorf("expected no path, got %s", path)
+	}
+	if !strings.HasSuffix(PathFor("cache", "acme-prod"), "cache.acme-prod") {
+		t.Errorf("expected the organization to be appended to a path without extension")
+	}
+}
@@ -0,0 +1,112 @@
+package outputs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Env names the file GitHub Actions reads step outputs from
+const Env = "GITHUB_OUTPUT"
+
+// Writer buffers the outputs of a step and writes them to the output file
+// in a single append when flushed, so outputs set from several goroutines
+// never interleave. It is safe for concurrent use.
+type Writer struct {
+	mu   sync.Mutex
+	path string
+	// names keeps the order outputs were first set in
+	names  []string
+	values map[string]string
+}
+
+// NewWriter creates a writer for an output file; an empty path, as outside
+// of GitHub Actions, discards the outputs
+func NewWriter(path string) *Writer {
+	return &Writer{path: path, values: make(map[string]string)}
+}
+
+// Set sets an output. Setting an output again replaces its value, as
+// GitHub Actions keeps the last value written for a name.
+func (w *Writer) Set(name, value string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, found := w.values[name]; !found {
+		w.names = append(w.names, name)
+	}
+	w.values[name] = value
+}
+
+// Get returns the value of an output and whether it was set
+func (w *Writer) Get(name string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	value, found := w.values[name]
+	return value, found
+}
+
+// Flush appends the buffered outputs to the output file and clears them.
+// Multiline values are written with a delimiter the value does not contain.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.path == "" || len(w.names) == 0 {
+		w.names, w.values = nil, make(map[string]string)
+		return nil
+	}
+
+	var b strings.Builder
+	for _, name := range w.names {
+		if err := writeOutput(&b, name, w.values[name]); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open GitHub output file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write GitHub outputs: %w", err)
+	}
+
+	w.names, w.values = nil, make(map[string]string)
+	return nil
+}
+
+// writeOutput formats a single output: name=value, or for multiline values
+// name<<delimiter, the value and the delimiter on lines of their own
+func writeOutput(b *strings.Builder, name, value string) error {
+	if !strings.ContainsAny(value, "\r\n") {
+		fmt.Fprintf(b, "%s=%s\n", name, value)
+		return nil
+	}
+
+	delimiter, err := newDelimiter(value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	return nil
+}
+
+// newDelimiter returns a random delimiter that does not occur in value
+func newDelimiter(value string) (string, error) {
+	for {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("failed to generate output delimiter: %w", err)
+		}
+		delimiter := "ghadelimiter_" + hex.EncodeToString(random)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package outputs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(path, []byte("earlier=step\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := NewWriter(path)
+	w.Set("success", "false")
+	w.Set("plan-hash", "sha256:abc")
+	w.Set("success", "true")
+	w.Set("report", "## Drift\n\n| Object |")
+	if value, found := w.Get("success"); !found || value != "true" {
+		t.Errorf("expected success=true, got %q, %v", value, found)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("expected 8 lines, got %q", lines)
+	}
+	for i, expected := range []string{"earlier=step", "success=true", "plan-hash=sha256:abc"} {
+		if lines[i] != expected {
+			t.Errorf("line %d: expected %q, got %q", i+1, expected, lines[i])
+		}
+	}
+	delimiter, found := strings.CutPrefix(lines[3], "report<<")
+	if !found || delimiter == "" {
+		t.Fatalf("expected a delimited multiline output, got %q", lines[3])
+	}
+	if strings.Join(lines[4:7], "\n") != "## Drift\n\n| Object |" || lines[7] != delimiter {
+		t.Errorf("unexpected multiline output %q", lines[3:])
+	}
+
+	// Flushed outputs are not written again
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Errorf("expected nothing to be written again, got %q", again)
+	}
+}
+
+func TestConcurrentSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	w := NewWriter(path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.Set(fmt.Sprintf("kind-%d", i), strings.Repeat("x", 100))
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("expected 50 outputs, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "kind-") || !strings.HasSuffix(line, "="+strings.Repeat("x", 100)) {
+			t.Errorf("unexpected output line %q", line)
+		}
+	}
+}
+
+func TestFlushWithoutPath(t *testing.T) {
+	w := NewWriter("")
+	w.Set("success", "true")
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, found := w.Get("success"); found {
+		t.Error("expected the outputs to be discarded")
+	}
+}
@@ -0,0 +1,291 @@
+package owners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// DefaultAPIURL is the GitHub API used when GITHUB_API_URL is not set
+const DefaultAPIURL = "https://api.github.com"
+
+// Client is a minimal GitHub API client used to find who is behind a change
+// and the teams they belong to
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Config holds GitHub client configuration
+type Config struct {
+	// APIURL is the GitHub API, e.g. https://github.example.com/api/v3 for
+	// GitHub Enterprise Server; it defaults to DefaultAPIURL
+	APIURL  string
+	Token   string
+	Timeout time.Duration
+}
+
+// Change is the change a run applies
+type Change struct {
+	// PullRequest is the number of the pull request of the change, or 0 for
+	// a commit pushed without one
+	PullRequest int
+	// Author opened the pull request, or pushed the commit
+	Author string
+	// Approvers approved the pull request in their latest review
+	Approvers []string
+	// Files are the paths the pull request changed, relative to the
+	// repository root; nil when unknown, so every owned file is checked
+	Files []string
+}
+
+// pullRequest is the subset of the GitHub pull request object used here
+type pullRequest struct {
+	Number int `json:"number"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	MergedAt *time.Time `json:"merged_at"`
+}
+
+// review is the subset of the GitHub pull request review object used here
+type review struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State string `json:"state"`
+}
+
+// NewClient creates a GitHub client
+func NewClient(config *Config) (*Client, error) {
+	if config == nil {
+		return nil, errors.NewConfigError("github config cannot be nil", nil)
+	}
+	if config.Token == "" {
+		return nil, errors.NewConfigError("github token is required", nil)
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(config.APIURL, "/"),
+		token:      config.Token,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// ChangeFromEnv returns the change of the running workflow: the pull request
+// of a pull_request event, or the pull request a pushed commit was merged
+// from. A commit pushed without a pull request is the change of its actor.
+func (c *Client) ChangeFromEnv(ctx context.Context) (*Change, error) {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	if repository == "" {
+		return nil, errors.NewConfigError("GITHUB_REPOSITORY is not set", nil)
+	}
+
+	pr := eventPullRequest(os.Getenv("GITHUB_EVENT_PATH"))
+	if pr == nil {
+		if sha := os.Getenv("GITHUB_SHA"); sha != "" {
+			var pulls []pullRequest
+			if err := c.get(ctx, fmt.Sprintf("/repos/%s/commits/%s/pulls", repository, url.PathEscape(sha)), &pulls); err != nil {
+				return nil, fmt.Errorf("failed to find the pull request of commit %s: %w", sha, err)
+			}
+			for i := range pulls {
+				if pulls[i].MergedAt != nil {
+					pr = &pulls[i]
+					break
+				}
+			}
+		}
+	}
+	if pr == nil {
+		return &Change{Author: os.Getenv("GITHUB_ACTOR")}, nil
+	}
+
+	change := &Change{PullRequest: pr.Number, Author: pr.User.Login, Files: []string{}}
+	if err := c.addReviews(ctx, repository, change); err != nil {
+		return nil, err
+	}
+	if err := c.addFiles(ctx, repository, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// addReviews adds the users whose latest review approved the pull request
+func (c *Client) addReviews(ctx context.Context, repository string, change *Change) error {
+	latest := make(map[string]string)
+	var order []string
+	for page := 1; ; page++ {
+		var reviews []review
+		if err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d/reviews?per_page=100&page=%d", repository, change.PullRequest, page), &reviews); err != nil {
+			return fmt.Errorf("failed to list reviews of pull request #%d: %w", change.PullRequest, err)
+		}
+		for _, r := range reviews {
+			// Comments do not withdraw an approval
+			if r.State == "COMMENTED" {
+				continue
+			}
+			if _, seen := latest[r.User.Login]; !seen {
+				order = append(order, r.User.Login)
+			}
+			latest[r.User.Login] = r.State
+		}
+		if len(reviews) < 100 {
+			break
+		}
+	}
+
+	for _, login := range order {
+		if latest[login] == "APPROVED" && login != change.Author {
+			change.Approvers = append(change.Approvers, login)
+		}
+	}
+	return nil
+}
+
+// addFiles adds the paths the pull request changed, including the previous
+// paths of renamed files
+func (c *Client) addFiles(ctx context.Context, repository string, change *Change) error {
+	for page := 1; ; page++ {
+		var files []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+		}
+		if err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=100&page=%d", repository, change.PullRequest, page), &files); err != nil {
+			return fmt.Errorf("failed to list files of pull request #%d: %w", change.PullRequest, err)
+		}
+		for _, file := range files {
+			change.Files = append(change.Files, file.Filename)
+			if file.PreviousFilename != "" {
+				change.Files = append(change.Files, file.PreviousFilename)
+			}
+		}
+		if len(files) < 100 {
+			return nil
+		}
+	}
+}
+
+// IsTeamMember reports whether a user is an active member of a team of an
+// organization
+func (c *Client) IsTeamMember(ctx context.Context, org, team, user string) (bool, error) {
+	var membership struct {
+		State string `json:"state"`
+	}
+	endpoint := fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", url.PathEscape(org), url.PathEscape(team), url.PathEscape(user))
+	status, err := c.request(ctx, endpoint, &membership)
+	if status == http.StatusNotFound {
+		// Users outside the team and teams the token cannot see alike
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check membership of %s in team %s/%s: %w", user, org, team, err)
+	}
+	return membership.State == "active", nil
+}
+
+// TeamMembers returns the logins of the members of a team of an
+// organization, including the members of its child teams
+func (c *Client) TeamMembers(ctx context.Context, org, team string) ([]string, error) {
+	var logins []string
+	for page := 1; ; page++ {
+		var members []struct {
+			Login string `json:"login"`
+		}
+		endpoint := fmt.Sprintf("/orgs/%s/teams/%s/members?per_page=100&page=%d", url.PathEscape(org), url.PathEscape(team), page)
+		if err := c.get(ctx, endpoint, &members); err != nil {
+			return nil, fmt.Errorf("failed to list members of team %s/%s: %w", org, team, err)
+		}
+		for _, member := range members {
+			logins = append(logins, member.Login)
+		}
+		if len(members) < 100 {
+			return logins, nil
+		}
+	}
+}
+
+// PublicEmail returns the public profile email of a user, or "" when the
+// user has not made one public
+func (c *Client) PublicEmail(ctx context.Context, login string) (string, error) {
+	var user struct {
+		Email *string `json:"email"`
+	}
+	if err := c.get(ctx, "/users/"+url.PathEscape(login), &user); err != nil {
+		return "", fmt.Errorf("failed to read the profile of %s: %w", login, err)
+	}
+	if user.Email == nil {
+		return "", nil
+	}
+	return *user.Email, nil
+}
+
+// get performs an authenticated GET request and decodes the JSON response
+// into out
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	_, err := c.request(ctx, path, out)
+	return err
+}
+
+// request performs an authenticated GET request, decodes the JSON response
+// into out and returns the response status, or 0 when no response arrived
+func (c *Client) request(ctx context.Context, path string, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, errors.NewNetworkError("github request failed", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp.StatusCode, errors.NewAuthError(fmt.Sprintf("github returned %d for %s; the token needs read access to pull requests and read:org for team memberships", resp.StatusCode, path), nil)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return resp.StatusCode, errors.NewRateLimitError("github rate limit exceeded", nil)
+	case resp.StatusCode >= 300:
+		return resp.StatusCode, errors.NewNetworkError(fmt.Sprintf("github returned %d for %s", resp.StatusCode, path), nil)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode github response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// eventPullRequest reads the pull request of a pull_request event payload,
+// or returns nil for other events
+func eventPullRequest(eventPath string) *pullRequest {
+	if eventPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil
+	}
+	var event struct {
+		PullRequest *pullRequest `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil
+	}
+	return event.PullRequest
+}
@@ -0,0 +1,153 @@
+package owners
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/ownership"
+)
+
+// Annotation lists the GitHub teams owning the file that declares the
+// object, e.g. x-nobl9-action/owners: "team-a, team-b". Changes to the file
+// are only applied when the pull request author or an approver belongs to
+// one of them.
+const Annotation = "x-nobl9-action/owners"
+
+// Parse parses a list of teams written as "team-a, team-b" or
+// "[team-a, team-b]". A leading @ is dropped, so "@acme/team-a" works too.
+func Parse(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+	var teams []string
+	for _, team := range strings.Split(value, ",") {
+		team = strings.TrimPrefix(strings.Trim(strings.TrimSpace(team), `"'`), "@")
+		if team != "" {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// Of returns the owner teams of a file: the owners of its ActionMeta
+// document and those annotated on its objects, without duplicates
+func Of(metaOwners []string, objects []manifest.Object) []string {
+	var teams []string
+	seen := make(map[string]bool)
+	add := func(team string) {
+		team = strings.TrimPrefix(strings.TrimSpace(team), "@")
+		if team != "" && !seen[team] {
+			seen[team] = true
+			teams = append(teams, team)
+		}
+	}
+
+	for _, team := range metaOwners {
+		add(team)
+	}
+	for _, obj := range objects {
+		for _, team := range Parse(ownership.AnnotationsOf(obj)[Annotation]) {
+			add(team)
+		}
+	}
+	return teams
+}
+
+// MembershipChecker reports whether a user belongs to a team of an
+// organization; *Client implements it
+type MembershipChecker interface {
+	IsTeamMember(ctx context.Context, org, team, user string) (bool, error)
+}
+
+// Verifier checks that the people behind a change belong to the owner teams
+// of the files it changes
+type Verifier struct {
+	members MembershipChecker
+	// org owns teams named without an organization
+	org    string
+	change *Change
+	// cache holds memberships already checked, by org/team/user
+	cache map[string]bool
+}
+
+// NewVerifier creates a verifier of a change. Teams named without an
+// organization, such as "team-a", belong to org.
+func NewVerifier(members MembershipChecker, org string, change *Change) *Verifier {
+	return &Verifier{members: members, org: org, change: change, cache: make(map[string]bool)}
+}
+
+// Changed reports whether the change touched a file. path is relative to
+// the repository root. Without a list of changed files every file counts as
+// changed.
+func (v *Verifier) Changed(path string) bool {
+	if v.change.Files == nil {
+		return true
+	}
+	path = filepath.ToSlash(path)
+	for _, file := range v.change.Files {
+		if file == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a policy error unless the author or an approver of the
+// change belongs to one of the teams
+func (v *Verifier) Check(ctx context.Context, teams []string) error {
+	users := append([]string{v.change.Author}, v.change.Approvers...)
+	for _, user := range users {
+		if user == "" {
+			continue
+		}
+		for _, team := range teams {
+			member, err := v.isMember(ctx, team, user)
+			if err != nil {
+				return err
+			}
+			if member {
+				return nil
+			}
+		}
+	}
+
+	return errors.NewPolicyErrorWithDetails(
+		fmt.Sprintf("the file is owned by %s, and neither the author of %s nor an approver belongs to one of these teams", strings.Join(teams, ", "), v.describe()),
+		nil,
+		map[string]interface{}{
+			"owners":       strings.Join(teams, ","),
+			"author":       v.change.Author,
+			"approvers":    strings.Join(v.change.Approvers, ","),
+			"pull_request": v.change.PullRequest,
+		},
+	)
+}
+
+// isMember checks a team membership once per run
+func (v *Verifier) isMember(ctx context.Context, team, user string) (bool, error) {
+	org, slug := v.org, team
+	if owner, name, found := strings.Cut(team, "/"); found {
+		org, slug = owner, name
+	}
+	key := org + "/" + slug + "/" + user
+	if member, found := v.cache[key]; found {
+		return member, nil
+	}
+
+	member, err := v.members.IsTeamMember(ctx, org, slug, user)
+	if err != nil {
+		return false, err
+	}
+	v.cache[key] = member
+	return member, nil
+}
+
+// describe names the change in errors, e.g. "pull request #42 (octocat)"
+func (v *Verifier) describe() string {
+	if v.change.PullRequest == 0 {
+		return fmt.Sprintf("the push (%s)", v.change.Author)
+	}
+	return fmt.Sprintf("pull request #%d (%s)", v.change.PullRequest, v.change.Author)
+}
@@ -0,0 +1,187 @@
+package owners
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const project = `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+  annotations:
+    x-nobl9-action/owners: "[team-payments, '@acme/sre']"
+spec:
+  description: Payments team
+`
+
+func TestParse(t *testing.T) {
+	for value, expected := range map[string]string{
+		"team-a":                 "team-a",
+		"team-a, team-b":         "team-a,team-b",
+		"[team-a, '@acme/team']": "team-a,acme/team",
+		"":                       "",
+	} {
+		if teams := strings.Join(Parse(value), ","); teams != expected {
+			t.Errorf("%q: expected %q, got %q", value, expected, teams)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(project))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if teams := strings.Join(Of([]string{"sre", "team-payments"}, objects), ","); teams != "sre,team-payments,acme/sre" {
+		t.Errorf("unexpected owners: %s", teams)
+	}
+}
+
+// members maps org/team to the users in it
+type members map[string][]string
+
+func (m members) IsTeamMember(_ context.Context, org, team, user string) (bool, error) {
+	for _, member := range m[org+"/"+team] {
+		if member == user {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestVerifier(t *testing.T) {
+	teams := members{"acme/payments": {"alice"}, "platform/sre": {"carol"}}
+	change := &Change{PullRequest: 42, Author: "bob", Approvers: []string{"alice"}, Files: []string{"nobl9/payments.yaml"}}
+	verifier := NewVerifier(teams, "acme", change)
+
+	if !verifier.Changed(filepath.Join("nobl9", "payments.yaml")) || verifier.Changed("nobl9/billing.yaml") {
+		t.Errorf("expected only the changed file to be checked")
+	}
+	if err := verifier.Check(context.Background(), []string{"payments"}); err != nil {
+		t.Errorf("expected the approver to be an owner, got %v", err)
+	}
+	if err := verifier.Check(context.Background(), []string{"platform/sre"}); err == nil || !strings.Contains(err.Error(), "pull request #42 (bob)") {
+		t.Errorf("expected a policy error, got %v", err)
+	}
+
+	// Without the changed files, every file counts as changed
+	if !NewVerifier(teams, "acme", &Change{Author: "carol"}).Changed("any.yaml") {
+		t.Errorf("expected every file to be changed")
+	}
+}
+
+func TestChangeFromEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/acme/nobl9/commits/abc123/pulls":
+			fmt.Fprint(w, `[{"number": 7, "user": {"login": "bob"}, "merged_at": null}, {"number": 42, "user": {"login": "bob"}, "merged_at": "2026-01-02T03:04:05Z"}]`)
+		case "/repos/acme/nobl9/pulls/42/reviews":
+			fmt.Fprint(w, `[{"user": {"login": "alice"}, "state": "APPROVED"}, {"user": {"login": "carol"}, "state": "APPROVED"},
+				{"user": {"login": "carol"}, "state": "CHANGES_REQUESTED"}, {"user": {"login": "alice"}, "state": "COMMENTED"}]`)
+		case "/repos/acme/nobl9/pulls/42/files":
+			fmt.Fprint(w, `[{"filename": "nobl9/payments.yaml"}, {"filename": "nobl9/billing.yaml", "previous_filename": "billing.yaml"}]`)
+		case "/orgs/acme/teams/payments/memberships/alice":
+			fmt.Fprint(w, `{"state": "active"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_REPOSITORY", "acme/nobl9")
+	t.Setenv("GITHUB_SHA", "abc123")
+	t.Setenv("GITHUB_EVENT_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	client, err := NewClient(&Config{APIURL: server.URL, Token: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	change, err := client.ChangeFromEnv(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if change.PullRequest != 42 || change.Author != "bob" || strings.Join(change.Approvers, ",") != "alice" {
+		t.Errorf("expected the merged pull request approved by alice, got %+v", change)
+	}
+	if strings.Join(change.Files, ",") != "nobl9/payments.yaml,nobl9/billing.yaml,billing.yaml" {
+		t.Errorf("unexpected changed files: %v", change.Files)
+	}
+
+	for user, expected := range map[string]bool{"alice": true, "bob": false} {
+		member, err := client.IsTeamMember(context.Background(), "acme", "payments", user)
+		if err != nil || member != expected {
+			t.Errorf("%s: expected membership %t, got %t, %v", user, expected, member, err)
+		}
+	}
+
+	// A pull_request event names its pull request
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request": {"number": 42, "user": {"login": "bob"}}}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	t.Setenv("GITHUB_SHA", "")
+	if change, err := client.ChangeFromEnv(context.Background()); err != nil || change.PullRequest != 42 {
+		t.Errorf("expected the event's pull request, got %+v, %v", change, err)
+	}
+}
+
+func TestTeamExpander(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/acme/teams/payments/members":
+			fmt.Fprint(w, `[{"login": "Octocat"}, {"login": "alice"}, {"login": "bob"}]`)
+		case "/users/alice":
+			fmt.Fprint(w, `{"login": "alice", "email": "Alice@Example.com"}`)
+		case "/users/bob":
+			fmt.Fprint(w, `{"login": "bob", "email": null}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(&Config{APIURL: server.URL, Token: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	emails, err := ParseLoginEmails("octocat=octo@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expander := NewTeamExpander(client, "acme", emails)
+
+	// Mapped logins win over the profile, and bob has no public email
+	for _, name := range []string{"acme/payments", "payments"} {
+		members, err := expander.GetGroupMemberEmails(context.Background(), name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if strings.Join(members, ",") != "octo@example.com,alice@example.com" {
+			t.Errorf("%s: unexpected members %v", name, members)
+		}
+	}
+
+	if _, err := expander.GetGroupMemberEmails(context.Background(), "acme/unknown"); err == nil {
+		t.Error("expected an error for an unknown team")
+	}
+	if !IsTeamReference(" github-team:acme/payments") || TeamName("github-team: acme/payments ") != "acme/payments" {
+		t.Error("expected the team reference to be recognized")
+	}
+	if _, err := ParseLoginEmails("octocat"); err == nil {
+		t.Error("expected an error for a login without an email")
+	}
+}
@@ -0,0 +1,109 @@
+package owners
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TeamPrefix marks a role binding user as a reference to a GitHub team
+// (e.g. "github-team:acme/payments") instead of a single email address
+const TeamPrefix = "github-team:"
+
+// IsTeamReference returns true if the role binding user references a GitHub
+// team
+func IsTeamReference(user string) bool {
+	return strings.HasPrefix(strings.TrimSpace(user), TeamPrefix)
+}
+
+// TeamName extracts the org/team name from a team reference
+func TeamName(user string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(user), TeamPrefix))
+}
+
+// TeamLister lists the members of GitHub teams and reads their profiles
+type TeamLister interface {
+	TeamMembers(ctx context.Context, org, team string) ([]string, error)
+	PublicEmail(ctx context.Context, login string) (string, error)
+}
+
+// TeamExpander expands GitHub teams into the emails of their members. A
+// member's email comes from the login mapping, or else from the public
+// email of their profile; members with neither are left out.
+type TeamExpander struct {
+	teams  TeamLister
+	org    string
+	emails map[string]string
+}
+
+// NewTeamExpander creates a team expander. Teams named without an
+// organization belong to org; emails maps GitHub logins to emails.
+func NewTeamExpander(teams TeamLister, org string, emails map[string]string) *TeamExpander {
+	normalized := make(map[string]string, len(emails))
+	for login, email := range emails {
+		normalized[strings.ToLower(login)] = email
+	}
+	return &TeamExpander{teams: teams, org: org, emails: normalized}
+}
+
+// ParseLoginEmails parses a comma separated list of login=email pairs,
+// e.g. octocat=cat@example.com,hubot=bot@example.com
+func ParseLoginEmails(value string) (map[string]string, error) {
+	emails := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		login, email, found := strings.Cut(pair, "=")
+		login, email = strings.TrimSpace(login), strings.TrimSpace(email)
+		if !found || login == "" || !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("'%s' is not a login=email pair", pair)
+		}
+		emails[login] = email
+	}
+	return emails, nil
+}
+
+// GetGroupMemberEmails returns the emails of the members of a GitHub team
+// named org/team, or team for a team of the expander's organization
+func (e *TeamExpander) GetGroupMemberEmails(ctx context.Context, name string) ([]string, error) {
+	org, team, found := strings.Cut(strings.TrimPrefix(name, "@"), "/")
+	if !found {
+		org, team = e.org, org
+	}
+	if org == "" || team == "" {
+		return nil, fmt.Errorf("team %s must be named org/team", name)
+	}
+
+	logins, err := e.teams.TeamMembers(ctx, org, team)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(logins))
+	var unmapped []string
+	for _, login := range logins {
+		email, mapped := e.emails[strings.ToLower(login)]
+		if !mapped {
+			if email, err = e.teams.PublicEmail(ctx, login); err != nil {
+				return nil, err
+			}
+		}
+		if email == "" {
+			unmapped = append(unmapped, login)
+			continue
+		}
+		emails = append(emails, strings.ToLower(email))
+	}
+
+	if len(unmapped) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"team":   org + "/" + team,
+			"logins": strings.Join(unmapped, ","),
+		}).Warn("Team members without a mapped or public email are left out")
+	}
+	return emails, nil
+}
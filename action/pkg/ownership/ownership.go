@@ -0,0 +1,235 @@
+package ownership
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha"
+	v1alphaAgent "github.com/nobl9/nobl9-go/manifest/v1alpha/agent"
+	v1alphaAlertMethod "github.com/nobl9/nobl9-go/manifest/v1alpha/alertmethod"
+	v1alphaAlertPolicy "github.com/nobl9/nobl9-go/manifest/v1alpha/alertpolicy"
+	v1alphaDirect "github.com/nobl9/nobl9-go/manifest/v1alpha/direct"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaService "github.com/nobl9/nobl9-go/manifest/v1alpha/service"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+)
+
+// DefaultLabel marks objects applied by the action
+const DefaultLabel = "managed-by=nobl9-github-action"
+
+// Annotations tracing an applied object back to the change that applied it
+const (
+	// RepositoryAnnotation is the repository declaring the object
+	RepositoryAnnotation = "nobl9-action/repository"
+	// CommitAnnotation is the commit SHA the object was last applied from
+	CommitAnnotation = "nobl9-action/commit"
+	// SourceAnnotation is the file declaring the object
+	SourceAnnotation = "nobl9-action/source"
+)
+
+// Marker sets the ownership label and trace annotations on objects before
+// they are applied. Role bindings and other kinds without metadata labels
+// or annotations are left as they are.
+type Marker struct {
+	// LabelKey and LabelValue are the ownership label; an empty key sets no
+	// label
+	LabelKey   string
+	LabelValue string
+	// Annotate sets the trace annotations of Repository and Commit
+	Annotate   bool
+	Repository string
+	Commit     string
+	// Extra are further annotations set on every object with annotations,
+	// such as the audit annotations; empty values are left out
+	Extra map[string]string
+}
+
+// ParseLabel parses a key=value ownership label. The key must be a valid
+// Nobl9 label key: lower case letters, digits, - and _.
+func ParseLabel(spec string) (key, value string, err error) {
+	key, value, found := strings.Cut(strings.TrimSpace(spec), "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !found || key == "" || value == "" {
+		return "", "", fmt.Errorf("label '%s' must be key=value", spec)
+	}
+	if err := v1alpha.LabelsValidationRules().Validate(v1alpha.Labels{key: {value}}); err != nil {
+		return "", "", fmt.Errorf("invalid label '%s': %w", spec, err)
+	}
+	return key, value, nil
+}
+
+// New creates a marker setting the key=value label, unless it is empty, and
+// the trace annotations when annotate is set. It returns nil when it would
+// mark nothing.
+func New(label string, annotate bool, repository, commit string) (*Marker, error) {
+	marker := &Marker{Annotate: annotate, Repository: repository, Commit: commit}
+	if strings.TrimSpace(label) != "" {
+		key, value, err := ParseLabel(label)
+		if err != nil {
+			return nil, err
+		}
+		marker.LabelKey, marker.LabelValue = key, value
+	}
+	if marker.LabelKey == "" && !annotate {
+		return nil, nil
+	}
+	return marker, nil
+}
+
+// Mark returns copies of the objects with the ownership label and the trace
+// annotations naming the source file set. Labels and annotations declared
+// with the same keys are replaced.
+func (m *Marker) Mark(objects []manifest.Object, source string) []manifest.Object {
+	marked := make([]manifest.Object, 0, len(objects))
+	for _, obj := range objects {
+		marked = append(marked, m.mark(obj, source))
+	}
+	return marked
+}
+
+// mark returns a copy of a single object with the marker set
+func (m *Marker) mark(obj manifest.Object, source string) manifest.Object {
+	switch v := obj.(type) {
+	case v1alphaProject.Project:
+		v.Metadata.Labels = m.labels(v.Metadata.Labels)
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaService.Service:
+		v.Metadata.Labels = m.labels(v.Metadata.Labels)
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaSLO.SLO:
+		v.Metadata.Labels = m.labels(v.Metadata.Labels)
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaAlertPolicy.AlertPolicy:
+		v.Metadata.Labels = m.labels(v.Metadata.Labels)
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaAgent.Agent:
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaDirect.Direct:
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	case v1alphaAlertMethod.AlertMethod:
+		v.Metadata.Annotations = m.annotations(v.Metadata.Annotations, source)
+		return v
+	}
+	return obj
+}
+
+// labels returns a copy of the labels with the ownership label set
+func (m *Marker) labels(labels v1alpha.Labels) v1alpha.Labels {
+	if m.LabelKey == "" {
+		return labels
+	}
+	marked := make(v1alpha.Labels, len(labels)+1)
+	for key, values := range labels {
+		marked[key] = values
+	}
+	marked[m.LabelKey] = []string{m.LabelValue}
+	return marked
+}
+
+// annotations returns a copy of the annotations with the trace and extra
+// annotations set; empty values are left out
+func (m *Marker) annotations(annotations v1alpha.MetadataAnnotations, source string) v1alpha.MetadataAnnotations {
+	if !m.Annotate && len(m.Extra) == 0 {
+		return annotations
+	}
+	marked := make(v1alpha.MetadataAnnotations, len(annotations)+len(m.Extra)+3)
+	for key, value := range annotations {
+		marked[key] = value
+	}
+	for key, value := range m.Extra {
+		if value != "" {
+			marked[key] = value
+		}
+	}
+	if !m.Annotate {
+		return marked
+	}
+	for key, value := range map[string]string{
+		RepositoryAnnotation: m.Repository,
+		CommitAnnotation:     m.Commit,
+		SourceAnnotation:     source,
+	} {
+		if value != "" {
+			marked[key] = value
+		}
+	}
+	return marked
+}
+
+// Foreign returns why a live object belongs to someone else, or "" when it
+// may be managed by this marker's repository: the object carries the
+// ownership label with another value, or the repository annotation of
+// another repository. Objects without either are not foreign.
+func (m *Marker) Foreign(obj manifest.Object) string {
+	labels, annotations := metadataOf(obj)
+	if m.LabelKey != "" {
+		if values, found := labels[m.LabelKey]; found && !contains(values, m.LabelValue) {
+			return fmt.Sprintf("labeled %s=%s", m.LabelKey, strings.Join(values, ","))
+		}
+	}
+	if repository := annotations[RepositoryAnnotation]; repository != "" && m.Repository != "" && repository != m.Repository {
+		return fmt.Sprintf("managed by repository %s", repository)
+	}
+	return ""
+}
+
+// IgnoreFields returns the [kind:]path drift rules of the fields the marker
+// sets, which manifests do not declare
+func (m *Marker) IgnoreFields() []string {
+	var fields []string
+	if m.LabelKey != "" {
+		fields = append(fields, "metadata.labels."+m.LabelKey)
+	}
+	if m.Annotate {
+		for _, key := range []string{RepositoryAnnotation, CommitAnnotation, SourceAnnotation} {
+			fields = append(fields, "metadata.annotations."+key)
+		}
+	}
+	return fields
+}
+
+// AnnotationsOf returns the annotations of an object, or nil if its kind has
+// none
+func AnnotationsOf(obj manifest.Object) v1alpha.MetadataAnnotations {
+	_, annotations := metadataOf(obj)
+	return annotations
+}
+
+// metadataOf returns the labels and annotations of an object, if its kind
+// has them
+func metadataOf(obj manifest.Object) (v1alpha.Labels, v1alpha.MetadataAnnotations) {
+	switch v := obj.(type) {
+	case v1alphaProject.Project:
+		return v.Metadata.Labels, v.Metadata.Annotations
+	case v1alphaService.Service:
+		return v.Metadata.Labels, v.Metadata.Annotations
+	case v1alphaSLO.SLO:
+		return v.Metadata.Labels, v.Metadata.Annotations
+	case v1alphaAlertPolicy.AlertPolicy:
+		return v.Metadata.Labels, v.Metadata.Annotations
+	case v1alphaAgent.Agent:
+		return nil, v.Metadata.Annotations
+	case v1alphaDirect.Direct:
+		return nil, v.Metadata.Annotations
+	case v1alphaAlertMethod.AlertMethod:
+		return nil, v.Metadata.Annotations
+	}
+	return nil, nil
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
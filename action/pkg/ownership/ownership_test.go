@@ -0,0 +1,135 @@
+package ownership
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const objects = `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+    labels:
+      team: [payments]
+    annotations:
+      owner: payments-team
+  spec:
+    description: Payments team
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-alice
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+`
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	decoded, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return decoded
+}
+
+func TestMark(t *testing.T) {
+	marker, err := New(DefaultLabel, true, "acme/nobl9-config", "0a1b2c3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	original := decode(t, objects)
+	marked := marker.Mark(original, "projects/payments.yaml")
+
+	project := marked[0].(v1alphaProject.Project)
+	if values := project.Metadata.Labels["managed-by"]; len(values) != 1 || values[0] != "nobl9-github-action" {
+		t.Errorf("expected ownership label, got %v", project.Metadata.Labels)
+	}
+	if values := project.Metadata.Labels["team"]; len(values) != 1 || values[0] != "payments" {
+		t.Errorf("expected declared labels to be kept, got %v", project.Metadata.Labels)
+	}
+	expected := map[string]string{
+		"owner":              "payments-team",
+		RepositoryAnnotation: "acme/nobl9-config",
+		CommitAnnotation:     "0a1b2c3",
+		SourceAnnotation:     "projects/payments.yaml",
+	}
+	for key, value := range expected {
+		if project.Metadata.Annotations[key] != value {
+			t.Errorf("expected annotation %s=%s, got %v", key, value, project.Metadata.Annotations)
+		}
+	}
+	if err := project.Validate(); err != nil {
+		t.Errorf("expected marked project to be valid: %v", err)
+	}
+
+	if _, ok := original[0].(v1alphaProject.Project).Metadata.Labels["managed-by"]; ok {
+		t.Error("expected the original project not to be modified")
+	}
+	if marked[1].(v1alphaRoleBinding.RoleBinding) != original[1].(v1alphaRoleBinding.RoleBinding) {
+		t.Error("expected the role binding to be left as it is")
+	}
+}
+
+func TestNew(t *testing.T) {
+	marker, err := New("", false, "acme/nobl9-config", "0a1b2c3")
+	if err != nil || marker != nil {
+		t.Errorf("expected no marker when nothing is marked, got %+v, %v", marker, err)
+	}
+
+	for _, label := range []string{"managed-by", "Managed-By=action", "managed by=action"} {
+		if _, err := New(label, false, "", ""); err == nil {
+			t.Errorf("expected label %q to be rejected", label)
+		}
+	}
+
+	marker, err = New("owner = platform-team", false, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marker.LabelKey != "owner" || marker.LabelValue != "platform-team" {
+		t.Errorf("expected owner=platform-team, got %+v", marker)
+	}
+	if fields := marker.IgnoreFields(); strings.Join(fields, ",") != "metadata.labels.owner" {
+		t.Errorf("expected the label to be ignored, got %v", fields)
+	}
+}
+
+func TestForeign(t *testing.T) {
+	marker, err := New(DefaultLabel, true, "acme/nobl9-config", "0a1b2c3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	project := decode(t, objects)[0]
+
+	if reason := marker.Foreign(project); reason != "" {
+		t.Errorf("expected an unmarked project not to be foreign, got %q", reason)
+	}
+	if reason := marker.Foreign(marker.Mark([]manifest.Object{project}, "payments.yaml")[0]); reason != "" {
+		t.Errorf("expected a project marked by the repository not to be foreign, got %q", reason)
+	}
+
+	other, err := New("managed-by=terraform", true, "acme/other", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reason := marker.Foreign(other.Mark([]manifest.Object{project}, "payments.yaml")[0]); reason != "labeled managed-by=terraform" {
+		t.Errorf("expected a project labeled by another tool to be foreign, got %q", reason)
+	}
+
+	sibling, err := New(DefaultLabel, true, "acme/other", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reason := marker.Foreign(sibling.Mark([]manifest.Object{project}, "payments.yaml")[0]); reason != "managed by repository acme/other" {
+		t.Errorf("expected a project of another repository to be foreign, got %q", reason)
+	}
+}
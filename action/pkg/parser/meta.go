@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetaKind is the kind of the document configuring how the action handles
+// the file it is in. It is not a Nobl9 object and is never applied.
+const MetaKind = "ActionMeta"
+
+// MetaAPIVersion is the API version of ActionMeta documents
+const MetaAPIVersion = "nobl9-action/v1"
+
+// DefaultTicketPattern matches issue keys such as PAY-123
+const DefaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// Meta configures per-file behavior of the action
+type Meta struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Spec       MetaSpec `yaml:"spec"`
+}
+
+// MetaSpec holds the per-file settings
+type MetaSpec struct {
+	// Organization the file is applied to; runs against other
+	// organizations skip the file
+	Organization string `yaml:"organization"`
+	// SkipPrune keeps the file's projects out of pruning, so removing them
+	// from the repository never deletes them from Nobl9
+	SkipPrune bool `yaml:"skipPrune"`
+	// Owner is the team owning the file, reported with its results
+	Owner string `yaml:"owner"`
+	// Owners are the GitHub teams allowed to change the file: changes are
+	// only applied when the pull request author or an approver belongs to
+	// one of them
+	Owners []string `yaml:"owners"`
+	// RequireTicket fails the file unless the commit or pull request
+	// references a ticket matching TicketPattern
+	RequireTicket bool   `yaml:"requireTicket"`
+	TicketPattern string `yaml:"ticketPattern"`
+	// Environments specialize the file for environments selected with
+	// --environment, keyed by environment name
+	Environments map[string]Environment `yaml:"environments"`
+}
+
+// ExtractMeta returns the ActionMeta document of a file and the content
+// without it, ready to be decoded as Nobl9 objects. Content without an
+// ActionMeta document is returned unchanged with a nil Meta. A file may hold
+// at most one ActionMeta document.
+func ExtractMeta(content []byte) (*Meta, []byte, error) {
+	if !bytes.Contains(content, []byte(MetaKind)) {
+		return nil, content, nil
+	}
+
+	var meta *Meta
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+
+		if !isMetaDocument(&document) {
+			documents = append(documents, &document)
+			continue
+		}
+		if meta != nil {
+			return nil, nil, fmt.Errorf("line %d: only one %s document is allowed per file", document.Content[0].Line, MetaKind)
+		}
+
+		parsed, err := decodeMeta(&document)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta = parsed
+	}
+
+	if meta == nil {
+		return nil, content, nil
+	}
+
+	// The remaining documents are encoded again; comments and layout do not
+	// matter to the decoder
+	var remaining bytes.Buffer
+	encoder := yaml.NewEncoder(&remaining)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return meta, remaining.Bytes(), nil
+}
+
+// TicketRegexp returns the pattern tickets must match
+func (m *Meta) TicketRegexp() (*regexp.Regexp, error) {
+	pattern := m.Spec.TicketPattern
+	if pattern == "" {
+		pattern = DefaultTicketPattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// isMetaDocument reports whether a document is an ActionMeta document
+func isMetaDocument(document *yaml.Node) bool {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "kind" {
+			return mapping.Content[i+1].Value == MetaKind
+		}
+	}
+	return false
+}
+
+// decodeMeta decodes and checks an ActionMeta document
+func decodeMeta(document *yaml.Node) (*Meta, error) {
+	line := document.Content[0].Line
+
+	// Decode through YAML so unknown fields are reported
+	data, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: invalid %s: %w", line, MetaKind, err)
+	}
+
+	meta := &Meta{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(meta); err != nil {
+		return nil, fmt.Errorf("line %d: invalid %s: %w", line, MetaKind, err)
+	}
+
+	if meta.APIVersion != MetaAPIVersion {
+		return nil, fmt.Errorf("line %d: %s must have apiVersion %s, got '%s'", line, MetaKind, MetaAPIVersion, meta.APIVersion)
+	}
+	if _, err := meta.TicketRegexp(); err != nil {
+		return nil, fmt.Errorf("line %d: invalid %s ticketPattern: %w", line, MetaKind, err)
+	}
+	if err := validateEnvironments(meta.Spec.Environments); err != nil {
+		return nil, fmt.Errorf("line %d: invalid %s environments: %w", line, MetaKind, err)
+	}
+	return meta, nil
+}
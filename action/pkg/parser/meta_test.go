@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const metaManifest = `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  organization: acme-prod
+  skipPrune: true
+  owner: team-payments
+  owners: [team-payments]
+  requireTicket: true
+  ticketPattern: 'PAY-[0-9]+'
+---
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+`
+
+func TestExtractMeta(t *testing.T) {
+	meta, content, err := ExtractMeta([]byte(metaManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta == nil {
+		t.Fatal("expected an ActionMeta document")
+	}
+
+	expected := MetaSpec{Organization: "acme-prod", SkipPrune: true, Owner: "team-payments", Owners: []string{"team-payments"}, RequireTicket: true, TicketPattern: "PAY-[0-9]+"}
+	if !reflect.DeepEqual(meta.Spec, expected) {
+		t.Errorf("expected %+v, got %+v", expected, meta.Spec)
+	}
+
+	objects, err := sdk.DecodeObjects(content)
+	if err != nil {
+		t.Fatalf("expected the remaining content to decode, got %v", err)
+	}
+	if len(objects) != 1 || objects[0].GetName() != "payments" {
+		t.Errorf("unexpected objects: %v", objects)
+	}
+
+	pattern, err := meta.TicketRegexp()
+	if err != nil || pattern.FindString("Fix PAY-42 checkout") != "PAY-42" {
+		t.Errorf("unexpected ticket pattern %v: %v", pattern, err)
+	}
+}
+
+func TestExtractMetaWithoutMeta(t *testing.T) {
+	content := []byte("apiVersion: n9/v1alpha\nkind: Project\nmetadata:\n  name: payments\n")
+	meta, remaining, err := ExtractMeta(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta != nil || string(remaining) != string(content) {
+		t.Errorf("expected the content unchanged, got %v %q", meta, remaining)
+	}
+}
+
+func TestExtractMetaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{
+			name:    "unknown field",
+			content: "apiVersion: nobl9-action/v1\nkind: ActionMeta\nspec:\n  skip-prune: true\n",
+			message: "field skip-prune not found",
+		},
+		{
+			name:    "wrong api version",
+			content: "apiVersion: n9/v1alpha\nkind: ActionMeta\n",
+			message: "must have apiVersion nobl9-action/v1",
+		},
+		{
+			name:    "invalid ticket pattern",
+			content: "apiVersion: nobl9-action/v1\nkind: ActionMeta\nspec:\n  ticketPattern: '['\n",
+			message: "invalid ActionMeta ticketPattern",
+		},
+		{
+			name:    "two documents",
+			content: "apiVersion: nobl9-action/v1\nkind: ActionMeta\n---\napiVersion: nobl9-action/v1\nkind: ActionMeta\n",
+			message: "line 4: only one ActionMeta document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ExtractMeta([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment specializes the objects of a file for one environment, such
+// as dev, staging or prod
+type Environment struct {
+	// Projects renames projects from their base name to the name they have
+	// in the environment, along with the objects referencing them
+	Projects map[string]string `yaml:"projects"`
+	// Labels are added to every object that has labels: projects, services,
+	// SLOs and alert policies
+	Labels map[string][]string `yaml:"labels"`
+	// Patches change or remove single objects
+	Patches []Patch `yaml:"patches"`
+}
+
+// Patch changes the object it targets like a JSON merge patch: mappings are
+// merged, null removes a field and any other value replaces it
+type Patch struct {
+	Target PatchTarget `yaml:"target"`
+	Patch  yaml.Node   `yaml:"patch"`
+	// Remove leaves the object out of the environment
+	Remove bool `yaml:"remove"`
+}
+
+// PatchTarget selects an object by its base kind, name and project
+type PatchTarget struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+	// Project is only needed when objects of several projects share a name
+	Project string `yaml:"project"`
+}
+
+// labeledKinds are the kinds whose metadata holds labels
+var labeledKinds = map[string]bool{
+	"Project":     true,
+	"Service":     true,
+	"SLO":         true,
+	"AlertPolicy": true,
+}
+
+// Environments returns the names of the environments of the file, sorted
+func (m *Meta) Environments() []string {
+	if m == nil {
+		return nil
+	}
+	names := make([]string, 0, len(m.Spec.Environments))
+	for name := range m.Spec.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Overlay returns content specialized for an environment. Patches are
+// applied first and target objects by their base names; projects are then
+// renamed and labels added. Content is returned unchanged when the file has
+// no overlay for the environment.
+func (m *Meta) Overlay(content []byte, environment string) ([]byte, error) {
+	if m == nil || environment == "" {
+		return content, nil
+	}
+	overlay, found := m.Spec.Environments[environment]
+	if !found {
+		return content, nil
+	}
+
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		documents = append(documents, &document)
+	}
+
+	documents, err := applyPatches(documents, overlay.Patches)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s: %w", environment, err)
+	}
+	for _, document := range documents {
+		object := documentRoot(document)
+		if object == nil {
+			continue
+		}
+		renameProjects(object, overlay.Projects)
+		addLabels(object, overlay.Labels)
+	}
+
+	var overlaid bytes.Buffer
+	encoder := yaml.NewEncoder(&overlaid)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return overlaid.Bytes(), nil
+}
+
+// validateEnvironments checks the environments of an ActionMeta document
+func validateEnvironments(environments map[string]Environment) error {
+	for name, environment := range environments {
+		if name == "" {
+			return fmt.Errorf("environment names cannot be empty")
+		}
+		for from, to := range environment.Projects {
+			if from == "" || to == "" {
+				return fmt.Errorf("environment %s: project renames need a base and a new name", name)
+			}
+		}
+		for i, patch := range environment.Patches {
+			if patch.Target.Kind == "" || patch.Target.Name == "" {
+				return fmt.Errorf("environment %s: patch %d needs a target kind and name", name, i+1)
+			}
+			if patch.Remove && !patch.Patch.IsZero() {
+				return fmt.Errorf("environment %s: patch %d cannot both remove and patch its target", name, i+1)
+			}
+			if !patch.Remove && patch.Patch.Kind != yaml.MappingNode {
+				return fmt.Errorf("environment %s: patch %d must be a mapping of the fields to change", name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// applyPatches applies patches to the documents they target. A patch that
+// targets no object is an error, so typos do not silently leave an
+// environment unchanged.
+func applyPatches(documents []*yaml.Node, patches []Patch) ([]*yaml.Node, error) {
+	removed := make(map[*yaml.Node]bool)
+	for i, patch := range patches {
+		matched := false
+		for _, document := range documents {
+			object := documentRoot(document)
+			if object == nil || removed[document] || !patch.Target.matches(object) {
+				continue
+			}
+			matched = true
+			if patch.Remove {
+				removed[document] = true
+				continue
+			}
+			mergePatch(object, &patch.Patch)
+		}
+		if !matched {
+			return nil, fmt.Errorf("patch %d targets %s %s, which the file does not declare", i+1, patch.Target.Kind, patch.Target.Name)
+		}
+	}
+
+	kept := documents[:0]
+	for _, document := range documents {
+		if !removed[document] {
+			kept = append(kept, document)
+		}
+	}
+	return kept, nil
+}
+
+// matches reports whether an object is the target
+func (t PatchTarget) matches(object *yaml.Node) bool {
+	if yamlnode.ScalarValue(yamlnode.MappingValue(object, "kind")) != t.Kind {
+		return false
+	}
+	metadata := yamlnode.MappingValue(object, "metadata")
+	if yamlnode.ScalarValue(yamlnode.MappingValue(metadata, "name")) != t.Name {
+		return false
+	}
+	return t.Project == "" || yamlnode.ScalarValue(yamlnode.MappingValue(metadata, "project")) == t.Project
+}
+
+// mergePatch merges patch into target, a mapping node
+func mergePatch(target, patch *yaml.Node) {
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i], patch.Content[i+1]
+		index := mappingIndex(target, key.Value)
+
+		if value.Tag == "!!null" {
+			if index >= 0 {
+				target.Content = append(target.Content[:index], target.Content[index+2:]...)
+			}
+			continue
+		}
+		if value.Kind == yaml.MappingNode {
+			if index < 0 || target.Content[index+1].Kind != yaml.MappingNode {
+				merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				mergePatch(merged, value)
+				setMappingValue(target, key.Value, merged)
+				continue
+			}
+			mergePatch(target.Content[index+1], value)
+			continue
+		}
+		setMappingValue(target, key.Value, copyNode(value))
+	}
+}
+
+// copyNode returns a deep copy of a node, so that patching several objects,
+// or the same file for several environments, never shares nodes
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+// renameProjects renames projects and the references to them: the project
+// of project-scoped objects and the projectRef of role bindings
+func renameProjects(object *yaml.Node, projects map[string]string) {
+	if len(projects) == 0 {
+		return
+	}
+	rename := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return
+		}
+		if to, found := projects[node.Value]; found {
+			node.Value = to
+		}
+	}
+
+	metadata := yamlnode.MappingValue(object, "metadata")
+	if yamlnode.ScalarValue(yamlnode.MappingValue(object, "kind")) == "Project" {
+		rename(yamlnode.MappingValue(metadata, "name"))
+	}
+	rename(yamlnode.MappingValue(metadata, "project"))
+	rename(yamlnode.MappingValue(yamlnode.MappingValue(object, "spec"), "projectRef"))
+}
+
+// addLabels adds labels to objects of kinds that have labels. Values are
+// appended to existing values of the same label.
+func addLabels(object *yaml.Node, labels map[string][]string) {
+	if len(labels) == 0 || !labeledKinds[yamlnode.ScalarValue(yamlnode.MappingValue(object, "kind"))] {
+		return
+	}
+	metadata := yamlnode.MappingValue(object, "metadata")
+	if metadata == nil || metadata.Kind != yaml.MappingNode {
+		return
+	}
+	existing := yamlnode.MappingValue(metadata, "labels")
+	if existing == nil || existing.Kind != yaml.MappingNode {
+		existing = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(metadata, "labels", existing)
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := yamlnode.MappingValue(existing, key)
+		if values == nil || values.Kind != yaml.SequenceNode {
+			values = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(existing, key, values)
+		}
+		for _, value := range labels[key] {
+			if !sequenceContains(values, value) {
+				values.Content = append(values.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+			}
+		}
+	}
+}
+
+// documentRoot returns the mapping of a document, or nil when the document
+// is not a mapping
+func documentRoot(document *yaml.Node) *yaml.Node {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return document.Content[0]
+}
+
+// mappingIndex returns the index of a key of a mapping node, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setMappingValue sets the value of a key of a mapping node
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	if index := mappingIndex(mapping, key); index >= 0 {
+		mapping.Content[index+1] = value
+		return
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// sequenceContains reports whether a sequence node holds a scalar value
+func sequenceContains(sequence *yaml.Node, value string) bool {
+	for _, item := range sequence.Content {
+		if item.Kind == yaml.ScalarNode && item.Value == value {
+			return true
+		}
+	}
+	return false
+}
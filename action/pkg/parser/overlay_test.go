@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const overlayManifest = `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  environments:
+    staging:
+      projects:
+        payments: payments-staging
+      labels:
+        env: [staging]
+      patches:
+        - target: {kind: RoleBinding, name: payments-alice}
+          patch:
+            spec:
+              roleRef: project-viewer
+        - target: {kind: RoleBinding, name: payments-bob}
+          remove: true
+---
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+  labels:
+    team: [payments]
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+spec:
+  user: alice
+  roleRef: project-owner
+  projectRef: payments
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-bob
+spec:
+  user: bob
+  roleRef: project-owner
+  projectRef: payments
+`
+
+func TestOverlay(t *testing.T) {
+	meta, content, err := ExtractMeta([]byte(overlayManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if environments := meta.Environments(); len(environments) != 1 || environments[0] != "staging" {
+		t.Fatalf("unexpected environments: %v", environments)
+	}
+
+	overlaid, err := meta.Overlay(content, "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	objects, err := sdk.DecodeObjects(overlaid)
+	if err != nil {
+		t.Fatalf("expected the overlay to decode, got %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected payments-bob to be removed, got %d objects", len(objects))
+	}
+
+	project := objects[0].(v1alphaProject.Project)
+	if project.GetName() != "payments-staging" {
+		t.Errorf("expected the project to be renamed, got %s", project.GetName())
+	}
+	if env := project.Metadata.Labels["env"]; len(env) != 1 || env[0] != "staging" {
+		t.Errorf("expected the env label, got %v", project.Metadata.Labels)
+	}
+	if team := project.Metadata.Labels["team"]; len(team) != 1 || team[0] != "payments" {
+		t.Errorf("expected the base labels to be kept, got %v", project.Metadata.Labels)
+	}
+
+	binding := objects[1].(v1alphaRoleBinding.RoleBinding)
+	if binding.Spec.ProjectRef != "payments-staging" || binding.Spec.RoleRef != "project-viewer" {
+		t.Errorf("unexpected role binding: %+v", binding.Spec)
+	}
+
+	// The base manifest and environments the file does not declare are left
+	// unchanged, and the overlay does not alter the meta for later calls
+	for _, environment := range []string{"", "prod", "staging"} {
+		again, err := meta.Overlay(content, environment)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if environment == "staging" {
+			if string(again) != string(overlaid) {
+				t.Errorf("expected overlays to be repeatable, got\n%s", again)
+			}
+			continue
+		}
+		if string(again) != string(content) {
+			t.Errorf("expected environment %q to use the base manifest", environment)
+		}
+	}
+}
+
+func TestOverlayErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		overlay  string
+		expected string
+	}{
+		{
+			name: "unmatched patch",
+			overlay: `      patches:
+        - target: {kind: RoleBinding, name: payments-carol}
+          remove: true`,
+			expected: "patch 1 targets RoleBinding payments-carol, which the file does not declare",
+		},
+		{
+			name: "patch without target",
+			overlay: `      patches:
+        - patch: {spec: {roleRef: project-viewer}}`,
+			expected: "patch 1 needs a target kind and name",
+		},
+		{
+			name: "patch and remove",
+			overlay: `      patches:
+        - target: {kind: RoleBinding, name: payments-alice}
+          remove: true
+          patch: {spec: {roleRef: project-viewer}}`,
+			expected: "cannot both remove and patch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := "apiVersion: nobl9-action/v1\nkind: ActionMeta\nspec:\n  environments:\n    dev:\n" + tt.overlay + "\n---\n" +
+				strings.SplitN(overlayManifest, "---\n", 2)[1]
+			meta, content, err := ExtractMeta([]byte(manifest))
+			if err == nil {
+				_, err = meta.Overlay(content, "dev")
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
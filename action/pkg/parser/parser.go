@@ -20,6 +20,7 @@ type FileInfo struct {
 	ModTime      fs.FileInfo
 	IsDir        bool
 	IsYAML       bool
+	IsJSON       bool
 	IsNobl9      bool
 	Content      []byte
 	Error        error
@@ -34,6 +35,7 @@ type Parser struct {
 // ParseResult represents the result of parsing a YAML file
 type ParseResult struct {
 	FileInfo       *FileInfo
+	Meta           *Meta
 	Manifests      []manifest.Object
 	ValidObjects   []manifest.Object
 	InvalidObjects []InvalidObject
@@ -44,9 +46,14 @@ type ParseResult struct {
 
 // InvalidObject represents an invalid Nobl9 object
 type InvalidObject struct {
-	Object   manifest.Object
-	Error    error
+	Object manifest.Object
+	Error  error
+	// Position is the object's index in the file followed by Location,
+	// e.g. "object 2, document 2, line 7, column 3 (metadata.name)"
 	Position string
+	// Location is where the failing field is in the file; it is zero when
+	// the object could not be located
+	Location Position
 }
 
 // New creates a new parser instance
@@ -88,8 +95,17 @@ func (p *Parser) ParseFile(ctx context.Context, fileInfo *FileInfo) (*ParseResul
 		return result, nil
 	}
 
+	// Separate the ActionMeta document from the Nobl9 objects
+	meta, content, err := ExtractMeta(fileInfo.Content)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to parse %s: %w", MetaKind, err))
+		result.IsValid = false
+		return result, nil
+	}
+	result.Meta = meta
+
 	// Parse YAML content
-	manifests, err := p.parseYAMLContent(fileInfo.Content)
+	manifests, err := p.parseYAMLContent(content)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Errorf("failed to parse YAML: %w", err))
 		result.IsValid = false
@@ -98,6 +114,13 @@ func (p *Parser) ParseFile(ctx context.Context, fileInfo *FileInfo) (*ParseResul
 
 	result.Manifests = manifests
 
+	// Objects are located in the file as written, since the content
+	// decoded has its ActionMeta document removed
+	nodes, err := objectNodes(fileInfo.Content)
+	if err != nil || len(nodes) != len(manifests) {
+		nodes = nil
+	}
+
 	// Validate each manifest
 	for i, obj := range manifests {
 		if err := p.validateObject(ctx, obj); err != nil {
@@ -106,8 +129,16 @@ func (p *Parser) ParseFile(ctx context.Context, fileInfo *FileInfo) (*ParseResul
 				Error:    err,
 				Position: fmt.Sprintf("object %d", i+1),
 			}
+			if nodes != nil {
+				invalidObj.Location = nodes[i].position(failingField(err))
+			} else {
+				invalidObj.Location, _ = LocateError(fileInfo.Content, obj, err)
+			}
+			if invalidObj.Location.Line > 0 {
+				invalidObj.Position += ", " + invalidObj.Location.String()
+			}
 			result.InvalidObjects = append(result.InvalidObjects, invalidObj)
-			result.Errors = append(result.Errors, fmt.Errorf("object %d validation failed: %w", i+1, err))
+			result.Errors = append(result.Errors, fmt.Errorf("%s validation failed: %w", invalidObj.Position, err))
 			result.IsValid = false
 		} else {
 			result.ValidObjects = append(result.ValidObjects, obj)
@@ -233,12 +264,12 @@ func (p *Parser) validateObject(ctx context.Context, obj manifest.Object) error
 func (p *Parser) validateNobl9Schema(obj manifest.Object) error {
 	// Validate API version
 	if obj.GetVersion() != "n9/v1alpha" {
-		return fmt.Errorf("invalid API version: %s, expected n9/v1alpha", obj.GetVersion())
+		return &fieldError{field: "apiVersion", err: fmt.Errorf("invalid API version: %s, expected n9/v1alpha", obj.GetVersion())}
 	}
 
 	// Validate name follows DNS RFC1123 conventions
 	if err := p.validateName(obj.GetName()); err != nil {
-		return fmt.Errorf("invalid name '%s': %w", obj.GetName(), err)
+		return &fieldError{field: "metadata.name", err: fmt.Errorf("invalid name '%s': %w", obj.GetName(), err)}
 	}
 
 	// Validate kind is supported
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/manifest/v1alpha"
+	"gopkg.in/yaml.v3"
+)
+
+// Position locates a field of an object in the file it was read from. Lines
+// and columns start at 1; zero values mean the position is unknown.
+type Position struct {
+	// Document is the 1-based index of the YAML document holding the
+	// object, counting every document of the file
+	Document int
+	Line     int
+	Column   int
+	// Field is the path of the failing field, e.g. spec.description, or
+	// empty when the whole object failed
+	Field string
+}
+
+// String returns the position, e.g. "document 2, line 7, column 3
+// (metadata.name)"
+func (p Position) String() string {
+	if p.Line == 0 {
+		return ""
+	}
+	position := fmt.Sprintf("document %d, line %d, column %d", p.Document, p.Line, p.Column)
+	if p.Field != "" {
+		position += " (" + p.Field + ")"
+	}
+	return position
+}
+
+// objectNode is the YAML node an object was decoded from
+type objectNode struct {
+	document int
+	node     *yaml.Node
+}
+
+// objectNodes returns the node of every object in content, in the order
+// sdk.DecodeObjects decodes them. A document is an object or a list of
+// objects; ActionMeta documents and empty documents hold none.
+func objectNodes(content []byte) ([]objectNode, error) {
+	var nodes []objectNode
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for document := 1; ; document++ {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nodes, nil
+			}
+			return nil, err
+		}
+		if len(root.Content) == 0 || isMetaDocument(&root) {
+			continue
+		}
+
+		node := root.Content[0]
+		switch node.Kind {
+		case yaml.MappingNode:
+			nodes = append(nodes, objectNode{document: document, node: node})
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				nodes = append(nodes, objectNode{document: document, node: item})
+			}
+		}
+	}
+}
+
+// position returns the position of field within the object, or of the
+// object itself when the field is not found
+func (o objectNode) position(field string) Position {
+	node := locateField(o.node, field)
+	return Position{Document: o.document, Line: node.Line, Column: node.Column, Field: field}
+}
+
+// locateField returns the node of the deepest part of path found below
+// node. Mapping entries resolve to their key, so errors point at the line
+// naming the field.
+func locateField(node *yaml.Node, path string) *yaml.Node {
+	position := node
+	for _, segment := range splitFieldPath(path) {
+		key, value := childNode(node, segment)
+		if value == nil {
+			break
+		}
+		position, node = key, value
+	}
+	return position
+}
+
+// childNode returns the key and value of a mapping entry, or the element of
+// a sequence twice, named by segment
+func childNode(node *yaml.Node, segment string) (*yaml.Node, *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				return node.Content[i], node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if !strings.HasPrefix(segment, "[") {
+			return nil, nil
+		}
+		index, err := strconv.Atoi(strings.Trim(segment, "[]"))
+		if err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index], node.Content[index]
+		}
+	}
+	return nil, nil
+}
+
+// splitFieldPath splits a field path such as
+// spec.objectives[0].value or metadata.labels.['team.io'] into its keys
+// and [index] segments
+func splitFieldPath(path string) []string {
+	var segments []string
+	var key strings.Builder
+	flush := func() {
+		if key.Len() > 0 {
+			segments = append(segments, key.String())
+			key.Reset()
+		}
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '.':
+			flush()
+		case c == '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				key.WriteString(path[i:])
+				i = len(path)
+				continue
+			}
+			inner := path[i+1 : i+end]
+			if quoted := strings.Trim(inner, `'"`); quoted != inner {
+				segments = append(segments, quoted)
+			} else {
+				segments = append(segments, "["+inner+"]")
+			}
+			i += end
+		default:
+			key.WriteByte(c)
+		}
+	}
+	flush()
+	return segments
+}
+
+// failingField returns the path of the first field a validation error
+// names, or "" when it names none
+func failingField(err error) string {
+	var objectErr *v1alpha.ObjectError
+	if errors.As(err, &objectErr) && len(objectErr.Errors) > 0 {
+		return objectErr.Errors[0].PropertyName
+	}
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.field
+	}
+	return ""
+}
+
+// fieldError is a validation error of a single field
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// LocateObject returns the position of field in the object of the kind and
+// name in content, or false when content holds no such object. An empty
+// field, or one not found, gives the position of the object.
+func LocateObject(content []byte, kind manifest.Kind, name, field string) (Position, bool) {
+	nodes, err := objectNodes(content)
+	if err != nil {
+		return Position{}, false
+	}
+	for _, object := range nodes {
+		if fieldValue(object.node, "kind") == kind.String() && fieldValue(locateValue(object.node, "metadata"), "name") == name {
+			return object.position(field), true
+		}
+	}
+	return Position{}, false
+}
+
+// LocateError returns the position of the field a validation error of the
+// object names, like LocateObject
+func LocateError(content []byte, obj manifest.Object, err error) (Position, bool) {
+	return LocateObject(content, obj.GetKind(), obj.GetName(), failingField(err))
+}
+
+// locateValue returns the value of key in a mapping node, or nil
+func locateValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// fieldValue returns the scalar value of key in a mapping node, or ""
+func fieldValue(node *yaml.Node, key string) string {
+	if value := locateValue(node, key); value != nil {
+		return value.Value
+	}
+	return ""
+}
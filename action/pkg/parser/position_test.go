@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/sirupsen/logrus"
+)
+
+const positionManifest = `apiVersion: nobl9-action/v1
+kind: ActionMeta
+spec:
+  owner: payments
+---
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+spec:
+  description: Payments
+---
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: Billing
+    displayName: Billing
+  spec:
+    description: Billing
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: billing-owner
+  spec:
+    projectRef: billing
+    roleRef: project-owner
+`
+
+func TestParseFileLocatesInvalidObjects(t *testing.T) {
+	p := New(&sdk.Client{}, logrus.New())
+	result, err := p.ParseFile(context.Background(), &FileInfo{
+		Path:    "payments.yaml",
+		IsYAML:  true,
+		IsNobl9: true,
+		Content: []byte(positionManifest),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.InvalidObjects) != 2 {
+		t.Fatalf("expected 2 invalid objects, got %d: %v", len(result.InvalidObjects), result.Errors)
+	}
+
+	// The ActionMeta document counts as a document, so lines are the ones
+	// of the file as written
+	name := result.InvalidObjects[0]
+	want := Position{Document: 3, Line: 16, Column: 5, Field: "metadata.name"}
+	if name.Location != want {
+		t.Errorf("expected %+v, got %+v", want, name.Location)
+	}
+	if name.Position != "object 2, document 3, line 16, column 5 (metadata.name)" {
+		t.Errorf("unexpected position %q", name.Position)
+	}
+	if !strings.Contains(result.Errors[0].Error(), "object 2, document 3, line 16, column 5") {
+		t.Errorf("expected the error to hold the position, got %v", result.Errors[0])
+	}
+
+	roleBinding := result.InvalidObjects[1]
+	want = Position{Document: 3, Line: 24, Column: 3, Field: "spec"}
+	if roleBinding.Location != want {
+		t.Errorf("expected %+v, got %+v", want, roleBinding.Location)
+	}
+}
+
+func TestLocateObject(t *testing.T) {
+	content := []byte(positionManifest)
+
+	position, found := LocateObject(content, manifest.KindRoleBinding, "billing-owner", "spec.roleRef")
+	if !found || position.Line != 26 || position.Column != 5 {
+		t.Errorf("expected line 26, column 5, got %+v (found %v)", position, found)
+	}
+
+	// A field that is not written gives the position of its closest
+	// written parent
+	position, found = LocateObject(content, manifest.KindProject, "payments", "spec.labels.team")
+	if !found || position.Document != 2 || position.Line != 10 || position.Column != 1 {
+		t.Errorf("expected the deepest field found, got %+v (found %v)", position, found)
+	}
+
+	if _, found := LocateObject(content, manifest.KindProject, "checkout", ""); found {
+		t.Error("expected a missing object not to be found")
+	}
+}
+
+func TestSplitFieldPath(t *testing.T) {
+	tests := map[string][]string{
+		"metadata.name":                {"metadata", "name"},
+		"spec.objectives[0].value":     {"spec", "objectives", "[0]", "value"},
+		"metadata.labels.['team.io']":  {"metadata", "labels", "team.io"},
+		"spec.alertMethods[1].project": {"spec", "alertMethods", "[1]", "project"},
+		"":                             nil,
+	}
+	for path, want := range tests {
+		if got := splitFieldPath(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitFieldPath(%q) = %q, expected %q", path, got, want)
+		}
+	}
+}
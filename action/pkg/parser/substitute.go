@@ -0,0 +1,331 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VarsEnv holds KEY=value variables, one per line, substituted like the
+// ones passed with --vars. The action sets it from its vars input, as
+// values may contain spaces.
+const VarsEnv = "NOBL9_VARS"
+
+// variablePattern matches $${NAME} escapes, ${NAME} and ${NAME:-default}
+// references, {{ .Env.NAME }} references and {{ .Values.path }} references
+// to values files
+var variablePattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}|\{\{\s*\.Env\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}|\{\{\s*\.Values\.([A-Za-z_][A-Za-z0-9_-]*(?:\.[A-Za-z_][A-Za-z0-9_-]*)*)\s*\}\}`)
+
+// variableNamePattern matches the names of --vars variables
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sensitiveEnvNames are parts of environment variable names that are never
+// substituted, as their values may be secrets. Action inputs (INPUT_*) are
+// refused too, since they include the Nobl9 credentials.
+var sensitiveEnvNames = []string{"SECRET", "TOKEN", "PASSWORD", "CREDENTIAL", "PRIVATE_KEY", "API_KEY"}
+
+// Variables resolves the variables substituted into manifests: the ones
+// passed with --vars, then environment variables, and the values of values
+// files
+type Variables struct {
+	vars      map[string]string
+	lookupEnv func(string) (string, bool)
+	values    map[string]interface{}
+}
+
+// NewVariables creates variables from --vars values and an environment
+// lookup such as os.LookupEnv. lookupEnv may be nil to use --vars only.
+func NewVariables(vars map[string]string, lookupEnv func(string) (string, bool)) *Variables {
+	return &Variables{vars: vars, lookupEnv: lookupEnv}
+}
+
+// WithValues sets the values {{ .Values.path }} references resolve to, as
+// merged by MergeValues
+func (v *Variables) WithValues(values map[string]interface{}) *Variables {
+	v.values = values
+	return v
+}
+
+// ParseValues parses a values file: a YAML mapping whose keys may nest
+func ParseValues(content []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("values must be a YAML mapping: %w", err)
+	}
+	return values, nil
+}
+
+// MergeValues merges values files in order, like Helm: mappings are merged
+// key by key, and any other value of a later file replaces the earlier one
+func MergeValues(files ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, values := range files {
+		mergeValues(merged, values)
+	}
+	return merged
+}
+
+func mergeValues(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeValues(dstMap, srcMap)
+				continue
+			}
+			copied := make(map[string]interface{}, len(srcMap))
+			mergeValues(copied, srcMap)
+			value = copied
+		}
+		dst[key] = value
+	}
+}
+
+// lookupValue returns the value at a dotted path of the values files. Only
+// scalars can be substituted; null counts as undefined.
+func (v *Variables) lookupValue(path string) (interface{}, bool, error) {
+	var value interface{} = v.values
+	for _, key := range strings.Split(path, ".") {
+		mapping, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		if value, ok = mapping[key]; !ok {
+			return nil, false, nil
+		}
+	}
+	switch value.(type) {
+	case nil:
+		return nil, false, nil
+	case map[string]interface{}, []interface{}:
+		return nil, false, fmt.Errorf("value %s is not a string, number or boolean and cannot be substituted", path)
+	}
+	return value, true, nil
+}
+
+// ParseVars parses KEY=value pairs. Each entry may hold several pairs, one
+// per line; blank lines are ignored.
+func ParseVars(entries []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, entry := range entries {
+		for _, line := range strings.Split(entry, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			name, value, found := strings.Cut(line, "=")
+			name = strings.TrimSpace(name)
+			if !found || !variableNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid variable %q: expected KEY=value with a KEY of letters, digits and underscores", line)
+			}
+			vars[name] = value
+		}
+	}
+	return vars, nil
+}
+
+// lookup returns the value of a variable. Environment variables whose names
+// suggest a secret are refused rather than reported as undefined.
+func (v *Variables) lookup(name string) (string, bool, error) {
+	if value, found := v.vars[name]; found {
+		return value, true, nil
+	}
+	if v.lookupEnv == nil {
+		return "", false, nil
+	}
+	if sensitiveEnvName(name) {
+		return "", false, fmt.Errorf("environment variable %s may hold a secret and is not substituted; pass the value with --vars if it is not one", name)
+	}
+	value, found := v.lookupEnv(name)
+	return value, found, nil
+}
+
+// Substitute replaces variable references in the scalar values of YAML
+// content: ${NAME}, ${NAME:-default}, {{ .Env.NAME }} and
+// {{ .Values.path }}, with $${ writing a literal ${. Only values are
+// substituted, never keys, and a value can
+// never add YAML structure, however it is quoted. References to undefined
+// variables without a default fail. Content without references is returned
+// unchanged.
+func (v *Variables) Substitute(content []byte) ([]byte, error) {
+	if !bytes.Contains(content, []byte("${")) && !bytes.Contains(content, []byte(".Env.")) && !bytes.Contains(content, []byte(".Values.")) {
+		return content, nil
+	}
+
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		documents = append(documents, &document)
+	}
+
+	undefined := make(map[string]bool)
+	changed := false
+	for _, document := range documents {
+		if err := v.substituteNode(document, undefined, &changed); err != nil {
+			return nil, err
+		}
+	}
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+	if !changed {
+		return content, nil
+	}
+
+	var substituted bytes.Buffer
+	encoder := yaml.NewEncoder(&substituted)
+	for _, document := range documents {
+		blockStyle(document)
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return substituted.Bytes(), nil
+}
+
+// blockStyle encodes the root of a document read from JSON in block style.
+// Encoded in flow style it would start with [ or { and be decoded as JSON,
+// which flow style YAML is not.
+func blockStyle(document *yaml.Node) {
+	if len(document.Content) > 0 {
+		document.Content[0].Style &^= yaml.FlowStyle
+	}
+}
+
+// substituteNode substitutes the scalar values below a node
+func (v *Variables) substituteNode(node *yaml.Node, undefined map[string]bool, changed *bool) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, err := v.substituteValue(node.Value, undefined)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if value != node.Value {
+			// The value stays a string, whatever it looks like, unless it
+			// is a single number or boolean of the values files
+			node.Tag = v.valueTag(node.Value)
+			node.Value = value
+			node.Style = 0
+			*changed = true
+		}
+	case yaml.MappingNode:
+		// Keys are left as they are
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := v.substituteNode(node.Content[i], undefined, changed); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := v.substituteNode(child, undefined, changed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// substituteValue substitutes the references of a single value
+func (v *Variables) substituteValue(value string, undefined map[string]bool) (string, error) {
+	var lookupErr error
+	substituted := variablePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+		match := variablePattern.FindStringSubmatch(reference)
+		if path := match[4]; path != "" {
+			resolved, found, err := v.lookupValue(path)
+			switch {
+			case err != nil:
+				if lookupErr == nil {
+					lookupErr = err
+				}
+			case found:
+				return fmt.Sprint(resolved)
+			default:
+				undefined[".Values."+path] = true
+			}
+			return reference
+		}
+		name := match[1]
+		if name == "" {
+			name = match[3]
+		}
+
+		resolved, found, err := v.lookup(name)
+		if err != nil {
+			if lookupErr == nil {
+				lookupErr = err
+			}
+			return reference
+		}
+		if found {
+			return resolved
+		}
+		// A default is only given with the ${NAME:-default} form
+		if strings.Contains(reference, ":-") {
+			return match[2]
+		}
+		undefined[name] = true
+		return reference
+	})
+	return substituted, lookupErr
+}
+
+// valueTag returns the tag of a value once substituted: that of the number
+// or boolean a value made of a single {{ .Values.path }} reference resolves
+// to, and !!str for any other value
+func (v *Variables) valueTag(value string) string {
+	match := variablePattern.FindStringSubmatchIndex(value)
+	if match == nil || match[0] != 0 || match[1] != len(value) || match[8] < 0 {
+		return "!!str"
+	}
+	resolved, found, err := v.lookupValue(value[match[8]:match[9]])
+	if err != nil || !found {
+		return "!!str"
+	}
+	switch resolved.(type) {
+	case bool:
+		return "!!bool"
+	case int, int64, uint64:
+		return "!!int"
+	case float64:
+		return "!!float"
+	}
+	return "!!str"
+}
+
+// sensitiveEnvName reports whether an environment variable may hold a
+// secret
+func sensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	if strings.HasPrefix(upper, "INPUT_") {
+		return true
+	}
+	for _, part := range sensitiveEnvNames {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"TEAM=payments", "ENV=staging\nDESCRIPTION=Payments, checkout and refunds\n\n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vars) != 3 || vars["TEAM"] != "payments" || vars["DESCRIPTION"] != "Payments, checkout and refunds" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	for _, entry := range []string{"TEAM", "=payments", "MY-TEAM=payments"} {
+		if _, err := ParseVars([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	env := map[string]string{
+		"TEAM":                "payments",
+		"BRANCH":              "feature-x",
+		"NOBL9_CLIENT_SECRET": "s3cret",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, found := env[name]
+		return value, found
+	}
+	variables := NewVariables(map[string]string{"TEAM": "checkout", "INJECT": "x\nkind: RoleBinding"}, lookupEnv)
+
+	content := `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: ${TEAM}-{{ .Env.BRANCH }}
+  labels:
+    ${TEAM}:
+      - ${TIER:-gold}
+spec:
+  description: "${INJECT} costs $${PRICE}"
+`
+	substituted, err := variables.Substitute([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := string(substituted)
+	for _, expected := range []string{
+		"name: checkout-feature-x", // --vars take precedence over the environment
+		"${TEAM}:",                 // keys are never substituted
+		"- gold",                   // defaults apply to undefined variables
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in\n%s", expected, got)
+		}
+	}
+
+	// Values never add YAML structure
+	var decoded struct {
+		Kind string `yaml:"kind"`
+		Spec struct {
+			Description string `yaml:"description"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(substituted, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.Kind != "Project" || decoded.Spec.Description != "x\nkind: RoleBinding costs ${PRICE}" {
+		t.Errorf("unexpected object: %+v", decoded)
+	}
+
+	plain := []byte("kind: Project\nmetadata:\n  name: payments\n")
+	if unchanged, err := variables.Substitute(plain); err != nil || string(unchanged) != string(plain) {
+		t.Errorf("expected content without references to be unchanged, got %q, %v", unchanged, err)
+	}
+
+	_, err = variables.Substitute([]byte("name: ${MISSING}-${OTHER}\n"))
+	if err == nil || !strings.Contains(err.Error(), "undefined variables: MISSING, OTHER") {
+		t.Errorf("expected undefined variables to fail, got %v", err)
+	}
+
+	_, err = variables.Substitute([]byte("description: ${NOBL9_CLIENT_SECRET}\n"))
+	if err == nil || !strings.Contains(err.Error(), "may hold a secret") {
+		t.Errorf("expected secrets to be refused, got %v", err)
+	}
+}
+
+func TestSubstituteJSON(t *testing.T) {
+	variables := NewVariables(map[string]string{"TEAM": "payments"}, func(string) (string, bool) { return "", false })
+	substituted, err := variables.Substitute([]byte(`[{"apiVersion": "n9/v1alpha", "kind": "Project", "metadata": {"name": "${TEAM}"}}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// JSON re-encoded as flow style YAML would no longer decode as JSON
+	if trimmed := strings.TrimSpace(string(substituted)); strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		t.Errorf("expected block style YAML, got %s", substituted)
+	}
+	var objects []map[string]interface{}
+	if err := yaml.Unmarshal(substituted, &objects); err != nil || len(objects) != 1 || objects[0]["metadata"].(map[string]interface{})["name"] != "payments" {
+		t.Errorf("unexpected substitution %s (%v)", substituted, err)
+	}
+}
+
+func TestSubstituteValues(t *testing.T) {
+	base, err := ParseValues([]byte(`
+team:
+  name: platform
+  tier: gold
+objective:
+  target: 0.99
+  enabled: true
+labels: [a, b]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	override, err := ParseValues([]byte(`
+team:
+  name: payments
+objective:
+  target: 0.999
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := MergeValues(base, override)
+	variables := NewVariables(nil, nil).WithValues(values)
+
+	substituted, err := variables.Substitute([]byte(`apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: "{{ .Values.team.name }}-latency"
+  displayName: "{{ .Values.team.name }} ({{ .Values.team.tier }})"
+spec:
+  objectives:
+    - target: "{{ .Values.objective.target }}"
+      primary: '{{.Values.objective.enabled}}'
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var slo struct {
+		Metadata map[string]string
+		Spec     struct {
+			Objectives []map[string]interface{}
+		}
+	}
+	if err := yaml.Unmarshal(substituted, &slo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slo.Metadata["name"] != "payments-latency" || slo.Metadata["displayName"] != "payments (gold)" {
+		t.Errorf("expected the later values file to win, got %v", slo.Metadata)
+	}
+	// A value made of a single reference keeps the type of the value, even
+	// though the reference is quoted to be valid YAML
+	if objective := slo.Spec.Objectives[0]; objective["target"] != 0.999 || objective["primary"] != true {
+		t.Errorf("expected a number and a boolean, got %#v", objective)
+	}
+
+	if _, err := variables.Substitute([]byte("name: '{{ .Values.team.owner }}'\n")); err == nil || !strings.Contains(err.Error(), "undefined variables: .Values.team.owner") {
+		t.Errorf("expected an undefined value to fail, got %v", err)
+	}
+	// Values cannot add structure to a manifest
+	if _, err := variables.Substitute([]byte("labels: '{{ .Values.labels }}'\n")); err == nil || !strings.Contains(err.Error(), "cannot be substituted") {
+		t.Errorf("expected a list value to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package planner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+)
+
+// Item is an object to apply together with the file it was read from
+type Item struct {
+	Object manifest.Object
+	Source string
+}
+
+// Group is the set of items of a stage that came from the same file
+type Group struct {
+	Source  string
+	Objects []manifest.Object
+}
+
+// Stage is a set of items that can be applied together once every
+// previous stage has been applied
+type Stage struct {
+	Kinds []manifest.Kind
+	Items []Item
+}
+
+// Plan is an ordered list of stages
+type Plan struct {
+	Stages []Stage
+}
+
+// Planner orders apply operations by the dependencies between object kinds
+type Planner struct {
+	dependencies map[manifest.Kind][]manifest.Kind
+}
+
+// DefaultDependencies lists, for each kind, the kinds that must be applied
+// before it. Objects referencing a missing dependency are assumed to refer
+// to an object that already exists in Nobl9.
+func DefaultDependencies() map[manifest.Kind][]manifest.Kind {
+	return map[manifest.Kind][]manifest.Kind{
+		manifest.KindProject:          {},
+		manifest.KindAlertMethod:      {manifest.KindProject},
+		manifest.KindAgent:            {manifest.KindProject},
+		manifest.KindDirect:           {manifest.KindProject},
+		manifest.KindDataExport:       {manifest.KindProject},
+		manifest.KindService:          {manifest.KindProject},
+		manifest.KindAlertPolicy:      {manifest.KindProject, manifest.KindAlertMethod},
+		manifest.KindSLO:              {manifest.KindProject, manifest.KindService, manifest.KindAlertPolicy, manifest.KindAgent, manifest.KindDirect},
+		manifest.KindAlertSilence:     {manifest.KindSLO, manifest.KindAlertPolicy},
+		manifest.KindAnnotation:       {manifest.KindSLO},
+		manifest.KindBudgetAdjustment: {manifest.KindSLO},
+		manifest.KindReport:           {manifest.KindProject, manifest.KindService, manifest.KindSLO},
+		// Access is granted last, once the project's resources exist
+		manifest.KindRoleBinding: {manifest.KindProject, manifest.KindService, manifest.KindSLO, manifest.KindAlertPolicy},
+	}
+}
+
+// New creates a planner using DefaultDependencies
+func New() *Planner {
+	return NewWithDependencies(DefaultDependencies())
+}
+
+// NewWithDependencies creates a planner with custom kind dependencies
+func NewWithDependencies(dependencies map[manifest.Kind][]manifest.Kind) *Planner {
+	return &Planner{dependencies: dependencies}
+}
+
+// Levels assigns every known kind to a level so that each kind's
+// dependencies are on a lower level. It returns an error if the
+// dependencies contain a cycle.
+func (p *Planner) Levels() (map[manifest.Kind]int, error) {
+	// Kahn's algorithm, processing one level at a time
+	inDegree := make(map[manifest.Kind]int)
+	dependents := make(map[manifest.Kind][]manifest.Kind)
+
+	for kind, deps := range p.dependencies {
+		if _, ok := inDegree[kind]; !ok {
+			inDegree[kind] = 0
+		}
+		for _, dep := range deps {
+			if _, ok := inDegree[dep]; !ok {
+				inDegree[dep] = 0
+			}
+			inDegree[kind]++
+			dependents[dep] = append(dependents[dep], kind)
+		}
+	}
+
+	levels := make(map[manifest.Kind]int, len(inDegree))
+	var current []manifest.Kind
+	for kind, degree := range inDegree {
+		if degree == 0 {
+			current = append(current, kind)
+		}
+	}
+
+	for level := 0; len(current) > 0; level++ {
+		var next []manifest.Kind
+		for _, kind := range current {
+			levels[kind] = level
+			for _, dependent := range dependents[kind] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+
+	if len(levels) != len(inDegree) {
+		var cyclic []string
+		for kind := range inDegree {
+			if _, ok := levels[kind]; !ok {
+				cyclic = append(cyclic, kind.String())
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("dependency cycle between kinds: %s", strings.Join(cyclic, ", "))
+	}
+
+	return levels, nil
+}
+
+// Plan orders items into stages by kind level. Items keep their input
+// order within a stage; kinds without declared dependencies are applied
+// in the first stage.
+func (p *Planner) Plan(items []Item) (*Plan, error) {
+	levels, err := p.Levels()
+	if err != nil {
+		return nil, err
+	}
+
+	byLevel := make(map[int]*Stage)
+	for _, item := range items {
+		level := levels[item.Object.GetKind()]
+
+		stage, ok := byLevel[level]
+		if !ok {
+			stage = &Stage{}
+			byLevel[level] = stage
+		}
+		stage.Items = append(stage.Items, item)
+		if !containsKind(stage.Kinds, item.Object.GetKind()) {
+			stage.Kinds = append(stage.Kinds, item.Object.GetKind())
+		}
+	}
+
+	order := make([]int, 0, len(byLevel))
+	for level := range byLevel {
+		order = append(order, level)
+	}
+	sort.Ints(order)
+
+	plan := &Plan{Stages: make([]Stage, 0, len(order))}
+	for _, level := range order {
+		stage := byLevel[level]
+		sort.Slice(stage.Kinds, func(i, j int) bool { return stage.Kinds[i] < stage.Kinds[j] })
+		plan.Stages = append(plan.Stages, *stage)
+	}
+
+	return plan, nil
+}
+
+// BySource groups the stage's items by source file, in order of first appearance
+func (s Stage) BySource() []Group {
+	var groups []Group
+	index := make(map[string]int)
+
+	for _, item := range s.Items {
+		i, ok := index[item.Source]
+		if !ok {
+			i = len(groups)
+			index[item.Source] = i
+			groups = append(groups, Group{Source: item.Source})
+		}
+		groups[i].Objects = append(groups[i].Objects, item.Object)
+	}
+
+	return groups
+}
+
+// Apply granularities: how the objects of a group are split into apply
+// calls. A failed call fails only its own objects.
+const (
+	GranularityFile    = "file"
+	GranularityProject = "project"
+	GranularityObject  = "object"
+)
+
+// ParseGranularity checks an apply granularity; empty selects
+// GranularityFile
+func ParseGranularity(granularity string) (string, error) {
+	switch granularity {
+	case "":
+		return GranularityFile, nil
+	case GranularityFile, GranularityProject, GranularityObject:
+		return granularity, nil
+	}
+	return "", fmt.Errorf("unknown apply granularity %q, expected %s, %s or %s", granularity, GranularityObject, GranularityFile, GranularityProject)
+}
+
+// Batches splits objects into the apply calls of the granularity: one for
+// all of them, one per project in order of first appearance, or one per
+// object
+func Batches(objects []manifest.Object, granularity string) [][]manifest.Object {
+	switch granularity {
+	case GranularityObject:
+		batches := make([][]manifest.Object, 0, len(objects))
+		for _, obj := range objects {
+			batches = append(batches, []manifest.Object{obj})
+		}
+		return batches
+	case GranularityProject:
+		var batches [][]manifest.Object
+		index := make(map[string]int)
+		for _, obj := range objects {
+			project := ProjectOf(obj)
+			i, ok := index[project]
+			if !ok {
+				i = len(batches)
+				index[project] = i
+				batches = append(batches, nil)
+			}
+			batches[i] = append(batches[i], obj)
+		}
+		return batches
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+	return [][]manifest.Object{objects}
+}
+
+// ProjectOf returns the project an object belongs to: the project itself,
+// the projectRef of a role binding or the project of a project-scoped
+// object, or "" for organization objects
+func ProjectOf(obj manifest.Object) string {
+	switch v := obj.(type) {
+	case v1alphaRoleBinding.RoleBinding:
+		return v.Spec.ProjectRef
+	case manifest.ProjectScopedObject:
+		return v.GetProject()
+	}
+	if obj.GetKind() == manifest.KindProject {
+		return obj.GetName()
+	}
+	return ""
+}
+
+// KindNames returns the names of the stage's kinds
+func (s Stage) KindNames() []string {
+	names := make([]string, 0, len(s.Kinds))
+	for _, kind := range s.Kinds {
+		names = append(names, kind.String())
+	}
+	return names
+}
+
+// containsKind checks if kinds contains kind
+func containsKind(kinds []manifest.Kind, kind manifest.Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns a stable digest of the objects to apply: the SHA-256 of the
+// sorted SHA-256 digests of each object's JSON encoding, as sha256:<hex>.
+// It does not depend on the order of the objects or the files they came
+// from, so two runs planning the same objects produce the same hash.
+func Hash(objects []manifest.Object) (string, error) {
+	digests := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		digest := sha256.Sum256(data)
+		digests = append(digests, hex.EncodeToString(digest[:]))
+	}
+	sort.Strings(digests)
+
+	hash := sha256.Sum256([]byte(strings.Join(digests, "\n")))
+	return "sha256:" + hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,263 @@
+package planner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaAlertPolicy "github.com/nobl9/nobl9-go/manifest/v1alpha/alertpolicy"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	v1alphaService "github.com/nobl9/nobl9-go/manifest/v1alpha/service"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+)
+
+func TestPlan(t *testing.T) {
+	user := "user@example.com"
+
+	// Role binding and SLO files come before the project file, as a
+	// directory walk might return them
+	items := []Item{
+		{Source: "rolebindings.yaml", Object: v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "owner"}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "project-owner", ProjectRef: "payments"})},
+		{Source: "slos.yaml", Object: v1alphaSLO.New(v1alphaSLO.Metadata{Name: "latency", Project: "payments"}, v1alphaSLO.Spec{})},
+		{Source: "slos.yaml", Object: v1alphaService.New(v1alphaService.Metadata{Name: "api", Project: "payments"}, v1alphaService.Spec{})},
+		{Source: "slos.yaml", Object: v1alphaAlertPolicy.New(v1alphaAlertPolicy.Metadata{Name: "burn", Project: "payments"}, v1alphaAlertPolicy.Spec{})},
+		{Source: "project.yaml", Object: v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{})},
+	}
+
+	plan, err := New().Plan(items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]manifest.Kind{
+		{manifest.KindProject},
+		{manifest.KindService},
+		{manifest.KindAlertPolicy},
+		{manifest.KindSLO},
+		{manifest.KindRoleBinding},
+	}
+
+	if len(plan.Stages) != len(expected) {
+		t.Fatalf("expected %d stages, got %d", len(expected), len(plan.Stages))
+	}
+
+	for i, stage := range plan.Stages {
+		if len(stage.Kinds) != len(expected[i]) || stage.Kinds[0] != expected[i][0] {
+			t.Errorf("stage %d: expected kinds %v, got %v", i, expected[i], stage.KindNames())
+		}
+		for _, item := range stage.Items {
+			if !containsKind(stage.Kinds, item.Object.GetKind()) {
+				t.Errorf("stage %d: unexpected %s object", i, item.Object.GetKind())
+			}
+		}
+	}
+}
+
+func TestPlanEmpty(t *testing.T) {
+	plan, err := New().Plan(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Stages) != 0 {
+		t.Errorf("expected no stages, got %d", len(plan.Stages))
+	}
+}
+
+func TestLevelsCycle(t *testing.T) {
+	planner := NewWithDependencies(map[manifest.Kind][]manifest.Kind{
+		manifest.KindProject: {},
+		manifest.KindService: {manifest.KindProject, manifest.KindSLO},
+		manifest.KindSLO:     {manifest.KindService},
+	})
+
+	if _, err := planner.Levels(); err == nil {
+		t.Error("expected error for dependency cycle")
+	}
+
+	if _, err := planner.Plan(nil); err == nil {
+		t.Error("expected plan to fail for dependency cycle")
+	}
+}
+
+func TestDefaultDependenciesAreAcyclic(t *testing.T) {
+	levels, err := New().Levels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for kind, deps := range DefaultDependencies() {
+		for _, dep := range deps {
+			if levels[dep] >= levels[kind] {
+				t.Errorf("expected %s to be applied before %s", dep, kind)
+			}
+		}
+	}
+}
+
+func TestStageBySource(t *testing.T) {
+	stage := Stage{
+		Items: []Item{
+			{Source: "b.yaml", Object: v1alphaProject.New(v1alphaProject.Metadata{Name: "one"}, v1alphaProject.Spec{})},
+			{Source: "a.yaml", Object: v1alphaProject.New(v1alphaProject.Metadata{Name: "two"}, v1alphaProject.Spec{})},
+			{Source: "b.yaml", Object: v1alphaProject.New(v1alphaProject.Metadata{Name: "three"}, v1alphaProject.Spec{})},
+		},
+	}
+
+	groups := stage.BySource()
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Source != "b.yaml" || len(groups[0].Objects) != 2 {
+		t.Errorf("expected b.yaml with 2 objects first, got %s with %d", groups[0].Source, len(groups[0].Objects))
+	}
+	if groups[1].Source != "a.yaml" || len(groups[1].Objects) != 1 {
+		t.Errorf("expected a.yaml with 1 object second, got %s with %d", groups[1].Source, len(groups[1].Objects))
+	}
+}
+
+func TestBatches(t *testing.T) {
+	user := "user@example.com"
+	objects := []manifest.Object{
+		v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-owner"}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "project-owner", ProjectRef: "payments"}),
+		v1alphaService.New(v1alphaService.Metadata{Name: "api", Project: "billing"}, v1alphaService.Spec{}),
+		v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "admin"}, v1alphaRoleBinding.Spec{User: &user, RoleRef: "organization-admin"}),
+		v1alphaService.New(v1alphaService.Metadata{Name: "checkout", Project: "payments"}, v1alphaService.Spec{}),
+	}
+
+	names := func(batches [][]manifest.Object) string {
+		var parts []string
+		for _, batch := range batches {
+			var batchNames []string
+			for _, obj := range batch {
+				batchNames = append(batchNames, obj.GetName())
+			}
+			parts = append(parts, strings.Join(batchNames, ","))
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	for granularity, expected := range map[string]string{
+		GranularityFile:    "payments-owner,api,admin,checkout",
+		GranularityProject: "payments-owner,checkout | api | admin",
+		GranularityObject:  "payments-owner | api | admin | checkout",
+	} {
+		if got := names(Batches(objects, granularity)); got != expected {
+			t.Errorf("%s: expected %q, got %q", granularity, expected, got)
+		}
+	}
+	if batches := Batches(nil, GranularityFile); len(batches) != 0 {
+		t.Errorf("expected no batches without objects, got %v", batches)
+	}
+
+	if granularity, err := ParseGranularity(""); err != nil || granularity != GranularityFile {
+		t.Errorf("expected the file granularity by default, got %q, %v", granularity, err)
+	}
+	if _, err := ParseGranularity("stage"); err == nil {
+		t.Error("expected an error for an unknown granularity")
+	}
+}
+
+func TestHash(t *testing.T) {
+	alice, bob := "00u1alice", "00u1bob"
+	project := v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{Description: "Payments team"})
+	binding := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-alice"}, v1alphaRoleBinding.Spec{User: &alice, RoleRef: "project-owner", ProjectRef: "payments"})
+
+	hash, err := Hash([]manifest.Object{project, binding})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hash) != len("sha256:")+64 || hash[:7] != "sha256:" {
+		t.Fatalf("unexpected hash format: %s", hash)
+	}
+
+	// The order of the objects does not matter
+	reordered, err := Hash([]manifest.Object{binding, project})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reordered != hash {
+		t.Errorf("expected the same hash for reordered objects, got %s and %s", hash, reordered)
+	}
+
+	// Any change to an object does
+	changed := v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-alice"}, v1alphaRoleBinding.Spec{User: &bob, RoleRef: "project-owner", ProjectRef: "payments"})
+	other, err := Hash([]manifest.Object{project, changed})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other == hash {
+		t.Error("expected a different hash for a changed object")
+	}
+
+	empty, err := Hash(nil)
+	if err != nil || empty == hash {
+		t.Errorf("unexpected hash of no objects: %s, %v", empty, err)
+	}
+}
+
+func TestSavedPlan(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "payments.yaml")
+	if err := os.WriteFile(input, []byte("kind: Project\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alice := "00u1alice"
+	objects := []manifest.Object{
+		v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{Description: "Payments team"}),
+		v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "payments-alice"}, v1alphaRoleBinding.Spec{User: &alice, RoleRef: "project-owner", ProjectRef: "payments"}),
+	}
+
+	saved, err := NewSavedPlan("acme", []string{input})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := saved.AddFile(input, objects, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	planPath := filepath.Join(dir, "plan.bin")
+	if err := saved.WriteFile(planPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadSavedPlan(planPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hash, _ := Hash(objects)
+	if read.Hash != hash || read.Organization != "acme" || len(read.Files) != 1 || read.Files[0].EmailsResolved != 1 {
+		t.Fatalf("unexpected plan: %+v", read)
+	}
+	if err := read.Verify(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	decoded, err := read.Files[0].DecodeObjects()
+	if err != nil || len(decoded) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(decoded), err)
+	}
+	if err := read.CheckInputs([]string{input}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Changed and added input files are refused
+	if err := os.WriteFile(input, []byte("kind: Project\n# edited\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	added := filepath.Join(dir, "checkout.yaml")
+	if err := os.WriteFile(added, []byte("kind: Project\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = read.CheckInputs([]string{added, input})
+	if err == nil || !strings.Contains(err.Error(), added+" added, "+input+" changed") {
+		t.Errorf("expected added and changed files, got %v", err)
+	}
+
+	// Edited objects no longer match the recorded hash
+	read.Files[0].Objects = []byte(strings.Replace(string(read.Files[0].Objects), "00u1alice", "00u1mallory", 1))
+	if err := read.Verify(); err == nil || !strings.Contains(err.Error(), "the plan file was modified") {
+		t.Errorf("expected a modified plan error, got %v", err)
+	}
+}
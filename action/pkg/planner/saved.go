@@ -0,0 +1,205 @@
+package planner
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+// SavedPlanVersion is the format version of saved plan files
+const SavedPlanVersion = 1
+
+// SavedPlan is a plan written by one run and applied by another. It holds
+// the objects to apply, already resolved, and a digest of every input file
+// so the applying run can refuse to apply when the repository changed.
+type SavedPlan struct {
+	Version      int         `json:"version"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Organization string      `json:"organization,omitempty"`
+	Hash         string      `json:"hash"`
+	Inputs       []Input     `json:"inputs"`
+	Files        []SavedFile `json:"files"`
+}
+
+// Input is an input file of a saved plan with the digest of its content
+type Input struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// SavedFile holds the objects a saved plan applies from one file
+type SavedFile struct {
+	Path           string          `json:"path"`
+	EmailsResolved int             `json:"emails_resolved,omitempty"`
+	Objects        json.RawMessage `json:"objects"`
+}
+
+// NewSavedPlan creates a saved plan of the given input files. Files are
+// added with AddFile; the plan hash is set when the plan is written.
+func NewSavedPlan(organization string, inputs []string) (*SavedPlan, error) {
+	plan := &SavedPlan{
+		Version:      SavedPlanVersion,
+		CreatedAt:    time.Now().UTC(),
+		Organization: organization,
+		Files:        []SavedFile{},
+	}
+	for _, path := range inputs {
+		digest, err := DigestFile(path)
+		if err != nil {
+			return nil, err
+		}
+		plan.Inputs = append(plan.Inputs, Input{Path: path, Digest: digest})
+	}
+	return plan, nil
+}
+
+// AddFile adds the objects to apply from a file
+func (p *SavedPlan) AddFile(path string, objects []manifest.Object, emailsResolved int) error {
+	if objects == nil {
+		objects = []manifest.Object{}
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return fmt.Errorf("failed to encode objects of %s: %w", path, err)
+	}
+	p.Files = append(p.Files, SavedFile{Path: path, EmailsResolved: emailsResolved, Objects: data})
+	return nil
+}
+
+// DecodeObjects decodes the objects of a saved file
+func (f SavedFile) DecodeObjects() ([]manifest.Object, error) {
+	objects, err := sdk.DecodeObjects(f.Objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode objects of %s: %w", f.Path, err)
+	}
+	return objects, nil
+}
+
+// Verify checks that the plan hash still matches the saved objects
+func (p *SavedPlan) Verify() error {
+	hash, err := p.objectHash()
+	if err != nil {
+		return err
+	}
+	if hash != p.Hash {
+		return fmt.Errorf("plan objects hash to %s instead of the recorded %s; the plan file was modified", hash, p.Hash)
+	}
+	return nil
+}
+
+// CheckInputs compares the current input files with the ones the plan was
+// made from and returns an error naming the files that were added,
+// removed or changed since
+func (p *SavedPlan) CheckInputs(paths []string) error {
+	recorded := make(map[string]string, len(p.Inputs))
+	for _, input := range p.Inputs {
+		recorded[input.Path] = input.Digest
+	}
+
+	var changes []string
+	for _, path := range paths {
+		digest, found := recorded[path]
+		if !found {
+			changes = append(changes, path+" added")
+			continue
+		}
+		delete(recorded, path)
+
+		current, err := DigestFile(path)
+		if err != nil {
+			return err
+		}
+		if current != digest {
+			changes = append(changes, path+" changed")
+		}
+	}
+	for path := range recorded {
+		changes = append(changes, path+" removed")
+	}
+
+	if len(changes) > 0 {
+		sort.Strings(changes)
+		return fmt.Errorf("the repository changed since the plan was made: %s", strings.Join(changes, ", "))
+	}
+	return nil
+}
+
+// objectHash hashes the objects of every saved file with Hash
+func (p *SavedPlan) objectHash() (string, error) {
+	var objects []manifest.Object
+	for _, file := range p.Files {
+		decoded, err := file.DecodeObjects()
+		if err != nil {
+			return "", err
+		}
+		objects = append(objects, decoded...)
+	}
+	return Hash(objects)
+}
+
+// WriteFile sets the plan hash and writes the plan as gzip compressed JSON
+func (p *SavedPlan) WriteFile(path string) error {
+	hash, err := p.objectHash()
+	if err != nil {
+		return err
+	}
+	p.Hash = hash
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create plan file: %w", err)
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(p); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return file.Close()
+}
+
+// ReadSavedPlan reads a plan written by WriteFile
+func ReadSavedPlan(path string) (*SavedPlan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan file: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a plan file: %w", path, err)
+	}
+	defer reader.Close()
+
+	var plan SavedPlan
+	if err := json.NewDecoder(reader).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if plan.Version != SavedPlanVersion {
+		return nil, fmt.Errorf("unsupported plan file version %d, expected %d", plan.Version, SavedPlanVersion)
+	}
+	return &plan, nil
+}
+
+// DigestFile returns the SHA-256 digest of a file's content as sha256:<hex>
+func DigestFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	digest := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(digest[:]), nil
+}
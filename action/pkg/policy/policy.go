@@ -0,0 +1,505 @@
+package policy
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaAgent "github.com/nobl9/nobl9-go/manifest/v1alpha/agent"
+	v1alphaAlertMethod "github.com/nobl9/nobl9-go/manifest/v1alpha/alertmethod"
+	v1alphaDirect "github.com/nobl9/nobl9-go/manifest/v1alpha/direct"
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"github.com/your-org/nobl9-action/pkg/resolver"
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultName selects the embedded default policy instead of a file
+const DefaultName = "default"
+
+// Rule IDs reported with violations; they are also the rule keys in a
+// policy file
+const (
+	RuleRequiredProjectLabels = "required-project-labels"
+	RuleNamingPrefix          = "naming-prefix"
+	RuleMaxUsersPerRole       = "max-users-per-role"
+	RuleForbiddenRoles        = "forbidden-roles"
+	RuleAllowedDataSources    = "allowed-data-sources"
+	RuleAllowedEmailDomains   = "allowed-email-domains"
+)
+
+//go:embed default.yaml
+var defaultPolicy []byte
+
+// prefixPattern matches the project name prefixes of the naming-prefix rule
+var prefixPattern = regexp.MustCompile(`^[a-z0-9][-a-z0-9]*$`)
+
+// Policy is a set of organizational guardrails checked before anything is
+// applied. Rules left out of the policy are not checked.
+type Policy struct {
+	Rules Rules `yaml:"rules"`
+}
+
+// Rules configures each rule
+type Rules struct {
+	RequiredProjectLabels *RequiredProjectLabels `yaml:"required-project-labels"`
+	NamingPrefix          *NamingPrefix          `yaml:"naming-prefix"`
+	MaxUsersPerRole       *MaxUsersPerRole       `yaml:"max-users-per-role"`
+	ForbiddenRoles        *ForbiddenRoles        `yaml:"forbidden-roles"`
+	AllowedDataSources    *AllowedDataSources    `yaml:"allowed-data-sources"`
+	AllowedEmailDomains   *AllowedEmailDomains   `yaml:"allowed-email-domains"`
+}
+
+// RequiredProjectLabels requires every Project to carry the labels
+type RequiredProjectLabels struct {
+	Labels []string `yaml:"labels"`
+}
+
+// NamingPrefix requires a Project's name to start with the prefix of the
+// team named by its label, e.g. projects labeled team=payments must be named
+// pay-*
+type NamingPrefix struct {
+	// Label holding the team name; defaults to "team"
+	Label    string            `yaml:"label"`
+	Prefixes map[string]string `yaml:"prefixes"`
+}
+
+// MaxUsersPerRole limits how many users a project grants the same role to,
+// across all role bindings
+type MaxUsersPerRole struct {
+	Max int `yaml:"max"`
+	// Roles limited; empty limits every role
+	Roles []string `yaml:"roles"`
+}
+
+// ForbiddenRoles are roles no role binding may grant
+type ForbiddenRoles struct {
+	Roles []string `yaml:"roles"`
+}
+
+// AllowedDataSources restricts Agents and Directs to the listed kinds and
+// data source types (e.g. Prometheus, Datadog); an empty list allows any
+type AllowedDataSources struct {
+	Kinds []string `yaml:"kinds"`
+	Types []string `yaml:"types"`
+}
+
+// AllowedEmailDomains restricts role binding user emails and email alert
+// method recipients to the listed domains; subdomains are not included
+type AllowedEmailDomains struct {
+	Domains []string `yaml:"domains"`
+}
+
+// Item is an object to check together with the file it was read from
+type Item struct {
+	Object manifest.Object
+	Source string
+}
+
+// Violation is an object breaking a rule
+type Violation struct {
+	RuleID      string
+	Source      string
+	Kind        string
+	Name        string
+	Message     string
+	Remediation string
+}
+
+// String describes the violation for logs
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", v.RuleID, v.Kind, v.Name, v.Message)
+}
+
+// Load reads a policy file, or the embedded default policy for DefaultName
+func Load(path string) (*Policy, error) {
+	if path == DefaultName {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("failed to read policy file %s", path), err)
+	}
+	return parse(data, path)
+}
+
+// Default returns the embedded default policy
+func Default() *Policy {
+	policy, err := parse(defaultPolicy, "")
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded policy: %v", err))
+	}
+	return policy
+}
+
+// ValidateDefault checks the embedded default policy, so a broken build
+// fails at startup rather than when the policy is first used
+func ValidateDefault() error {
+	_, err := parse(defaultPolicy, DefaultName)
+	return err
+}
+
+// Parse parses and checks a policy. Unknown rules are rejected so a typo
+// does not silently disable a guardrail.
+func Parse(data []byte) (*Policy, error) {
+	return parse(data, "")
+}
+
+// parse parses and checks the policy of a file. Errors name the file, if
+// any, and the line of the rule at fault.
+func parse(data []byte, path string) (*Policy, error) {
+	invalid := "invalid policy"
+	if path != "" {
+		invalid = fmt.Sprintf("invalid policy %s", path)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, errors.NewConfigError(invalid, err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, errors.NewConfigError(invalid, err)
+	}
+	ruleError := func(rule, format string, args ...interface{}) error {
+		return errors.NewConfigError(fmt.Sprintf("%s: line %d: rule %s: %s", invalid, ruleLine(&document, rule), rule, fmt.Sprintf(format, args...)), nil)
+	}
+
+	rules := policy.Rules
+	if rules.RequiredProjectLabels != nil {
+		if len(rules.RequiredProjectLabels.Labels) == 0 {
+			return nil, ruleError(RuleRequiredProjectLabels, "labels cannot be empty")
+		}
+		for _, label := range rules.RequiredProjectLabels.Labels {
+			if strings.TrimSpace(label) == "" {
+				return nil, ruleError(RuleRequiredProjectLabels, "empty label name")
+			}
+		}
+	}
+	if rules.NamingPrefix != nil {
+		if rules.NamingPrefix.Label == "" {
+			rules.NamingPrefix.Label = "team"
+		}
+		if len(rules.NamingPrefix.Prefixes) == 0 {
+			return nil, ruleError(RuleNamingPrefix, "prefixes cannot be empty")
+		}
+		teams := make([]string, 0, len(rules.NamingPrefix.Prefixes))
+		for team := range rules.NamingPrefix.Prefixes {
+			teams = append(teams, team)
+		}
+		sort.Strings(teams)
+		for _, team := range teams {
+			if prefix := rules.NamingPrefix.Prefixes[team]; !prefixPattern.MatchString(prefix) {
+				return nil, ruleError(RuleNamingPrefix, "prefix %q of team %s must be lowercase letters, digits and dashes", prefix, team)
+			}
+		}
+	}
+	if rules.MaxUsersPerRole != nil && rules.MaxUsersPerRole.Max <= 0 {
+		return nil, ruleError(RuleMaxUsersPerRole, "max must be positive")
+	}
+	if rules.ForbiddenRoles != nil && len(rules.ForbiddenRoles.Roles) == 0 {
+		return nil, ruleError(RuleForbiddenRoles, "roles cannot be empty")
+	}
+	if rules.AllowedDataSources != nil {
+		for _, kind := range rules.AllowedDataSources.Kinds {
+			parsed, err := manifest.ParseKind(kind)
+			if err != nil || (parsed != manifest.KindAgent && parsed != manifest.KindDirect) {
+				return nil, ruleError(RuleAllowedDataSources, "kind %q must be Agent or Direct", kind)
+			}
+		}
+	}
+
+	if rules.AllowedEmailDomains != nil {
+		if len(rules.AllowedEmailDomains.Domains) == 0 {
+			return nil, ruleError(RuleAllowedEmailDomains, "domains cannot be empty")
+		}
+		for _, domain := range rules.AllowedEmailDomains.Domains {
+			if strings.TrimSpace(domain) == "" || strings.Contains(domain, "@") {
+				return nil, ruleError(RuleAllowedEmailDomains, "invalid domain %q", domain)
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// ruleLine returns the line of a rule in a policy document, or 0 when the
+// rule is not found
+func ruleLine(document *yaml.Node, rule string) int {
+	if len(document.Content) == 0 {
+		return 0
+	}
+	rules := yamlnode.MappingValue(document.Content[0], "rules")
+	if rules == nil {
+		return 0
+	}
+	for i := 0; i+1 < len(rules.Content); i += 2 {
+		if rules.Content[i].Value == rule {
+			return rules.Content[i].Line
+		}
+	}
+	return 0
+}
+
+// Enabled returns the IDs of the rules the policy checks
+func (p *Policy) Enabled() []string {
+	if p == nil {
+		return nil
+	}
+
+	var ids []string
+	if p.Rules.RequiredProjectLabels != nil {
+		ids = append(ids, RuleRequiredProjectLabels)
+	}
+	if p.Rules.NamingPrefix != nil {
+		ids = append(ids, RuleNamingPrefix)
+	}
+	if p.Rules.MaxUsersPerRole != nil {
+		ids = append(ids, RuleMaxUsersPerRole)
+	}
+	if p.Rules.ForbiddenRoles != nil {
+		ids = append(ids, RuleForbiddenRoles)
+	}
+	if p.Rules.AllowedDataSources != nil {
+		ids = append(ids, RuleAllowedDataSources)
+	}
+	if p.Rules.AllowedEmailDomains != nil {
+		ids = append(ids, RuleAllowedEmailDomains)
+	}
+	return ids
+}
+
+// Evaluate checks the objects against every rule. Objects are checked
+// together, since a limit such as max-users-per-role can be exceeded by role
+// bindings spread across files.
+func (p *Policy) Evaluate(items []Item) []Violation {
+	if p == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, item := range items {
+		switch obj := item.Object.(type) {
+		case v1alphaProject.Project:
+			violations = append(violations, p.checkProject(item.Source, obj)...)
+		case v1alphaRoleBinding.RoleBinding:
+			violations = append(violations, p.checkRole(item.Source, obj)...)
+			if obj.Spec.User != nil && strings.Contains(*obj.Spec.User, "@") {
+				violations = append(violations, p.checkEmailDomain(item, "spec.user", *obj.Spec.User)...)
+			}
+		case v1alphaAlertMethod.AlertMethod:
+			for _, recipient := range resolver.Recipients(obj) {
+				violations = append(violations, p.checkEmailDomain(item, recipient.Field, recipient.Email)...)
+			}
+		case v1alphaAgent.Agent:
+			dataSourceType, _ := obj.Spec.GetType()
+			violations = append(violations, p.checkDataSource(item, dataSourceType.String())...)
+		case v1alphaDirect.Direct:
+			dataSourceType, _ := obj.Spec.GetType()
+			violations = append(violations, p.checkDataSource(item, dataSourceType.String())...)
+		}
+	}
+
+	return append(violations, p.checkUsersPerRole(items)...)
+}
+
+// checkProject checks the required labels and the naming prefix
+func (p *Policy) checkProject(source string, project v1alphaProject.Project) []Violation {
+	var violations []Violation
+
+	if rule := p.Rules.RequiredProjectLabels; rule != nil {
+		var missing []string
+		for _, label := range rule.Labels {
+			if len(project.Metadata.Labels[label]) == 0 {
+				missing = append(missing, label)
+			}
+		}
+		if len(missing) > 0 {
+			violations = append(violations, Violation{
+				RuleID:      RuleRequiredProjectLabels,
+				Source:      source,
+				Kind:        manifest.KindProject.String(),
+				Name:        project.Metadata.Name,
+				Message:     fmt.Sprintf("missing required labels: %s", strings.Join(missing, ", ")),
+				Remediation: fmt.Sprintf("add metadata.labels for %s", strings.Join(missing, ", ")),
+			})
+		}
+	}
+
+	if rule := p.Rules.NamingPrefix; rule != nil {
+		for _, team := range project.Metadata.Labels[rule.Label] {
+			prefix, ok := rule.Prefixes[team]
+			if !ok || strings.HasPrefix(project.Metadata.Name, prefix) {
+				continue
+			}
+			violations = append(violations, Violation{
+				RuleID:      RuleNamingPrefix,
+				Source:      source,
+				Kind:        manifest.KindProject.String(),
+				Name:        project.Metadata.Name,
+				Message:     fmt.Sprintf("projects of %s '%s' must be named with prefix '%s'", rule.Label, team, prefix),
+				Remediation: fmt.Sprintf("rename the project to %s%s or correct its %s label", prefix, project.Metadata.Name, rule.Label),
+			})
+		}
+	}
+
+	return violations
+}
+
+// checkRole checks the role binding does not grant a forbidden role
+func (p *Policy) checkRole(source string, roleBinding v1alphaRoleBinding.RoleBinding) []Violation {
+	rule := p.Rules.ForbiddenRoles
+	if rule == nil || !containsFold(rule.Roles, roleBinding.Spec.RoleRef) {
+		return nil
+	}
+
+	return []Violation{{
+		RuleID:      RuleForbiddenRoles,
+		Source:      source,
+		Kind:        manifest.KindRoleBinding.String(),
+		Name:        roleBinding.Metadata.Name,
+		Message:     fmt.Sprintf("role '%s' may not be granted from the repository", roleBinding.Spec.RoleRef),
+		Remediation: "grant a less privileged role, or ask an organization admin to grant this role in Nobl9",
+	}}
+}
+
+// checkDataSource checks the Agent or Direct is of an allowed kind and type
+func (p *Policy) checkDataSource(item Item, dataSourceType string) []Violation {
+	rule := p.Rules.AllowedDataSources
+	if rule == nil {
+		return nil
+	}
+
+	kind := item.Object.GetKind().String()
+	violation := Violation{
+		RuleID: RuleAllowedDataSources,
+		Source: item.Source,
+		Kind:   kind,
+		Name:   item.Object.GetName(),
+	}
+
+	switch {
+	case len(rule.Kinds) > 0 && !containsFold(rule.Kinds, kind):
+		violation.Message = fmt.Sprintf("data sources of kind %s are not allowed", kind)
+		violation.Remediation = fmt.Sprintf("use one of the allowed kinds: %s", strings.Join(rule.Kinds, ", "))
+	case len(rule.Types) > 0 && !containsFold(rule.Types, dataSourceType):
+		violation.Message = fmt.Sprintf("data source type '%s' is not allowed", dataSourceType)
+		violation.Remediation = fmt.Sprintf("use one of the allowed types: %s", strings.Join(rule.Types, ", "))
+	default:
+		return nil
+	}
+	return []Violation{violation}
+}
+
+// checkEmailDomain checks an email of an object is in an allowed domain
+func (p *Policy) checkEmailDomain(item Item, field, email string) []Violation {
+	rule := p.Rules.AllowedEmailDomains
+	if rule == nil || containsFold(rule.Domains, resolver.EmailDomain(email)) {
+		return nil
+	}
+
+	return []Violation{{
+		RuleID:      RuleAllowedEmailDomains,
+		Source:      item.Source,
+		Kind:        item.Object.GetKind().String(),
+		Name:        item.Object.GetName(),
+		Message:     fmt.Sprintf("%s '%s' is not in an allowed email domain", field, email),
+		Remediation: fmt.Sprintf("use an address in one of the allowed domains: %s", strings.Join(rule.Domains, ", ")),
+	}}
+}
+
+// checkUsersPerRole counts the distinct users each project grants each role
+// to and reports the role bindings past the limit
+func (p *Policy) checkUsersPerRole(items []Item) []Violation {
+	rule := p.Rules.MaxUsersPerRole
+	if rule == nil {
+		return nil
+	}
+
+	type grant struct{ project, role string }
+	users := make(map[grant]map[string]bool)
+	var violations []Violation
+
+	for _, item := range items {
+		roleBinding, ok := item.Object.(v1alphaRoleBinding.RoleBinding)
+		if !ok || roleBinding.Spec.ProjectRef == "" {
+			continue
+		}
+		if len(rule.Roles) > 0 && !containsFold(rule.Roles, roleBinding.Spec.RoleRef) {
+			continue
+		}
+
+		subject := ""
+		switch {
+		case roleBinding.Spec.User != nil:
+			subject = *roleBinding.Spec.User
+		case roleBinding.Spec.GroupRef != nil:
+			subject = "group:" + *roleBinding.Spec.GroupRef
+		}
+
+		key := grant{project: roleBinding.Spec.ProjectRef, role: roleBinding.Spec.RoleRef}
+		if users[key] == nil {
+			users[key] = make(map[string]bool)
+		}
+		if users[key][subject] {
+			continue
+		}
+		users[key][subject] = true
+
+		if count := len(users[key]); count > rule.Max {
+			violations = append(violations, Violation{
+				RuleID:      RuleMaxUsersPerRole,
+				Source:      item.Source,
+				Kind:        manifest.KindRoleBinding.String(),
+				Name:        roleBinding.Metadata.Name,
+				Message:     fmt.Sprintf("project '%s' grants role '%s' to %d users, more than the maximum of %d", key.project, key.role, count, rule.Max),
+				Remediation: "grant the role to a user group instead of individual users, or remove users who no longer need it",
+			})
+		}
+	}
+
+	return violations
+}
+
+// Error returns a policy error describing the violations, or nil
+func Error(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]bool)
+	for _, violation := range violations {
+		ids[violation.RuleID] = true
+	}
+	rules := make([]string, 0, len(ids))
+	for id := range ids {
+		rules = append(rules, id)
+	}
+	sort.Strings(rules)
+
+	return errors.NewPolicyErrorWithDetails(
+		fmt.Sprintf("%d policy violations (%s)", len(violations), strings.Join(rules, ", ")),
+		nil,
+		map[string]interface{}{"rules": rules, "violations": len(violations)},
+	)
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+const testPolicy = `rules:
+  required-project-labels:
+    labels: [team, cost-center]
+  naming-prefix:
+    prefixes:
+      payments: pay-
+  max-users-per-role:
+    max: 2
+    roles: [project-owner]
+  forbidden-roles:
+    roles: [organization-admin]
+  allowed-data-sources:
+    kinds: [Agent]
+    types: [Prometheus]
+  allowed-email-domains:
+    domains: [example.com]
+`
+
+func items(t *testing.T, source, data string) []Item {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := make([]Item, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, Item{Object: obj, Source: source})
+	}
+	return result
+}
+
+func roleBindings(role string, users ...string) string {
+	var b strings.Builder
+	for _, user := range users {
+		fmt.Fprintf(&b, `
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: pay-checkout-%s
+  spec:
+    user: %s
+    roleRef: %s
+    projectRef: pay-checkout
+`, user, user, role)
+	}
+	return b.String()
+}
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	objects := items(t, "projects.yaml", `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: pay-checkout
+    labels:
+      team: [payments]
+      cost-center: ["42"]
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: checkout
+    labels:
+      team: [payments]
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: admin
+  spec:
+    user: 00u1
+    roleRef: organization-admin
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: contractor
+  spec:
+    user: bob@contractor.io
+    roleRef: project-viewer
+    projectRef: pay-checkout
+- apiVersion: n9/v1alpha
+  kind: AlertMethod
+  metadata:
+    name: oncall
+    project: pay-checkout
+  spec:
+    email:
+      to: [oncall@Example.com]
+      cc: [pager@vendor.io]
+- apiVersion: n9/v1alpha
+  kind: Agent
+  metadata:
+    name: datadog
+    project: pay-checkout
+  spec:
+    datadog:
+      site: com
+`)
+	// Owners spread across files still count against one limit
+	objects = append(objects, items(t, "owners.yaml", roleBindings("project-owner", "00u1", "00u2", "00u3"))...)
+	objects = append(objects, items(t, "viewers.yaml", roleBindings("project-viewer", "00u4", "00u5", "00u6"))...)
+
+	var got []string
+	for _, violation := range policy.Evaluate(objects) {
+		if violation.Remediation == "" {
+			t.Errorf("expected a remediation hint for %s", violation.RuleID)
+		}
+		got = append(got, violation.Source+" "+violation.RuleID+" "+violation.Name)
+	}
+	expected := []string{
+		"projects.yaml required-project-labels checkout",
+		"projects.yaml naming-prefix checkout",
+		"projects.yaml forbidden-roles admin",
+		"projects.yaml allowed-email-domains contractor",
+		"projects.yaml allowed-email-domains oncall",
+		"projects.yaml allowed-data-sources datadog",
+		"owners.yaml max-users-per-role pay-checkout-00u3",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected violations:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "unknown rule", input: "rules:\n  forbidden-role:\n    roles: [organization-admin]\n"},
+		{name: "non-positive max", input: "rules:\n  max-users-per-role:\n    max: 0\n"},
+		{name: "data source kind", input: "rules:\n  allowed-data-sources:\n    kinds: [SLO]\n"},
+		{name: "no required labels", input: "rules:\n  required-project-labels:\n    labels: []\n"},
+		{name: "invalid prefix", input: "rules:\n  naming-prefix:\n    prefixes:\n      payments: Pay_\n"},
+		{name: "no forbidden roles", input: "rules:\n  forbidden-roles: {}\n"},
+		{name: "no email domains", input: "rules:\n  allowed-email-domains:\n    domains: []\n"},
+		{name: "email as domain", input: "rules:\n  allowed-email-domains:\n    domains: [alice@example.com]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if enabled := Default().Enabled(); len(enabled) == 0 {
+		t.Error("expected the default policy to enable rules")
+	}
+	if err := ValidateDefault(); err != nil {
+		t.Errorf("expected the default policy to be valid, got %v", err)
+	}
+}
+
+func TestLoadErrorLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	data := "rules:\n  forbidden-roles:\n    roles: [organization-admin]\n  max-users-per-role:\n    max: 0\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	expected := path + ": line 4: rule max-users-per-role: max must be positive"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %q in error, got %v", expected, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	if Error(nil) != nil {
+		t.Error("expected no error without violations")
+	}
+
+	err := Error([]Violation{{RuleID: RuleForbiddenRoles}, {RuleID: RuleNamingPrefix}})
+	nobl9Err, ok := err.(*errors.Nobl9Error)
+	if !ok || nobl9Err.Type != errors.ErrorTypePolicy {
+		t.Fatalf("expected a policy error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "forbidden-roles, naming-prefix") {
+		t.Errorf("expected rule IDs in the error, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// RegoPackage is the package Rego policies declare their rules in
+const RegoPackage = "nobl9"
+
+// Verdicts of Rego rules. Rules named deny or deny_<name> fail the file;
+// rules named warn or warn_<name> are only reported.
+const (
+	VerdictDeny = "deny"
+	VerdictWarn = "warn"
+)
+
+// Rego is a set of Rego policies evaluated against each manifest object
+type Rego struct {
+	query   rego.PreparedEvalQuery
+	modules []string
+}
+
+// LoadRego compiles the Rego policies in the given files and directories.
+// Directories are searched recursively for .rego files; _test.rego files
+// are skipped.
+func LoadRego(ctx context.Context, paths []string) (*Rego, error) {
+	var files []string
+	for _, path := range paths {
+		found, err := regoFiles(path)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("failed to read Rego policy %s", path), err)
+		}
+		files = append(files, found...)
+	}
+	if len(files) == 0 {
+		return nil, errors.NewConfigError("no Rego policies found", nil)
+	}
+
+	options := []func(*rego.Rego){rego.Query("data." + RegoPackage)}
+	declared := false
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("failed to read Rego policy %s", file), err)
+		}
+		module, err := ast.ParseModule(file, string(content))
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid Rego policy %s", file), err)
+		}
+		if module.Package.Path.String() == "data."+RegoPackage {
+			declared = true
+		}
+		options = append(options, rego.ParsedModule(module))
+	}
+	if !declared {
+		return nil, errors.NewConfigError(fmt.Sprintf("no Rego policy declares package %s", RegoPackage), nil)
+	}
+
+	query, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, errors.NewConfigError("failed to compile Rego policies", err)
+	}
+
+	return &Rego{query: query, modules: files}, nil
+}
+
+// Modules returns the policy files that were loaded
+func (r *Rego) Modules() []string {
+	return r.modules
+}
+
+// Evaluate runs the Rego rules against each object, with the object as JSON
+// input, and returns the deny and warn verdicts. A nil Rego reports nothing.
+func (r *Rego) Evaluate(ctx context.Context, items []Item) (denies, warnings []Violation, err error) {
+	if r == nil {
+		return nil, nil, nil
+	}
+
+	for _, item := range items {
+		input, err := regoInput(item)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		results, err := r.query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, nil, errors.NewPolicyError(fmt.Sprintf("failed to evaluate Rego policies for %s %s", item.Object.GetKind(), item.Object.GetName()), err)
+		}
+		if len(results) == 0 || len(results[0].Expressions) == 0 {
+			continue
+		}
+		rules, ok := results[0].Expressions[0].Value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, name := range sortedRuleNames(rules) {
+			verdict := ruleVerdict(name)
+			if verdict == "" {
+				continue
+			}
+			for _, message := range ruleMessages(rules[name]) {
+				if message.Msg == "" {
+					message.Msg = fmt.Sprintf("matched Rego rule %s", name)
+				}
+				violation := Violation{
+					RuleID:      name,
+					Source:      item.Source,
+					Kind:        item.Object.GetKind().String(),
+					Name:        item.Object.GetName(),
+					Message:     message.Msg,
+					Remediation: message.Remediation,
+				}
+				if verdict == VerdictDeny {
+					denies = append(denies, violation)
+				} else {
+					warnings = append(warnings, violation)
+				}
+			}
+		}
+	}
+
+	return denies, warnings, nil
+}
+
+// regoMessage is a message of a deny or warn rule. Rules produce either a
+// string or an object with msg and an optional remediation.
+type regoMessage struct {
+	Msg         string
+	Remediation string
+}
+
+// regoFiles returns path if it is a file, or the .rego files below it if
+// it is a directory
+func regoFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(file, ".rego") && !strings.HasSuffix(file, "_test.rego") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// regoInput converts the object to plain JSON values for OPA
+func regoInput(item Item) (interface{}, error) {
+	data, err := json.Marshal(item.Object)
+	if err != nil {
+		return nil, errors.NewPolicyError(fmt.Sprintf("failed to encode %s %s for Rego", item.Object.GetKind(), item.Object.GetName()), err)
+	}
+	var input interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, errors.NewPolicyError(fmt.Sprintf("failed to encode %s %s for Rego", item.Object.GetKind(), item.Object.GetName()), err)
+	}
+	return input, nil
+}
+
+// ruleVerdict returns the verdict of a rule by its name, or "" for rules
+// that are neither deny nor warn rules
+func ruleVerdict(name string) string {
+	for _, verdict := range []string{VerdictDeny, VerdictWarn} {
+		if name == verdict || strings.HasPrefix(name, verdict+"_") {
+			return verdict
+		}
+	}
+	return ""
+}
+
+// ruleMessages returns the messages a rule produced. Sets and arrays hold
+// one message per element; a rule that is simply true has an empty message.
+func ruleMessages(value interface{}) []regoMessage {
+	var values []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		values = v
+	case bool:
+		if !v {
+			return nil
+		}
+		return []regoMessage{{}}
+	default:
+		values = []interface{}{v}
+	}
+
+	messages := make([]regoMessage, 0, len(values))
+	for _, v := range values {
+		switch message := v.(type) {
+		case string:
+			messages = append(messages, regoMessage{Msg: message})
+		case map[string]interface{}:
+			msg, _ := message["msg"].(string)
+			remediation, _ := message["remediation"].(string)
+			if msg == "" {
+				data, _ := json.Marshal(message)
+				msg = string(data)
+			}
+			messages = append(messages, regoMessage{Msg: msg, Remediation: remediation})
+		default:
+			data, _ := json.Marshal(message)
+			messages = append(messages, regoMessage{Msg: string(data)})
+		}
+	}
+	return messages
+}
+
+// sortedRuleNames returns the rule names in a stable order
+func sortedRuleNames(rules map[string]interface{}) []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
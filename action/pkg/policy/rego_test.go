@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testRego = `package nobl9
+
+deny_owner_label contains {"msg": msg, "remediation": "add a team label"} if {
+	input.kind == "Project"
+	not input.metadata.labels.team
+	msg := sprintf("project %s has no team label", [input.metadata.name])
+}
+
+warn contains msg if {
+	input.kind == "Project"
+	object.get(input, ["spec", "description"], "") == ""
+	msg := "project has no description"
+}
+`
+
+func TestRegoEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "projects.rego"), []byte(testRego), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Tests of the policies themselves are not loaded
+	if err := os.WriteFile(filepath.Join(dir, "projects_test.rego"), []byte("package nobl9_test\n\nbroken {"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policies, err := LoadRego(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	denies, warnings, err := policies.Evaluate(context.Background(), items(t, "projects.yaml", `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: billing
+    labels:
+      team: [billing]
+  spec:
+    description: Billing
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(denies) != 1 {
+		t.Fatalf("expected 1 deny, got %+v", denies)
+	}
+	deny := denies[0]
+	if deny.RuleID != "deny_owner_label" || deny.Name != "payments" || deny.Source != "projects.yaml" {
+		t.Errorf("unexpected deny: %+v", deny)
+	}
+	if deny.Message != "project payments has no team label" || deny.Remediation != "add a team label" {
+		t.Errorf("unexpected deny message: %+v", deny)
+	}
+
+	if len(warnings) != 1 || warnings[0].RuleID != "warn" || warnings[0].Name != "payments" {
+		t.Errorf("unexpected warnings: %+v", warnings)
+	}
+}
+
+func TestLoadRegoErrors(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.rego")
+	if err := os.WriteFile(other, []byte("package other\n\ndeny contains \"x\" if { true }\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := LoadRego(context.Background(), []string{other}); err == nil {
+		t.Error("expected an error without a nobl9 package")
+	}
+
+	invalid := filepath.Join(dir, "invalid.rego")
+	if err := os.WriteFile(invalid, []byte("package nobl9\n\ndeny contains"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := LoadRego(context.Background(), []string{invalid}); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+
+	if _, err := LoadRego(context.Background(), []string{filepath.Join(dir, "missing.rego")}); err == nil {
+		t.Error("expected an error for a missing policy")
+	}
+}
+
+func TestRegoEvaluateNil(t *testing.T) {
+	var policies *Rego
+	denies, warnings, err := policies.Evaluate(context.Background(), nil)
+	if err != nil || denies != nil || warnings != nil {
+		t.Errorf("expected nothing from a nil Rego, got %v %v %v", denies, warnings, err)
+	}
+}
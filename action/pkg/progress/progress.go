@@ -0,0 +1,191 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often provisional progress is written by default
+const DefaultInterval = 30 * time.Second
+
+// File statuses reported in a snapshot
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	StatusAborted   = "aborted"
+)
+
+// File is a file the run is done with
+type File struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// Snapshot is the progress of a run at a point in time. Final is only set
+// once the run finished; a snapshot without it is provisional, written
+// while the run was still going or when it was cancelled.
+type Snapshot struct {
+	Final          bool      `json:"final"`
+	Cancelled      bool      `json:"cancelled,omitempty"`
+	Phase          string    `json:"phase,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	TotalFiles     int       `json:"total_files"`
+	FilesDone      int       `json:"files_done"`
+	FilesFailed    int       `json:"files_failed"`
+	ObjectsApplied int       `json:"objects_applied"`
+	Files          []File    `json:"files"`
+}
+
+// Tracker follows the progress of a run and periodically hands snapshots
+// of it to a flush function. It is safe for concurrent use, and the methods
+// of a nil tracker do nothing.
+type Tracker struct {
+	mu       sync.Mutex
+	snapshot Snapshot
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// New creates a tracker for a run of the given number of files
+func New(totalFiles int, startedAt time.Time) *Tracker {
+	return &Tracker{snapshot: Snapshot{
+		StartedAt:  startedAt.UTC(),
+		TotalFiles: totalFiles,
+		Files:      []File{},
+	}}
+}
+
+// SetPhase records the phase the run is in
+func (t *Tracker) SetPhase(phase string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshot.Phase = phase
+}
+
+// AddFile records a file the run is done with
+func (t *Tracker) AddFile(path, status string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshot.FilesDone++
+	if status == StatusFailed {
+		t.snapshot.FilesFailed++
+	}
+	t.snapshot.Files = append(t.snapshot.Files, File{Path: path, Status: status})
+}
+
+// AddObjects counts objects applied, or checked in a dry run
+func (t *Tracker) AddObjects(count int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshot.ObjectsApplied += count
+}
+
+// Snapshot returns the current progress
+func (t *Tracker) Snapshot() Snapshot {
+	if t == nil {
+		return Snapshot{Files: []File{}}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := t.snapshot
+	snapshot.UpdatedAt = time.Now().UTC()
+	snapshot.Files = append([]File{}, t.snapshot.Files...)
+	return snapshot
+}
+
+// Start calls flush with a snapshot every interval in the background until
+// Stop is called. A zero interval flushes nothing until Stop.
+func (t *Tracker) Start(interval time.Duration, flush func(Snapshot)) {
+	if t == nil || interval <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		return
+	}
+
+	t.stop, t.done = make(chan struct{}), make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				flush(t.Snapshot())
+			case <-stop:
+				return
+			}
+		}
+	}(t.stop, t.done)
+}
+
+// Stop stops the periodic flush and waits for a flush in progress, so no
+// provisional snapshot is written after the final one
+func (t *Tracker) Stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	stop, done := t.stop, t.done
+	t.mu.Unlock()
+	if stop == nil {
+		return
+	}
+
+	t.stopOnce.Do(func() { close(stop) })
+	<-done
+}
+
+// Write saves a snapshot as JSON. The file is replaced in a single rename,
+// so a step reading it while the run goes on never sees a partial file.
+func (s Snapshot) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode progress: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create progress directory: %w", err)
+	}
+	temp, err := os.CreateTemp(dir, ".progress-*")
+	if err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write progress file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package progress
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := New(3, start)
+	tracker.SetPhase("parse")
+	tracker.AddFile("projects/broken.yaml", StatusFailed)
+	tracker.SetPhase("apply")
+	tracker.AddObjects(4)
+	tracker.AddFile("projects/payments.yaml", StatusSucceeded)
+
+	snapshot := tracker.Snapshot()
+	if snapshot.Final || snapshot.Phase != "apply" || !snapshot.StartedAt.Equal(start) {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.TotalFiles != 3 || snapshot.FilesDone != 2 || snapshot.FilesFailed != 1 || snapshot.ObjectsApplied != 4 {
+		t.Errorf("unexpected counts %+v", snapshot)
+	}
+	if len(snapshot.Files) != 2 || snapshot.Files[1] != (File{Path: "projects/payments.yaml", Status: StatusSucceeded}) {
+		t.Errorf("unexpected files %+v", snapshot.Files)
+	}
+
+	// Snapshots do not change with the tracker
+	tracker.AddFile("projects/late.yaml", StatusAborted)
+	if len(snapshot.Files) != 2 {
+		t.Errorf("expected the snapshot to keep 2 files, got %d", len(snapshot.Files))
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.SetPhase("parse")
+	tracker.AddFile("projects/payments.yaml", StatusSucceeded)
+	tracker.AddObjects(1)
+	tracker.Start(time.Millisecond, func(Snapshot) { t.Error("expected no flush") })
+	tracker.Stop()
+	if snapshot := tracker.Snapshot(); snapshot.FilesDone != 0 {
+		t.Errorf("expected an empty snapshot, got %+v", snapshot)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	tracker := New(1, time.Now())
+
+	var mu sync.Mutex
+	var flushed []Snapshot
+	tracker.Start(time.Millisecond, func(snapshot Snapshot) {
+		mu.Lock()
+		defer mu.Unlock()
+		flushed = append(flushed, snapshot)
+	})
+	tracker.AddFile("projects/payments.yaml", StatusSucceeded)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		done := len(flushed) > 0 && flushed[len(flushed)-1].FilesDone == 1
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a flush with the done file")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tracker.Stop()
+	mu.Lock()
+	count := len(flushed)
+	mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushed) != count {
+		t.Errorf("expected no flush after Stop, got %d more", len(flushed)-count)
+	}
+
+	// Stopping again does nothing
+	tracker.Stop()
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "progress.json")
+	tracker := New(2, time.Now())
+	tracker.AddFile("projects/payments.yaml", StatusSucceeded)
+	snapshot := tracker.Snapshot()
+	snapshot.Final = true
+
+	if err := snapshot.Write(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var written Snapshot
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !written.Final || written.FilesDone != 1 || len(written.Files) != 1 {
+		t.Errorf("unexpected progress file %s", data)
+	}
+
+	// Only the progress file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the progress file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,390 @@
+package promote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/kindlist"
+	"github.com/your-org/nobl9-action/pkg/planner"
+	"github.com/your-org/nobl9-action/pkg/rename"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what a promotion step does in the target organization
+type Action string
+
+const (
+	// ActionCreate applies an object missing from the target organization
+	ActionCreate Action = "create"
+	// ActionUpdate applies an object over the one in the target organization
+	ActionUpdate Action = "update"
+	// ActionSkip is an object that cannot be promoted
+	ActionSkip Action = "skip"
+)
+
+// DefaultKinds are the project-scoped kinds promoted by default. Role
+// bindings are left out since user IDs differ between organizations.
+var DefaultKinds = []manifest.Kind{
+	manifest.KindService,
+	manifest.KindAlertPolicy,
+	manifest.KindSLO,
+}
+
+// secretKinds hold credentials the API never returns, so exported copies
+// would be incomplete
+var secretKinds = map[manifest.Kind]bool{
+	manifest.KindAgent:       true,
+	manifest.KindDirect:      true,
+	manifest.KindAlertMethod: true,
+}
+
+// Transforms are the environment-specific changes made to objects on their
+// way to the target organization
+type Transforms struct {
+	ProjectPrefix ProjectPrefix    `yaml:"project-prefix"`
+	DataSources   []DataSourceSwap `yaml:"data-sources"`
+}
+
+// ProjectPrefix renames promoted projects by replacing a name prefix, e.g.
+// stg-payments becomes prod-payments
+type ProjectPrefix struct {
+	Remove string `yaml:"remove"`
+	Add    string `yaml:"add"`
+}
+
+// DataSourceSwap points SLOs using one data source at another
+type DataSourceSwap struct {
+	From DataSource `yaml:"from"`
+	To   DataSource `yaml:"to"`
+}
+
+// DataSource is an Agent or Direct referenced by an SLO's metric source.
+// An empty project matches any project in From and keeps the SLO's project
+// in To; an empty kind matches any kind in From and keeps the kind in To.
+type DataSource struct {
+	Name    string `yaml:"name"`
+	Project string `yaml:"project"`
+	Kind    string `yaml:"kind"`
+}
+
+// Step is one object promoted to the target organization
+type Step struct {
+	Action  Action
+	Kind    manifest.Kind
+	Project string
+	Name    string
+	Detail  string
+	// Object is applied by create and update steps
+	Object manifest.Object
+}
+
+// String describes the step for plans and logs
+func (s Step) String() string {
+	target := fmt.Sprintf("%s %s", s.Kind, s.Name)
+	if s.Project != "" {
+		target = fmt.Sprintf("%s %s/%s", s.Kind, s.Project, s.Name)
+	}
+	if s.Detail == "" {
+		return fmt.Sprintf("%s %s", s.Action, target)
+	}
+	return fmt.Sprintf("%s %s (%s)", s.Action, target, s.Detail)
+}
+
+// Source is a project exported from the source organization with its objects
+type Source struct {
+	Project manifest.Object
+	Objects []manifest.Object
+}
+
+// Plan describes the objects applied to the target organization
+type Plan struct {
+	From     string
+	To       string
+	Steps    []Step
+	Warnings []string
+}
+
+// LoadTransforms reads a transforms file; an empty path means no transforms
+func LoadTransforms(filePath string) (*Transforms, error) {
+	transforms := &Transforms{}
+	if filePath == "" {
+		return transforms, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transforms file %s: %w", filePath, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(transforms); err != nil {
+		return nil, fmt.Errorf("invalid transforms file %s: %w", filePath, err)
+	}
+
+	for i, swap := range transforms.DataSources {
+		if swap.From.Name == "" || swap.To.Name == "" {
+			return nil, fmt.Errorf("invalid transforms file %s: data source swap %d needs from.name and to.name", filePath, i+1)
+		}
+	}
+	return transforms, nil
+}
+
+// ProjectName returns the name of a promoted project in the target organization
+func (t *Transforms) ProjectName(name string) string {
+	if t.ProjectPrefix.Remove == "" && t.ProjectPrefix.Add == "" {
+		return name
+	}
+	return t.ProjectPrefix.Add + strings.TrimPrefix(name, t.ProjectPrefix.Remove)
+}
+
+// ParseKinds parses a comma separated list of project-scoped kinds to
+// promote. An empty list selects DefaultKinds.
+func ParseKinds(spec string) ([]manifest.Kind, error) {
+	kinds, err := kindlist.Parse(spec, kindlist.RequireProjectScoped)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kinds) == 0 {
+		return DefaultKinds, nil
+	}
+	return kinds, nil
+}
+
+// MatchProjects returns the names of the projects matching any of the glob
+// patterns (e.g. payments-*), sorted
+func MatchProjects(patterns []string, projects []manifest.Object) ([]string, error) {
+	var matched []string
+	for _, project := range projects {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, project.GetName())
+			if err != nil {
+				return nil, fmt.Errorf("invalid project pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				matched = append(matched, project.GetName())
+				break
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+// Build plans the promotion of the source projects and their objects. Each
+// object is renamed into its target project, has its data sources swapped
+// and is created, or updated when the target organization already has it.
+// Objects holding credentials are skipped.
+func Build(from, to string, sources []Source, existing []manifest.Object, transforms *Transforms) (*Plan, error) {
+	levels, err := planner.New().Levels()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(sources))
+	for _, source := range sources {
+		names[source.Project.GetName()] = transforms.ProjectName(source.Project.GetName())
+	}
+	// Projects are renamed one after another, so a new name must not be
+	// another project's old name or another project's new name
+	promotedAs := make(map[string]string, len(names))
+	for oldName, newName := range names {
+		if _, ok := names[newName]; ok && newName != oldName {
+			return nil, fmt.Errorf("project '%s' would be promoted as '%s', which is another promoted project", oldName, newName)
+		}
+		if other, ok := promotedAs[newName]; ok {
+			return nil, fmt.Errorf("projects '%s' and '%s' would both be promoted as '%s'", other, oldName, newName)
+		}
+		promotedAs[newName] = oldName
+	}
+
+	inTarget := make(map[compare.Key]bool, len(existing))
+	for _, obj := range existing {
+		inTarget[compare.KeyOf(obj)] = true
+	}
+
+	plan := &Plan{From: from, To: to}
+	var objects []Step
+	used := make(map[int]bool)
+
+	for _, source := range sources {
+		project, err := transform(source.Project, names, transforms, used)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, newStep(project, inTarget, source.Project.GetName()))
+
+		for _, obj := range source.Objects {
+			if secretKinds[obj.GetKind()] {
+				objects = append(objects, Step{
+					Action:  ActionSkip,
+					Kind:    obj.GetKind(),
+					Project: names[source.Project.GetName()],
+					Name:    obj.GetName(),
+					Detail:  "credentials are not returned by the API, apply it to " + to + " from a manifest with its secrets",
+				})
+				continue
+			}
+
+			promoted, err := transform(obj, names, transforms, used)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, newStep(promoted, inTarget, ""))
+		}
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return levels[objects[i].Kind] < levels[objects[j].Kind]
+	})
+	plan.Steps = append(plan.Steps, objects...)
+
+	for i, swap := range transforms.DataSources {
+		if !used[i] {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("data source swap %s -> %s matched no SLO", swap.From.Name, swap.To.Name))
+		}
+	}
+	if skipped := plan.Count(ActionSkip); skipped > 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%d objects hold credentials and must already exist in %s for the promoted objects to work", skipped, to))
+	}
+
+	return plan, nil
+}
+
+// Count returns the number of steps with the given action
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Objects returns the objects applied by the plan, in order
+func (p *Plan) Objects() []manifest.Object {
+	var objects []manifest.Object
+	for _, step := range p.Steps {
+		if step.Object != nil {
+			objects = append(objects, step.Object)
+		}
+	}
+	return objects
+}
+
+// newStep returns the create or update step of a promoted object
+func newStep(obj manifest.Object, inTarget map[compare.Key]bool, sourceProject string) Step {
+	step := Step{Action: ActionCreate, Kind: obj.GetKind(), Name: obj.GetName(), Object: obj}
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok {
+		step.Project = scoped.GetProject()
+	}
+	if inTarget[compare.KeyOf(obj)] {
+		step.Action = ActionUpdate
+	}
+	if sourceProject != "" && sourceProject != obj.GetName() {
+		step.Detail = "from " + sourceProject
+	}
+	return step
+}
+
+// transform renames the projects referenced by an object and swaps the data
+// source of SLOs. used records the data source swaps that matched.
+func transform(obj manifest.Object, names map[string]string, transforms *Transforms, used map[int]bool) (manifest.Object, error) {
+	result := obj
+	for oldName, newName := range names {
+		if oldName == newName {
+			continue
+		}
+		copied, err := rename.CopyObject(result, oldName, newName)
+		if err != nil {
+			return nil, err
+		}
+		result = copied
+	}
+
+	if result.GetKind() != manifest.KindSLO || len(transforms.DataSources) == 0 {
+		return result, nil
+	}
+	return swapDataSource(result, names, transforms.DataSources, used)
+}
+
+// swapDataSource points the SLO's metric source at the data source of the
+// first matching swap. Swaps name source projects, so they are compared
+// after the projects have been renamed.
+func swapDataSource(obj manifest.Object, names map[string]string, swaps []DataSourceSwap, used map[int]bool) (manifest.Object, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SLO '%s': %w", obj.GetName(), err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to encode SLO '%s': %w", obj.GetName(), err)
+	}
+
+	spec, _ := document["spec"].(map[string]interface{})
+	indicator, _ := spec["indicator"].(map[string]interface{})
+	metricSource, _ := indicator["metricSource"].(map[string]interface{})
+	if metricSource == nil {
+		return obj, nil
+	}
+
+	sloProject := ""
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok {
+		sloProject = scoped.GetProject()
+	}
+	name, _ := metricSource["name"].(string)
+	project, _ := metricSource["project"].(string)
+	if project == "" {
+		project = sloProject
+	}
+	kind, _ := metricSource["kind"].(string)
+	if kind == "" {
+		kind = manifest.KindAgent.String()
+	}
+
+	for i, swap := range swaps {
+		fromProject := swap.From.Project
+		if renamed, ok := names[fromProject]; ok {
+			fromProject = renamed
+		}
+		if swap.From.Name != name ||
+			(fromProject != "" && fromProject != project) ||
+			(swap.From.Kind != "" && !strings.EqualFold(swap.From.Kind, kind)) {
+			continue
+		}
+
+		used[i] = true
+		metricSource["name"] = swap.To.Name
+		if swap.To.Project != "" {
+			metricSource["project"] = swap.To.Project
+		}
+		if swap.To.Kind != "" {
+			metricSource["kind"] = swap.To.Kind
+		}
+
+		swapped, err := json.Marshal(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode SLO '%s': %w", obj.GetName(), err)
+		}
+		objects, err := sdk.DecodeObjects(swapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode SLO '%s': %w", obj.GetName(), err)
+		}
+		if len(objects) != 1 {
+			return nil, fmt.Errorf("expected one SLO '%s', decoded %d objects", obj.GetName(), len(objects))
+		}
+		return objects[0], nil
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,209 @@
+package promote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	objects, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return objects
+}
+
+const stagingProject = `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: stg-payments
+`
+
+const stagingObjects = `
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout-latency
+    project: stg-payments
+  spec:
+    service: checkout
+    budgetingMethod: Occurrences
+    indicator:
+      metricSource:
+        name: prometheus-staging
+        project: stg-payments
+    timeWindows:
+      - unit: Day
+        count: 28
+        isRolling: true
+    objectives:
+      - displayName: Good
+        value: 1
+        target: 0.99
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: stg-payments
+- apiVersion: n9/v1alpha
+  kind: Agent
+  metadata:
+    name: prometheus-staging
+    project: stg-payments
+  spec:
+    prometheus:
+      url: http://prometheus
+`
+
+func TestBuild(t *testing.T) {
+	transforms := &Transforms{
+		ProjectPrefix: ProjectPrefix{Remove: "stg-", Add: "prod-"},
+		DataSources: []DataSourceSwap{
+			{From: DataSource{Name: "prometheus-staging", Project: "stg-payments"}, To: DataSource{Name: "prometheus-prod"}},
+			{From: DataSource{Name: "datadog"}, To: DataSource{Name: "datadog-prod"}},
+		},
+	}
+	sources := []Source{{
+		Project: decode(t, stagingProject)[0],
+		Objects: decode(t, stagingObjects),
+	}}
+	existing := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: prod-payments
+`)
+
+	plan, err := Build("staging", "prod", sources, existing, transforms)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, step := range plan.Steps {
+		got = append(got, step.String())
+	}
+	want := []string{
+		"create Project prod-payments (from stg-payments)",
+		"update Service prod-payments/checkout",
+		"skip Agent prod-payments/prometheus-staging (credentials are not returned by the API, apply it to prod from a manifest with its secrets)",
+		"create SLO prod-payments/checkout-latency",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected steps %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("step %d: expected %q, got %q", i+1, want[i], got[i])
+		}
+	}
+
+	slo, ok := plan.Steps[3].Object.(v1alphaSLO.SLO)
+	if !ok {
+		t.Fatalf("expected an SLO, got %T", plan.Steps[3].Object)
+	}
+	metricSource := slo.Spec.Indicator.MetricSource
+	if metricSource.Name != "prometheus-prod" || metricSource.Project != "prod-payments" {
+		t.Errorf("unexpected metric source: %+v", metricSource)
+	}
+
+	if len(plan.Objects()) != 3 {
+		t.Errorf("expected 3 objects to apply, got %d", len(plan.Objects()))
+	}
+	if len(plan.Warnings) != 2 {
+		t.Errorf("expected warnings for the unused swap and the skipped agent, got %v", plan.Warnings)
+	}
+}
+
+func TestBuildNameCollision(t *testing.T) {
+	transforms := &Transforms{ProjectPrefix: ProjectPrefix{Remove: "stg-"}}
+	sources := []Source{
+		{Project: decode(t, stagingProject)[0]},
+		{Project: decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+`)[0]},
+	}
+	if _, err := Build("staging", "prod", sources, nil, transforms); err == nil {
+		t.Error("expected an error when two projects are promoted under one name")
+	}
+}
+
+func TestMatchProjects(t *testing.T) {
+	projects := decode(t, `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments-eu
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: billing
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments-us
+`)
+	matched, err := MatchProjects([]string{"payments-*"}, projects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matched) != 2 || matched[0] != "payments-eu" || matched[1] != "payments-us" {
+		t.Errorf("unexpected projects: %v", matched)
+	}
+
+	if _, err := MatchProjects([]string{"[payments"}, projects); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoadTransforms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transforms.yaml")
+	if err := os.WriteFile(path, []byte(`project-prefix:
+  remove: stg-
+  add: prod-
+data-sources:
+  - from: {name: prometheus-staging}
+    to: {name: prometheus-prod, kind: Direct}
+`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transforms, err := LoadTransforms(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transforms.ProjectName("stg-payments") != "prod-payments" || transforms.ProjectName("billing") != "prod-billing" {
+		t.Errorf("unexpected project names: %s, %s", transforms.ProjectName("stg-payments"), transforms.ProjectName("billing"))
+	}
+	if len(transforms.DataSources) != 1 || transforms.DataSources[0].To.Kind != "Direct" {
+		t.Errorf("unexpected data sources: %+v", transforms.DataSources)
+	}
+
+	if err := os.WriteFile(path, []byte("project-prefixes: {}\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := LoadTransforms(path); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds("")
+	if err != nil || len(kinds) != len(DefaultKinds) {
+		t.Errorf("expected the default kinds, got %v, %v", kinds, err)
+	}
+	if _, err := ParseKinds("project"); err == nil {
+		t.Error("expected an error for a kind outside projects")
+	}
+}
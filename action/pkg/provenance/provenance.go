@@ -0,0 +1,194 @@
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+// branchRefPrefix is the prefix of GITHUB_REF for branches
+const branchRefPrefix = "refs/heads/"
+
+// DefaultAllowedEvents are the workflow events allowed to apply by default
+var DefaultAllowedEvents = []string{"push"}
+
+// Policy restricts the branches and workflow events allowed to apply
+// manifests to Nobl9
+type Policy struct {
+	// AllowedBranches are branch names or glob patterns (e.g. "main",
+	// "release/*"); an empty list disables the check
+	AllowedBranches []string
+	// AllowedEvents are GitHub event names (e.g. "push")
+	AllowedEvents []string
+}
+
+// Source describes where the running workflow comes from
+type Source struct {
+	Repository string
+	Ref        string
+	EventName  string
+	Actor      string
+	SHA        string
+	// RunURL links to the running workflow run
+	RunURL string
+	// Description is the text describing the change: the commit messages
+	// of a push, or the title and body of a pull request
+	Description string
+}
+
+// NewPolicy creates a policy from comma separated branch and event lists.
+// Events default to DefaultAllowedEvents.
+func NewPolicy(branches, events string) (*Policy, error) {
+	policy := &Policy{
+		AllowedBranches: commalist.Split(branches),
+		AllowedEvents:   commalist.Split(events),
+	}
+	if len(policy.AllowedEvents) == 0 {
+		policy.AllowedEvents = DefaultAllowedEvents
+	}
+
+	for _, pattern := range policy.AllowedBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid branch pattern %q", pattern), err)
+		}
+	}
+
+	return policy, nil
+}
+
+// SourceFromEnv reads the workflow source from the GitHub Actions environment
+func SourceFromEnv() Source {
+	return Source{
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+		Ref:        os.Getenv("GITHUB_REF"),
+		EventName:  os.Getenv("GITHUB_EVENT_NAME"),
+		Actor:      os.Getenv("GITHUB_ACTOR"),
+		SHA:        os.Getenv("GITHUB_SHA"),
+		RunURL:     workflowRunURL(),
+
+		Description: changeDescription(os.Getenv("GITHUB_EVENT_PATH")),
+	}
+}
+
+// workflowRunURL returns the URL of the running workflow run, or "" outside
+// GitHub Actions
+func workflowRunURL() string {
+	repository, runID := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if repository == "" || runID == "" {
+		return ""
+	}
+	server := os.Getenv("GITHUB_SERVER_URL")
+	if server == "" {
+		server = "https://github.com"
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(server, "/"), repository, runID)
+}
+
+// changeDescription reads the commit messages or the pull request title and
+// body from a GitHub event payload. Missing or unreadable payloads describe
+// nothing.
+func changeDescription(eventPath string) string {
+	if eventPath == "" {
+		return ""
+	}
+	data, err := os.ReadFile(eventPath)
+	if err != nil {
+		return ""
+	}
+
+	var event struct {
+		HeadCommit *struct {
+			Message string `json:"message"`
+		} `json:"head_commit"`
+		Commits []struct {
+			Message string `json:"message"`
+		} `json:"commits"`
+		PullRequest *struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return ""
+	}
+
+	var parts []string
+	if event.PullRequest != nil {
+		parts = append(parts, event.PullRequest.Title, event.PullRequest.Body)
+	}
+	if event.HeadCommit != nil {
+		parts = append(parts, event.HeadCommit.Message)
+	}
+	for _, commit := range event.Commits {
+		parts = append(parts, commit.Message)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Enabled reports whether the policy restricts anything
+func (p *Policy) Enabled() bool {
+	return p != nil && len(p.AllowedBranches) > 0
+}
+
+// Check returns a policy error unless the source is a branch matching an
+// allowed pattern and was triggered by an allowed event
+func (p *Policy) Check(source Source) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	details := map[string]interface{}{
+		"ref":              source.Ref,
+		"event":            source.EventName,
+		"actor":            source.Actor,
+		"sha":              source.SHA,
+		"allowed_branches": strings.Join(p.AllowedBranches, ","),
+		"allowed_events":   strings.Join(p.AllowedEvents, ","),
+	}
+
+	branch, ok := strings.CutPrefix(source.Ref, branchRefPrefix)
+	if !ok {
+		return errors.NewPolicyErrorWithDetails(
+			fmt.Sprintf("refusing to apply: ref %q is not a branch (allowed branches: %s)", source.Ref, strings.Join(p.AllowedBranches, ", ")),
+			nil, details)
+	}
+
+	if !p.allowsBranch(branch) {
+		return errors.NewPolicyErrorWithDetails(
+			fmt.Sprintf("refusing to apply: branch %q is not an allowed branch (allowed branches: %s)", branch, strings.Join(p.AllowedBranches, ", ")),
+			nil, details)
+	}
+
+	if !contains(p.AllowedEvents, source.EventName) {
+		return errors.NewPolicyErrorWithDetails(
+			fmt.Sprintf("refusing to apply: event %q is not an allowed event (allowed events: %s)", source.EventName, strings.Join(p.AllowedEvents, ", ")),
+			nil, details)
+	}
+
+	return nil
+}
+
+// allowsBranch reports whether the branch matches an allowed pattern
+func (p *Policy) allowsBranch(branch string) bool {
+	for _, pattern := range p.AllowedBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether the slice contains the item
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
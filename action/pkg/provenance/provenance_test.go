@@ -0,0 +1,124 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/your-org/nobl9-action/pkg/errors"
+)
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := NewPolicy(" main, release/* ,", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.AllowedBranches) != 2 {
+		t.Errorf("expected 2 branches, got %v", policy.AllowedBranches)
+	}
+	if len(policy.AllowedEvents) != 1 || policy.AllowedEvents[0] != "push" {
+		t.Errorf("expected events to default to push, got %v", policy.AllowedEvents)
+	}
+
+	if _, err := NewPolicy("release/[", ""); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+
+	disabled, err := NewPolicy("", "push")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if disabled.Enabled() {
+		t.Error("expected policy without branches to be disabled")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := NewPolicy("main,release/*", "push")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		source      Source
+		expectError bool
+	}{
+		{name: "push to main", source: Source{Ref: "refs/heads/main", EventName: "push"}},
+		{name: "push to release branch", source: Source{Ref: "refs/heads/release/2024.05", EventName: "push"}},
+		{name: "feature branch", source: Source{Ref: "refs/heads/feature/x", EventName: "push"}, expectError: true},
+		{name: "dispatch from main", source: Source{Ref: "refs/heads/main", EventName: "workflow_dispatch"}, expectError: true},
+		{name: "tag", source: Source{Ref: "refs/tags/main", EventName: "push"}, expectError: true},
+		{name: "pull request", source: Source{Ref: "refs/pull/12/merge", EventName: "pull_request"}, expectError: true},
+		{name: "outside GitHub Actions", source: Source{}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.source)
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			nobl9Err, ok := err.(*errors.Nobl9Error)
+			if !ok || nobl9Err.Type != errors.ErrorTypePolicy {
+				t.Errorf("expected policy error, got %v", err)
+			}
+		})
+	}
+
+	// A disabled policy allows everything
+	var disabled *Policy
+	if err := disabled.Check(Source{Ref: "refs/heads/feature/x"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSourceFromEnv(t *testing.T) {
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_EVENT_NAME", "push")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	t.Setenv("GITHUB_SHA", "abc123")
+	t.Setenv("GITHUB_REPOSITORY", "acme/nobl9-config")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_SERVER_URL", "")
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"head_commit":{"message":"PAY-12 Add checkout SLO"},"commits":[{"message":"Fix typo"}]}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+
+	source := SourceFromEnv()
+	expected := Source{
+		Repository:  "acme/nobl9-config",
+		Ref:         "refs/heads/main",
+		EventName:   "push",
+		Actor:       "octocat",
+		SHA:         "abc123",
+		RunURL:      "https://github.com/acme/nobl9-config/actions/runs/42",
+		Description: "PAY-12 Add checkout SLO\nFix typo",
+	}
+	if source != expected {
+		t.Errorf("expected %+v, got %+v", expected, source)
+	}
+}
+
+func TestChangeDescriptionPullRequest(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request":{"title":"Add checkout SLO","body":"Fixes PAY-12"}}`), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := changeDescription(eventPath); got != "Add checkout SLO\nFixes PAY-12" {
+		t.Errorf("unexpected description: %q", got)
+	}
+	if got := changeDescription(filepath.Join(t.TempDir(), "missing.json")); got != "" {
+		t.Errorf("expected no description for a missing payload, got %q", got)
+	}
+}
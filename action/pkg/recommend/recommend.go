@@ -0,0 +1,189 @@
+package recommend
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Facts are the statistics of a finished run the rules look at
+type Facts struct {
+	// Emails is the number of distinct emails looked up and
+	// UnresolvedEmails the number that did not resolve to a user
+	Emails           int
+	UnresolvedEmails int
+	// PersistentCache is set when the user cache is saved between runs;
+	// CacheMisses counts users looked up in Nobl9 during the run
+	PersistentCache bool
+	CacheMisses     int
+
+	// RateLimited counts 429 responses, BreakerTrips the times the circuit
+	// breaker opened and RetryableErrors the transient errors of the run
+	RateLimited     int
+	BreakerTrips    int
+	RetryableErrors int
+	// AuthErrors counts errors caused by rejected credentials
+	AuthErrors int
+
+	// Files is the number of files scanned; FailedFiles are the paths of
+	// the files that failed, relative to the repository
+	Files       int
+	FailedFiles []string
+	// OtherOrganizationFiles counts files skipped for naming another
+	// organization in their ActionMeta
+	OtherOrganizationFiles int
+	// SkippedObjects counts decoded objects not applied because their kind
+	// is not selected, and SkippedKinds are those kinds
+	SkippedObjects int
+	SkippedKinds   []string
+}
+
+// Recommendation is an action to take, produced by the rule named Rule
+type Recommendation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Rule recommends Advice when Applies holds for the facts of a run
+type Rule struct {
+	Name    string
+	Applies func(f Facts) bool
+	Advice  func(f Facts) string
+}
+
+// Thresholds of the default rules
+const (
+	// unresolvedEmailRatio is the share of emails that must fail to resolve
+	unresolvedEmailRatio = 0.2
+	// cacheMissThreshold is the number of lookups worth persisting
+	cacheMissThreshold = 20
+	// retryThreshold is the number of rate limited or transient failures
+	// that suggest the run is too aggressive for the API
+	retryThreshold = 5
+	// skippedObjectThreshold is the number of skipped objects worth acting on
+	skippedObjectThreshold = 10
+)
+
+// nonManifestDirs are directory names that usually hold example or test
+// files rather than manifests to apply
+var nonManifestDirs = []string{"docs", "example", "examples", "fixtures", "samples", "templates", "test", "testdata", "tests"}
+
+// Rules are the default rules, evaluated in order. Add a Rule here to
+// recommend something new.
+var Rules = []Rule{
+	{
+		Name:    "rotate-credentials",
+		Applies: func(f Facts) bool { return f.AuthErrors > 0 },
+		Advice: func(f Facts) string {
+			return "Nobl9 rejected the client credentials: rotate the access key, update the client-id and client-secret secrets, and check the key still has the roles it needs"
+		},
+	},
+	{
+		Name: "unresolved-emails",
+		Applies: func(f Facts) bool {
+			return f.UnresolvedEmails > 1 && float64(f.UnresolvedEmails) >= unresolvedEmailRatio*float64(f.Emails)
+		},
+		Advice: func(f Facts) string {
+			return fmt.Sprintf("%d of %d emails did not resolve to Nobl9 users: check them for typos, invite the missing users, or normalize them with email-lowercase, email-strip-plus or email-domain-aliases",
+				f.UnresolvedEmails, f.Emails)
+		},
+	},
+	{
+		Name:    "persistent-cache",
+		Applies: func(f Facts) bool { return !f.PersistentCache && f.CacheMisses >= cacheMissThreshold },
+		Advice: func(f Facts) string {
+			return fmt.Sprintf("%d users were looked up in Nobl9: enable a persistent cache with user-cache-file and actions/cache so later runs skip the lookups", f.CacheMisses)
+		},
+	},
+	{
+		Name: "api-retries",
+		Applies: func(f Facts) bool {
+			return f.BreakerTrips > 0 || f.RateLimited+f.RetryableErrors >= retryThreshold
+		},
+		Advice: func(f Facts) string {
+			return fmt.Sprintf("The Nobl9 API was rate limited %d times, failed transiently %d times and tripped the circuit breaker %d times: lower max-rps or spread runs out with concurrency groups",
+				f.RateLimited, f.RetryableErrors, f.BreakerTrips)
+		},
+	},
+	{
+		Name:    "exclude-directory",
+		Applies: func(f Facts) bool { return nonManifestDir(f.FailedFiles) != "" },
+		Advice: func(f Facts) string {
+			dir := nonManifestDir(f.FailedFiles)
+			return fmt.Sprintf("Files under %s/ failed to process: narrow file-pattern (e.g. nobl9/**/*.yaml) so it does not match %s/", dir, dir)
+		},
+	},
+	{
+		Name:    "skipped-objects",
+		Applies: func(f Facts) bool { return f.SkippedObjects >= skippedObjectThreshold },
+		Advice: func(f Facts) string {
+			return fmt.Sprintf("%d objects of kinds %s were skipped: add their kinds to kinds, or move them out of file-pattern",
+				f.SkippedObjects, strings.Join(f.SkippedKinds, ", "))
+		},
+	},
+	{
+		Name:    "other-organizations",
+		Applies: func(f Facts) bool { return f.OtherOrganizationFiles > 0 && 2*f.OtherOrganizationFiles >= f.Files },
+		Advice: func(f Facts) string {
+			return fmt.Sprintf("%d of %d files are for other organizations: run one step per organization with a file-pattern matching only its files",
+				f.OtherOrganizationFiles, f.Files)
+		},
+	},
+}
+
+// Evaluate returns the recommendations of the default rules for a run
+func Evaluate(f Facts) []Recommendation {
+	return EvaluateRules(Rules, f)
+}
+
+// EvaluateRules returns the advice of every rule that applies, in order
+func EvaluateRules(rules []Rule, f Facts) []Recommendation {
+	var recommendations []Recommendation
+	for _, rule := range rules {
+		if rule.Applies(f) {
+			recommendations = append(recommendations, Recommendation{Rule: rule.Name, Message: rule.Advice(f)})
+		}
+	}
+	return recommendations
+}
+
+// nonManifestDir returns the example or test directory most failed files
+// are in, or "" when none is
+func nonManifestDir(files []string) string {
+	counts := make(map[string]int)
+	for _, file := range files {
+		dirs := strings.Split(path.Dir(strings.ReplaceAll(file, "\\", "/")), "/")
+		for i, dir := range dirs {
+			if isNonManifestDir(dir) {
+				counts[strings.Join(dirs[:i+1], "/")]++
+				break
+			}
+		}
+	}
+
+	found := make([]string, 0, len(counts))
+	for dir := range counts {
+		found = append(found, dir)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if counts[found[i]] != counts[found[j]] {
+			return counts[found[i]] > counts[found[j]]
+		}
+		return found[i] < found[j]
+	})
+	if len(found) == 0 {
+		return ""
+	}
+	return found[0]
+}
+
+// isNonManifestDir reports whether a directory name is one of nonManifestDirs
+func isNonManifestDir(name string) bool {
+	for _, dir := range nonManifestDirs {
+		if strings.EqualFold(name, dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package recommend
+
+import (
+	"strings"
+	"testing"
+)
+
+func rules(recommendations []Recommendation) string {
+	names := make([]string, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		names = append(names, recommendation.Rule)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEvaluate(t *testing.T) {
+	healthy := Facts{
+		Emails:           20,
+		UnresolvedEmails: 1,
+		CacheMisses:      50,
+		PersistentCache:  true,
+		RateLimited:      2,
+		Files:            10,
+		FailedFiles:      []string{"nobl9/payments.yaml"},
+		SkippedObjects:   3,
+	}
+	if recommendations := Evaluate(healthy); len(recommendations) != 0 {
+		t.Errorf("expected no recommendations for a healthy run, got %+v", recommendations)
+	}
+
+	tests := []struct {
+		name  string
+		facts Facts
+		rule  string
+		text  string
+	}{
+		{"credentials", Facts{AuthErrors: 1}, "rotate-credentials", "rotate the access key"},
+		{"emails", Facts{Emails: 10, UnresolvedEmails: 4}, "unresolved-emails", "4 of 10 emails"},
+		{"cache", Facts{CacheMisses: 25}, "persistent-cache", "user-cache-file"},
+		{"retries", Facts{RateLimited: 3, RetryableErrors: 2}, "api-retries", "lower max-rps"},
+		{"breaker", Facts{BreakerTrips: 1}, "api-retries", "circuit breaker 1 times"},
+		{"examples", Facts{FailedFiles: []string{"examples/slo.yaml", "nobl9/examples/a.yaml", "nobl9/examples/b.yaml"}}, "exclude-directory", "under nobl9/examples/"},
+		{"skipped", Facts{SkippedObjects: 12, SkippedKinds: []string{"Agent", "SLO"}}, "skipped-objects", "kinds Agent, SLO"},
+		{"organizations", Facts{Files: 4, OtherOrganizationFiles: 2}, "other-organizations", "2 of 4 files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recommendations := Evaluate(tt.facts)
+			if rules(recommendations) != tt.rule {
+				t.Fatalf("expected %s, got %+v", tt.rule, recommendations)
+			}
+			if !strings.Contains(recommendations[0].Message, tt.text) {
+				t.Errorf("expected %q in %q", tt.text, recommendations[0].Message)
+			}
+		})
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	custom := append(Rules, Rule{
+		Name:    "many-files",
+		Applies: func(f Facts) bool { return f.Files > 100 },
+		Advice:  func(f Facts) string { return "split the repository" },
+	})
+
+	recommendations := EvaluateRules(custom, Facts{Files: 200, AuthErrors: 1})
+	if rules(recommendations) != "rotate-credentials,many-files" {
+		t.Errorf("expected the rules in order, got %+v", recommendations)
+	}
+}
@@ -0,0 +1,151 @@
+package refdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Statuses of the objects of a diff
+const (
+	// StatusAdded is an object only the head declares
+	StatusAdded = "added"
+	// StatusRemoved is an object only the base declares
+	StatusRemoved = "removed"
+	// StatusChanged is an object whose fields differ between base and head
+	StatusChanged = "changed"
+)
+
+// Change is a field whose value differs between base and head. Base is nil
+// for a field the head adds and Head is nil for a field the head removes.
+type Change struct {
+	Path string      `json:"path"`
+	Base interface{} `json:"base"`
+	Head interface{} `json:"head"`
+}
+
+// Object is an object added, removed or changed by the head, with the file
+// declaring it: in the head, or in the base for a removed object
+type Object struct {
+	compare.Key
+	Source  string   `json:"source"`
+	Status  string   `json:"status"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Report is the object-level difference between the manifests of two refs
+type Report struct {
+	Base     string   `json:"base"`
+	Head     string   `json:"head"`
+	Compared int      `json:"compared"`
+	Objects  []Object `json:"objects"`
+}
+
+// Compare diffs the objects of the base and head refs, named base and head.
+// Objects are matched by kind, project and name, and compared like drift
+// compares live objects: fields the detector ignores, empty values and key
+// order make no difference. Objects are sorted by kind, project and name.
+func Compare(detector *drift.Detector, baseName, headName string, base, head []planner.Item) (*Report, error) {
+	baseByKey := make(map[compare.Key]planner.Item, len(base))
+	for _, item := range base {
+		baseByKey[compare.KeyOf(item.Object)] = item
+	}
+	headByKey := make(map[compare.Key]planner.Item, len(head))
+	for _, item := range head {
+		headByKey[compare.KeyOf(item.Object)] = item
+	}
+
+	report := &Report{Base: baseName, Head: headName, Objects: []Object{}}
+	for key, item := range headByKey {
+		report.Compared++
+		baseItem, found := baseByKey[key]
+		if !found {
+			report.Objects = append(report.Objects, Object{Key: key, Source: item.Source, Status: StatusAdded})
+			continue
+		}
+		changes, err := detector.Diff(item.Object, baseItem.Object)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		object := Object{Key: key, Source: item.Source, Status: StatusChanged}
+		for _, change := range changes {
+			object.Changes = append(object.Changes, Change{Path: change.Path, Base: change.Live, Head: change.Desired})
+		}
+		report.Objects = append(report.Objects, object)
+	}
+	for key, item := range baseByKey {
+		if _, found := headByKey[key]; !found {
+			report.Compared++
+			report.Objects = append(report.Objects, Object{Key: key, Source: item.Source, Status: StatusRemoved})
+		}
+	}
+
+	sort.Slice(report.Objects, func(i, j int) bool {
+		a, b := report.Objects[i].Key, report.Objects[j].Key
+		if a.Kind != b.Kind {
+			return a.Kind.String() < b.Kind.String()
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// Count returns the number of objects with the status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, object := range r.Objects {
+		if object.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// HasChanges reports whether the head adds, removes or changes any object
+func (r *Report) HasChanges() bool {
+	return len(r.Objects) > 0
+}
+
+// Summary describes the counts of the report, e.g. "1 added, 0 removed,
+// 2 changed"
+func (r *Report) Summary() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed", r.Count(StatusAdded), r.Count(StatusRemoved), r.Count(StatusChanged))
+}
+
+// Markdown renders the report for a pull request comment or job summary
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Nobl9 Manifest Diff\n\n`%s`...`%s`: ", r.Base, r.Head)
+	if !r.HasChanges() {
+		fmt.Fprintf(&b, "no changes to the %d objects.\n", r.Compared)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s of %d objects.\n", r.Summary(), r.Compared)
+	for _, object := range r.Objects {
+		fmt.Fprintf(&b, "\n### %s\n\n", object.Key)
+		switch object.Status {
+		case StatusAdded:
+			fmt.Fprintf(&b, "Added in `%s`.\n", object.Source)
+			continue
+		case StatusRemoved:
+			fmt.Fprintf(&b, "Removed from `%s`.\n", object.Source)
+			continue
+		}
+		fmt.Fprintf(&b, "Changed in `%s`.\n\n| Field | %s | %s |\n|-------|------|------|\n", object.Source, r.Base, r.Head)
+		for _, change := range object.Changes {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", change.Path, drift.MarkdownValue(change.Base), drift.MarkdownValue(change.Head))
+		}
+	}
+	return b.String()
+}
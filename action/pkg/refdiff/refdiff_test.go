@@ -0,0 +1,78 @@
+package refdiff
+
+import (
+	"strings"
+	"testing"
+
+	v1alphaProject "github.com/nobl9/nobl9-go/manifest/v1alpha/project"
+	v1alphaService "github.com/nobl9/nobl9-go/manifest/v1alpha/service"
+	"github.com/your-org/nobl9-action/pkg/drift"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// service returns a service of the payments project with a description
+func service(name, description, source string) planner.Item {
+	return planner.Item{
+		Object: v1alphaService.New(v1alphaService.Metadata{Name: name, Project: "payments"}, v1alphaService.Spec{Description: description}),
+		Source: source,
+	}
+}
+
+func TestCompare(t *testing.T) {
+	project := planner.Item{Object: v1alphaProject.New(v1alphaProject.Metadata{Name: "payments"}, v1alphaProject.Spec{}), Source: "payments.yaml"}
+	base := []planner.Item{
+		project,
+		service("api", "Payments API", "payments.yaml"),
+		service("worker", "Payments worker", "payments.yaml"),
+		service("web", "Checkout web", "payments.yaml"),
+	}
+	head := []planner.Item{
+		project,
+		// Moving an object to another file is not a change
+		service("api", "Payments API", "services/api.yaml"),
+		service("worker", "Settles payments", "payments.yaml"),
+		service("ledger", "Ledger", "payments.yaml"),
+	}
+
+	report, err := Compare(drift.New(), "main", "feature", base, head)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Compared != 5 || report.Summary() != "1 added, 1 removed, 1 changed" {
+		t.Fatalf("unexpected report: %d compared, %s", report.Compared, report.Summary())
+	}
+
+	var keys []string
+	for _, object := range report.Objects {
+		keys = append(keys, object.Key.String()+"="+object.Status)
+	}
+	if got := strings.Join(keys, ","); got != "Service payments/ledger=added,Service payments/web=removed,Service payments/worker=changed" {
+		t.Errorf("unexpected objects: %s", got)
+	}
+	changed := report.Objects[2]
+	if len(changed.Changes) != 1 || changed.Changes[0].Path != "spec.description" || changed.Changes[0].Base != "Payments worker" || changed.Changes[0].Head != "Settles payments" {
+		t.Errorf("unexpected changes: %+v", changed.Changes)
+	}
+
+	markdown := report.Markdown()
+	for _, expected := range []string{"`main`...`feature`: 1 added, 1 removed, 1 changed of 5 objects.", "Removed from `payments.yaml`.", "| `spec.description` | `\"Payments worker\"` | `\"Settles payments\"` |"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in markdown:\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	items := []planner.Item{service("api", "Payments API", "payments.yaml")}
+
+	report, err := Compare(drift.New(), "main", "HEAD", items, items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.HasChanges() {
+		t.Errorf("expected no changes, got %+v", report.Objects)
+	}
+	if markdown := report.Markdown(); !strings.Contains(markdown, "no changes to the 1 objects") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
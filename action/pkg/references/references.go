@@ -0,0 +1,205 @@
+package references
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaAlertPolicy "github.com/nobl9/nobl9-go/manifest/v1alpha/alertpolicy"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Reference is an object another object refers to by name. Project is
+// empty for a reference to a project.
+type Reference struct {
+	Kind    manifest.Kind
+	Project string
+	Name    string
+}
+
+// String describes the reference, e.g. Service payments/checkout or
+// Project payments
+func (r Reference) String() string {
+	if r.Project == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Project, r.Name)
+}
+
+// Field is a reference with the field of the referencing object holding it
+type Field struct {
+	Path string
+	Reference
+}
+
+// Issue is a reference to an object the repository does not declare and,
+// when Nobl9 was checked, Nobl9 does not have either. Expected lists the
+// files declaring the project of the missing object, where it would be
+// expected to be defined.
+type Issue struct {
+	Source    string
+	Kind      manifest.Kind
+	Project   string
+	Name      string
+	Field     string
+	Reference Reference
+	Expected  []string
+	// Live is set when Nobl9 was checked and does not have the object
+	Live bool
+}
+
+// Message describes the issue, naming the file expected to define the
+// missing object
+func (i Issue) Message() string {
+	message := fmt.Sprintf("%s references %s, which is not declared in the repository", i.Field, i.Reference)
+	if i.Live {
+		message += " nor present in Nobl9"
+	}
+	switch {
+	case len(i.Expected) > 0:
+		message += fmt.Sprintf("; expected in %s", strings.Join(i.Expected, ", "))
+	case i.Reference.Kind != manifest.KindProject:
+		message += fmt.Sprintf("; no file declares project %s", i.Reference.Project)
+	}
+	return message
+}
+
+// Lookup reports which of the references exist in Nobl9
+type Lookup func(ctx context.Context, refs []Reference) (map[Reference]bool, error)
+
+// Of returns the objects an object refers to: the project of a project
+// scoped object, the project of a role binding, the service, data source
+// and alert policies of an SLO and the alert methods of an alert policy.
+// Omitted kinds and projects take their defaults: an Agent, and the
+// referencing object's project.
+func Of(obj manifest.Object) []Field {
+	var fields []Field
+	project := ""
+	if scoped, ok := obj.(manifest.ProjectScopedObject); ok {
+		project = scoped.GetProject()
+		if project != "" {
+			fields = append(fields, Field{Path: "metadata.project", Reference: Reference{Kind: manifest.KindProject, Name: project}})
+		}
+	}
+
+	switch typed := obj.(type) {
+	case v1alphaRoleBinding.RoleBinding:
+		if typed.Spec.ProjectRef != "" {
+			fields = append(fields, Field{Path: "spec.projectRef", Reference: Reference{Kind: manifest.KindProject, Name: typed.Spec.ProjectRef}})
+		}
+	case v1alphaSLO.SLO:
+		if typed.Spec.Service != "" {
+			fields = append(fields, Field{Path: "spec.service", Reference: Reference{Kind: manifest.KindService, Project: project, Name: typed.Spec.Service}})
+		}
+		if typed.Spec.Indicator != nil && typed.Spec.Indicator.MetricSource.Name != "" {
+			source := typed.Spec.Indicator.MetricSource
+			ref := Reference{Kind: source.Kind, Project: source.Project, Name: source.Name}
+			if ref.Kind == 0 {
+				ref.Kind = manifest.KindAgent
+			}
+			if ref.Project == "" {
+				ref.Project = project
+			}
+			fields = append(fields, Field{Path: "spec.indicator.metricSource", Reference: ref})
+		}
+		for i, policy := range typed.Spec.AlertPolicies {
+			fields = append(fields, Field{Path: fmt.Sprintf("spec.alertPolicies[%d]", i), Reference: Reference{Kind: manifest.KindAlertPolicy, Project: project, Name: policy}})
+		}
+	case v1alphaAlertPolicy.AlertPolicy:
+		for i, method := range typed.Spec.AlertMethods {
+			ref := Reference{Kind: manifest.KindAlertMethod, Project: method.Metadata.Project, Name: method.Metadata.Name}
+			if ref.Project == "" {
+				ref.Project = project
+			}
+			fields = append(fields, Field{Path: fmt.Sprintf("spec.alertMethods[%d]", i), Reference: ref})
+		}
+	}
+	return fields
+}
+
+// Check returns the references of the items to objects none of the items
+// declare. With a lookup, references Nobl9 has are dropped and the issues
+// are marked Live; without one, the issues are references whose existence
+// in Nobl9 is unknown. Issues are sorted by file, object and field.
+func Check(ctx context.Context, items []planner.Item, lookup Lookup) ([]Issue, error) {
+	declared := make(map[Reference]bool, len(items))
+	projectFiles := make(map[string][]string)
+	for _, item := range items {
+		ref := Reference{Kind: item.Object.GetKind(), Name: item.Object.GetName()}
+		if scoped, ok := item.Object.(manifest.ProjectScopedObject); ok {
+			ref.Project = scoped.GetProject()
+		}
+		declared[ref] = true
+		if ref.Kind == manifest.KindProject && !contains(projectFiles[ref.Name], item.Source) {
+			projectFiles[ref.Name] = append(projectFiles[ref.Name], item.Source)
+		}
+	}
+
+	var issues []Issue
+	var missing []Reference
+	seen := make(map[Reference]bool)
+	for _, item := range items {
+		for _, field := range Of(item.Object) {
+			if declared[field.Reference] {
+				continue
+			}
+			issue := Issue{
+				Source:    item.Source,
+				Kind:      item.Object.GetKind(),
+				Project:   planner.ProjectOf(item.Object),
+				Name:      item.Object.GetName(),
+				Field:     field.Path,
+				Reference: field.Reference,
+				Live:      lookup != nil,
+			}
+			if field.Reference.Kind != manifest.KindProject {
+				issue.Expected = projectFiles[field.Reference.Project]
+			}
+			issues = append(issues, issue)
+			if !seen[field.Reference] {
+				seen[field.Reference] = true
+				missing = append(missing, field.Reference)
+			}
+		}
+	}
+
+	if lookup != nil && len(missing) > 0 {
+		live, err := lookup(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		kept := issues[:0]
+		for _, issue := range issues {
+			if !live[issue.Reference] {
+				kept = append(kept, issue)
+			}
+		}
+		issues = kept
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Name < b.Name
+	})
+	return issues, nil
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package references
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+const testProject = `apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments
+---
+apiVersion: n9/v1alpha
+kind: Service
+metadata:
+  name: checkout
+  project: payments
+`
+
+const testObjects = `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: billing-owner
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: billing
+---
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: latency
+  project: payments
+spec:
+  service: api
+  budgetingMethod: Occurrences
+  alertPolicies: [fast-burn]
+  objectives:
+    - displayName: Good
+      value: 1
+      target: 0.99
+      rawMetric:
+        query:
+          prometheus:
+            promql: latency
+  indicator:
+    metricSource:
+      name: prometheus
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+---
+apiVersion: n9/v1alpha
+kind: SLO
+metadata:
+  name: availability
+  project: payments
+spec:
+  service: checkout
+  budgetingMethod: Occurrences
+  objectives:
+    - displayName: Good
+      value: 1
+      target: 0.99
+      rawMetric:
+        query:
+          prometheus:
+            promql: up
+  indicator:
+    metricSource:
+      name: datadog
+      project: shared
+      kind: Direct
+  timeWindows:
+    - unit: Day
+      count: 28
+      isRolling: true
+---
+apiVersion: n9/v1alpha
+kind: AlertPolicy
+metadata:
+  name: fast-burn
+  project: payments
+spec:
+  severity: High
+  coolDown: 5m
+  conditions:
+    - measurement: averageBurnRate
+      value: 2
+      alertingWindow: 5m
+  alertMethods:
+    - metadata:
+        name: pagerduty
+`
+
+func testItems(t *testing.T) []planner.Item {
+	t.Helper()
+	var items []planner.Item
+	for source, content := range map[string]string{"payments/project.yaml": testProject, "payments/objects.yaml": testObjects} {
+		objects, err := sdk.DecodeObjects([]byte(content))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, obj := range objects {
+			items = append(items, planner.Item{Object: obj, Source: source})
+		}
+	}
+	return items
+}
+
+func TestCheck(t *testing.T) {
+	issues, err := Check(context.Background(), testItems(t), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		if issue.Source != "payments/objects.yaml" || issue.Live {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+		got = append(got, issue.Name+" "+issue.Message())
+	}
+	want := []string{
+		"billing-owner spec.projectRef references Project billing, which is not declared in the repository",
+		"availability spec.indicator.metricSource references Direct shared/datadog, which is not declared in the repository; no file declares project shared",
+		"fast-burn spec.alertMethods[0] references AlertMethod payments/pagerduty, which is not declared in the repository; expected in payments/project.yaml",
+		"latency spec.service references Service payments/api, which is not declared in the repository; expected in payments/project.yaml",
+		"latency spec.indicator.metricSource references Agent payments/prometheus, which is not declared in the repository; expected in payments/project.yaml",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestCheckLookup(t *testing.T) {
+	var looked []Reference
+	lookup := func(_ context.Context, refs []Reference) (map[Reference]bool, error) {
+		looked = refs
+		return map[Reference]bool{
+			{Kind: manifest.KindProject, Name: "billing"}:                            true,
+			{Kind: manifest.KindAgent, Project: "payments", Name: "prometheus"}:      true,
+			{Kind: manifest.KindDirect, Project: "shared", Name: "datadog"}:          true,
+			{Kind: manifest.KindAlertMethod, Project: "payments", Name: "pagerduty"}: true,
+		}, nil
+	}
+	issues, err := Check(context.Background(), testItems(t), lookup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(looked) != 5 {
+		t.Errorf("expected every undeclared reference looked up once, got %v", looked)
+	}
+	if len(issues) != 1 || !issues[0].Live || issues[0].Reference.Name != "api" {
+		t.Fatalf("expected the missing service, got %+v", issues)
+	}
+	if want := "nor present in Nobl9"; !strings.Contains(issues[0].Message(), want) {
+		t.Errorf("expected %q in %s", want, issues[0].Message())
+	}
+}
@@ -0,0 +1,201 @@
+package rename
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/compare"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Action is what a migration step does in Nobl9
+type Action string
+
+const (
+	// ActionCreate creates the new project
+	ActionCreate Action = "create"
+	// ActionCopy applies a copy of a live object in the new project
+	ActionCopy Action = "copy"
+	// ActionManual is an object that must be recreated by hand
+	ActionManual Action = "manual"
+	// ActionKeep is an object already present in the new project
+	ActionKeep Action = "keep"
+	// ActionDelete deletes the old project and everything left in it
+	ActionDelete Action = "delete"
+)
+
+// secretKinds hold credentials the API never returns, so copies would be
+// incomplete
+var secretKinds = map[manifest.Kind]bool{
+	manifest.KindAgent:       true,
+	manifest.KindDirect:      true,
+	manifest.KindAlertMethod: true,
+}
+
+// FileChange is a manifest rewritten for the new project name
+type FileChange struct {
+	Path       string
+	References []Reference
+	Content    []byte
+}
+
+// Step is one live-side migration step
+type Step struct {
+	Action  Action
+	Kind    manifest.Kind
+	Name    string
+	Project string
+	Detail  string
+	// Object is applied by create and copy steps
+	Object manifest.Object
+}
+
+// String describes the step for plans and logs
+func (s Step) String() string {
+	target := fmt.Sprintf("%s %s", s.Kind, s.Name)
+	if s.Project != "" {
+		target = fmt.Sprintf("%s %s/%s", s.Kind, s.Project, s.Name)
+	}
+	if s.Detail == "" {
+		return fmt.Sprintf("%s %s", s.Action, target)
+	}
+	return fmt.Sprintf("%s %s (%s)", s.Action, target, s.Detail)
+}
+
+// Plan describes how to move everything from one project to another
+type Plan struct {
+	OldProject string
+	NewProject string
+	Files      []FileChange
+	Steps      []Step
+	Warnings   []string
+}
+
+// CopyObject returns a copy of a live object with every reference to the
+// old project replaced by the new one
+func CopyObject(obj manifest.Object, oldName, newName string) (manifest.Object, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s '%s': %w", obj.GetKind(), obj.GetName(), err)
+	}
+
+	// JSON is YAML, so the manifest rewriter handles it as well
+	rewritten, _, err := RewriteProject(data, oldName, newName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s '%s': %w", obj.GetKind(), obj.GetName(), err)
+	}
+
+	objects, err := sdk.DecodeObjects(rewritten)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s '%s': %w", obj.GetKind(), obj.GetName(), err)
+	}
+	if len(objects) != 1 {
+		return nil, fmt.Errorf("expected one %s '%s', decoded %d objects", obj.GetKind(), obj.GetName(), len(objects))
+	}
+	return objects[0], nil
+}
+
+// Live is the state of both projects in Nobl9
+type Live struct {
+	// Project is the live old project
+	Project manifest.Object
+	// Objects are the live objects of the old project
+	Objects []manifest.Object
+	// NewProjectExists reports whether the new project was already created
+	NewProjectExists bool
+	// Existing are the live objects already in the new project
+	Existing []manifest.Object
+}
+
+// BuildSteps returns the live-side steps of a rename: create the new project
+// from the old one, copy every live object of the old project in dependency
+// order and delete the old project. Objects already in the new project are
+// kept, so a rename interrupted halfway can be planned again. Objects with
+// credentials the API does not return are listed as manual steps.
+func BuildSteps(live Live, oldName, newName string) ([]Step, []string, error) {
+	levels, err := planner.New().Levels()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	var steps []Step
+
+	if live.NewProjectExists {
+		steps = append(steps, Step{Action: ActionKeep, Kind: manifest.KindProject, Name: newName, Detail: "already exists"})
+	} else {
+		project, err := CopyObject(live.Project, oldName, newName)
+		if err != nil {
+			return nil, nil, err
+		}
+		steps = append(steps, Step{Action: ActionCreate, Kind: manifest.KindProject, Name: newName, Detail: "copy of " + oldName, Object: project})
+	}
+
+	existing := make(map[compare.Key]bool, len(live.Existing))
+	for _, obj := range live.Existing {
+		existing[keyIn(obj, newName)] = true
+	}
+
+	ordered := make([]manifest.Object, len(live.Objects))
+	copy(ordered, live.Objects)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return levels[ordered[i].GetKind()] < levels[ordered[j].GetKind()]
+	})
+
+	var slos int
+	for _, obj := range ordered {
+		kind := obj.GetKind()
+		step := Step{Kind: kind, Name: obj.GetName(), Project: newName}
+
+		switch {
+		case existing[keyIn(obj, newName)]:
+			step.Action = ActionKeep
+			step.Detail = "already in " + newName
+		case secretKinds[kind]:
+			step.Action = ActionManual
+			step.Detail = "credentials are not returned by the API, apply it from a manifest with its secrets"
+		default:
+			copied, err := CopyObject(obj, oldName, newName)
+			if err != nil {
+				return nil, nil, err
+			}
+			step.Action = ActionCopy
+			step.Object = copied
+			if kind == manifest.KindSLO {
+				slos++
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	steps = append(steps, Step{Action: ActionDelete, Kind: manifest.KindProject, Name: oldName, Detail: "deletes every object left in it"})
+
+	if slos > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d SLOs are copied without their history; reliability data of project '%s' is lost when it is deleted", slos, oldName))
+	}
+
+	return steps, warnings, nil
+}
+
+// Count returns the number of steps with the given action
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// keyIn returns the key an object would have in another project
+func keyIn(obj manifest.Object, project string) compare.Key {
+	key := compare.KeyOf(obj)
+	if key.Project != "" {
+		key.Project = project
+	}
+	return key
+}
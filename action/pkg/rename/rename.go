@@ -0,0 +1,220 @@
+package rename
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reference is a reference to the renamed project found in a manifest
+type Reference struct {
+	Line int    // line of the value in the file
+	Kind string // kind of the object containing the reference
+	Name string // name of the object containing the reference
+	Path string // path of the field, e.g. "spec.projectRef"
+}
+
+// String describes the reference for plans and logs
+func (r Reference) String() string {
+	return fmt.Sprintf("line %d: %s %s %s", r.Line, r.Kind, r.Name, r.Path)
+}
+
+// projectKeys are the fields that reference a project by name: metadata.project
+// of project scoped objects, spec.projectRef of role bindings and the project
+// of nested references such as composite SLO components, metric sources and
+// alert methods
+var projectKeys = map[string]bool{
+	"project":    true,
+	"projectRef": true,
+}
+
+// skippedPaths hold free-form user data that is never rewritten
+var skippedPaths = map[string]bool{
+	"metadata.labels":      true,
+	"metadata.annotations": true,
+}
+
+// edit replaces a scalar value at a position in the content
+type edit struct {
+	line   int
+	column int
+	quoted bool
+}
+
+// RewriteProject replaces every reference to the project oldName with
+// newName in YAML manifest content. Only the referencing values are changed,
+// so comments, ordering and formatting are preserved. It returns the
+// rewritten content and the references it replaced.
+func RewriteProject(content []byte, oldName, newName string) ([]byte, []Reference, error) {
+	if oldName == "" || newName == "" {
+		return nil, nil, fmt.Errorf("project names cannot be empty")
+	}
+
+	var references []Reference
+	var edits []edit
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		root := doc.Content[0]
+		objects := []*yaml.Node{root}
+		if root.Kind == yaml.SequenceNode {
+			objects = root.Content
+		}
+
+		for _, obj := range objects {
+			if obj.Kind != yaml.MappingNode {
+				continue
+			}
+			found := findReferences(obj, oldName)
+			for _, node := range found {
+				references = append(references, Reference{
+					Line: node.value.Line,
+					Kind: scalarAt(obj, "kind"),
+					Name: scalarAt(obj, "metadata", "name"),
+					Path: node.path,
+				})
+				edits = append(edits, edit{
+					line:   node.value.Line,
+					column: node.value.Column,
+					quoted: node.value.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0,
+				})
+			}
+		}
+	}
+
+	if len(edits) == 0 {
+		return content, nil, nil
+	}
+
+	rewritten, err := applyEdits(content, edits, oldName, newName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewritten, references, nil
+}
+
+// foundValue is a scalar referencing the project and its field path
+type foundValue struct {
+	value *yaml.Node
+	path  string
+}
+
+// findReferences returns the values in an object that reference the project
+func findReferences(obj *yaml.Node, project string) []foundValue {
+	var found []foundValue
+
+	// A Project is referenced by its own name
+	if scalarAt(obj, "kind") == "Project" {
+		if name := nodeAt(obj, "metadata", "name"); name != nil && name.Value == project {
+			found = append(found, foundValue{value: name, path: "metadata.name"})
+		}
+	}
+
+	walk(obj, "", func(key string, value *yaml.Node, path string) {
+		if projectKeys[key] && value.Kind == yaml.ScalarNode && value.Value == project {
+			found = append(found, foundValue{value: value, path: path})
+		}
+	})
+
+	return found
+}
+
+// walk calls fn for every mapping entry below node, skipping free-form data
+func walk(node *yaml.Node, path string, fn func(key string, value *yaml.Node, path string)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if skippedPaths[childPath] {
+				continue
+			}
+			fn(key, value, childPath)
+			walk(value, childPath, fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			walk(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+// nodeAt returns the value at the given mapping keys, or nil
+func nodeAt(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// scalarAt returns the scalar value at the given mapping keys, or ""
+func scalarAt(node *yaml.Node, keys ...string) string {
+	if value := nodeAt(node, keys...); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
+
+// applyEdits replaces oldName with newName at each edit position. Positions
+// are 1-based lines and columns counted in characters, as reported by the
+// YAML parser.
+func applyEdits(content []byte, edits []edit, oldName, newName string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+
+	byLine := make(map[int][]edit)
+	for _, e := range edits {
+		byLine[e.line] = append(byLine[e.line], e)
+	}
+
+	for lineNumber, lineEdits := range byLine {
+		if lineNumber < 1 || lineNumber > len(lines) {
+			return nil, fmt.Errorf("reference on line %d is outside the file", lineNumber)
+		}
+		runes := []rune(lines[lineNumber-1])
+		// Apply edits right to left so earlier columns on the line stay valid
+		sort.Slice(lineEdits, func(i, j int) bool { return lineEdits[i].column < lineEdits[j].column })
+		for i := len(lineEdits) - 1; i >= 0; i-- {
+			start := lineEdits[i].column - 1
+			if lineEdits[i].quoted {
+				start++
+			}
+			end := start + len([]rune(oldName))
+			if start < 0 || end > len(runes) || string(runes[start:end]) != oldName {
+				return nil, fmt.Errorf("unexpected value on line %d, expected %q", lineNumber, oldName)
+			}
+			runes = append(runes[:start], append([]rune(newName), runes[end:]...)...)
+		}
+		lines[lineNumber-1] = string(runes)
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
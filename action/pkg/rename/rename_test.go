@@ -0,0 +1,182 @@
+package rename
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const testManifests = `# Payments project
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: payments # keep this comment
+  labels:
+    project: [payments]
+---
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: "00u1"
+    roleRef: project-owner
+    projectRef: "payments"
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout
+    project: 'payments'
+  spec:
+    service: checkout
+    objectives:
+      - displayName: Composite
+        composite:
+          components:
+            objectives:
+              - project: payments
+                slo: api
+                objective: good
+              - project: payments-legacy
+                slo: api
+                objective: good
+`
+
+func TestRewriteProject(t *testing.T) {
+	rewritten, references, err := RewriteProject([]byte(testManifests), "payments", "billing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := strings.NewReplacer(
+		"name: payments # keep", "name: billing # keep",
+		`projectRef: "payments"`, `projectRef: "billing"`,
+		"project: 'payments'", "project: 'billing'",
+		"- project: payments\n", "- project: billing\n",
+	).Replace(testManifests)
+	if string(rewritten) != expected {
+		t.Errorf("unexpected content:\n%s", rewritten)
+	}
+
+	paths := make([]string, 0, len(references))
+	for _, reference := range references {
+		paths = append(paths, reference.Kind+" "+reference.Path)
+	}
+	want := []string{
+		"Project metadata.name",
+		"RoleBinding spec.projectRef",
+		"SLO metadata.project",
+		"SLO spec.objectives[0].composite.components.objectives[0].project",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected references:\n%s", strings.Join(paths, "\n"))
+	}
+}
+
+func TestRewriteProjectUnchanged(t *testing.T) {
+	content := []byte("kind: Project\nmetadata:\n  name: other\n")
+	rewritten, references, err := RewriteProject(content, "payments", "billing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(references) != 0 || string(rewritten) != string(content) {
+		t.Errorf("expected content to be unchanged, got %d references", len(references))
+	}
+
+	if _, _, err := RewriteProject([]byte("kind: [unclosed"), "payments", "billing"); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestBuildSteps(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(`
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: SLO
+  metadata:
+    name: checkout
+    project: payments
+  spec:
+    service: checkout
+    budgetingMethod: Occurrences
+    objectives:
+      - displayName: Good
+        value: 1
+        target: 0.99
+        rawMetric:
+          query:
+            prometheus:
+              promql: up
+    indicator:
+      metricSource:
+        name: prometheus
+        project: payments
+    timeWindows:
+      - unit: Day
+        count: 28
+        isRolling: true
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+- apiVersion: n9/v1alpha
+  kind: Agent
+  metadata:
+    name: prometheus
+    project: payments
+  spec:
+    prometheus:
+      url: http://prometheus
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	steps, warnings, err := BuildSteps(Live{Project: objects[0], Objects: objects[1:]}, "payments", "billing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var described []string
+	for _, step := range steps {
+		described = append(described, string(step.Action)+" "+step.Kind.String())
+	}
+	want := []string{
+		"create Project",
+		"copy Service",
+		"manual Agent",
+		"copy SLO",
+		"delete Project",
+	}
+	if strings.Join(described, ", ") != strings.Join(want, ", ") {
+		t.Errorf("unexpected steps: %s", strings.Join(described, ", "))
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning about SLO history, got %v", warnings)
+	}
+
+	slo := steps[3].Object.(manifest.ProjectScopedObject)
+	if slo.GetProject() != "billing" {
+		t.Errorf("expected copied SLO in billing, got %s", slo.GetProject())
+	}
+
+	// Objects already in the new project are kept
+	steps, _, err = BuildSteps(Live{
+		Project:          objects[0],
+		Objects:          objects[2:3],
+		NewProjectExists: true,
+		Existing:         objects[2:3],
+	}, "payments", "billing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if steps[0].Action != ActionKeep || steps[1].Action != ActionKeep {
+		t.Errorf("expected existing objects to be kept, got %v", steps)
+	}
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"github.com/google/go-jsonnet"
+	"gopkg.in/yaml.v3"
+)
+
+// Engine is a language manifests are rendered from
+type Engine string
+
+const (
+	// EngineNone reads manifests as written
+	EngineNone Engine = ""
+	// EngineJsonnet evaluates .jsonnet files
+	EngineJsonnet Engine = "jsonnet"
+	// EngineCUE evaluates .cue files
+	EngineCUE Engine = "cue"
+)
+
+// Engines are the engines --render accepts
+var Engines = []Engine{EngineJsonnet, EngineCUE}
+
+// ParseEngine parses the value of --render; an empty value renders nothing
+func ParseEngine(value string) (Engine, error) {
+	engine := Engine(strings.ToLower(strings.TrimSpace(value)))
+	if engine == EngineNone {
+		return EngineNone, nil
+	}
+	for _, known := range Engines {
+		if engine == known {
+			return engine, nil
+		}
+	}
+	return EngineNone, fmt.Errorf("unknown render engine '%s', expected jsonnet or cue", value)
+}
+
+// Extension returns the extension of the files the engine renders
+func (e Engine) Extension() string {
+	if e == EngineNone {
+		return ""
+	}
+	return "." + string(e)
+}
+
+// IsSource reports whether the engine renders the file
+func (e Engine) IsSource(path string) bool {
+	return e != EngineNone && strings.ToLower(filepath.Ext(path)) == e.Extension()
+}
+
+// Render evaluates the content of a source file into YAML. The file
+// evaluates to an object, which becomes one document, or a list of objects,
+// which become one document each, so an ActionMeta document can be rendered
+// alongside the objects. Jsonnet imports are resolved relative to the file;
+// CUE files are evaluated on their own, without imports.
+func (e Engine) Render(path string, content []byte) ([]byte, error) {
+	var data []byte
+	var err error
+	switch e {
+	case EngineJsonnet:
+		data, err = evaluateJsonnet(path, content)
+	case EngineCUE:
+		data, err = evaluateCUE(path, content)
+	default:
+		return nil, fmt.Errorf("unknown render engine '%s'", e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toYAML(data)
+}
+
+// evaluateJsonnet evaluates a Jsonnet file into JSON
+func evaluateJsonnet(path string, content []byte) ([]byte, error) {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: []string{filepath.Dir(path)}})
+	output, err := vm.EvaluateAnonymousSnippet(path, string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate Jsonnet: %w", err)
+	}
+	return []byte(output), nil
+}
+
+// evaluateCUE evaluates a CUE file into JSON. Every value must be concrete.
+func evaluateCUE(path string, content []byte) ([]byte, error) {
+	value := cuecontext.New().CompileBytes(content, cue.Filename(path))
+	if err := value.Validate(cue.Concrete(true)); err != nil {
+		return nil, fmt.Errorf("failed to evaluate CUE: %s", errors.Details(err, nil))
+	}
+	data, err := value.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate CUE: %s", errors.Details(err, nil))
+	}
+	return data, nil
+}
+
+// toYAML converts the JSON a file evaluated to into YAML documents
+func toYAML(data []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to read rendered output: %w", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	documents := []*yaml.Node{root.Content[0]}
+	if root.Content[0].Kind == yaml.SequenceNode {
+		documents = root.Content[0].Content
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if document.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("rendered output is not an object or a list of objects")
+		}
+		defaultStyle(document)
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode rendered output: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode rendered output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultStyle drops the flow style and quotes of a node read from JSON,
+// and of every node below it, so the rendered YAML reads like a hand
+// written manifest. Strings that would read as another type stay quoted.
+func defaultStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		defaultStyle(child)
+	}
+}
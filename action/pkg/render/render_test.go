@@ -0,0 +1,92 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEngine(t *testing.T) {
+	for value, expected := range map[string]Engine{"": EngineNone, "jsonnet": EngineJsonnet, " CUE ": EngineCUE} {
+		engine, err := ParseEngine(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, engine)
+	}
+	_, err := ParseEngine("helm")
+	assert.ErrorContains(t, err, "unknown render engine 'helm'")
+}
+
+func TestIsSource(t *testing.T) {
+	assert.True(t, EngineJsonnet.IsSource("nobl9/projects.jsonnet"))
+	assert.True(t, EngineCUE.IsSource("nobl9/projects.CUE"))
+	assert.False(t, EngineJsonnet.IsSource("nobl9/projects.cue"))
+	assert.False(t, EngineNone.IsSource("nobl9/projects.yaml"))
+}
+
+func TestRenderJsonnet(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "teams.libsonnet"), []byte(`["payments", "billing"]`), 0o600))
+	path := filepath.Join(dir, "projects.jsonnet")
+	content := []byte(`
+local teams = import 'teams.libsonnet';
+[
+  {
+    apiVersion: 'n9/v1alpha',
+    kind: 'Project',
+    metadata: { name: team },
+    spec: { description: 'Owned by ' + team },
+  }
+  for team in teams
+]
+`)
+
+	rendered, err := EngineJsonnet.Render(path, content)
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "kind: Project\n")
+	assert.Contains(t, string(rendered), "  description: Owned by payments\n")
+	objects, err := sdk.DecodeObjects(rendered)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "payments", objects[0].GetName())
+	assert.Equal(t, "billing", objects[1].GetName())
+
+	_, err = EngineJsonnet.Render(path, []byte(`{ name: error 'no team' }`))
+	assert.ErrorContains(t, err, "no team")
+}
+
+func TestRenderCUE(t *testing.T) {
+	content := []byte(`
+#Project: {
+	apiVersion: "n9/v1alpha"
+	kind:       "Project"
+	metadata: {
+		name: string
+		labels?: [string]: [...string]
+	}
+	spec: description: "Owned by \(metadata.name)"
+}
+
+#Project & {metadata: {name: "payments", labels: version: ["1.0"]}}
+`)
+
+	rendered, err := EngineCUE.Render("projects.cue", content)
+	require.NoError(t, err)
+	objects, err := sdk.DecodeObjects(rendered)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "payments", objects[0].GetName())
+	assert.Contains(t, string(rendered), `- "1.0"`, "strings that read as numbers stay quoted")
+
+	// Values left open would silently render nothing
+	_, err = EngineCUE.Render("projects.cue", []byte(`metadata: name: string`))
+	assert.ErrorContains(t, err, "failed to evaluate CUE")
+}
+
+func TestRenderRejectsScalars(t *testing.T) {
+	_, err := EngineJsonnet.Render("value.jsonnet", []byte(`[1, 2]`))
+	assert.ErrorContains(t, err, "not an object")
+}
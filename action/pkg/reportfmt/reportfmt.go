@@ -0,0 +1,22 @@
+// Package reportfmt formats the values of the Markdown and text reports,
+// such as the access review, the SLO inventory and run history, so every
+// report writes them the same way.
+package reportfmt
+
+import "fmt"
+
+// OrDash returns the value, or "-" for an empty table cell
+func OrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// Plural formats a count with its noun, e.g. "1 SLO" or "3 SLOs"
+func Plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,21 @@
+package reportfmt
+
+import "testing"
+
+func TestOrDash(t *testing.T) {
+	if OrDash("") != "-" || OrDash("payments") != "payments" {
+		t.Errorf("unexpected values %q, %q", OrDash(""), OrDash("payments"))
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for expected, actual := range map[string]string{
+		"0 SLOs":  Plural(0, "SLO"),
+		"1 user":  Plural(1, "user"),
+		"3 users": Plural(3, "user"),
+	} {
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
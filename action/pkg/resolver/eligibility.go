@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolvablePaths are the fields, as Kind:path, whose emails can be resolved
+// to user IDs. users[].id also matches plain scalar list items.
+var ResolvablePaths = []string{
+	"RoleBinding:spec.user",
+	"RoleBinding:spec.users[].id",
+	"RoleBinding:spec.userIds",
+}
+
+// protectedKinds hold emails the Nobl9 API expects as written; they are
+// never rewritten, whatever the configuration
+var protectedKinds = map[string]string{
+	"AlertMethod": "alert methods send notifications to the addresses as written",
+	"Annotation":  "annotation text is free-form and kept as written",
+	"Report":      "reports are sent to the addresses as written",
+}
+
+// Eligibility is the set of Kind:path fields whose emails are resolved to
+// user IDs. A nil Eligibility allows every resolvable path.
+type Eligibility map[string]bool
+
+// ParseEligibility parses a comma separated list of fields eligible for
+// email resolution. Each entry is Kind:path, e.g. RoleBinding:spec.user, or
+// a kind alone for all of its resolvable paths. An empty list or "all"
+// allows every resolvable path and "none" disables resolution.
+func ParseEligibility(spec string) (Eligibility, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		return nil, nil
+	}
+	if strings.EqualFold(spec, "none") {
+		return Eligibility{}, nil
+	}
+
+	eligibility := make(Eligibility)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, path, hasPath := strings.Cut(entry, ":")
+		kind, path = strings.TrimSpace(kind), strings.TrimSpace(path)
+		if reason, protected := protectedKinds[canonicalKind(kind)]; protected {
+			return nil, fmt.Errorf("emails of kind '%s' are never resolved: %s", canonicalKind(kind), reason)
+		}
+
+		matched := false
+		for _, resolvable := range ResolvablePaths {
+			resolvableKind, resolvablePath, _ := strings.Cut(resolvable, ":")
+			if !strings.EqualFold(kind, resolvableKind) || (hasPath && path != resolvablePath) {
+				continue
+			}
+			eligibility[resolvable] = true
+			matched = true
+		}
+		if !matched {
+			return nil, fmt.Errorf("unknown resolution path '%s', expected one of %s", entry, strings.Join(ResolvablePaths, ", "))
+		}
+	}
+
+	if len(eligibility) == 0 {
+		return nil, fmt.Errorf("no resolution paths selected")
+	}
+
+	return eligibility, nil
+}
+
+// Allows reports whether emails in the given field of objects of the given
+// kind are resolved to user IDs
+func (e Eligibility) Allows(kind, path string) bool {
+	if _, protected := protectedKinds[kind]; protected {
+		return false
+	}
+	if e == nil {
+		for _, resolvable := range ResolvablePaths {
+			if resolvable == kind+":"+path {
+				return true
+			}
+		}
+		return false
+	}
+	return e[kind+":"+path]
+}
+
+// String describes the eligible paths for logging
+func (e Eligibility) String() string {
+	if e == nil {
+		return "all"
+	}
+	if len(e) == 0 {
+		return "none"
+	}
+
+	paths := make([]string, 0, len(e))
+	for path := range e {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+// canonicalKind returns the kind as written in manifests when it names a
+// known kind regardless of case
+func canonicalKind(kind string) string {
+	kind = strings.TrimSpace(kind)
+	for _, resolvable := range ResolvablePaths {
+		if name, _, _ := strings.Cut(resolvable, ":"); strings.EqualFold(kind, name) {
+			return name
+		}
+	}
+	for name := range protectedKinds {
+		if strings.EqualFold(kind, name) {
+			return name
+		}
+	}
+	return kind
+}
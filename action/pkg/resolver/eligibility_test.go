@@ -0,0 +1,108 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEligibility(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{spec: "", expected: "all"},
+		{spec: "ALL", expected: "all"},
+		{spec: "none", expected: "none"},
+		{spec: "RoleBinding:spec.user", expected: "RoleBinding:spec.user"},
+		{spec: "rolebinding", expected: "RoleBinding:spec.user,RoleBinding:spec.userIds,RoleBinding:spec.users[].id"},
+		{spec: " RoleBinding : spec.userIds , RoleBinding:spec.users[].id", expected: "RoleBinding:spec.userIds,RoleBinding:spec.users[].id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			eligibility, err := ParseEligibility(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if eligibility.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, eligibility.String())
+			}
+		})
+	}
+
+	for spec, message := range map[string]string{
+		"alertmethod:spec.email.to": "kind 'AlertMethod' are never resolved",
+		"Annotation":                "kind 'Annotation' are never resolved",
+		"RoleBinding:spec.owner":    "unknown resolution path",
+		"Project":                   "unknown resolution path",
+		",":                         "no resolution paths selected",
+	} {
+		if _, err := ParseEligibility(spec); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected an error containing %q for %q, got %v", message, spec, err)
+		}
+	}
+}
+
+func TestEligibilityAllows(t *testing.T) {
+	var all Eligibility
+	if !all.Allows("RoleBinding", "spec.user") || !all.Allows("RoleBinding", "spec.userIds") {
+		t.Error("expected nil eligibility to allow the resolvable paths")
+	}
+	if all.Allows("AlertMethod", "spec.email.to") || all.Allows("RoleBinding", "metadata.name") {
+		t.Error("expected nil eligibility to allow only resolvable paths")
+	}
+
+	userOnly, err := ParseEligibility("RoleBinding:spec.user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !userOnly.Allows("RoleBinding", "spec.user") || userOnly.Allows("RoleBinding", "spec.users[].id") {
+		t.Errorf("expected only spec.user to be allowed, got %s", userOnly)
+	}
+
+	none, _ := ParseEligibility("none")
+	if none.Allows("RoleBinding", "spec.user") {
+		t.Error("expected none to allow nothing")
+	}
+}
+
+func TestExtractEmailsFromYAMLEligibility(t *testing.T) {
+	content := []byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: team
+spec:
+  user: alice@example.com
+  users:
+    - bob@example.com
+  userIds: carol@example.com
+---
+apiVersion: n9/v1alpha
+kind: AlertMethod
+metadata:
+  name: oncall
+spec:
+  email:
+    to:
+      - oncall@example.com
+`)
+
+	resolver := &Resolver{}
+	resolver.SetEligibility(Eligibility{"RoleBinding:spec.users[].id": true})
+	emails, err := resolver.extractEmailsFromYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(emails) != 1 || emails[0] != "bob@example.com" {
+		t.Errorf("expected only the spec.users email, got %v", emails)
+	}
+
+	resolver.SetEligibility(nil)
+	emails, err = resolver.extractEmailsFromYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(emails) != 3 {
+		t.Errorf("expected the three role binding emails and no alert method email, got %v", emails)
+	}
+}
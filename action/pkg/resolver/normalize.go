@@ -0,0 +1,130 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Normalizer rewrites email addresses before they are resolved and cached,
+// so addresses written differently in manifests resolve to the same user. A
+// nil Normalizer leaves addresses unchanged.
+type Normalizer struct {
+	// Lowercase lowercases the whole address
+	Lowercase bool
+	// StripPlus removes plus addressing ("alice+nobl9@corp.com" becomes
+	// "alice@corp.com")
+	StripPlus bool
+	// DomainAliases maps legacy domains to the current ones (e.g.
+	// "old-corp.com" to "corp.com"); domains are matched case-insensitively
+	DomainAliases map[string]string
+}
+
+// NewNormalizer creates a normalizer. Aliases are a comma separated list of
+// old=new domain pairs, e.g. "old-corp.com=corp.com,acquired.io=corp.com".
+// It returns nil when no normalization is configured.
+func NewNormalizer(lowercase, stripPlus bool, aliases string) (*Normalizer, error) {
+	domainAliases, err := ParseDomainAliases(aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lowercase && !stripPlus && len(domainAliases) == 0 {
+		return nil, nil
+	}
+
+	return &Normalizer{
+		Lowercase:     lowercase,
+		StripPlus:     stripPlus,
+		DomainAliases: domainAliases,
+	}, nil
+}
+
+// ParseDomainAliases parses a comma separated list of old=new domain pairs
+func ParseDomainAliases(value string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		from, to, ok := strings.Cut(pair, "=")
+		from = strings.ToLower(strings.TrimSpace(from))
+		to = strings.ToLower(strings.TrimSpace(to))
+		if !ok || from == "" || to == "" || strings.Contains(from, "@") || strings.Contains(to, "@") {
+			return nil, fmt.Errorf("invalid domain alias %q, expected old-domain=new-domain", pair)
+		}
+		if from == to {
+			return nil, fmt.Errorf("domain alias %q maps a domain to itself", pair)
+		}
+		if existing, found := aliases[from]; found && existing != to {
+			return nil, fmt.Errorf("domain %q is aliased to both %q and %q", from, existing, to)
+		}
+		aliases[from] = to
+	}
+
+	// Chained aliases would make the result depend on the order they are applied in
+	for from, to := range aliases {
+		if _, chained := aliases[to]; chained {
+			return nil, fmt.Errorf("domain alias %s=%s points to an aliased domain", from, to)
+		}
+	}
+
+	return aliases, nil
+}
+
+// Normalize returns the normalized form of an email address. Surrounding
+// whitespace is always removed; addresses without a domain are otherwise
+// left as they are.
+func (n *Normalizer) Normalize(email string) string {
+	email = strings.TrimSpace(email)
+	if n == nil {
+		return email
+	}
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || domain == "" {
+		return email
+	}
+
+	if n.StripPlus {
+		if before, _, found := strings.Cut(local, "+"); found && before != "" {
+			local = before
+		}
+	}
+
+	if alias, found := n.DomainAliases[strings.ToLower(domain)]; found {
+		domain = alias
+	}
+
+	normalized := local + "@" + domain
+	if n.Lowercase {
+		normalized = strings.ToLower(normalized)
+	}
+	return normalized
+}
+
+// String describes the configured rules for logging
+func (n *Normalizer) String() string {
+	if n == nil {
+		return "none"
+	}
+
+	var rules []string
+	if n.Lowercase {
+		rules = append(rules, "lowercase")
+	}
+	if n.StripPlus {
+		rules = append(rules, "strip-plus")
+	}
+
+	aliases := make([]string, 0, len(n.DomainAliases))
+	for from, to := range n.DomainAliases {
+		aliases = append(aliases, from+"="+to)
+	}
+	sort.Strings(aliases)
+	rules = append(rules, aliases...)
+
+	return strings.Join(rules, ",")
+}
@@ -0,0 +1,74 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer, err := NewNormalizer(true, true, "old-corp.com=corp.com, Acquired.io=corp.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{email: "Alice@Corp.com", expected: "alice@corp.com"},
+		{email: " alice+nobl9@corp.com ", expected: "alice@corp.com"},
+		{email: "alice@OLD-CORP.com", expected: "alice@corp.com"},
+		{email: "bob+ops@acquired.io", expected: "bob@corp.com"},
+		{email: "+alice@corp.com", expected: "+alice@corp.com"},
+		{email: "not-an-email", expected: "not-an-email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if normalized := normalizer.Normalize(tt.email); normalized != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, normalized)
+			}
+		})
+	}
+
+	// Only the configured rules are applied
+	aliasOnly, err := NewNormalizer(false, false, "old-corp.com=corp.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if normalized := aliasOnly.Normalize("Alice+x@old-corp.com"); normalized != "Alice+x@corp.com" {
+		t.Errorf("expected only the domain to change, got %q", normalized)
+	}
+
+	// Without rules there is no normalizer and emails are only trimmed
+	none, err := NewNormalizer(false, false, "")
+	if err != nil || none != nil {
+		t.Fatalf("expected no normalizer, got %v, %v", none, err)
+	}
+	if normalized := none.Normalize(" Alice@Corp.com "); normalized != "Alice@Corp.com" {
+		t.Errorf("expected email to be trimmed only, got %q", normalized)
+	}
+}
+
+func TestParseDomainAliases(t *testing.T) {
+	invalid := []string{
+		"old-corp.com",
+		"old-corp.com=",
+		"alice@old-corp.com=corp.com",
+		"corp.com=corp.com",
+		"old-corp.com=corp.com,old-corp.com=other.com",
+		"a.com=b.com,b.com=c.com",
+	}
+	for _, value := range invalid {
+		if _, err := ParseDomainAliases(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+
+	aliases, err := ParseDomainAliases("old-corp.com=corp.com,,acquired.io=corp.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(aliases) != 2 || aliases["acquired.io"] != "corp.com" {
+		t.Errorf("unexpected aliases: %v", aliases)
+	}
+}
@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaAlertMethod "github.com/nobl9/nobl9-go/manifest/v1alpha/alertmethod"
+)
+
+// Recipient is an email address an object sends notifications to. Unlike
+// role binding users, recipients are passed to the Nobl9 API as written and
+// are never resolved to user IDs.
+type Recipient struct {
+	Kind    string
+	Name    string
+	Project string
+	// Field holding the address, e.g. spec.email.to
+	Field string
+	Email string
+}
+
+// String describes where the recipient is written
+func (r Recipient) String() string {
+	return fmt.Sprintf("%s '%s' %s '%s'", r.Kind, r.Name, r.Field, r.Email)
+}
+
+// Recipients returns the email recipients of an object: the to, cc and bcc
+// addresses of email alert methods. Reports carry no recipients in the
+// manifest schema; they are shared with users in Nobl9 instead.
+func Recipients(obj manifest.Object) []Recipient {
+	alertMethod, ok := obj.(v1alphaAlertMethod.AlertMethod)
+	if !ok || alertMethod.Spec.Email == nil {
+		return nil
+	}
+
+	var recipients []Recipient
+	for _, field := range []struct {
+		name   string
+		emails []string
+	}{
+		{name: "spec.email.to", emails: alertMethod.Spec.Email.To},
+		{name: "spec.email.cc", emails: alertMethod.Spec.Email.Cc},
+		{name: "spec.email.bcc", emails: alertMethod.Spec.Email.Bcc},
+	} {
+		for _, email := range field.emails {
+			recipients = append(recipients, Recipient{
+				Kind:    manifest.KindAlertMethod.String(),
+				Name:    alertMethod.Metadata.Name,
+				Project: alertMethod.Metadata.Project,
+				Field:   field.name,
+				Email:   email,
+			})
+		}
+	}
+	return recipients
+}
+
+// ValidEmail reports whether s is a bare email address, without a display
+// name or surrounding whitespace, whose domain has at least two labels
+func ValidEmail(s string) bool {
+	address, err := mail.ParseAddress(s)
+	if err != nil || address.Name != "" || address.Address != s {
+		return false
+	}
+	_, domain, _ := strings.Cut(address.Address, "@")
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
+
+// EmailDomain returns the lowercased domain of an email address, or "" when
+// it has none
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
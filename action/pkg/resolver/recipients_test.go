@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func TestRecipients(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(`- apiVersion: n9/v1alpha
+  kind: AlertMethod
+  metadata:
+    name: oncall
+    project: payments
+  spec:
+    email:
+      to: [oncall@example.com]
+      cc: [lead@example.com]
+      bcc: [audit@example.com]
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recipients := Recipients(objects[0])
+	if len(recipients) != 3 {
+		t.Fatalf("expected 3 recipients, got %v", recipients)
+	}
+	expected := Recipient{Kind: "AlertMethod", Name: "oncall", Project: "payments", Field: "spec.email.cc", Email: "lead@example.com"}
+	if recipients[1] != expected {
+		t.Errorf("expected %+v, got %+v", expected, recipients[1])
+	}
+	if recipients[1].String() != "AlertMethod 'oncall' spec.email.cc 'lead@example.com'" {
+		t.Errorf("unexpected description %q", recipients[1].String())
+	}
+	if len(Recipients(objects[1])) != 0 {
+		t.Error("expected projects to have no recipients")
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	for email, valid := range map[string]bool{
+		"alice@example.com":         true,
+		"alice+alerts@example.com":  true,
+		"alice@example":             false,
+		"alice":                     false,
+		" alice@example.com":        false,
+		"Alice <alice@example.com>": false,
+		"alice@@example.com":        false,
+	} {
+		if ValidEmail(email) != valid {
+			t.Errorf("expected ValidEmail(%q) to be %t", email, valid)
+		}
+	}
+
+	if domain := EmailDomain("Alice@Example.COM"); domain != "example.com" {
+		t.Errorf("expected example.com, got %q", domain)
+	}
+	if domain := EmailDomain("alice"); domain != "" {
+		t.Errorf("expected no domain, got %q", domain)
+	}
+}
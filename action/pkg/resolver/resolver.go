@@ -1,21 +1,42 @@
 package resolver
 
 import (
+	"bytes"
+	"container/list"
 	"context"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/your-org/nobl9-action/pkg/errors"
 	"github.com/your-org/nobl9-action/pkg/logger"
 	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/tracing"
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultRetryDelay is how long the resolver waits before retrying emails
+// that failed with transient errors
+const DefaultRetryDelay = 10 * time.Second
+
 // Resolver handles email-to-UserID resolution using the Nobl9 API
 type Resolver struct {
-	client *nobl9.Client
-	logger *logger.Logger
-	cache  *UserCache
+	client      nobl9.Nobl9API
+	logger      *logger.Logger
+	cache       *UserCache
+	retryDelay  time.Duration
+	normalizer  *Normalizer
+	eligibility Eligibility
 }
 
 // UserInfo represents user information from Nobl9
@@ -27,6 +48,7 @@ type UserInfo struct {
 	Active   bool
 	Found    bool
 	Error    error
+	CachedAt time.Time
 }
 
 // ResolutionResult represents the result of email resolution
@@ -37,6 +59,7 @@ type ResolutionResult struct {
 	Error     error
 	Duration  time.Duration
 	FromCache bool
+	Retried   bool
 }
 
 // BatchResolutionResult represents the result of batch email resolution
@@ -46,40 +69,123 @@ type BatchResolutionResult struct {
 	ResolvedCount int
 	ErrorCount    int
 	CacheHits     int
+	RetriedCount  int
 	Duration      time.Duration
 	Errors        []error
 }
 
-// UserCache provides caching for user information
+// RetryQueue collects emails whose resolution failed with a transient error
+// so they can be retried once more at the end of a run
+type RetryQueue struct {
+	emails []string
+	seen   map[string]bool
+	mutex  sync.Mutex
+}
+
+// DefaultMaxCacheEntries bounds the user cache for large organizations
+const DefaultMaxCacheEntries = 10000
+
+// UserCache provides caching for user information with TTL expiry and
+// least-recently-used eviction once maxEntries is reached
 type UserCache struct {
-	users map[string]*UserInfo
-	mutex sync.RWMutex
-	ttl   time.Duration
+	users      map[string]*UserInfo
+	order      *list.List               // most recently used email at the front
+	elements   map[string]*list.Element // email -> position in order
+	mutex      sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+
+	hits        int
+	misses      int
+	evictions   int
+	expirations int
+
+	stopSweep chan struct{}
+	stopOnce  sync.Once
+}
+
+// cacheFileVersion is the version of the persisted cache file format
+const cacheFileVersion = 1
+
+// cacheFile is the on-disk representation of the user cache
+type cacheFile struct {
+	Version int                       `json:"version"`
+	SavedAt time.Time                 `json:"saved_at"`
+	Entries map[string]cacheFileEntry `json:"entries"`
+}
+
+// cacheFileEntry is a single persisted email-to-UserID resolution
+type cacheFileEntry struct {
+	UserID   string    `json:"user_id"`
+	CachedAt time.Time `json:"cached_at"`
 }
 
 // New creates a new resolver instance
-func New(client *nobl9.Client, log *logger.Logger) *Resolver {
+func New(client nobl9.Nobl9API, log *logger.Logger) *Resolver {
 	return &Resolver{
-		client: client,
-		logger: log,
-		cache:  NewUserCache(30 * time.Minute), // 30 minute TTL
+		client:     client,
+		logger:     log,
+		cache:      NewUserCache(30 * time.Minute), // 30 minute TTL
+		retryDelay: DefaultRetryDelay,
 	}
 }
 
+// SetRetryDelay sets how long to wait before retrying transient failures
+func (r *Resolver) SetRetryDelay(delay time.Duration) {
+	r.retryDelay = delay
+}
+
+// SetNormalizer sets the rules applied to emails, after lowercasing, before
+// they are resolved and cached
+func (r *Resolver) SetNormalizer(normalizer *Normalizer) {
+	r.normalizer = normalizer
+}
+
+// SetEligibility sets the fields whose emails are extracted from YAML
+// content and resolved; nil selects every resolvable field
+func (r *Resolver) SetEligibility(eligibility Eligibility) {
+	r.eligibility = eligibility
+}
+
 // NewUserCache creates a new user cache with the specified TTL
 func NewUserCache(ttl time.Duration) *UserCache {
+	return NewBoundedUserCache(ttl, DefaultMaxCacheEntries)
+}
+
+// NewBoundedUserCache creates a new user cache with the specified TTL that
+// holds at most maxEntries users (0 means unbounded)
+func NewBoundedUserCache(ttl time.Duration, maxEntries int) *UserCache {
 	return &UserCache{
-		users: make(map[string]*UserInfo),
-		ttl:   ttl,
+		users:      make(map[string]*UserInfo),
+		order:      list.New(),
+		elements:   make(map[string]*list.Element),
+		ttl:        ttl,
+		maxEntries: maxEntries,
 	}
 }
 
 // ResolveEmail resolves a single email address to a UserID
 func (r *Resolver) ResolveEmail(ctx context.Context, email string) (*ResolutionResult, error) {
+	// Only the domain is recorded, to keep addresses out of traces
+	ctx, span := tracing.Start(ctx, "resolve email", attribute.String("email.domain", EmailDomain(email)))
+	result, err := r.resolveEmail(ctx, email)
+	spanErr := err
+	if result != nil {
+		span.SetAttributes(attribute.Bool("resolved", result.Resolved), attribute.Bool("from_cache", result.FromCache))
+		if spanErr == nil {
+			spanErr = result.Error
+		}
+	}
+	tracing.End(span, spanErr)
+	return result, err
+}
+
+// resolveEmail resolves an email address from the cache or the Nobl9 API
+func (r *Resolver) resolveEmail(ctx context.Context, email string) (*ResolutionResult, error) {
 	start := time.Now()
 
 	// Normalize email
-	normalizedEmail := strings.ToLower(strings.TrimSpace(email))
+	normalizedEmail := r.normalizer.Normalize(strings.ToLower(strings.TrimSpace(email)))
 
 	r.logger.Debug("Resolving email to UserID", logger.Fields{
 		"email": normalizedEmail,
@@ -111,7 +217,7 @@ func (r *Resolver) ResolveEmail(ctx context.Context, email string) (*ResolutionR
 			return &ResolutionResult{
 				Email:     normalizedEmail,
 				Resolved:  false,
-				Error:     fmt.Errorf("user not found"),
+				Error:     errors.ErrUserNotFound,
 				Duration:  time.Since(start),
 				FromCache: true,
 			}, nil
@@ -122,7 +228,7 @@ func (r *Resolver) ResolveEmail(ctx context.Context, email string) (*ResolutionR
 	user, err := r.client.GetUser(ctx, normalizedEmail)
 	if err != nil {
 		// Check if it's a "not found" error
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") {
+		if stderrors.Is(err, errors.ErrUserNotFound) || strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "404") {
 			// Cache the "not found" result
 			r.cache.Set(normalizedEmail, &UserInfo{
 				Email: normalizedEmail,
@@ -138,7 +244,7 @@ func (r *Resolver) ResolveEmail(ctx context.Context, email string) (*ResolutionR
 			return &ResolutionResult{
 				Email:    normalizedEmail,
 				Resolved: false,
-				Error:    fmt.Errorf("user not found: %w", err),
+				Error:    fmt.Errorf("%w: %w", errors.ErrUserNotFound, err),
 				Duration: time.Since(start),
 			}, nil
 		}
@@ -189,6 +295,8 @@ func (r *Resolver) ResolveEmail(ctx context.Context, email string) (*ResolutionR
 // ResolveEmails resolves multiple email addresses to UserIDs
 func (r *Resolver) ResolveEmails(ctx context.Context, emails []string) (*BatchResolutionResult, error) {
 	start := time.Now()
+	ctx, span := tracing.Start(ctx, "resolve", attribute.Int("email.count", len(emails)))
+	defer span.End()
 
 	if len(emails) == 0 {
 		return &BatchResolutionResult{
@@ -233,6 +341,9 @@ func (r *Resolver) ResolveEmails(ctx context.Context, emails []string) (*BatchRe
 	// Wait for all resolutions to complete
 	wg.Wait()
 
+	// Retry transient failures once more after a backoff
+	retried := r.retryTransientFailures(ctx, results)
+
 	// Calculate statistics
 	resolvedCount := 0
 	errorCount := 0
@@ -257,21 +368,139 @@ func (r *Resolver) ResolveEmails(ctx context.Context, emails []string) (*BatchRe
 		ResolvedCount: resolvedCount,
 		ErrorCount:    errorCount,
 		CacheHits:     cacheHits,
+		RetriedCount:  retried,
 		Duration:      time.Since(start),
 		Errors:        errors,
 	}
+	span.SetAttributes(
+		attribute.Int("email.resolved", resolvedCount),
+		attribute.Int("email.unresolved", errorCount),
+		attribute.Int("email.cache_hits", cacheHits),
+	)
 
 	r.logger.Info("Batch email resolution completed", logger.Fields{
 		"total_emails":   batchResult.TotalEmails,
 		"resolved_count": batchResult.ResolvedCount,
 		"error_count":    batchResult.ErrorCount,
 		"cache_hits":     batchResult.CacheHits,
+		"retried_count":  batchResult.RetriedCount,
 		"duration":       batchResult.Duration.String(),
 	})
 
 	return batchResult, nil
 }
 
+// retryTransientFailures re-resolves results that failed with a transient
+// error after the retry delay, replacing them in place. It returns the
+// number of emails retried.
+func (r *Resolver) retryTransientFailures(ctx context.Context, results []*ResolutionResult) int {
+	queue := NewRetryQueue()
+	indexes := make(map[string][]int)
+
+	for i, result := range results {
+		if result != nil && !result.Resolved && queue.Add(result.Email, result.Error) {
+			indexes[result.Email] = append(indexes[result.Email], i)
+		}
+	}
+
+	if queue.Len() == 0 {
+		return 0
+	}
+
+	r.logger.Info("Retrying emails that failed with transient errors", logger.Fields{
+		"email_count": queue.Len(),
+		"delay":       r.retryDelay.String(),
+	})
+
+	select {
+	case <-time.After(r.retryDelay):
+	case <-ctx.Done():
+		r.logger.Warn("Skipping email resolution retry", logger.Fields{
+			"error": ctx.Err().Error(),
+		})
+		return 0
+	}
+
+	emails := queue.Drain()
+	for _, email := range emails {
+		result, err := r.ResolveEmail(ctx, email)
+		if err != nil || result == nil {
+			continue
+		}
+		result.Retried = true
+		for _, i := range indexes[email] {
+			results[i] = result
+		}
+	}
+
+	return len(emails)
+}
+
+// IsTransientError reports whether an email resolution error is worth
+// retrying later (server errors, rate limits and timeouts)
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Both the SDK's HTTPError and Nobl9Error know whether they are retryable
+	var retryable interface{ IsRetryable() bool }
+	if stderrors.As(err, &retryable) {
+		return retryable.IsRetryable()
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.IsRetryableError(err) || errors.IsTimeoutError(err)
+}
+
+// NewRetryQueue creates an empty retry queue
+func NewRetryQueue() *RetryQueue {
+	return &RetryQueue{
+		seen: make(map[string]bool),
+	}
+}
+
+// Add queues the email if err is transient and reports whether it was queued
+func (q *RetryQueue) Add(email string, err error) bool {
+	if !IsTransientError(err) {
+		return false
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.seen[email] {
+		q.seen[email] = true
+		q.emails = append(q.emails, email)
+	}
+
+	return true
+}
+
+// Len returns the number of queued emails
+func (q *RetryQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.emails)
+}
+
+// Drain returns the queued emails and empties the queue
+func (q *RetryQueue) Drain() []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	emails := q.emails
+	q.emails = nil
+	q.seen = make(map[string]bool)
+
+	return emails
+}
+
 // ResolveEmailsFromYAML extracts emails from YAML content and resolves them
 func (r *Resolver) ResolveEmailsFromYAML(ctx context.Context, yamlContent []byte) (*BatchResolutionResult, error) {
 	// Extract emails from YAML content
@@ -301,39 +530,111 @@ func (r *Resolver) ResolveEmailsFromYAML(ctx context.Context, yamlContent []byte
 	return r.ResolveEmails(ctx, emails)
 }
 
-// extractEmailsFromYAML extracts email addresses from YAML content
+// extractEmailsFromYAML extracts the lowercased email addresses of the
+// RoleBinding users in YAML content, as far as the eligibility allows
 func (r *Resolver) extractEmailsFromYAML(yamlContent []byte) ([]string, error) {
-	// This is a simplified implementation
-	// In a real implementation, you would parse the YAML and extract emails from specific fields
+	users, err := RoleBindingUsers(yamlContent, r.eligibility)
+	if err != nil {
+		return nil, err
+	}
 
-	content := string(yamlContent)
 	emails := make([]string, 0)
 	emailSet := make(map[string]bool)
+	for _, user := range users {
+		if !r.isValidEmail(user) {
+			continue
+		}
+		normalizedEmail := strings.ToLower(user)
+		if !emailSet[normalizedEmail] {
+			emailSet[normalizedEmail] = true
+			emails = append(emails, normalizedEmail)
+		}
+	}
+
+	return emails, nil
+}
+
+// RoleBindingUsers returns the users, as written, in the user fields of the
+// RoleBinding documents in YAML content: spec.user, spec.users[] and its
+// {id: ...} entries, and the comma separated spec.userIds, as far as the
+// eligibility allows. Emails anywhere else, such as in comments,
+// descriptions or alert methods, are not users and are left alone.
+func RoleBindingUsers(yamlContent []byte, eligibility Eligibility) ([]string, error) {
+	var users []string
+
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlContent))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if stderrors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		users = append(users, roleBindingUsers(&doc, eligibility)...)
+	}
+
+	return users, nil
+}
 
-	// Simple regex-like extraction for demonstration
-	// In practice, you would use proper YAML parsing
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Look for email patterns in the YAML
-		if strings.Contains(line, "@") && strings.Contains(line, ".") {
-			// Extract potential email addresses
-			words := strings.Fields(line)
-			for _, word := range words {
-				word = strings.Trim(word, "[]{}:,\"'")
-				if r.isValidEmail(word) {
-					normalizedEmail := strings.ToLower(strings.TrimSpace(word))
-					if !emailSet[normalizedEmail] {
-						emailSet[normalizedEmail] = true
-						emails = append(emails, normalizedEmail)
-					}
+// roleBindingUsers returns the values of the eligible user fields of the
+// RoleBinding objects in a YAML document, which holds a single object or a
+// list of them
+func roleBindingUsers(doc *yaml.Node, eligibility Eligibility) []string {
+	root := resolveAlias(doc)
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = resolveAlias(root.Content[0])
+	}
+
+	objects := []*yaml.Node{root}
+	if root.Kind == yaml.SequenceNode {
+		objects = root.Content
+	}
+
+	var users []string
+	for _, object := range objects {
+		kind := resolveAlias(yamlnode.MappingValue(resolveAlias(object), "kind"))
+		if kind == nil || kind.Value != "RoleBinding" {
+			continue
+		}
+		spec := resolveAlias(yamlnode.MappingValue(resolveAlias(object), "spec"))
+		if spec == nil || spec.Kind != yaml.MappingNode {
+			continue
+		}
+
+		if user := resolveAlias(yamlnode.MappingValue(spec, "user")); user != nil && user.Kind == yaml.ScalarNode && eligibility.Allows("RoleBinding", "spec.user") {
+			users = append(users, strings.TrimSpace(user.Value))
+		}
+		if list := resolveAlias(yamlnode.MappingValue(spec, "users")); list != nil && list.Kind == yaml.SequenceNode && eligibility.Allows("RoleBinding", "spec.users[].id") {
+			for _, item := range list.Content {
+				item = resolveAlias(item)
+				if item.Kind == yaml.MappingNode {
+					item = resolveAlias(yamlnode.MappingValue(item, "id"))
 				}
+				if item != nil && item.Kind == yaml.ScalarNode {
+					users = append(users, strings.TrimSpace(item.Value))
+				}
+			}
+		}
+		if ids := resolveAlias(yamlnode.MappingValue(spec, "userIds")); ids != nil && ids.Kind == yaml.ScalarNode && eligibility.Allows("RoleBinding", "spec.userIds") {
+			for _, id := range strings.Split(ids.Value, ",") {
+				users = append(users, strings.TrimSpace(id))
 			}
 		}
 	}
 
-	return emails, nil
+	return users
+}
+
+// resolveAlias returns the node an alias refers to, or the node itself
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
 }
 
 // isValidEmail performs basic email validation
@@ -374,22 +675,63 @@ func (r *Resolver) GetCacheStats() map[string]interface{} {
 	return r.cache.GetStats()
 }
 
+// LoadCache loads a cache file persisted by a previous run
+func (r *Resolver) LoadCache(path string) error {
+	loaded, err := r.cache.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Loaded user cache", logger.Fields{
+		"path":    path,
+		"entries": loaded,
+	})
+
+	return nil
+}
+
+// SaveCache persists the user cache so later runs can reuse it
+func (r *Resolver) SaveCache(path string) error {
+	saved, err := r.cache.SaveFile(path)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Saved user cache", logger.Fields{
+		"path":    path,
+		"entries": saved,
+	})
+
+	return nil
+}
+
 // ClearCache clears the user cache
 func (r *Resolver) ClearCache() {
 	r.cache.Clear()
 	r.logger.Info("User cache cleared")
 }
 
-// Get retrieves a user from cache
+// Get retrieves a user from cache, evicting it if its TTL has passed
 func (c *UserCache) Get(email string) *UserInfo {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	if user, exists := c.users[email]; exists {
-		return user
+	user, exists := c.users[email]
+	if !exists {
+		c.misses++
+		return nil
 	}
 
-	return nil
+	if c.expired(user.CachedAt) {
+		c.remove(email)
+		c.expirations++
+		c.misses++
+		return nil
+	}
+
+	c.hits++
+	c.order.MoveToFront(c.elements[email])
+	return user
 }
 
 // Set stores a user in cache
@@ -397,7 +739,200 @@ func (c *UserCache) Set(email string, user *UserInfo) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if user.CachedAt.IsZero() {
+		user.CachedAt = time.Now()
+	}
+
+	c.set(email, user)
+}
+
+// set stores a user and evicts the least recently used entries over the
+// limit; callers must hold the write lock
+func (c *UserCache) set(email string, user *UserInfo) {
 	c.users[email] = user
+
+	if element, exists := c.elements[email]; exists {
+		c.order.MoveToFront(element)
+	} else {
+		c.elements[email] = c.order.PushFront(email)
+	}
+
+	for c.maxEntries > 0 && len(c.users) > c.maxEntries {
+		oldest := c.order.Back()
+		c.remove(oldest.Value.(string))
+		c.evictions++
+	}
+}
+
+// remove deletes a user from the cache; callers must hold the write lock
+func (c *UserCache) remove(email string) {
+	if element, exists := c.elements[email]; exists {
+		c.order.Remove(element)
+		delete(c.elements, email)
+	}
+	delete(c.users, email)
+}
+
+// Sweep removes all expired entries and returns how many were removed
+func (c *UserCache) Sweep() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for email, user := range c.users {
+		if c.expired(user.CachedAt) {
+			c.remove(email)
+			removed++
+		}
+	}
+	c.expirations += removed
+
+	return removed
+}
+
+// StartSweep periodically removes expired entries in the background until
+// Stop is called
+func (c *UserCache) StartSweep(interval time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stopSweep != nil || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	c.stopSweep = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.Sweep()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background sweep started by StartSweep
+func (c *UserCache) Stop() {
+	c.mutex.RLock()
+	stop := c.stopSweep
+	c.mutex.RUnlock()
+
+	if stop == nil {
+		return
+	}
+
+	c.stopOnce.Do(func() {
+		close(stop)
+	})
+}
+
+// LoadFile loads resolved users persisted by SaveFile, skipping entries
+// older than the cache TTL. A missing file is not an error.
+func (c *UserCache) LoadFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("failed to parse cache file: %w", err)
+	}
+
+	if file.Version != cacheFileVersion {
+		return 0, fmt.Errorf("unsupported cache file version %d", file.Version)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Insert oldest entries first so the most recently resolved users are
+	// the last to be evicted
+	emails := make([]string, 0, len(file.Entries))
+	for email := range file.Entries {
+		emails = append(emails, email)
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		return file.Entries[emails[i]].CachedAt.Before(file.Entries[emails[j]].CachedAt)
+	})
+
+	loaded := 0
+	for _, email := range emails {
+		entry := file.Entries[email]
+		if entry.UserID == "" || c.expired(entry.CachedAt) {
+			continue
+		}
+		c.set(email, &UserInfo{
+			Email:    email,
+			UserID:   entry.UserID,
+			Active:   true,
+			Found:    true,
+			CachedAt: entry.CachedAt,
+		})
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// SaveFile persists resolved users to a JSON file so later runs can reuse
+// them. "Not found" results are not persisted, since users may be created
+// in Nobl9 between runs.
+func (c *UserCache) SaveFile(path string) (int, error) {
+	c.mutex.RLock()
+	file := cacheFile{
+		Version: cacheFileVersion,
+		SavedAt: time.Now().UTC(),
+		Entries: make(map[string]cacheFileEntry),
+	}
+	for email, user := range c.users {
+		if !user.Found || c.expired(user.CachedAt) {
+			continue
+		}
+		file.Entries[email] = cacheFileEntry{
+			UserID:   user.UserID,
+			CachedAt: user.CachedAt.UTC(),
+		}
+	}
+	c.mutex.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode cache file: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a
+	// truncated cache behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return 0, fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	return len(file.Entries), nil
+}
+
+// expired reports whether an entry cached at the given time is past the TTL
+func (c *UserCache) expired(cachedAt time.Time) bool {
+	return c.ttl > 0 && time.Since(cachedAt) > c.ttl
 }
 
 // GetStats returns cache statistics
@@ -405,9 +940,20 @@ func (c *UserCache) GetStats() map[string]interface{} {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
+	hitRate := 0.0
+	if lookups := c.hits + c.misses; lookups > 0 {
+		hitRate = float64(c.hits) / float64(lookups)
+	}
+
 	return map[string]interface{}{
-		"size": len(c.users),
-		"ttl":  c.ttl.String(),
+		"size":        len(c.users),
+		"ttl":         c.ttl.String(),
+		"max_entries": c.maxEntries,
+		"hits":        c.hits,
+		"misses":      c.misses,
+		"hit_rate":    hitRate,
+		"evictions":   c.evictions,
+		"expirations": c.expirations,
 	}
 }
 
@@ -417,6 +963,8 @@ func (c *UserCache) Clear() {
 	defer c.mutex.Unlock()
 
 	c.users = make(map[string]*UserInfo)
+	c.order.Init()
+	c.elements = make(map[string]*list.Element)
 }
 
 // GetResolvedUserIDs returns a map of email to UserID for resolved users
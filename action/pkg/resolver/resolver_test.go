@@ -1,12 +1,21 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/errors"
 	"github.com/your-org/nobl9-action/pkg/logger"
 	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9/nobl9test"
 )
 
 func TestNewResolver(t *testing.T) {
@@ -107,6 +116,177 @@ func TestUserCacheOperations(t *testing.T) {
 	}
 }
 
+func TestUserCacheExpiry(t *testing.T) {
+	cache := NewUserCache(time.Hour)
+
+	cache.Set("fresh@example.com", &UserInfo{Email: "fresh@example.com", UserID: "user-1", Found: true})
+	cache.Set("stale@example.com", &UserInfo{Email: "stale@example.com", UserID: "user-2", Found: true, CachedAt: time.Now().Add(-2 * time.Hour)})
+
+	if cache.Get("fresh@example.com") == nil {
+		t.Error("expected fresh entry to be returned")
+	}
+
+	// Expired entries are evicted lazily on Get
+	if cache.Get("stale@example.com") != nil {
+		t.Error("expected expired entry not to be returned")
+	}
+
+	stats := cache.GetStats()
+	if stats["size"] != 1 {
+		t.Errorf("expected cache size 1 after lazy eviction, got %v", stats["size"])
+	}
+	if stats["expirations"] != 1 {
+		t.Errorf("expected 1 expiration, got %v", stats["expirations"])
+	}
+
+	// Sweep removes expired entries that are never read again
+	cache.Set("old@example.com", &UserInfo{Email: "old@example.com", Found: false, CachedAt: time.Now().Add(-3 * time.Hour)})
+	if removed := cache.Sweep(); removed != 1 {
+		t.Errorf("expected sweep to remove 1 entry, got %d", removed)
+	}
+	if stats := cache.GetStats(); stats["size"] != 1 {
+		t.Errorf("expected cache size 1 after sweep, got %v", stats["size"])
+	}
+}
+
+func TestUserCacheBackgroundSweep(t *testing.T) {
+	cache := NewUserCache(10 * time.Millisecond)
+	cache.Set("user@example.com", &UserInfo{Email: "user@example.com", UserID: "user-1", Found: true})
+
+	cache.StartSweep(5 * time.Millisecond)
+	defer cache.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		cache.mutex.RLock()
+		size := len(cache.users)
+		cache.mutex.RUnlock()
+
+		if size == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if stats := cache.GetStats(); stats["size"] != 0 {
+		t.Errorf("expected background sweep to empty the cache, got size %v", stats["size"])
+	}
+
+	// Stop is safe to call more than once
+	cache.Stop()
+}
+
+func TestUserCacheLRUEviction(t *testing.T) {
+	cache := NewBoundedUserCache(time.Hour, 2)
+
+	cache.Set("a@example.com", &UserInfo{Email: "a@example.com", UserID: "a", Found: true})
+	cache.Set("b@example.com", &UserInfo{Email: "b@example.com", UserID: "b", Found: true})
+
+	// Touch a so that b becomes the least recently used entry
+	if cache.Get("a@example.com") == nil {
+		t.Fatal("expected a@example.com to be cached")
+	}
+
+	cache.Set("c@example.com", &UserInfo{Email: "c@example.com", UserID: "c", Found: true})
+
+	if cache.Get("b@example.com") != nil {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if cache.Get("a@example.com") == nil || cache.Get("c@example.com") == nil {
+		t.Error("expected recently used entries to remain cached")
+	}
+
+	stats := cache.GetStats()
+	if stats["size"] != 2 {
+		t.Errorf("expected cache size 2, got %v", stats["size"])
+	}
+	if stats["evictions"] != 1 {
+		t.Errorf("expected 1 eviction, got %v", stats["evictions"])
+	}
+
+	// One miss (b) out of four lookups
+	if stats["hits"] != 3 || stats["misses"] != 1 {
+		t.Errorf("expected 3 hits and 1 miss, got %v and %v", stats["hits"], stats["misses"])
+	}
+	if stats["hit_rate"] != 0.75 {
+		t.Errorf("expected hit rate 0.75, got %v", stats["hit_rate"])
+	}
+}
+
+func TestUserCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "users.json")
+
+	cache := NewUserCache(time.Hour)
+	cache.Set("fresh@example.com", &UserInfo{Email: "fresh@example.com", UserID: "user-1", Found: true})
+	cache.Set("stale@example.com", &UserInfo{Email: "stale@example.com", UserID: "user-2", Found: true, CachedAt: time.Now().Add(-2 * time.Hour)})
+	cache.Set("missing@example.com", &UserInfo{Email: "missing@example.com", Found: false})
+
+	saved, err := cache.SaveFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error saving cache: %v", err)
+	}
+	if saved != 1 {
+		t.Errorf("expected 1 saved entry, got %d", saved)
+	}
+
+	restored := NewUserCache(time.Hour)
+	loaded, err := restored.LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading cache: %v", err)
+	}
+	if loaded != 1 {
+		t.Errorf("expected 1 loaded entry, got %d", loaded)
+	}
+
+	user := restored.Get("fresh@example.com")
+	if user == nil || !user.Found || user.UserID != "user-1" {
+		t.Errorf("expected fresh@example.com to resolve to user-1, got %+v", user)
+	}
+	if restored.Get("stale@example.com") != nil {
+		t.Error("expected expired entry not to be saved")
+	}
+	if restored.Get("missing@example.com") != nil {
+		t.Error("expected not found entry not to be saved")
+	}
+
+	// Entries that expire between runs are dropped on load
+	shortLived := NewUserCache(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if loaded, err := shortLived.LoadFile(path); err != nil || loaded != 0 {
+		t.Errorf("expected expired entries to be skipped, got %d (err: %v)", loaded, err)
+	}
+}
+
+func TestUserCacheLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewUserCache(time.Hour)
+
+	loaded, err := cache.LoadFile(filepath.Join(dir, "does-not-exist.json"))
+	if err != nil || loaded != 0 {
+		t.Errorf("expected missing file to be ignored, got %d (err: %v)", loaded, err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", "{not json"},
+		{"unsupported version", `{"version": 99, "entries": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "cache.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to write cache file: %v", err)
+			}
+			if _, err := cache.LoadFile(path); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
+
 func TestIsValidEmail(t *testing.T) {
 	log := logger.New(logger.LevelInfo, logger.FormatJSON)
 	client := &nobl9.Client{}
@@ -292,6 +472,66 @@ spec:
 			yamlContent: []byte{},
 			expected:    []string{},
 		},
+		{
+			name: "user and userIds fields",
+			yamlContent: []byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: owner
+spec:
+  user: Owner@Example.com
+  userIds: "user3@example.com, okta-group:sre,user4@example.com"
+  roleRef: project-owner`),
+			expected: []string{"owner@example.com", "user3@example.com", "user4@example.com"},
+		},
+		{
+			name: "emails outside of role binding users",
+			yamlContent: []byte(`# Owned by team-lead@example.com
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  name: test-project
+spec:
+  description: Contact oncall@example.com for access
+---
+apiVersion: n9/v1alpha
+kind: AlertMethod
+metadata:
+  name: email-alerts
+  project: test-project
+spec:
+  email:
+    to:
+      - alerts@example.com
+---
+apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: viewer
+  project: test-project
+spec:
+  user: viewer@example.com # approved by manager@example.com
+  roleRef: project-viewer`),
+			expected: []string{"viewer@example.com"},
+		},
+		{
+			name: "list of objects with anchors",
+			yamlContent: []byte(`- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: first
+  spec:
+    user: &shared shared@example.com
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: second
+  spec:
+    users:
+      - *shared
+      - id: other@example.com`),
+			expected: []string{"shared@example.com", "other@example.com"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -322,14 +562,117 @@ spec:
 			}
 		})
 	}
+
+	if _, err := resolver.extractEmailsFromYAML([]byte("kind: RoleBinding\nspec: [unclosed")); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
+
+func TestRoleBindingUsers(t *testing.T) {
+	content := []byte(`kind: RoleBinding
+spec:
+  users:
+    - Alice@Example.com
+    - id: 00u1bob
+---
+kind: Project
+spec:
+  description: owned by carol@example.com
+`)
+	users, err := RoleBindingUsers(content, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(users, ",") != "Alice@Example.com,00u1bob" {
+		t.Errorf("expected the role binding users as written, got %v", users)
+	}
 }
 
 func TestResolveEmails(t *testing.T) {
-	t.Skip("Skipping test that requires real Nobl9 client connection")
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New().
+		AddUser("alice@example.com", "00u1alice").
+		AddUser("bob@example.com", "00u1bob")
+	resolver := New(client, log)
+	emails := []string{"alice@example.com", "Bob@Example.com", "carol@example.com"}
+
+	result, err := resolver.ResolveEmails(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ResolvedCount != 2 || result.ErrorCount != 1 || result.CacheHits != 0 {
+		t.Errorf("expected 2 resolved and 1 unresolved email, got %+v", result)
+	}
+	userIDs := resolver.GetResolvedUserIDs(result)
+	if userIDs["alice@example.com"] != "00u1alice" || userIDs["bob@example.com"] != "00u1bob" {
+		t.Errorf("unexpected user IDs %v", userIDs)
+	}
+	if unresolved := resolver.GetUnresolvedEmails(result); len(unresolved) != 1 || unresolved[0] != "carol@example.com" {
+		t.Errorf("expected carol@example.com to be unresolved, got %v", unresolved)
+	}
+
+	// Found and missing users are both answered from the cache
+	result, err = resolver.ResolveEmails(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.CacheHits != 3 {
+		t.Errorf("expected 3 cache hits, got %d", result.CacheHits)
+	}
+	if calls := client.Calls("GetUser"); calls != 3 {
+		t.Errorf("expected 3 user lookups, got %d", calls)
+	}
+}
+
+func TestResolveEmailAPIError(t *testing.T) {
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New().
+		AddUser("alice@example.com", "00u1alice").
+		FailOn("GetUser", &sdk.HTTPError{StatusCode: http.StatusBadRequest})
+	resolver := New(client, log)
+
+	result, err := resolver.ResolveEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Resolved || result.Error == nil {
+		t.Fatalf("expected the API error to leave the email unresolved, got %+v", result)
+	}
+
+	// API errors are not cached
+	client.FailOn("GetUser", nil)
+	result, _ = resolver.ResolveEmail(context.Background(), "alice@example.com")
+	if !result.Resolved || result.FromCache {
+		t.Errorf("expected the email to be resolved from the API, got %+v", result)
+	}
 }
 
 func TestResolveEmailsFromYAML(t *testing.T) {
-	t.Skip("Skipping test that requires real Nobl9 client connection")
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New().AddUser("alice@example.com", "00u1alice")
+	resolver := New(client, log)
+
+	content := []byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-alice
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: payments
+`)
+	result, err := resolver.ResolveEmailsFromYAML(context.Background(), content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalEmails != 1 || result.ResolvedCount != 1 {
+		t.Errorf("expected alice@example.com to be resolved, got %+v", result)
+	}
+
+	result, err = resolver.ResolveEmailsFromYAML(context.Background(), []byte("kind: Project\nmetadata:\n  name: payments\n"))
+	if err != nil || result.TotalEmails != 0 {
+		t.Errorf("expected no emails, got %+v, %v", result, err)
+	}
 }
 
 func TestGetResolvedUserIDs(t *testing.T) {
@@ -440,5 +783,107 @@ func TestCacheOperations(t *testing.T) {
 }
 
 func TestConcurrentResolution(t *testing.T) {
-	t.Skip("Skipping test that requires real Nobl9 client connection")
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New()
+	emails := make([]string, 0, 40)
+	for i := 0; i < 40; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		client.AddUser(email, fmt.Sprintf("00u%d", i))
+		emails = append(emails, email)
+	}
+	resolver := New(client, log)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := resolver.ResolveEmails(context.Background(), emails)
+			if err != nil || result.ResolvedCount != len(emails) {
+				t.Errorf("expected every email to be resolved, got %+v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := client.Calls("GetUser"); calls < len(emails) || calls > 4*len(emails) {
+		t.Errorf("unexpected number of user lookups: %d", calls)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil error", nil, false},
+		{"sdk server error", fmt.Errorf("failed to get user: %w", &sdk.HTTPError{StatusCode: http.StatusBadGateway}), true},
+		{"sdk client error", fmt.Errorf("failed to get user: %w", &sdk.HTTPError{StatusCode: http.StatusBadRequest}), false},
+		{"network error", errors.NewNetworkError("connection reset", nil), true},
+		{"auth error", errors.NewAuthError("unauthorized", nil), false},
+		{"timeout message", fmt.Errorf("context deadline exceeded"), true},
+		{"user not found", fmt.Errorf("user not found"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsTransientError(tt.err); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRetryQueue(t *testing.T) {
+	queue := NewRetryQueue()
+
+	if queue.Add("permanent@example.com", fmt.Errorf("user not found")) {
+		t.Error("expected permanent failure not to be queued")
+	}
+	if !queue.Add("flaky@example.com", errors.NewTimeoutError("request timed out", nil)) {
+		t.Error("expected transient failure to be queued")
+	}
+	if !queue.Add("flaky@example.com", errors.NewTimeoutError("request timed out", nil)) {
+		t.Error("expected repeated transient failure to be reported as queued")
+	}
+
+	if queue.Len() != 1 {
+		t.Errorf("expected 1 queued email, got %d", queue.Len())
+	}
+
+	emails := queue.Drain()
+	if len(emails) != 1 || emails[0] != "flaky@example.com" {
+		t.Errorf("expected [flaky@example.com], got %v", emails)
+	}
+	if queue.Len() != 0 {
+		t.Errorf("expected empty queue after drain, got %d", queue.Len())
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	resolver := New(&nobl9.Client{}, log)
+	resolver.SetRetryDelay(time.Hour)
+
+	// Nothing to retry when failures are permanent
+	results := []*ResolutionResult{
+		{Email: "resolved@example.com", UserID: "user-1", Resolved: true},
+		{Email: "missing@example.com", Error: fmt.Errorf("user not found")},
+	}
+	if retried := resolver.retryTransientFailures(context.Background(), results); retried != 0 {
+		t.Errorf("expected no retries, got %d", retried)
+	}
+
+	// A cancelled context skips the retry instead of waiting out the delay
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results = append(results, &ResolutionResult{Email: "flaky@example.com", Error: errors.NewNetworkError("connection reset", nil)})
+	if retried := resolver.retryTransientFailures(ctx, results); retried != 0 {
+		t.Errorf("expected retry to be skipped on cancelled context, got %d", retried)
+	}
+	if results[2].Resolved || results[2].Retried {
+		t.Error("expected transient failure to remain unresolved")
+	}
 }
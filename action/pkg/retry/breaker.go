@@ -0,0 +1,157 @@
+package retry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects every call until the cool-down has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single trial call through after the cool-down
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerOpenError is returned for calls rejected by an open circuit breaker
+type BreakerOpenError struct {
+	Failures int
+	RetryIn  time.Duration
+}
+
+// Error implements the error interface
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open after %d consecutive failures, failing fast for %s", e.Failures, e.RetryIn.Round(time.Second))
+}
+
+// BreakerStats summarizes what a circuit breaker did
+type BreakerStats struct {
+	State    BreakerState
+	Trips    int // times the breaker opened
+	Rejected int // calls failed fast while open
+}
+
+// Breaker is a circuit breaker shared by concurrent callers. It opens after
+// a number of consecutive failures, fails calls fast for a cool-down period
+// and then lets one trial call through: success closes it again, failure
+// reopens it. A nil Breaker lets everything through.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+	trips    int
+	rejected int
+	mutex    sync.Mutex
+}
+
+// NewBreaker creates a breaker that opens after threshold consecutive
+// failures. It returns nil when threshold is not positive.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow returns a BreakerOpenError if the call must fail fast. Every allowed
+// call must be followed by Record.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == BreakerOpen {
+		if elapsed := b.now().Sub(b.openedAt); elapsed < b.cooldown {
+			b.rejected++
+			return &BreakerOpenError{Failures: b.failures, RetryIn: b.cooldown - elapsed}
+		}
+		b.state = BreakerHalfOpen
+	}
+
+	if b.state == BreakerHalfOpen {
+		// Only one trial call at a time while half open
+		if b.trial {
+			b.rejected++
+			return &BreakerOpenError{Failures: b.failures}
+		}
+		b.trial = true
+	}
+
+	return nil
+}
+
+// Record reports the outcome of an allowed call and returns the state
+// before and after it
+func (b *Breaker) Record(success bool) (from, to BreakerState) {
+	if b == nil {
+		return BreakerClosed, BreakerClosed
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	from = b.state
+	b.trial = false
+
+	switch {
+	case success:
+		b.failures = 0
+		b.state = BreakerClosed
+	case b.state == BreakerHalfOpen:
+		b.failures++
+		b.open()
+	default:
+		b.failures++
+		if b.state == BreakerClosed && b.failures >= b.threshold {
+			b.open()
+		}
+	}
+
+	return from, b.state
+}
+
+// open trips the breaker
+func (b *Breaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.trips++
+}
+
+// Stats returns the breaker's state and counters
+func (b *Breaker) Stats() BreakerStats {
+	if b == nil {
+		return BreakerStats{State: BreakerClosed}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return BreakerStats{State: b.state, Trips: b.trips, Rejected: b.rejected}
+}
+
+// Cooldown returns how long the breaker stays open
+func (b *Breaker) Cooldown() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.cooldown
+}
@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	// A success resets the consecutive failures
+	breaker.Record(false)
+	breaker.Record(true)
+	if _, to := breaker.Record(false); to != BreakerClosed {
+		t.Fatalf("expected breaker to stay closed, got %s", to)
+	}
+
+	if from, to := breaker.Record(false); from != BreakerClosed || to != BreakerOpen {
+		t.Fatalf("expected breaker to open, got %s -> %s", from, to)
+	}
+
+	// Calls fail fast during the cool-down
+	err := breaker.Allow()
+	openErr, ok := err.(*BreakerOpenError)
+	if !ok || openErr.RetryIn != time.Minute {
+		t.Fatalf("expected BreakerOpenError with a minute left, got %v", err)
+	}
+
+	// After the cool-down a single trial call is let through
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("expected trial call to be allowed, got %v", err)
+	}
+	if err := breaker.Allow(); err == nil {
+		t.Fatal("expected only one trial call while half open")
+	}
+
+	// A failed trial reopens the breaker, a successful one closes it
+	if from, to := breaker.Record(false); from != BreakerHalfOpen || to != BreakerOpen {
+		t.Fatalf("expected failed trial to reopen, got %s -> %s", from, to)
+	}
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, to := breaker.Record(true); to != BreakerClosed {
+		t.Fatalf("expected successful trial to close, got %s", to)
+	}
+
+	stats := breaker.Stats()
+	if stats.State != BreakerClosed || stats.Trips != 2 || stats.Rejected != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// A nil breaker lets everything through
+	var disabled *Breaker
+	if err := disabled.Allow(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if NewBreaker(0, time.Minute) != nil {
+		t.Error("expected no breaker for a zero threshold")
+	}
+}
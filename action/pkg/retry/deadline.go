@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+)
+
+// CallTimeoutError is returned when a single API call outlived its own
+// deadline while the operation it belongs to still had time left
+type CallTimeoutError struct {
+	Timeout time.Duration
+	Method  string
+	URL     string
+}
+
+// Error implements the error interface
+func (e *CallTimeoutError) Error() string {
+	return fmt.Sprintf("call timeout: %s %s did not complete within %s", e.Method, e.URL, e.Timeout)
+}
+
+// Unwrap makes the error match context.DeadlineExceeded
+func (e *CallTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// IsRetryable reports that the call can be sent again; a hung connection
+// says nothing about the next one
+func (e *CallTimeoutError) IsRetryable() bool {
+	return true
+}
+
+// CallTimedOut reports whether err is or wraps a CallTimeoutError
+func CallTimedOut(err error) bool {
+	var timeoutErr *CallTimeoutError
+	return stderrors.As(err, &timeoutErr)
+}
+
+// remainingBudget returns how long ctx has left before its deadline, and
+// false when it has none
+func remainingBudget(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
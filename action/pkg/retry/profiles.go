@@ -0,0 +1,193 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Operation classes of Nobl9 API calls, each retried by its own policy
+const (
+	// OperationUsers resolves emails to users
+	OperationUsers = "users"
+	// OperationApply applies objects, creating or updating them
+	OperationApply = "apply"
+	// OperationOrganization reads the organization of the credentials
+	OperationOrganization = "organization"
+)
+
+// Profiles an operation class can be retried with, built by the matching
+// CreatePolicyFor constructor
+const (
+	ProfileAPI       = "api"
+	ProfileNetwork   = "network"
+	ProfileRateLimit = "rate-limit"
+	// ProfileNone makes a single attempt
+	ProfileNone = "none"
+)
+
+// DefaultProfiles is the profile of each operation class unless configured
+// otherwise
+const DefaultProfiles = "users=api,apply=api,organization=network"
+
+// DefaultMaxAttempts is how many times an operation is attempted by default
+const DefaultMaxAttempts = 3
+
+// Operations returns the operation classes, sorted
+func Operations() []string {
+	return []string{OperationApply, OperationOrganization, OperationUsers}
+}
+
+// Profiles holds the retry policy of each operation class
+type Profiles struct {
+	names    map[string]string
+	policies map[string]*Policy
+}
+
+// ParseProfiles parses comma separated operation=profile pairs, such as
+// "apply=rate-limit,organization=none". Operation classes that are not
+// listed keep their DefaultProfiles profile. Every policy makes up to
+// maxAttempts attempts; a baseDelay above zero replaces the initial delay
+// of the profiles.
+func ParseProfiles(spec string, maxAttempts int, baseDelay time.Duration) (*Profiles, error) {
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("retry attempts must be at least 1, got %d", maxAttempts)
+	}
+	if baseDelay < 0 {
+		return nil, fmt.Errorf("retry base delay cannot be negative")
+	}
+
+	names := make(map[string]string)
+	for _, s := range []string{DefaultProfiles, spec} {
+		for _, pair := range strings.Split(s, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			operation, profile, found := strings.Cut(pair, "=")
+			operation, profile = strings.TrimSpace(operation), strings.TrimSpace(profile)
+			if !found || operation == "" || profile == "" {
+				return nil, fmt.Errorf("invalid retry policy %q, expected operation=profile", pair)
+			}
+			if !isOperation(operation) {
+				return nil, fmt.Errorf("unknown operation %q in retry policy, expected one of %s", operation, strings.Join(Operations(), ", "))
+			}
+			names[operation] = profile
+		}
+	}
+
+	profiles := &Profiles{names: names, policies: make(map[string]*Policy, len(names))}
+	for operation, profile := range names {
+		policy, err := NewProfilePolicy(profile, maxAttempts, baseDelay)
+		if err != nil {
+			return nil, fmt.Errorf("retry policy of %s: %w", operation, err)
+		}
+		profiles.policies[operation] = policy
+	}
+	return profiles, nil
+}
+
+// NewProfilePolicy creates the policy of a profile making up to maxAttempts
+// attempts; a baseDelay above zero replaces its initial delay
+func NewProfilePolicy(profile string, maxAttempts int, baseDelay time.Duration) (*Policy, error) {
+	var policy *Policy
+	switch profile {
+	case ProfileAPI:
+		policy = CreatePolicyForAPI(maxAttempts)
+	case ProfileNetwork:
+		policy = CreatePolicyForNetwork(maxAttempts)
+	case ProfileRateLimit:
+		policy = CreatePolicyForRateLimit(maxAttempts)
+	case ProfileNone:
+		policy = CreatePolicyForAPI(1)
+	default:
+		return nil, fmt.Errorf("unknown retry profile %q, expected %s, %s, %s or %s", profile, ProfileAPI, ProfileNetwork, ProfileRateLimit, ProfileNone)
+	}
+
+	if baseDelay > 0 {
+		policy.InitialDelay = baseDelay
+		if policy.MaxDelay < baseDelay {
+			policy.MaxDelay = baseDelay
+		}
+	}
+	return policy, nil
+}
+
+// For returns the policy of an operation class. A nil Profiles, or an
+// unknown class, makes a single attempt.
+func (p *Profiles) For(operation string) *Policy {
+	if p != nil {
+		if policy, found := p.policies[operation]; found {
+			return policy
+		}
+	}
+	return CreatePolicyForAPI(1)
+}
+
+// String returns the profile of each operation class, e.g.
+// "apply=api,organization=network,users=api"
+func (p *Profiles) String() string {
+	if p == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(p.names))
+	for operation, profile := range p.names {
+		pairs = append(pairs, operation+"="+profile)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// isOperation reports whether operation is a known operation class
+func isOperation(operation string) bool {
+	for _, known := range Operations() {
+		if operation == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Do calls fn until it succeeds, fails with an error the policy does not
+// retry, runs out of attempts or ctx is done, waiting between attempts like
+// Retry. Unlike Retry it logs nothing and returns the error of the last
+// attempt unchanged, so callers classify and report it as if fn had been
+// called once; only when ctx would be done before the next attempt does it
+// stop early with the timeout error of the operation, which BudgetExhausted
+// reports. onRetry, if set, is called before waiting to retry.
+func Do(ctx context.Context, policy *Policy, operation string, fn func(ctx context.Context) error, onRetry func(attempt int, err error, delay time.Duration)) error {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || !isRetryableError(err, policy.RetryableErrors) {
+			return err
+		}
+
+		delay := calculateDelay(attempt, policy)
+		if retryAfter, rateLimited := RetryAfter(err); rateLimited {
+			delay = retryAfter
+		}
+		if remaining, ok := remainingBudget(ctx); ok && remaining <= delay {
+			return newBudgetError(operation, attempt, policy.MaxAttempts, delay, remaining, err)
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := ParseProfiles("apply=rate-limit, organization=none", 5, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := profiles.String(); got != "apply=rate-limit,organization=none,users=api" {
+		t.Errorf("unexpected profiles %q", got)
+	}
+
+	apply := profiles.For(OperationApply)
+	if apply.MaxAttempts != 5 || apply.InitialDelay != 2*time.Second || apply.MaxDelay != 60*time.Second {
+		t.Errorf("expected the rate limit policy with 5 attempts, got %+v", apply)
+	}
+	if organization := profiles.For(OperationOrganization); organization.MaxAttempts != 1 {
+		t.Errorf("expected a single attempt for organization, got %d", organization.MaxAttempts)
+	}
+	if users := profiles.For(OperationUsers); users.MaxAttempts != 5 || users.InitialDelay != time.Second {
+		t.Errorf("expected the API policy for users, got %+v", users)
+	}
+	if unknown := profiles.For("export"); unknown.MaxAttempts != 1 {
+		t.Errorf("expected a single attempt for an unknown operation, got %d", unknown.MaxAttempts)
+	}
+	var none *Profiles
+	if policy := none.For(OperationApply); policy.MaxAttempts != 1 {
+		t.Errorf("expected nil profiles to make a single attempt, got %d", policy.MaxAttempts)
+	}
+}
+
+func TestParseProfilesBaseDelay(t *testing.T) {
+	profiles, err := ParseProfiles("", 3, 90*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	network := profiles.For(OperationOrganization)
+	if network.InitialDelay != 90*time.Second || network.MaxDelay != 90*time.Second {
+		t.Errorf("expected the base delay to replace the initial delay and raise the maximum, got %+v", network)
+	}
+}
+
+func TestParseProfilesErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		maxAttempts int
+		baseDelay   time.Duration
+	}{
+		{spec: "apply", maxAttempts: 3},
+		{spec: "apply=forever", maxAttempts: 3},
+		{spec: "export=api", maxAttempts: 3},
+		{spec: "", maxAttempts: 0},
+		{spec: "", maxAttempts: 3, baseDelay: -time.Second},
+	}
+	for _, tt := range tests {
+		if _, err := ParseProfiles(tt.spec, tt.maxAttempts, tt.baseDelay); err == nil {
+			t.Errorf("expected an error for %q with %d attempts and %s base delay", tt.spec, tt.maxAttempts, tt.baseDelay)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	policy := NewPolicy(3, time.Millisecond, time.Millisecond, 1, 0)
+
+	attempts, retries := 0, 0
+	err := Do(context.Background(), policy, "apply objects", func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("503 Service Unavailable")
+		}
+		return nil
+	}, func(attempt int, err error, delay time.Duration) { retries++ })
+	if err != nil || attempts != 3 || retries != 2 {
+		t.Errorf("expected success on the third attempt after 2 retries, got %v after %d attempts and %d retries", err, attempts, retries)
+	}
+
+	// The error of the last attempt is returned unchanged
+	notFound := fmt.Errorf("404 Not Found")
+	attempts = 0
+	err = Do(context.Background(), policy, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return notFound
+	}, nil)
+	if err != notFound || attempts != 1 {
+		t.Errorf("expected a non-retryable error to be returned after 1 attempt, got %v after %d", err, attempts)
+	}
+
+	unavailable := fmt.Errorf("503 Service Unavailable")
+	attempts = 0
+	err = Do(context.Background(), policy, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return unavailable
+	}, nil)
+	if err != unavailable || attempts != 3 {
+		t.Errorf("expected the last error after 3 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestDoStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := NewPolicy(3, time.Minute, time.Minute, 1, 0)
+
+	attempts := 0
+	err := Do(ctx, policy, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return fmt.Errorf("timeout")
+	}, func(int, error, time.Duration) { cancel() })
+	if err == nil || attempts != 1 {
+		t.Errorf("expected the wait to end with the context, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestDoStopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	policy := NewPolicy(3, time.Second, time.Second, 1, 0)
+
+	unavailable := fmt.Errorf("503 Service Unavailable")
+	attempts, retries := 0, 0
+	err := Do(ctx, policy, "apply objects", func(ctx context.Context) error {
+		attempts++
+		return unavailable
+	}, func(int, error, time.Duration) { retries++ })
+	if !BudgetExhausted(err) || !stderrors.Is(err, unavailable) || attempts != 1 || retries != 0 {
+		t.Errorf("expected the budget error wrapping the last error without waiting past the deadline, got %v after %d attempts and %d retries", err, attempts, retries)
+	}
+	if !strings.Contains(err.Error(), "apply objects gave up after 1 of 3 attempts") {
+		t.Errorf("expected the attempts in the error, got %v", err)
+	}
+}
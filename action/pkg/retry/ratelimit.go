@@ -0,0 +1,157 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryAfterError is returned when the server rate limited a request and
+// said how long to wait before sending it again
+type RetryAfterError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Method     string
+	URL        string
+}
+
+// Error implements the error interface
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("rate limit exceeded (%d %s) for %s %s, retry after %s",
+		e.StatusCode, http.StatusText(e.StatusCode), e.Method, e.URL, e.RetryAfter)
+}
+
+// IsRetryable reports that the request can be sent again after RetryAfter
+func (e *RetryAfterError) IsRetryable() bool {
+	return true
+}
+
+// RetryAfter returns how long the server asked to wait if err is or wraps a
+// RetryAfterError
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryAfterErr *RetryAfterError
+	if stderrors.As(err, &retryAfterErr) {
+		return retryAfterErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// ParseRetryAfter parses a Retry-After header value, which is either a
+// number of seconds or an HTTP date. Dates in the past yield zero.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := at.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// Limiter is a token bucket rate limiter shared by concurrent callers. A nil
+// Limiter does not limit anything.
+type Limiter struct {
+	rate   float64 // tokens added per second
+	burst  float64 // maximum number of tokens
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// NewLimiter creates a limiter allowing rps requests per second on average.
+// Bursts of up to one second's worth of requests are allowed. It returns nil
+// when rps is not positive.
+func NewLimiter(rps float64) *Limiter {
+	if rps <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Ceil(rps))
+
+	return &Limiter{
+		rate:   rps,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or the context is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long to wait until it is available
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used
+func (l *Limiter) cancel() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Rate returns the average number of requests per second the limiter allows
+func (l *Limiter) Rate() float64 {
+	if l == nil {
+		return 0
+	}
+	return l.rate
+}
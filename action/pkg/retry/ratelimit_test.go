@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/your-org/nobl9-action/pkg/logger"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "seconds", value: "7", expected: 7 * time.Second, ok: true},
+		{name: "http date", value: "Wed, 01 May 2024 12:00:30 GMT", expected: 30 * time.Second, ok: true},
+		{name: "date in the past", value: "Wed, 01 May 2024 11:00:00 GMT", expected: 0, ok: true},
+		{name: "empty", value: "", ok: false},
+		{name: "negative", value: "-1", ok: false},
+		{name: "garbage", value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := ParseRetryAfter(tt.value, now)
+			if ok != tt.ok || delay != tt.expected {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.expected, tt.ok, delay, ok)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	log := logger.New(logger.LevelError, logger.FormatJSON)
+	// The backoff would wait far longer than the server asked
+	policy := NewPolicy(2, time.Hour, time.Hour, 2.0, 0)
+
+	attempts := 0
+	fn := func(ctx context.Context) (interface{}, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, fmt.Errorf("apply: %w", &RetryAfterError{StatusCode: 429, RetryAfter: 10 * time.Millisecond})
+		}
+		return "ok", nil
+	}
+
+	result, err := Retry(context.Background(), policy, log, "apply", fn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", result.Attempts)
+	}
+	if result.TotalDelay != 10*time.Millisecond {
+		t.Errorf("expected to wait exactly the Retry-After, got %v", result.TotalDelay)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(2)
+	start := time.Now()
+
+	// The burst is used right away; the next request waits for a token
+	if delay := limiter.reserve(start); delay != 0 {
+		t.Errorf("expected no delay, got %v", delay)
+	}
+	if delay := limiter.reserve(start); delay != 0 {
+		t.Errorf("expected no delay, got %v", delay)
+	}
+	if delay := limiter.reserve(start); delay != 500*time.Millisecond {
+		t.Errorf("expected 500ms delay, got %v", delay)
+	}
+
+	// Tokens refill over time
+	if delay := limiter.reserve(start.Add(2 * time.Second)); delay != 0 {
+		t.Errorf("expected no delay after refill, got %v", delay)
+	}
+
+	// A cancelled wait gives its token back
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter = NewLimiter(0.001)
+	limiter.reserve(time.Now())
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("expected context error")
+	}
+
+	// A nil limiter never waits
+	var unlimited *Limiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if NewLimiter(0) != nil {
+		t.Error("expected no limiter for a zero rate")
+	}
+}
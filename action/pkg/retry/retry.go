@@ -2,6 +2,7 @@ package retry
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -160,15 +161,40 @@ func Retry(ctx context.Context, policy *Policy, log *logger.Logger, operation st
 			break
 		}
 
-		// Calculate delay for next attempt
+		// Calculate delay for next attempt; a rate limited request waits
+		// exactly as long as the server asked instead of backing off
 		delay := calculateDelay(attempt, policy)
+		retryAfter, rateLimited := RetryAfter(lastError)
+		if rateLimited {
+			delay = retryAfter
+		}
+
+		// Give up instead of sleeping into a context that is done by the
+		// time the next attempt could start
+		remaining, hasDeadline := remainingBudget(ctx)
+		if hasDeadline && remaining <= delay {
+			result.LastError = lastError
+			log.Warn("Retry budget exhausted, giving up before the deadline", logger.Fields{
+				"operation":        operation,
+				"attempts":         attempt,
+				"max_attempts":     policy.MaxAttempts,
+				"delay":            delay.String(),
+				"remaining_budget": remaining.String(),
+			})
+			return result, newBudgetError(operation, attempt, policy.MaxAttempts, delay, remaining, lastError)
+		}
 		result.TotalDelay += delay
 
-		log.Debug("Waiting before retry", logger.Fields{
-			"operation": operation,
-			"attempt":   attempt,
-			"delay":     delay.String(),
-		})
+		fields := logger.Fields{
+			"operation":    operation,
+			"attempt":      attempt,
+			"delay":        delay.String(),
+			"rate_limited": rateLimited,
+		}
+		if hasDeadline {
+			fields["remaining_budget"] = remaining.String()
+		}
+		log.Debug("Waiting before retry", fields)
 
 		// Wait for the delay or context cancellation
 		select {
@@ -199,6 +225,33 @@ func Retry(ctx context.Context, policy *Policy, log *logger.Logger, operation st
 	return result, errors.NewRetryableError(fmt.Sprintf("operation %s failed after %d attempts", operation, result.Attempts), lastError)
 }
 
+// newBudgetError creates the timeout error of an operation that stopped
+// retrying because the next attempt could not start before the deadline
+func newBudgetError(operation string, attempts, maxAttempts int, delay, remaining time.Duration, err error) error {
+	if remaining < 0 {
+		remaining = 0
+	}
+	message := fmt.Sprintf("%s gave up after %d of %d attempts: the next retry in %s would pass the deadline in %s",
+		operation, attempts, maxAttempts, delay.Round(time.Millisecond), remaining.Round(time.Millisecond))
+	return errors.NewTimeoutErrorWithDetails(message, err, map[string]interface{}{
+		"attempts":         attempts,
+		"max_attempts":     maxAttempts,
+		"delay":            delay.String(),
+		"remaining_budget": remaining.String(),
+	})
+}
+
+// BudgetExhausted reports whether err is or wraps the error of an operation
+// that stopped retrying before the deadline
+func BudgetExhausted(err error) bool {
+	var nobl9Err *errors.Nobl9Error
+	if !stderrors.As(err, &nobl9Err) || nobl9Err.Type != errors.ErrorTypeTimeout {
+		return false
+	}
+	_, found := nobl9Err.Details["remaining_budget"]
+	return found
+}
+
 // RetryWithResult executes a function with retry logic and returns the result
 func RetryWithResult(ctx context.Context, policy *Policy, log *logger.Logger, operation string, fn RetryableFunc) (interface{}, error) {
 	result, err := Retry(ctx, policy, log, operation, fn)
@@ -229,6 +282,17 @@ func isRetryableError(err error, retryablePatterns []string) bool {
 		return errors.IsRetryableError(err)
 	}
 
+	// Rate limited requests can always be sent again after Retry-After
+	if _, ok := RetryAfter(err); ok {
+		return true
+	}
+
+	// A call that hit its own deadline can be sent again while the
+	// operation still has time
+	if CallTimedOut(err) {
+		return true
+	}
+
 	errorMsg := err.Error()
 	for _, pattern := range retryablePatterns {
 		if containsIgnoreCase(errorMsg, pattern) {
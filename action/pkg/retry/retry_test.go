@@ -3,9 +3,11 @@ package retry
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/your-org/nobl9-action/pkg/errors"
 	"github.com/your-org/nobl9-action/pkg/logger"
 )
 
@@ -166,6 +168,37 @@ func TestRetryWithContextCancellation(t *testing.T) {
 	}
 }
 
+func TestRetryStopsBeforeDeadline(t *testing.T) {
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	policy := NewPolicy(5, time.Second, time.Second, 1, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := Retry(ctx, policy, log, "test operation", func(ctx context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("503 Service Unavailable")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected to give up without waiting for the deadline, took %s", elapsed)
+	}
+	if result.Attempts != 1 || result.TotalDelay != 0 {
+		t.Errorf("expected 1 attempt without delay, got %d attempts and %s", result.Attempts, result.TotalDelay)
+	}
+	if !errors.IsTimeoutError(err) {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "gave up after 1 of 5 attempts") {
+		t.Errorf("expected the error to name the attempts made, got %v", err)
+	}
+	if result.LastError == nil || !strings.Contains(err.Error(), "503 Service Unavailable") {
+		t.Errorf("expected the error to wrap the last error, got %v", err)
+	}
+}
+
 func TestRetryWithResult(t *testing.T) {
 	log := logger.New(logger.LevelInfo, logger.FormatJSON)
 	policy := DefaultPolicy()
@@ -0,0 +1,100 @@
+package roles
+
+import (
+	"fmt"
+)
+
+// Binding is a role binding with every user it lists, as written in the
+// manifest: spec.user, spec.users and spec.userIds
+type Binding struct {
+	// Source is the file the role binding was read from
+	Source string
+	Name   string
+	// Project is the projectRef, empty for an organization role binding
+	Project string
+	Role    string
+	Users   []string
+}
+
+// Duplicate is a user granted a role that is already granted to the same
+// user, either earlier in the same role binding or by another role binding
+// of the same project
+type Duplicate struct {
+	Binding Binding
+	User    string
+	// First is the role binding that first grants the role, and FirstUser
+	// the user as written there
+	First     Binding
+	FirstUser string
+}
+
+// Within reports whether the user is listed twice in one role binding
+func (d Duplicate) Within() bool {
+	return d.Binding.Source == d.First.Source && d.Binding.Name == d.First.Name
+}
+
+// String describes the duplicate
+func (d Duplicate) String() string {
+	user := fmt.Sprintf("'%s'", d.User)
+	if d.FirstUser != d.User {
+		user = fmt.Sprintf("'%s' (same user as '%s')", d.User, d.FirstUser)
+	}
+	if d.Within() {
+		return fmt.Sprintf("user %s is listed more than once", user)
+	}
+	return fmt.Sprintf("user %s is already granted role '%s' %s by role binding '%s' in %s", user, d.Role(), scopeOf(d.Binding.Project), d.First.Name, d.First.Source)
+}
+
+// Role is the role granted twice
+func (d Duplicate) Role() string {
+	return d.Binding.Role
+}
+
+// FindDuplicates returns the users granted the same role more than once,
+// within a role binding or across the role bindings of a project. identity
+// maps a user as written to the user it stands for, e.g. a normalized email
+// or a resolved user ID, so differently written emails of one user are
+// found too; a nil identity compares users as written. The first grant is
+// kept and every later one is reported.
+func FindDuplicates(bindings []Binding, identity func(user string) string) []Duplicate {
+	type grant struct{ project, role, user string }
+	type first struct {
+		binding Binding
+		user    string
+	}
+	granted := make(map[grant]first)
+
+	var duplicates []Duplicate
+	for _, binding := range bindings {
+		for _, user := range binding.Users {
+			if user == "" {
+				continue
+			}
+			id := user
+			if identity != nil {
+				id = identity(user)
+			}
+
+			key := grant{project: binding.Project, role: binding.Role, user: id}
+			if existing, found := granted[key]; found {
+				duplicates = append(duplicates, Duplicate{
+					Binding:   binding,
+					User:      user,
+					First:     existing.binding,
+					FirstUser: existing.user,
+				})
+				continue
+			}
+			granted[key] = first{binding: binding, user: user}
+		}
+	}
+	return duplicates
+}
+
+// scopeOf describes where a role is granted
+func scopeOf(project string) string {
+	if project == "" {
+		return "in the organization"
+	}
+	return fmt.Sprintf("in project '%s'", project)
+}
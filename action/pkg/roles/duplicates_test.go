@@ -0,0 +1,34 @@
+package roles
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	bindings := []Binding{
+		{Source: "team.yaml", Name: "team", Project: "payments", Role: "project-viewer", Users: []string{"alice@example.com", "bob@example.com", "Alice@Example.com"}},
+		{Source: "owners.yaml", Name: "owners", Project: "payments", Role: "project-owner", Users: []string{"alice@example.com"}},
+		{Source: "extra.yaml", Name: "extra", Project: "payments", Role: "project-viewer", Users: []string{"bob@example.com"}},
+		{Source: "other.yaml", Name: "other", Project: "checkout", Role: "project-viewer", Users: []string{"bob@example.com"}},
+	}
+
+	// Compared as written, only the second bob is a duplicate
+	duplicates := FindDuplicates(bindings, nil)
+	if len(duplicates) != 1 || duplicates[0].Binding.Name != "extra" || duplicates[0].Within() {
+		t.Fatalf("unexpected duplicates %+v", duplicates)
+	}
+	expected := "user 'bob@example.com' is already granted role 'project-viewer' in project 'payments' by role binding 'team' in team.yaml"
+	if duplicates[0].String() != expected {
+		t.Errorf("expected %q, got %q", expected, duplicates[0].String())
+	}
+
+	// Differently written emails of one user are found with an identity
+	duplicates = FindDuplicates(bindings, strings.ToLower)
+	if len(duplicates) != 2 {
+		t.Fatalf("expected 2 duplicates, got %+v", duplicates)
+	}
+	if !duplicates[0].Within() || duplicates[0].String() != "user 'Alice@Example.com' (same user as 'alice@example.com') is listed more than once" {
+		t.Errorf("unexpected duplicate %q", duplicates[0].String())
+	}
+}
@@ -0,0 +1,259 @@
+package roles
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/your-org/nobl9-action/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultName selects the embedded catalog of built-in roles alone
+const DefaultName = "default"
+
+// maxSuggestionDistance is the number of edits up to which a known role is
+// suggested for an unknown one
+const maxSuggestionDistance = 3
+
+//go:embed catalog.yaml
+var defaultCatalog []byte
+
+// Catalog lists the roles role bindings may reference, by scope: project
+// roles are granted with a projectRef, organization roles without one
+type Catalog struct {
+	Project      []string `yaml:"project"`
+	Organization []string `yaml:"organization"`
+}
+
+// UnknownRole is a role binding whose role is not in the catalog
+type UnknownRole struct {
+	// Name is the name of the role binding and Project its projectRef,
+	// empty for an organization role binding
+	Name    string
+	Project string
+	Role    string
+	// Suggestion is the closest known role of the same scope, if any
+	Suggestion string
+}
+
+// Error describes the unknown role
+func (u UnknownRole) Error() string {
+	message := fmt.Sprintf("role '%s' is not a known %s role", u.Role, scope(u.Project != ""))
+	if u.Suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", u.Suggestion)
+	}
+	return message
+}
+
+// Load returns the built-in catalog for DefaultName; any other path names a
+// catalog file whose roles are added to the built-in ones
+func Load(path string) (*Catalog, error) {
+	catalog := Default()
+	if path == DefaultName {
+		return catalog, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("failed to read role catalog %s", path), err)
+	}
+	custom, err := parse(data, path)
+	if err != nil {
+		return nil, err
+	}
+	catalog.Project = append(catalog.Project, custom.Project...)
+	catalog.Organization = append(catalog.Organization, custom.Organization...)
+	return catalog, nil
+}
+
+// Default returns the embedded catalog of built-in Nobl9 roles
+func Default() *Catalog {
+	catalog, err := parse(defaultCatalog, "")
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded role catalog: %v", err))
+	}
+	return catalog
+}
+
+// ValidateDefault checks the embedded catalog, so a broken build fails at
+// startup rather than when the catalog is first used
+func ValidateDefault() error {
+	_, err := parse(defaultCatalog, DefaultName)
+	return err
+}
+
+// Parse parses a catalog. Unknown keys are rejected so a typo does not
+// silently drop custom roles.
+func Parse(data []byte) (*Catalog, error) {
+	return parse(data, "")
+}
+
+// parse parses the catalog of a file. Errors name the file, if any, and
+// the line at fault.
+func parse(data []byte, path string) (*Catalog, error) {
+	invalid := "invalid role catalog"
+	if path != "" {
+		invalid = fmt.Sprintf("invalid role catalog %s", path)
+	}
+
+	var catalog Catalog
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&catalog); err != nil {
+		return nil, errors.NewConfigError(invalid, err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, errors.NewConfigError(invalid, err)
+	}
+	if line := emptyRoleLine(&document); line > 0 {
+		return nil, errors.NewConfigError(fmt.Sprintf("%s: line %d: empty role name", invalid, line), nil)
+	}
+	return &catalog, nil
+}
+
+// emptyRoleLine returns the line of the first empty role name of a catalog
+// document, or 0 when every role is named
+func emptyRoleLine(document *yaml.Node) int {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return 0
+	}
+	scopes := document.Content[0]
+	for i := 1; i < len(scopes.Content); i += 2 {
+		for _, role := range scopes.Content[i].Content {
+			if strings.TrimSpace(role.Value) == "" {
+				return role.Line
+			}
+		}
+	}
+	return 0
+}
+
+// Known reports whether role is a role of the given scope
+func (c *Catalog) Known(role string, projectScoped bool) bool {
+	for _, known := range c.roles(projectScoped) {
+		if known == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Learn adds the roles of live role bindings to the catalog, so custom
+// roles already granted in the organization are accepted
+func (c *Catalog) Learn(bindings []v1alphaRoleBinding.RoleBinding) {
+	for _, binding := range bindings {
+		projectScoped := binding.Spec.ProjectRef != ""
+		if binding.Spec.RoleRef == "" || c.Known(binding.Spec.RoleRef, projectScoped) {
+			continue
+		}
+		if projectScoped {
+			c.Project = append(c.Project, binding.Spec.RoleRef)
+		} else {
+			c.Organization = append(c.Organization, binding.Spec.RoleRef)
+		}
+	}
+}
+
+// Check returns the role bindings among objects whose role is not in the
+// catalog, each with the closest known role of its scope
+func (c *Catalog) Check(objects []manifest.Object) []UnknownRole {
+	var unknown []UnknownRole
+	for _, obj := range objects {
+		binding, ok := obj.(v1alphaRoleBinding.RoleBinding)
+		if !ok {
+			continue
+		}
+		projectScoped := binding.Spec.ProjectRef != ""
+		if c.Known(binding.Spec.RoleRef, projectScoped) {
+			continue
+		}
+		unknown = append(unknown, UnknownRole{
+			Name:       binding.GetName(),
+			Project:    binding.Spec.ProjectRef,
+			Role:       binding.Spec.RoleRef,
+			Suggestion: c.suggest(binding.Spec.RoleRef, projectScoped),
+		})
+	}
+	return unknown
+}
+
+// Error combines the unknown roles of a file into one validation error; it
+// returns nil when there are none
+func Error(unknown []UnknownRole) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, u := range unknown {
+		names[u.Role] = true
+	}
+	roles := make([]string, 0, len(names))
+	for role := range names {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	return errors.NewValidationErrorWithDetails(
+		fmt.Sprintf("%d role bindings reference unknown roles (%s)", len(unknown), strings.Join(roles, ", ")),
+		nil,
+		map[string]interface{}{"roles": roles, "role_bindings": len(unknown)},
+	)
+}
+
+// roles returns the roles of a scope
+func (c *Catalog) roles(projectScoped bool) []string {
+	if projectScoped {
+		return c.Project
+	}
+	return c.Organization
+}
+
+// suggest returns the known role of the scope closest to role, or "" when
+// none is within maxSuggestionDistance edits
+func (c *Catalog) suggest(role string, projectScoped bool) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, known := range c.roles(projectScoped) {
+		if d := distance(strings.ToLower(role), known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// scope names the scope of a role in messages
+func scope(projectScoped bool) string {
+	if projectScoped {
+		return "project"
+	}
+	return "organization"
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,144 @@
+package roles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const bindings = `
+- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec: {}
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-alice
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-bob
+  spec:
+    user: 00u1bob
+    roleRef: project-editr
+    projectRef: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: carol-admin
+  spec:
+    user: 00u1carol
+    roleRef: project-viewer
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-dave
+  spec:
+    user: 00u1dave
+    roleRef: payments-auditor
+    projectRef: payments
+`
+
+func decode(t *testing.T, data string) []manifest.Object {
+	t.Helper()
+	decoded, err := sdk.DecodeObjects([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return decoded
+}
+
+func TestCheck(t *testing.T) {
+	unknown := Default().Check(decode(t, bindings))
+
+	var messages []string
+	for _, u := range unknown {
+		messages = append(messages, u.Name+": "+u.Error())
+	}
+	expected := []string{
+		"payments-bob: role 'project-editr' is not a known project role (did you mean project-editor?)",
+		"carol-admin: role 'project-viewer' is not a known organization role",
+		"payments-dave: role 'payments-auditor' is not a known project role",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected unknown roles:\n%s", strings.Join(messages, "\n"))
+	}
+
+	err := Error(unknown)
+	if err == nil || !strings.Contains(err.Error(), "3 role bindings reference unknown roles (payments-auditor, project-editr, project-viewer)") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if Error(nil) != nil {
+		t.Error("expected no error without unknown roles")
+	}
+}
+
+func TestLearn(t *testing.T) {
+	catalog := Default()
+	catalog.Learn([]v1alphaRoleBinding.RoleBinding{
+		v1alphaRoleBinding.New(v1alphaRoleBinding.Metadata{Name: "custom"},
+			v1alphaRoleBinding.Spec{RoleRef: "payments-auditor", ProjectRef: "shared"}),
+	})
+
+	if !catalog.Known("payments-auditor", true) {
+		t.Error("expected a role granted by a live role binding to be known")
+	}
+	if catalog.Known("payments-auditor", false) {
+		t.Error("expected a learned project role not to be an organization role")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "roles.yaml")
+	if err := os.WriteFile(path, []byte("project: [payments-auditor]\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	catalog, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !catalog.Known("payments-auditor", true) || !catalog.Known("project-owner", true) {
+		t.Errorf("expected custom roles to extend the built-in ones, got %+v", catalog)
+	}
+
+	for name, content := range map[string]string{
+		"unknown key": "projects: [payments-auditor]\n",
+		"empty role":  "organization: ['']\n",
+	} {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected a catalog with an %s to be rejected", name)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected a missing catalog to be rejected")
+	}
+
+	// The file and line of an empty role are reported
+	path = filepath.Join(dir, "located.yaml")
+	if err := os.WriteFile(path, []byte("project:\n  - payments-auditor\n  - ''\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path+": line 3: empty role name") {
+		t.Errorf("expected the location of the empty role, got %v", err)
+	}
+	if err := ValidateDefault(); err != nil {
+		t.Errorf("expected the built-in catalog to be valid, got %v", err)
+	}
+}
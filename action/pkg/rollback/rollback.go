@@ -0,0 +1,127 @@
+package rollback
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// Record is the state of an object in Nobl9 before a run applied it: its
+// previous definition, or that the run created it
+type Record struct {
+	// Previous is the live definition the object had before it was applied
+	Previous json.RawMessage `json:"previous,omitempty"`
+	// Created is set when the object did not exist before it was applied
+	Created bool `json:"created,omitempty"`
+	// Project is the project a created object is deleted from, which for
+	// role bindings is their projectRef
+	Project string `json:"project,omitempty"`
+}
+
+// NewRecord records the state of an applied object. live is its version
+// read before the apply, or nil when it did not exist.
+func NewRecord(applied, live manifest.Object) (*Record, error) {
+	if live == nil {
+		return &Record{Created: true, Project: planner.ProjectOf(applied)}, nil
+	}
+	data, err := json.Marshal(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode previous %s %s: %w", live.GetKind(), live.GetName(), err)
+	}
+	return &Record{Previous: data}, nil
+}
+
+// Entry is an applied object to roll back. Record is nil when the state
+// of the object before the apply could not be read.
+type Entry struct {
+	Kind    string
+	Project string
+	Name    string
+	Source  string
+	Record  *Record
+}
+
+// Target identifies an object of a rollback step
+type Target struct {
+	Kind    manifest.Kind
+	Project string
+	Name    string
+	Source  string
+}
+
+// String returns the target as kind project/name, or kind name for
+// objects outside a project
+func (t Target) String() string {
+	if t.Project == "" {
+		return fmt.Sprintf("%s %s", t.Kind, t.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", t.Kind, t.Project, t.Name)
+}
+
+// Restore is a previous definition to apply again
+type Restore struct {
+	Target Target
+	Object manifest.Object
+}
+
+// Plan lists the rollback steps: previous definitions to restore in
+// dependency order, then created objects to delete in reverse dependency
+// order, so projects are restored first and deleted last
+type Plan struct {
+	Restore []Restore
+	Delete  []Target
+	// Unknown are applied objects whose previous state was not recorded;
+	// they are left as applied
+	Unknown []Target
+}
+
+// NewPlan plans the rollback of the applied objects
+func NewPlan(entries []Entry) (*Plan, error) {
+	levels, err := planner.New().Levels()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, entry := range entries {
+		kind, err := manifest.ParseKind(entry.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("object %s of %s: %w", entry.Name, entry.Source, err)
+		}
+		target := Target{Kind: kind, Project: entry.Project, Name: entry.Name, Source: entry.Source}
+
+		switch {
+		case entry.Record == nil:
+			plan.Unknown = append(plan.Unknown, target)
+		case entry.Record.Created:
+			target.Project = entry.Record.Project
+			plan.Delete = append(plan.Delete, target)
+		default:
+			objects, err := sdk.DecodeObjects(entry.Record.Previous)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode previous definition of %s: %w", target, err)
+			}
+			if len(objects) != 1 {
+				return nil, fmt.Errorf("previous definition of %s holds %d objects, expected 1", target, len(objects))
+			}
+			plan.Restore = append(plan.Restore, Restore{Target: target, Object: objects[0]})
+		}
+	}
+
+	sort.SliceStable(plan.Restore, func(i, j int) bool {
+		return levels[plan.Restore[i].Target.Kind] < levels[plan.Restore[j].Target.Kind]
+	})
+	sort.SliceStable(plan.Delete, func(i, j int) bool {
+		return levels[plan.Delete[i].Kind] > levels[plan.Delete[j].Kind]
+	})
+	return plan, nil
+}
+
+// Steps returns the number of restores and deletions of the plan
+func (p *Plan) Steps() int {
+	return len(p.Restore) + len(p.Delete)
+}
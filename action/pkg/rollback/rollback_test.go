@@ -0,0 +1,111 @@
+package rollback
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+const applied = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+`
+
+const live = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+  spec:
+    description: Before the run
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+  spec:
+    description: Before the run
+`
+
+func TestNewPlan(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(applied))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous, err := sdk.DecodeObjects([]byte(live))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The role binding was created; the project and service existed and
+	// are listed service first to check restores follow dependency order
+	var entries []Entry
+	for _, i := range []int{1, 0, 2} {
+		var before manifest.Object
+		if i < len(previous) {
+			before = previous[i]
+		}
+		record, err := NewRecord(objects[i], before)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, Entry{Kind: objects[i].GetKind().String(), Name: objects[i].GetName(), Source: "payments.yaml", Record: record})
+	}
+	entries = append(entries, Entry{Kind: "SLO", Project: "payments", Name: "latency", Source: "payments.yaml"})
+
+	plan, err := NewPlan(entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var restored []string
+	for _, restore := range plan.Restore {
+		restored = append(restored, restore.Target.String())
+		if !strings.Contains(mustJSON(t, restore.Object), "Before the run") {
+			t.Errorf("expected the previous definition of %s, got %s", restore.Target, mustJSON(t, restore.Object))
+		}
+	}
+	if strings.Join(restored, ", ") != "Project payments, Service checkout" {
+		t.Errorf("unexpected restores: %v", restored)
+	}
+	if len(plan.Delete) != 1 || plan.Delete[0].String() != "RoleBinding payments/payments-owner" {
+		t.Errorf("expected the created role binding to be deleted from its project, got %v", plan.Delete)
+	}
+	if len(plan.Unknown) != 1 || plan.Unknown[0].String() != "SLO payments/latency" {
+		t.Errorf("expected the unrecorded SLO to be unknown, got %v", plan.Unknown)
+	}
+	if plan.Steps() != 3 {
+		t.Errorf("expected 3 steps, got %d", plan.Steps())
+	}
+}
+
+func TestNewPlanRejectsUnknownKinds(t *testing.T) {
+	_, err := NewPlan([]Entry{{Kind: "Widget", Name: "w", Source: "w.yaml", Record: &Record{Created: true}}})
+	if err == nil {
+		t.Fatal("expected an error for an unknown kind")
+	}
+}
+
+func mustJSON(t *testing.T, obj manifest.Object) string {
+	t.Helper()
+	record, err := NewRecord(obj, obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(record.Previous)
+}
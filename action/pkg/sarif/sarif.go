@@ -0,0 +1,182 @@
+// Package sarif writes findings as a SARIF 2.1.0 log, the format GitHub code
+// scanning reads, so invalid objects show up on the lines of a pull request
+// that introduced them.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Version is the SARIF version of written logs
+const Version = "2.1.0"
+
+// Schema is the JSON schema of written logs
+const Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Levels of results
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// Rule describes a kind of finding
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Result is one finding in a file. Line and Column are 1-based; a zero Line
+// places the finding on the file as a whole.
+type Result struct {
+	RuleID  string
+	Level   string
+	Message string
+	Path    string
+	Line    int
+	Column  int
+}
+
+// Log collects the results of one run of a tool. It is safe for concurrent
+// use.
+type Log struct {
+	tool    string
+	version string
+	rules   []Rule
+
+	mu      sync.Mutex
+	results []Result
+}
+
+// New creates an empty log of the tool and its rules
+func New(tool, version string, rules ...Rule) *Log {
+	return &Log{tool: tool, version: version, rules: rules}
+}
+
+// Add adds a result; an empty level is an error
+func (l *Log) Add(result Result) {
+	if result.Level == "" {
+		result.Level = LevelError
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.results = append(l.results, result)
+}
+
+// Len returns the number of results
+func (l *Log) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.results)
+}
+
+// Marshal encodes the log, with the results ordered by file, line and column
+func (l *Log) Marshal() ([]byte, error) {
+	l.mu.Lock()
+	results := append([]Result(nil), l.results...)
+	l.mu.Unlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	driver := toolComponent{Name: l.tool, Version: l.version, Rules: make([]reportingDescriptor, 0, len(l.rules))}
+	for _, rule := range l.rules {
+		driver.Rules = append(driver.Rules, reportingDescriptor{ID: rule.ID, ShortDescription: message{Text: rule.Description}})
+	}
+	logRun := run{Tool: tool{Driver: driver}, Results: make([]result, 0, len(results))}
+	for _, r := range results {
+		at := location{PhysicalLocation: physicalLocation{ArtifactLocation: artifactLocation{URI: r.Path}}}
+		if r.Line > 0 {
+			at.PhysicalLocation.Region = &region{StartLine: r.Line, StartColumn: r.Column}
+		}
+		logRun.Results = append(logRun.Results, result{
+			RuleID:    r.RuleID,
+			Level:     r.Level,
+			Message:   message{Text: r.Message},
+			Locations: []location{at},
+		})
+	}
+
+	return json.MarshalIndent(sarifLog{Schema: Schema, Version: Version, Runs: []run{logRun}}, "", "  ")
+}
+
+// Write writes the log to a file
+func (l *Log) Write(path string) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF log: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write SARIF log %s: %w", path, err)
+	}
+	return nil
+}
+
+// The SARIF objects written, named as in the specification
+
+type sarifLog struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool    tool     `json:"tool"`
+	Results []result `json:"results"`
+}
+
+type tool struct {
+	Driver toolComponent `json:"driver"`
+}
+
+type toolComponent struct {
+	Name    string                `json:"name"`
+	Version string                `json:"version,omitempty"`
+	Rules   []reportingDescriptor `json:"rules"`
+}
+
+type reportingDescriptor struct {
+	ID               string  `json:"id"`
+	ShortDescription message `json:"shortDescription"`
+}
+
+type result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   message    `json:"message"`
+	Locations []location `json:"locations"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type location struct {
+	PhysicalLocation physicalLocation `json:"physicalLocation"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifactLocation `json:"artifactLocation"`
+	Region           *region          `json:"region,omitempty"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
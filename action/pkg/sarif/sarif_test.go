@@ -0,0 +1,81 @@
+package sarif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogWrite(t *testing.T) {
+	log := New("nobl9-action", "v1.2.3", Rule{ID: "invalid-object", Description: "Nobl9 object fails validation"})
+	log.Add(Result{RuleID: "invalid-object", Message: "SLO 'latency': objective is required", Path: "slos/payments.yaml", Line: 12, Column: 5})
+	log.Add(Result{RuleID: "invalid-object", Message: "Project 'payments': name is invalid", Path: "projects/payments.yaml"})
+	if log.Len() != 2 {
+		t.Fatalf("expected 2 results, got %d", log.Len())
+	}
+
+	path := filepath.Join(t.TempDir(), "nobl9.sarif")
+	if err := log.Write(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var written struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if written.Version != Version || len(written.Runs) != 1 {
+		t.Fatalf("expected one SARIF %s run, got %s", Version, data)
+	}
+	run := written.Runs[0]
+	if run.Tool.Driver.Name != "nobl9-action" || len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "invalid-object" {
+		t.Errorf("unexpected tool %+v", run.Tool)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+
+	// Results are ordered by file; a result without a line has no region
+	first, second := run.Results[0].Locations[0].PhysicalLocation, run.Results[1].Locations[0].PhysicalLocation
+	if first.ArtifactLocation.URI != "projects/payments.yaml" || first.Region != nil {
+		t.Errorf("expected the project file without a region first, got %+v", first)
+	}
+	if second.ArtifactLocation.URI != "slos/payments.yaml" || second.Region == nil || second.Region.StartLine != 12 || second.Region.StartColumn != 5 {
+		t.Errorf("expected the SLO at line 12, column 5, got %+v", second)
+	}
+	if run.Results[0].Level != LevelError {
+		t.Errorf("expected results to default to errors, got %s", run.Results[0].Level)
+	}
+}
@@ -0,0 +1,191 @@
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+	"github.com/your-org/nobl9-action/pkg/generate"
+	"github.com/your-org/nobl9-action/pkg/kindlist"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/yamlfmt"
+)
+
+// PlaceholderOwner is the owner given the project-owner role when no owner
+// is given, to be replaced before the manifests are applied
+const PlaceholderOwner = "owner@example.com"
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Kinds are the kinds with a template, in the order their files are written
+var Kinds = []manifest.Kind{manifest.KindProject, manifest.KindRoleBinding, manifest.KindService, manifest.KindSLO}
+
+// kindFiles are the template and file names of each kind
+var kindFiles = map[manifest.Kind]struct{ template, file string }{
+	manifest.KindProject:     {"project.yaml.tmpl", "project.yaml"},
+	manifest.KindRoleBinding: {"rolebinding.yaml.tmpl", "rolebindings.yaml"},
+	manifest.KindService:     {"service.yaml.tmpl", "services.yaml"},
+	manifest.KindSLO:         {"slo.yaml.tmpl", "slos.yaml"},
+}
+
+// nameRegexp matches the project, service and team names allowed in object
+// names
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// templates are the kind templates, sharing the blocks of _meta.yaml.tmpl
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"slug":  generate.Slug,
+	"quote": strconv.Quote,
+}).Option("missingkey=error").ParseFS(templateFS, "templates/*.tmpl"))
+
+// Project is the input of a scaffold
+type Project struct {
+	// Name of the project
+	Name string
+	// DisplayName defaults to the name
+	DisplayName string
+	// Description defaults to "<display name> project"
+	Description string
+	// Owners are the emails given the project-owner role, PlaceholderOwner
+	// when empty
+	Owners []string
+	// Service covered by the example SLO, defaults to the project name
+	Service string
+	// Team owning the files, written to their ActionMeta document and team
+	// label when set
+	Team string
+}
+
+// File is a scaffolded manifest, holding the objects of one kind
+type File struct {
+	Kind    manifest.Kind
+	Name    string
+	Content []byte
+	Objects []manifest.Object
+}
+
+// ParseKinds parses a comma separated list of kinds with a template, or
+// all for every one of Kinds. The kinds are returned in the order of Kinds.
+func ParseKinds(spec string) ([]manifest.Kind, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "all") {
+		return Kinds, nil
+	}
+	parsed, err := kindlist.Parse(spec, hasTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no kinds selected")
+	}
+	selected := make(map[manifest.Kind]bool, len(parsed))
+	for _, kind := range parsed {
+		selected[kind] = true
+	}
+	var kinds []manifest.Kind
+	for _, kind := range Kinds {
+		if selected[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// hasTemplate rejects the kinds without a template
+func hasTemplate(kind manifest.Kind) error {
+	if _, found := kindFiles[kind]; !found {
+		return fmt.Errorf("no template for kind '%s', expected %s", kind, kindNames())
+	}
+	return nil
+}
+
+// Render writes the manifests of the kinds for a project, one file per
+// kind, in the canonical form of the fmt command. Every file decodes and
+// validates as Nobl9 objects; the fields a team has to fill in are marked
+// with TODO comments.
+func Render(project Project, kinds []manifest.Kind) ([]*File, error) {
+	if err := project.normalize(); err != nil {
+		return nil, err
+	}
+	files := make([]*File, 0, len(kinds))
+	for _, kind := range kinds {
+		names, found := kindFiles[kind]
+		if !found {
+			return nil, fmt.Errorf("no template for kind '%s'", kind)
+		}
+		var rendered bytes.Buffer
+		if err := templates.ExecuteTemplate(&rendered, names.template, project); err != nil {
+			return nil, fmt.Errorf("%s: %w", kind, err)
+		}
+		content, err := yamlfmt.Format(rendered.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names.file, err)
+		}
+		file := &File{Kind: kind, Name: names.file, Content: content}
+
+		_, documents, err := parser.ExtractMeta(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid %s: %w", file.Name, parser.MetaKind, err)
+		}
+		if file.Objects, err = sdk.DecodeObjects(documents); err != nil {
+			return nil, fmt.Errorf("%s: invalid Nobl9 YAML: %w", file.Name, err)
+		}
+		for _, obj := range file.Objects {
+			if err := obj.Validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// normalize fills in the defaults of a project and checks its fields
+func (p *Project) normalize() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if !nameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("project name '%s' must be lowercase letters, digits and dashes", p.Name)
+	}
+	if p.DisplayName == "" {
+		p.DisplayName = p.Name
+	}
+	if p.Description == "" {
+		p.Description = p.DisplayName + " project"
+	}
+	if p.Service == "" {
+		p.Service = p.Name
+	}
+	if !nameRegexp.MatchString(p.Service) {
+		return fmt.Errorf("service name '%s' must be lowercase letters, digits and dashes", p.Service)
+	}
+	if p.Team != "" && !nameRegexp.MatchString(p.Team) {
+		return fmt.Errorf("team name '%s' must be lowercase letters, digits and dashes", p.Team)
+	}
+
+	if len(p.Owners) == 0 {
+		p.Owners = []string{PlaceholderOwner}
+	}
+	for i, owner := range p.Owners {
+		p.Owners[i] = strings.TrimSpace(owner)
+		if !strings.Contains(p.Owners[i], "@") {
+			return fmt.Errorf("owner '%s' is not an email", owner)
+		}
+	}
+	return nil
+}
+
+// kindNames lists the kinds with a template, e.g. for error messages
+func kindNames() string {
+	names := make([]string, 0, len(Kinds))
+	for _, kind := range Kinds {
+		names = append(names, kind.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,118 @@
+package scaffold
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	v1alphaRoleBinding "github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
+	v1alphaSLO "github.com/nobl9/nobl9-go/manifest/v1alpha/slo"
+	"github.com/your-org/nobl9-action/pkg/parser"
+	"github.com/your-org/nobl9-action/pkg/yamlfmt"
+)
+
+func TestRender(t *testing.T) {
+	files, err := Render(Project{
+		Name:   "payments",
+		Owners: []string{"alice@example.com", " bob@example.com"},
+		Team:   "payments-team",
+	}, Kinds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+		meta, _, err := parser.ExtractMeta(file.Content)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", file.Name, err)
+		}
+		if meta == nil || meta.Spec.Owner != "payments-team" {
+			t.Errorf("%s: expected the team as ActionMeta owner, got %+v", file.Name, meta)
+		}
+		if formatted, err := yamlfmt.Format(file.Content); err != nil || string(formatted) != string(file.Content) {
+			t.Errorf("%s: expected formatted content, got:\n%s", file.Name, file.Content)
+		}
+		for _, obj := range file.Objects {
+			if obj.GetKind() != file.Kind {
+				t.Errorf("%s: unexpected %s", file.Name, obj.GetKind())
+			}
+		}
+	}
+	if got := strings.Join(names, ","); got != "project.yaml,rolebindings.yaml,services.yaml,slos.yaml" {
+		t.Errorf("unexpected files %s", got)
+	}
+
+	bindings := files[1].Objects
+	if len(bindings) != 2 {
+		t.Fatalf("expected a role binding per owner, got %d", len(bindings))
+	}
+	binding := bindings[1].(v1alphaRoleBinding.RoleBinding)
+	if binding.Metadata.Name != "payments-bob-example-com" || *binding.Spec.User != "bob@example.com" ||
+		binding.Spec.RoleRef != "project-owner" || binding.Spec.ProjectRef != "payments" {
+		t.Errorf("unexpected role binding %+v", binding)
+	}
+
+	slo := files[3].Objects[0].(v1alphaSLO.SLO)
+	if slo.Metadata.Project != "payments" || slo.Spec.Service != "payments" || slo.Metadata.Name != "payments-availability" {
+		t.Errorf("unexpected SLO %+v", slo.Metadata)
+	}
+	if !strings.Contains(string(files[3].Content), "# TODO:") {
+		t.Error("expected TODO placeholders in the SLO manifest")
+	}
+}
+
+func TestRenderDefaults(t *testing.T) {
+	files, err := Render(Project{Name: "checkout", Service: "api"}, []manifest.Kind{manifest.KindRoleBinding, manifest.KindService})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	for _, file := range files {
+		if strings.Contains(string(file.Content), parser.MetaKind) {
+			t.Errorf("%s: unexpected %s without a team", file.Name, parser.MetaKind)
+		}
+	}
+	binding := files[0].Objects[0].(v1alphaRoleBinding.RoleBinding)
+	if *binding.Spec.User != PlaceholderOwner {
+		t.Errorf("expected the placeholder owner, got %s", *binding.Spec.User)
+	}
+	if name := files[1].Objects[0].GetName(); name != "api" {
+		t.Errorf("expected service api, got %s", name)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := map[string]Project{
+		"project name":    {Name: "Payments"},
+		"service name":    {Name: "payments", Service: "Checkout API"},
+		"team name":       {Name: "payments", Team: "Payments Team"},
+		"is not an email": {Name: "payments", Owners: []string{"alice"}},
+	}
+	for want, project := range tests {
+		if _, err := Render(project, Kinds); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error about %s, got %v", want, err)
+		}
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds("slo, project")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kinds) != 2 || kinds[0] != manifest.KindProject || kinds[1] != manifest.KindSLO {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+	if kinds, err := ParseKinds("all"); err != nil || len(kinds) != len(Kinds) {
+		t.Errorf("expected every kind, got %v, %v", kinds, err)
+	}
+	for _, spec := range []string{"alertpolicy", "widget", ","} {
+		if _, err := ParseKinds(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
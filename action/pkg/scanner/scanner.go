@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -24,6 +25,7 @@ type FileInfo struct {
 	ModTime      fs.FileInfo
 	IsDir        bool
 	IsYAML       bool
+	IsJSON       bool
 	IsNobl9      bool
 	Content      []byte
 	Error        error
@@ -34,6 +36,7 @@ type ScanResult struct {
 	Files        []*FileInfo
 	TotalFiles   int
 	YAMLFiles    int
+	JSONFiles    int
 	Nobl9Files   int
 	Errors       []error
 	ScanDuration string
@@ -79,11 +82,13 @@ func (s *Scanner) Scan(repoPath, filePattern string) (*ScanResult, error) {
 	// Update statistics
 	result.TotalFiles = len(result.Files)
 	result.YAMLFiles = s.countYAMLFiles(result.Files)
+	result.JSONFiles = s.countJSONFiles(result.Files)
 	result.Nobl9Files = s.countNobl9Files(result.Files)
 
 	logrus.WithFields(logrus.Fields{
 		"total_files": result.TotalFiles,
 		"yaml_files":  result.YAMLFiles,
+		"json_files":  result.JSONFiles,
 		"nobl9_files": result.Nobl9Files,
 		"errors":      len(result.Errors),
 	}).Info("Repository file scan completed")
@@ -178,11 +183,12 @@ func (s *Scanner) processFile(filePath string, result *ScanResult) error {
 		ModTime:      info,
 		IsDir:        false,
 		IsYAML:       s.isYAMLFile(filePath),
+		IsJSON:       s.isJSONFile(filePath),
 		IsNobl9:      false, // Will be determined after content analysis
 	}
 
-	// Read file content for YAML files
-	if fileInfo.IsYAML {
+	// Read file content for YAML and JSON files
+	if fileInfo.IsYAML || fileInfo.IsJSON {
 		content, err := os.ReadFile(filePath)
 		if err != nil {
 			fileInfo.Error = fmt.Errorf("failed to read file: %w", err)
@@ -197,6 +203,7 @@ func (s *Scanner) processFile(filePath string, result *ScanResult) error {
 	logrus.WithFields(logrus.Fields{
 		"file_path": filePath,
 		"is_yaml":   fileInfo.IsYAML,
+		"is_json":   fileInfo.IsJSON,
 		"is_nobl9":  fileInfo.IsNobl9,
 		"size":      fileInfo.Size,
 	}).Debug("File processed")
@@ -217,35 +224,61 @@ func (s *Scanner) isYAMLFile(filePath string) bool {
 	return ext == ".yaml" || ext == ".yml"
 }
 
-// isNobl9File checks if file content contains Nobl9 configuration
-func (s *Scanner) isNobl9File(content []byte) bool {
-	contentStr := string(content)
+// isJSONFile checks if a file is a JSON file
+func (s *Scanner) isJSONFile(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".json"
+}
 
-	// Check for Nobl9-specific indicators based on the official YAML guide
-	nobl9Indicators := []string{
-		"apiVersion: n9/v1alpha",
-		"kind: Agent",
-		"kind: Alert",
-		"kind: AlertMethod",
-		"kind: AlertPolicy",
-		"kind: AlertSilence",
-		"kind: Annotation",
-		"kind: BudgetAdjustment",
-		"kind: DataExport",
-		"kind: Direct",
-		"kind: Objective",
-		"kind: Project",
-		"kind: Report",
-		"kind: RoleBinding",
-		"kind: Service",
-		"kind: SLO",
-		"kind: UserGroup",
-		// Composite SLO indicators
-		"composite:",
-		"maxDelay:",
-		"components:",
-		"whenDelayed:",
+// nobl9Indicators are Nobl9-specific indicators based on the official YAML
+// guide
+var nobl9Indicators = []string{
+	"apiVersion: n9/v1alpha",
+	"kind: Agent",
+	"kind: Alert",
+	"kind: AlertMethod",
+	"kind: AlertPolicy",
+	"kind: AlertSilence",
+	"kind: Annotation",
+	"kind: BudgetAdjustment",
+	"kind: DataExport",
+	"kind: Direct",
+	"kind: Objective",
+	"kind: Project",
+	"kind: Report",
+	"kind: RoleBinding",
+	"kind: Service",
+	"kind: SLO",
+	"kind: UserGroup",
+	// Composite SLO indicators
+	"composite:",
+	"maxDelay:",
+	"components:",
+	"whenDelayed:",
+}
+
+// nobl9JSONIndicators are the indicators as written in JSON manifests, e.g.
+// "kind": "Project"
+var nobl9JSONIndicators = jsonIndicators(nobl9Indicators)
+
+// jsonIndicators returns the patterns matching "key: value" and "key:"
+// indicators written as JSON
+func jsonIndicators(indicators []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(indicators))
+	for _, indicator := range indicators {
+		key, value, _ := strings.Cut(indicator, ":")
+		pattern := `"` + regexp.QuoteMeta(key) + `"\s*:`
+		if value = strings.TrimSpace(value); value != "" {
+			pattern += `\s*"` + regexp.QuoteMeta(value) + `"`
+		}
+		patterns = append(patterns, regexp.MustCompile(pattern))
 	}
+	return patterns
+}
+
+// isNobl9File checks if file content contains Nobl9 configuration, written
+// as YAML or JSON
+func (s *Scanner) isNobl9File(content []byte) bool {
+	contentStr := string(content)
 
 	for _, indicator := range nobl9Indicators {
 		if strings.Contains(contentStr, indicator) {
@@ -253,6 +286,12 @@ func (s *Scanner) isNobl9File(content []byte) bool {
 		}
 	}
 
+	for _, indicator := range nobl9JSONIndicators {
+		if indicator.Match(content) {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -267,6 +306,17 @@ func (s *Scanner) countYAMLFiles(files []*FileInfo) int {
 	return count
 }
 
+// countJSONFiles counts the number of JSON files
+func (s *Scanner) countJSONFiles(files []*FileInfo) int {
+	count := 0
+	for _, file := range files {
+		if file.IsJSON {
+			count++
+		}
+	}
+	return count
+}
+
 // countNobl9Files counts the number of Nobl9 files
 func (s *Scanner) countNobl9Files(files []*FileInfo) int {
 	count := 0
@@ -318,10 +368,11 @@ func (s *Scanner) ValidateFile(filePath string) (*FileInfo, error) {
 	fileInfo := &FileInfo{
 		Path:   filePath,
 		IsYAML: s.isYAMLFile(filePath),
+		IsJSON: s.isJSONFile(filePath),
 	}
 
-	if !fileInfo.IsYAML {
-		return fileInfo, fmt.Errorf("file is not a YAML file")
+	if !fileInfo.IsYAML && !fileInfo.IsJSON {
+		return fileInfo, fmt.Errorf("file is not a YAML or JSON file")
 	}
 
 	// Read file content
@@ -110,6 +110,21 @@ func TestIsYAMLFile(t *testing.T) {
 	}
 }
 
+func TestIsJSONFile(t *testing.T) {
+	scanner := New()
+
+	for filePath, expected := range map[string]bool{
+		"slos.json": true,
+		"slos.JSON": true,
+		"slos.yaml": false,
+		"json":      false,
+	} {
+		if result := scanner.isJSONFile(filePath); result != expected {
+			t.Errorf("expected %v for %s, got %v", expected, filePath, result)
+		}
+	}
+}
+
 func TestIsNobl9File(t *testing.T) {
 	scanner := New()
 
@@ -166,6 +181,26 @@ metadata:
   name: my-agent`,
 			expected: true,
 		},
+		{
+			name:     "JSON Project",
+			content:  `{"apiVersion": "n9/v1alpha", "kind": "Project", "metadata": {"name": "my-project"}}`,
+			expected: true,
+		},
+		{
+			name: "JSON list of SLOs",
+			content: `[
+  {
+    "apiVersion":"n9/v1alpha",
+    "kind":"SLO"
+  }
+]`,
+			expected: true,
+		},
+		{
+			name:     "Regular JSON",
+			content:  `{"kind": "ConfigMap", "data": {"project": "Project"}}`,
+			expected: false,
+		},
 		{
 			name: "Regular YAML",
 			content: `apiVersion: v1
@@ -0,0 +1,123 @@
+package skiplist
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/commalist"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// List holds the object kinds and project/name patterns an apply run leaves
+// out. A nil list skips nothing.
+type List struct {
+	kinds   map[manifest.Kind]bool
+	objects []Pattern
+}
+
+// Pattern matches objects by project and name; both parts may be glob
+// patterns (e.g. payments/*). A project is matched under its own name, and
+// objects of no project, such as organization role bindings, by an empty
+// project part (e.g. /admin-alice).
+type Pattern struct {
+	Project string
+	Name    string
+}
+
+// Parse parses comma separated kinds and project/name patterns. It returns
+// nil when both are empty.
+func Parse(kinds, objects string) (*List, error) {
+	list := &List{kinds: make(map[manifest.Kind]bool)}
+	for _, name := range commalist.Split(kinds) {
+		kind, err := manifest.ParseKind(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown kind '%s'", name)
+		}
+		list.kinds[kind] = true
+	}
+
+	for _, value := range commalist.Split(objects) {
+		project, name, found := strings.Cut(value, "/")
+		if !found || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("object '%s' must be project/name", value)
+		}
+		pattern := Pattern{Project: project, Name: name}
+		if _, err := path.Match(pattern.Project, ""); err != nil {
+			return nil, fmt.Errorf("invalid object pattern '%s': %w", value, err)
+		}
+		if _, err := path.Match(pattern.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid object pattern '%s': %w", value, err)
+		}
+		list.objects = append(list.objects, pattern)
+	}
+
+	if len(list.kinds) == 0 && len(list.objects) == 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// Skips reports whether an object of the kind, project and name is left
+// out. The project of a project is its name, whether given or empty.
+func (l *List) Skips(kind manifest.Kind, project, name string) bool {
+	if l == nil {
+		return false
+	}
+	if l.kinds[kind] {
+		return true
+	}
+	if kind == manifest.KindProject {
+		project = name
+	}
+	for _, pattern := range l.objects {
+		if pattern.Matches(project, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipsObject reports whether the object is left out
+func (l *List) SkipsObject(obj manifest.Object) bool {
+	return l.Skips(obj.GetKind(), planner.ProjectOf(obj), obj.GetName())
+}
+
+// Matches reports whether the pattern matches the project and name
+func (p Pattern) Matches(project, name string) bool {
+	projectMatched, _ := path.Match(p.Project, project)
+	nameMatched, _ := path.Match(p.Name, name)
+	return projectMatched && nameMatched
+}
+
+// String returns the pattern as project/name
+func (p Pattern) String() string {
+	return p.Project + "/" + p.Name
+}
+
+// Kinds returns the skipped kinds in alphabetical order
+func (l *List) Kinds() []string {
+	if l == nil {
+		return nil
+	}
+	names := make([]string, 0, len(l.kinds))
+	for kind := range l.kinds {
+		names = append(names, kind.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Objects returns the skipped project/name patterns as given
+func (l *List) Objects() []string {
+	if l == nil {
+		return nil
+	}
+	patterns := make([]string, 0, len(l.objects))
+	for _, pattern := range l.objects {
+		patterns = append(patterns, pattern.String())
+	}
+	return patterns
+}
@@ -0,0 +1,78 @@
+package skiplist
+
+import (
+	"testing"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func TestParse(t *testing.T) {
+	list, err := Parse("", " ")
+	if err != nil || list != nil {
+		t.Fatalf("expected no list for empty filters, got %v, %v", list, err)
+	}
+
+	for _, tc := range []struct{ kinds, objects string }{
+		{kinds: "slo,widget"},
+		{objects: "latency"},
+		{objects: "payments/"},
+		{objects: "payments/slo/latency"},
+		{objects: "payments/[a"},
+	} {
+		if _, err := Parse(tc.kinds, tc.objects); err == nil {
+			t.Errorf("expected an error for %+v", tc)
+		}
+	}
+}
+
+func TestSkips(t *testing.T) {
+	list, err := Parse("alertmethod", "payments/latency, billing/*, /admin-*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		kind          manifest.Kind
+		project, name string
+		want          bool
+	}{
+		{manifest.KindAlertMethod, "payments", "pagerduty", true},
+		{manifest.KindSLO, "payments", "latency", true},
+		{manifest.KindSLO, "payments", "availability", false},
+		{manifest.KindService, "billing", "invoices", true},
+		{manifest.KindProject, "", "billing", true},
+		{manifest.KindProject, "", "payments", false},
+		{manifest.KindRoleBinding, "", "admin-alice", true},
+		{manifest.KindRoleBinding, "payments", "admin-alice", false},
+	}
+	for _, tc := range tests {
+		if got := list.Skips(tc.kind, tc.project, tc.name); got != tc.want {
+			t.Errorf("Skips(%s, %q, %q) = %v, want %v", tc.kind, tc.project, tc.name, got, tc.want)
+		}
+	}
+
+	var empty *List
+	if empty.Skips(manifest.KindSLO, "payments", "latency") {
+		t.Error("expected a nil list to skip nothing")
+	}
+}
+
+func TestSkipsObject(t *testing.T) {
+	objects, err := sdk.DecodeObjects([]byte(`apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: billing-alice
+spec:
+  user: alice@example.com
+  roleRef: project-owner
+  projectRef: billing
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, _ := Parse("", "billing/*")
+	if !list.SkipsObject(objects[0]) {
+		t.Error("expected a role binding to be matched by its projectRef")
+	}
+}
@@ -0,0 +1,44 @@
+package state
+
+import "time"
+
+// DefaultHistorySize is the number of runs the history keeps by default
+const DefaultHistorySize = 100
+
+// Run summarizes a run recorded in the history
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// Commit and RunURL identify the commit applied and the GitHub Actions
+	// run that applied it, when known
+	Commit string `json:"commit,omitempty"`
+	RunURL string `json:"run_url,omitempty"`
+
+	FilesProcessed  int `json:"files_processed"`
+	FilesWithErrors int `json:"files_with_errors"`
+	// ObjectsChanged counts the objects applied and the projects deleted
+	ObjectsChanged int `json:"objects_changed"`
+	// Errors counts failed files, state errors and files left unprocessed
+	// after an abort
+	Errors  int  `json:"errors"`
+	Aborted bool `json:"aborted,omitempty"`
+}
+
+// Failed reports whether the run ended with errors
+func (r Run) Failed() bool {
+	return r.Errors > 0 || r.Aborted
+}
+
+// Duration returns how long the run took
+func (r Run) Duration() time.Duration {
+	return time.Duration(r.DurationMs) * time.Millisecond
+}
+
+// RecordRun appends a run to the history, keeping the last limit runs. A
+// limit of zero or less keeps every run.
+func (s *State) RecordRun(run Run, limit int) {
+	s.History = append(s.History, run)
+	if limit > 0 && len(s.History) > limit {
+		s.History = append([]Run(nil), s.History[len(s.History)-limit:]...)
+	}
+}
@@ -0,0 +1,196 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nobl9/nobl9-go/manifest"
+	"github.com/your-org/nobl9-action/pkg/planner"
+)
+
+// ObjectRecord is an object the action applied, with the hash of its content
+type ObjectRecord struct {
+	Kind    string `json:"kind"`
+	Project string `json:"project,omitempty"`
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"`
+	// Hash is the ContentHash of the object as last applied
+	Hash      string    `json:"hash"`
+	AppliedAt time.Time `json:"applied_at"`
+	// RemovedAt is when a run first found the object no longer declared
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+}
+
+// Rename is a recorded object no longer declared whose content is declared
+// under another name
+type Rename struct {
+	From ObjectRecord
+	To   ObjectRecord
+}
+
+// ObjectPrunePlan lists what pruning does with each recorded object that is
+// no longer declared
+type ObjectPrunePlan struct {
+	Mark    []ObjectRecord // first found removed; deleted after the grace period
+	Pending []ObjectRecord // removed earlier and still within the grace period
+	Delete  []ObjectRecord // removed earlier and past the grace period
+}
+
+// NewObjectRecord records an object applied from source. Role bindings are
+// recorded in their projectRef; projects in no project.
+func NewObjectRecord(obj manifest.Object, source string, now time.Time) (ObjectRecord, error) {
+	hash, err := ContentHash(obj)
+	if err != nil {
+		return ObjectRecord{}, err
+	}
+	record := ObjectRecord{
+		Kind:      obj.GetKind().String(),
+		Name:      obj.GetName(),
+		Source:    source,
+		Hash:      hash,
+		AppliedAt: now.UTC(),
+	}
+	if obj.GetKind() != manifest.KindProject {
+		record.Project = planner.ProjectOf(obj)
+	}
+	return record, nil
+}
+
+// Key identifies the object, e.g. SLO payments/latency
+func (r ObjectRecord) Key() string {
+	if r.Project == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Project, r.Name)
+}
+
+// ContentHash digests an object's JSON encoding without its name, so an
+// object renamed without other changes keeps its hash
+func ContentHash(obj manifest.Object) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "name")
+	}
+	// encoding/json sorts map keys, so the encoding is stable
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Unchanged reports whether the object is recorded with the same content,
+// so applying it again changes nothing the action knows of
+func (s *State) Unchanged(record ObjectRecord) bool {
+	recorded, found := s.Objects[record.Key()]
+	return found && recorded.Hash == record.Hash && recorded.RemovedAt == nil
+}
+
+// RecordObjects records applied objects, replacing earlier records of the
+// same objects
+func (s *State) RecordObjects(records ...ObjectRecord) {
+	if s.Objects == nil {
+		s.Objects = make(map[string]ObjectRecord, len(records))
+	}
+	for _, record := range records {
+		s.Objects[record.Key()] = record
+	}
+}
+
+// MarkRemoved records when an object was first found no longer declared
+func (s *State) MarkRemoved(key string, now time.Time) {
+	if record, found := s.Objects[key]; found && record.RemovedAt == nil {
+		removedAt := now.UTC()
+		record.RemovedAt = &removedAt
+		s.Objects[key] = record
+	}
+}
+
+// ForgetObject removes a deleted object from the state
+func (s *State) ForgetObject(key string) {
+	delete(s.Objects, key)
+}
+
+// Renames matches recorded objects that are no longer declared with
+// declared objects that are not recorded yet, of the same kind and project
+// and with the same content. Each object is matched at most once.
+func (s *State) Renames(declared []ObjectRecord) []Rename {
+	isDeclared := make(map[string]bool, len(declared))
+	for _, record := range declared {
+		isDeclared[record.Key()] = true
+	}
+
+	removed := make(map[string][]ObjectRecord)
+	for _, record := range s.sortedObjects() {
+		if !isDeclared[record.Key()] {
+			match := record.Kind + "\x00" + record.Project + "\x00" + record.Hash
+			removed[match] = append(removed[match], record)
+		}
+	}
+
+	var renames []Rename
+	for _, record := range declared {
+		if _, recorded := s.Objects[record.Key()]; recorded {
+			continue
+		}
+		match := record.Kind + "\x00" + record.Project + "\x00" + record.Hash
+		if candidates := removed[match]; len(candidates) > 0 {
+			renames = append(renames, Rename{From: candidates[0], To: record})
+			removed[match] = candidates[1:]
+		}
+	}
+	return renames
+}
+
+// PlanObjectPrune compares the declared objects with the recorded ones.
+// Recorded objects that are no longer declared are first marked; they are
+// deleted only once a later run finds them past the grace period. A grace
+// period of zero deletes them right away. Projects are left to PlanPrune.
+func (s *State) PlanObjectPrune(declared []ObjectRecord, grace time.Duration, now time.Time) ObjectPrunePlan {
+	isDeclared := make(map[string]bool, len(declared))
+	for _, record := range declared {
+		isDeclared[record.Key()] = true
+	}
+
+	var plan ObjectPrunePlan
+	for _, record := range s.sortedObjects() {
+		if record.Kind == manifest.KindProject.String() || isDeclared[record.Key()] {
+			continue
+		}
+		switch {
+		case grace <= 0:
+			plan.Delete = append(plan.Delete, record)
+		case record.RemovedAt == nil:
+			plan.Mark = append(plan.Mark, record)
+		case now.Before(record.RemovedAt.Add(grace)):
+			plan.Pending = append(plan.Pending, record)
+		default:
+			plan.Delete = append(plan.Delete, record)
+		}
+	}
+	return plan
+}
+
+// sortedObjects returns the recorded objects sorted by key
+func (s *State) sortedObjects() []ObjectRecord {
+	records := make([]ObjectRecord, 0, len(s.Objects))
+	for _, record := range s.Objects {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key() < records[j].Key()
+	})
+	return records
+}
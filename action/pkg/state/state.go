@@ -0,0 +1,334 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileVersion is the version of the state file format
+const fileVersion = 1
+
+// PendingDeleteLabel marks a project that is scheduled for deletion
+const PendingDeleteLabel = "pending-delete"
+
+// DeleteAfterAnnotation records when a project marked for deletion may be deleted
+const DeleteAfterAnnotation = "nobl9-action/delete-after"
+
+// State records what the action manages between runs
+type State struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Projects are the names of the projects declared by the manifests
+	Projects []string `json:"projects"`
+	// Tombstones are projects no longer declared that are waiting to be deleted
+	Tombstones map[string]Tombstone `json:"tombstones,omitempty"`
+	// Objects are the objects the action applied, by ObjectRecord.Key
+	Objects map[string]ObjectRecord `json:"objects,omitempty"`
+
+	// Settings are the key action inputs of the run that saved the state,
+	// and Fingerprint is their hash, used to detect configuration drift
+	Settings    map[string]string `json:"settings,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+
+	// History summarizes the last runs, oldest first
+	History []Run `json:"history,omitempty"`
+}
+
+// Tombstone records a project marked for deletion
+type Tombstone struct {
+	MarkedAt    time.Time `json:"marked_at"`
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// SettingChange is a setting whose value differs from the previous run
+type SettingChange struct {
+	Name     string
+	Previous string
+	Current  string
+}
+
+// PrunePlan lists what pruning does with each managed project
+type PrunePlan struct {
+	Mark    []string // no longer declared; mark as pending delete
+	Pending []string // marked earlier and still within the grace period
+	Delete  []string // marked earlier and past the grace period
+	Restore []string // marked earlier but declared again
+}
+
+// New creates an empty state
+func New() *State {
+	return &State{
+		Version:    fileVersion,
+		Tombstones: make(map[string]Tombstone),
+	}
+}
+
+// Load reads the state from a JSON file. A missing file is not an error and
+// yields an empty state, since the first run has nothing recorded yet.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(), nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	if s.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported state file version %d", s.Version)
+	}
+
+	if s.Tombstones == nil {
+		s.Tombstones = make(map[string]Tombstone)
+	}
+
+	return &s, nil
+}
+
+// Save writes the state to a JSON file
+func (s *State) Save(path string) error {
+	s.Version = fileVersion
+	s.UpdatedAt = time.Now().UTC()
+	sort.Strings(s.Projects)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state file: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a
+	// truncated state behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// Merge adds what another state records, such as the state of a run on
+// another branch kept as an artifact. Projects and tombstones are combined,
+// keeping the earlier tombstone of a project; of two records of an object
+// the later applied one is kept; the history is combined by start time; and
+// the settings of the later saved state are kept.
+func (s *State) Merge(other *State) {
+	s.Projects = uniqueSorted(append(s.Projects, other.Projects...))
+
+	if s.Tombstones == nil {
+		s.Tombstones = make(map[string]Tombstone, len(other.Tombstones))
+	}
+	for name, tombstone := range other.Tombstones {
+		if current, found := s.Tombstones[name]; !found || tombstone.MarkedAt.Before(current.MarkedAt) {
+			s.Tombstones[name] = tombstone
+		}
+	}
+
+	for key, record := range other.Objects {
+		if current, found := s.Objects[key]; !found || record.AppliedAt.After(current.AppliedAt) {
+			s.RecordObjects(record)
+		}
+	}
+
+	seen := make(map[time.Time]bool, len(s.History))
+	for _, run := range s.History {
+		seen[run.StartedAt] = true
+	}
+	for _, run := range other.History {
+		if !seen[run.StartedAt] {
+			s.History = append(s.History, run)
+		}
+	}
+	sort.SliceStable(s.History, func(i, j int) bool {
+		return s.History[i].StartedAt.Before(s.History[j].StartedAt)
+	})
+
+	if other.UpdatedAt.After(s.UpdatedAt) && other.Settings != nil {
+		s.SetSettings(other.Settings)
+	}
+	if other.UpdatedAt.After(s.UpdatedAt) {
+		s.UpdatedAt = other.UpdatedAt
+	}
+}
+
+// PlanPrune compares the declared projects with the managed ones. Projects
+// that are no longer declared are first marked; they are deleted only once a
+// later run finds them past the grace period. A grace period of zero deletes
+// them right away.
+func (s *State) PlanPrune(declared []string, grace time.Duration, now time.Time) PrunePlan {
+	var plan PrunePlan
+
+	isDeclared := make(map[string]bool, len(declared))
+	for _, name := range declared {
+		isDeclared[name] = true
+	}
+
+	for _, name := range s.managed() {
+		tombstone, marked := s.Tombstones[name]
+		switch {
+		case isDeclared[name]:
+			if marked {
+				plan.Restore = append(plan.Restore, name)
+			}
+		case grace <= 0:
+			plan.Delete = append(plan.Delete, name)
+		case !marked:
+			plan.Mark = append(plan.Mark, name)
+		case now.Before(tombstone.DeleteAfter):
+			plan.Pending = append(plan.Pending, name)
+		default:
+			plan.Delete = append(plan.Delete, name)
+		}
+	}
+
+	return plan
+}
+
+// MarkForDeletion records a tombstone for a project
+func (s *State) MarkForDeletion(name string, grace time.Duration, now time.Time) Tombstone {
+	tombstone := Tombstone{MarkedAt: now.UTC(), DeleteAfter: now.Add(grace).UTC()}
+	s.Tombstones[name] = tombstone
+	return tombstone
+}
+
+// Restore drops the tombstone of a project that is declared again
+func (s *State) Restore(name string) {
+	delete(s.Tombstones, name)
+}
+
+// Forget removes a deleted project from the state
+func (s *State) Forget(name string) {
+	delete(s.Tombstones, name)
+	s.Projects = removeString(s.Projects, name)
+}
+
+// SetDeclared records the projects declared by this run. Projects waiting
+// to be deleted stay tracked through their tombstones.
+func (s *State) SetDeclared(declared []string) {
+	s.Projects = uniqueSorted(declared)
+}
+
+// SetSettings records the settings of this run and their fingerprint
+func (s *State) SetSettings(settings map[string]string) {
+	s.Settings = make(map[string]string, len(settings))
+	for name, value := range settings {
+		s.Settings[name] = value
+	}
+	s.Fingerprint = Fingerprint(settings)
+}
+
+// CompareSettings returns the settings that changed since the run that saved
+// the state, sorted by name. A state without recorded settings has nothing
+// to compare with.
+func (s *State) CompareSettings(settings map[string]string) []SettingChange {
+	if s.Settings == nil || s.Fingerprint == Fingerprint(settings) {
+		return nil
+	}
+
+	names := make([]string, 0, len(settings)+len(s.Settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	for name := range s.Settings {
+		names = append(names, name)
+	}
+
+	var changes []SettingChange
+	for _, name := range uniqueSorted(names) {
+		previous, current := s.Settings[name], settings[name]
+		if previous != current {
+			changes = append(changes, SettingChange{Name: name, Previous: previous, Current: current})
+		}
+	}
+	return changes
+}
+
+// Fingerprint returns a stable hash of the settings
+func Fingerprint(settings map[string]string) string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%s\n", name, settings[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// managed returns every project the state tracks, sorted
+func (s *State) managed() []string {
+	names := append([]string{}, s.Projects...)
+	for name := range s.Tombstones {
+		names = append(names, name)
+	}
+	return uniqueSorted(names)
+}
+
+// ParseDuration parses a Go duration that may also use a day suffix, such as
+// "7d" or "36h"
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// uniqueSorted returns the sorted distinct values
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// removeString returns values without the given value
+func removeString(values []string, value string) []string {
+	kept := values[:0]
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
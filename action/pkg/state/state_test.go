@@ -0,0 +1,342 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nobl9/nobl9-go/sdk"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.Projects) != 0 || len(s.Tombstones) != 0 {
+		t.Errorf("expected empty state, got %+v", s)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "nobl9.json")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s := New()
+	s.SetDeclared([]string{"payments", "billing", "payments"})
+	s.MarkForDeletion("legacy", 7*24*time.Hour, now)
+
+	if err := s.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded.Projects, []string{"billing", "payments"}) {
+		t.Errorf("expected projects [billing payments], got %v", loaded.Projects)
+	}
+	tombstone, ok := loaded.Tombstones["legacy"]
+	if !ok {
+		t.Fatal("expected tombstone for legacy")
+	}
+	if !tombstone.DeleteAfter.Equal(now.Add(7 * 24 * time.Hour)) {
+		t.Errorf("unexpected delete after %v", tombstone.DeleteAfter)
+	}
+}
+
+func TestLoadUnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	grace := 7 * 24 * time.Hour
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	s := New()
+	s.SetDeclared([]string{"payments", "removed"})
+	s.MarkForDeletion("waiting", grace, now.Add(-24*time.Hour))
+	s.MarkForDeletion("expired", grace, now.Add(-8*24*time.Hour))
+	s.MarkForDeletion("returned", grace, now.Add(-24*time.Hour))
+
+	plan := s.PlanPrune([]string{"payments", "returned"}, grace, now)
+
+	expected := PrunePlan{
+		Mark:    []string{"removed"},
+		Pending: []string{"waiting"},
+		Delete:  []string{"expired"},
+		Restore: []string{"returned"},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected %+v, got %+v", expected, plan)
+	}
+
+	// Without a grace period removed projects are deleted right away
+	plan = s.PlanPrune([]string{"payments", "returned"}, 0, now)
+	if !reflect.DeepEqual(plan.Delete, []string{"expired", "removed", "waiting"}) {
+		t.Errorf("expected every removed project to be deleted, got %v", plan.Delete)
+	}
+	if len(plan.Mark) != 0 {
+		t.Errorf("expected nothing to be marked, got %v", plan.Mark)
+	}
+}
+
+func TestTombstoneLifecycle(t *testing.T) {
+	now := time.Now()
+
+	s := New()
+	s.SetDeclared([]string{"legacy"})
+
+	// Removed projects stay tracked through their tombstone
+	s.MarkForDeletion("legacy", time.Hour, now)
+	s.SetDeclared(nil)
+	if plan := s.PlanPrune(nil, time.Hour, now); !reflect.DeepEqual(plan.Pending, []string{"legacy"}) {
+		t.Errorf("expected legacy to be pending, got %+v", plan)
+	}
+
+	s.Forget("legacy")
+	if plan := s.PlanPrune(nil, time.Hour, now.Add(2*time.Hour)); !reflect.DeepEqual(plan, PrunePlan{}) {
+		t.Errorf("expected nothing to prune after forgetting, got %+v", plan)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: "1.5d", expected: 36 * time.Hour},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "0", expected: 0},
+		{input: "soon", expectError: true},
+		{input: "-1d", expectError: true},
+		{input: "-1h", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDuration(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestCompareSettings(t *testing.T) {
+	s := New()
+	settings := map[string]string{"file_pattern": "**/*.yaml", "prune": "false", "organization": "acme"}
+
+	// The first run has nothing to compare with
+	if changes := s.CompareSettings(settings); changes != nil {
+		t.Errorf("expected no changes without recorded settings, got %v", changes)
+	}
+
+	s.SetSettings(settings)
+	if changes := s.CompareSettings(settings); changes != nil {
+		t.Errorf("expected no changes for identical settings, got %v", changes)
+	}
+
+	changed := map[string]string{"file_pattern": "teams/**/*.yaml", "prune": "false", "organization": "acme", "kinds": "all"}
+	expected := []SettingChange{
+		{Name: "file_pattern", Previous: "**/*.yaml", Current: "teams/**/*.yaml"},
+		{Name: "kinds", Previous: "", Current: "all"},
+	}
+	if changes := s.CompareSettings(changed); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	// Recording copies the settings
+	settings["prune"] = "true"
+	if s.Settings["prune"] != "false" {
+		t.Error("expected recorded settings not to change with the caller's map")
+	}
+	if Fingerprint(changed) == s.Fingerprint {
+		t.Error("expected different settings to have different fingerprints")
+	}
+}
+
+func TestRecordRun(t *testing.T) {
+	s := New()
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		s.RecordRun(Run{StartedAt: start.Add(time.Duration(i) * time.Hour), Errors: i % 2}, 3)
+	}
+	if len(s.History) != 3 || !s.History[0].StartedAt.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("expected the last 3 runs, got %+v", s.History)
+	}
+	if !s.History[1].Failed() || s.History[2].Failed() {
+		t.Errorf("unexpected failures: %+v", s.History)
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.History, s.History) {
+		t.Errorf("expected the history to round trip, got %+v", loaded.History)
+	}
+}
+
+const objects = `- apiVersion: n9/v1alpha
+  kind: Project
+  metadata:
+    name: payments
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout
+    project: payments
+  spec:
+    description: Checkout
+- apiVersion: n9/v1alpha
+  kind: Service
+  metadata:
+    name: checkout-api
+    project: payments
+  spec:
+    description: Checkout
+- apiVersion: n9/v1alpha
+  kind: RoleBinding
+  metadata:
+    name: payments-owner
+  spec:
+    user: 00u1alice
+    roleRef: project-owner
+    projectRef: payments
+`
+
+func objectRecords(t *testing.T, now time.Time) []ObjectRecord {
+	t.Helper()
+	decoded, err := sdk.DecodeObjects([]byte(objects))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := make([]ObjectRecord, 0, len(decoded))
+	for _, obj := range decoded {
+		record, err := NewObjectRecord(obj, "payments.yaml", now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestObjectRecords(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	records := objectRecords(t, now)
+	project, checkout, checkoutAPI, binding := records[0], records[1], records[2], records[3]
+
+	if project.Key() != "Project payments" || binding.Key() != "RoleBinding payments/payments-owner" {
+		t.Errorf("unexpected keys %q and %q", project.Key(), binding.Key())
+	}
+	if checkout.Hash != checkoutAPI.Hash {
+		t.Error("expected objects differing only in name to share their content hash")
+	}
+
+	s := New()
+	s.RecordObjects(project, checkout, binding)
+	if !s.Unchanged(checkout) || s.Unchanged(checkoutAPI) {
+		t.Error("expected only the recorded service to be unchanged")
+	}
+
+	// checkout was renamed to checkout-api
+	declared := []ObjectRecord{project, checkoutAPI, binding}
+	renames := s.Renames(declared)
+	if len(renames) != 1 || renames[0].From.Name != "checkout" || renames[0].To.Name != "checkout-api" {
+		t.Errorf("expected checkout to be renamed to checkout-api, got %+v", renames)
+	}
+
+	// Removed objects are marked first and deleted after the grace period;
+	// projects are left to PlanPrune
+	grace := 7 * 24 * time.Hour
+	plan := s.PlanObjectPrune([]ObjectRecord{checkoutAPI}, grace, now)
+	if len(plan.Mark) != 2 || plan.Mark[0].Key() != "RoleBinding payments/payments-owner" || plan.Mark[1].Key() != "Service payments/checkout" {
+		t.Fatalf("expected the role binding and the service to be marked, got %+v", plan)
+	}
+	for _, record := range plan.Mark {
+		s.MarkRemoved(record.Key(), now)
+	}
+	if plan := s.PlanObjectPrune(declared, grace, now.Add(24*time.Hour)); len(plan.Pending) != 1 || plan.Pending[0].Name != "checkout" {
+		t.Errorf("expected checkout to be pending, got %+v", plan)
+	}
+	if plan := s.PlanObjectPrune(declared, grace, now.Add(8*24*time.Hour)); len(plan.Delete) != 1 || plan.Delete[0].Name != "checkout" {
+		t.Errorf("expected checkout to be deleted, got %+v", plan)
+	}
+	if s.Unchanged(checkout) {
+		t.Error("expected a removed object not to be unchanged")
+	}
+
+	s.ForgetObject(checkout.Key())
+	if plan := s.PlanObjectPrune(declared, 0, now); len(plan.Delete) != 0 {
+		t.Errorf("expected nothing to delete after forgetting, got %+v", plan)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	records := objectRecords(t, now)
+
+	s := New()
+	s.SetDeclared([]string{"payments"})
+	s.MarkForDeletion("legacy", time.Hour, now)
+	s.RecordObjects(records[1])
+	s.RecordRun(Run{StartedAt: now}, 0)
+	s.UpdatedAt = now
+
+	other := New()
+	other.SetDeclared([]string{"search"})
+	other.MarkForDeletion("legacy", time.Hour, now.Add(-time.Hour))
+	updated := records[1]
+	updated.Hash, updated.AppliedAt = "newer", now.Add(time.Hour)
+	other.RecordObjects(updated, records[3])
+	other.RecordRun(Run{StartedAt: now}, 0)
+	other.RecordRun(Run{StartedAt: now.Add(-time.Hour)}, 0)
+	other.SetSettings(map[string]string{"prune": "true"})
+	other.UpdatedAt = now.Add(time.Hour)
+
+	s.Merge(other)
+
+	if !reflect.DeepEqual(s.Projects, []string{"payments", "search"}) {
+		t.Errorf("expected combined projects, got %v", s.Projects)
+	}
+	if !s.Tombstones["legacy"].MarkedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected the earlier tombstone, got %+v", s.Tombstones["legacy"])
+	}
+	if len(s.Objects) != 2 || s.Objects[records[1].Key()].Hash != "newer" {
+		t.Errorf("expected the later applied records, got %+v", s.Objects)
+	}
+	if len(s.History) != 2 || !s.History[0].StartedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected the combined history by start time, got %+v", s.History)
+	}
+	if s.Settings["prune"] != "true" {
+		t.Errorf("expected the settings of the later state, got %v", s.Settings)
+	}
+}
@@ -0,0 +1,140 @@
+package useraudit
+
+import (
+	"context"
+	"fmt"
+	"github.com/your-org/nobl9-action/pkg/reportfmt"
+	"sort"
+	"strings"
+)
+
+// Problems of a stale user
+const (
+	// ProblemNotFound is a user Nobl9 does not know
+	ProblemNotFound = "not found in Nobl9"
+	// ProblemNotInOkta is an email Okta has no user for
+	ProblemNotInOkta = "not found in Okta"
+)
+
+// Reference is a role binding of the repository that grants a role to a
+// user, given as an email or a Nobl9 user ID
+type Reference struct {
+	User        string `json:"user"`
+	RoleBinding string `json:"role_binding"`
+	File        string `json:"file"`
+}
+
+// UserLookup reports whether Nobl9 has a user with the email or user ID
+type UserLookup func(ctx context.Context, user string) (bool, error)
+
+// StatusLookup returns the Okta status of the user with the email, e.g.
+// ACTIVE or DEPROVISIONED, or an empty status when Okta has no such user
+type StatusLookup func(ctx context.Context, email string) (string, error)
+
+// StaleUser is a user that no longer exists or is deactivated, with the
+// role bindings that still grant them a role
+type StaleUser struct {
+	User       string      `json:"user"`
+	Problem    string      `json:"problem"`
+	References []Reference `json:"references"`
+}
+
+// Report lists the stale users among the users the role bindings refer to
+type Report struct {
+	// Checked is the number of distinct users looked up
+	Checked int `json:"checked"`
+	// OktaChecked is set when the users were also looked up in Okta
+	OktaChecked bool        `json:"okta_checked"`
+	Stale       []StaleUser `json:"stale"`
+}
+
+// Audit looks up every user of the references in Nobl9 and, when status is
+// set, the Okta status of those given as emails. A user Nobl9 does not know
+// is reported as not found; a user Nobl9 knows but Okta has not, or not as
+// an active user, is reported with the Okta problem. Stale users are sorted
+// by user. A failed lookup stops the audit, so an outage does not report
+// every user as stale.
+func Audit(ctx context.Context, references []Reference, lookup UserLookup, status StatusLookup) (*Report, error) {
+	byUser := make(map[string][]Reference)
+	var users []string
+	for _, reference := range references {
+		if _, seen := byUser[reference.User]; !seen {
+			users = append(users, reference.User)
+		}
+		byUser[reference.User] = append(byUser[reference.User], reference)
+	}
+	sort.Strings(users)
+
+	report := &Report{Checked: len(users), OktaChecked: status != nil}
+	for _, user := range users {
+		problem, err := check(ctx, user, lookup, status)
+		if err != nil {
+			return nil, err
+		}
+		if problem != "" {
+			report.Stale = append(report.Stale, StaleUser{User: user, Problem: problem, References: byUser[user]})
+		}
+	}
+	return report, nil
+}
+
+// check returns the problem of a user, or an empty string for a user that
+// exists and is active
+func check(ctx context.Context, user string, lookup UserLookup, status StatusLookup) (string, error) {
+	found, err := lookup(ctx, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user %s in Nobl9: %w", user, err)
+	}
+	if !found {
+		return ProblemNotFound, nil
+	}
+	if status == nil || !strings.Contains(user, "@") {
+		return "", nil
+	}
+
+	oktaStatus, err := status(ctx, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user %s in Okta: %w", user, err)
+	}
+	switch oktaStatus {
+	case "":
+		return ProblemNotInOkta, nil
+	case "ACTIVE":
+		return "", nil
+	}
+	return fmt.Sprintf("%s in Okta", strings.ToLower(oktaStatus)), nil
+}
+
+// Users returns the stale users
+func (r *Report) Users() []string {
+	users := make([]string, 0, len(r.Stale))
+	for _, stale := range r.Stale {
+		users = append(users, stale.User)
+	}
+	return users
+}
+
+// Markdown renders the report for a cleanup issue
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Nobl9 User Audit\n\n")
+	checked := "Nobl9"
+	if r.OktaChecked {
+		checked = "Nobl9 and Okta"
+	}
+	if len(r.Stale) == 0 {
+		fmt.Fprintf(&b, "All %s of the role bindings exist in %s.\n", reportfmt.Plural(r.Checked, "user"), checked)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s of %s of the role bindings no longer exist in %s or are deactivated.\n\n", reportfmt.Plural(len(r.Stale), "user"), reportfmt.Plural(r.Checked, "user"), checked)
+	b.WriteString("| User | Problem | Role bindings |\n|------|---------|---------------|\n")
+	for _, stale := range r.Stale {
+		bindings := make([]string, 0, len(stale.References))
+		for _, reference := range stale.References {
+			bindings = append(bindings, fmt.Sprintf("`%s` (%s)", reference.RoleBinding, reference.File))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", stale.User, stale.Problem, strings.Join(bindings, "<br>"))
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package useraudit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	references := []Reference{
+		{User: "alice@example.com", RoleBinding: "payments-alice", File: "payments.yaml"},
+		{User: "bob@example.com", RoleBinding: "payments-bob", File: "payments.yaml"},
+		{User: "bob@example.com", RoleBinding: "checkout-bob", File: "checkout.yaml"},
+		{User: "carol@example.com", RoleBinding: "checkout-carol", File: "checkout.yaml"},
+		{User: "00u2y4e4atkzaYkXP4x8", RoleBinding: "checkout-dave", File: "checkout.yaml"},
+	}
+	lookups := 0
+	lookup := func(ctx context.Context, user string) (bool, error) {
+		lookups++
+		return user != "carol@example.com", nil
+	}
+	status := func(ctx context.Context, email string) (string, error) {
+		if email == "00u2y4e4atkzaYkXP4x8" {
+			t.Error("user IDs must not be looked up in Okta")
+		}
+		return map[string]string{"alice@example.com": "ACTIVE", "bob@example.com": "DEPROVISIONED"}[email], nil
+	}
+
+	report, err := Audit(context.Background(), references, lookup, status)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Checked != 4 || lookups != 4 {
+		t.Errorf("expected 4 users checked once, got %d checked and %d lookups", report.Checked, lookups)
+	}
+	if users := strings.Join(report.Users(), ","); users != "bob@example.com,carol@example.com" {
+		t.Fatalf("unexpected stale users: %s", users)
+	}
+	if report.Stale[0].Problem != "deprovisioned in Okta" || len(report.Stale[0].References) != 2 {
+		t.Errorf("unexpected stale user: %+v", report.Stale[0])
+	}
+	if report.Stale[1].Problem != ProblemNotFound {
+		t.Errorf("unexpected stale user: %+v", report.Stale[1])
+	}
+
+	markdown := report.Markdown()
+	for _, expected := range []string{"2 users of 4 users", "Nobl9 and Okta", "`checkout-bob` (checkout.yaml)"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in markdown:\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestAuditWithoutOkta(t *testing.T) {
+	references := []Reference{{User: "bob@example.com", RoleBinding: "payments-bob", File: "payments.yaml"}}
+	lookup := func(ctx context.Context, user string) (bool, error) { return true, nil }
+
+	report, err := Audit(context.Background(), references, lookup, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Stale) != 0 {
+		t.Errorf("expected no stale users, got %+v", report.Stale)
+	}
+	if markdown := report.Markdown(); !strings.Contains(markdown, "All 1 user of the role bindings exist in Nobl9.") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
+
+func TestAuditLookupError(t *testing.T) {
+	references := []Reference{{User: "bob@example.com", RoleBinding: "payments-bob", File: "payments.yaml"}}
+	lookup := func(ctx context.Context, user string) (bool, error) { return false, errors.New("unavailable") }
+
+	if _, err := Audit(context.Background(), references, lookup, nil); err == nil || !strings.Contains(err.Error(), "bob@example.com") {
+		t.Errorf("expected lookup error naming the user, got %v", err)
+	}
+}
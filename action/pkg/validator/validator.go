@@ -6,16 +6,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nobl9/nobl9-go/manifest"
 	"github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
 	"github.com/your-org/nobl9-action/pkg/errors"
 	"github.com/your-org/nobl9-action/pkg/logger"
 	"github.com/your-org/nobl9-action/pkg/nobl9"
 	"github.com/your-org/nobl9-action/pkg/resolver"
+	"github.com/your-org/nobl9-action/pkg/roles"
 )
 
 // Validator handles validation of users, permissions, and role bindings
 type Validator struct {
-	client   *nobl9.Client
+	client   nobl9.Nobl9API
 	resolver *resolver.Resolver
 	logger   *logger.Logger
 }
@@ -64,7 +66,7 @@ type RoleBindingRequirements struct {
 }
 
 // New creates a new validator instance
-func New(client *nobl9.Client, resolver *resolver.Resolver, log *logger.Logger) *Validator {
+func New(client nobl9.Nobl9API, resolver *resolver.Resolver, log *logger.Logger) *Validator {
 	return &Validator{
 		client:   client,
 		resolver: resolver,
@@ -98,13 +100,19 @@ func (v *Validator) ValidateRoleBinding(ctx context.Context, roleBindingObj *rol
 		validation.IsValid = false
 	}
 
-	// Step 2: Validate project exists
+	// Step 2: Validate the role is one of the organization's roles
+	if err := v.validateRole(ctx, roleBindingObj); err != nil {
+		validation.Errors = append(validation.Errors, err)
+		validation.IsValid = false
+	}
+
+	// Step 3: Validate project exists
 	if err := v.validateProjectExists(ctx, roleBindingObj.Spec.ProjectRef); err != nil {
 		validation.Errors = append(validation.Errors, err)
 		validation.IsValid = false
 	}
 
-	// Step 3: Extract and validate users
+	// Step 4: Extract and validate users
 	users, err := v.extractUsersFromRoleBinding(roleBindingObj)
 	if err != nil {
 		validation.Errors = append(validation.Errors, err)
@@ -113,7 +121,7 @@ func (v *Validator) ValidateRoleBinding(ctx context.Context, roleBindingObj *rol
 		validation.Users = users
 	}
 
-	// Step 4: Validate each user
+	// Step 5: Validate each user
 	for _, user := range validation.Users {
 		if err := v.validateUser(ctx, user, emailToUserID); err != nil {
 			user.ValidationError = err
@@ -122,13 +130,13 @@ func (v *Validator) ValidateRoleBinding(ctx context.Context, roleBindingObj *rol
 		}
 	}
 
-	// Step 5: Validate role binding requirements
+	// Step 6: Validate role binding requirements
 	if err := v.validateRoleBindingRequirements(validation); err != nil {
 		validation.Errors = append(validation.Errors, err)
 		validation.IsValid = false
 	}
 
-	// Step 6: Check for existing role binding conflicts
+	// Step 7: Check for existing role binding conflicts
 	if err := v.checkRoleBindingConflicts(ctx, validation); err != nil {
 		validation.Warnings = append(validation.Warnings, err.Error())
 	}
@@ -210,11 +218,38 @@ func (v *Validator) validateRoleBindingStructure(roleBindingObj *rolebinding.Rol
 	return nil
 }
 
-// validateProjectExists checks if the project exists
+// validateRole checks the role binding's role is one of the organization's
+// roles of its scope
+func (v *Validator) validateRole(ctx context.Context, roleBindingObj *rolebinding.RoleBinding) error {
+	if roleBindingObj.Spec.RoleRef == "" {
+		return nil
+	}
+
+	catalog, err := v.client.GetOrganizationRoles(ctx)
+	if err != nil {
+		return errors.NewValidationError("failed to get organization roles", err)
+	}
+	return checkRole(catalog, roleBindingObj)
+}
+
+// checkRole rejects a role binding whose role is not in the catalog
+func checkRole(catalog *roles.Catalog, roleBindingObj *rolebinding.RoleBinding) error {
+	unknown := catalog.Check([]manifest.Object{*roleBindingObj})
+	if len(unknown) == 0 {
+		return nil
+	}
+	return errors.NewValidationError(unknown[0].Error(), nil)
+}
+
+// validateProjectExists checks if the project exists; the projects are
+// listed once for all role bindings
 func (v *Validator) validateProjectExists(ctx context.Context, projectName string) error {
-	_, err := v.client.GetProject(ctx, projectName)
+	exists, err := v.client.ProjectExists(ctx, projectName)
 	if err != nil {
-		return errors.NewValidationError(fmt.Sprintf("project %s does not exist", projectName), err)
+		return errors.NewValidationError(fmt.Sprintf("failed to check project %s", projectName), err)
+	}
+	if !exists {
+		return errors.NewValidationError(fmt.Sprintf("project %s does not exist", projectName), nil)
 	}
 	return nil
 }
@@ -259,7 +294,7 @@ func (v *Validator) validateUser(ctx context.Context, user *UserValidation, emai
 		}
 
 		if !result.Resolved {
-			return errors.NewUserResolutionError("user not found", fmt.Errorf("user %s does not exist", user.Email))
+			return errors.NewUserResolutionError("user not found", fmt.Errorf("%w: %s does not exist", errors.ErrUserNotFound, user.Email))
 		}
 
 		user.UserID = result.UserID
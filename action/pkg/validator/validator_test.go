@@ -1,13 +1,20 @@
 package validator
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/nobl9/nobl9-go/manifest/v1alpha/rolebinding"
 	"github.com/stretchr/testify/assert"
+	"github.com/your-org/nobl9-action/pkg/errors"
 	"github.com/your-org/nobl9-action/pkg/logger"
 	"github.com/your-org/nobl9-action/pkg/nobl9"
+	"github.com/your-org/nobl9-action/pkg/nobl9/nobl9test"
 	"github.com/your-org/nobl9-action/pkg/resolver"
+	"github.com/your-org/nobl9-action/pkg/roles"
 )
 
 func TestNew(t *testing.T) {
@@ -23,8 +30,53 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, log, validator.logger)
 }
 
+func TestCheckRole(t *testing.T) {
+	catalog := roles.Default()
+	catalog.Project = append(catalog.Project, "slo-reviewer")
+
+	user := "00u1alice"
+	binding := func(role, project string) *rolebinding.RoleBinding {
+		rb := rolebinding.New(rolebinding.Metadata{Name: "payments-alice"}, rolebinding.Spec{User: &user, RoleRef: role, ProjectRef: project})
+		return &rb
+	}
+
+	assert.NoError(t, checkRole(catalog, binding("project-viewer", "payments")))
+	assert.NoError(t, checkRole(catalog, binding("slo-reviewer", "payments")))
+
+	err := checkRole(catalog, binding("project-editr", "payments"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "did you mean project-editor?")
+	}
+	// Project roles cannot be granted organization-wide
+	assert.Error(t, checkRole(catalog, binding("project-viewer", "")))
+}
+
 func TestValidateRoleBindingName(t *testing.T) {
-	t.Skip("Skipping test that requires real Nobl9 client connection")
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	validator := New(nobl9test.New(), &resolver.Resolver{}, log)
+
+	assert.NoError(t, validator.validateRoleBindingName("payments-alice"))
+	assert.Error(t, validator.validateRoleBindingName(""))
+	assert.Error(t, validator.validateRoleBindingName("Payments-Alice"))
+	assert.Error(t, validator.validateRoleBindingName("payments_alice"))
+	assert.Error(t, validator.validateRoleBindingName(strings.Repeat("a", 64)))
+}
+
+func TestValidateProjectExists(t *testing.T) {
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New().AddProject("payments")
+	validator := New(client, &resolver.Resolver{}, log)
+	ctx := context.Background()
+
+	assert.NoError(t, validator.validateProjectExists(ctx, "payments"))
+	if err := validator.validateProjectExists(ctx, "billing"); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "project billing does not exist")
+	}
+
+	client.FailOn("ProjectExists", fmt.Errorf("connection reset"))
+	if err := validator.validateProjectExists(ctx, "payments"); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to check project payments")
+	}
 }
 
 func TestValidateProjectName(t *testing.T) {
@@ -295,5 +347,17 @@ func TestGetValidationSummary(t *testing.T) {
 }
 
 func TestValidateUsers(t *testing.T) {
-	t.Skip("Skipping test that requires real Nobl9 client connection")
+	log := logger.New(logger.LevelInfo, logger.FormatJSON)
+	client := nobl9test.New().AddUser("alice@example.com", "00u1alice")
+	validator := New(client, resolver.New(client, log), log)
+
+	validations, err := validator.ValidateUsers(context.Background(), []string{"alice@example.com", "bob@example.com", "not-an-email"}, map[string]string{})
+	assert.NoError(t, err)
+	if assert.Len(t, validations, 3) {
+		assert.True(t, validations[0].CanBeAssigned)
+		assert.Equal(t, "00u1alice", validations[0].UserID)
+		assert.False(t, validations[1].CanBeAssigned)
+		assert.ErrorIs(t, validations[1].ValidationError, errors.ErrUserNotFound)
+		assert.False(t, validations[2].CanBeAssigned)
+	}
 }
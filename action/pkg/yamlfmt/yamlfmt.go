@@ -0,0 +1,213 @@
+package yamlfmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/your-org/nobl9-action/pkg/yamlnode"
+	"gopkg.in/yaml.v3"
+)
+
+// Indent is the indentation of formatted YAML
+const Indent = 2
+
+// Format rewrites YAML documents into their canonical form: mapping keys
+// sorted, block style with two space indentation, documents separated by
+// a single --- and lists of emails, such as alert method recipients,
+// sorted. Comments, quoting, literal blocks and anchors are kept, so the
+// documents decode to the same objects. Formatting formatted content
+// returns it unchanged.
+func Format(content []byte) ([]byte, error) {
+	return format(content, nil)
+}
+
+// Fix formats YAML documents like Format and also removes the users listed
+// more than once in the spec.users of a RoleBinding, keeping the first.
+// Users are compared by email or ID, ignoring case and surrounding spaces.
+// It returns the number of users removed.
+func Fix(content []byte) ([]byte, int, error) {
+	removed := 0
+	formatted, err := format(content, func(document *yaml.Node) {
+		removed += dedupeUsers(document)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return formatted, removed, nil
+}
+
+// format canonicalizes YAML documents, after applying fix, when set, to
+// each of them
+func format(content []byte, fix func(document *yaml.Node)) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var documents []*yaml.Node
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if isEmpty(&document) {
+			continue
+		}
+		documents = append(documents, &document)
+	}
+	if len(documents) == 0 {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(Indent)
+	for _, document := range documents {
+		if fix != nil {
+			fix(document)
+		}
+		sortDocument(document)
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// isEmpty reports whether a document holds neither a value nor a comment,
+// like the one between two consecutive separators
+func isEmpty(document *yaml.Node) bool {
+	if document.HeadComment != "" || document.LineComment != "" || document.FootComment != "" {
+		return false
+	}
+	for _, node := range document.Content {
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!null" || node.Value != "" ||
+			node.HeadComment != "" || node.LineComment != "" || node.FootComment != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// sortDocument canonicalizes a document. The comment above its first key,
+// such as a file header or a note about the object, stays above the
+// object when another key is sorted first.
+func sortDocument(document *yaml.Node) {
+	if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode || len(document.Content[0].Content) == 0 {
+		canonicalize(document)
+		return
+	}
+	mapping := document.Content[0]
+	head := mapping.Content[0].HeadComment
+	mapping.Content[0].HeadComment = ""
+	canonicalize(document)
+	if head == "" {
+		return
+	}
+	if first := mapping.Content[0]; first.HeadComment != "" {
+		first.HeadComment = head + "\n" + first.HeadComment
+	} else {
+		first.HeadComment = head
+	}
+}
+
+// canonicalize sorts the keys of every mapping and the emails of every list
+// of emails below node, and drops the flow style
+func canonicalize(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style &^= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		canonicalize(child)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		sortMapping(node)
+	case yaml.SequenceNode:
+		if isEmailList(node) {
+			sort.SliceStable(node.Content, func(i, j int) bool {
+				return strings.ToLower(node.Content[i].Value) < strings.ToLower(node.Content[j].Value)
+			})
+		}
+	}
+}
+
+// sortMapping sorts the key and value pairs of a mapping by key
+func sortMapping(mapping *yaml.Node) {
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, pair{mapping.Content[i], mapping.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].key.Value < pairs[j].key.Value })
+	for i, p := range pairs {
+		mapping.Content[2*i], mapping.Content[2*i+1] = p.key, p.value
+	}
+}
+
+// dedupeUsers removes the repeated users of the RoleBinding objects in a
+// document, which holds a single object or a list of them, and returns how
+// many it removed
+func dedupeUsers(document *yaml.Node) int {
+	if len(document.Content) != 1 {
+		return 0
+	}
+	objects := []*yaml.Node{document.Content[0]}
+	if document.Content[0].Kind == yaml.SequenceNode {
+		objects = document.Content[0].Content
+	}
+
+	removed := 0
+	for _, object := range objects {
+		if yamlnode.ScalarValue(yamlnode.MappingValue(object, "kind")) != "RoleBinding" {
+			continue
+		}
+		users := yamlnode.MappingValue(yamlnode.MappingValue(object, "spec"), "users")
+		if users == nil || users.Kind != yaml.SequenceNode {
+			continue
+		}
+		seen := make(map[string]bool, len(users.Content))
+		kept := users.Content[:0]
+		for _, item := range users.Content {
+			user := item
+			if item.Kind == yaml.MappingNode {
+				user = yamlnode.MappingValue(item, "id")
+			}
+			key := strings.ToLower(strings.TrimSpace(yamlnode.ScalarValue(user)))
+			if key != "" && seen[key] {
+				removed++
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, item)
+		}
+		users.Content = kept
+	}
+	return removed
+}
+
+// isEmailList reports whether every item of a sequence is an email address
+func isEmailList(sequence *yaml.Node) bool {
+	if len(sequence.Content) < 2 {
+		return false
+	}
+	for _, item := range sequence.Content {
+		if item.Kind != yaml.ScalarNode || item.Tag != "!!str" || !isEmail(item.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmail reports whether a value reads as a single email address
+func isEmail(value string) bool {
+	local, domain, found := strings.Cut(value, "@")
+	return found && local != "" && strings.Contains(domain, ".") && !strings.ContainsAny(value, " \t,;<>")
+}
@@ -0,0 +1,157 @@
+package yamlfmt
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const testManifests = `# Payments project
+---
+kind: Project
+apiVersion: n9/v1alpha
+metadata:
+    name: payments # keep this comment
+    displayName: 'Payments'
+---
+---
+apiVersion: n9/v1alpha
+kind: AlertMethod
+metadata: {name: email, project: payments}
+spec:
+    email:
+        to: [zed@example.com, Alice@example.com, bob@example.com]
+        subject: |
+            Budget burning
+    description: "alerts"
+    tags: [b, a]
+`
+
+const testFormatted = `# Payments project
+apiVersion: n9/v1alpha
+kind: Project
+metadata:
+  displayName: 'Payments'
+  name: payments # keep this comment
+---
+apiVersion: n9/v1alpha
+kind: AlertMethod
+metadata:
+  name: email
+  project: payments
+spec:
+  description: "alerts"
+  email:
+    subject: |
+      Budget burning
+    to:
+      - Alice@example.com
+      - bob@example.com
+      - zed@example.com
+  tags:
+    - b
+    - a
+`
+
+func TestFormat(t *testing.T) {
+	formatted, err := Format([]byte(testManifests))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(formatted) != testFormatted {
+		t.Errorf("unexpected content:\n%s", formatted)
+	}
+
+	again, err := Format(formatted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(again) != string(formatted) {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatEmpty(t *testing.T) {
+	for _, content := range []string{"", "---\n"} {
+		formatted, err := Format([]byte(content))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(formatted) != content {
+			t.Errorf("expected %q unchanged, got %q", content, formatted)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	content := `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-owners
+spec:
+  projectRef: payments
+  roleRef: project-owner
+  users:
+    - bob@example.com
+    - id: Alice@example.com
+    - alice@example.com
+    - " bob@example.com"
+`
+	fixed, removed, err := Fix([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 duplicate users removed, got %d", removed)
+	}
+	want := `apiVersion: n9/v1alpha
+kind: RoleBinding
+metadata:
+  name: payments-owners
+spec:
+  projectRef: payments
+  roleRef: project-owner
+  users:
+    - bob@example.com
+    - id: Alice@example.com
+`
+	if string(fixed) != want {
+		t.Errorf("unexpected content:\n%s", fixed)
+	}
+
+	// Format keeps the duplicates
+	formatted, err := Format([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(formatted) != content {
+		t.Errorf("expected Format to keep the users, got:\n%s", formatted)
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	if _, err := Format([]byte("metadata:\n  name: {{ .Values.name }\n")); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
+
+func TestIsEmailList(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   bool
+	}{
+		{[]string{"a@example.com", "b@example.com"}, true},
+		{[]string{"a@example.com"}, false},
+		{[]string{"a@example.com", "okta-group:sre"}, false},
+		{[]string{"Alice <a@example.com>", "b@example.com"}, false},
+	}
+	for _, tt := range tests {
+		sequence := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, value := range tt.values {
+			sequence.Content = append(sequence.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		}
+		if got := isEmailList(sequence); got != tt.want {
+			t.Errorf("isEmailList(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
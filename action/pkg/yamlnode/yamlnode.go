@@ -0,0 +1,36 @@
+// Package yamlnode reads values from yaml.v3 node trees, for the packages
+// that rewrite or check manifests as written instead of decoding them
+package yamlnode
+
+import "gopkg.in/yaml.v3"
+
+// MappingValue returns the value of a key of a mapping node, or nil when the
+// node is not a mapping or has no such key. Aliases are returned as is.
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ScalarValue returns the value of a scalar node, or "" when the node is nil
+// or not a scalar
+func ScalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// JoinPath appends a key to a dotted field path such as spec.objectives
+func JoinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,58 @@
+package yamlnode
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMappingValue(t *testing.T) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte("kind: Project\nspec:\n  description: Payments\n"), &document); err != nil {
+		t.Fatal(err)
+	}
+	mapping := document.Content[0]
+
+	if value := MappingValue(mapping, "kind"); value == nil || value.Value != "Project" {
+		t.Errorf("expected kind Project, got %v", value)
+	}
+	if value := MappingValue(MappingValue(mapping, "spec"), "description"); value == nil || value.Value != "Payments" {
+		t.Errorf("expected description Payments, got %v", value)
+	}
+	if value := MappingValue(mapping, "metadata"); value != nil {
+		t.Errorf("expected no metadata, got %v", value)
+	}
+	if value := MappingValue(MappingValue(mapping, "kind"), "name"); value != nil {
+		t.Errorf("expected nil for a scalar node, got %v", value)
+	}
+	if value := MappingValue(nil, "kind"); value != nil {
+		t.Errorf("expected nil for a nil node, got %v", value)
+	}
+}
+
+func TestScalarValue(t *testing.T) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte("kind: Project\nlabels: [payments]\n"), &document); err != nil {
+		t.Fatal(err)
+	}
+	mapping := document.Content[0]
+
+	if value := ScalarValue(MappingValue(mapping, "kind")); value != "Project" {
+		t.Errorf("expected Project, got %q", value)
+	}
+	if value := ScalarValue(MappingValue(mapping, "labels")); value != "" {
+		t.Errorf("expected no value for a sequence node, got %q", value)
+	}
+	if value := ScalarValue(nil); value != "" {
+		t.Errorf("expected no value for a nil node, got %q", value)
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	if path := JoinPath("", "spec"); path != "spec" {
+		t.Errorf("expected spec, got %s", path)
+	}
+	if path := JoinPath("spec", "objectives"); path != "spec.objectives" {
+		t.Errorf("expected spec.objectives, got %s", path)
+	}
+}